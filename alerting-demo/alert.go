@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Severity ranks alerts; channels may ignore severities below their own threshold
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Status tells channels whether an alert is starting or clearing
+type Status string
+
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// Entry is one decoded log line as seen by the monitor
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	Level      string                 `json:"level"`
+	Message    string                 `json:"message"`
+	Caller     string                 `json:"caller,omitempty"`
+	Stacktrace string                 `json:"stacktrace,omitempty"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Alert is what channels receive
+type Alert struct {
+	Rule      string
+	Status    Status
+	Severity  Severity
+	Summary   string
+	Service   map[string]string
+	ErrorRate float64
+	Errors    int
+	Total     int
+	Window    time.Duration
+	Threshold float64
+	Samples   []Entry
+	FiredAt   time.Time
+	// Suppressed counts how many times the rule re-crossed the threshold inside the dedup window
+	Suppressed int
+	// Entry is set when a single log line (a Fatal) triggered the alert rather than a rate
+	Entry *Entry
+	// Context holds the log lines of any level written just before Entry
+	Context []Entry
+}
+
+// Channel delivers alerts to one destination
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+}
+
+// Route sends alerts at or above MinSeverity to a channel
+type Route struct {
+	Channel     Channel
+	MinSeverity Severity
+	// FatalOnly restricts the channel to alerts raised by a Fatal entry
+	FatalOnly bool
+}
+
+// rank orders severities; unknown values rank lowest
+func (s Severity) rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
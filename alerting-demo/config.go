@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config mirrors alerting.yaml
+type Config struct {
+	EvaluationInterval time.Duration `yaml:"evaluation_interval"`
+	Rules              []RuleConfig  `yaml:"rules"`
+	Channels           struct {
+		Slack     SlackConfig     `yaml:"slack"`
+		PagerDuty PagerDutyConfig `yaml:"pagerduty"`
+		Email     EmailConfig     `yaml:"email"`
+	} `yaml:"channels"`
+}
+
+// RuleConfig describes one error-rate threshold
+type RuleConfig struct {
+	Name        string        `yaml:"name"`
+	Severity    Severity      `yaml:"severity"`
+	Window      time.Duration `yaml:"window"`
+	For         time.Duration `yaml:"for"`
+	Threshold   float64       `yaml:"threshold"`
+	MinErrors   int           `yaml:"min_errors"`
+	DedupWindow time.Duration `yaml:"dedup_window"`
+	Samples     int           `yaml:"samples"`
+}
+
+// SlackConfig configures the incoming webhook channel
+type SlackConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	WebhookURL  string   `yaml:"webhook_url"`
+	Channel     string   `yaml:"channel"`
+	Username    string   `yaml:"username"`
+	MinSeverity Severity `yaml:"min_severity"`
+}
+
+// PagerDutyConfig configures the Events API v2 channel
+type PagerDutyConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	RoutingKey  string   `yaml:"routing_key"`
+	EventsURL   string   `yaml:"events_url"`
+	MinSeverity Severity `yaml:"min_severity"`
+}
+
+// EmailConfig configures the SMTP channel, which only sends for Fatal entries
+type EmailConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	SMTPAddr       string   `yaml:"smtp_addr"`
+	Username       string   `yaml:"username"`
+	Password       string   `yaml:"password"`
+	From           string   `yaml:"from"`
+	To             []string `yaml:"to"`
+	ContextEntries int      `yaml:"context_entries"`
+}
+
+// LoadConfig reads the YAML file and applies defaults and environment overrides
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if cfg.EvaluationInterval <= 0 {
+		cfg.EvaluationInterval = time.Second
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if rule.Window <= 0 || rule.Threshold <= 0 || rule.Threshold > 1 {
+			return nil, fmt.Errorf("rule %s needs a positive window and a threshold in (0, 1]", rule.Name)
+		}
+		if rule.Severity == "" {
+			rule.Severity = SeverityWarning
+		}
+		if rule.Samples <= 0 {
+			rule.Samples = 3
+		}
+	}
+
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		cfg.Channels.Slack.WebhookURL = url
+	}
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		cfg.Channels.PagerDuty.RoutingKey = key
+	}
+	if cfg.Channels.PagerDuty.EventsURL == "" {
+		cfg.Channels.PagerDuty.EventsURL = "https://events.pagerduty.com/v2/enqueue"
+	}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		cfg.Channels.Email.SMTPAddr = addr
+	}
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		cfg.Channels.Email.Password = password
+	}
+	if cfg.Channels.Email.Enabled {
+		if len(cfg.Channels.Email.To) == 0 || cfg.Channels.Email.From == "" {
+			return nil, fmt.Errorf("email channel needs from and at least one recipient")
+		}
+		if cfg.Channels.Email.ContextEntries <= 0 {
+			cfg.Channels.Email.ContextEntries = 20
+		}
+	} else {
+		cfg.Channels.Email.ContextEntries = 0
+	}
+	if cfg.Channels.PagerDuty.MinSeverity == "" {
+		cfg.Channels.PagerDuty.MinSeverity = SeverityCritical
+	}
+	return cfg, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EmailChannel mails Fatal and panic alerts with the log lines leading up to them
+type EmailChannel struct {
+	cfg  EmailConfig
+	auth smtp.Auth
+}
+
+// NewEmailChannel creates the channel; PLAIN auth is only used when a username is configured
+func NewEmailChannel(cfg EmailConfig) *EmailChannel {
+	channel := &EmailChannel{cfg: cfg}
+	if cfg.Username != "" {
+		host, _, _ := strings.Cut(cfg.SMTPAddr, ":")
+		channel.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return channel
+}
+
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send delivers one message. net/smtp has no context support, so ctx only guards the start.
+func (c *EmailChannel) Send(ctx context.Context, alert Alert) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(c.cfg.SMTPAddr, c.auth, c.cfg.From, c.cfg.To, c.compose(alert))
+}
+
+func (c *EmailChannel) compose(alert Alert) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", emailSubject(alert))
+	fmt.Fprintf(&msg, "Date: %s\r\n", alert.FiredAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	body := emailBody(alert)
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+// emailSubject distinguishes recovered panics, which the service logs with a "panic" field
+func emailSubject(alert Alert) string {
+	kind := "FATAL"
+	if _, ok := alert.Entry.Fields["panic"]; ok {
+		kind = "PANIC"
+	}
+	return fmt.Sprintf("[%s] %s (%s): %s", kind, alert.Service["service.name"], alert.Service["environment"], alert.Entry.Message)
+}
+
+func emailBody(alert Alert) string {
+	entry := alert.Entry
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Service:     %s %s\n", alert.Service["service.name"], alert.Service["service.version"])
+	fmt.Fprintf(&b, "Environment: %s\n", alert.Service["environment"])
+	fmt.Fprintf(&b, "Time:        %s\n", entry.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "Caller:      %s\n", entry.Caller)
+	fmt.Fprintf(&b, "Message:     %s\n", entry.Message)
+	if runbook := alert.Service["runbook_url"]; runbook != "" {
+		fmt.Fprintf(&b, "Runbook:     %s\n", runbook)
+	}
+
+	if len(entry.Fields) > 0 {
+		b.WriteString("\nFields:\n")
+		keys := make([]string, 0, len(entry.Fields))
+		for key := range entry.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  %s = %v\n", key, entry.Fields[key])
+		}
+	}
+
+	if entry.Stacktrace != "" {
+		b.WriteString("\nStack trace:\n")
+		for _, line := range strings.Split(entry.Stacktrace, "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "\nLast %d log entries before this one (oldest first):\n", len(alert.Context))
+	for _, line := range strings.Split(formatSamples(alert.Context), "\n") {
+		if line != "" {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/redact"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.uber.org/zap"
+)
+
+// phase is one stretch of simulated traffic with a fixed error ratio
+type phase struct {
+	name       string
+	duration   time.Duration
+	errorRatio float64
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Log Alerting Demo ===")
+	console.Println("Evaluates error-rate thresholds over the log stream and notifies Slack, PagerDuty and email")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Diagnostics about alerting itself must not be evaluated by the monitor
+	baseLogger, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stderr"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	// Mock channels and delivery errors echo recipient addresses back
+	baseLogger = redact.Default().Wrap(baseLogger)
+	diagnostics := baseLogger.With("component", "alerting")
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(diagnostics)
+
+	configPath := getEnvOrDefault("CONFIG_PATH", "alerting.yaml")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to load alerting config", "path", configPath, "error", err.Error())
+	}
+
+	var routes []Route
+	if cfg.Channels.Slack.Enabled {
+		if cfg.Channels.Slack.WebhookURL == "" {
+			mock, err := StartMockSlack("127.0.0.1:0", baseLogger.With("component", "mock-slack"))
+			if err != nil {
+				crash.Fatal(diagnostics, "Failed to start mock Slack", "error", err.Error())
+			}
+			defer mock.Close()
+			cfg.Channels.Slack.WebhookURL = mock.WebhookURL()
+			diagnostics.Infow("Started in-process mock Slack webhook", "webhook_url", mock.WebhookURL())
+		}
+		routes = append(routes, Route{Channel: NewSlackChannel(cfg.Channels.Slack), MinSeverity: cfg.Channels.Slack.MinSeverity})
+	}
+	if cfg.Channels.PagerDuty.Enabled {
+		if cfg.Channels.PagerDuty.RoutingKey == "" {
+			mock, err := StartMockPagerDuty("127.0.0.1:0", baseLogger.With("component", "mock-pagerduty"))
+			if err != nil {
+				crash.Fatal(diagnostics, "Failed to start mock PagerDuty", "error", err.Error())
+			}
+			defer mock.Close()
+			cfg.Channels.PagerDuty.RoutingKey = "mock-routing-key"
+			cfg.Channels.PagerDuty.EventsURL = mock.EventsURL()
+			diagnostics.Infow("Started in-process mock PagerDuty", "events_url", mock.EventsURL())
+		}
+		routes = append(routes, Route{Channel: NewPagerDutyChannel(cfg.Channels.PagerDuty), MinSeverity: cfg.Channels.PagerDuty.MinSeverity})
+	}
+	if cfg.Channels.Email.Enabled {
+		if cfg.Channels.Email.SMTPAddr == "" {
+			mock, err := StartMockSMTP("127.0.0.1:0", baseLogger.With("component", "mock-smtp"))
+			if err != nil {
+				crash.Fatal(diagnostics, "Failed to start mock SMTP server", "error", err.Error())
+			}
+			defer mock.Close()
+			cfg.Channels.Email.SMTPAddr = mock.Addr()
+			diagnostics.Infow("Started in-process mock SMTP server", "addr", mock.Addr())
+		}
+		routes = append(routes, Route{Channel: NewEmailChannel(cfg.Channels.Email), MinSeverity: SeverityCritical, FatalOnly: true})
+	}
+
+	monitor := NewMonitor(cfg, routes, diagnostics, clock.Real)
+	crash.OnExit("alert monitor", monitor.Close)
+
+	// The zap engine opens output paths through zap's sink registry, so a custom scheme plugs straight in
+	if err := zap.RegisterSink("alert", func(*url.URL) (zap.Sink, error) { return monitor, nil }); err != nil {
+		crash.Fatal(diagnostics, "Failed to register alert sink", "error", err.Error())
+	}
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout", "alert://monitor"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+			"runbook_url":     getEnvOrDefault("RUNBOOK_URL", "https://runbooks.example.com/apiserver/errors"),
+		},
+	}
+	cliflags.Apply(logOption)
+	appLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create application logger", "error", err.Error())
+	}
+	crash.OnExit("application logger", appLogger.Flush)
+
+	ruleNames := make([]string, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		ruleNames = append(ruleNames, rule.Name)
+	}
+	sanitize.Startup(diagnostics, logOption, "config", configPath, "crash_mode", getEnvOrDefault("CRASH_MODE", "fatal"))
+	diagnostics.Infow("Alerting configured",
+		"config", configPath,
+		"rules", ruleNames,
+		"channels", len(routes),
+		"evaluation_interval", cfg.EvaluationInterval.String(),
+	)
+
+	// The second incident starts inside the dedup window of the first, so it is counted but not posted
+	phases := []phase{
+		{name: "normal", duration: 3 * time.Second, errorRatio: 0.02},
+		{name: "incident", duration: 7 * time.Second, errorRatio: 0.45},
+		{name: "recovery", duration: 7 * time.Second, errorRatio: 0.02},
+		{name: "flapping", duration: 3 * time.Second, errorRatio: 0.45},
+		{name: "recovery", duration: 7 * time.Second, errorRatio: 0.02},
+	}
+	for _, p := range phases {
+		diagnostics.Infow("Traffic phase started", "phase", p.name, "duration", p.duration.String(), "error_ratio", p.errorRatio)
+		generateTraffic(appLogger, p)
+	}
+
+	switch crashMode := getEnvOrDefault("CRASH_MODE", "fatal"); crashMode {
+	case "fatal":
+		// The monitor delivers the fatal alert synchronously inside the write, before the process exits
+		crash.Fatal(appLogger, "Database connection pool exhausted", "pool", "orders-primary", "max_open", 50, "waiting", 312)
+	case "panic":
+		defer logPanic(appLogger)
+		reconcileLedger(nil)
+	}
+
+	appLogger.Flush()
+	monitor.Close()
+	diagnostics.Infow("Alerting demo finished")
+}
+
+// logPanic turns an unrecovered panic into a Fatal entry; zap attaches the stack trace,
+// which still contains the panicking frames because it is captured inside the deferred call
+func logPanic(appLogger core.Logger) {
+	if r := recover(); r != nil {
+		crash.Fatal(appLogger, "Unhandled panic", "panic", fmt.Sprint(r))
+	}
+}
+
+// reconcileLedger stands in for code with a latent nil-map bug
+func reconcileLedger(balances map[string]int) {
+	balances["acct-42"] += 100
+}
+
+// generateTraffic logs about 50 requests per second for the phase
+func generateTraffic(appLogger core.Logger, p phase) {
+	routes := []string{"/orders", "/payments", "/cart", "/search"}
+	failures := []struct {
+		message string
+		err     string
+	}{
+		{"Payment gateway timeout", "context deadline exceeded"},
+		{"Inventory lookup failed", "connection refused"},
+		{"Order persistence failed", "deadlock detected"},
+	}
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(p.duration)
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		route := routes[rng.Intn(len(routes))]
+		requestID := requestid.New(requestid.Request)
+
+		if rng.Float64() < p.errorRatio {
+			failure := failures[rng.Intn(len(failures))]
+			appLogger.Errorw(failure.message, "request_id", requestID, "route", route, "status", 500, "error", failure.err)
+			continue
+		}
+		appLogger.Infow("Request completed", "request_id", requestID, "route", route, "status", 200, "latency_ms", rng.Intn(200))
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
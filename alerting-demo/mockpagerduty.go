@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockPagerDuty imitates the Events API v2 enqueue endpoint
+type MockPagerDuty struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	events atomic.Uint64
+}
+
+// StartMockPagerDuty listens on addr
+func StartMockPagerDuty(addr string, logger core.Logger) (*MockPagerDuty, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockPagerDuty{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/enqueue", m.handleEnqueue)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// EventsURL is the enqueue endpoint
+func (m *MockPagerDuty) EventsURL() string {
+	return "http://" + m.listener.Addr().String() + "/v2/enqueue"
+}
+
+// Events returns how many events were accepted
+func (m *MockPagerDuty) Events() uint64 {
+	return m.events.Load()
+}
+
+// Close stops the server
+func (m *MockPagerDuty) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockPagerDuty) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	var event pagerDutyEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		m.reject(w, "Event object is invalid")
+		return
+	}
+	if event.RoutingKey == "" {
+		m.reject(w, "'routing_key' is missing or blank")
+		return
+	}
+	if event.EventAction == "trigger" && (event.Payload == nil || event.Payload.Summary == "" || event.Payload.Source == "") {
+		m.reject(w, "'payload' requires summary, source and severity")
+		return
+	}
+	m.events.Add(1)
+
+	fields := []interface{}{"event_action", event.EventAction, "dedup_key", event.DedupKey}
+	if event.Payload != nil {
+		fields = append(fields,
+			"summary", event.Payload.Summary,
+			"pd_severity", event.Payload.Severity,
+			"pd_source", event.Payload.Source,
+			"group", event.Payload.Group,
+			"class", event.Payload.Class,
+			"custom_details", event.Payload.CustomDetails,
+		)
+	}
+	if len(event.Links) > 0 {
+		fields = append(fields, "links", event.Links)
+	}
+	m.logger.Infow("Mock PagerDuty received event", fields...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":    "success",
+		"message":   "Event processed",
+		"dedup_key": event.DedupKey,
+	})
+}
+
+func (m *MockPagerDuty) reject(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "invalid event",
+		"message": "Event object is invalid",
+		"errors":  []string{reason},
+	})
+}
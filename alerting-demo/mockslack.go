@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockSlack accepts incoming-webhook posts and logs what would have appeared in the channel
+type MockSlack struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	messages atomic.Uint64
+}
+
+// StartMockSlack listens on addr
+func StartMockSlack(addr string, logger core.Logger) (*MockSlack, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockSlack{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/", m.handleWebhook)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// WebhookURL mimics the hooks.slack.com path layout
+func (m *MockSlack) WebhookURL() string {
+	return "http://" + m.listener.Addr().String() + "/services/T000/B000/XXXX"
+}
+
+// Messages returns how many messages were posted
+func (m *MockSlack) Messages() uint64 {
+	return m.messages.Load()
+}
+
+// Close stops the server
+func (m *MockSlack) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockSlack) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	var message slackMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		// Slack answers malformed payloads with 400 and a plain-text reason
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+		return
+	}
+	m.messages.Add(1)
+
+	fields := []interface{}{"channel", message.Channel, "text", message.Text}
+	if len(message.Attachments) > 0 {
+		attachment := message.Attachments[0]
+		details := make(map[string]string, len(attachment.Fields))
+		for _, field := range attachment.Fields {
+			details[field.Title] = field.Value
+		}
+		fields = append(fields, "title", attachment.Title, "color", attachment.Color, "fields", details)
+	}
+	m.logger.Infow("Mock Slack received message", fields...)
+	w.Write([]byte("ok"))
+}
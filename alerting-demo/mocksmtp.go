@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockSMTP is a minimal in-process SMTP server that logs each message it accepts
+type MockSMTP struct {
+	listener net.Listener
+	logger   core.Logger
+}
+
+// StartMockSMTP listens on addr and serves connections in the background
+func StartMockSMTP(addr string, logger core.Logger) (*MockSMTP, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockSMTP{listener: listener, logger: logger}
+	go m.serve()
+	return m, nil
+}
+
+// Addr returns the listening address
+func (m *MockSMTP) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// Close stops accepting connections
+func (m *MockSMTP) Close() error {
+	return m.listener.Close()
+}
+
+func (m *MockSMTP) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+func (m *MockSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 mock-smtp ready")
+	var recipients []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		upper := strings.ToUpper(cmd)
+
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 mock-smtp")
+		case strings.HasPrefix(upper, "MAIL FROM"):
+			recipients = nil
+			reply("250 2.1.0 OK")
+		case strings.HasPrefix(upper, "RCPT TO"):
+			recipients = append(recipients, strings.Trim(cmd[len("RCPT TO:"):], " <>"))
+			reply("250 2.1.5 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var message strings.Builder
+			for {
+				data, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if data == ".\r\n" {
+					break
+				}
+				// Undo dot-stuffing
+				data = strings.TrimPrefix(data, ".")
+				message.WriteString(strings.TrimSuffix(data, "\r\n") + "\n")
+			}
+			m.logMessage(recipients, message.String())
+			reply("250 2.0.0 Queued")
+		case upper == "RSET", upper == "NOOP":
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (m *MockSMTP) logMessage(recipients []string, message string) {
+	headers, body, _ := strings.Cut(message, "\n\n")
+	subject := ""
+	for _, header := range strings.Split(headers, "\n") {
+		if value, ok := strings.CutPrefix(header, "Subject: "); ok {
+			subject = value
+		}
+	}
+	m.logger.Infow("Mock SMTP received email",
+		"recipients", recipients,
+		"subject", subject,
+		"body_lines", strings.Count(body, "\n"),
+	)
+	// Print the body as-is so the demo shows exactly what on-call would read
+	fmt.Fprintf(os.Stderr, "----- email body -----\n%s----- end of email -----\n", body)
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// baseKeys are decoded into Entry fields or dropped instead of being kept as fields
+var baseKeys = map[string]bool{
+	"level": true, "timestamp": true, "message": true, "caller": true, "stacktrace": true, "engine": true,
+}
+
+// serviceKeys are InitialFields copied into every alert
+var serviceKeys = []string{"service.name", "service.version", "environment", "runbook_url"}
+
+// fatalRule names alerts raised by a single Fatal entry
+const fatalRule = "fatal_log"
+
+type bucket struct {
+	second int64
+	total  int
+	errors int
+}
+
+type ruleState struct {
+	pendingSince time.Time
+	firing       bool
+	notified     bool
+	lastNotified time.Time
+	suppressed   int
+}
+
+// Monitor is a zap sink that counts log lines per second and fires alerts when
+// a rule's error rate is crossed. Fatal entries are the only ones that alert on their own.
+type Monitor struct {
+	rules    []RuleConfig
+	routes   []Route
+	logger   core.Logger
+	interval time.Duration
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	buckets   []bucket
+	recent    []Entry
+	context   []Entry
+	contextN  int
+	service   map[string]string
+	states    map[string]*ruleState
+	maxWindow time.Duration
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewMonitor starts the evaluation loop, which ticks on clk
+func NewMonitor(cfg *Config, routes []Route, diagnostics core.Logger, clk clock.Clock) *Monitor {
+	m := &Monitor{
+		rules:    cfg.Rules,
+		routes:   routes,
+		logger:   diagnostics,
+		interval: cfg.EvaluationInterval,
+		clock:    clk,
+		service:  map[string]string{},
+		states:   map[string]*ruleState{},
+		// One extra slot, because the fatal entry itself is recorded before the alert is built
+		contextN: cfg.Channels.Email.ContextEntries + 1,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, rule := range cfg.Rules {
+		m.states[rule.Name] = &ruleState{}
+		if rule.Window > m.maxWindow {
+			m.maxWindow = rule.Window
+		}
+	}
+
+	go m.run()
+	return m
+}
+
+// Write decodes one JSON log line and records it
+func (m *Monitor) Write(p []byte) (int, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(p), &raw); err != nil {
+		return len(p), nil
+	}
+	entry := decodeEntry(raw, m.clock.Now())
+
+	m.record(raw, entry)
+
+	// The process exits right after writing a Fatal entry, so this alert cannot wait for the
+	// next evaluation. crash.Fatal writes its entry at error level with exit_code set.
+	if isFatal(entry.Level) || isExiting(entry) {
+		m.dispatch(m.fatalAlert(entry))
+	}
+	return len(p), nil
+}
+
+func (m *Monitor) record(raw map[string]interface{}, entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range serviceKeys {
+		if value, ok := raw[key]; ok {
+			m.service[key] = fmt.Sprint(value)
+		}
+	}
+
+	second := entry.Time.Unix()
+	if n := len(m.buckets); n == 0 || m.buckets[n-1].second != second {
+		m.buckets = append(m.buckets, bucket{second: second})
+	}
+	current := &m.buckets[len(m.buckets)-1]
+	current.total++
+
+	if m.contextN > 1 {
+		m.context = append(m.context, entry)
+		if len(m.context) > m.contextN {
+			m.context = m.context[len(m.context)-m.contextN:]
+		}
+	}
+
+	if isError(entry.Level) {
+		current.errors++
+		m.recent = append(m.recent, entry)
+		if len(m.recent) > 50 {
+			m.recent = m.recent[len(m.recent)-50:]
+		}
+	}
+}
+
+// fatalAlert builds a critical alert for one Fatal entry, with the errors leading up to it as samples
+func (m *Monitor) fatalAlert(entry Entry) Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	samples := m.samples(entry.Time.Add(-time.Minute), 5)
+	if n := len(samples); n > 0 && samples[n-1].Message == entry.Message && samples[n-1].Time.Equal(entry.Time) {
+		samples = samples[:n-1]
+	}
+	// The fatal entry itself is the last one recorded
+	context := make([]Entry, 0, len(m.context))
+	if n := len(m.context); n > 0 {
+		context = append(context, m.context[:n-1]...)
+	}
+
+	return Alert{
+		Context:  context,
+		Rule:     fatalRule,
+		Status:   StatusFiring,
+		Severity: SeverityCritical,
+		Summary:  "Fatal: " + entry.Message,
+		Service:  m.copyService(),
+		Samples:  samples,
+		FiredAt:  entry.Time,
+		Entry:    &entry,
+	}
+}
+
+// Sync is a no-op; alerts are evaluated on their own schedule
+func (m *Monitor) Sync() error {
+	return nil
+}
+
+// Close runs a final evaluation and stops the loop
+func (m *Monitor) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+		<-m.stopped
+	})
+	return nil
+}
+
+func (m *Monitor) run() {
+	defer close(m.stopped)
+
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C():
+			m.evaluate(now)
+		case <-m.done:
+			m.evaluate(m.clock.Now())
+			return
+		}
+	}
+}
+
+func (m *Monitor) evaluate(now time.Time) {
+	var alerts []Alert
+
+	m.mu.Lock()
+	m.prune(now)
+	for _, rule := range m.rules {
+		if alert, ok := m.evaluateRule(rule, now); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		m.dispatch(alert)
+	}
+}
+
+// evaluateRule updates the rule state and returns an alert when channels should be notified
+func (m *Monitor) evaluateRule(rule RuleConfig, now time.Time) (Alert, bool) {
+	state := m.states[rule.Name]
+	since := now.Add(-rule.Window)
+
+	total, errors := 0, 0
+	for _, b := range m.buckets {
+		if b.second >= since.Unix() {
+			total += b.total
+			errors += b.errors
+		}
+	}
+	rate := 0.0
+	if total > 0 {
+		rate = float64(errors) / float64(total)
+	}
+	crossed := rate >= rule.Threshold && errors >= rule.MinErrors
+
+	alert := Alert{
+		Rule:      rule.Name,
+		Severity:  rule.Severity,
+		Service:   m.copyService(),
+		ErrorRate: rate,
+		Errors:    errors,
+		Total:     total,
+		Window:    rule.Window,
+		Threshold: rule.Threshold,
+		FiredAt:   now,
+	}
+
+	if !crossed {
+		state.pendingSince = time.Time{}
+	}
+
+	switch {
+	case crossed && !state.firing:
+		// A rule with "for" only fires once the rate has stayed above the threshold that long
+		if rule.For > 0 {
+			if state.pendingSince.IsZero() {
+				state.pendingSince = now
+			}
+			if now.Sub(state.pendingSince) < rule.For {
+				return Alert{}, false
+			}
+		}
+		state.firing = true
+		if !state.lastNotified.IsZero() && now.Sub(state.lastNotified) < rule.DedupWindow {
+			state.suppressed++
+			state.notified = false
+			m.logger.Infow("Alert suppressed by dedup window",
+				"rule", rule.Name,
+				"error_rate", rate,
+				"last_notified", state.lastNotified.Format(time.RFC3339),
+				"dedup_window", rule.DedupWindow.String(),
+				"suppressed", state.suppressed,
+			)
+			return Alert{}, false
+		}
+		state.notified = true
+		state.lastNotified = now
+		alert.Status = StatusFiring
+		alert.Suppressed = state.suppressed
+		alert.Samples = m.samples(since, rule.Samples)
+		alert.Summary = fmt.Sprintf("Error rate %.0f%% over the last %s (%d of %d log lines), threshold %.0f%%",
+			rate*100, rule.Window, errors, total, rule.Threshold*100)
+		state.suppressed = 0
+		return alert, true
+
+	case !crossed && state.firing:
+		state.firing = false
+		if !state.notified {
+			return Alert{}, false
+		}
+		alert.Status = StatusResolved
+		alert.Summary = fmt.Sprintf("Error rate back to %.0f%% over the last %s", rate*100, rule.Window)
+		return alert, true
+	}
+	return Alert{}, false
+}
+
+func (m *Monitor) dispatch(alert Alert) {
+	m.logger.Infow("Alert triggered",
+		"rule", alert.Rule,
+		"status", alert.Status,
+		"severity", alert.Severity,
+		"error_rate", alert.ErrorRate,
+		"errors", alert.Errors,
+		"total", alert.Total,
+	)
+
+	for _, route := range m.routes {
+		if alert.Severity.rank() < route.MinSeverity.rank() || (route.FatalOnly && alert.Entry == nil) {
+			continue
+		}
+		channel := route.Channel
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := channel.Send(ctx, alert)
+		cancel()
+		if err != nil {
+			m.logger.Errorw("Failed to deliver alert",
+				"channel", channel.Name(),
+				"rule", alert.Rule,
+				"status", alert.Status,
+				"error", err.Error(),
+			)
+			continue
+		}
+		m.logger.Infow("Alert delivered", "channel", channel.Name(), "rule", alert.Rule, "status", alert.Status)
+	}
+}
+
+// prune drops buckets and samples older than the widest rule window
+func (m *Monitor) prune(now time.Time) {
+	cutoff := now.Add(-m.maxWindow).Unix()
+	i := 0
+	for i < len(m.buckets) && m.buckets[i].second < cutoff {
+		i++
+	}
+	m.buckets = m.buckets[i:]
+}
+
+// samples returns the newest error entries inside the window, oldest first
+func (m *Monitor) samples(since time.Time, limit int) []Entry {
+	var samples []Entry
+	for i := len(m.recent) - 1; i >= 0 && len(samples) < limit; i-- {
+		if m.recent[i].Time.Before(since) {
+			break
+		}
+		samples = append([]Entry{m.recent[i]}, samples...)
+	}
+	return samples
+}
+
+func (m *Monitor) copyService() map[string]string {
+	service := make(map[string]string, len(m.service))
+	for k, v := range m.service {
+		service[k] = v
+	}
+	return service
+}
+
+// decodeEntry builds an Entry from a decoded line; now is used when it has no timestamp
+func decodeEntry(raw map[string]interface{}, now time.Time) Entry {
+	entry := Entry{Time: now, Fields: map[string]interface{}{}}
+	if level, ok := raw["level"].(string); ok {
+		entry.Level = level
+	}
+	if message, ok := raw["message"].(string); ok {
+		entry.Message = message
+	}
+	if caller, ok := raw["caller"].(string); ok {
+		entry.Caller = caller
+	}
+	if stacktrace, ok := raw["stacktrace"].(string); ok {
+		entry.Stacktrace = stacktrace
+	}
+	if ts, ok := raw["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			entry.Time = t
+		}
+	}
+
+	for key, value := range raw {
+		if !baseKeys[key] && !isServiceKey(key) {
+			entry.Fields[key] = value
+		}
+	}
+	return entry
+}
+
+func isServiceKey(key string) bool {
+	for _, serviceKey := range serviceKeys {
+		if key == serviceKey {
+			return true
+		}
+	}
+	return false
+}
+
+func isError(level string) bool {
+	return level == "error" || isFatal(level)
+}
+
+func isFatal(level string) bool {
+	return level == "fatal" || level == "panic" || level == "dpanic"
+}
+
+// isExiting reports an entry written by crash.Fatal or crash.Recover just before exiting
+func isExiting(entry Entry) bool {
+	_, ok := entry.Fields["exit_code"]
+	return ok
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+// recordingChannel keeps what it was sent
+type recordingChannel struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (c *recordingChannel) Name() string { return "recording" }
+
+func (c *recordingChannel) Send(_ context.Context, alert Alert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = append(c.alerts, alert)
+	return nil
+}
+
+// waitFor returns the alerts once there are n of them. Advance only hands the tick over,
+// so the evaluation may still be running when it returns.
+func (c *recordingChannel) waitFor(t *testing.T, n int) []Alert {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		alerts := append([]Alert(nil), c.alerts...)
+		c.mu.Unlock()
+		if len(alerts) >= n {
+			return alerts
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("channel got %d alerts, want %d", len(alerts), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestMonitorLifecycle drives one rule through firing, resolving and a dedup-suppressed
+// re-fire on a fake clock and compares the diagnostics with testdata/monitor.golden
+func TestMonitorLifecycle(t *testing.T) {
+	rec := testlog.New(t)
+	fake := clock.NewFake(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	channel := &recordingChannel{}
+
+	cfg := &Config{
+		EvaluationInterval: 5 * time.Second,
+		Rules: []RuleConfig{{
+			Name:        "error_rate",
+			Severity:    SeverityWarning,
+			Window:      10 * time.Second,
+			Threshold:   0.5,
+			MinErrors:   3,
+			DedupWindow: time.Minute,
+			Samples:     2,
+		}},
+	}
+	monitor := NewMonitor(cfg, []Route{{Channel: channel, MinSeverity: SeverityWarning}}, rec.Logger, fake)
+	fake.BlockUntil(1)
+
+	write := func(level, message string) {
+		line, _ := json.Marshal(map[string]interface{}{
+			"level":        level,
+			"timestamp":    fake.Now().Format(time.RFC3339Nano),
+			"message":      message,
+			"service.name": "checkout",
+		})
+		_, _ = monitor.Write(line)
+	}
+
+	// 4 of 5 lines are errors: fires at the 10:00:05 evaluation
+	for i := 0; i < 4; i++ {
+		write("error", fmt.Sprintf("Payment gateway timeout %d", i))
+	}
+	write("info", "Request completed")
+	fake.Advance(5 * time.Second)
+
+	// The errors leave the 10s window by 10:00:15 and the rule resolves
+	fake.Advance(10 * time.Second)
+	channel.waitFor(t, 2)
+
+	// Crossing again inside the dedup window is logged but not sent
+	for i := 0; i < 3; i++ {
+		write("error", "Inventory lookup failed")
+	}
+	fake.Advance(5 * time.Second)
+	_ = monitor.Close()
+
+	alerts := channel.waitFor(t, 2)
+	if len(alerts) != 2 || alerts[0].Status != StatusFiring || alerts[1].Status != StatusResolved {
+		t.Fatalf("channel got %d alerts: %+v", len(alerts), alerts)
+	}
+	if got := alerts[0].FiredAt; !got.Equal(time.Date(2024, 5, 1, 10, 0, 5, 0, time.UTC)) {
+		t.Errorf("firing alert FiredAt = %v", got)
+	}
+	rec.AssertGolden(filepath.Join("testdata", "monitor.golden"))
+}
+
+// TestMonitorAlertsOnCrashExit raises the fatal alert for the error-level entry crash.Fatal
+// writes before exiting
+func TestMonitorAlertsOnCrashExit(t *testing.T) {
+	rec := testlog.New(t)
+	fake := clock.NewFake(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	channel := &recordingChannel{}
+
+	monitor := NewMonitor(&Config{EvaluationInterval: 5 * time.Second}, []Route{{Channel: channel, MinSeverity: SeverityCritical, FatalOnly: true}}, rec.Logger, fake)
+	defer monitor.Close()
+
+	for _, fields := range []map[string]interface{}{
+		{"level": "error", "message": "Payment gateway timeout"},
+		{"level": "error", "message": "Database connection pool exhausted", "exit_code": 2},
+	} {
+		line, _ := json.Marshal(fields)
+		_, _ = monitor.Write(line)
+	}
+
+	alerts := channel.waitFor(t, 1)
+	if alerts[0].Rule != fatalRule || alerts[0].Entry == nil || alerts[0].Entry.Message != "Database connection pool exhausted" {
+		t.Fatalf("alert = %+v", alerts[0])
+	}
+}
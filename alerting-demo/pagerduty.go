@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PagerDutyChannel sends Events API v2 trigger and resolve events
+type PagerDutyChannel struct {
+	cfg    PagerDutyConfig
+	client *http.Client
+}
+
+// NewPagerDutyChannel creates the channel; the routing key must already be resolved
+func NewPagerDutyChannel(cfg PagerDutyConfig) *PagerDutyChannel {
+	return &PagerDutyChannel{cfg: cfg, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *PagerDutyChannel) Name() string {
+	return "pagerduty"
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+	Client      string            `json:"client,omitempty"`
+}
+
+// Send enqueues one event; PagerDuty answers 202 on success
+func (c *PagerDutyChannel) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(c.event(alert))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.EventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pagerduty returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// event maps InitialFields onto the payload: service.name is the source and component,
+// environment the group, and runbook_url becomes a link on the incident
+func (c *PagerDutyChannel) event(alert Alert) pagerDutyEvent {
+	service := alert.Service["service.name"]
+	environment := alert.Service["environment"]
+
+	event := pagerDutyEvent{
+		RoutingKey: c.cfg.RoutingKey,
+		DedupKey:   dedupKey(alert),
+		Client:     "kart-io/logger alerting",
+	}
+
+	if alert.Status == StatusResolved {
+		// Resolve events only need the key of the incident they close
+		event.EventAction = "resolve"
+		return event
+	}
+
+	details := map[string]interface{}{
+		"service.version": alert.Service["service.version"],
+		"rule":            alert.Rule,
+	}
+	if alert.Entry != nil {
+		details["caller"] = alert.Entry.Caller
+		details["fields"] = alert.Entry.Fields
+	} else {
+		details["error_rate"] = fmt.Sprintf("%.1f%%", alert.ErrorRate*100)
+		details["errors"] = alert.Errors
+		details["total"] = alert.Total
+		details["window"] = alert.Window.String()
+	}
+	if len(alert.Samples) > 0 {
+		details["samples"] = strings.Split(formatSamples(alert.Samples), "\n")
+	}
+
+	event.EventAction = "trigger"
+	event.Payload = &pagerDutyPayload{
+		Summary:       fmt.Sprintf("[%s] %s: %s", environment, service, alert.Summary),
+		Source:        service,
+		Severity:      pagerDutySeverity(alert.Severity),
+		Timestamp:     alert.FiredAt.UTC().Format(time.RFC3339),
+		Component:     service,
+		Group:         environment,
+		Class:         alert.Rule,
+		CustomDetails: details,
+	}
+	if runbook := alert.Service["runbook_url"]; runbook != "" {
+		event.Links = []pagerDutyLink{{Href: runbook, Text: "Runbook"}}
+	}
+	return event
+}
+
+// dedupKey lets the resolve close the same incident; fatal alerts never resolve, so each message gets its own
+func dedupKey(alert Alert) string {
+	key := fmt.Sprintf("%s/%s/%s", alert.Service["service.name"], alert.Service["environment"], alert.Rule)
+	if alert.Entry != nil {
+		key += "/" + alert.Entry.Message
+	}
+	return key
+}
+
+func pagerDutySeverity(severity Severity) string {
+	if severity == SeverityCritical {
+		return "critical"
+	}
+	return "warning"
+}
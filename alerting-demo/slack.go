@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SlackChannel posts alerts to an incoming webhook
+type SlackChannel struct {
+	cfg    SlackConfig
+	client *http.Client
+}
+
+// NewSlackChannel creates the channel; the webhook URL must already be resolved
+func NewSlackChannel(cfg SlackConfig) *SlackChannel {
+	return &SlackChannel{cfg: cfg, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *SlackChannel) Name() string {
+	return "slack"
+}
+
+// Send posts one message; non-2xx responses are errors so the monitor can log them
+func (c *SlackChannel) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(c.message(alert))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text"`
+	Fields []slackField `json:"fields,omitempty"`
+	Footer string       `json:"footer,omitempty"`
+	Ts     int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+func (c *SlackChannel) message(alert Alert) slackMessage {
+	service := alert.Service["service.name"]
+	environment := alert.Service["environment"]
+
+	color, icon := "warning", ":warning:"
+	if alert.Severity == SeverityCritical {
+		color, icon = "danger", ":rotating_light:"
+	}
+	if alert.Status == StatusResolved {
+		color, icon = "good", ":white_check_mark:"
+	}
+
+	attachment := slackAttachment{
+		Color:  color,
+		Title:  fmt.Sprintf("[%s] %s on %s (%s)", strings.ToUpper(string(alert.Status)), alert.Rule, service, environment),
+		Text:   alert.Summary,
+		Footer: "kart-io/logger alerting",
+		Ts:     alert.FiredAt.Unix(),
+	}
+	if alert.Entry != nil {
+		attachment.Fields = []slackField{
+			{Title: "Caller", Value: alert.Entry.Caller, Short: true},
+			{Title: "Version", Value: alert.Service["service.version"], Short: true},
+			{Title: "Fatal entry", Value: "```" + formatSamples([]Entry{*alert.Entry}) + "```"},
+		}
+	} else {
+		attachment.Fields = []slackField{
+			{Title: "Error rate", Value: fmt.Sprintf("%.1f%%", alert.ErrorRate*100), Short: true},
+			{Title: "Errors / total", Value: fmt.Sprintf("%d / %d", alert.Errors, alert.Total), Short: true},
+			{Title: "Window", Value: alert.Window.String(), Short: true},
+			{Title: "Version", Value: alert.Service["service.version"], Short: true},
+		}
+	}
+	if runbook := alert.Service["runbook_url"]; runbook != "" {
+		attachment.Fields = append(attachment.Fields, slackField{Title: "Runbook", Value: runbook, Short: true})
+	}
+	if alert.Suppressed > 0 {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: "Suppressed since last alert",
+			Value: fmt.Sprintf("%d", alert.Suppressed),
+			Short: true,
+		})
+	}
+	if len(alert.Samples) > 0 {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: fmt.Sprintf("Triggering log samples (%d)", len(alert.Samples)),
+			Value: "```" + formatSamples(alert.Samples) + "```",
+		})
+	}
+
+	return slackMessage{
+		Channel:     c.cfg.Channel,
+		Username:    c.cfg.Username,
+		Text:        fmt.Sprintf("%s %s: %s", icon, alert.Rule, alert.Summary),
+		Attachments: []slackAttachment{attachment},
+	}
+}
+
+// formatSamples renders one line per entry with its fields in a stable order
+func formatSamples(samples []Entry) string {
+	var b strings.Builder
+	for i, entry := range samples {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s %s %q", entry.Time.Format("15:04:05.000"), strings.ToUpper(entry.Level), entry.Message)
+
+		keys := make([]string, 0, len(entry.Fields))
+		for key := range entry.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%v", key, entry.Fields[key])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStats are cumulative counters since the cache was created
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Expirations uint64
+	Evictions   uint64
+	Entries     int
+}
+
+// HitRate is hits over lookups, or 0 before the first lookup
+func (s CacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// KeyCount is one entry of the hot-key report
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Cache is an LRU cache with per-prefix TTLs that also counts lookups per key,
+// so the reporter can name the hottest keys of each interval
+type Cache struct {
+	maxEntries int
+	defaultTTL time.Duration
+	ttls       map[string]time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List
+	items   map[string]*list.Element
+	stats   CacheStats
+	lookups map[string]int
+}
+
+// NewCache creates a cache from the YAML settings
+func NewCache(cfg CacheConfig) *Cache {
+	return &Cache{
+		maxEntries: cfg.MaxEntries,
+		defaultTTL: cfg.DefaultTTL,
+		ttls:       cfg.TTLs,
+		lru:        list.New(),
+		items:      make(map[string]*list.Element),
+		lookups:    make(map[string]int),
+	}
+}
+
+// TTLFor returns the TTL configured for key's prefix
+func (c *Cache) TTLFor(key string) time.Duration {
+	prefix, _, _ := strings.Cut(key, ":")
+	if ttl, ok := c.ttls[prefix]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// Get returns the cached value; an expired entry is removed and counted as a miss
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lookups[key]++
+	elem, ok := c.items[key]
+	if ok {
+		entry := elem.Value.(*cacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(elem)
+			c.stats.Hits++
+			return entry.value, true
+		}
+		c.remove(elem)
+		c.stats.Expirations++
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+// Set stores value under the prefix TTL, evicting the least recently used entry when full
+func (c *Cache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.TTLFor(key))
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+	if c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove unlinks elem; callers hold c.mu
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry).key)
+}
+
+// Stats returns the cumulative counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// TakeHotKeys returns the n most looked-up keys since the previous call and resets the counts
+func (c *Cache) TakeHotKeys(n int) []KeyCount {
+	c.mu.Lock()
+	lookups := c.lookups
+	c.lookups = make(map[string]int)
+	c.mu.Unlock()
+
+	hot := make([]KeyCount, 0, len(lookups))
+	for key, count := range lookups {
+		hot = append(hot, KeyCount{Key: key, Count: count})
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Count != hot[j].Count {
+			return hot[i].Count > hot[j].Count
+		}
+		return hot[i].Key < hot[j].Key
+	})
+	if len(hot) > n {
+		hot = hot[:n]
+	}
+	return hot
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config mirrors caching.yaml
+type Config struct {
+	StatsInterval time.Duration  `yaml:"stats_interval"`
+	HotKeys       int            `yaml:"hot_keys"`
+	Cache         CacheConfig    `yaml:"cache"`
+	Store         StoreConfig    `yaml:"store"`
+	Workload      WorkloadConfig `yaml:"workload"`
+}
+
+// CacheConfig sizes the cache and sets TTLs per key prefix
+type CacheConfig struct {
+	MaxEntries int                      `yaml:"max_entries"`
+	DefaultTTL time.Duration            `yaml:"default_ttl"`
+	TTLs       map[string]time.Duration `yaml:"ttls"`
+}
+
+// StoreConfig controls how slow the backing store is
+type StoreConfig struct {
+	Latency time.Duration `yaml:"latency"`
+	Jitter  time.Duration `yaml:"jitter"`
+}
+
+// WorkloadConfig shapes the simulated read traffic
+type WorkloadConfig struct {
+	Rate    int     `yaml:"rate"`
+	Workers int     `yaml:"workers"`
+	Keys    uint64  `yaml:"keys"`
+	Skew    float64 `yaml:"skew"`
+}
+
+// LoadConfig reads the YAML file and applies defaults
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if cfg.StatsInterval <= 0 {
+		cfg.StatsInterval = 5 * time.Second
+	}
+	if cfg.HotKeys <= 0 {
+		cfg.HotKeys = 5
+	}
+	if cfg.Cache.MaxEntries <= 0 {
+		return nil, fmt.Errorf("cache.max_entries must be positive")
+	}
+	if cfg.Cache.DefaultTTL <= 0 {
+		cfg.Cache.DefaultTTL = time.Minute
+	}
+	for prefix, ttl := range cfg.Cache.TTLs {
+		if ttl <= 0 {
+			return nil, fmt.Errorf("cache.ttls.%s must be a positive duration", prefix)
+		}
+	}
+	if cfg.Workload.Rate <= 0 {
+		cfg.Workload.Rate = 100
+	}
+	if cfg.Workload.Workers <= 0 {
+		cfg.Workload.Workers = 4
+	}
+	if cfg.Workload.Keys == 0 {
+		cfg.Workload.Keys = 100
+	}
+	if cfg.Workload.Skew <= 1 {
+		return nil, fmt.Errorf("workload.skew must be greater than 1")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+var prefixes = []string{"product", "price", "user"}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Cache-Aside Demo ===")
+	console.Println("Cache-aside over a slow store with periodic hit-rate, eviction and hot-key logging")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	configPath := getEnvOrDefault("CONFIG_PATH", "caching.yaml")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to load caching config", "path", configPath, "error", err.Error())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelRun := context.WithTimeout(ctx, getDurationEnv("DURATION", 20*time.Second))
+	defer cancelRun()
+
+	cache := NewCache(cfg.Cache)
+	store := NewSlowStore(cfg.Store)
+	service := NewService(cache, store, baseLogger.With("component", "cache-aside"))
+
+	ttls := make(map[string]string, len(prefixes))
+	for _, prefix := range prefixes {
+		ttls[prefix] = cache.TTLFor(prefix + ":").String()
+	}
+	sanitize.Startup(baseLogger, logOption, "config", configPath)
+	baseLogger.Infow("Starting cache-aside demo",
+		"config", configPath,
+		"max_entries", cfg.Cache.MaxEntries,
+		"ttls", ttls,
+		"store_latency", cfg.Store.Latency.String(),
+		"rate", cfg.Workload.Rate,
+		"keys", cfg.Workload.Keys*uint64(len(prefixes)),
+	)
+
+	reporter := NewReporter(cache, store, cfg.HotKeys, baseLogger.With("component", "cache-stats"))
+	go reporter.Run(ctx, cfg.StatsInterval)
+
+	runWorkload(ctx, service, cfg.Workload, baseLogger.With("component", "workload"))
+	reporter.Final()
+}
+
+// runWorkload issues Zipf-distributed lookups from a pool of workers until ctx is done
+func runWorkload(ctx context.Context, service *Service, cfg WorkloadConfig, log core.Logger) {
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				if _, err := service.Get(ctx, key); err != nil && ctx.Err() == nil {
+					log.Warnw("Lookup failed", "key", key, "error", err.Error())
+				}
+			}
+		}()
+	}
+
+	// Its own stream, so --seed repeats the key sequence whatever the workers draw
+	keys := rng.New("caching-keys")
+	zipf := rand.NewZipf(keys, cfg.Skew, 1, cfg.Keys-1)
+	ticker := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		key := fmt.Sprintf("%s:%d", prefixes[keys.Intn(len(prefixes))], zipf.Uint64())
+		select {
+		case jobs <- key:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Service reads through the cache: on a miss it loads from the store and populates the cache
+type Service struct {
+	cache  *Cache
+	store  *SlowStore
+	logger core.Logger
+}
+
+// NewService wires the cache in front of the store
+func NewService(cache *Cache, store *SlowStore, logger core.Logger) *Service {
+	return &Service{cache: cache, store: store, logger: logger}
+}
+
+// Get returns the value for key, loading it from the store on a cache miss
+func (s *Service) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, ok := s.cache.Get(key); ok {
+		return value, nil
+	}
+
+	start := time.Now()
+	value, err := s.store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", key, err)
+	}
+	s.cache.Set(key, value)
+	// Misses are the normal cold path; they are counted by the reporter and only logged in debug
+	s.logger.Debugw("Cache miss loaded from store",
+		"key", key,
+		"load_ms", time.Since(start).Milliseconds(),
+		"ttl", s.cache.TTLFor(key).String(),
+	)
+	return value, nil
+}
+
+// Reporter logs cache effectiveness per interval: hit rate, evictions, expirations and hot keys
+type Reporter struct {
+	cache   *Cache
+	store   *SlowStore
+	hotKeys int
+	logger  core.Logger
+
+	started time.Time
+	last    CacheStats
+}
+
+// NewReporter creates a reporter naming the top hotKeys keys each interval
+func NewReporter(cache *Cache, store *SlowStore, hotKeys int, logger core.Logger) *Reporter {
+	return &Reporter{cache: cache, store: store, hotKeys: hotKeys, logger: logger, started: time.Now()}
+}
+
+// Run logs one stats entry per interval until ctx is done
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.report(interval)
+		}
+	}
+}
+
+func (r *Reporter) report(interval time.Duration) {
+	stats := r.cache.Stats()
+	delta := CacheStats{
+		Hits:        stats.Hits - r.last.Hits,
+		Misses:      stats.Misses - r.last.Misses,
+		Expirations: stats.Expirations - r.last.Expirations,
+		Evictions:   stats.Evictions - r.last.Evictions,
+	}
+	r.last = stats
+
+	r.logger.Infow("Cache stats",
+		"interval", interval.String(),
+		"lookups", delta.Hits+delta.Misses,
+		"hits", delta.Hits,
+		"misses", delta.Misses,
+		"hit_rate", round(delta.HitRate()),
+		"cumulative_hit_rate", round(stats.HitRate()),
+		"evictions", delta.Evictions,
+		"expirations", delta.Expirations,
+		"entries", stats.Entries,
+		"hot_keys", r.cache.TakeHotKeys(r.hotKeys),
+	)
+}
+
+// Final logs totals for the whole run, including how much store time the cache saved
+func (r *Reporter) Final() {
+	stats := r.cache.Stats()
+	loads, loadTime := r.store.Loads()
+	var avgLoad time.Duration
+	if loads > 0 {
+		avgLoad = loadTime / time.Duration(loads)
+	}
+	r.logger.Infow("Cache summary",
+		"uptime", time.Since(r.started).Round(time.Second).String(),
+		"lookups", stats.Hits+stats.Misses,
+		"hit_rate", round(stats.HitRate()),
+		"evictions", stats.Evictions,
+		"expirations", stats.Expirations,
+		"entries", stats.Entries,
+		"store_loads", loads,
+		"avg_load_ms", avgLoad.Milliseconds(),
+		"store_time_saved_ms", (time.Duration(stats.Hits) * avgLoad).Milliseconds(),
+	)
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/rng"
+)
+
+// SlowStore stands in for a database: every load costs latency plus random jitter
+type SlowStore struct {
+	latency time.Duration
+	jitter  time.Duration
+
+	loads   atomic.Uint64
+	totalNs atomic.Int64
+}
+
+// NewSlowStore creates a store with the configured latency
+func NewSlowStore(cfg StoreConfig) *SlowStore {
+	return &SlowStore{latency: cfg.Latency, jitter: cfg.Jitter}
+}
+
+// Load fetches the value for key
+func (s *SlowStore) Load(ctx context.Context, key string) ([]byte, error) {
+	delay := s.latency
+	if s.jitter > 0 {
+		delay += time.Duration(rng.Int63n(int64(s.jitter)))
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	s.loads.Add(1)
+	s.totalNs.Add(int64(delay))
+	return []byte(fmt.Sprintf(`{"key":%q,"loaded_at":%q}`, key, time.Now().Format(time.RFC3339Nano))), nil
+}
+
+// Loads returns how many loads completed and their total latency
+func (s *SlowStore) Loads() (uint64, time.Duration) {
+	return s.loads.Load(), time.Duration(s.totalNs.Load())
+}
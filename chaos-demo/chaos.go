@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/logger/core"
+)
+
+// FaultType is the kind of fault a rule injects
+type FaultType string
+
+const (
+	FaultLatency FaultType = "latency"
+	FaultError   FaultType = "error"
+	FaultPanic   FaultType = "panic"
+)
+
+// chaosFaultKey is the gin context key holding the injected rule ID
+const chaosFaultKey = "chaos_fault"
+
+// Rule injects one fault on matching routes with the given probability
+type Rule struct {
+	ID          string    `json:"id"`
+	Route       string    `json:"route"`
+	Method      string    `json:"method,omitempty"`
+	Fault       FaultType `json:"fault"`
+	Probability float64   `json:"probability"`
+	LatencyMS   int       `json:"latency_ms,omitempty"`
+	Status      int       `json:"status,omitempty"`
+}
+
+// matches reports whether the rule applies to a route; "*" matches every route
+func (r Rule) matches(method, route string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	return r.Route == "*" || r.Route == route
+}
+
+// ChaosConfig is the state exchanged with the admin endpoint
+type ChaosConfig struct {
+	Enabled bool   `json:"enabled"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Validate fills defaults and rejects rules that cannot be applied
+func (cfg *ChaosConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Route == "" {
+			return fmt.Errorf("rule %s needs a route (gin route pattern or \"*\")", rule.ID)
+		}
+		if rule.Probability <= 0 || rule.Probability > 1 {
+			return fmt.Errorf("rule %s needs a probability in (0, 1]", rule.ID)
+		}
+		switch rule.Fault {
+		case FaultLatency:
+			if rule.LatencyMS <= 0 {
+				return fmt.Errorf("rule %s needs latency_ms", rule.ID)
+			}
+		case FaultError:
+			if rule.Status == 0 {
+				rule.Status = http.StatusServiceUnavailable
+			}
+			if rule.Status < 400 || rule.Status > 599 {
+				return fmt.Errorf("rule %s needs an error status between 400 and 599", rule.ID)
+			}
+		case FaultPanic:
+		default:
+			return fmt.Errorf("rule %s has unknown fault %q (latency, error, panic)", rule.ID, rule.Fault)
+		}
+	}
+	return nil
+}
+
+// Chaos holds the active fault rules; it is safe for concurrent use
+type Chaos struct {
+	mu       sync.RWMutex
+	config   ChaosConfig
+	injected map[string]int
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	// logger records configuration changes; injected faults are logged through the
+	// request's logger
+	logger core.Logger
+}
+
+// NewChaos creates a disabled injector; seed makes the fault sequence reproducible
+func NewChaos(seed int64, logger core.Logger) *Chaos {
+	return &Chaos{
+		injected: make(map[string]int),
+		rng:      rand.New(rand.NewSource(seed)),
+		logger:   logger,
+	}
+}
+
+// Middleware injects faults on matching routes; admin and health routes are never affected
+func (ch *Chaos) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" || route == "/health" || strings.HasPrefix(route, "/admin") {
+			c.Next()
+			return
+		}
+
+		rule, roll, ok := ch.pick(c.Request.Method, route)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		c.Set(chaosFaultKey, rule.ID)
+		c.Header("X-Chaos-Fault", rule.ID)
+		// The request logger already carries request_id, method and route
+		logger := logcontext.MustFromGin(c)
+		fields := []interface{}{
+			"rule_id", rule.ID,
+			"fault", rule.Fault,
+			"probability", rule.Probability,
+			"roll", roll,
+		}
+
+		switch rule.Fault {
+		case FaultLatency:
+			logger.Warnw("Chaos fault injected", append(fields, "latency_ms", rule.LatencyMS)...)
+			time.Sleep(time.Duration(rule.LatencyMS) * time.Millisecond)
+			c.Next()
+		case FaultError:
+			logger.Warnw("Chaos fault injected", append(fields, "status", rule.Status)...)
+			c.AbortWithStatusJSON(rule.Status, gin.H{"error": "injected fault", "rule": rule.ID})
+		case FaultPanic:
+			logger.Warnw("Chaos fault injected", fields...)
+			panic(fmt.Sprintf("chaos: injected panic (rule %s)", rule.ID))
+		}
+	}
+}
+
+// pick returns the first matching rule whose roll succeeds
+func (ch *Chaos) pick(method, route string) (Rule, float64, bool) {
+	ch.mu.RLock()
+	enabled := ch.config.Enabled
+	rules := ch.config.Rules
+	ch.mu.RUnlock()
+	if !enabled {
+		return Rule{}, 0, false
+	}
+
+	for _, rule := range rules {
+		if !rule.matches(method, route) {
+			continue
+		}
+		ch.rngMu.Lock()
+		roll := ch.rng.Float64()
+		ch.rngMu.Unlock()
+		if roll < rule.Probability {
+			ch.mu.Lock()
+			ch.injected[rule.ID]++
+			ch.mu.Unlock()
+			return rule, roll, true
+		}
+	}
+	return Rule{}, 0, false
+}
+
+// Set replaces the configuration
+func (ch *Chaos) Set(cfg ChaosConfig, actor string) {
+	ch.mu.Lock()
+	previous := ch.config
+	ch.config = cfg
+	ch.injected = make(map[string]int)
+	ch.mu.Unlock()
+
+	ids := make([]string, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		ids = append(ids, fmt.Sprintf("%s:%s:%s@%g", rule.ID, rule.Fault, rule.Route, rule.Probability))
+	}
+	ch.logger.Warnw("Chaos configuration updated",
+		"enabled", cfg.Enabled,
+		"previous_enabled", previous.Enabled,
+		"rules", ids,
+		"previous_rules", len(previous.Rules),
+		"actor", actor,
+	)
+}
+
+// SetEnabled toggles injection without touching the rules
+func (ch *Chaos) SetEnabled(enabled bool, actor string) {
+	ch.mu.Lock()
+	previous := ch.config.Enabled
+	ch.config.Enabled = enabled
+	rules := len(ch.config.Rules)
+	ch.mu.Unlock()
+
+	if previous == enabled {
+		return
+	}
+	if enabled {
+		ch.logger.Warnw("Chaos injection enabled", "rules", rules, "actor", actor)
+		return
+	}
+	ch.logger.Infow("Chaos injection disabled", "rules", rules, "actor", actor)
+}
+
+// Snapshot returns the configuration and per-rule injection counts
+func (ch *Chaos) Snapshot() (ChaosConfig, map[string]int) {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	injected := make(map[string]int, len(ch.injected))
+	for id, n := range ch.injected {
+		injected[id] = n
+	}
+	return ch.config, injected
+}
+
+// RegisterAdmin mounts the admin endpoints on group
+func (ch *Chaos) RegisterAdmin(group *gin.RouterGroup) {
+	group.GET("/chaos", func(c *gin.Context) {
+		cfg, injected := ch.Snapshot()
+		c.JSON(http.StatusOK, gin.H{"enabled": cfg.Enabled, "rules": cfg.Rules, "injected": injected})
+	})
+	group.PUT("/chaos", func(c *gin.Context) {
+		var cfg ChaosConfig
+		if err := c.ShouldBindJSON(&cfg); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := cfg.Validate(); err != nil {
+			logcontext.MustFromGin(c).Warnw("Rejected chaos configuration", "error", err.Error(), "actor", actorOf(c))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ch.Set(cfg, actorOf(c))
+		c.JSON(http.StatusOK, cfg)
+	})
+	group.POST("/chaos/enable", func(c *gin.Context) {
+		ch.SetEnabled(true, actorOf(c))
+		c.JSON(http.StatusOK, gin.H{"enabled": true})
+	})
+	group.POST("/chaos/disable", func(c *gin.Context) {
+		ch.SetEnabled(false, actorOf(c))
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+	})
+	group.DELETE("/chaos", func(c *gin.Context) {
+		ch.Set(ChaosConfig{}, actorOf(c))
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// actorOf identifies who changed the configuration, for the audit trail in the logs
+func actorOf(c *gin.Context) string {
+	if user := c.GetHeader("X-Admin-User"); user != "" {
+		return user
+	}
+	return c.ClientIP()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func newTestRouter(rec *testlog.Recorder, cfg ChaosConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	chaos := NewChaos(1, rec.Logger)
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	chaos.Set(cfg, "test")
+
+	r := gin.New()
+	r.Use(logcontext.GinRequestLogger(rec.Logger), accessLog(), chaos.Middleware())
+	r.GET("/inventory/:sku", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sku": c.Param("sku")})
+	})
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	})
+	return r
+}
+
+func TestChaosErrorFaultIsLogged(t *testing.T) {
+	rec := testlog.New(t)
+	r := newTestRouter(rec, ChaosConfig{Enabled: true, Rules: []Rule{
+		{ID: "inventory-503", Route: "/inventory/:sku", Fault: FaultError, Probability: 1},
+	}})
+	rec.AssertLogged("warn", "Chaos configuration updated", "enabled", true, "actor", "test")
+
+	req := httptest.NewRequest(http.MethodGet, "/inventory/sku-1", nil)
+	req.Header.Set(requestid.Header, "req-test-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+	rec.AssertLogged("warn", "Chaos fault injected",
+		"rule_id", "inventory-503",
+		"fault", "error",
+		"route", "/inventory/:sku",
+		"status", 503,
+		"request_id", "req-test-1",
+	)
+	rec.AssertLogged("info", "Request completed",
+		"request_id", "req-test-1",
+		"status", 503,
+		"chaos_rule", "inventory-503",
+	)
+}
+
+func TestChaosSkipsHealthAndDisabledConfig(t *testing.T) {
+	rec := testlog.New(t)
+	r := newTestRouter(rec, ChaosConfig{Enabled: true, Rules: []Rule{
+		{ID: "everything", Route: "*", Fault: FaultError, Probability: 1},
+	}})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	rec.AssertNotLogged("", "Chaos fault injected")
+	entry := rec.AssertLogged("info", "Request completed", "route", "/health", "status", 200)
+	if _, ok := entry["chaos_rule"]; ok {
+		t.Errorf("untouched request tagged with chaos_rule: %s", entry)
+	}
+}
+
+func TestChaosRejectsInvalidConfig(t *testing.T) {
+	rec := testlog.New(t)
+	r := newTestRouter(rec, ChaosConfig{})
+	chaos := NewChaos(1, rec.Logger)
+	chaos.RegisterAdmin(r.Group("/admin"))
+
+	body := `{"enabled":true,"rules":[{"route":"/orders","fault":"error","probability":2}]}`
+	req := httptest.NewRequest(http.MethodPut, "/admin/chaos", strings.NewReader(body))
+	req.Header.Set("X-Admin-User", "alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	rec.AssertLogged("warn", "Rejected chaos configuration", "actor", "alice", "error", "rule rule-1 needs a probability in (0, 1]")
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/logrules"
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/go-example/pkg/slo"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Chaos Fault Injection Demo ===")
+	console.Println("Admin-controlled middleware injecting latency, errors and panics on selected routes")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer leakwatch.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	// Every entry the service writes is checked against logrules.yaml, so a burst of failed
+	// requests raises its own warning without a log pipeline in between
+	rulesPath := getEnvOrDefault("LOG_RULES_FILE", "logrules.yaml")
+	rulesCfg, err := logrules.Load(rulesPath)
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to load log rules", "path", rulesPath, "error", err.Error())
+	}
+	rules, err := logrules.New(rulesCfg, baseLogger.With("component", "logrules"))
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to start log rules", "path", rulesPath, "error", err.Error())
+	}
+	defer rules.Close()
+	// crash.Fatal logs at error level, so webhooks it triggers are still in flight when it exits
+	crash.OnExit("log rules", func() error {
+		rules.Close()
+		return nil
+	})
+	observed := rules.Wrap(baseLogger)
+
+	// The access log doubles as the SLI source: GET /slo shows availability and latency
+	// against slo.yaml and a fast-burning error budget is logged as a warning
+	sloPath := getEnvOrDefault("SLO_FILE", "slo.yaml")
+	sloCfg, err := slo.Load(sloPath)
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to load SLO config", "path", sloPath, "error", err.Error())
+	}
+	tracker, err := slo.New(sloCfg, observed.With("component", "slo"))
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to start SLO tracking", "path", sloPath, "error", err.Error())
+	}
+	tracker.Start()
+	defer tracker.Stop()
+	serviceLogger := tracker.Wrap(observed.With("component", "http"))
+
+	seed := getInt64Env("CHAOS_SEED", rng.Seed())
+	chaos := NewChaos(seed, observed.With("component", "chaos"))
+	configPath := cliflags.ConfigOr(os.Getenv("CHAOS_CONFIG"))
+	if configPath != "" {
+		cfg, err := loadChaosConfig(configPath)
+		if err != nil {
+			crash.Fatal(baseLogger, "Failed to load chaos configuration", "path", configPath, "error", err.Error())
+		}
+		chaos.Set(cfg, "file:"+configPath)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	reg := metrics.New("chaos-demo")
+	rules.Instrument(reg)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	// RED sits outside the chaos middleware so injected errors and latency show up in the metrics
+	r.Use(reg.RED())
+	r.Use(requestid.GinMiddleware(requestid.Request))
+	// Everything below logs through the request's child logger, which carries request_id,
+	// method, route and client_ip
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+	r.Use(accessLog())
+	// The panic is logged as structured JSON below, so gin's plain-text dump is discarded
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logcontext.MustFromGin(c).Errorw("Panic recovered",
+			"panic", fmt.Sprint(recovered),
+			"chaos_rule", c.GetString(chaosFaultKey),
+		)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}))
+	r.Use(chaos.Middleware())
+
+	r.GET("/orders/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"order_id": c.Param("id"), "status": "shipped"})
+	})
+	r.POST("/orders", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"order_id": fmt.Sprintf("ord-%d", time.Now().UnixMilli()%100000)})
+	})
+	r.GET("/inventory/:sku", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sku": c.Param("sku"), "available": 42})
+	})
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/metrics", reg.GinHandler())
+	r.GET("/health", health.Handler(nil))
+	r.GET("/slo", gin.WrapH(tracker.Handler()))
+
+	// Fault injection is behind ADMIN_TOKEN, which has no default: without it /admin/chaos
+	// is not served at all
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	// Admin requests, let through or not, go to the audit trail in AUDIT_LOG_FILE
+	auditPath := getEnvOrDefault("AUDIT_LOG_FILE", "logs/audit.log")
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to open audit log", "path", auditPath, "error", err.Error())
+	}
+	defer auditLog.Close()
+	endpoints := []string{"/orders/:id", "/orders", "/inventory/:sku", "/health", "/version", "/metrics", "/slo"}
+	if adminToken != "" {
+		chaos.RegisterAdmin(r.Group("/admin", auditLog.GinMiddleware(audit.ActionAdminRequest, serviceLogger), adminAuth(adminToken)))
+		endpoints = append(endpoints, "/admin/chaos")
+	} else {
+		serviceLogger.Warnw("Admin routes disabled", "reason", "ADMIN_TOKEN not set")
+	}
+
+	port := getEnvOrDefault("PORT", "8107")
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "chaos_seed", seed, "chaos_config", configPath, "log_rules", len(rulesCfg.Rules), "slo_window", sloCfg.Window.String(), "audit_log", auditPath)
+	serviceLogger.Infow("Starting chaos demo server",
+		"port", port,
+		"chaos_seed", seed,
+		"faults", []FaultType{FaultLatency, FaultError, FaultPanic},
+		"endpoints", endpoints,
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	if adminToken != "" {
+		// The token itself stays out of the output
+		console.Printf("  curl -X PUT http://localhost:%s/admin/chaos -H \"%s: $ADMIN_TOKEN\" -d '{\"enabled\":true,\"rules\":[{\"route\":\"/orders/:id\",\"fault\":\"latency\",\"probability\":0.5,\"latency_ms\":300},{\"route\":\"/inventory/:sku\",\"fault\":\"error\",\"probability\":0.3,\"status\":503},{\"route\":\"/orders\",\"method\":\"POST\",\"fault\":\"panic\",\"probability\":0.2}]}'\n", port, adminTokenHeader)
+	}
+	console.Printf("  for i in $(seq 10); do curl -s http://localhost:%s/inventory/sku-1; echo; done\n", port)
+	console.Printf("  curl http://localhost:%s/slo\n", port)
+	if adminToken != "" {
+		console.Printf("  curl http://localhost:%s/admin/chaos -H \"%s: $ADMIN_TOKEN\"\n", port, adminTokenHeader)
+		console.Printf("  curl -X POST http://localhost:%s/admin/chaos/disable -H \"%s: $ADMIN_TOKEN\"\n", port, adminTokenHeader)
+	} else {
+		console.Println("  (set ADMIN_TOKEN to inject faults through /admin/chaos)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// accessLog writes one line per request, tagging responses shaped by chaos
+func accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		fields := []interface{}{
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		}
+		if rule := c.GetString(chaosFaultKey); rule != "" {
+			fields = append(fields, "chaos_rule", rule)
+		}
+		logcontext.MustFromGin(c).Infow("Request completed", fields...)
+	}
+}
+
+// adminAuth protects the admin endpoints with a static token
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hmac.Equal([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) {
+			logcontext.MustFromGin(c).Warnw("Unauthorized admin request", "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// loadChaosConfig reads an initial configuration in the same format the admin endpoint accepts
+func loadChaosConfig(path string) (ChaosConfig, error) {
+	var cfg ChaosConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read chaos config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse chaos config: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kart-io/logger/core"
+)
+
+const (
+	// sendBuffer is how many outbound messages a client may have pending
+	sendBuffer = 64
+	// slowConsumerGrace disconnects a consumer that keeps dropping messages this long
+	slowConsumerGrace = time.Second
+	writeWait         = 5 * time.Second
+	pongWait          = 30 * time.Second
+	pingPeriod        = pongWait * 9 / 10
+	maxMessageBytes   = 2048
+)
+
+// Client is a single WebSocket connection in a room
+type Client struct {
+	id       uint64
+	user     string
+	conn     *websocket.Conn
+	room     *Room
+	logger   core.Logger
+	send     chan Message
+	joinedAt time.Time
+
+	// slowDelay artificially slows the writer to demonstrate slow consumers
+	slowDelay time.Duration
+
+	closeOnce        sync.Once
+	done             chan struct{}
+	consecutiveDrops atomic.Int64
+	firstDropAt      atomic.Int64
+
+	sent     atomic.Uint64
+	received atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// enqueue hands a message to the writer, dropping it if the client's buffer is full
+func (c *Client) enqueue(msg Message) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+		// Only a consumer that has drained half its buffer counts as caught up
+		if len(c.send) <= sendBuffer/2 {
+			c.consecutiveDrops.Store(0)
+		}
+		return true
+	default:
+	}
+
+	dropped := c.dropped.Add(1)
+	consecutive := c.consecutiveDrops.Add(1)
+	if consecutive == 1 {
+		c.firstDropAt.Store(time.Now().UnixNano())
+	}
+	behind := time.Since(time.Unix(0, c.firstDropAt.Load()))
+
+	// Log the first drop and then every 10th to avoid flooding the output
+	if consecutive == 1 || dropped%10 == 0 {
+		c.logger.Warnw("Message dropped, client buffer full",
+			"message_type", msg.Type,
+			"buffer_size", sendBuffer,
+			"dropped_total", dropped,
+			"consecutive_drops", consecutive,
+		)
+	}
+
+	if behind >= slowConsumerGrace {
+		c.logger.Warnw("Slow consumer disconnected",
+			"consecutive_drops", consecutive,
+			"dropped_total", dropped,
+			"behind_ms", behind.Milliseconds(),
+		)
+		c.close("slow_consumer")
+	}
+	return false
+}
+
+// close tears down the connection exactly once
+func (c *Client) close(reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.room.Leave(c, reason)
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, reason),
+			time.Now().Add(writeWait))
+		c.conn.Close()
+	})
+}
+
+// readPump reads chat messages from the connection until it closes
+func (c *Client) readPump() {
+	reason := "client_closed"
+	defer func() { c.close(reason) }()
+
+	c.conn.SetReadLimit(maxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			switch {
+			case errors.Is(err, websocket.ErrReadLimit):
+				c.logger.Warnw("Message too large", "max_bytes", maxMessageBytes)
+				reason = "message_too_large"
+			case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+				reason = "client_closed"
+			case websocket.IsUnexpectedCloseError(err):
+				c.logger.Warnw("Connection read failed", "error", err.Error())
+				reason = "read_error"
+			}
+			return
+		}
+
+		c.received.Add(1)
+
+		var incoming struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(data, &incoming) != nil || incoming.Text == "" {
+			// Plain text frames are accepted as the message body
+			incoming.Text = string(data)
+		}
+
+		c.logger.Debugw("Message received", "bytes", len(data))
+		c.room.Broadcast(Message{Type: "message", Room: c.room.name, User: c.user, Text: incoming.Text})
+	}
+}
+
+// writePump delivers queued messages and keeps the connection alive with pings
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			if c.slowDelay > 0 {
+				time.Sleep(c.slowDelay)
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				select {
+				case <-c.done:
+					// Already closing; the write raced with close
+				default:
+					c.logger.Warnw("Connection write failed", "error", err.Error())
+					c.close("write_error")
+				}
+				return
+			}
+			c.sent.Add(1)
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close("ping_failed")
+				return
+			}
+		}
+	}
+}
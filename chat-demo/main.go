@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Demo only: accept connections from any origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== WebSocket Chat Demo ===")
+	console.Println("Rooms with per-room and per-connection child loggers")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "chat",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	hub := NewHub(serviceLogger)
+	var nextConnID atomic.Uint64
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	// Handlers log through the request's child logger (request_id, method, route, client_ip);
+	// connections keep the room's logger for the life of the socket
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.GET("/", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(indexHTML))
+	})
+
+	r.GET("/rooms", func(c *gin.Context) {
+		c.JSON(http.StatusOK, hub.Stats())
+	})
+
+	r.GET("/ws/:room", func(c *gin.Context) {
+		user := c.Query("user")
+		if user == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user query parameter is required"})
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			logcontext.MustFromGin(c).Warnw("WebSocket upgrade failed",
+				"room", c.Param("room"),
+				"error", err.Error(),
+			)
+			return
+		}
+
+		slowMs, _ := strconv.Atoi(c.Query("slow_ms"))
+		client := hub.Join(c.Param("room"), func(room *Room) *Client {
+			id := nextConnID.Add(1)
+			return &Client{
+				id:   id,
+				user: user,
+				conn: conn,
+				room: room,
+				logger: room.logger.With(
+					"conn_id", id,
+					"user", user,
+					"remote_addr", c.ClientIP(),
+				),
+				send:      make(chan Message, sendBuffer),
+				done:      make(chan struct{}),
+				joinedAt:  time.Now(),
+				slowDelay: time.Duration(slowMs) * time.Millisecond,
+			}
+		})
+		if client.slowDelay > 0 {
+			client.logger.Infow("Client writer slowed down for demo", "slow_ms", slowMs)
+		}
+
+		go client.writePump()
+		client.readPump()
+		hub.removeIfEmpty(client.room)
+	})
+
+	port := getEnvOrDefault("PORT", "8095")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port)
+	serviceLogger.Infow("Starting chat server",
+		"port", port,
+		"send_buffer", sendBuffer,
+		"slow_consumer_grace", slowConsumerGrace.String(),
+		"endpoints", []string{"/", "/rooms", "/ws/:room", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Printf("Open http://localhost:%s/ in two browser tabs, or use websocat:\n", port)
+	console.Printf("  websocat 'ws://localhost:%s/ws/general?user=alice'\n", port)
+	console.Printf("  websocat 'ws://localhost:%s/ws/general?user=slowpoke&slow_ms=500'   # becomes a slow consumer\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head><title>chat-demo</title></head>
+<body>
+<input id="room" value="general"> <input id="user" placeholder="name"> <button onclick="join()">Join</button>
+<pre id="log" style="height:300px;overflow:auto;border:1px solid #ccc"></pre>
+<input id="text" size="60" onkeydown="if(event.key==='Enter')send()"> <button onclick="send()">Send</button>
+<script>
+let ws;
+function join() {
+  const room = document.getElementById('room').value;
+  const user = document.getElementById('user').value || 'guest';
+  ws = new WebSocket('ws://' + location.host + '/ws/' + encodeURIComponent(room) + '?user=' + encodeURIComponent(user));
+  ws.onmessage = e => {
+    const m = JSON.parse(e.data);
+    const line = m.type === 'message' ? m.user + ': ' + m.text : '* ' + m.user + ' ' + m.type + 's';
+    const log = document.getElementById('log');
+    log.textContent += line + '\n';
+    log.scrollTop = log.scrollHeight;
+  };
+}
+function send() {
+  const input = document.getElementById('text');
+  if (ws && input.value) { ws.send(JSON.stringify({text: input.value})); input.value = ''; }
+}
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Message is broadcast to every member of a room
+type Message struct {
+	Type   string    `json:"type"`
+	Room   string    `json:"room"`
+	User   string    `json:"user,omitempty"`
+	Text   string    `json:"text,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Room fans out messages to its members; each room has its own child logger
+type Room struct {
+	name   string
+	logger core.Logger
+
+	mu      sync.RWMutex
+	members map[uint64]*Client
+
+	messages uint64
+	dropped  uint64
+}
+
+// Hub owns all rooms and creates them on demand
+type Hub struct {
+	logger core.Logger
+
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+// NewHub creates an empty hub
+func NewHub(logger core.Logger) *Hub {
+	return &Hub{logger: logger, rooms: make(map[string]*Room)}
+}
+
+// Join adds a client built for the named room, creating the room if needed.
+// Holding the hub lock keeps a concurrent removeIfEmpty from closing the room in between.
+func (h *Hub) Join(name string, newClient func(room *Room) *Client) *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[name]
+	if !ok {
+		room = &Room{
+			name:    name,
+			logger:  h.logger.With("room", name),
+			members: make(map[uint64]*Client),
+		}
+		h.rooms[name] = room
+		room.logger.Infow("Room created", "rooms", len(h.rooms))
+	}
+
+	client := newClient(room)
+	room.Join(client)
+	return client
+}
+
+// removeIfEmpty deletes a room once its last member has left
+func (h *Hub) removeIfEmpty(room *Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if room.Size() > 0 || h.rooms[room.name] != room {
+		return
+	}
+	delete(h.rooms, room.name)
+	room.logger.Infow("Room closed",
+		"messages", room.messages,
+		"dropped", room.dropped,
+		"rooms", len(h.rooms),
+	)
+}
+
+// Stats returns member counts for every room
+func (h *Hub) Stats() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := make(map[string]int, len(h.rooms))
+	for name, room := range h.rooms {
+		stats[name] = room.Size()
+	}
+	return stats
+}
+
+// Join adds a client to the room and announces it
+func (r *Room) Join(client *Client) {
+	r.mu.Lock()
+	r.members[client.id] = client
+	size := len(r.members)
+	r.mu.Unlock()
+
+	client.logger.Infow("User joined", "members", size)
+	r.Broadcast(Message{Type: "join", Room: r.name, User: client.user})
+}
+
+// Leave removes a client and announces it
+func (r *Room) Leave(client *Client, reason string) {
+	r.mu.Lock()
+	if _, ok := r.members[client.id]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.members, client.id)
+	size := len(r.members)
+	r.mu.Unlock()
+
+	client.logger.Infow("User left",
+		"reason", reason,
+		"members", size,
+		"sent", client.sent.Load(),
+		"received", client.received.Load(),
+		"dropped", client.dropped.Load(),
+		"duration_ms", time.Since(client.joinedAt).Milliseconds(),
+	)
+	if size > 0 {
+		r.Broadcast(Message{Type: "leave", Room: r.name, User: client.user})
+	}
+}
+
+// Broadcast delivers a message to every member without blocking on slow consumers
+func (r *Room) Broadcast(msg Message) {
+	msg.SentAt = time.Now().UTC()
+
+	r.mu.Lock()
+	r.messages++
+	members := make([]*Client, 0, len(r.members))
+	for _, member := range r.members {
+		members = append(members, member)
+	}
+	r.mu.Unlock()
+
+	for _, member := range members {
+		if !member.enqueue(msg) {
+			r.mu.Lock()
+			r.dropped++
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Size returns the number of members
+func (r *Room) Size() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.members)
+}
@@ -0,0 +1,57 @@
+// Command audit-verify checks the hash chain of audit files written by pkg/audit. For each
+// file it reports the number of entries and the last hash, or the first line that breaks
+// the chain:
+//
+//	go run ./cmd/audit-verify chaos-demo/logs/audit.log
+//	go run ./cmd/audit-verify -expect 41d0… chaos-demo/logs/audit.log
+//
+// A chain can only show tampering inside a file. Removing entries from its end leaves an
+// intact chain, so compare the last hash with one kept elsewhere, which -expect does.
+// The exit code is 1 when a chain is broken or does not end in the expected hash.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kart-io/go-example/pkg/audit"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	expect := flag.String("expect", "", "hash the last entry must have, e.g. one recorded by a log shipper")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: audit-verify [-expect hash] file...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flag.Args() {
+		result, err := audit.VerifyFile(path)
+		var chainErr *audit.ChainError
+		switch {
+		case errors.As(err, &chainErr):
+			fmt.Printf("%s:%d: chain broken: %s (%d entries intact before it)\n", path, chainErr.Line, chainErr.Reason, result.Entries)
+			status = 1
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "audit-verify: %v\n", err)
+			return 2
+		case *expect != "" && result.LastHash != *expect:
+			fmt.Printf("%s: chain intact but ends in %s, not the expected %s: entries are missing from its end\n", path, result.LastHash, *expect)
+			status = 1
+		default:
+			fmt.Printf("%s: %d entries, chain intact, last hash %s\n", path, result.Entries, result.LastHash)
+		}
+	}
+	return status
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+// target is the request a load test repeats
+type target struct {
+	method string
+	url    string
+	body   string
+	// status is the expected status code; 0 accepts any 2xx
+	status int
+}
+
+// loadResult is what a load test measured
+type loadResult struct {
+	sent, ok, failed int
+	// dropped counts requests not sent because maxInFlight were still outstanding,
+	// which means the server could not keep up with the rate
+	dropped   int
+	elapsed   time.Duration
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+}
+
+// attack sends requests to t at a fixed rate for the given duration. It is open loop: a
+// slow server does not slow the sender down, so latency reflects the offered load rather
+// than what the server chose to accept.
+func attack(ctx context.Context, client *http.Client, t target, rps int, duration time.Duration, maxInFlight int) loadResult {
+	res := loadResult{statuses: map[int]int{}, errors: map[string]int{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxInFlight)
+
+	interval := time.Second / time.Duration(rps)
+	start := time.Now()
+	end := start.Add(duration)
+	for i := 0; ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		if !next.Before(end) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(next)):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			mu.Lock()
+			res.dropped++
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			sent := time.Now()
+			status, err := send(ctx, client, t)
+			latency := time.Since(sent)
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.sent++
+			switch {
+			case err != nil:
+				res.failed++
+				res.errors[errorKind(err)]++
+				return
+			case t.status == 0 && status >= 200 && status < 300, status == t.status:
+				res.ok++
+			default:
+				res.failed++
+			}
+			res.statuses[status]++
+			res.latencies = append(res.latencies, latency)
+		}()
+	}
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+	return res
+}
+
+func send(ctx context.Context, client *http.Client, t target) (int, error) {
+	var body io.Reader
+	if t.body != "" {
+		body = strings.NewReader(t.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, t.method, t.url, body)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// errorKind shortens transport errors so they group in the report
+func errorKind(err error) string {
+	msg := err.Error()
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		msg = msg[i+2:]
+	}
+	return msg
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies, nearest rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// logCounter counts the lines a demo writes and how many of them are log entries, and
+// optionally passes the output on
+type logCounter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	partial []byte
+	lines   int
+	entries int
+	bytes   int64
+}
+
+// logCount is a snapshot of a logCounter
+type logCount struct {
+	lines, entries int
+	bytes          int64
+}
+
+func (c *logCounter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.out != nil {
+		_, _ = c.out.Write(p)
+	}
+	c.bytes += int64(len(p))
+	data := append(c.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		c.lines++
+		if logschema.IsEntry(bytes.TrimSpace(data[:i])) {
+			c.entries++
+		}
+		data = data[i+1:]
+	}
+	c.partial = append(c.partial[:0], data...)
+	return len(p), nil
+}
+
+func (c *logCounter) snapshot() logCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return logCount{lines: c.lines, entries: c.entries, bytes: c.bytes}
+}
+
+// loadOptions are the flags of "demo-runner loadtest"
+type loadOptions struct {
+	rps         int
+	duration    time.Duration
+	maxInFlight int
+	portEnv     string
+	ldflags     bool
+	verbose     bool
+	extraEnv    []string
+	target      target
+}
+
+func loadtestCommand(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	root := fs.String("root", "", "repository root (default: found from the current directory)")
+	rps := fs.Int("rps", 100, "requests per second")
+	duration := fs.Duration("duration", 10*time.Second, "how long to send requests")
+	method := fs.String("method", "GET", "request method")
+	path := fs.String("path", "/health", "request path")
+	body := fs.String("body", "", "JSON request body")
+	status := fs.Int("status", 0, "expected status code (default: any 2xx)")
+	maxInFlight := fs.Int("max-inflight", 256, "outstanding requests before new ones are dropped")
+	timeout := fs.Duration("timeout", 5*time.Second, "per-request timeout")
+	portEnv := fs.String("port-env", "", "port variable to send requests to (default: the demo's first, usually PORT)")
+	url := fs.String("url", "", "load an already running server at this base URL instead of starting a demo")
+	noLdflags := fs.Bool("no-ldflags", false, "do not inject service name and version")
+	verbose := fs.Bool("v", false, "print the demo's output")
+	var env envFlag
+	fs.Var(&env, "env", "extra environment variable for the demo, KEY=value (repeatable)")
+	_ = fs.Parse(args)
+
+	if *rps <= 0 || *duration <= 0 || *maxInFlight <= 0 {
+		fmt.Fprintln(os.Stderr, "demo-runner: -rps, -duration and -max-inflight must be positive")
+		return 2
+	}
+	if (*url == "") == (fs.NArg() == 0) {
+		usage()
+		return 2
+	}
+
+	opts := loadOptions{
+		rps:         *rps,
+		duration:    *duration,
+		maxInFlight: *maxInFlight,
+		portEnv:     *portEnv,
+		ldflags:     !*noLdflags,
+		verbose:     *verbose,
+		extraEnv:    env,
+		target:      target{method: strings.ToUpper(*method), url: *path, body: *body, status: *status},
+	}
+	client := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConns: *maxInFlight, MaxIdleConnsPerHost: *maxInFlight},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *url != "" {
+		opts.target.url = strings.TrimRight(*url, "/") + *path
+		fmt.Fprintf(os.Stderr, "demo-runner: %s %s at %d rps for %s\n", opts.target.method, opts.target.url, opts.rps, opts.duration)
+		res := attack(ctx, client, opts.target, opts.rps, opts.duration, opts.maxInFlight)
+		return report(res, nil)
+	}
+
+	repoRoot, all, code := load(*root)
+	if all == nil {
+		return code
+	}
+	demo, err := demos.Find(all, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "demo-runner: %v\n", err)
+		return 2
+	}
+	exit, err := loadtest(ctx, repoRoot, demo, client, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "demo-runner: %v\n", err)
+	}
+	return exit
+}
+
+// loadtest builds and starts the demo with every port moved to a free one, waits until it
+// listens, sends the load, stops it and reports latency and the logs written under load
+func loadtest(ctx context.Context, root string, demo demos.Demo, client *http.Client, opts loadOptions) (int, error) {
+	if len(demo.Ports) == 0 {
+		return 2, fmt.Errorf("%s has no listen port", demo.Name)
+	}
+	ports := map[string]int{}
+	var env []string
+	for _, port := range demo.Ports {
+		free, err := demos.FreePort()
+		if err != nil {
+			return 1, fmt.Errorf("pick a port for %s: %w", port.Env, err)
+		}
+		ports[port.Env] = free
+		env = append(env, port.Env+"="+strconv.Itoa(free))
+	}
+	portEnv := opts.portEnv
+	if portEnv == "" {
+		portEnv = demo.Ports[0].Env
+	}
+	port, ok := ports[portEnv]
+	if !ok {
+		return 2, fmt.Errorf("%s does not read %s", demo.Name, portEnv)
+	}
+	if demo.UsesLogDir {
+		if err := os.MkdirAll(filepath.Join(demo.Dir, "logs"), 0o755); err != nil {
+			return 1, fmt.Errorf("create log directory: %w", err)
+		}
+	}
+
+	binDir, err := os.MkdirTemp("", "go-example-loadtest-")
+	if err != nil {
+		return 1, err
+	}
+	defer os.RemoveAll(binDir)
+	fmt.Fprintf(os.Stderr, "demo-runner: building %s\n", demo.Name)
+	bin, out, err := demos.Build(root, demo, binDir, opts.ldflags)
+	if err != nil {
+		_, _ = os.Stderr.Write(out)
+		return 1, fmt.Errorf("build %s: %w", demo.Name, err)
+	}
+
+	counter := &logCounter{}
+	if opts.verbose {
+		counter.out = os.Stderr
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = demo.Dir
+	cmd.Env = append(append(os.Environ(), env...), opts.extraEnv...)
+	cmd.Stdout, cmd.Stderr = counter, counter
+	if err := cmd.Start(); err != nil {
+		return 1, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	defer func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			_ = cmd.Process.Kill()
+			<-done
+		}
+	}()
+
+	ready := time.NewTicker(100 * time.Millisecond)
+	defer ready.Stop()
+	deadline := time.After(60 * time.Second)
+	for !demos.Listening(port) {
+		select {
+		case err := <-done:
+			done <- err
+			return 1, fmt.Errorf("%s exited before listening on %s=%d", demo.Name, portEnv, port)
+		case <-deadline:
+			return 1, fmt.Errorf("%s is not listening on %s=%d after 60s", demo.Name, portEnv, port)
+		case <-ctx.Done():
+			return 1, ctx.Err()
+		case <-ready.C:
+		}
+	}
+
+	opts.target.url = fmt.Sprintf("http://127.0.0.1:%d%s", port, opts.target.url)
+	fmt.Fprintf(os.Stderr, "demo-runner: %s %s at %d rps for %s (%s=%d)\n",
+		opts.target.method, opts.target.url, opts.rps, opts.duration, portEnv, port)
+
+	before := counter.snapshot()
+	res := attack(ctx, client, opts.target, opts.rps, opts.duration, opts.maxInFlight)
+	// Let the demo finish logging the last requests
+	time.Sleep(200 * time.Millisecond)
+	logs := counter.snapshot()
+	logs.lines -= before.lines
+	logs.entries -= before.entries
+	logs.bytes -= before.bytes
+	return report(res, &logs), nil
+}
+
+// report prints the results and returns the exit code: 1 when any request failed or was
+// dropped. logs is nil when the server is not a demo started here.
+func report(res loadResult, logs *logCount) int {
+	seconds := res.elapsed.Seconds()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Requests\t%d sent, %d ok, %d failed, %d dropped (%.1f rps achieved)\n",
+		res.sent, res.ok, res.failed, res.dropped, float64(res.sent)/seconds)
+	if len(res.latencies) > 0 {
+		fmt.Fprintf(w, "Latency\tmin %s  p50 %s  p95 %s  p99 %s  max %s\n",
+			round(res.latencies[0]), round(percentile(res.latencies, 50)), round(percentile(res.latencies, 95)),
+			round(percentile(res.latencies, 99)), round(res.latencies[len(res.latencies)-1]))
+	}
+	if len(res.statuses) > 0 {
+		codes := make([]int, 0, len(res.statuses))
+		for code := range res.statuses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		parts := make([]string, len(codes))
+		for i, code := range codes {
+			parts[i] = fmt.Sprintf("%d: %d", code, res.statuses[code])
+		}
+		fmt.Fprintf(w, "Status codes\t%s\n", strings.Join(parts, ", "))
+	}
+	for msg, n := range res.errors {
+		fmt.Fprintf(w, "Errors\t%d x %s\n", n, msg)
+	}
+	if logs != nil {
+		perRequest := 0.0
+		if res.sent > 0 {
+			perRequest = float64(logs.entries) / float64(res.sent)
+		}
+		fmt.Fprintf(w, "Log output\t%d entries (%.1f/s, %.2f per request), %d other lines, %.1f KB (%.1f KB/s)\n",
+			logs.entries, float64(logs.entries)/seconds, perRequest, logs.lines-logs.entries,
+			float64(logs.bytes)/1024, float64(logs.bytes)/1024/seconds)
+	}
+	_ = w.Flush()
+
+	if res.failed > 0 || res.dropped > 0 {
+		return 1
+	}
+	return 0
+}
+
+// round keeps latencies readable: microseconds below a millisecond, else 0.01ms
+func round(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
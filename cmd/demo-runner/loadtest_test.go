@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAttack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	res := attack(context.Background(), server.Client(), target{method: "POST", url: server.URL + "/users", body: `{"name":"a"}`},
+		100, 200*time.Millisecond, 10)
+	if res.sent != 20 || res.ok != 20 || res.failed != 0 || res.dropped != 0 || res.statuses[201] != 20 {
+		t.Errorf("any 2xx: %+v", res)
+	}
+	if len(res.latencies) != 20 || res.latencies[0] > res.latencies[19] {
+		t.Errorf("latencies not recorded or not sorted: %v", res.latencies)
+	}
+
+	res = attack(context.Background(), server.Client(), target{method: "GET", url: server.URL + "/missing", status: 200},
+		100, 100*time.Millisecond, 10)
+	if res.ok != 0 || res.failed != 10 || res.statuses[404] != 10 {
+		t.Errorf("unexpected status: %+v", res)
+	}
+}
+
+func TestAttackDropsWhenSaturated(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	done := make(chan loadResult)
+	go func() {
+		done <- attack(context.Background(), server.Client(), target{method: "GET", url: server.URL}, 200, 100*time.Millisecond, 2)
+	}()
+	// Both slots stay busy until the sender has finished, so everything after them drops
+	time.Sleep(150 * time.Millisecond)
+	release <- struct{}{}
+	release <- struct{}{}
+	res := <-done
+	if res.sent != 2 || res.dropped != 18 {
+		t.Errorf("sent = %d, dropped = %d; want 2 and 18", res.sent, res.dropped)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond, 0: time.Millisecond} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("p%v = %s, want %s", p, got, want)
+		}
+	}
+	if percentile(nil, 50) != 0 {
+		t.Error("percentile of no latencies")
+	}
+}
+
+func TestLogCounter(t *testing.T) {
+	c := &logCounter{}
+	_, _ = c.Write([]byte("=== Gin Web Server Demo ===\n{\"level\":\"info\",\"mess"))
+	_, _ = c.Write([]byte("age\":\"Request handled\"}\n{\"order_id\":42}\n"))
+	_, _ = c.Write([]byte(`{"level":"warn","message":"Slow request"}`))
+
+	got := c.snapshot()
+	if got.lines != 3 || got.entries != 1 || got.bytes != 130 {
+		t.Errorf("snapshot = %+v, want 3 lines, 1 entry, 130 bytes", got)
+	}
+	_, _ = c.Write([]byte("\n"))
+	if got := c.snapshot(); got.lines != 4 || got.entries != 2 {
+		t.Errorf("after newline = %+v", got)
+	}
+}
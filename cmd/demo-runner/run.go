@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+)
+
+// runOptions are the flags of "demo-runner run"
+type runOptions struct {
+	port     int
+	ldflags  bool
+	extraEnv []string
+}
+
+// prepare works out the environment for a demo: ports that are taken are moved to free
+// ones, and the logs directory is created when the demo writes into it. Variables already
+// set by the caller are left alone.
+func prepare(demo demos.Demo, opts runOptions, getenv func(string) string) ([]string, []string, error) {
+	var env, notes []string
+	for i, port := range demo.Ports {
+		if getenv(port.Env) != "" {
+			continue
+		}
+		switch {
+		case i == 0 && opts.port > 0:
+			env = append(env, port.Env+"="+strconv.Itoa(opts.port))
+		case !demos.PortFree(port.Default):
+			free, err := demos.FreePort()
+			if err != nil {
+				return nil, nil, fmt.Errorf("pick a port for %s: %w", port.Env, err)
+			}
+			env = append(env, port.Env+"="+strconv.Itoa(free))
+			notes = append(notes, fmt.Sprintf("port %d is in use, %s=%d", port.Default, port.Env, free))
+		}
+	}
+
+	if demo.UsesLogDir {
+		logDir := filepath.Join(demo.Dir, "logs")
+		if err := os.MkdirAll(logDir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("create log directory: %w", err)
+		}
+		notes = append(notes, "logs are written to "+logDir)
+	}
+	return append(env, opts.extraEnv...), notes, nil
+}
+
+// run starts "go run ." in the demo directory and waits for it. Interrupts are passed on
+// so the demo can shut down cleanly; the demo's exit code is returned.
+func run(root string, demo demos.Demo, opts runOptions, args []string) (int, error) {
+	env, notes, err := prepare(demo, opts, os.Getenv)
+	if err != nil {
+		return 1, err
+	}
+
+	goArgs := []string{"run"}
+	if opts.ldflags {
+		goArgs = append(goArgs, "-ldflags", demos.Ldflags(root, demo))
+	}
+	goArgs = append(goArgs, ".")
+	goArgs = append(goArgs, args...)
+
+	cmd := exec.Command("go", goArgs...)
+	cmd.Dir = demo.Dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	fmt.Fprintf(os.Stderr, "demo-runner: %s (%s)\n", demo.Name, relOrAbs(root, demo.Dir))
+	for _, note := range append(notes, env...) {
+		fmt.Fprintf(os.Stderr, "demo-runner:   %s\n", note)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return 1, err
+	}
+	go func() {
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func relOrAbs(root, dir string) string {
+	if rel, err := filepath.Rel(root, dir); err == nil {
+		return rel
+	}
+	return dir
+}
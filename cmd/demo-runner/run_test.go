@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+)
+
+func TestPrepareMovesTakenPort(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	dir := t.TempDir()
+	demo := demos.Demo{Name: "webhook", Dir: dir, UsesLogDir: true, Ports: []demos.PortEnv{{Env: "PORT", Default: taken}, {Env: "API_PORT", Default: taken}}}
+	getenv := func(key string) string {
+		if key == "API_PORT" {
+			return "9999"
+		}
+		return ""
+	}
+	env, _, err := prepare(demo, runOptions{extraEnv: []string{"LOG_LEVEL=debug"}}, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 2 || !strings.HasPrefix(env[0], "PORT=") || env[0] == "PORT="+strconv.Itoa(taken) || env[1] != "LOG_LEVEL=debug" {
+		t.Errorf("env = %v; want PORT moved off %d, API_PORT left to the caller", env, taken)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs")); err != nil {
+		t.Errorf("logs directory not created: %v", err)
+	}
+
+	env, _, _ = prepare(demo, runOptions{port: 9090}, func(string) string { return "" })
+	if env[0] != "PORT=9090" {
+		t.Errorf("with -port env = %v", env)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+// fatalLevels fail a case unless it sets allow_fatal
+var fatalLevels = map[string]bool{"fatal": true, "panic": true, "dpanic": true}
+
+// tally counts one kind of problem across lines
+type tally struct {
+	count     int
+	firstLine int
+	firstMsg  string
+}
+
+// checkLogs validates the JSON log lines in a demo's output against the log entry schema
+// and the case, and returns how many there were and what is wrong with them. Banners,
+// tables and pretty-printed payloads in between are ignored.
+func checkLogs(output []byte, c Case, schema *logschema.Validator) (int, []string) {
+	var problems []string
+	lines := 0
+	var order []string
+	tallies := map[string]*tally{}
+	count := func(problem string, n int, msg string) {
+		t := tallies[problem]
+		if t == nil {
+			t = &tally{firstLine: n, firstMsg: msg}
+			tallies[problem] = t
+			order = append(order, problem)
+		}
+		t.count++
+	}
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if !logschema.IsEntry(text) {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(text, &entry); err != nil {
+			continue
+		}
+		lines++
+		msg, _ := entry["message"].(string)
+		seen[msg] = true
+
+		for _, violation := range schema.ValidateLine(text) {
+			count(violation.String(), n, msg)
+		}
+		for _, field := range c.Fields {
+			if !present(entry[field]) {
+				count(field+" missing or empty", n, msg)
+			}
+		}
+		if level, _ := entry["level"].(string); fatalLevels[level] && !c.AllowFatal {
+			problems = append(problems, fmt.Sprintf("%s log on line %d: %s", level, n, describe(entry, msg)))
+		}
+	}
+
+	if lines == 0 {
+		return 0, append(problems, "no JSON log lines")
+	}
+	for _, problem := range order {
+		t := tallies[problem]
+		problems = append(problems, fmt.Sprintf("%s in %d of %d lines (first on line %d: %q)",
+			problem, t.count, lines, t.firstLine, t.firstMsg))
+	}
+	for _, msg := range c.Messages {
+		if !seen[msg] {
+			problems = append(problems, fmt.Sprintf("no log line with message %q", msg))
+		}
+	}
+	return lines, problems
+}
+
+// strayLines reports the lines of a demo's stdout that are not JSON log lines, which
+// --quiet is supposed to leave out
+func strayLines(stdout []byte) []string {
+	count, first, firstLine := 0, 0, ""
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || logschema.IsEntry(text) {
+			continue
+		}
+		if count == 0 {
+			first, firstLine = n, string(text)
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%d lines on stdout are not JSON log lines (first on line %d: %q)", count, first, firstLine)}
+}
+
+// present reports whether a decoded value counts as set
+func present(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	}
+	return true
+}
+
+// describe adds the error field to a message, which is what explains a fatal line
+func describe(entry map[string]interface{}, msg string) string {
+	if err, ok := entry["error"].(string); ok && err != "" {
+		return msg + ": " + err
+	}
+	return msg
+}
+
+// tail returns the last n lines of output
+func tail(output []byte, n int) []string {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
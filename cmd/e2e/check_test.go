@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+func TestCheckLogs(t *testing.T) {
+	output := strings.Join([]string{
+		"=== Gin Web Server Demo ===",
+		`{"level":"info","timestamp":"2024-05-01T10:00:00Z","service.name":"gin-demo","service.version":"v1","message":"Server starting"}`,
+		`{`,
+		`  "pretty": "printed payload"`,
+		`}`,
+		`{"level":"info","timestamp":"2024-05-01T10:00:01Z","service.name":"","service.version":"v1","message":"Request handled","route":""}`,
+		`{"order_id":"42","status":"paid"}`,
+		`{"level":"info","timestamp":"2024-05-01 10:00:01","service.name":"gin-demo","service.version":"v1","message":"Request handled","route":"/"}`,
+		`{"level":"fatal","timestamp":"2024-05-01T10:00:02Z","service.name":"gin-demo","service.version":"v1","message":"Listen failed","error":"address in use"}`,
+	}, "\n")
+
+	c := Case{Fields: []string{"route"}, Messages: []string{"Server starting", "Server stopped"}}
+	lines, problems := checkLogs([]byte(output), c, logschema.Default())
+	if lines != 4 {
+		t.Errorf("lines = %d, want 4", lines)
+	}
+	want := []string{
+		"fatal log on line 9: Listen failed: address in use",
+		`route missing or empty in 3 of 4 lines (first on line 2: "Server starting")`,
+		`service.name: minLength: got 0, want 1 in 1 of 4 lines (first on line 6: "Request handled")`,
+		`timestamp: '2024-05-01 10:00:01' is not valid date-time: less than 20 characters long in 1 of 4 lines (first on line 8: "Request handled")`,
+		`no log line with message "Server stopped"`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	c = Case{AllowFatal: true}
+	if _, problems := checkLogs([]byte(output), c, logschema.Default()); len(problems) != 2 {
+		t.Errorf("with allow_fatal problems = %v, want only the schema violations", problems)
+	}
+	if _, problems := checkLogs([]byte("plain text only\n"), c, logschema.Default()); len(problems) != 1 || problems[0] != "no JSON log lines" {
+		t.Errorf("plain output problems = %v", problems)
+	}
+}
+
+func TestStrayLines(t *testing.T) {
+	entry := `{"level":"info","timestamp":"2024-05-01T10:00:00Z","service.name":"gin-demo","message":"Server starting"}`
+	if problems := strayLines([]byte(entry + "\n\n" + entry + "\n")); problems != nil {
+		t.Errorf("pure NDJSON problems = %v", problems)
+	}
+	problems := strayLines([]byte(entry + "\n[GIN-debug] GET /health\n" + entry + "\n{\"order_id\":\"42\"}\n"))
+	want := `2 lines on stdout are not JSON log lines (first on line 2: "[GIN-debug] GET /health")`
+	if len(problems) != 1 || problems[0] != want {
+		t.Errorf("problems = %v", problems)
+	}
+}
+
+func TestExitProblem(t *testing.T) {
+	if problem := exitProblem(exec.Command("sh", "-c", "exit 3").Run(), true); problem != "exit status 3" {
+		t.Errorf("exit 3: %q", problem)
+	}
+	if problem := exitProblem(exec.Command("sh", "-c", "kill -INT $$").Run(), true); problem != "" {
+		t.Errorf("SIGINT after interrupt: %q", problem)
+	}
+	if problem := exitProblem(exec.Command("sh", "-c", "kill -INT $$").Run(), false); problem != "killed by interrupt" {
+		t.Errorf("SIGINT without interrupt: %q", problem)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "e2e.yaml")
+	content := `
+defaults:
+  run: 2s
+  env: {DURATION: 3s, LOG_LEVEL: info}
+demos:
+  gin:
+    env: {LOG_LEVEL: debug}
+    endpoints:
+      - {path: /health}
+      - {method: POST, path: /users, status: 201}
+  chat:
+    endpoints:
+      - {path: /rooms}
+  caching:
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin := cfg.caseFor("gin")
+	if got := strings.Join(environ(gin.Env), " "); got != "DURATION=3s LOG_LEVEL=debug" {
+		t.Errorf("gin env = %s", got)
+	}
+	if e := gin.Endpoints[0]; e.Method != "GET" || e.Status != 200 {
+		t.Errorf("gin endpoint defaults = %+v", e)
+	}
+	if gin.Run != 2*time.Second || gin.Timeout != time.Minute || len(gin.Fields) != 0 {
+		t.Errorf("gin = %+v", gin)
+	}
+	if chat := cfg.caseFor("chat"); len(chat.Endpoints) != 2 || chat.Endpoints[0].Path != healthPath || len(gin.Endpoints) != 2 {
+		t.Errorf("/health not requested first, and only once: chat %+v, gin %+v", chat.Endpoints, gin.Endpoints)
+	}
+	if len(cfg.caseFor("caching").Endpoints) != 0 {
+		t.Error("/health added to a demo that is not exercised over HTTP")
+	}
+	if cfg.caseFor("caching").Env["DURATION"] != "3s" || cfg.caseFor("unlisted").Run != 2*time.Second {
+		t.Error("defaults not applied to a bare or unlisted demo")
+	}
+}
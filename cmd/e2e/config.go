@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of e2e.yaml
+type Config struct {
+	Defaults Defaults         `yaml:"defaults"`
+	Demos    map[string]*Case `yaml:"demos"`
+}
+
+// Defaults apply to every demo, including ones e2e.yaml does not mention
+type Defaults struct {
+	// Env is set for every demo, e.g. a short DURATION for demos that run for a while
+	Env map[string]string `yaml:"env"`
+	// Fields must be present and non-empty in every JSON log line, on top of what the
+	// log entry schema requires
+	Fields []string `yaml:"fields"`
+	// Timeout bounds a whole case, from start to exit
+	Timeout time.Duration `yaml:"timeout"`
+	// Run is how long a demo that keeps running is left alone before it is interrupted
+	Run time.Duration `yaml:"run"`
+	// Grace is how long a demo has to exit after the interrupt
+	Grace time.Duration `yaml:"grace"`
+}
+
+// Case describes how one demo is exercised
+type Case struct {
+	Env map[string]string `yaml:"env"`
+	// Exits is set for demos that finish on their own; they must exit 0 within Timeout
+	Exits bool `yaml:"exits"`
+	// Endpoints are requested in order once the demo's first port accepts connections
+	Endpoints []Endpoint `yaml:"endpoints"`
+	// Requires names external services the demo cannot run without; such demos are
+	// skipped unless -all is given
+	Requires []string `yaml:"requires"`
+	// Skip skips the demo with the given reason
+	Skip string `yaml:"skip"`
+	// Fields replaces Defaults.Fields
+	Fields []string `yaml:"fields"`
+	// Messages must each appear as the message of at least one log line
+	Messages []string `yaml:"messages"`
+	// AllowFatal accepts fatal log lines, which otherwise fail the case
+	AllowFatal bool          `yaml:"allow_fatal"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Run        time.Duration `yaml:"run"`
+}
+
+// Endpoint is one HTTP request made against a running demo
+type Endpoint struct {
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	Body   string `yaml:"body"`
+	// Port names the port variable to use when the demo listens on several, e.g. ORDERS_PORT
+	Port   string `yaml:"port"`
+	Status int    `yaml:"status"`
+}
+
+func (e Endpoint) String() string {
+	return e.Method + " " + e.Path
+}
+
+// loadConfig reads path and fills in defaults
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if cfg.Defaults.Timeout == 0 {
+		cfg.Defaults.Timeout = 60 * time.Second
+	}
+	if cfg.Defaults.Run == 0 {
+		cfg.Defaults.Run = 5 * time.Second
+	}
+	if cfg.Defaults.Grace == 0 {
+		cfg.Defaults.Grace = 10 * time.Second
+	}
+
+	for name, c := range cfg.Demos {
+		if c == nil {
+			c = &Case{}
+			cfg.Demos[name] = c
+		}
+		for i := range c.Endpoints {
+			if c.Endpoints[i].Method == "" {
+				c.Endpoints[i].Method = "GET"
+			}
+			if c.Endpoints[i].Status == 0 {
+				c.Endpoints[i].Status = 200
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// caseFor returns the settings for a demo, with defaults merged in
+func (cfg *Config) caseFor(name string) Case {
+	c := Case{}
+	if configured := cfg.Demos[name]; configured != nil {
+		c = *configured
+	}
+
+	env := make(map[string]string, len(cfg.Defaults.Env)+len(c.Env))
+	for k, v := range cfg.Defaults.Env {
+		env[k] = v
+	}
+	for k, v := range c.Env {
+		env[k] = v
+	}
+	c.Env = env
+
+	if len(c.Fields) == 0 {
+		c.Fields = cfg.Defaults.Fields
+	}
+	if c.Timeout == 0 {
+		c.Timeout = cfg.Defaults.Timeout
+	}
+	if c.Run == 0 {
+		c.Run = cfg.Defaults.Run
+	}
+	c.Endpoints = withHealth(c.Endpoints)
+	return c
+}
+
+// healthPath is the endpoint every demo that serves HTTP has; its body is checked against
+// pkg/health's schema
+const healthPath = "/health"
+
+// withHealth puts GET /health first for a demo that is exercised over HTTP and does not
+// request it itself, so no server escapes the health contract
+func withHealth(endpoints []Endpoint) []Endpoint {
+	if len(endpoints) == 0 {
+		return endpoints
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Path == healthPath {
+			return endpoints
+		}
+	}
+	return append([]Endpoint{{Method: "GET", Path: healthPath, Status: 200}}, endpoints...)
+}
+
+// environ renders env as KEY=value pairs in a stable order
+func environ(env map[string]string) []string {
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+)
+
+var (
+	healthRoute   = regexp.MustCompile(`\.GET\("/health"`)
+	healthHandler = regexp.MustCompile(`health\.(?:Service)?Handler\(`)
+)
+
+// TestHealthContract checks the demos' sources: every router that serves /version also
+// serves /health, and every /health route answers through pkg/health, so the response
+// has the schema that e2e runs validate. A demo added without it fails here before
+// anyone runs it.
+func TestHealthContract(t *testing.T) {
+	root, err := demos.FindRoot(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := demos.Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := 0
+	for _, demo := range all {
+		files, err := filepath.Glob(filepath.Join(demo.Dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := string(data)
+			name, _ := filepath.Rel(root, file)
+
+			versions := strings.Count(src, `"/version", buildinfo.Handler()`)
+			routes := len(healthRoute.FindAllStringIndex(src, -1))
+			handlers := len(healthHandler.FindAllStringIndex(src, -1))
+			if versions > 0 {
+				servers++
+			}
+			if routes < versions {
+				t.Errorf("%s: %d routers serve /version but only %d serve /health", name, versions, routes)
+			}
+			if handlers < routes {
+				t.Errorf("%s: %d /health routes but only %d answer through pkg/health", name, routes, handlers)
+			}
+		}
+	}
+	if servers == 0 {
+		t.Error("no demo serves /version; is the source layout still what this test expects?")
+	}
+}
@@ -0,0 +1,218 @@
+// Command e2e smoke-tests the demos. Each one is built, started as a subprocess with its
+// ports moved to free ones, exercised and stopped, and every JSON log line it wrote is
+// validated against the log entry schema in pkg/logschema, which requires a non-empty
+// service.name, a known level and an RFC 3339 timestamp:
+//
+//	go run ./cmd/e2e                  # every demo that needs no external services
+//	go run ./cmd/e2e gin secretscan   # selected demos
+//	go run ./cmd/e2e -all             # include demos that need MinIO or a cluster
+//	go run ./cmd/e2e -quiet           # also require stdout to be pure NDJSON under --quiet
+//
+// How each demo is run, which endpoints are requested and which services it needs are
+// set in cmd/e2e/e2e.yaml; demos it does not mention are started with the defaults and
+// interrupted after a few seconds. The exit code is 1 when any demo fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	root := flag.String("root", "", "repository root (default: found from the current directory)")
+	configPath := flag.String("config", "", "case file (default: <root>/cmd/e2e/e2e.yaml)")
+	all := flag.Bool("all", false, "also run demos that require external services")
+	verbose := flag.Bool("v", false, "print every demo's output, not only failing ones")
+	noLdflags := flag.Bool("no-ldflags", false, "do not inject service name and version")
+	schemaPath := flag.String("schema", "", "log entry JSON Schema (default: the one in pkg/logschema)")
+	quiet := flag.Bool("quiet", false, "run demos with --quiet and fail any stdout line that is not a JSON log line")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: e2e [-root dir] [-config file] [-schema file] [-all] [-v] [-quiet] [-no-ldflags] [demo...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *root == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			*root, err = demos.FindRoot(wd)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+			return 2
+		}
+	}
+	if *configPath == "" {
+		*configPath = filepath.Join(*root, "cmd", "e2e", "e2e.yaml")
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 2
+	}
+
+	schema := logschema.Default()
+	if *schemaPath != "" {
+		data, err := os.ReadFile(*schemaPath)
+		if err == nil {
+			schema, err = logschema.New(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+			return 2
+		}
+	}
+
+	found, err := demos.Discover(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 2
+	}
+	if unknown := unknownCases(cfg, found); len(unknown) > 0 {
+		fmt.Fprintf(os.Stderr, "e2e: %s lists demos that do not exist: %s\n", *configPath, strings.Join(unknown, ", "))
+		return 2
+	}
+	selected, err := selectDemos(found, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 2
+	}
+
+	binDir, err := os.MkdirTemp("", "go-example-e2e-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(binDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	r := &runner{
+		root:    *root,
+		binDir:  binDir,
+		ldflags: !*noLdflags,
+		grace:   cfg.Defaults.Grace,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		schema:  schema,
+		quiet:   *quiet,
+	}
+	// Naming demos on the command line is asking for them, whatever they require
+	explicit := len(flag.Args()) > 0
+
+	var results []result
+	for _, demo := range selected {
+		c := cfg.caseFor(demo.Name)
+		var res result
+		switch {
+		case ctx.Err() != nil:
+			res = result{demo: demo.Name, status: statusSkip, problems: []string{"interrupted"}}
+		case c.Skip != "":
+			res = result{demo: demo.Name, status: statusSkip, problems: []string{c.Skip}}
+		case len(c.Requires) > 0 && !*all && !explicit:
+			res = result{demo: demo.Name, status: statusSkip, problems: []string{"requires " + strings.Join(c.Requires, ", ")}}
+		default:
+			fmt.Fprintf(os.Stderr, "e2e: %s ...\n", demo.Name)
+			res = r.run(ctx, demo, c)
+		}
+		if res.status == statusFail || (*verbose && res.output != nil) {
+			printOutput(res)
+		}
+		results = append(results, res)
+	}
+	return summary(results)
+}
+
+// unknownCases returns the names in e2e.yaml that match no demo, so a renamed demo does
+// not silently lose its case
+func unknownCases(cfg *Config, found []demos.Demo) []string {
+	names := make(map[string]bool, len(found))
+	for _, demo := range found {
+		names[demo.Name] = true
+	}
+	var unknown []string
+	for name := range cfg.Demos {
+		if !names[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// selectDemos resolves the names given on the command line; none means every demo
+func selectDemos(found []demos.Demo, names []string) ([]demos.Demo, error) {
+	if len(names) == 0 {
+		return found, nil
+	}
+	selected := make([]demos.Demo, 0, len(names))
+	for _, name := range names {
+		demo, err := demos.Find(found, name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, demo)
+	}
+	return selected, nil
+}
+
+func printOutput(res result) {
+	if len(res.output) == 0 {
+		return
+	}
+	lines := tail(res.output, 30)
+	fmt.Fprintf(os.Stderr, "--- %s output (last %d lines)\n", res.demo, len(lines))
+	for _, line := range lines {
+		fmt.Fprintf(os.Stderr, "    %s\n", line)
+	}
+}
+
+// summary prints one row per demo and returns the exit code
+func summary(results []result) int {
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DEMO\tSTATUS\tLINES\tTIME\tDETAIL")
+	for _, res := range results {
+		counts[res.status]++
+		lines, elapsed := "-", "-"
+		if res.status != statusSkip {
+			lines = strconv.Itoa(res.lines)
+			elapsed = res.elapsed.Round(100 * time.Millisecond).String()
+		}
+		detail := ""
+		if len(res.problems) > 0 {
+			detail = res.problems[0]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", res.demo, res.status, lines, elapsed, detail)
+		if res.status == statusFail {
+			for _, problem := range res.problems[1:] {
+				fmt.Fprintf(w, "\t\t\t\t%s\n", problem)
+			}
+		}
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%d passed, %d failed, %d skipped\n", counts[statusPass], counts[statusFail], counts[statusSkip])
+	if counts[statusFail] > 0 {
+		return 1
+	}
+	return 0
+}
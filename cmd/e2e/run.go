@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/cmd/internal/demos"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+// Result statuses
+const (
+	statusPass = "PASS"
+	statusFail = "FAIL"
+	statusSkip = "SKIP"
+)
+
+// result is the outcome of one case
+type result struct {
+	demo     string
+	status   string
+	lines    int
+	elapsed  time.Duration
+	problems []string
+	output   []byte
+}
+
+func (r *result) fail(format string, args ...interface{}) {
+	r.status = statusFail
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// runner builds and runs demos
+type runner struct {
+	root    string
+	binDir  string
+	ldflags bool
+	// grace is how long a demo has to exit after the interrupt
+	grace  time.Duration
+	client *http.Client
+	schema *logschema.Validator
+	// quiet runs demos with --quiet and fails any stdout line that is not a log line
+	quiet bool
+}
+
+// env returns the demo's environment and the port each port variable was given. Every
+// port moves to a free one so demos never collide with each other or a local service.
+func (r *runner) env(demo demos.Demo, c Case) ([]string, map[string]int, error) {
+	vars := map[string]string{}
+	ports := map[string]int{}
+	for _, port := range demo.Ports {
+		free, err := demos.FreePort()
+		if err != nil {
+			return nil, nil, fmt.Errorf("pick a port for %s: %w", port.Env, err)
+		}
+		vars[port.Env] = strconv.Itoa(free)
+	}
+	for k, v := range c.Env {
+		vars[k] = v
+	}
+	for _, port := range demo.Ports {
+		if n, err := strconv.Atoi(vars[port.Env]); err == nil {
+			ports[port.Env] = n
+		}
+	}
+	return append(os.Environ(), environ(vars)...), ports, nil
+}
+
+// run executes one case: build, start, exercise, stop, then check the logs
+func (r *runner) run(ctx context.Context, demo demos.Demo, c Case) (res result) {
+	res = result{demo: demo.Name, status: statusPass}
+	start := time.Now()
+	defer func() { res.elapsed = time.Since(start) }()
+
+	bin, out, err := demos.Build(r.root, demo, r.binDir, r.ldflags)
+	if err != nil {
+		res.output = out
+		res.fail("build failed: %v", err)
+		return res
+	}
+	env, ports, err := r.env(demo, c)
+	if err != nil {
+		res.fail("%v", err)
+		return res
+	}
+	if demo.UsesLogDir {
+		if err := os.MkdirAll(filepath.Join(demo.Dir, "logs"), 0o755); err != nil {
+			res.fail("create log directory: %v", err)
+			return res
+		}
+	}
+
+	var output, stderr bytes.Buffer
+	cmd := exec.Command(bin)
+	cmd.Dir = demo.Dir
+	cmd.Env = env
+	// One writer for both streams, so exec never writes to it from two goroutines at once
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if r.quiet {
+		// stdout on its own, so whatever the demo still prints there shows up
+		cmd.Args = append(cmd.Args, "--quiet")
+		cmd.Stderr = &stderr
+	}
+	if err := cmd.Start(); err != nil {
+		res.fail("start: %v", err)
+		return res
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	timeout := time.NewTimer(c.Timeout)
+	defer timeout.Stop()
+
+	var exitErr error
+	exited := false
+	switch {
+	case c.Exits:
+		select {
+		case exitErr = <-done:
+			exited = true
+		case <-timeout.C:
+			res.fail("did not exit within %s", c.Timeout)
+		case <-ctx.Done():
+			res.fail("interrupted")
+		}
+
+	case len(c.Endpoints) > 0:
+		exited, exitErr = r.exercise(ctx, &res, demo, c, ports, done, timeout.C)
+
+	default:
+		select {
+		case exitErr = <-done:
+			exited = true
+		case <-time.After(c.Run):
+		case <-timeout.C:
+			res.fail("still running after %s", c.Timeout)
+		case <-ctx.Done():
+			res.fail("interrupted")
+		}
+	}
+
+	interrupted := false
+	if !exited {
+		interrupted = true
+		_ = cmd.Process.Signal(os.Interrupt)
+		select {
+		case exitErr = <-done:
+		case <-time.After(r.grace):
+			_ = cmd.Process.Kill()
+			exitErr = <-done
+			res.fail("did not stop within %s of the interrupt", r.grace)
+		}
+	}
+	if problem := exitProblem(exitErr, interrupted); problem != "" {
+		res.fail("%s", problem)
+	}
+
+	res.output = output.Bytes()
+	if r.quiet {
+		for _, problem := range strayLines(res.output) {
+			res.fail("%s", problem)
+		}
+		res.output = append(res.output, stderr.Bytes()...)
+	}
+	lines, problems := checkLogs(res.output, c, r.schema)
+	res.lines = lines
+	for _, problem := range problems {
+		res.fail("%s", problem)
+	}
+	return res
+}
+
+// exercise waits for the demo to listen and requests each endpoint. It reports whether
+// the demo exited on its own, which fails the case: a server is expected to keep running.
+func (r *runner) exercise(ctx context.Context, res *result, demo demos.Demo, c Case, ports map[string]int,
+	done <-chan error, timeout <-chan time.Time) (bool, error) {
+	if len(demo.Ports) == 0 {
+		res.fail("endpoints configured but no port variable found in the source")
+		return false, nil
+	}
+	mainPort := demo.Ports[0].Env
+
+	ready := time.NewTicker(100 * time.Millisecond)
+	defer ready.Stop()
+	for !demos.Listening(ports[mainPort]) {
+		select {
+		case err := <-done:
+			res.fail("exited before listening on %s=%d", mainPort, ports[mainPort])
+			return true, err
+		case <-timeout:
+			res.fail("not listening on %s=%d after %s", mainPort, ports[mainPort], c.Timeout)
+			return false, nil
+		case <-ctx.Done():
+			res.fail("interrupted")
+			return false, nil
+		case <-ready.C:
+		}
+	}
+
+	for _, endpoint := range c.Endpoints {
+		env := endpoint.Port
+		if env == "" {
+			env = mainPort
+		}
+		port, ok := ports[env]
+		if !ok {
+			res.fail("%s: unknown port variable %s", endpoint, env)
+			continue
+		}
+		status, body, err := r.request(ctx, port, endpoint)
+		switch {
+		case err != nil:
+			res.fail("%s: %v", endpoint, err)
+		case status != endpoint.Status:
+			res.fail("%s: status %d, want %d", endpoint, status, endpoint.Status)
+		case endpoint.Path == healthPath:
+			for _, problem := range health.Validate(body) {
+				res.fail("%s on %s: %s", endpoint, env, problem)
+			}
+		}
+	}
+
+	// Let the demo finish logging the last requests before it is stopped
+	select {
+	case err := <-done:
+		res.fail("exited while being exercised")
+		return true, err
+	case <-time.After(200 * time.Millisecond):
+	}
+	return false, nil
+}
+
+func (r *runner) request(ctx context.Context, port int, endpoint Endpoint) (int, []byte, error) {
+	var body io.Reader
+	if endpoint.Body != "" {
+		body = strings.NewReader(endpoint.Body)
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", port, endpoint.Path)
+	req, err := http.NewRequestWithContext(ctx, endpoint.Method, url, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+// exitProblem describes an unexpected exit. After the interrupt, dying from the signal
+// counts as a clean stop for demos that do not handle it.
+func exitProblem(err error, interrupted bool) string {
+	if err == nil {
+		return ""
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err.Error()
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		if interrupted && status.Signal() == syscall.SIGINT {
+			return ""
+		}
+		return "killed by " + status.Signal().String()
+	}
+	return fmt.Sprintf("exit status %d", exitErr.ExitCode())
+}
@@ -0,0 +1,273 @@
+// Package demos finds the runnable examples in this repository. It is shared by
+// cmd/demo-runner and cmd/e2e.
+package demos
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Suffix marks the directories that hold a runnable demo
+const Suffix = "-demo"
+
+// ModulePath identifies the repository root by its go.mod
+const ModulePath = "github.com/kart-io/go-example"
+
+// versionPkg receives build information through -ldflags, as in the Makefile
+const versionPkg = "github.com/kart-io/version"
+
+// PortEnv is an environment variable a demo reads its listen port from
+type PortEnv struct {
+	Env     string
+	Default int
+}
+
+// Demo is one runnable example found under the repository root
+type Demo struct {
+	// Name is the directory name without the -demo suffix, e.g. file-logging
+	Name  string
+	Dir   string
+	Title string
+	Ports []PortEnv
+	// UsesLogDir is set when the demo writes into a relative logs/ directory
+	UsesLogDir bool
+	// OwnModule is set for demos with their own go.mod, such as viper-config-demo
+	OwnModule bool
+}
+
+var (
+	// getEnvOrDefault("PORT", "8090") and friends
+	portEnvPattern = regexp.MustCompile(`getEnvOrDefault\("([A-Z_]*PORT)", "(\d+)"\)`)
+	// port := ":8082" followed by an os.Getenv("PORT") override, as in gin-demo
+	portLiteralPattern = regexp.MustCompile(`(?s)":(\d{2,5})".{0,80}?os\.Getenv\("([A-Z_]*PORT)"\)`)
+	logDirPattern      = regexp.MustCompile(`"logs["/]`)
+	bannerPattern      = regexp.MustCompile(`(?:console|fmt)\.Println\("=== (.+?) ===`)
+)
+
+// FindRoot walks up from dir to the directory whose go.mod declares ModulePath
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if module, err := readModule(filepath.Join(dir, "go.mod")); err == nil && module == ModulePath {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no go.mod for %s above the current directory; pass -root", ModulePath)
+		}
+		dir = parent
+	}
+}
+
+// readModule returns the module path declared in a go.mod file
+func readModule(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("no module directive")
+}
+
+// Discover lists the demos under root, sorted by name
+func Discover(root string) ([]Demo, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var demos []Demo
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), Suffix) {
+			continue
+		}
+		demo, ok, err := inspect(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			demos = append(demos, demo)
+		}
+	}
+	sort.Slice(demos, func(i, j int) bool { return demos[i].Name < demos[j].Name })
+	return demos, nil
+}
+
+// inspect reads a demo directory; ok is false when it holds no Go sources
+func inspect(dir string) (Demo, bool, error) {
+	base := filepath.Base(dir)
+	demo := Demo{Name: strings.TrimSuffix(base, Suffix), Dir: dir}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return Demo{}, false, err
+	}
+	seen := map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return Demo{}, false, err
+		}
+		for _, m := range portEnvPattern.FindAllSubmatch(src, -1) {
+			demo.addPort(seen, string(m[1]), string(m[2]))
+		}
+		for _, m := range portLiteralPattern.FindAllSubmatch(src, -1) {
+			demo.addPort(seen, string(m[2]), string(m[1]))
+		}
+		if logDirPattern.Match(src) {
+			demo.UsesLogDir = true
+		}
+		if demo.Title == "" {
+			if m := bannerPattern.FindSubmatch(src); m != nil {
+				demo.Title = string(m[1])
+			}
+		}
+	}
+	if len(files) == 0 {
+		return Demo{}, false, nil
+	}
+
+	if title := readmeTitle(filepath.Join(dir, "README.md")); title != "" {
+		demo.Title = title
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		demo.OwnModule = true
+	}
+	return demo, true, nil
+}
+
+func (d *Demo) addPort(seen map[string]bool, env, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || seen[env] {
+		return
+	}
+	seen[env] = true
+	d.Ports = append(d.Ports, PortEnv{Env: env, Default: port})
+}
+
+// readmeTitle returns the first "# " heading of a README, or "" without one
+func readmeTitle(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if title, ok := strings.CutPrefix(scanner.Text(), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+// Find resolves a name given on the command line. The -demo suffix is optional and a
+// unique prefix is enough: "file" finds file-logging.
+func Find(demos []Demo, name string) (Demo, error) {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "/"), Suffix)
+	var matches []Demo
+	for _, demo := range demos {
+		if demo.Name == name {
+			return demo, nil
+		}
+		if strings.HasPrefix(demo.Name, name) {
+			matches = append(matches, demo)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return Demo{}, fmt.Errorf("no demo named %q; run \"make list-demos\"", name)
+	}
+	names := make([]string, len(matches))
+	for i, demo := range matches {
+		names[i] = demo.Name
+	}
+	return Demo{}, fmt.Errorf("%q matches %s", name, strings.Join(names, ", "))
+}
+
+// PortFree reports whether port can be listened on
+func PortFree(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// FreePort asks the kernel for an unused port
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Ldflags injects the demo's directory name as the service name and the checkout's
+// version, so service.name and service.version are filled in the demo's logs
+func Ldflags(root string, demo Demo) string {
+	flags := []string{fmt.Sprintf("-X '%s.serviceName=%s%s'", versionPkg, demo.Name, Suffix)}
+	if out, err := exec.Command("git", "-C", root, "describe", "--tags", "--always", "--dirty").Output(); err == nil {
+		flags = append(flags, fmt.Sprintf("-X '%s.gitVersion=%s'", versionPkg, strings.TrimSpace(string(out))))
+	}
+	if out, err := exec.Command("git", "-C", root, "rev-parse", "HEAD").Output(); err == nil {
+		flags = append(flags, fmt.Sprintf("-X '%s.gitCommit=%s'", versionPkg, strings.TrimSpace(string(out))))
+	}
+	return strings.Join(flags, " ")
+}
+
+// Build compiles the demo into binDir and returns the binary's path and the compiler
+// output. A binary rather than go run, so an interrupt reaches the demo itself instead of
+// the go command.
+func Build(root string, demo Demo, binDir string, ldflags bool) (string, []byte, error) {
+	bin := filepath.Join(binDir, demo.Name+Suffix)
+	args := []string{"build", "-o", bin}
+	if ldflags {
+		args = append(args, "-ldflags", Ldflags(root, demo))
+	}
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = demo.Dir
+	out, err := cmd.CombinedOutput()
+	return bin, out, err
+}
+
+// Listening reports whether something accepts connections on the loopback port
+func Listening(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -0,0 +1,88 @@
+package demos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRepo(t *testing.T) string {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module "+ModulePath+"\n\ngo 1.25\n")
+	writeFile(t, filepath.Join(root, "webhook-demo", "README.md"), "# Webhook Receiver Demo\n\nText\n")
+	writeFile(t, filepath.Join(root, "webhook-demo", "main.go"), `package demos
+func main() {
+	port := getEnvOrDefault("PORT", "8090")
+	api := getEnvOrDefault("API_PORT", "8102")
+}`)
+	writeFile(t, filepath.Join(root, "gin-demo", "main.go"), `package demos
+func main() {
+	console.Println("=== Gin Web Server Demo ===")
+	port := ":8082" // Default port
+	if envPort := os.Getenv("PORT"); envPort != "" {
+	}
+	logFile := filepath.Join("logs", "access.log")
+}`)
+	writeFile(t, filepath.Join(root, "viper-config-demo", "go.mod"), "module "+ModulePath+"/viper-config-demo\n")
+	writeFile(t, filepath.Join(root, "viper-config-demo", "main.go"), "package demos\n")
+	writeFile(t, filepath.Join(root, "empty-demo", "README.md"), "# Nothing to run\n")
+	writeFile(t, filepath.Join(root, "pkg", "clock", "clock.go"), "package clock\n")
+	return root
+}
+
+func TestDiscover(t *testing.T) {
+	root := testRepo(t)
+	found, err := FindRoot(filepath.Join(root, "viper-config-demo"))
+	if err != nil || found != root {
+		t.Fatalf("FindRoot() = %q, %v; want %q", found, err, root)
+	}
+
+	demos, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, demo := range demos {
+		names = append(names, demo.Name)
+	}
+	if got := strings.Join(names, ","); got != "gin,viper-config,webhook" {
+		t.Fatalf("discovered %s", got)
+	}
+
+	gin, webhook, viper := demos[0], demos[2], demos[1]
+	if gin.Title != "Gin Web Server Demo" || !gin.UsesLogDir || len(gin.Ports) != 1 || gin.Ports[0] != (PortEnv{"PORT", 8082}) {
+		t.Errorf("gin = %+v", gin)
+	}
+	if webhook.Title != "Webhook Receiver Demo" || len(webhook.Ports) != 2 || webhook.Ports[1] != (PortEnv{"API_PORT", 8102}) {
+		t.Errorf("webhook = %+v", webhook)
+	}
+	if !viper.OwnModule || viper.UsesLogDir {
+		t.Errorf("viper-config = %+v", viper)
+	}
+}
+
+func TestFind(t *testing.T) {
+	demos := []Demo{{Name: "file-logging"}, {Name: "fluent-forward"}, {Name: "gin"}}
+	for input, want := range map[string]string{"gin-demo": "gin", "file": "file-logging", "gin-demo/": "gin"} {
+		if demo, err := Find(demos, input); err != nil || demo.Name != want {
+			t.Errorf("find(%q) = %q, %v; want %q", input, demo.Name, err, want)
+		}
+	}
+	if _, err := Find(demos, "f"); err == nil || !strings.Contains(err.Error(), "file-logging, fluent-forward") {
+		t.Errorf("find(f) error = %v, want the ambiguous matches", err)
+	}
+	if _, err := Find(demos, "kafka"); err == nil {
+		t.Error("find(kafka) found a demo")
+	}
+}
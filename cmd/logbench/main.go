@@ -0,0 +1,153 @@
+// Command logbench compares what a log entry costs under each logger configuration: zap
+// and slog, json and console, with and without InitialFields and OTLP export. It measures
+// every combination in pkg/logbench and prints a table sorted by throughput:
+//
+//	go run ./cmd/logbench
+//	go run ./cmd/logbench -engine slog -format json -n 500000
+//	go run ./cmd/logbench -otlp otel-collector:4318 -output /tmp/bench.log
+//
+// OTLP configurations export to an in-process sink unless -otlp names a collector.
+// For allocation profiles use the benchmarks instead:
+//
+//	go test -run '^$' -bench . -benchmem ./pkg/logbench
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kart-io/go-example/pkg/logbench"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	n := flag.Int("n", 200000, "entries per configuration and run")
+	count := flag.Int("count", 3, "runs per configuration; the median is reported")
+	engines := flag.String("engine", "", "comma-separated engines to include (default: all)")
+	formats := flag.String("format", "", "comma-separated formats to include (default: all)")
+	endpoint := flag.String("otlp", "", "OTLP/HTTP endpoint for the +otlp configurations (default: an in-process sink)")
+	noOTLP := flag.Bool("no-otlp", false, "skip the +otlp configurations")
+	output := flag.String("output", "", "file the entries are written to (default: "+os.DevNull+")")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logbench [-n entries] [-count runs] [-engine list] [-format list] [-otlp host:port] [-no-otlp] [-output file]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *n <= 0 || *count <= 0 {
+		fmt.Fprintln(os.Stderr, "logbench: -n and -count must be positive")
+		return 2
+	}
+
+	configs := selectConfigs(logbench.Matrix(), split(*engines), split(*formats), !*noOTLP)
+	if len(configs) == 0 {
+		fmt.Fprintln(os.Stderr, "logbench: no configuration matches the filters")
+		return 2
+	}
+
+	if *endpoint == "" && !*noOTLP {
+		sink, err := logbench.StartSink()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logbench: start OTLP sink: %v\n", err)
+			return 1
+		}
+		defer sink.Close()
+		*endpoint = sink.Addr()
+	}
+
+	results := make([]logbench.Result, 0, len(configs))
+	for _, c := range configs {
+		fmt.Fprintf(os.Stderr, "logbench: %s ...\n", c.Name())
+		runs := make([]logbench.Result, 0, *count)
+		for i := 0; i < *count; i++ {
+			result, err := logbench.Measure(c, *n, *output, *endpoint)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "logbench: %v\n", err)
+				return 1
+			}
+			runs = append(runs, result)
+		}
+		results = append(results, median(runs))
+	}
+
+	printTable(results)
+	fmt.Printf("\n%d entries x %d runs per configuration, %s/%s, %d CPUs, %s\n",
+		*n, *count, runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), runtime.Version())
+	return 0
+}
+
+// selectConfigs keeps the configurations whose engine and format are listed; an empty
+// list keeps all of them
+func selectConfigs(configs []logbench.Config, engines, formats []string, otlp bool) []logbench.Config {
+	var selected []logbench.Config
+	for _, c := range configs {
+		if !contains(engines, c.Engine) || !contains(formats, c.Format) || (c.OTLP && !otlp) {
+			continue
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func split(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// median returns the run with the median throughput, which is steadier than the mean
+// when one run is hit by GC or a noisy neighbour
+func median(runs []logbench.Result) logbench.Result {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].PerSecond() < runs[j].PerSecond() })
+	return runs[len(runs)/2]
+}
+
+// printTable prints one row per configuration, fastest first, with each row's time per
+// entry relative to the fastest
+func printTable(results []logbench.Result) {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].PerSecond() > results[j].PerSecond() })
+	fastest := results[0].NsPerEntry()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONFIG\tENGINE\tFORMAT\tFIELDS\tOTLP\tENTRIES/S\tNS/ENTRY\tALLOCS/ENTRY\tB/ENTRY\tVS FASTEST")
+	for _, r := range results {
+		relative := "-"
+		if fastest > 0 {
+			relative = fmt.Sprintf("%.2fx", r.NsPerEntry()/fastest)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.0f\t%.0f\t%.1f\t%.0f\t%s\n",
+			r.Config.Name(), r.Config.Engine, r.Config.Format, yesNo(r.Config.InitialFields), yesNo(r.Config.OTLP),
+			r.PerSecond(), r.NsPerEntry(), r.AllocsPerEntry(), r.BytesPerEntry(), relative)
+	}
+	_ = w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Modes
+const (
+	// ModeRemove drops every line that mentions a subject
+	ModeRemove = "remove"
+	// ModePseudonymize replaces each mention with a keyed pseudonym and keeps the line
+	ModePseudonymize = "pseudonymize"
+)
+
+// identifier characters: a user ID only matches where it is not part of a longer token,
+// so erasing u-10 leaves u-100 alone
+const identChars = `A-Za-z0-9_.\-`
+
+// UserIDKeys are the field names, matched in any case, a numeric user ID is erased under.
+// A bare number is too common (status codes, counts, ports) to erase wherever it appears.
+var UserIDKeys = []string{"user", "user_id", "userid", "user.id", "usr.id", "enduser.id", "uid", "customer_id", "account_id"}
+
+// Eraser finds the data subjects' user IDs and email addresses in log lines
+type Eraser struct {
+	mode string
+	key  []byte
+	// match finds any non-numeric subject inside text, nil when there is none
+	match *regexp.Regexp
+	// keyed finds a numeric user ID given as the value of one of UserIDKeys, as in
+	// "user_id":1001, "uid":"1001" or user_id=1001; nil when there is none
+	keyed *regexp.Regexp
+	// numbers are the numeric user IDs
+	numbers map[string]bool
+}
+
+// NewEraser builds an Eraser for the subjects; emails match case-insensitively. key signs
+// the pseudonyms, so the same subject gets the same one in every file erased with it.
+func NewEraser(mode string, userIDs, emails []string, key []byte) (*Eraser, error) {
+	if mode != ModeRemove && mode != ModePseudonymize {
+		return nil, fmt.Errorf("mode must be %s or %s, not %q", ModeRemove, ModePseudonymize, mode)
+	}
+	var alternatives, numeric []string
+	numbers := map[string]bool{}
+	for _, id := range userIDs {
+		if id != "" && strings.Trim(id, "0123456789") == "" {
+			numeric = append(numeric, id)
+			numbers[id] = true
+			continue
+		}
+		alternatives = append(alternatives, regexp.QuoteMeta(id))
+	}
+	for _, email := range emails {
+		alternatives = append(alternatives, "(?i:"+regexp.QuoteMeta(email)+")")
+	}
+	if len(alternatives) == 0 && len(numeric) == 0 {
+		return nil, fmt.Errorf("no subjects: give -user-id, -email or both")
+	}
+	e := &Eraser{mode: mode, key: key, numbers: numbers}
+	// Go's regexp has no lookaround, so the boundaries are matched as groups 1 and 3. A
+	// trailing dot ends a sentence, not the identifier, unless more of it follows.
+	end := `($|[^` + identChars + `]|\.(?:$|[^` + identChars + `]))`
+	var err error
+	if len(alternatives) > 0 {
+		e.match, err = regexp.Compile(`(^|[^` + identChars + `])(` + strings.Join(alternatives, "|") + `)` + end)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(numeric) > 0 {
+		keys := make([]string, len(UserIDKeys))
+		for i, k := range UserIDKeys {
+			keys[i] = regexp.QuoteMeta(k)
+		}
+		// The ID is group 2 here as well, with the key and separator in group 1
+		e.keyed, err = regexp.Compile(`((?:^|[^` + identChars + `])(?i:` + strings.Join(keys, "|") + `)"?\s*[:=]\s*"?)(` +
+			strings.Join(numeric, "|") + `)` + end)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// isUserIDKey reports whether key is one of UserIDKeys
+func isUserIDKey(key string) bool {
+	for _, k := range UserIDKeys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pseudonym is the replacement for subject: anon_ and 16 hex digits of its HMAC, emails
+// lowercased first so every spelling of an address gets the same one
+func (e *Eraser) Pseudonym(subject string) string {
+	if strings.Contains(subject, "@") {
+		subject = strings.ToLower(subject)
+	}
+	mac := hmac.New(sha256.New, e.key)
+	mac.Write([]byte(subject))
+	return "anon_" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Matches reports whether line mentions a subject
+func (e *Eraser) Matches(line string) bool {
+	return (e.match != nil && e.match.MatchString(line)) || (e.keyed != nil && e.keyed.MatchString(line))
+}
+
+// replace pseudonymizes every mention in s and returns how many there were
+func (e *Eraser) replace(s string) (string, int) {
+	n := 0
+	for _, re := range []*regexp.Regexp{e.match, e.keyed} {
+		if re == nil {
+			continue
+		}
+		// A match consumes its boundary characters, so adjacent mentions need another pass
+		for {
+			loc := re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				break
+			}
+			s = s[:loc[4]] + e.Pseudonym(s[loc[4]:loc[5]]) + s[loc[5]:]
+			n++
+		}
+	}
+	return s, n
+}
+
+// Line erases the subjects from one line. It returns the line to write, false when the
+// line is dropped, and the number of mentions pseudonymized.
+func (e *Eraser) Line(line string) (string, bool, int) {
+	if !e.Matches(line) {
+		return line, true, 0
+	}
+	if e.mode == ModeRemove {
+		return "", false, 0
+	}
+	if strings.HasPrefix(line, "{") && json.Valid([]byte(line)) {
+		out, n := e.jsonLine(line)
+		if json.Valid([]byte(out)) {
+			return out, true, n
+		}
+		// Cannot happen with subjects that need no JSON escaping; drop rather than keep it
+		return "", false, 0
+	}
+	out, n := e.replace(line)
+	return out, true, n
+}
+
+// jsonLine pseudonymizes the mentions in the strings of a JSON entry, and numeric user IDs
+// that are the value of one of UserIDKeys, leaving key order and formatting as they were
+func (e *Eraser) jsonLine(line string) (string, int) {
+	var b strings.Builder
+	total := 0
+	// key is the key of the value being read, empty outside an object member's value
+	key := ""
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			raw := line[i+1 : end]
+			i = end + 1
+			out, n := e.replace(raw)
+			if strings.HasPrefix(strings.TrimLeft(line[i:], " \t"), ":") {
+				key = raw
+			} else {
+				if isUserIDKey(key) && e.numbers[raw] {
+					out, n = e.Pseudonym(raw), 1
+				}
+				key = ""
+			}
+			b.WriteByte('"')
+			b.WriteString(out)
+			b.WriteByte('"')
+			total += n
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(line) && strings.IndexByte("0123456789.eE+-", line[end]) >= 0 {
+				end++
+			}
+			if number := line[i:end]; isUserIDKey(key) && e.numbers[number] {
+				b.WriteString(`"` + e.Pseudonym(number) + `"`)
+				total++
+			} else {
+				b.WriteString(number)
+			}
+			key = ""
+			i = end
+		default:
+			if c == '{' || c == '[' {
+				key = ""
+			}
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), total
+}
+
+// FileReport is what erasing one file did
+type FileReport struct {
+	Path  string `json:"path"`
+	Lines int    `json:"lines"`
+	// Matched lines mentioned a subject; they were Removed or had Pseudonymized mentions
+	// replaced
+	Matched       int    `json:"matched"`
+	Removed       int    `json:"removed"`
+	Pseudonymized int    `json:"pseudonymized"`
+	Rewritten     bool   `json:"rewritten"`
+	Error         string `json:"error,omitempty"`
+}
+
+// File erases the subjects from the file at path, read and written gzip-compressed when
+// it ends in .gz. The result goes to a temporary file next to it that replaces the
+// original, with its mode, only when something matched and dryRun is false.
+func (e *Eraser) File(path string, dryRun bool) (FileReport, error) {
+	report := FileReport{Path: path}
+	in, err := os.Open(path)
+	if err != nil {
+		return report, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return report, err
+	}
+	var r io.Reader = in
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return report, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".logerase-*")
+	if err != nil {
+		return report, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	var w io.Writer = tmp
+	var gzw *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gzw = gzip.NewWriter(tmp)
+		w = gzw
+	}
+	bw := bufio.NewWriter(w)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		report.Lines++
+		out, keep, n := e.Line(line)
+		if !keep || n > 0 {
+			report.Matched++
+		}
+		if !keep {
+			report.Removed++
+			continue
+		}
+		report.Pseudonymized += n
+		bw.WriteString(out)
+		bw.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if report.Matched == 0 || dryRun {
+		return report, nil
+	}
+
+	if err := bw.Flush(); err != nil {
+		return report, err
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			return report, err
+		}
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return report, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return report, err
+	}
+	if err := tmp.Close(); err != nil {
+		return report, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return report, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	report.Rewritten = true
+	return report, nil
+}
@@ -0,0 +1,141 @@
+// Command logerase erases a data subject from historical log files, for deletion requests
+// under the GDPR and similar laws. Every line that mentions one of the subject's user IDs
+// or email addresses, in a JSON field, a message or a plain-text line, is removed or, with
+// -mode pseudonymize, kept with each mention replaced by a keyed pseudonym:
+//
+//	go run ./cmd/logerase -user-id u-1001 -email alice@example.com -request DSR-2291 logs/*.log
+//	LOGERASE_KEY=... go run ./cmd/logerase -mode pseudonymize -user-id u-1001 logs/app.log logs/app-*.log.gz
+//	go run ./cmd/logerase -dry-run -email alice@example.com logs/*.log
+//
+// User IDs match where they are not part of a longer identifier, emails in any case. A
+// numeric user ID only matches as the value of a user ID field (user_id, uid, ... in
+// UserIDKeys), so erasing user 200 leaves "status":200 alone.
+// Files are rewritten in place through a temporary file, .gz files compressed again, and
+// only when something matched. Stop the service, or limit the run to rotated files, first:
+// a process still appending to a file keeps writing to the replaced one.
+//
+// The erasure report, written to stdout or -report, counts what was done per file and
+// names the request, never the subject. Pseudonyms are anon_ and an HMAC of the value
+// under LOGERASE_KEY (or -key); without a key they are random per run and cannot be
+// linked to pseudonyms from another run. The exit code is 1 when a file could not be
+// erased.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Report is the erasure report
+type Report struct {
+	Request    string       `json:"request,omitempty"`
+	Mode       string       `json:"mode"`
+	DryRun     bool         `json:"dry_run"`
+	UserIDs    int          `json:"user_ids"`
+	Emails     int          `json:"emails"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Files      []FileReport `json:"files"`
+	Totals     Totals       `json:"totals"`
+}
+
+// Totals sums the file reports
+type Totals struct {
+	Lines         int `json:"lines"`
+	Matched       int `json:"matched"`
+	Removed       int `json:"removed"`
+	Pseudonymized int `json:"pseudonymized"`
+	Rewritten     int `json:"files_rewritten"`
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	userIDs := flag.String("user-id", "", "user IDs to erase, comma-separated")
+	emails := flag.String("email", "", "email addresses to erase, comma-separated")
+	mode := flag.String("mode", ModeRemove, "remove the lines, or pseudonymize the mentions")
+	key := flag.String("key", os.Getenv("LOGERASE_KEY"), "key for the pseudonyms (default $LOGERASE_KEY, random when empty)")
+	request := flag.String("request", "", "deletion request reference to put in the report")
+	reportPath := flag.String("report", "", "write the report to this file instead of stdout")
+	dryRun := flag.Bool("dry-run", false, "report what would be erased without changing any file")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logerase [-user-id ids] [-email addrs] [-mode remove|pseudonymize] [-key k] [-request ref] [-report file] [-dry-run] file...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	secret := []byte(*key)
+	if len(secret) == 0 && *mode == ModePseudonymize {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+		fmt.Fprintln(os.Stderr, "logerase: no key given, pseudonyms are random to this run")
+	}
+	ids, addrs := split(*userIDs), split(*emails)
+	eraser, err := NewEraser(*mode, ids, addrs, secret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logerase: %v\n", err)
+		return 2
+	}
+
+	report := Report{Request: *request, Mode: *mode, DryRun: *dryRun, UserIDs: len(ids), Emails: len(addrs), StartedAt: time.Now().UTC()}
+	status := 0
+	for _, path := range flag.Args() {
+		file, err := eraser.File(path, *dryRun)
+		if err != nil {
+			file.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "logerase: %v\n", err)
+			status = 1
+		}
+		report.Files = append(report.Files, file)
+		report.Totals.Lines += file.Lines
+		report.Totals.Matched += file.Matched
+		report.Totals.Removed += file.Removed
+		report.Totals.Pseudonymized += file.Pseudonymized
+		if file.Rewritten {
+			report.Totals.Rewritten++
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+
+	out := os.Stdout
+	if *reportPath != "" {
+		f, err := os.OpenFile(*reportPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logerase: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "logerase: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "logerase: %d of %d lines matched in %d files (%d removed, %d mentions pseudonymized)\n",
+		report.Totals.Matched, report.Totals.Lines, len(report.Files), report.Totals.Removed, report.Totals.Pseudonymized)
+	return status
+}
+
+// split returns the non-empty, trimmed values of a comma-separated list
+func split(list string) []string {
+	var out []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = `=== banner for user u-1001 ===
+{"level":"info","message":"User logged in","user_id":"u-1001","email":"Alice@Example.com"}
+{"level":"info","message":"User logged in","user_id":"u-10010","email":"bob@example.com"}
+{"level":"warn","message":"Password reset sent to alice@example.com.","account":1001}
+{"level":"info","message":"Order created","customer_id":1001,"total":1001.5}
+{"level":"info","message":"Order created","customer_id":2002}
+`
+
+func TestRemove(t *testing.T) {
+	e, err := NewEraser(ModeRemove, []string{"u-1001"}, []string{"alice@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, line := range strings.Split(strings.TrimSpace(sample), "\n") {
+		if out, keep, _ := e.Line(line); keep {
+			kept = append(kept, out)
+		}
+	}
+	if len(kept) != 3 || !strings.Contains(kept[0], "u-10010") || !strings.Contains(kept[2], "2002") {
+		t.Errorf("kept %q, want only the lines of other users", kept)
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	e, err := NewEraser(ModePseudonymize, []string{"u-1001", "1001"}, []string{"alice@example.com"}, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, email, number := e.Pseudonym("u-1001"), e.Pseudonym("alice@example.com"), e.Pseudonym("1001")
+	if e.Pseudonym("ALICE@example.com") != email || !strings.HasPrefix(id, "anon_") || len(id) != 21 {
+		t.Fatalf("pseudonyms %s, %s", id, email)
+	}
+
+	tests := []struct {
+		line, want string
+		n          int
+	}{
+		{`{"user_id":"u-1001","email":"Alice@Example.com","level":"info"}`,
+			`{"user_id":"` + id + `","email":"` + email + `","level":"info"}`, 2},
+		{`{"message":"Password reset sent to alice@example.com.","uid":1001}`,
+			`{"message":"Password reset sent to ` + email + `.","uid":"` + number + `"}`, 2},
+		{`{"userId": "1001","message":"uid=1001 signed out"}`,
+			`{"userId": "` + number + `","message":"uid=` + number + ` signed out"}`, 2},
+		// 1001.5 is a different number, u-10010 a different user
+		{`{"customer_id":1001,"total":1001.5,"ref":"u-10010"}`, `{"customer_id":"` + number + `","total":1001.5,"ref":"u-10010"}`, 1},
+		{`=== banner for user u-1001 ===`, `=== banner for user ` + id + ` ===`, 1},
+	}
+	for _, tt := range tests {
+		out, keep, n := e.Line(tt.line)
+		if !keep || out != tt.want || n != tt.n {
+			t.Errorf("Line(%s) = %s, %v, %d, want %s, %d", tt.line, out, keep, n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "app.log")
+	os.WriteFile(plain, []byte(sample), 0o640)
+	compressed := filepath.Join(dir, "app-1.log.gz")
+	f, _ := os.Create(compressed)
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(sample))
+	gz.Close()
+	f.Close()
+	untouched := filepath.Join(dir, "other.log")
+	os.WriteFile(untouched, []byte(`{"user_id":"u-2002"}`+"\n"), 0o644)
+
+	e, _ := NewEraser(ModeRemove, []string{"u-1001"}, []string{"alice@example.com"}, nil)
+
+	report, err := e.File(plain, true)
+	if err != nil || report.Matched != 3 || report.Rewritten {
+		t.Fatalf("dry run = %+v, %v", report, err)
+	}
+	if data, _ := os.ReadFile(plain); string(data) != sample {
+		t.Fatal("dry run changed the file")
+	}
+
+	for _, path := range []string{plain, compressed} {
+		report, err := e.File(path, false)
+		if err != nil || report.Lines != 6 || report.Removed != 3 || !report.Rewritten {
+			t.Fatalf("File(%s) = %+v, %v", path, report, err)
+		}
+	}
+	data, _ := os.ReadFile(plain)
+	if strings.Contains(strings.ToLower(string(data)), "alice") || strings.Count(string(data), "\n") != 3 {
+		t.Errorf("erased file:\n%s", data)
+	}
+	if info, _ := os.Stat(plain); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want the original 0640", info.Mode().Perm())
+	}
+	f, _ = os.Open(compressed)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("rewritten .gz: %v", err)
+	}
+	var b strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, err := zr.Read(buf)
+		b.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	if strings.Contains(b.String(), "u-1001\"") || strings.Count(b.String(), "\n") != 3 {
+		t.Errorf("erased .gz:\n%s", b.String())
+	}
+
+	if report, err := e.File(untouched, false); err != nil || report.Rewritten {
+		t.Errorf("File(untouched) = %+v, %v", report, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("%d files in the directory, want no temporary files left", len(entries))
+	}
+}
+
+// TestNumericUserIDCollision erases user 200 without touching the status codes, durations
+// and counts that happen to be 200 too
+func TestNumericUserIDCollision(t *testing.T) {
+	lines := []string{
+		`{"level":"info","message":"Request completed","status":200,"latency_ms":200}`,
+		`{"level":"info","message":"Request completed","status":200,"user_id":200}`,
+		`GET /orders 200 user_id=200`,
+		`GET /orders 200 12ms`,
+	}
+
+	remove, err := NewEraser(ModeRemove, []string{"200"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, false, true} {
+		if _, keep, _ := remove.Line(lines[i]); keep != want {
+			t.Errorf("Line(%s) kept = %v, want %v", lines[i], keep, want)
+		}
+	}
+
+	e, _ := NewEraser(ModePseudonymize, []string{"200"}, nil, []byte("key"))
+	number := e.Pseudonym("200")
+	tests := []struct {
+		line, want string
+		n          int
+	}{
+		{lines[0], lines[0], 0},
+		{lines[1], `{"level":"info","message":"Request completed","status":200,"user_id":"` + number + `"}`, 1},
+		{lines[2], `GET /orders 200 user_id=` + number, 1},
+		{lines[3], lines[3], 0},
+	}
+	for _, tt := range tests {
+		if out, keep, n := e.Line(tt.line); !keep || out != tt.want || n != tt.n {
+			t.Errorf("Line(%s) = %s, %v, %d, want %s, %d", tt.line, out, keep, n, tt.want, tt.n)
+		}
+	}
+}
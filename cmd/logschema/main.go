@@ -0,0 +1,98 @@
+// Command logschema validates JSON log lines against the log entry schema in
+// pkg/logschema. It reads files, or standard input when none are given, skips lines that
+// are not log entries, such as banners, and reports every line that breaks the schema:
+//
+//	go run ./secretscan-demo | go run ./cmd/logschema
+//	go run ./cmd/logschema file-logging-demo/logs/app.log
+//	go run ./cmd/logschema -print > log-entry.schema.json
+//
+// The exit code is 1 when a line is invalid or no log entries were found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kart-io/go-example/pkg/logschema"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	schemaPath := flag.String("schema", "", "JSON Schema to validate against (default: the one in pkg/logschema)")
+	printSchema := flag.Bool("print", false, "write the schema to stdout and exit")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logschema [-schema file] [-print] [file...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	schema := logschema.Schema
+	if *schemaPath != "" {
+		data, err := os.ReadFile(*schemaPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logschema: %v\n", err)
+			return 2
+		}
+		schema = data
+	}
+	if *printSchema {
+		_, _ = os.Stdout.Write(schema)
+		return 0
+	}
+	validator, err := logschema.New(schema)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logschema: %v\n", err)
+		return 2
+	}
+
+	inputs := flag.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	entries, invalid := 0, 0
+	for _, input := range inputs {
+		n, bad, err := check(validator, input)
+		entries += n
+		invalid += bad
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logschema: %v\n", err)
+			return 2
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d entries, %d invalid\n", entries, invalid)
+	if entries == 0 || invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+// check validates one input, printing each invalid line as name:line: violation
+func check(validator *logschema.Validator, input string) (int, int, error) {
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer f.Close()
+		r, name = f, input
+	}
+
+	entries, failed, err := validator.Validate(r)
+	for _, result := range failed {
+		for _, violation := range result.Violations {
+			fmt.Printf("%s:%d: %s\n", name, result.Line, violation)
+		}
+	}
+	if err != nil {
+		return entries, len(failed), fmt.Errorf("read %s: %w", name, err)
+	}
+	return entries, len(failed), nil
+}
@@ -0,0 +1,236 @@
+// Command otlp-replay sends dead-lettered OTLP log batches (see pkg/otlpspool) to a
+// collector. Each batch is sent with the resource it was first exported with, so replayed
+// records are attributed to the service that wrote them, and its file is deleted once the
+// collector accepted it:
+//
+//	go run ./cmd/otlp-replay -dir /tmp/unified-otlp-demo-dead-letter -endpoint localhost:4318
+//	OTLP_DEAD_LETTER_DIR=/tmp/unified-otlp-demo-dead-letter go run ./cmd/otlp-replay -dry-run
+//
+// The flags default to OTLP_DEAD_LETTER_DIR, OTLP_ENDPOINT, OTLP_PROTOCOL, OTLP_HEADERS and,
+// for TLS, OTLP_CA_FILE, OTLP_CERT_FILE and OTLP_KEY_FILE. Header values may reference a
+// secret as ${file:PATH} or ${env:NAME}, see pkg/secretref.
+// The exit code is 1 when a batch could not be read or sent; those files are kept.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/otlpspool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	dir := flag.String("dir", os.Getenv("OTLP_DEAD_LETTER_DIR"), "dead-letter directory to replay")
+	endpoint := flag.String("endpoint", getEnvOrDefault("OTLP_ENDPOINT", "localhost:4318"), "collector host:port")
+	protocol := flag.String("protocol", getEnvOrDefault("OTLP_PROTOCOL", otelsetup.ProtocolHTTP), "http or grpc")
+	insecure := flag.Bool("insecure", true, "use a plaintext connection")
+	headers := flag.String("headers", os.Getenv("OTLP_HEADERS"), "extra request headers, key=value,key2=value2; a value may be ${file:path} or ${env:NAME}")
+	caFile := flag.String("ca", os.Getenv("OTLP_CA_FILE"), "CA bundle to verify the collector with; turns off -insecure")
+	certFile := flag.String("cert", os.Getenv("OTLP_CERT_FILE"), "client certificate for mutual TLS")
+	keyFile := flag.String("key", os.Getenv("OTLP_KEY_FILE"), "client key for mutual TLS")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout for each batch")
+	dryRun := flag.Bool("dry-run", false, "list the batches without sending them")
+	keep := flag.Bool("keep", false, "keep batch files after they were sent")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: otlp-replay -dir dir [-endpoint host:port] [-protocol http|grpc] [-insecure] [-headers k=v,...] [-ca file] [-cert file -key file] [-timeout d] [-dry-run] [-keep]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *dir == "" || flag.NArg() > 0 {
+		flag.Usage()
+		return 2
+	}
+
+	files, err := otlpspool.List(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "otlp-replay: %v\n", err)
+		return 2
+	}
+	tlsFiles := certwatch.Config{CAFile: *caFile, CertFile: *certFile, KeyFile: *keyFile}
+	if tlsFiles.Enabled() {
+		// Unusable files are a usage error, not a failure of every batch
+		if _, err := certwatch.Load(tlsFiles); err != nil {
+			fmt.Fprintf(os.Stderr, "otlp-replay: %v\n", err)
+			return 2
+		}
+	}
+	// Failures are reported per batch below, not a second time by the SDK
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	r := &replayer{
+		cfg: otelsetup.Config{
+			Endpoint: *endpoint,
+			Protocol: *protocol,
+			Insecure: *insecure,
+			Headers:  parseHeaders(*headers),
+			TLS:      tlsFiles,
+			Timeout:  *timeout,
+		},
+		targets: map[string]*target{},
+	}
+	defer r.shutdown()
+
+	sent, records, failed := 0, 0, 0
+	for _, f := range files {
+		path := filepath.Join(*dir, f.Name)
+		b, err := otlpspool.ReadBatch(path)
+		if err != nil {
+			fmt.Printf("%s: unreadable: %v\n", f.Name, err)
+			failed++
+			continue
+		}
+		service := "unknown service"
+		if v, ok := b.Resource().Set().Value("service.name"); ok && v.AsString() != "" {
+			service = v.AsString()
+		}
+		if *dryRun {
+			fmt.Printf("%s: %d records from %s, first failed %s\n", f.Name, b.Len(), service, f.Time.Format(time.RFC3339))
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err = r.send(ctx, b)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: %v\n", f.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: sent %d records from %s\n", f.Name, b.Len(), service)
+		if !*keep {
+			if err := os.Remove(path); err != nil {
+				fmt.Fprintf(os.Stderr, "otlp-replay: %v\n", err)
+			}
+		}
+		sent++
+		records += b.Len()
+	}
+
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "%d batches in %s\n", len(files), *dir)
+	} else {
+		fmt.Fprintf(os.Stderr, "%d batches (%d records) sent to %s, %d failed\n", sent, records, *endpoint, failed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replayer keeps one LoggerProvider per resource, so every batch goes out under its own
+type replayer struct {
+	cfg     otelsetup.Config
+	targets map[string]*target
+}
+
+type target struct {
+	provider *sdklog.LoggerProvider
+	exporter *checkedExporter
+}
+
+func (r *replayer) send(ctx context.Context, b *otlpspool.Batch) error {
+	t, err := r.target(ctx, b.Resource())
+	if err != nil {
+		return err
+	}
+	t.exporter.reset()
+	b.Emit(ctx, t.provider)
+	if err := t.provider.ForceFlush(ctx); err != nil {
+		return err
+	}
+	return t.exporter.err()
+}
+
+func (r *replayer) target(ctx context.Context, res *resource.Resource) (*target, error) {
+	key := res.SchemaURL() + "|" + res.Encoded(attribute.DefaultEncoder())
+	if t, ok := r.targets[key]; ok {
+		return t, nil
+	}
+
+	next, err := otelsetup.LogExporter(ctx, r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	t := &target{exporter: &checkedExporter{Exporter: next}}
+	t.provider = sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(t.exporter)),
+		sdklog.WithResource(res),
+	)
+	r.targets[key] = t
+	return t, nil
+}
+
+func (r *replayer) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout+time.Second)
+	defer cancel()
+	for _, t := range r.targets {
+		_ = t.provider.Shutdown(ctx)
+	}
+}
+
+// checkedExporter remembers the first export error since reset; the batch processor
+// would otherwise only hand it to the global error handler
+type checkedExporter struct {
+	sdklog.Exporter
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func (e *checkedExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	err := e.Exporter.Export(ctx, records)
+	if err != nil {
+		e.mu.Lock()
+		if e.lastErr == nil {
+			e.lastErr = err
+		}
+		e.mu.Unlock()
+	}
+	return err
+}
+
+func (e *checkedExporter) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastErr = nil
+}
+
+func (e *checkedExporter) err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastErr
+}
+
+// parseHeaders reads "key=value,key2=value2", the format of OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if key, val, ok := strings.Cut(strings.TrimSpace(pair), "="); ok && key != "" {
+			headers[key] = val
+		}
+	}
+	return headers
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/otlpspool"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type failingExporter struct{}
+
+func (failingExporter) Export(context.Context, []sdklog.Record) error {
+	return errors.New("connection refused")
+}
+func (failingExporter) ForceFlush(context.Context) error { return nil }
+func (failingExporter) Shutdown(context.Context) error   { return nil }
+
+// deadLetter writes one batch per service into a dead-letter directory
+func deadLetter(t *testing.T, services ...string) string {
+	t.Helper()
+	dl, err := otlpspool.NewDeadLetter(t.TempDir(), testlog.New(t).Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range services {
+		provider := sdklog.NewLoggerProvider(
+			sdklog.WithProcessor(sdklog.NewSimpleProcessor(dl.Exporter(failingExporter{}))),
+			sdklog.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+		)
+		var r otellog.Record
+		r.SetBody(otellog.StringValue("Order completed"))
+		provider.Logger("orders").Emit(context.Background(), r)
+		provider.Shutdown(context.Background())
+	}
+	return dl.Dir()
+}
+
+// collector accepts OTLP/HTTP logs and keeps the service.name of every record
+type collector struct {
+	mu       sync.Mutex
+	services []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var req collogsv1.ExportLogsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rl := range req.GetResourceLogs() {
+		for _, kv := range rl.GetResource().GetAttributes() {
+			if kv.GetKey() == "service.name" {
+				for _, sl := range rl.GetScopeLogs() {
+					for range sl.GetLogRecords() {
+						c.services = append(c.services, kv.GetValue().GetStringValue())
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestReplayKeepsEachBatchResource(t *testing.T) {
+	dir := deadLetter(t, "orders", "billing")
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	r := &replayer{
+		cfg:     otelsetup.Config{Endpoint: server.URL, Timeout: 5 * time.Second},
+		targets: map[string]*target{},
+	}
+	defer r.shutdown()
+	files, err := otlpspool.List(dir)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files = %v (%v)", files, err)
+	}
+	for _, f := range files {
+		b, err := otlpspool.ReadBatch(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.send(context.Background(), b); err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if got := strings.Join(c.services, ","); got != "orders,billing" {
+		t.Errorf("collector received records from %q, want orders,billing", got)
+	}
+}
+
+func TestReplayReportsFailure(t *testing.T) {
+	dir := deadLetter(t, "orders")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	r := &replayer{
+		cfg:     otelsetup.Config{Endpoint: server.URL, Timeout: 5 * time.Second},
+		targets: map[string]*target{},
+	}
+	defer r.shutdown()
+	files, _ := otlpspool.List(dir)
+	b, err := otlpspool.ReadBatch(filepath.Join(dir, files[0].Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.send(context.Background(), b); err == nil {
+		t.Error("rejected batch reported as sent")
+	}
+}
@@ -1,23 +1,30 @@
 package main
 
 import (
-	"fmt"
-	"os"
 
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/k8smeta"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
 	"github.com/kart-io/logger"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 )
 
 func main() {
-	fmt.Println("=== Custom Initial Fields Demo ===")
-	fmt.Println("Demonstrating how all InitialFields are included in every log entry\n")
+	cliflags.Parse()
+	console.Println("=== Custom Initial Fields Demo ===")
+	console.Println("Demonstrating how all InitialFields are included in every log entry")
 
 	// Get version info for some fields
 	versionInfo := version.Get()
 
 	// Demo: Logger with many different types of initial fields
-	fmt.Println("Logger with various types of initial fields:")
+	console.Println("Logger with various types of initial fields:")
 	logOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "info",
@@ -52,40 +59,51 @@ func main() {
 			"cost_center": "engineering",
 			"project":     "customer-portal",
 			
-			// Infrastructure
-			"container_id": getContainerID(),
-			"node_name":    getNodeName(),
-			
 			// Compliance and governance
 			"data_classification": "internal",
 			"retention_days":      30,
 		},
 	}
 
+	// Infrastructure: real host, container and pod values instead of hardcoded stand-ins.
+	// Outside a container or cluster those fields are simply absent.
+	for key, value := range hostmeta.Fields() {
+		logOption.InitialFields[key] = value
+	}
+	for key, value := range k8smeta.Fields() {
+		logOption.InitialFields[key] = value
+	}
+
+	cliflags.Apply(logOption)
 	logger, err := logger.New(logOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
+	crash.OnExit("logger", logger.Flush)
+	defer crash.Recover(logger)
+	defer resusage.FromEnv(logger)()
+	defer pidfile.FromEnv(logger)()
+	sanitize.Startup(logger, logOption)
 
 	// All these log entries will include ALL the initial fields above
-	fmt.Println("\n1. Simple info log:")
+	console.Println("\n1. Simple info log:")
 	logger.Info("Application started successfully")
 
-	fmt.Println("\n2. Structured log with additional fields:")
+	console.Println("\n2. Structured log with additional fields:")
 	logger.Infow("User login", 
 		"user_id", "user-12345",
 		"ip_address", "192.168.1.100",
 		"login_method", "oauth2",
 	)
 
-	fmt.Println("\n3. Error log:")
+	console.Println("\n3. Error log:")
 	logger.Errorw("Database connection failed",
 		"error", "connection timeout",
 		"retry_count", 3,
 		"duration_ms", 5000,
 	)
 
-	fmt.Println("\n4. Debug log with nested data:")
+	console.Println("\n4. Debug log with nested data:")
 	logger.Debugw("Processing request",
 		"request_id", "req-789",
 		"user_agent", "Mozilla/5.0...",
@@ -95,29 +113,14 @@ func main() {
 		},
 	)
 
-	fmt.Println("\n=== Demo Complete ===")
-	fmt.Println("\nNotice how EVERY log entry includes all the InitialFields:")
-	fmt.Println("- Service information (name, version)")
-	fmt.Println("- Environment details (region, datacenter)")
-	fmt.Println("- Team ownership (team, squad, maintainer)")
-	fmt.Println("- Technical context (language, framework, port)")
-	fmt.Println("- Business context (cost_center, project)")
-	fmt.Println("- Infrastructure details (container_id, node_name)")
-	fmt.Println("- Compliance info (data_classification, retention_days)")
-	fmt.Println("- Plus any runtime fields added via Infow(), Errorw(), etc.")
+	console.Println("\n=== Demo Complete ===")
+	console.Println("\nNotice how EVERY log entry includes all the InitialFields:")
+	console.Println("- Service information (name, version)")
+	console.Println("- Environment details (region, datacenter)")
+	console.Println("- Team ownership (team, squad, maintainer)")
+	console.Println("- Technical context (language, framework, port)")
+	console.Println("- Business context (cost_center, project)")
+	console.Println("- Infrastructure details (host.name, host.ip, container.id, k8s.node.name)")
+	console.Println("- Compliance info (data_classification, retention_days)")
+	console.Println("- Plus any runtime fields added via Infow(), Errorw(), etc.")
 }
-
-// Simulated functions to get infrastructure details
-func getContainerID() string {
-	if id := os.Getenv("CONTAINER_ID"); id != "" {
-		return id
-	}
-	return "container-abc123"
-}
-
-func getNodeName() string {
-	if name := os.Getenv("NODE_NAME"); name != "" {
-		return name
-	}
-	return "node-worker-01"
-}
\ No newline at end of file
@@ -2,18 +2,28 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/go-example/pkg/timefmt"
 	"github.com/kart-io/logger"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 )
 
 func main() {
-	fmt.Println("=== Default Fields Demo ===")
-	fmt.Println("Demonstrating logger behavior with and without InitialFields\n")
+	cliflags.Parse()
+	console.Println("=== Default Fields Demo ===")
+	console.Println("Demonstrating logger behavior with and without InitialFields")
 
 	// Demo 1: Logger without InitialFields - should show "unknown"
-	fmt.Println("1. Logger without InitialFields (should show 'unknown' values):")
+	console.Println("1. Logger without InitialFields (should show 'unknown' values):")
 	basicOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "info",
@@ -22,15 +32,22 @@ func main() {
 		// No InitialFields specified
 	}
 
+	cliflags.Apply(basicOption)
 	basicLogger, err := logger.New(basicOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
+	crash.OnExit("logger", basicLogger.Flush)
+	defer crash.Recover(basicLogger)
+	defer resusage.FromEnv(basicLogger)()
+	defer pidfile.FromEnv(basicLogger)()
+	sanitize.Startup(basicLogger, basicOption)
+
 	basicLogger.Infow("Basic logger message", "test", "value1")
-	fmt.Println()
+	console.Println()
 
 	// Demo 2: Logger with empty InitialFields - should still show "unknown"
-	fmt.Println("2. Logger with empty InitialFields (should show 'unknown' values):")
+	console.Println("2. Logger with empty InitialFields (should show 'unknown' values):")
 	emptyOption := &option.LogOption{
 		Engine:        "slog",
 		Level:         "info",
@@ -39,15 +56,16 @@ func main() {
 		InitialFields: map[string]interface{}{}, // Empty map
 	}
 
+	cliflags.Apply(emptyOption)
 	emptyLogger, err := logger.New(emptyOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 	emptyLogger.Infow("Empty initial fields message", "test", "value2")
-	fmt.Println()
+	console.Println()
 
 	// Demo 3: Logger with partial InitialFields - should show mix of provided and "unknown"
-	fmt.Println("3. Logger with partial InitialFields (service.name provided, service.version unknown):")
+	console.Println("3. Logger with partial InitialFields (service.name provided, service.version unknown):")
 	partialOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "info",
@@ -59,15 +77,16 @@ func main() {
 		},
 	}
 
+	cliflags.Apply(partialOption)
 	partialLogger, err := logger.New(partialOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 	partialLogger.Infow("Partial fields message", "test", "value3")
-	fmt.Println()
+	console.Println()
 
 	// Demo 4: Logger with complete InitialFields - should show all provided values
-	fmt.Println("4. Logger with complete InitialFields (all values provided):")
+	console.Println("4. Logger with complete InitialFields (all values provided):")
 	versionInfo := version.Get()
 	completeOption := &option.LogOption{
 		Engine:      "slog",
@@ -81,15 +100,16 @@ func main() {
 		},
 	}
 
+	cliflags.Apply(completeOption)
 	completeLogger, err := logger.New(completeOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 	completeLogger.Infow("Complete fields message", "test", "value4")
-	fmt.Println()
+	console.Println()
 
 	// Demo 5: Logger with overridden default values
-	fmt.Println("5. Logger with custom values overriding defaults:")
+	console.Println("5. Logger with custom values overriding defaults:")
 	customOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "info",
@@ -102,14 +122,72 @@ func main() {
 		},
 	}
 
+	cliflags.Apply(customOption)
 	customLogger, err := logger.New(customOption)
 	if err != nil {
-		panic(err)
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 	customLogger.Infow("Custom fields message", "test", "value5")
-	fmt.Println()
+	console.Println()
+
+	// Demo 6: One entry written under each timestamp setting
+	console.Println("6. The same entry under each timestamp layout and time zone (logger.timestamp in viper-config-demo):")
+	timestampDemo()
+	console.Println()
+
+	console.Println("=== Demo Complete ===")
+	console.Println("Notice how 'service.name' and 'service.version' are always present,")
+	console.Println("with 'unknown' as default when not explicitly provided.")
+}
+
+// timestampDemo logs one entry to a file per timefmt setting and prints the lines. They go
+// through console rather than stdout: an epoch timestamp is not the RFC 3339 string that
+// pkg/logschema expects of the demo's own log lines.
+func timestampDemo() {
+	settings := []timefmt.Config{
+		{Layout: timefmt.RFC3339Nano, Timezone: timefmt.UTC},
+		{Layout: timefmt.RFC3339Millis, Timezone: timefmt.Local},
+		{Layout: timefmt.EpochMillis},
+	}
+	dir, err := os.MkdirTemp("", "timefmt-demo-")
+	if err != nil {
+		crash.Fatal(nil, "Failed to create temporary directory", "error", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	if err := timefmt.RegisterSink(); err != nil {
+		crash.Fatal(nil, "Failed to register timefmt sink", "error", err.Error())
+	}
 
-	fmt.Println("=== Demo Complete ===")
-	fmt.Println("Notice how 'service.name' and 'service.version' are always present,")
-	fmt.Println("with 'unknown' as default when not explicitly provided.")
-}
\ No newline at end of file
+	// One logger with every setting as an output, so each line is the same entry
+	files := make([]string, len(settings))
+	paths := make([]string, len(settings))
+	for i, c := range settings {
+		files[i] = filepath.Join(dir, fmt.Sprintf("%d.log", i))
+		paths[i] = c.Path(files[i])
+	}
+	timestampLogger, err := logger.New(&option.LogOption{
+		Engine:        "zap",
+		Level:         "info",
+		Format:        "json",
+		OutputPaths:   paths,
+		DisableCaller: true,
+		InitialFields: map[string]interface{}{"service.name": "timestamp-demo"},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	timestampLogger.Infow("Order created", "order_id", "ord-1")
+	timestampLogger.Flush()
+
+	for i, c := range settings {
+		line, err := os.ReadFile(files[i])
+		if err != nil {
+			crash.Fatal(nil, "Failed to read log file", "error", err.Error())
+		}
+		zone := c.Timezone
+		if zone == "" {
+			zone = "-"
+		}
+		console.Printf("   %-14s %-6s %s", c.Layout, zone, line)
+	}
+}
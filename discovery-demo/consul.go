@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownCheck is returned when the agent no longer knows a check, e.g. after an agent restart
+var ErrUnknownCheck = errors.New("consul agent does not know the check")
+
+// Registration is the subset of Consul's agent service definition the demo uses
+type Registration struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *Check            `json:"Check,omitempty"`
+}
+
+// Check is a TTL health check: the service must report in before TTL runs out
+type Check struct {
+	CheckID                        string `json:"CheckID"`
+	TTL                            string `json:"TTL"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+// Instance is one healthy entry returned by the health endpoint
+type Instance struct {
+	ID      string
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+// Endpoint returns host:port
+func (i Instance) Endpoint() string {
+	return fmt.Sprintf("%s:%d", i.Address, i.Port)
+}
+
+// ConsulClient talks to the local Consul agent's HTTP API
+type ConsulClient struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+// NewConsulClient targets the agent at addr, e.g. http://127.0.0.1:8500
+func NewConsulClient(addr, token string) *ConsulClient {
+	// Blocking queries hold the connection for up to the wait time, so the timeout must exceed it
+	return &ConsulClient{addr: addr, token: token, client: &http.Client{Timeout: 2 * time.Minute}}
+}
+
+// Register adds the service and its check to the local agent
+func (c *ConsulClient) Register(ctx context.Context, reg Registration) error {
+	return c.put(ctx, "/v1/agent/service/register", reg)
+}
+
+// Deregister removes the service from the local agent
+func (c *ConsulClient) Deregister(ctx context.Context, serviceID string) error {
+	return c.put(ctx, "/v1/agent/service/deregister/"+url.PathEscape(serviceID), nil)
+}
+
+// PassTTL marks a TTL check as passing
+func (c *ConsulClient) PassTTL(ctx context.Context, checkID string) error {
+	return c.put(ctx, "/v1/agent/check/pass/"+url.PathEscape(checkID), nil)
+}
+
+// HealthyInstances returns the passing instances of service. With a non-zero index it is a
+// blocking query that returns once the result changes or wait elapses.
+func (c *ConsulClient) HealthyInstances(ctx context.Context, service string, index uint64, wait time.Duration) ([]Instance, uint64, error) {
+	query := url.Values{"passing": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", wait.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.addr+"/v1/health/service/"+url.PathEscape(service)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul returned %s", resp.Status)
+	}
+
+	var entries []struct {
+		Node    struct{ Address string }
+		Service struct {
+			ID      string
+			Address string
+			Port    int
+			Meta    map[string]string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode health response: %w", err)
+	}
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	instances := make([]Instance, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		instances = append(instances, Instance{ID: entry.Service.ID, Address: address, Port: entry.Service.Port, Meta: entry.Service.Meta})
+	}
+	return instances, newIndex, nil
+}
+
+func (c *ConsulClient) put(ctx context.Context, path string, body interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.addr+path, &payload)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/v1/agent/check/"):
+		return ErrUnknownCheck
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("consul %s returned %s", path, resp.Status)
+	}
+	return nil
+}
+
+func (c *ConsulClient) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Consul Service Discovery Demo ===")
+	console.Println("Registers services with TTL health checks and resolves a downstream service by name")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	discoveryLogger := baseLogger.With("component", "discovery")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if d := getDurationEnv("DURATION", 0); d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	consulAddr := os.Getenv("CONSUL_HTTP_ADDR")
+	if consulAddr == "" {
+		mock, err := StartMockConsul("127.0.0.1:0", baseLogger.With("component", "mock-consul"))
+		if err != nil {
+			crash.Fatal(discoveryLogger, "Failed to start mock Consul", "error", err.Error())
+		}
+		defer mock.Close()
+		consulAddr = mock.URL()
+	}
+	consul := NewConsulClient(consulAddr, os.Getenv("CONSUL_HTTP_TOKEN"))
+	address := getEnvOrDefault("SERVICE_ADDRESS", "127.0.0.1")
+	ttl := getDurationEnv("CHECK_TTL", 3*time.Second)
+	discoveryLogger.Infow("Using Consul agent", "addr", consulAddr, "check_ttl", ttl.String())
+
+	// Two instances of the downstream service
+	var (
+		registrars []*Registrar
+		servers    []*http.Server
+	)
+	for _, port := range []string{getEnvOrDefault("INVENTORY_PORT_1", "8105"), getEnvOrDefault("INVENTORY_PORT_2", "8106")} {
+		instanceID := "inventory-" + port
+		servers = append(servers, serve(newInventoryRouter(instanceID, summary), port, baseLogger.With("component", instanceID)))
+		registrar := NewRegistrar(consul, registration("inventory", instanceID, address, port, versionInfo.GitVersion), ttl, discoveryLogger)
+		if err := registrar.Start(ctx); err != nil {
+			crash.Fatal(discoveryLogger, "Failed to register service", "service_id", instanceID, "error", err.Error())
+		}
+		registrars = append(registrars, registrar)
+	}
+
+	// The storefront registers itself and resolves inventory by name
+	port := getEnvOrDefault("PORT", "8104")
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "consul_addr", consulAddr, "service_address", address, "check_ttl", ttl.String())
+	storefrontLogger := baseLogger.With("component", "storefront")
+	storefrontRegistrar := NewRegistrar(consul, registration("storefront", "storefront-"+port, address, port, versionInfo.GitVersion), ttl, discoveryLogger)
+	if err := storefrontRegistrar.Start(ctx); err != nil {
+		crash.Fatal(discoveryLogger, "Failed to register service", "service_id", "storefront-"+port, "error", err.Error())
+	}
+	registrars = append(registrars, storefrontRegistrar)
+
+	resolver := NewResolver(consul, "inventory", getDurationEnv("WATCH_WAIT", 30*time.Second), discoveryLogger)
+	if err := resolver.Start(ctx); err != nil {
+		crash.Fatal(discoveryLogger, "Failed to resolve downstream service", "downstream", "inventory", "error", err.Error())
+	}
+	servers = append(servers, serve(newStorefrontRouter(resolver, storefrontLogger, summary), port, storefrontLogger))
+
+	// Simulate the second inventory instance hanging, then recovering
+	if pauseAt := getDurationEnv("PAUSE_AT", 5*time.Second); pauseAt > 0 {
+		go func() {
+			time.Sleep(pauseAt)
+			registrars[1].Pause()
+			time.Sleep(getDurationEnv("PAUSE_FOR", 6*time.Second))
+			registrars[1].Resume()
+		}()
+	}
+	if rps := getIntEnv("TRAFFIC_RPS", 2); rps > 0 {
+		go generateTraffic(ctx, "http://localhost:"+port, rps)
+	}
+
+	console.Printf("Storefront on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl http://localhost:%s/products/sku-keyboard\n", port)
+
+	<-ctx.Done()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	for _, registrar := range registrars {
+		registrar.Stop(shutdownCtx)
+	}
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			baseLogger.Errorw("Server shutdown failed", "addr", srv.Addr, "error", err.Error())
+		}
+	}
+	summary.Log(baseLogger)
+}
+
+func registration(name, id, address, port, version string) Registration {
+	p, _ := strconv.Atoi(port)
+	return Registration{
+		ID:      id,
+		Name:    name,
+		Address: address,
+		Port:    p,
+		Tags:    []string{"demo", getEnvOrDefault("DEPLOY_ENV", "development")},
+		Meta:    map[string]string{"version": version},
+	}
+}
+
+func newInventoryRouter(instanceID string, summary *runsummary.Summary) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+	r.GET("/stock/:sku", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sku": c.Param("sku"), "available": rng.Intn(50), "instance": instanceID})
+	})
+	return r
+}
+
+func newStorefrontRouter(resolver *Resolver, storefrontLogger core.Logger, summary *runsummary.Summary) *gin.Engine {
+	client := &http.Client{Timeout: 2 * time.Second}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.Use(logcontext.GinRequestLogger(storefrontLogger))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+	r.GET("/products/:sku", func(c *gin.Context) {
+		logger := logcontext.MustFromGin(c)
+		sku := c.Param("sku")
+		instance, err := resolver.Pick()
+		if errors.Is(err, ErrNoInstances) {
+			logger.Errorw("No inventory instance available", "sku", sku)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "inventory unavailable"})
+			return
+		}
+
+		resp, err := client.Get("http://" + instance.Endpoint() + "/stock/" + sku)
+		if err != nil {
+			logger.Warnw("Downstream call failed", "sku", sku, "instance_id", instance.ID, "endpoint", instance.Endpoint(), "error", err.Error())
+			c.JSON(http.StatusBadGateway, gin.H{"error": "inventory call failed"})
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		logger.Infow("Product served", "sku", sku, "instance_id", instance.ID, "endpoint", instance.Endpoint())
+		c.Data(resp.StatusCode, "application/json", body)
+	})
+	return r
+}
+
+// serve starts r on port in the background; the caller shuts the returned server down
+func serve(r *gin.Engine, port string, serviceLogger core.Logger) *http.Server {
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+	return srv
+}
+
+func generateTraffic(ctx context.Context, baseURL string, rps int) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+
+	skus := []string{"sku-keyboard", "sku-monitor", "sku-mouse"}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resp, err := client.Get(baseURL + "/products/" + skus[rng.Intn(len(skus))])
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
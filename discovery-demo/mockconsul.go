@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+type mockService struct {
+	reg        Registration
+	ttl        time.Duration
+	deregAfter time.Duration
+	lastPass   time.Time
+	passing    bool
+	critical   time.Time
+}
+
+// MockConsul implements the agent endpoints the demo uses, including TTL expiry,
+// DeregisterCriticalServiceAfter and blocking queries on the health endpoint
+type MockConsul struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	mu       sync.Mutex
+	services map[string]*mockService
+	index    uint64
+	changed  chan struct{}
+	done     chan struct{}
+}
+
+// StartMockConsul listens on addr
+func StartMockConsul(addr string, logger core.Logger) (*MockConsul, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockConsul{
+		logger:   logger,
+		listener: listener,
+		services: map[string]*mockService{},
+		index:    1,
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /v1/agent/service/register", m.handleRegister)
+	mux.HandleFunc("PUT /v1/agent/service/deregister/{id}", m.handleDeregister)
+	mux.HandleFunc("PUT /v1/agent/check/pass/{id}", m.handlePass)
+	mux.HandleFunc("GET /v1/health/service/{name}", m.handleHealth)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	go m.reap()
+	return m, nil
+}
+
+// URL returns the agent address
+func (m *MockConsul) URL() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+// Close stops the agent
+func (m *MockConsul) Close() error {
+	close(m.done)
+	return m.server.Close()
+}
+
+// bump advances the index and wakes blocking queries; callers hold mu
+func (m *MockConsul) bump() {
+	m.index++
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func (m *MockConsul) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var reg Registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	svc := &mockService{reg: reg, critical: time.Now()}
+	if reg.Check != nil {
+		svc.ttl, _ = time.ParseDuration(reg.Check.TTL)
+		svc.deregAfter, _ = time.ParseDuration(reg.Check.DeregisterCriticalServiceAfter)
+	}
+
+	m.mu.Lock()
+	m.services[reg.ID] = svc
+	m.bump()
+	m.mu.Unlock()
+	m.logger.Infow("Mock Consul registered service", "service_id", reg.ID, "check_status", "critical")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockConsul) handleDeregister(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	m.mu.Lock()
+	_, ok := m.services[id]
+	delete(m.services, id)
+	if ok {
+		m.bump()
+	}
+	m.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown service ID", http.StatusNotFound)
+		return
+	}
+	m.logger.Infow("Mock Consul deregistered service", "service_id", id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockConsul) handlePass(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.PathValue("id"), "service:")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	svc, ok := m.services[id]
+	if !ok {
+		http.Error(w, "unknown check ID", http.StatusNotFound)
+		return
+	}
+	svc.lastPass = time.Now()
+	if !svc.passing {
+		svc.passing = true
+		m.bump()
+		m.logger.Infow("Mock Consul check passing", "service_id", id)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockConsul) handleHealth(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil {
+		wait = 5 * time.Minute
+	}
+
+	m.mu.Lock()
+	if index > 0 && index >= m.index {
+		changed := m.changed
+		m.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		m.mu.Lock()
+	}
+
+	type entry struct {
+		Node    struct{ Address string }
+		Service Registration
+	}
+	entries := []entry{}
+	for _, svc := range m.services {
+		if svc.reg.Name == name && svc.passing {
+			e := entry{Service: svc.reg}
+			e.Node.Address = "127.0.0.1"
+			entries = append(entries, e)
+		}
+	}
+	currentIndex := m.index
+	m.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(currentIndex, 10))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// reap turns checks critical when their TTL expires and removes services that stayed
+// critical past DeregisterCriticalServiceAfter
+func (m *MockConsul) reap() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for id, svc := range m.services {
+				if svc.passing && svc.ttl > 0 && now.Sub(svc.lastPass) > svc.ttl {
+					svc.passing = false
+					svc.critical = now
+					m.bump()
+					m.logger.Warnw("Mock Consul check critical, TTL expired", "service_id", id, "ttl", svc.ttl.String())
+				}
+				if !svc.passing && svc.deregAfter > 0 && now.Sub(svc.critical) > svc.deregAfter {
+					delete(m.services, id)
+					m.bump()
+					m.logger.Warnw("Mock Consul deregistered critical service", "service_id", id)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Registrar keeps one service instance registered: it registers on Start, reports the TTL
+// check until stopped, re-registers if the agent forgot it, and deregisters on Stop
+type Registrar struct {
+	client *ConsulClient
+	reg    Registration
+	ttl    time.Duration
+	logger core.Logger
+
+	stop    chan struct{}
+	stopped chan struct{}
+	paused  chan bool
+}
+
+// NewRegistrar prepares reg with a TTL check named service:<id>
+func NewRegistrar(client *ConsulClient, reg Registration, ttl time.Duration, logger core.Logger) *Registrar {
+	reg.Check = &Check{
+		CheckID:                        "service:" + reg.ID,
+		TTL:                            ttl.String(),
+		DeregisterCriticalServiceAfter: "1m",
+	}
+	return &Registrar{
+		client:  client,
+		reg:     reg,
+		ttl:     ttl,
+		logger:  logger.With("service_id", reg.ID, "service_name", reg.Name),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		paused:  make(chan bool),
+	}
+}
+
+// Start registers the instance and starts the heartbeat
+func (r *Registrar) Start(ctx context.Context) error {
+	if err := r.client.Register(ctx, r.reg); err != nil {
+		return err
+	}
+	r.logger.Infow("Service registered",
+		"address", r.reg.Address,
+		"port", r.reg.Port,
+		"tags", r.reg.Tags,
+		"check_id", r.reg.Check.CheckID,
+		"check_ttl", r.reg.Check.TTL,
+		"deregister_critical_after", r.reg.Check.DeregisterCriticalServiceAfter,
+	)
+	// Consul starts TTL checks as critical; report in right away instead of waiting a full interval
+	r.heartbeat(ctx, false)
+
+	go r.run()
+	return nil
+}
+
+// Pause stops heartbeats without deregistering, as a hung process would
+func (r *Registrar) Pause() {
+	r.paused <- true
+}
+
+// Resume restarts heartbeats after Pause
+func (r *Registrar) Resume() {
+	r.paused <- false
+}
+
+// Stop ends the heartbeat and removes the instance from Consul
+func (r *Registrar) Stop(ctx context.Context) {
+	close(r.stop)
+	<-r.stopped
+	if err := r.client.Deregister(ctx, r.reg.ID); err != nil {
+		r.logger.Errorw("Service deregistration failed", "error", err.Error())
+		return
+	}
+	r.logger.Infow("Service deregistered")
+}
+
+func (r *Registrar) run() {
+	defer close(r.stopped)
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	failing, paused := false, false
+	for {
+		select {
+		case <-r.stop:
+			return
+		case paused = <-r.paused:
+			if paused {
+				r.logger.Warnw("Health check heartbeat paused, instance will turn critical", "check_ttl", r.ttl.String())
+				continue
+			}
+			r.logger.Infow("Health check heartbeat resumed")
+			failing = r.heartbeat(context.Background(), failing)
+		case <-ticker.C:
+			if !paused {
+				failing = r.heartbeat(context.Background(), failing)
+			}
+		}
+	}
+}
+
+// heartbeat passes the TTL check and reports whether it is failing, logging only on transitions
+func (r *Registrar) heartbeat(ctx context.Context, failing bool) bool {
+	ctx, cancel := context.WithTimeout(ctx, r.ttl/3)
+	defer cancel()
+
+	err := r.client.PassTTL(ctx, r.reg.Check.CheckID)
+	if errors.Is(err, ErrUnknownCheck) {
+		// The agent lost our registration (restart, or deregistered after being critical too long)
+		if err = r.client.Register(ctx, r.reg); err == nil {
+			r.logger.Warnw("Service re-registered after the agent dropped it")
+			err = r.client.PassTTL(ctx, r.reg.Check.CheckID)
+		}
+	}
+
+	switch {
+	case err != nil && !failing:
+		r.logger.Warnw("Health check heartbeat failed", "error", err.Error())
+		return true
+	case err == nil && failing:
+		r.logger.Infow("Health check heartbeat restored")
+		return false
+	}
+	return err != nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// ErrNoInstances is returned by Pick when the cache holds no healthy instance
+var ErrNoInstances = errors.New("no healthy instances")
+
+// Resolver keeps a cache of a service's healthy instances, refreshed by Consul blocking
+// queries, and hands them out round-robin
+type Resolver struct {
+	client  *ConsulClient
+	service string
+	wait    time.Duration
+	logger  core.Logger
+
+	mu        sync.RWMutex
+	instances []Instance
+	index     uint64
+	refreshes int
+	next      atomic.Uint64
+}
+
+// NewResolver resolves service; wait bounds each blocking query
+func NewResolver(client *ConsulClient, service string, wait time.Duration, logger core.Logger) *Resolver {
+	return &Resolver{client: client, service: service, wait: wait, logger: logger.With("downstream", service)}
+}
+
+// Start loads the cache once and keeps it fresh until ctx is cancelled
+func (r *Resolver) Start(ctx context.Context) error {
+	instances, index, err := r.client.HealthyInstances(ctx, r.service, 0, 0)
+	if err != nil {
+		return err
+	}
+	r.update(instances, index)
+	go r.watch(ctx)
+	return nil
+}
+
+// Pick returns the next healthy instance
+func (r *Resolver) Pick() (Instance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.instances) == 0 {
+		return Instance{}, ErrNoInstances
+	}
+	return r.instances[int(r.next.Add(1)-1)%len(r.instances)], nil
+}
+
+func (r *Resolver) watch(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		r.mu.RLock()
+		index := r.index
+		r.mu.RUnlock()
+
+		instances, newIndex, err := r.client.HealthyInstances(ctx, r.service, index, r.wait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Warnw("Resolution refresh failed, serving cached instances", "error", err.Error(), "retry_in", backoff.String())
+			time.Sleep(backoff)
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+
+		// The index can go backwards after an agent restart; Consul's guidance is to start over
+		if newIndex < index {
+			newIndex = 0
+		}
+		if newIndex != index {
+			r.update(instances, newIndex)
+		}
+	}
+}
+
+// update swaps the cache and logs what changed; a blocking query can return with the same set
+// (another service changed), which is recorded at debug level only
+func (r *Resolver) update(instances []Instance, index uint64) {
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	r.mu.Lock()
+	added, removed := diffInstances(r.instances, instances)
+	r.instances = instances
+	r.index = index
+	r.refreshes++
+	refreshes := r.refreshes
+	r.mu.Unlock()
+
+	endpoints := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		endpoints = append(endpoints, instance.Endpoint())
+	}
+	fields := []interface{}{
+		"consul_index", index,
+		"instances", len(instances),
+		"endpoints", endpoints,
+		"added", added,
+		"removed", removed,
+		"refresh", refreshes,
+	}
+	switch {
+	case len(instances) == 0:
+		r.logger.Errorw("Resolution cache refreshed, no healthy instances left", fields...)
+	case len(added) > 0 || len(removed) > 0:
+		r.logger.Infow("Resolution cache refreshed", fields...)
+	default:
+		r.logger.Debugw("Resolution cache refreshed without changes", fields...)
+	}
+}
+
+func diffInstances(before, after []Instance) ([]string, []string) {
+	old := make(map[string]bool, len(before))
+	for _, instance := range before {
+		old[instance.ID] = true
+	}
+	added := []string{}
+	for _, instance := range after {
+		if !old[instance.ID] {
+			added = append(added, instance.ID)
+		}
+		delete(old, instance.ID)
+	}
+	removed := []string{}
+	for id := range old {
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/dynamicfields"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Dynamic Fields Demo ===")
+	console.Println("Fields computed when each entry is written: goroutines, heap and in-flight jobs per entry, GC cycles sampled periodically")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Per entry: goroutines, heap_inuse_bytes and uptime_seconds, plus the demo's own counter
+	var inflight atomic.Int64
+	fields := dynamicfields.Default()
+	fields.Register("inflight_jobs", func() interface{} { return inflight.Load() })
+
+	// Periodic: ReadMemStats stops the world, so it is sampled on an interval, not per entry
+	sampleInterval := getDurationEnv("SAMPLE_INTERVAL", time.Second)
+	fields.Register("gc_cycles", dynamicfields.Periodic(ctx, sampleInterval, func() interface{} {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return stats.NumGC
+	}))
+
+	serviceLogger := fields.Wrap(baseLogger)
+	jobs := getIntEnv("JOBS", 20)
+	sanitize.Startup(baseLogger, logOption, "jobs", jobs, "sample_interval", sampleInterval.String())
+	serviceLogger.Infow("Starting dynamic fields demo",
+		"jobs", jobs,
+		"sample_interval", sampleInterval.String(),
+	)
+
+	// A bare entry every interval shows the fields changing with nothing at the call site
+	go func() {
+		ticker := time.NewTicker(sampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				serviceLogger.Info("Runtime snapshot")
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	workerLogger := serviceLogger.With("component", "worker")
+	for i := 0; i < jobs && ctx.Err() == nil; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runJob(ctx, workerLogger, &inflight)
+		}()
+		time.Sleep(time.Duration(50+rng.Intn(150)) * time.Millisecond)
+	}
+	wg.Wait()
+
+	runtime.GC()
+	serviceLogger.Infow("Dynamic fields demo finished", "jobs", jobs)
+}
+
+// runJob holds a buffer for a while so heap_inuse_bytes and goroutines rise and fall with
+// inflight_jobs in the entries around it
+func runJob(ctx context.Context, log core.Logger, inflight *atomic.Int64) {
+	jobID := requestid.New(requestid.Job)
+	size := (1 + rng.Intn(8)) << 20
+
+	inflight.Add(1)
+	defer inflight.Add(-1)
+	log.Infow("Job started", "job_id", jobID, "buffer_bytes", size)
+
+	buffer := make([]byte, size)
+	for i := range buffer {
+		buffer[i] = byte(i)
+	}
+
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		log.Warnw("Job cancelled", "job_id", jobID, "duration_ms", time.Since(start).Milliseconds())
+		return
+	case <-time.After(time.Duration(300+rng.Intn(1200)) * time.Millisecond):
+	}
+	runtime.KeepAlive(buffer)
+	log.Infow("Job finished", "job_id", jobID, "duration_ms", time.Since(start).Milliseconds())
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+)
+
+// FakeSMTPServer is a minimal in-process SMTP server used when no real server is configured.
+// It randomly answers 451 (transient) to exercise retries and rejects recipients on
+// blocked.example with 550 (permanent).
+type FakeSMTPServer struct {
+	listener        net.Listener
+	logger          core.Logger
+	transientChance float64
+}
+
+// StartFakeSMTPServer listens on addr and serves connections in the background
+func StartFakeSMTPServer(addr string, transientChance float64, logger core.Logger) (*FakeSMTPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &FakeSMTPServer{
+		listener:        listener,
+		logger:          logger,
+		transientChance: transientChance,
+	}
+	go server.serve()
+	return server, nil
+}
+
+// Addr returns the listening address
+func (s *FakeSMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *FakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *FakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 fake-smtp ready")
+	var recipients int
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake-smtp")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			if rng.Float64() < s.transientChance {
+				reply("451 4.3.0 Temporary local problem, try again later")
+				continue
+			}
+			recipients = 0
+			reply("250 2.1.0 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			if strings.Contains(cmd, "@BLOCKED.EXAMPLE") {
+				reply("550 5.1.1 Recipient address rejected")
+				continue
+			}
+			recipients++
+			reply("250 2.1.5 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			size := 0
+			for {
+				data, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if data == ".\r\n" {
+					break
+				}
+				size += len(data)
+			}
+			s.logger.Debugw("Fake SMTP server accepted message", "recipients", recipients, "bytes", size)
+			reply("250 2.0.0 Queued")
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
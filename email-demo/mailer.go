@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Mailer renders templates and delivers them over SMTP with retries
+type Mailer struct {
+	addr        string
+	from        string
+	auth        smtp.Auth
+	templates   *template.Template
+	logger      core.Logger
+	maxAttempts int
+	baseBackoff time.Duration
+}
+
+// Email is a rendered message ready for delivery
+type Email struct {
+	ID       string
+	Template string
+	To       []string
+	Subject  string
+	Body     string
+}
+
+// DeliveryResult describes the outcome of a delivery
+type DeliveryResult struct {
+	MessageID  string `json:"message_id"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	SMTPCode   int    `json:"smtp_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Render executes the named template with data; the first line of output is the subject
+func (m *Mailer) Render(id, name string, to []string, data interface{}) (*Email, error) {
+	var buf bytes.Buffer
+	if err := m.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+	return &Email{
+		ID:       id,
+		Template: name,
+		To:       to,
+		Subject:  strings.TrimPrefix(subject, "Subject: "),
+		Body:     strings.TrimLeft(body, "\n"),
+	}, nil
+}
+
+// Send delivers an email, retrying transient (4xx / network) failures with backoff
+func (m *Mailer) Send(email *Email) DeliveryResult {
+	start := time.Now()
+	mailLogger := m.logger.With(
+		"message_id", email.ID,
+		"template", email.Template,
+		"recipients", email.To,
+		"smtp_server", m.addr,
+	)
+
+	result := DeliveryResult{MessageID: email.ID}
+	for attempt := 1; attempt <= m.maxAttempts; attempt++ {
+		result.Attempts = attempt
+		attemptStart := time.Now()
+
+		err := smtp.SendMail(m.addr, m.auth, m.from, email.To, m.compose(email))
+		code, transient := classifySMTPError(err)
+		result.SMTPCode = code
+
+		if err == nil {
+			result.Status = "delivered"
+			result.SMTPCode = 250
+			result.Error = ""
+			result.DurationMs = time.Since(start).Milliseconds()
+			mailLogger.Infow("Email delivered",
+				"attempt", attempt,
+				"smtp_code", 250,
+				"duration_ms", result.DurationMs,
+			)
+			return result
+		}
+
+		result.Error = err.Error()
+		fields := []interface{}{
+			"attempt", attempt,
+			"max_attempts", m.maxAttempts,
+			"smtp_code", code,
+			"transient", transient,
+			"error", err.Error(),
+			"attempt_duration_ms", time.Since(attemptStart).Milliseconds(),
+		}
+
+		if !transient {
+			mailLogger.Errorw("Email delivery failed permanently", fields...)
+			result.Status = "rejected"
+			break
+		}
+		if attempt == m.maxAttempts {
+			mailLogger.Errorw("Email delivery retries exhausted", fields...)
+			result.Status = "failed"
+			break
+		}
+
+		backoff := m.baseBackoff * time.Duration(1<<(attempt-1))
+		mailLogger.Warnw("Email delivery attempt failed, retrying", append(fields, "backoff_ms", backoff.Milliseconds())...)
+		time.Sleep(backoff)
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
+
+func (m *Mailer) compose(email *Email) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", email.Subject)
+	fmt.Fprintf(&msg, "Message-ID: <%s@email-demo>\r\n", email.ID)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+// classifySMTPError extracts the SMTP reply code and decides whether to retry
+func classifySMTPError(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code, protoErr.Code >= 400 && protoErr.Code < 500
+	}
+
+	// Network problems (connection refused, timeouts) are worth retrying
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return 0, true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/redact"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// emailTemplates are plain text templates; the first line is the subject
+const emailTemplates = `
+{{define "welcome"}}Subject: Welcome to {{.Product}}, {{.Name}}!
+Hi {{.Name}},
+
+Thanks for signing up for {{.Product}}. Your account is ready.
+
+-- The {{.Product}} team
+{{end}}
+{{define "password_reset"}}Subject: Reset your {{.Product}} password
+Hi {{.Name}},
+
+Use the code {{.Code}} to reset your password. It expires in 15 minutes.
+{{end}}
+`
+
+// sendRequest is the body accepted by POST /emails/:template
+type sendRequest struct {
+	To   []string          `json:"to" binding:"required"`
+	Data map[string]string `json:"data"`
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== SMTP Email Demo ===")
+	console.Println("Templated email delivery with retries and masked recipients")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "slog",
+		Level:       "debug",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "mailer",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	// Recipients appear in fields and in SMTP errors ("550 <bob@example.com> rejected"),
+	// so everything is logged through the redactor rather than masked at each call site
+	serviceLogger = redact.Default().Wrap(serviceLogger)
+
+	// Use a real server (e.g. MailHog/Mailpit on localhost:1025) when SMTP_ADDR is set,
+	// otherwise start a flaky in-process server to demonstrate retries
+	smtpAddr := os.Getenv("SMTP_ADDR")
+	if smtpAddr == "" {
+		fake, err := StartFakeSMTPServer("127.0.0.1:0", 0.4, serviceLogger)
+		if err != nil {
+			crash.Fatal(serviceLogger, "Failed to start fake SMTP server", "error", err.Error())
+		}
+		smtpAddr = fake.Addr()
+		serviceLogger.Infow("Using in-process fake SMTP server", "smtp_server", smtpAddr, "transient_failure_rate", 0.4)
+	}
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		host := strings.Split(smtpAddr, ":")[0]
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	maxAttempts, _ := strconv.Atoi(getEnvOrDefault("SMTP_MAX_ATTEMPTS", "4"))
+	mailer := &Mailer{
+		addr:        smtpAddr,
+		from:        getEnvOrDefault("SMTP_FROM", "no-reply@example.com"),
+		auth:        auth,
+		templates:   template.Must(template.New("emails").Parse(emailTemplates)),
+		logger:      serviceLogger,
+		maxAttempts: maxAttempts,
+		baseBackoff: 200 * time.Millisecond,
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.POST("/emails/:template", func(c *gin.Context) {
+		var req sendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		data := map[string]string{"Product": "Go Example", "Name": "there", "Code": "000000"}
+		for k, v := range req.Data {
+			data[k] = v
+		}
+
+		email, err := mailer.Render(requestid.New(requestid.Message), c.Param("template"), req.To, data)
+		if err != nil {
+			logcontext.MustFromGin(c).Warnw("Email template rendering failed",
+				"template", c.Param("template"),
+				"error", err.Error(),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result := mailer.Send(email)
+		status := http.StatusOK
+		if result.Status != "delivered" {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, result)
+	})
+
+	port := getEnvOrDefault("PORT", "8093")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port, "smtp_server", smtpAddr, "smtp_username", os.Getenv("SMTP_USERNAME"), "max_attempts", maxAttempts)
+	serviceLogger.Infow("Starting email service",
+		"port", port,
+		"smtp_server", smtpAddr,
+		"max_attempts", maxAttempts,
+		"templates", []string{"welcome", "password_reset"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these requests:")
+	console.Printf("  curl -X POST http://localhost:%s/emails/welcome -d '{\"to\":[\"jane.doe@example.com\"],\"data\":{\"Name\":\"Jane\"}}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/emails/password_reset -d '{\"to\":[\"bob@example.com\"],\"data\":{\"Code\":\"481516\"}}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/emails/welcome -d '{\"to\":[\"spam@blocked.example\"]}'  # permanent 550\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
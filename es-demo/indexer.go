@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+)
+
+// IndexerConfig controls bulk sizing and 429 backoff
+type IndexerConfig struct {
+	BaseURL     string
+	IndexPrefix string
+	FlushDocs   int
+	FlushBytes  int
+	FlushEvery  time.Duration
+	QueueSize   int
+	MaxRetries  int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+}
+
+// IndexerStats are cumulative counters for the demo summary
+type IndexerStats struct {
+	Indexed  uint64         `json:"indexed"`
+	Failed   uint64         `json:"failed"`
+	Dropped  uint64         `json:"dropped_queue_full"`
+	Retried  uint64         `json:"retried_docs"`
+	Requests uint64         `json:"bulk_requests"`
+	Rejected uint64         `json:"rejected_429"`
+	ByIndex  map[string]int `json:"by_index"`
+}
+
+type bulkDoc struct {
+	index string
+	body  []byte
+}
+
+// BulkIndexer is a zap sink that bulk-indexes each JSON log line into a daily index
+type BulkIndexer struct {
+	cfg    IndexerConfig
+	client *http.Client
+	logger core.Logger
+
+	queue   chan bulkDoc
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	indexed  atomic.Uint64
+	failed   atomic.Uint64
+	dropped  atomic.Uint64
+	retried  atomic.Uint64
+	requests atomic.Uint64
+	rejected atomic.Uint64
+
+	mu      sync.Mutex
+	byIndex map[string]int
+}
+
+// NewBulkIndexer starts the background indexer
+func NewBulkIndexer(cfg IndexerConfig, diagnostics core.Logger) *BulkIndexer {
+	b := &BulkIndexer{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		logger:  diagnostics,
+		queue:   make(chan bulkDoc, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		byIndex: make(map[string]int),
+	}
+	go b.run()
+	return b
+}
+
+// IndexFor returns the daily index name for a timestamp, e.g. logs-demo-2024.05.01
+func (b *BulkIndexer) IndexFor(ts time.Time) string {
+	return b.cfg.IndexPrefix + ts.UTC().Format("2006.01.02")
+}
+
+// Write queues one encoded log line; the index is chosen from the line's own timestamp
+func (b *BulkIndexer) Write(p []byte) (int, error) {
+	body := bytes.TrimRight(append([]byte(nil), p...), "\n")
+
+	ts := time.Now()
+	var fields struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if json.Unmarshal(body, &fields) == nil {
+		if parsed, err := time.Parse(time.RFC3339Nano, fields.Timestamp); err == nil {
+			ts = parsed
+		}
+	}
+
+	select {
+	case b.queue <- bulkDoc{index: b.IndexFor(ts), body: body}:
+	default:
+		if dropped := b.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			b.logger.Warnw("Indexer queue full, dropping log lines",
+				"queue_size", b.cfg.QueueSize,
+				"dropped_total", dropped,
+			)
+		}
+	}
+	return len(p), nil
+}
+
+// Sync blocks until everything queued so far has been indexed or given up on
+func (b *BulkIndexer) Sync() error {
+	ack := make(chan struct{})
+	select {
+	case b.flushCh <- ack:
+		<-ack
+	case <-b.stopped:
+	}
+	return nil
+}
+
+// Close flushes and stops the indexer
+func (b *BulkIndexer) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+		<-b.stopped
+	})
+	return nil
+}
+
+// Stats returns a snapshot of the counters
+func (b *BulkIndexer) Stats() IndexerStats {
+	b.mu.Lock()
+	byIndex := make(map[string]int, len(b.byIndex))
+	for index, n := range b.byIndex {
+		byIndex[index] = n
+	}
+	b.mu.Unlock()
+
+	return IndexerStats{
+		Indexed:  b.indexed.Load(),
+		Failed:   b.failed.Load(),
+		Dropped:  b.dropped.Load(),
+		Retried:  b.retried.Load(),
+		Requests: b.requests.Load(),
+		Rejected: b.rejected.Load(),
+		ByIndex:  byIndex,
+	}
+}
+
+func (b *BulkIndexer) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.cfg.FlushEvery)
+	defer ticker.Stop()
+
+	var (
+		batch []bulkDoc
+		size  int
+	)
+	add := func(doc bulkDoc) {
+		batch = append(batch, doc)
+		size += len(doc.body)
+		if len(batch) >= b.cfg.FlushDocs || size >= b.cfg.FlushBytes {
+			b.flush(batch)
+			batch, size = nil, 0
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case doc := <-b.queue:
+				add(doc)
+			default:
+				if len(batch) > 0 {
+					b.flush(batch)
+					batch, size = nil, 0
+				}
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case doc := <-b.queue:
+			add(doc)
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(batch)
+				batch, size = nil, 0
+			}
+		case ack := <-b.flushCh:
+			drain()
+			close(ack)
+		case <-b.done:
+			drain()
+			return
+		}
+	}
+}
+
+// bulkResponse is the subset of the _bulk response we need
+type bulkResponse struct {
+	Took   int  `json:"took"`
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Index  string `json:"_index"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// flush sends a bulk request; rejected (429) items are retried with backoff, others are final
+func (b *BulkIndexer) flush(docs []bulkDoc) {
+	pending := docs
+	backoff := b.cfg.MinBackoff
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		start := time.Now()
+		resp, status, err := b.send(pending)
+		b.requests.Add(1)
+
+		var (
+			retry  []bulkDoc
+			reason string
+		)
+		switch {
+		case err != nil && (status == 0 || status == http.StatusTooManyRequests || status >= 500):
+			// The whole request was rejected or never arrived; retry every document
+			reason = "request_failed"
+			if status == http.StatusTooManyRequests {
+				b.rejected.Add(1)
+				reason = "request_429"
+			}
+			retry = pending
+		case err != nil:
+			b.failed.Add(uint64(len(pending)))
+			b.logger.Errorw("Bulk request failed, documents dropped",
+				"docs", len(pending),
+				"status", status,
+				"error", err.Error(),
+			)
+			return
+		default:
+			retry = b.collectItemResults(pending, resp, attempt)
+			reason = "items_429"
+		}
+
+		if len(retry) == 0 {
+			b.logger.Debugw("Bulk request completed",
+				"docs", len(pending),
+				"attempt", attempt,
+				"took_ms", resp.Took,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			return
+		}
+
+		if attempt > b.cfg.MaxRetries {
+			b.failed.Add(uint64(len(retry)))
+			b.logger.Errorw("Bulk retries exhausted, documents dropped",
+				"docs", len(retry),
+				"attempt", attempt,
+				"max_retries", b.cfg.MaxRetries,
+			)
+			return
+		}
+
+		// Full jitter keeps many writers from retrying in lockstep against a busy cluster
+		wait := time.Duration(rng.Int63n(int64(backoff)) + int64(backoff)/2)
+		b.retried.Add(uint64(len(retry)))
+		logFields := []interface{}{
+			"reason", reason,
+			"docs_retrying", len(retry),
+			"docs_in_request", len(pending),
+			"attempt", attempt,
+			"backoff", wait.Round(time.Millisecond).String(),
+			"queue_depth", len(b.queue),
+		}
+		if err != nil {
+			logFields = append(logFields, "status", status, "error", err.Error())
+		}
+		b.logger.Warnw("Bulk indexing backing off", logFields...)
+
+		time.Sleep(wait)
+		backoff *= 2
+		if backoff > b.cfg.MaxBackoff {
+			backoff = b.cfg.MaxBackoff
+		}
+		pending = retry
+	}
+}
+
+// collectItemResults counts per-item outcomes and returns the items worth retrying
+func (b *BulkIndexer) collectItemResults(pending []bulkDoc, resp *bulkResponse, attempt int) []bulkDoc {
+	var retry []bulkDoc
+	rejected := 0
+
+	for i, item := range resp.Items {
+		if i >= len(pending) {
+			break
+		}
+		result := item["index"]
+
+		switch {
+		case result.Status/100 == 2:
+			b.indexed.Add(1)
+			b.mu.Lock()
+			b.byIndex[result.Index]++
+			b.mu.Unlock()
+		case result.Status == http.StatusTooManyRequests:
+			rejected++
+			retry = append(retry, pending[i])
+		default:
+			b.failed.Add(1)
+			reason := ""
+			errType := ""
+			if result.Error != nil {
+				errType, reason = result.Error.Type, result.Error.Reason
+			}
+			b.logger.Errorw("Document rejected by Elasticsearch",
+				"index", result.Index,
+				"status", result.Status,
+				"error_type", errType,
+				"reason", reason,
+				"attempt", attempt,
+			)
+		}
+	}
+
+	if rejected > 0 {
+		b.rejected.Add(uint64(rejected))
+	}
+	return retry
+}
+
+func (b *BulkIndexer) send(docs []bulkDoc) (*bulkResponse, int, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		fmt.Fprintf(&body, `{"index":{"_index":%q}}`+"\n", doc.index)
+		body.Write(doc.body)
+		body.WriteByte('\n')
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+	defer cancel()
+
+	url := strings.TrimRight(b.cfg.BaseURL, "/") + "/_bulk"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, resp.StatusCode, fmt.Errorf("bulk returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var parsed bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	return &parsed, resp.StatusCode, nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.uber.org/zap"
+)
+
+const (
+	templateName = "logs-demo"
+	indexPrefix  = "logs-demo-"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Elasticsearch Bulk Indexing Demo ===")
+	console.Println("Daily indices, index template creation and 429 backoff")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Diagnostics about indexing go to stderr and never through the indexer itself
+	diagnostics, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("INDEXER_LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stderr"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "es-indexer",
+		},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", diagnostics.Flush)
+	defer crash.Recover(diagnostics)
+	defer resusage.FromEnv(diagnostics)()
+	defer pidfile.FromEnv(diagnostics)()
+
+	esURL := os.Getenv("ES_URL")
+	var mock *MockElasticsearch
+	if esURL == "" {
+		mock, err = StartMockElasticsearch("127.0.0.1:0")
+		if err != nil {
+			crash.Fatal(diagnostics, "Failed to start mock Elasticsearch", "error", err.Error())
+		}
+		defer mock.Close()
+		esURL = mock.URL()
+		diagnostics.Infow("Started in-process mock Elasticsearch (429 on every 5th bulk and some items)", "url", esURL)
+	}
+
+	ctx := context.Background()
+	if err := EnsureIndexTemplate(ctx, esURL, templateName, indexPrefix+"*", diagnostics); err != nil {
+		crash.Fatal(diagnostics, "Failed to install index template", "url", esURL, "error", err.Error())
+	}
+
+	indexer := NewBulkIndexer(IndexerConfig{
+		BaseURL:     esURL,
+		IndexPrefix: indexPrefix,
+		FlushDocs:   getIntEnv("FLUSH_DOCS", 500),
+		FlushBytes:  5 << 20,
+		FlushEvery:  time.Second,
+		QueueSize:   getIntEnv("QUEUE_SIZE", 10000),
+		MaxRetries:  6,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+		Timeout:     10 * time.Second,
+	}, diagnostics)
+
+	if err := zap.RegisterSink("elasticsearch", func(*url.URL) (zap.Sink, error) { return indexer, nil }); err != nil {
+		crash.Fatal(diagnostics, "Failed to register Elasticsearch sink", "error", err.Error())
+	}
+	crash.OnExit("Elasticsearch sink", indexer.Close)
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"elasticsearch://bulk"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+	cliflags.Apply(logOption)
+	appLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create application logger", "error", err.Error())
+	}
+	crash.OnExit("application logger", appLogger.Flush)
+
+	sanitize.Startup(diagnostics, logOption, "es_url", esURL, "index_prefix", indexPrefix)
+
+	start := time.Now()
+	backfilled := backfill(indexer, getIntEnv("BACKFILL", 300))
+	live := generateTraffic(appLogger, getIntEnv("RATE", 600), getDurationEnv("DURATION", 3*time.Second))
+
+	appLogger.Flush()
+	indexer.Close()
+
+	stats := indexer.Stats()
+	summary := []interface{}{
+		"backfilled", backfilled,
+		"live", live,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"stats", stats,
+	}
+	if mock != nil {
+		summary = append(summary, "cluster_docs", mock.Docs())
+	}
+	diagnostics.Infow("Indexing finished", summary...)
+
+	fmt.Fprintf(os.Stderr, "\nindexed=%d failed=%d retried=%d rejected_429=%d requests=%d\n",
+		stats.Indexed, stats.Failed, stats.Retried, stats.Rejected, stats.Requests)
+	for index, n := range stats.ByIndex {
+		fmt.Fprintf(os.Stderr, "  %s: %d\n", index, n)
+	}
+}
+
+// backfill replays older records straight into the indexer so they land in previous days' indices,
+// plus one malformed line to show a non-retryable item error
+func backfill(indexer *BulkIndexer, n int) int {
+	for i := 0; i < n; i++ {
+		ts := time.Now().Add(-time.Duration(1+i%2) * 24 * time.Hour).Add(-time.Duration(i) * time.Second)
+		line, _ := json.Marshal(map[string]interface{}{
+			"timestamp":    ts.UTC().Format(time.RFC3339Nano),
+			"level":        "info",
+			"message":      "Backfilled request",
+			"service.name": "legacy-gateway",
+			"request_id":   fmt.Sprintf("bf-%d", i),
+			"status":       200,
+		})
+		indexer.Write(line)
+	}
+	indexer.Write([]byte(`{"timestamp":"` + time.Now().UTC().Format(time.RFC3339Nano) + `","message": truncated`))
+	return n + 1
+}
+
+// generateTraffic logs a steady stream of events; messages vary to stay under zap's sampling threshold
+func generateTraffic(appLogger core.Logger, rate int, duration time.Duration) int {
+	events := []string{
+		"Order created", "Order paid", "Cart updated", "User signed in",
+		"Search executed", "Inventory checked", "Coupon applied", "Profile viewed",
+	}
+
+	perTick := rate / 100
+	if perTick < 1 {
+		perTick = 1
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	written := 0
+	for deadline := time.Now().Add(duration); time.Now().Before(deadline); {
+		<-ticker.C
+		for i := 0; i < perTick; i++ {
+			written++
+			latency := rng.Intn(300)
+			message := events[rng.Intn(len(events))]
+			if latency > 280 {
+				appLogger.Warnw(message+" slowly", "request_id", written, "status", 200, "latency_ms", latency)
+				continue
+			}
+			appLogger.Infow(message, "request_id", written, "status", 200, "latency_ms", latency)
+		}
+	}
+	return written
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MockElasticsearch implements just enough of _index_template and _bulk to exercise the indexer.
+// It rejects whole requests and individual items with 429 the way a saturated write thread pool does.
+type MockElasticsearch struct {
+	listener net.Listener
+	server   *http.Server
+
+	requests atomic.Uint64
+
+	mu        sync.Mutex
+	templates map[string]bool
+	docs      map[string]int
+}
+
+// StartMockElasticsearch listens on addr
+func StartMockElasticsearch(addr string) (*MockElasticsearch, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockElasticsearch{
+		listener:  listener,
+		templates: make(map[string]bool),
+		docs:      make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_index_template/", m.handleTemplate)
+	mux.HandleFunc("/_bulk", m.handleBulk)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// URL returns the base URL of the mock cluster
+func (m *MockElasticsearch) URL() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+// Docs returns indexed document counts per index
+func (m *MockElasticsearch) Docs() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]int, len(m.docs))
+	for index, n := range m.docs {
+		result[index] = n
+	}
+	return result
+}
+
+// Close stops the server
+func (m *MockElasticsearch) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockElasticsearch) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var template map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	m.templates[strings.TrimPrefix(r.URL.Path, "/_index_template/")] = true
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"acknowledged":true}`))
+}
+
+func (m *MockElasticsearch) handleBulk(w http.ResponseWriter, r *http.Request) {
+	n := m.requests.Add(1)
+	start := time.Now()
+	time.Sleep(20 * time.Millisecond)
+
+	// Every 5th request is rejected outright
+	if n%5 == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"type":"es_rejected_execution_exception","reason":"rejected execution of coordinating operation"},"status":429}`))
+		return
+	}
+
+	type itemResult struct {
+		Index  string            `json:"_index"`
+		Status int               `json:"status"`
+		Error  map[string]string `json:"error,omitempty"`
+	}
+	var items []map[string]itemResult
+	hasErrors := false
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for i := 0; scanner.Scan(); i++ {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+			} `json:"index"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			http.Error(w, "malformed bulk body", http.StatusBadRequest)
+			return
+		}
+		index := action.Index.Index
+
+		var doc map[string]interface{}
+		result := itemResult{Index: index, Status: http.StatusCreated}
+		switch {
+		case json.Unmarshal(scanner.Bytes(), &doc) != nil:
+			result.Status = http.StatusBadRequest
+			result.Error = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+		case (int(n)+i)%13 == 0:
+			// Some items hit a full write queue even when the request is accepted
+			result.Status = http.StatusTooManyRequests
+			result.Error = map[string]string{"type": "es_rejected_execution_exception", "reason": "rejected execution of write operation"}
+		default:
+			m.mu.Lock()
+			m.docs[index]++
+			m.mu.Unlock()
+		}
+		if result.Status/100 != 2 {
+			hasErrors = true
+		}
+		items = append(items, map[string]itemResult{"index": result})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"took":   time.Since(start).Milliseconds(),
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// indexTemplate maps the logger's standard fields; other strings default to keyword
+func indexTemplate(pattern string) map[string]interface{} {
+	return map[string]interface{}{
+		"index_patterns": []string{pattern},
+		"priority":       200,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"number_of_shards":   1,
+				"number_of_replicas": 0,
+				"refresh_interval":   "5s",
+			},
+			"mappings": map[string]interface{}{
+				"dynamic_templates": []interface{}{
+					map[string]interface{}{
+						"strings_as_keyword": map[string]interface{}{
+							"match_mapping_type": "string",
+							"mapping":            map[string]interface{}{"type": "keyword", "ignore_above": 1024},
+						},
+					},
+				},
+				"properties": map[string]interface{}{
+					"timestamp":  map[string]interface{}{"type": "date"},
+					"level":      map[string]interface{}{"type": "keyword"},
+					"message":    map[string]interface{}{"type": "text", "fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}}},
+					"caller":     map[string]interface{}{"type": "keyword"},
+					"stacktrace": map[string]interface{}{"type": "text", "index": false},
+					"service": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"name":    map[string]interface{}{"type": "keyword"},
+							"version": map[string]interface{}{"type": "keyword"},
+						},
+					},
+					"latency_ms": map[string]interface{}{"type": "long"},
+					"status":     map[string]interface{}{"type": "short"},
+				},
+			},
+		},
+	}
+}
+
+// EnsureIndexTemplate installs the composable index template before any daily index is created
+func EnsureIndexTemplate(ctx context.Context, baseURL, name, pattern string, diagnostics core.Logger) error {
+	body, err := json.Marshal(indexTemplate(pattern))
+	if err != nil {
+		return fmt.Errorf("failed to encode index template: %w", err)
+	}
+
+	url := strings.TrimRight(baseURL, "/") + "/_index_template/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put index template: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("put index template returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	diagnostics.Infow("Index template installed",
+		"template", name,
+		"index_pattern", pattern,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Event Sourcing Demo ===")
+	console.Println("Append-only event log with projections rebuilt on startup")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "slog",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	storePath := filepath.Join("data", "events.jsonl")
+	store, err := OpenEventStore(storePath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to open event store", "path", storePath, "error", err.Error())
+	}
+	defer store.Close()
+
+	serviceLogger.Infow("Event store opened", "path", storePath, "head", store.Head())
+
+	// Rebuild the read model from the full log before serving queries
+	projectionLogger := serviceLogger.With("component", "projection", "projection", "account_balances")
+	projection := NewBalanceProjection(store, projectionLogger, 50*time.Millisecond)
+	if err := projection.Rebuild(100); err != nil {
+		crash.Fatal(serviceLogger, "Projection rebuild failed", "error", err.Error())
+	}
+
+	// Background work and the server both stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go projection.Run(ctx, 10)
+	go projection.ReportLag(ctx, 2*time.Second, 500*time.Millisecond)
+
+	commands := &AccountCommands{
+		store:  store,
+		logger: serviceLogger.With("component", "command-handler"),
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.POST("/accounts/:id/open", func(c *gin.Context) {
+		event, err := commands.Open(c.Param("id"))
+		respond(c, event, err)
+	})
+
+	r.POST("/accounts/:id/deposit", func(c *gin.Context) {
+		var req amountData
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		event, err := commands.Deposit(c.Param("id"), req.Amount)
+		respond(c, event, err)
+	})
+
+	r.POST("/accounts/:id/withdraw", func(c *gin.Context) {
+		var req amountData
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		event, err := commands.Withdraw(c.Param("id"), req.Amount)
+		respond(c, event, err)
+	})
+
+	r.GET("/accounts/:id", func(c *gin.Context) {
+		account, ok := projection.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"account": account, "projection": projection.Lag()})
+	})
+
+	r.GET("/accounts/:id/events", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"events": store.Stream(c.Param("id"))})
+	})
+
+	r.GET("/projections", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"account_balances": projection.Lag()})
+	})
+
+	r.POST("/projections/rebuild", func(c *gin.Context) {
+		logger := logcontext.MustFromGin(c)
+		logger.Infow("Manual projection rebuild requested")
+		if err := projection.Rebuild(100); err != nil {
+			logger.Errorw("Manual projection rebuild failed", "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "rebuilt", "position": projection.Position()})
+	})
+
+	port := getEnvOrDefault("PORT", "8091")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port)
+	serviceLogger.Infow("Starting event store service",
+		"port", port,
+		"head", store.Head(),
+		"endpoints", []string{"/accounts/:id/open", "/accounts/:id/deposit", "/accounts/:id/withdraw", "/accounts/:id", "/projections", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these endpoints:")
+	console.Printf("  curl -X POST http://localhost:%s/accounts/acc-1/open\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/accounts/acc-1/deposit -d '{\"amount\":100}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/accounts/acc-1/withdraw -d '{\"amount\":30}'\n", port)
+	console.Printf("  curl http://localhost:%s/accounts/acc-1\n", port)
+	console.Println("\nRestart the demo to watch the projection being rebuilt from data/events.jsonl")
+
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// AccountCommands validates commands against the event stream and appends new events.
+// Commands on one account run one at a time, so two withdrawals cannot both pass the
+// balance check before either is appended.
+type AccountCommands struct {
+	store  *EventStore
+	logger core.Logger
+
+	mu sync.Mutex
+	// locks holds one command lock per account, created on first use
+	locks map[string]*sync.Mutex
+}
+
+// lock takes the command lock of accountID and returns the function releasing it
+func (a *AccountCommands) lock(accountID string) func() {
+	a.mu.Lock()
+	if a.locks == nil {
+		a.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := a.locks[accountID]
+	if !ok {
+		l = &sync.Mutex{}
+		a.locks[accountID] = l
+	}
+	a.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// Open appends an AccountOpened event if the stream does not exist yet
+func (a *AccountCommands) Open(accountID string) (Event, error) {
+	defer a.lock(accountID)()
+	if len(a.store.Stream(accountID)) > 0 {
+		return Event{}, fmt.Errorf("account %s already exists", accountID)
+	}
+	return a.append(accountID, "AccountOpened", map[string]string{"account_id": accountID})
+}
+
+// Deposit appends a MoneyDeposited event
+func (a *AccountCommands) Deposit(accountID string, amount int64) (Event, error) {
+	if amount <= 0 {
+		return Event{}, fmt.Errorf("amount must be positive")
+	}
+	defer a.lock(accountID)()
+	if len(a.store.Stream(accountID)) == 0 {
+		return Event{}, fmt.Errorf("account %s does not exist", accountID)
+	}
+	return a.append(accountID, "MoneyDeposited", amountData{Amount: amount})
+}
+
+// Withdraw appends a MoneyWithdrawn event when the stream balance allows it
+func (a *AccountCommands) Withdraw(accountID string, amount int64) (Event, error) {
+	if amount <= 0 {
+		return Event{}, fmt.Errorf("amount must be positive")
+	}
+
+	// The check and the append happen under the account's lock
+	defer a.lock(accountID)()
+	stream := a.store.Stream(accountID)
+	if len(stream) == 0 {
+		return Event{}, fmt.Errorf("account %s does not exist", accountID)
+	}
+
+	// Validate against the authoritative stream rather than the (possibly lagging) projection
+	var balance int64
+	for _, event := range stream {
+		var data amountData
+		_ = json.Unmarshal(event.Data, &data)
+		switch event.Type {
+		case "MoneyDeposited":
+			balance += data.Amount
+		case "MoneyWithdrawn":
+			balance -= data.Amount
+		}
+	}
+	if balance < amount {
+		a.logger.Warnw("Withdrawal rejected",
+			"stream_id", accountID,
+			"amount", amount,
+			"balance", balance,
+			"reason", "insufficient_funds",
+		)
+		return Event{}, fmt.Errorf("insufficient funds: balance %d, requested %d", balance, amount)
+	}
+
+	return a.append(accountID, "MoneyWithdrawn", amountData{Amount: amount})
+}
+
+func (a *AccountCommands) append(streamID, eventType string, data interface{}) (Event, error) {
+	start := time.Now()
+	event, err := a.store.Append(streamID, eventType, data)
+	if err != nil {
+		a.logger.Errorw("Event append failed",
+			"stream_id", streamID,
+			"event_type", eventType,
+			"error", err.Error(),
+		)
+		return Event{}, err
+	}
+
+	a.logger.Infow("Event appended",
+		"sequence", event.Sequence,
+		"stream_id", streamID,
+		"event_type", eventType,
+		"payload_bytes", len(event.Data),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return event, nil
+}
+
+func respond(c *gin.Context, event Event, err error) {
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"event": event})
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// AccountBalance is the read model maintained by BalanceProjection
+type AccountBalance struct {
+	AccountID    string    `json:"account_id"`
+	Balance      int64     `json:"balance"`
+	Transactions int       `json:"transactions"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// amountData is the payload of deposit and withdrawal events
+type amountData struct {
+	Amount int64 `json:"amount"`
+}
+
+// BalanceProjection folds account events into current balances
+type BalanceProjection struct {
+	// applying is held while events are read and applied, by Rebuild for the whole replay
+	// and by Run for each catch-up pass, so a rebuild never resets the position under Run
+	applying sync.Mutex
+	mu       sync.RWMutex
+	store    *EventStore
+	logger   core.Logger
+	position uint64
+	accounts map[string]*AccountBalance
+
+	// applyDelay simulates a slow read-model writer so lag becomes visible
+	applyDelay time.Duration
+}
+
+// NewBalanceProjection creates an empty projection over the given store
+func NewBalanceProjection(store *EventStore, logger core.Logger, applyDelay time.Duration) *BalanceProjection {
+	return &BalanceProjection{
+		store:      store,
+		logger:     logger,
+		accounts:   make(map[string]*AccountBalance),
+		applyDelay: applyDelay,
+	}
+}
+
+// Rebuild replays the entire event log from the beginning, logging progress. Run pauses
+// until it is done and then carries on from the rebuilt position.
+func (p *BalanceProjection) Rebuild(batchSize int) error {
+	p.applying.Lock()
+	defer p.applying.Unlock()
+
+	p.mu.Lock()
+	p.position = 0
+	p.accounts = make(map[string]*AccountBalance)
+	p.mu.Unlock()
+
+	head := p.store.Head()
+	start := time.Now()
+	p.logger.Infow("Projection rebuild started", "target_sequence", head, "batch_size", batchSize)
+
+	for {
+		batch := p.store.ReadFrom(p.Position(), batchSize)
+		if len(batch) == 0 {
+			break
+		}
+		for _, event := range batch {
+			if err := p.apply(event); err != nil {
+				return err
+			}
+		}
+
+		position := p.Position()
+		percent := 100.0
+		if head > 0 {
+			percent = float64(position) * 100 / float64(head)
+		}
+		p.logger.Infow("Projection replay progress",
+			"position", position,
+			"target_sequence", head,
+			"percent", fmt.Sprintf("%.1f", percent),
+		)
+	}
+
+	p.logger.Infow("Projection rebuild completed",
+		"position", p.Position(),
+		"accounts", p.AccountCount(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+// Run keeps the projection caught up with new appends until ctx is cancelled. An event
+// that fails to apply is logged and retried on the next pass, so the projection stalls
+// instead of stopping for good.
+func (p *BalanceProjection) Run(ctx context.Context, batchSize int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.store.Notifications():
+		case <-time.After(time.Second):
+		}
+		p.catchUp(batchSize)
+	}
+}
+
+// catchUp applies the events after the current position, stopping at the first failure
+func (p *BalanceProjection) catchUp(batchSize int) {
+	p.applying.Lock()
+	defer p.applying.Unlock()
+
+	for {
+		batch := p.store.ReadFrom(p.Position(), batchSize)
+		if len(batch) == 0 {
+			return
+		}
+		for _, event := range batch {
+			time.Sleep(p.applyDelay)
+			if err := p.apply(event); err != nil {
+				p.logger.Errorw("Projection failed to apply event, retrying",
+					"sequence", event.Sequence,
+					"event_type", event.Type,
+					"error", err.Error(),
+				)
+				return
+			}
+		}
+	}
+}
+
+// ReportLag periodically logs how far the projection is behind the log head
+func (p *BalanceProjection) ReportLag(ctx context.Context, interval, warnThreshold time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lag := p.Lag()
+		if lag.Events == 0 {
+			p.logger.Debugw("Projection caught up", "position", lag.Position)
+			continue
+		}
+
+		fields := []interface{}{
+			"position", lag.Position,
+			"head", lag.Head,
+			"lag_events", lag.Events,
+			"lag_ms", lag.Age.Milliseconds(),
+		}
+		if lag.Age > warnThreshold {
+			p.logger.Warnw("Projection lag above threshold", append(fields, "threshold_ms", warnThreshold.Milliseconds())...)
+		} else {
+			p.logger.Infow("Projection lag", fields...)
+		}
+	}
+}
+
+// ProjectionLag describes how far a projection trails the event log
+type ProjectionLag struct {
+	Position uint64        `json:"position"`
+	Head     uint64        `json:"head"`
+	Events   uint64        `json:"lag_events"`
+	Age      time.Duration `json:"lag_ns"`
+}
+
+// Lag computes the current lag in events and in time since the oldest unapplied event
+func (p *BalanceProjection) Lag() ProjectionLag {
+	position := p.Position()
+	head := p.store.Head()
+
+	lag := ProjectionLag{Position: position, Head: head}
+	if head > position {
+		lag.Events = head - position
+		if next := p.store.ReadFrom(position, 1); len(next) == 1 {
+			lag.Age = time.Since(next[0].RecordedAt)
+		}
+	}
+	return lag
+}
+
+func (p *BalanceProjection) apply(event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Sequence != p.position+1 {
+		return fmt.Errorf("out of order event: expected %d, got %d", p.position+1, event.Sequence)
+	}
+
+	account, ok := p.accounts[event.StreamID]
+	if !ok {
+		account = &AccountBalance{AccountID: event.StreamID}
+		p.accounts[event.StreamID] = account
+	}
+
+	switch event.Type {
+	case "AccountOpened":
+	case "MoneyDeposited", "MoneyWithdrawn":
+		var data amountData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return fmt.Errorf("invalid %s payload at sequence %d: %w", event.Type, event.Sequence, err)
+		}
+		if event.Type == "MoneyDeposited" {
+			account.Balance += data.Amount
+		} else {
+			account.Balance -= data.Amount
+		}
+		account.Transactions++
+	default:
+		return fmt.Errorf("unknown event type %q at sequence %d", event.Type, event.Sequence)
+	}
+
+	account.UpdatedAt = event.RecordedAt
+	p.position = event.Sequence
+	return nil
+}
+
+// Get returns a copy of an account's projected balance
+func (p *BalanceProjection) Get(accountID string) (AccountBalance, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	account, ok := p.accounts[accountID]
+	if !ok {
+		return AccountBalance{}, false
+	}
+	return *account, true
+}
+
+// Position returns the sequence number of the last applied event
+func (p *BalanceProjection) Position() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.position
+}
+
+// AccountCount returns the number of projected accounts
+func (p *BalanceProjection) AccountCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.accounts)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is a single immutable fact in the append-only log
+type Event struct {
+	Sequence   uint64          `json:"sequence"`
+	StreamID   string          `json:"stream_id"`
+	Type       string          `json:"type"`
+	Data       json.RawMessage `json:"data"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// EventStore is an append-only event log backed by a JSON lines file
+type EventStore struct {
+	mu     sync.RWMutex
+	path   string
+	file   *os.File
+	events []Event
+	notify chan struct{}
+}
+
+// OpenEventStore loads existing events and opens the file for appending
+func OpenEventStore(path string) (*EventStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	store := &EventStore{
+		path:   path,
+		notify: make(chan struct{}, 1),
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store for append: %w", err)
+	}
+	store.file = file
+
+	return store, nil
+}
+
+func (s *EventStore) load() error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("corrupt event at line %d: %w", len(s.events)+1, err)
+		}
+		if event.Sequence != uint64(len(s.events)+1) {
+			return fmt.Errorf("sequence gap at line %d: got %d", len(s.events)+1, event.Sequence)
+		}
+		s.events = append(s.events, event)
+	}
+	return scanner.Err()
+}
+
+// Append records a new event and returns it with its assigned sequence number
+func (s *EventStore) Append(streamID, eventType string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := Event{
+		Sequence:   uint64(len(s.events) + 1),
+		StreamID:   streamID,
+		Type:       eventType,
+		Data:       payload,
+		RecordedAt: time.Now().UTC(),
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return Event{}, fmt.Errorf("failed to append event: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return Event{}, fmt.Errorf("failed to sync store: %w", err)
+	}
+
+	s.events = append(s.events, event)
+
+	// Wake up projections without blocking the writer
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return event, nil
+}
+
+// ReadFrom returns up to limit events with sequence greater than after
+func (s *EventStore) ReadFrom(after uint64, limit int) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if after >= uint64(len(s.events)) {
+		return nil
+	}
+	end := len(s.events)
+	if limit > 0 && int(after)+limit < end {
+		end = int(after) + limit
+	}
+
+	result := make([]Event, end-int(after))
+	copy(result, s.events[after:end])
+	return result
+}
+
+// Stream returns all events for a single stream
+func (s *EventStore) Stream(streamID string) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Event
+	for _, event := range s.events {
+		if event.StreamID == streamID {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// Head returns the sequence number of the latest event
+func (s *EventStore) Head() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return uint64(len(s.events))
+}
+
+// Notifications signals whenever new events are appended
+func (s *EventStore) Notifications() <-chan struct{} {
+	return s.notify
+}
+
+// Close closes the underlying file
+func (s *EventStore) Close() error {
+	return s.file.Close()
+}
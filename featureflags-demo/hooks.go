@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/logger/core"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// LoggingHook logs every flag evaluation: which flag, which variant was served and why
+type LoggingHook struct {
+	openfeature.UnimplementedHook
+	logger core.Logger
+}
+
+// NewLoggingHook logs through logger unless the evaluation context carries a request logger
+func NewLoggingHook(logger core.Logger) *LoggingHook {
+	return &LoggingHook{logger: logger}
+}
+
+// After runs for every successful evaluation, including disabled flags served the default
+func (h *LoggingHook) After(ctx context.Context, hookContext openfeature.HookContext, details openfeature.InterfaceEvaluationDetails, _ openfeature.HookHints) error {
+	h.from(ctx).Infow("Flag evaluated",
+		"flag", hookContext.FlagKey(),
+		"flag_type", hookContext.FlagType().String(),
+		"variant", details.Variant,
+		"reason", string(details.Reason),
+		"value", details.Value,
+		"targeting_key", hookContext.EvaluationContext().TargetingKey(),
+		"provider", hookContext.ProviderMetadata().Name,
+		"flag_source", details.FlagMetadata["source"],
+	)
+	return nil
+}
+
+// Error runs when the provider could not resolve the flag and the caller's default was served
+func (h *LoggingHook) Error(ctx context.Context, hookContext openfeature.HookContext, err error, _ openfeature.HookHints) {
+	h.from(ctx).Warnw("Flag evaluation failed",
+		"flag", hookContext.FlagKey(),
+		"flag_type", hookContext.FlagType().String(),
+		"default_value", hookContext.DefaultValue(),
+		"targeting_key", hookContext.EvaluationContext().TargetingKey(),
+		"provider", hookContext.ProviderMetadata().Name,
+		"error", err.Error(),
+	)
+}
+
+func (h *LoggingHook) from(ctx context.Context) core.Logger {
+	if logger, ok := logcontext.Lookup(ctx); ok {
+		return logger
+	}
+	return h.logger
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// checkoutRequest is the body accepted by POST /checkout
+type checkoutRequest struct {
+	Items int `json:"items" binding:"required,min=1"`
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== OpenFeature Feature Flags Demo ===")
+	console.Println("File/ENV flag provider with every evaluation logged and a flag-gated checkout route")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer leakwatch.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	serviceLogger := baseLogger.With("component", "checkout")
+	flagLogger := baseLogger.With("component", "feature-flags")
+
+	flagsFile := cliflags.ConfigOr(getEnvOrDefault("FLAGS_FILE", "flags.yaml"))
+	provider, err := NewFileProvider(flagsFile)
+	if err != nil {
+		crash.Fatal(flagLogger, "Failed to load feature flags", "file", flagsFile, "error", err.Error())
+	}
+	if err := openfeature.SetProviderAndWait(provider); err != nil {
+		crash.Fatal(flagLogger, "Failed to register flag provider", "error", err.Error())
+	}
+	openfeature.AddHooks(NewLoggingHook(flagLogger))
+	client := openfeature.NewClient("checkout")
+	flagLogger.Infow("Feature flags loaded", "file", flagsFile, "provider", provider.Metadata().Name, "env_overrides", provider.Overrides())
+
+	// SIGHUP re-reads the file and the FLAG_* overrides without a restart
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			if err := provider.Reload(); err != nil {
+				flagLogger.Errorw("Feature flag reload failed, keeping previous flags", "file", flagsFile, "error", err.Error())
+				continue
+			}
+			flagLogger.Infow("Feature flags reloaded", "file", flagsFile, "env_overrides", provider.Overrides())
+		}
+	}()
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	// Handlers and flag evaluation hooks log through the request's child logger
+	r.Use(gin.Recovery(), logcontext.GinRequestLogger(serviceLogger))
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.POST("/checkout", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		log := logcontext.MustFromGin(c)
+		evalCtx := evaluationContext(c)
+
+		var req checkoutRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		maxItems := client.Int(ctx, "max-cart-items", 10, evalCtx)
+		if int64(req.Items) > maxItems {
+			log.Warnw("Cart exceeds item limit", "items", req.Items, "max_items", maxItems)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("at most %d items per order", maxItems)})
+			return
+		}
+
+		flow := "legacy"
+		if client.Boolean(ctx, "new-checkout", false, evalCtx) {
+			flow = "one-page"
+		}
+		shipping := []string{"standard"}
+		if client.Boolean(ctx, "express-shipping", false, evalCtx) {
+			shipping = append(shipping, "express")
+		}
+		banner := client.String(ctx, "checkout-banner", "", evalCtx)
+
+		log.Infow("Checkout completed", "flow", flow, "items", req.Items, "shipping_options", shipping)
+		c.JSON(http.StatusOK, gin.H{
+			"flow":             flow,
+			"items":            req.Items,
+			"shipping_options": shipping,
+			"banner":           banner,
+		})
+	})
+
+	// Evaluate every flag, or a single one, for the caller; unknown keys show the error path
+	r.GET("/flags", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		evalCtx := evaluationContext(c)
+		keys := []string{"new-checkout", "checkout-banner", "max-cart-items", "express-shipping"}
+		if key := c.Query("key"); key != "" {
+			keys = []string{key}
+		}
+		sort.Strings(keys)
+
+		result := make(gin.H, len(keys))
+		for _, key := range keys {
+			details, err := client.ObjectValueDetails(ctx, key, nil, evalCtx)
+			entry := gin.H{"value": details.Value, "variant": details.Variant, "reason": details.Reason}
+			if err != nil {
+				entry["error"] = string(details.ErrorCode)
+			}
+			result[key] = entry
+		}
+		c.JSON(http.StatusOK, result)
+	})
+
+	port := getEnvOrDefault("PORT", "8101")
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "flags_file", flagsFile)
+	serviceLogger.Infow("Starting feature flags demo server", "port", port)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl -X POST http://localhost:%s/checkout -H 'X-User-ID: u-1' -d '{\"items\":3}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/checkout -H 'X-User-ID: u-2' -H 'X-Plan: enterprise' -H 'X-Country: DE' -d '{\"items\":30}'\n", port)
+	console.Printf("  curl 'http://localhost:%s/flags?key=dark-mode' -H 'X-User-ID: u-1'\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// evaluationContext builds the OpenFeature context from request headers; the user ID is
+// the targeting key that percentage rollouts hash on
+func evaluationContext(c *gin.Context) openfeature.EvaluationContext {
+	return openfeature.NewEvaluationContext(c.GetHeader("X-User-ID"), map[string]any{
+		"plan":    headerOrDefault(c, "X-Plan", "free"),
+		"country": headerOrDefault(c, "X-Country", "US"),
+	})
+}
+
+func headerOrDefault(c *gin.Context, key, defaultValue string) string {
+	if value := c.GetHeader(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
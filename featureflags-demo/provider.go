@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"gopkg.in/yaml.v3"
+)
+
+// FlagFile is the layout of flags.yaml
+type FlagFile struct {
+	Flags map[string]Flag `yaml:"flags"`
+}
+
+// Flag is one flag definition: a set of named variants and the rules that pick one
+type Flag struct {
+	State          string                 `yaml:"state"`
+	Variants       map[string]interface{} `yaml:"variants"`
+	DefaultVariant string                 `yaml:"default_variant"`
+	Rules          []Rule                 `yaml:"rules"`
+}
+
+// Rule selects Variant when every Match attribute equals the evaluation context, or for
+// Percentage percent of targeting keys. Rules are tried in order.
+type Rule struct {
+	Variant    string            `yaml:"variant"`
+	Match      map[string]string `yaml:"match"`
+	Percentage int               `yaml:"percentage"`
+}
+
+// FileProvider is an OpenFeature provider backed by a YAML file, with per-flag
+// environment variable overrides
+type FileProvider struct {
+	path string
+
+	mu        sync.RWMutex
+	flags     map[string]Flag
+	overrides map[string]string
+}
+
+// NewFileProvider loads path and validates every flag
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload re-reads the file and the environment overrides
+func (p *FileProvider) Reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read flags: %w", err)
+	}
+	var file FlagFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	overrides := map[string]string{}
+	for key, flag := range file.Flags {
+		if _, ok := flag.Variants[flag.DefaultVariant]; !ok {
+			return fmt.Errorf("flag %s: default variant %q is not defined", key, flag.DefaultVariant)
+		}
+		for i, rule := range flag.Rules {
+			if _, ok := flag.Variants[rule.Variant]; !ok {
+				return fmt.Errorf("flag %s: rule %d uses undefined variant %q", key, i, rule.Variant)
+			}
+		}
+		if variant := os.Getenv(EnvOverrideKey(key)); variant != "" {
+			if _, ok := flag.Variants[variant]; !ok {
+				return fmt.Errorf("flag %s: %s=%q is not a defined variant", key, EnvOverrideKey(key), variant)
+			}
+			overrides[key] = variant
+		}
+	}
+
+	p.mu.Lock()
+	p.flags = file.Flags
+	p.overrides = overrides
+	p.mu.Unlock()
+	return nil
+}
+
+// Overrides returns the flags pinned by environment variables
+func (p *FileProvider) Overrides() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	result := make(map[string]string, len(p.overrides))
+	for key, variant := range p.overrides {
+		result[key] = variant
+	}
+	return result
+}
+
+// EnvOverrideKey maps a flag key to its override variable, new-checkout -> FLAG_NEW_CHECKOUT
+func EnvOverrideKey(flag string) string {
+	return "FLAG_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Metadata names the provider in evaluation details and hook contexts
+func (p *FileProvider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: "file"}
+}
+
+// Hooks returns no provider-level hooks
+func (p *FileProvider) Hooks() []openfeature.Hook {
+	return nil
+}
+
+// BooleanEvaluation resolves a boolean flag
+func (p *FileProvider) BooleanEvaluation(_ context.Context, flag string, defaultValue bool, evalCtx openfeature.FlattenedContext) openfeature.BoolResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if value == nil {
+		return openfeature.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	v, ok := value.(bool)
+	if !ok {
+		return openfeature.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, value)}
+	}
+	return openfeature.BoolResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// StringEvaluation resolves a string flag
+func (p *FileProvider) StringEvaluation(_ context.Context, flag string, defaultValue string, evalCtx openfeature.FlattenedContext) openfeature.StringResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if value == nil {
+		return openfeature.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	v, ok := value.(string)
+	if !ok {
+		return openfeature.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, value)}
+	}
+	return openfeature.StringResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// FloatEvaluation resolves a numeric flag; whole numbers in YAML are accepted
+func (p *FileProvider) FloatEvaluation(_ context.Context, flag string, defaultValue float64, evalCtx openfeature.FlattenedContext) openfeature.FloatResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if value == nil {
+		return openfeature.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	switch v := value.(type) {
+	case float64:
+		return openfeature.FloatResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+	case int:
+		return openfeature.FloatResolutionDetail{Value: float64(v), ProviderResolutionDetail: detail}
+	}
+	return openfeature.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, value)}
+}
+
+// IntEvaluation resolves an integer flag
+func (p *FileProvider) IntEvaluation(_ context.Context, flag string, defaultValue int64, evalCtx openfeature.FlattenedContext) openfeature.IntResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if value == nil {
+		return openfeature.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	v, ok := value.(int)
+	if !ok {
+		return openfeature.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, value)}
+	}
+	return openfeature.IntResolutionDetail{Value: int64(v), ProviderResolutionDetail: detail}
+}
+
+// ObjectEvaluation resolves a flag of any type
+func (p *FileProvider) ObjectEvaluation(_ context.Context, flag string, defaultValue any, evalCtx openfeature.FlattenedContext) openfeature.InterfaceResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if value == nil {
+		return openfeature.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	return openfeature.InterfaceResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// resolve picks the variant for flag. A nil value means the caller's default applies: the
+// detail then carries either an error or the DISABLED reason.
+func (p *FileProvider) resolve(key string, evalCtx openfeature.FlattenedContext) (interface{}, openfeature.ProviderResolutionDetail) {
+	p.mu.RLock()
+	flag, found := p.flags[key]
+	override, pinned := p.overrides[key]
+	p.mu.RUnlock()
+
+	if !found {
+		return nil, openfeature.ProviderResolutionDetail{
+			ResolutionError: openfeature.NewFlagNotFoundResolutionError(fmt.Sprintf("flag %s is not defined in %s", key, p.path)),
+			Reason:          openfeature.ErrorReason,
+		}
+	}
+	if pinned {
+		return flag.Variants[override], openfeature.ProviderResolutionDetail{
+			Reason:       openfeature.StaticReason,
+			Variant:      override,
+			FlagMetadata: openfeature.FlagMetadata{"source": "env", "env": EnvOverrideKey(key)},
+		}
+	}
+	if flag.State == "disabled" {
+		return nil, openfeature.ProviderResolutionDetail{Reason: openfeature.DisabledReason}
+	}
+
+	targetingKey, _ := evalCtx[openfeature.TargetingKey].(string)
+	for i, rule := range flag.Rules {
+		metadata := openfeature.FlagMetadata{"source": "file", "rule": i}
+		switch {
+		case len(rule.Match) > 0:
+			if matches(rule.Match, evalCtx) {
+				return flag.Variants[rule.Variant], openfeature.ProviderResolutionDetail{
+					Reason: openfeature.TargetingMatchReason, Variant: rule.Variant, FlagMetadata: metadata,
+				}
+			}
+		case rule.Percentage > 0:
+			if targetingKey == "" {
+				continue
+			}
+			if bucket(key, targetingKey) < rule.Percentage {
+				return flag.Variants[rule.Variant], openfeature.ProviderResolutionDetail{
+					Reason: openfeature.SplitReason, Variant: rule.Variant, FlagMetadata: metadata,
+				}
+			}
+		}
+	}
+
+	reason := openfeature.StaticReason
+	if len(flag.Rules) > 0 {
+		reason = openfeature.DefaultReason
+	}
+	return flag.Variants[flag.DefaultVariant], openfeature.ProviderResolutionDetail{
+		Reason:       reason,
+		Variant:      flag.DefaultVariant,
+		FlagMetadata: openfeature.FlagMetadata{"source": "file"},
+	}
+}
+
+func matches(match map[string]string, evalCtx openfeature.FlattenedContext) bool {
+	for attribute, expected := range match {
+		if fmt.Sprint(evalCtx[attribute]) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// bucket maps a user to 0-99, stable per flag so rollouts of different flags are independent
+func bucket(flag, targetingKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag + "/" + targetingKey))
+	return int(h.Sum32() % 100)
+}
+
+func typeMismatch(flag string, value interface{}) openfeature.ProviderResolutionDetail {
+	return openfeature.ProviderResolutionDetail{
+		ResolutionError: openfeature.NewTypeMismatchResolutionError(fmt.Sprintf("flag %s has a %T value", flag, value)),
+		Reason:          openfeature.ErrorReason,
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/fieldconv"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// output is one logger writing under one naming convention
+type output struct {
+	name   string
+	logger core.Logger
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Field Convention Demo ===")
+	console.Println("The same events written with the code's own names, then renamed to each convention at output time")
+	console.Println()
+
+	if err := fieldconv.RegisterSink(os.Stdout); err != nil {
+		crash.Fatal(nil, "Failed to register fieldconv sink", "error", err.Error())
+	}
+
+	outputs := []output{{name: "native", logger: newLogger("stdout")}}
+	for _, name := range strings.Split(getEnvOrDefault("CONVENTIONS", "otel,ecs"), ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := fieldconv.Lookup(name); !ok {
+			fmt.Fprintf(os.Stderr, "Skipping unknown convention %q\n", name)
+			continue
+		}
+		outputs = append(outputs, output{name: name, logger: newLogger(fieldconv.Scheme + "://" + name)})
+	}
+	defer func() {
+		for _, out := range outputs {
+			_ = out.logger.Flush()
+		}
+	}()
+	defer crash.Recover(outputs[0].logger)
+	defer resusage.FromEnv(outputs[0].logger)()
+	defer pidfile.FromEnv(outputs[0].logger)()
+	conventions := make([]string, 0, len(outputs)-1)
+	for _, out := range outputs[1:] {
+		conventions = append(conventions, out.name)
+	}
+	sanitize.Startup(outputs[0].logger, newLogOption("stdout"), "conventions", conventions)
+
+	traceID, spanID := "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	requestID := requestid.New(requestid.Request)
+
+	emit(outputs, "HTTP request", func(log core.Logger) {
+		log.Infow("Request completed",
+			"request_id", requestID,
+			"trace_id", traceID,
+			"span_id", spanID,
+			"method", "POST",
+			"route", "/orders/:id/pay",
+			"path", "/orders/1042/pay",
+			"status", 201,
+			"client_ip", "203.0.113.24",
+			"user_id", "user-7781",
+			"duration_ms", 84,
+		)
+	})
+
+	emit(outputs, "Error", func(log core.Logger) {
+		log.Errorw("Payment capture failed",
+			"request_id", requestID,
+			"trace_id", traceID,
+			"span_id", spanID,
+			"order_id", "1042",
+			"error", "card declined: insufficient funds",
+		)
+	})
+
+	emit(outputs, "Kubernetes placement", func(log core.Logger) {
+		log.Infow("Worker ready",
+			"k8s.pod.name", "orders-7c9d5b6f4-xk2lp",
+			"k8s.namespace.name", "shop",
+			"k8s.node.name", "node-pool-a-3",
+			"queue", "payments",
+		)
+	})
+}
+
+// emit logs one event through every output so the lines can be compared side by side
+func emit(outputs []output, title string, event func(core.Logger)) {
+	console.Printf("--- %s ---\n", title)
+	for _, out := range outputs {
+		event(out.logger)
+		_ = out.logger.Flush()
+	}
+	console.Println()
+}
+
+func newLogger(outputPath string) core.Logger {
+	log, err := logger.New(newLogOption(outputPath))
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit(outputPath+" logger", log.Flush)
+	return log
+}
+
+// newLogOption is the same for every output apart from its path
+func newLogOption(outputPath string) *option.LogOption {
+	versionInfo := version.Get()
+	return &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{outputPath},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
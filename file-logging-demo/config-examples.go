@@ -107,7 +107,7 @@ func developmentSetup() {
 	fmt.Println(`logOption := &option.LogOption{
     Engine:            "slog",          // Standard library
     Level:             "debug",         // Verbose for debugging
-    Format:            "console",       // Human-readable
+    Format:            devconsole.Format, // Colored key=value on stdout, console in the file
     OutputPaths:       []string{"stdout", "logs/dev.log"},
     Development:       true,            // Enable development features
     DisableCaller:     false,           // Show caller info
@@ -116,7 +116,8 @@ func developmentSetup() {
         ServiceName:       "dev-service",
         ServiceVersion:    "dev-build",
     },
-}`)
+}
+logger, err := devconsole.New(logOption, devconsole.ThemeFromEnv()) // LOG_THEME=dark, light or mono`)
 	fmt.Println("```")
 	fmt.Println()
 }
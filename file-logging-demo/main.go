@@ -9,45 +9,87 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/devconsole"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/outputlevels"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/go-example/pkg/traceparent"
 	"github.com/kart-io/logger"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 )
 
 func main() {
+	cliflags.Parse()
 	// Get version information
 	versionInfo := version.Get()
 
-	fmt.Println("=== File Logging Demo ===")
-	fmt.Printf("Service: %s\n", versionInfo.ServiceName)
-	fmt.Printf("Version: %s\n", versionInfo.GitVersion)
-	fmt.Printf("Build Date: %s\n", versionInfo.BuildDate)
-	fmt.Println()
+	console.Println("=== File Logging Demo ===")
+	console.Printf("Service: %s\n", versionInfo.ServiceName)
+	console.Printf("Version: %s\n", versionInfo.GitVersion)
+	console.Printf("Build Date: %s\n", versionInfo.BuildDate)
+	console.Println()
 
-	// Create logs directory if it doesn't exist
+	// Check every file the demos below write before any of them runs: the logs directory
+	// is created, and a file that cannot be written or rotated stops the demo here with one
+	// report instead of part way through
 	logsDir := "logs"
-	if err := os.MkdirAll(logsDir, 0755); err != nil {
-		panic(fmt.Sprintf("Failed to create logs directory: %v", err))
+	var logFiles []string
+	for _, name := range []string{"single.log", "multiple.log", "info.log", "error.log", "access.log", "application.log"} {
+		logFiles = append(logFiles, filepath.Join(logsDir, name))
 	}
+	outputs := outputcheck.Verify(context.Background(), &option.LogOption{OutputPaths: logFiles})
+
+	// Each demo below builds its own loggers; the startup configuration and resource usage go to stdout
+	usageOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+	usageLogger, err := logger.New(usageOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", usageLogger.Flush)
+	defer crash.Recover(usageLogger)
+	defer resusage.FromEnv(usageLogger)()
+	defer pidfile.FromEnv(usageLogger)()
+	outputs.Log(usageLogger)
+	sanitize.Startup(usageLogger, usageOption, "logs_dir", logsDir)
 
 	// Demo 1: Single file logging
-	fmt.Println("=== Demo 1: Single File Logging ===")
+	console.Println("=== Demo 1: Single File Logging ===")
 	singleFileDemo(versionInfo)
 
 	// Demo 2: Multiple output paths (console + file)
-	fmt.Println("\n=== Demo 2: Multiple Output Paths ===")
+	console.Println("\n=== Demo 2: Multiple Output Paths ===")
 	multipleOutputDemo(versionInfo)
 
 	// Demo 3: Different log levels to different files
-	fmt.Println("\n=== Demo 3: Level-based File Logging ===")
+	console.Println("\n=== Demo 3: Level-based File Logging ===")
 	levelBasedDemo(versionInfo)
 
 	// Demo 4: File rotation simulation
-	fmt.Println("\n=== Demo 4: File Rotation Simulation ===")
-	fileRotationDemo(versionInfo)
+	console.Println("\n=== Demo 4: File Rotation Simulation ===")
+	fileRotationDemo(versionInfo, clock.Real)
 
 	// Demo 5: Web server with file logging
-	fmt.Println("\n=== Demo 5: Web Server with File Logging ===")
+	console.Println("\n=== Demo 5: Web Server with File Logging ===")
 	webServerDemo(versionInfo)
 }
 
@@ -72,7 +114,7 @@ func singleFileDemo(versionInfo version.Info) {
 
 	logger, err := logger.New(logOption)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create logger: %v", err))
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 
 	// Log some messages
@@ -81,15 +123,15 @@ func singleFileDemo(versionInfo version.Info) {
 	logger.Warnw("High memory usage", "usage", "85%", "threshold", "80%")
 	logger.Errorw("Database connection failed", "error", "connection timeout", "retry_count", 3)
 
-	fmt.Printf("✅ Logs written to: %s\n", logFile)
+	console.Printf("✅ Logs written to: %s\n", logFile)
 	
 	// Show file contents
 	if content, err := os.ReadFile(logFile); err == nil {
-		fmt.Printf("📄 File contents (last 200 chars):\n")
+		console.Printf("📄 File contents (last 200 chars):\n")
 		if len(content) > 200 {
-			fmt.Printf("...%s", content[len(content)-200:])
+			console.Printf("...%s", content[len(content)-200:])
 		} else {
-			fmt.Printf("%s", content)
+			console.Printf("%s", content)
 		}
 	}
 }
@@ -107,10 +149,14 @@ func multipleOutputDemo(versionInfo version.Info) {
 			// ServiceName and ServiceVersion removed - handled via -ldflags injection
 		},
 	}
+	if console.Structured() {
+		// --quiet and CONSOLE_OUTPUT=stderr keep stdout for JSON lines
+		logOption.Format = "json"
+	}
 
 	coreLogger, err := logger.New(logOption)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create logger: %v", err))
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 
 	// Create a service-specific logger with service info
@@ -124,82 +170,61 @@ func multipleOutputDemo(versionInfo version.Info) {
 	)
 
 	// Log messages that will appear both in console and file
-	fmt.Println("📺 Watch the console output while logs are also written to file:")
+	console.Println("📺 Watch the console output while logs are also written to file:")
 	serviceLogger.Debug("Payment processing started")
 	serviceLogger.Info("Payment validation successful")
 	serviceLogger.Warn("Payment amount exceeds daily limit")
 	serviceLogger.Error("Payment gateway error")
 
-	fmt.Printf("✅ Logs written to both console and: %s\n", logFile)
+	console.Printf("✅ Logs written to both console and: %s\n", logFile)
 }
 
-// Demo 3: Different log levels to different files
+// Demo 3: Different log levels to different files, from one logger
 func levelBasedDemo(versionInfo version.Info) {
-	// Create separate loggers for different levels
 	infoLogFile := filepath.Join("logs", "info.log")
 	errorLogFile := filepath.Join("logs", "error.log")
 
-	// Info level logger (info and above)
-	infoOption := &option.LogOption{
+	// One option for both files; outputlevels gives each file its own minimum level
+	logOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "info",
 		Format:      "json",
-		OutputPaths: []string{infoLogFile},
+		OutputPaths: []string{infoLogFile, errorLogFile},
 		OTLP: &option.OTLPOption{
 			// ServiceName and ServiceVersion removed - handled via -ldflags injection
 		},
 	}
 
-	coreInfoLogger, err := logger.New(infoOption)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create info logger: %v", err))
-	}
-
-	// Add service info
-	infoLogger := coreInfoLogger.With(
-		"service.name", versionInfo.ServiceName,
-		"service.version", versionInfo.GitVersion,
-	)
-
-	// Error level logger (error and above)
-	errorOption := &option.LogOption{
-		Engine:      "slog",
-		Level:       "error",
-		Format:      "json",
-		OutputPaths: []string{errorLogFile},
-		OTLP: &option.OTLPOption{
-			// ServiceName and ServiceVersion removed - handled via -ldflags injection
-		},
-	}
-
-	coreErrorLogger, err := logger.New(errorOption)
+	coreLogger, err := outputlevels.New(logOption, map[string]string{
+		infoLogFile:  "info",
+		errorLogFile: "error",
+	})
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create error logger: %v", err))
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 
 	// Add service info
-	errorLogger := coreErrorLogger.With(
+	levelLogger := coreLogger.With(
 		"service.name", versionInfo.ServiceName,
 		"service.version", versionInfo.GitVersion,
 	)
 
-	// Log different levels
-	infoLogger.Info("Application started successfully")
-	infoLogger.Warn("Configuration file not found, using defaults")
-	infoLogger.Error("Failed to connect to database")
-
-	errorLogger.Error("Critical system error")
+	// Each entry is logged once and lands in every file whose level admits it
+	levelLogger.Info("Application started successfully")
+	levelLogger.Warn("Configuration file not found, using defaults")
+	levelLogger.Error("Failed to connect to database")
+	levelLogger.Error("Critical system error")
 	// Note: Fatal() would exit the program, so we use Error() instead for demo
-	errorLogger.Error("System shutdown due to critical error (simulated fatal)")
+	levelLogger.Error("System shutdown due to critical error (simulated fatal)")
 
-	fmt.Printf("✅ Info logs written to: %s\n", infoLogFile)
-	fmt.Printf("✅ Error logs written to: %s\n", errorLogFile)
+	console.Printf("✅ Info logs written to: %s\n", infoLogFile)
+	console.Printf("✅ Error logs written to: %s\n", errorLogFile)
 }
 
-// Demo 4: Simulate file rotation by creating timestamped files
-func fileRotationDemo(versionInfo version.Info) {
-	timestamp := time.Now().Format("20060102-150405")
-	logFile := filepath.Join("logs", fmt.Sprintf("rotated-%s.log", timestamp))
+// Demo 4: Simulate file rotation by creating timestamped files; the file name and the
+// timestamp field are read from clk
+func fileRotationDemo(versionInfo version.Info, clk clock.Clock) {
+	logFile := rotatedLogFile(clk)
 
 	logOption := &option.LogOption{
 		Engine:      "zap",
@@ -210,10 +235,14 @@ func fileRotationDemo(versionInfo version.Info) {
 			// ServiceName and ServiceVersion removed - handled via -ldflags injection
 		},
 	}
+	if console.Structured() {
+		// --quiet and CONSOLE_OUTPUT=stderr keep stdout for JSON lines
+		logOption.Format = "json"
+	}
 
 	coreLogger, err := logger.New(logOption)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create logger: %v", err))
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
 	}
 
 	// Add service info
@@ -235,16 +264,24 @@ func fileRotationDemo(versionInfo version.Info) {
 		logger.Infow("Business operation", 
 			"operation", op,
 			"step", i+1,
-			"timestamp", time.Now().Unix(),
+			"timestamp", clk.Now().Unix(),
 		)
-		time.Sleep(100 * time.Millisecond) // Simulate processing time
+		<-clk.After(100 * time.Millisecond) // Simulate processing time
 	}
 
-	fmt.Printf("✅ Timestamped logs written to: %s\n", logFile)
+	console.Printf("✅ Timestamped logs written to: %s\n", logFile)
+}
+
+// rotatedLogFile names the file for the current second, e.g. logs/rotated-20250901-083000.log
+func rotatedLogFile(clk clock.Clock) string {
+	return filepath.Join("logs", fmt.Sprintf("rotated-%s.log", clk.Now().Format("20060102-150405")))
 }
 
 // Demo 5: Web server with comprehensive file logging
 func webServerDemo(versionInfo version.Info) {
+	port := getEnvOrDefault("PORT", "8084")
+	baseURL := "http://localhost:" + port
+
 	// Create logs for different components
 	accessLogFile := filepath.Join("logs", "access.log")
 	appLogFile := filepath.Join("logs", "application.log")
@@ -262,8 +299,11 @@ func webServerDemo(versionInfo version.Info) {
 
 	coreAccessLogger, err := logger.New(accessLogOption)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create access logger: %v", err))
+		crash.Fatal(nil, "Failed to create access logger", "error", err.Error())
 	}
+	// The shutdown summary counts both loggers and flushes all three outputs
+	summary := runsummary.New()
+	coreAccessLogger = summary.Wrap(coreAccessLogger, accessLogOption)
 
 	// Add service info
 	accessLogger := coreAccessLogger.With(
@@ -271,21 +311,27 @@ func webServerDemo(versionInfo version.Info) {
 		"service.version", versionInfo.GitVersion,
 	)
 
-	// Application logger (console + file for development)
+	// Application logger (console + file for development). In development the pretty
+	// format renders stdout as colored key=value lines (LOG_THEME=dark, light or mono) and
+	// writes the file in the console format; with --quiet stdout gets JSON instead
 	appLogOption := &option.LogOption{
 		Engine:      "slog",
 		Level:       "debug",
-		Format:      "console",
+		Format:      devconsole.Format,
 		OutputPaths: []string{"stdout", appLogFile},
+		Development: true,
 		OTLP: &option.OTLPOption{
 			// ServiceName and ServiceVersion removed - handled via -ldflags injection
 		},
 	}
 
-	coreAppLogger, err := logger.New(appLogOption)
+	cliflags.Apply(appLogOption)
+
+	coreAppLogger, err := devconsole.New(appLogOption, devconsole.ThemeFromEnv())
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create app logger: %v", err))
+		crash.Fatal(nil, "Failed to create app logger", "error", err.Error())
 	}
+	coreAppLogger = summary.Wrap(coreAppLogger, appLogOption)
 
 	// Add service info
 	appLogger := coreAppLogger.With(
@@ -300,8 +346,14 @@ func webServerDemo(versionInfo version.Info) {
 	// Set up Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	r.Use(summary.Middleware())
 
-	// Custom logging middleware
+	// Handlers log through the request's child of the application logger, which carries
+	// request_id and, for callers that send a traceparent header, trace_id and span_id
+	r.Use(logcontext.GinRequestLogger(appLoggerWithContext), traceparent.GinMiddleware())
+
+	// Custom logging middleware; the access entry gets the same correlation fields, so
+	// access.log and application.log join on request_id and trace_id
 	r.Use(func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -310,7 +362,7 @@ func webServerDemo(versionInfo version.Info) {
 		c.Next()
 
 		// Log access information
-		accessLoggerWithContext.Infow("HTTP request",
+		logcontext.Correlated(c.Request.Context(), accessLoggerWithContext).Infow("HTTP request",
 			"method", method,
 			"path", path,
 			"status", c.Writer.Status(),
@@ -322,7 +374,7 @@ func webServerDemo(versionInfo version.Info) {
 
 	// Routes
 	r.GET("/", func(c *gin.Context) {
-		appLoggerWithContext.Debug("Handling root request")
+		logcontext.MustFromGin(c).Debug("Handling root request")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "File Logging Demo API",
 			"version": versionInfo.GitVersion,
@@ -333,18 +385,21 @@ func webServerDemo(versionInfo version.Info) {
 		})
 	})
 
+	r.GET("/version", buildinfo.Handler())
 	r.GET("/health", func(c *gin.Context) {
-		appLoggerWithContext.Debug("Health check requested")
-		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
-	})
+		logcontext.MustFromGin(c).Debug("Health check requested")
+	}, health.Handler(nil))
 
 	r.GET("/error", func(c *gin.Context) {
-		appLoggerWithContext.Error("Simulated error endpoint accessed")
+		// The error itself rather than err.Error(): in development the pretty format
+		// writes its chain and the stack under the entry; JSON gets the message
+		err := fmt.Errorf("load report: %w", fmt.Errorf("read logs/report.csv: %w", os.ErrNotExist))
+		logcontext.MustFromGin(c).Errorw("Simulated error endpoint accessed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Simulated error"})
 	})
 
 	r.GET("/logs", func(c *gin.Context) {
-		appLoggerWithContext.Debug("Log files listing requested")
+		logcontext.MustFromGin(c).Debug("Log files listing requested")
 		
 		// List all log files
 		logFiles := []string{}
@@ -363,13 +418,13 @@ func webServerDemo(versionInfo version.Info) {
 
 	// Start server in background
 	srv := &http.Server{
-		Addr:    ":8084",
+		Addr:    ":" + port,
 		Handler: r,
 	}
 
 	go func() {
 		appLoggerWithContext.Infow("Starting web server",
-			"port", 8084,
+			"port", port,
 			"access_log", accessLogFile,
 			"app_log", appLogFile,
 		)
@@ -382,31 +437,36 @@ func webServerDemo(versionInfo version.Info) {
 	// Wait a moment for server to start
 	time.Sleep(500 * time.Millisecond)
 
-	fmt.Printf("🚀 Web server started on http://localhost:8084\n")
-	fmt.Printf("📝 Access logs: %s\n", accessLogFile)
-	fmt.Printf("📱 App logs: %s\n", appLogFile)
-	fmt.Println()
-	fmt.Println("Available endpoints:")
-	fmt.Println("  GET http://localhost:8084/        - Main page")
-	fmt.Println("  GET http://localhost:8084/health  - Health check")
-	fmt.Println("  GET http://localhost:8084/error   - Simulate error")
-	fmt.Println("  GET http://localhost:8084/logs    - List log files")
-	fmt.Println()
-	fmt.Println("Making some test requests...")
+	console.Printf("🚀 Web server started on %s\n", baseURL)
+	console.Printf("📝 Access logs: %s\n", accessLogFile)
+	console.Printf("📱 App logs: %s\n", appLogFile)
+	console.Println()
+	console.Println("Available endpoints:")
+	console.Printf("  GET %s/        - Main page\n", baseURL)
+	console.Printf("  GET %s/health  - Health check\n", baseURL)
+	console.Printf("  GET %s/error   - Simulate error\n", baseURL)
+	console.Printf("  GET %s/logs    - List log files\n", baseURL)
+	console.Println()
+	console.Println("Making some test requests...")
 
 	// Make some test requests
 	testEndpoints := []string{
-		"http://localhost:8084/",
-		"http://localhost:8084/health",
-		"http://localhost:8084/logs",
-		"http://localhost:8084/error",
+		baseURL + "/",
+		baseURL + "/health",
+		baseURL + "/logs",
+		baseURL + "/error",
 	}
 
 	client := &http.Client{Timeout: 2 * time.Second}
 	for i, endpoint := range testEndpoints {
 		appLoggerWithContext.Debugw("Making test request", "endpoint", endpoint, "request", i+1)
 		
-		resp, err := client.Get(endpoint)
+		req, _ := http.NewRequest(http.MethodGet, endpoint, nil)
+		if i == len(testEndpoints)-1 {
+			// As from a traced caller: both log files record its trace_id for this request
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		}
+		resp, err := client.Do(req)
 		if err != nil {
 			appLoggerWithContext.Warnw("Test request failed", "endpoint", endpoint, "error", err)
 		} else {
@@ -417,7 +477,7 @@ func webServerDemo(versionInfo version.Info) {
 	}
 
 	// Graceful shutdown
-	fmt.Println("\nShutting down server...")
+	console.Println("\nShutting down server...")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	
@@ -426,6 +486,14 @@ func webServerDemo(versionInfo version.Info) {
 	} else {
 		appLoggerWithContext.Info("Server shutdown completed")
 	}
+	summary.Log(appLoggerWithContext)
+
+	console.Printf("✅ Demo completed. Check log files in the 'logs/' directory\n")
+}
 
-	fmt.Printf("✅ Demo completed. Check log files in the 'logs/' directory\n")
-}
\ No newline at end of file
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// EventTime is the forward protocol's nanosecond timestamp (msgpack ext type 0)
+type EventTime struct {
+	time.Time
+}
+
+func init() {
+	msgpack.RegisterExt(0, (*EventTime)(nil))
+}
+
+// MarshalMsgpack encodes seconds and nanoseconds as two big-endian uint32
+func (t *EventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+// UnmarshalMsgpack decodes the 8-byte EventTime payload
+func (t *EventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid EventTime length: %d", len(b))
+	}
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+	return nil
+}
+
+// ForwardConfig controls batching, acknowledgements and reconnects
+type ForwardConfig struct {
+	Addr         string
+	Tag          string
+	BatchSize    int
+	FlushEvery   time.Duration
+	QueueSize    int
+	RequireAck   bool
+	AckTimeout   time.Duration
+	WriteTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+// ForwardStats are cumulative counters
+type ForwardStats struct {
+	Sent       uint64 `json:"sent"`
+	Chunks     uint64 `json:"chunks"`
+	Resent     uint64 `json:"resent_chunks"`
+	Dropped    uint64 `json:"dropped_queue_full"`
+	Reconnects uint64 `json:"reconnects"`
+}
+
+type forwardEntry struct {
+	time   EventTime
+	record map[string]interface{}
+}
+
+// ForwardSink is a zap sink speaking the Fluentd forward protocol in Forward mode:
+// [tag, [[time, record], ...], {"chunk": id}] with optional at-least-once acknowledgements
+type ForwardSink struct {
+	cfg    ForwardConfig
+	logger core.Logger
+
+	conn net.Conn
+
+	queue   chan forwardEntry
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	sent     atomic.Uint64
+	chunks   atomic.Uint64
+	resent   atomic.Uint64
+	dropped  atomic.Uint64
+	connects atomic.Uint64
+}
+
+// NewForwardSink starts the background forwarder; connecting happens lazily
+func NewForwardSink(cfg ForwardConfig, diagnostics core.Logger) *ForwardSink {
+	s := &ForwardSink{
+		cfg:     cfg,
+		logger:  diagnostics,
+		queue:   make(chan forwardEntry, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write converts one JSON log line into a forward record
+func (s *ForwardSink) Write(p []byte) (int, error) {
+	entry := forwardEntry{time: EventTime{time.Now()}}
+	if err := json.Unmarshal(p, &entry.record); err != nil {
+		entry.record = map[string]interface{}{"message": string(p)}
+	}
+	if ts, ok := entry.record["timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			entry.time = EventTime{parsed}
+		}
+	}
+
+	select {
+	case s.queue <- entry:
+	default:
+		if dropped := s.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			s.logger.Warnw("Forward queue full, dropping records",
+				"queue_size", s.cfg.QueueSize,
+				"dropped_total", dropped,
+			)
+		}
+	}
+	return len(p), nil
+}
+
+// Sync waits until queued records are acknowledged or the sink gives up
+func (s *ForwardSink) Sync() error {
+	ack := make(chan struct{})
+	select {
+	case s.flushCh <- ack:
+		<-ack
+	case <-s.stopped:
+	}
+	return nil
+}
+
+// Close flushes and closes the connection
+func (s *ForwardSink) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		<-s.stopped
+	})
+	return nil
+}
+
+// Stats returns a snapshot of the counters
+func (s *ForwardSink) Stats() ForwardStats {
+	var reconnects uint64
+	if connects := s.connects.Load(); connects > 1 {
+		reconnects = connects - 1
+	}
+	return ForwardStats{
+		Sent:       s.sent.Load(),
+		Chunks:     s.chunks.Load(),
+		Resent:     s.resent.Load(),
+		Dropped:    s.dropped.Load(),
+		Reconnects: reconnects,
+	}
+}
+
+func (s *ForwardSink) run() {
+	defer close(s.stopped)
+	defer s.disconnect("sink_closed")
+
+	ticker := time.NewTicker(s.cfg.FlushEvery)
+	defer ticker.Stop()
+
+	var batch []forwardEntry
+	flush := func() {
+		if len(batch) > 0 {
+			s.sendChunk(batch)
+			batch = nil
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case entry := <-s.queue:
+				batch = append(batch, entry)
+				if len(batch) >= s.cfg.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-s.flushCh:
+			drain()
+			close(ack)
+		case <-s.done:
+			drain()
+			return
+		}
+	}
+}
+
+// sendChunk writes one Forward-mode message, reconnecting and resending until acknowledged or closed
+func (s *ForwardSink) sendChunk(batch []forwardEntry) {
+	chunkID := newChunkID()
+
+	entries := make([]interface{}, len(batch))
+	for i, entry := range batch {
+		t := entry.time
+		entries[i] = []interface{}{&t, entry.record}
+	}
+	options := map[string]interface{}{"size": len(batch)}
+	if s.cfg.RequireAck {
+		options["chunk"] = chunkID
+	}
+
+	payload, err := msgpack.Marshal([]interface{}{s.cfg.Tag, entries, options})
+	if err != nil {
+		s.logger.Errorw("Failed to encode forward chunk", "records", len(batch), "error", err.Error())
+		return
+	}
+
+	backoff := s.cfg.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.writeChunk(payload, chunkID)
+		if err == nil {
+			s.sent.Add(uint64(len(batch)))
+			s.chunks.Add(1)
+			if attempt > 1 {
+				s.resent.Add(1)
+			}
+			s.logger.Debugw("Forward chunk delivered",
+				"chunk", chunkID,
+				"records", len(batch),
+				"bytes", len(payload),
+				"attempt", attempt,
+				"acked", s.cfg.RequireAck,
+			)
+			return
+		}
+
+		s.disconnect("write_failed")
+		s.logger.Warnw("Forward chunk not delivered, will resend",
+			"chunk", chunkID,
+			"records", len(batch),
+			"attempt", attempt,
+			"backoff", backoff.String(),
+			"queue_depth", len(s.queue),
+			"error", err.Error(),
+		)
+
+		select {
+		case <-s.done:
+			// Shutting down with the destination unreachable: give up on this chunk
+			s.logger.Errorw("Forward chunk abandoned on shutdown", "chunk", chunkID, "records", len(batch))
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+func (s *ForwardSink) writeChunk(payload []byte, chunkID string) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	if _, err := s.conn.Write(payload); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	if !s.cfg.RequireAck {
+		return nil
+	}
+
+	// The server replies {"ack": chunk} once the chunk is safely buffered
+	s.conn.SetReadDeadline(time.Now().Add(s.cfg.AckTimeout))
+	var response map[string]interface{}
+	if err := msgpack.NewDecoder(s.conn).Decode(&response); err != nil {
+		return fmt.Errorf("waiting for ack: %w", err)
+	}
+	if response["ack"] != chunkID {
+		return fmt.Errorf("ack mismatch: got %v", response["ack"])
+	}
+	return nil
+}
+
+func (s *ForwardSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", s.cfg.Addr, s.cfg.WriteTimeout)
+	if err != nil {
+		return fmt.Errorf("connect failed: %w", err)
+	}
+	s.conn = conn
+
+	if s.connects.Add(1) > 1 {
+		s.logger.Infow("Reconnected to forward destination", "addr", s.cfg.Addr)
+	} else {
+		s.logger.Infow("Connected to forward destination", "addr", s.cfg.Addr, "require_ack", s.cfg.RequireAck)
+	}
+	return nil
+}
+
+func (s *ForwardSink) disconnect(reason string) {
+	if s.conn == nil {
+		return
+	}
+	s.conn.Close()
+	s.conn = nil
+	s.logger.Debugw("Forward connection closed", "reason", reason)
+}
+
+func newChunkID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b, uint64(time.Now().UnixNano()))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
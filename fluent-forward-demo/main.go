@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.uber.org/zap"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Fluentd / Fluent Bit Forward Protocol Demo ===")
+	console.Println("Ships logs as msgpack over TCP into an existing EFK pipeline")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Diagnostics about the forwarder go to stderr, never through the forward sink
+	baseLogger, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("SINK_LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stderr"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	diagnostics := baseLogger.With("component", "fluent-forward")
+
+	addr := os.Getenv("FLUENT_ADDR")
+	var mock *MockFluent
+	if addr == "" {
+		mock, err = StartMockFluent("127.0.0.1:0", baseLogger.With("component", "mock-fluent"))
+		if err != nil {
+			crash.Fatal(diagnostics, "Failed to start mock fluent receiver", "error", err.Error())
+		}
+		defer mock.Close()
+		addr = mock.Addr()
+		diagnostics.Infow("Started in-process mock forward receiver (drops every 4th chunk before ack)", "addr", addr)
+	}
+
+	tag := getEnvOrDefault("FLUENT_TAG", "app."+versionInfo.ServiceName)
+	sink := NewForwardSink(ForwardConfig{
+		Addr:         addr,
+		Tag:          tag,
+		BatchSize:    getIntEnv("BATCH_SIZE", 100),
+		FlushEvery:   time.Second,
+		QueueSize:    getIntEnv("QUEUE_SIZE", 5000),
+		RequireAck:   os.Getenv("REQUIRE_ACK") != "false",
+		AckTimeout:   5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		MinBackoff:   200 * time.Millisecond,
+		MaxBackoff:   5 * time.Second,
+	}, diagnostics)
+
+	// The zap engine resolves output paths through zap's sink registry
+	if err := zap.RegisterSink("fluent", func(*url.URL) (zap.Sink, error) { return sink, nil }); err != nil {
+		crash.Fatal(diagnostics, "Failed to register forward sink", "error", err.Error())
+	}
+	crash.OnExit("Fluent sink", sink.Close)
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"fluent://forward"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+	cliflags.Apply(logOption)
+	appLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create application logger", "error", err.Error())
+	}
+	crash.OnExit("application logger", appLogger.Flush)
+
+	sanitize.Startup(diagnostics, logOption, "fluent_addr", addr, "tag", tag)
+	diagnostics.Infow("Forwarding logs", "addr", addr, "tag", tag)
+
+	start := time.Now()
+	written := generateTraffic(appLogger, getIntEnv("RATE", 400), getDurationEnv("DURATION", 3*time.Second))
+
+	appLogger.Flush()
+	sink.Close()
+
+	stats := sink.Stats()
+	summary := []interface{}{
+		"written", written,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"stats", stats,
+	}
+	if mock != nil {
+		summary = append(summary, "receiver_records", mock.Records())
+	}
+	diagnostics.Infow("Forwarding finished", summary...)
+}
+
+// generateTraffic logs a steady stream of events; messages vary to stay under zap's sampling threshold
+func generateTraffic(appLogger core.Logger, rate int, duration time.Duration) int {
+	events := []string{"Order created", "Order paid", "Cart updated", "User signed in", "Search executed"}
+
+	perTick := rate / 100
+	if perTick < 1 {
+		perTick = 1
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	written := 0
+	for deadline := time.Now().Add(duration); time.Now().Before(deadline); {
+		<-ticker.C
+		for i := 0; i < perTick; i++ {
+			written++
+			appLogger.Infow(events[rng.Intn(len(events))],
+				"request_id", written,
+				"latency_ms", rng.Intn(300),
+			)
+		}
+	}
+	return written
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/kart-io/logger/core"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MockFluent is a minimal forward-protocol receiver that acknowledges chunks.
+// Every dropEvery-th chunk it closes the connection without acking to force a resend.
+type MockFluent struct {
+	logger    core.Logger
+	listener  net.Listener
+	dropEvery uint64
+
+	chunks  atomic.Uint64
+	records atomic.Uint64
+}
+
+// StartMockFluent listens on addr
+func StartMockFluent(addr string, logger core.Logger) (*MockFluent, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockFluent{logger: logger, listener: listener, dropEvery: 4}
+	go m.accept()
+	return m, nil
+}
+
+// Addr returns host:port
+func (m *MockFluent) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// Records returns how many records were accepted
+func (m *MockFluent) Records() uint64 {
+	return m.records.Load()
+}
+
+// Close stops accepting connections
+func (m *MockFluent) Close() error {
+	return m.listener.Close()
+}
+
+func (m *MockFluent) accept() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.serve(conn)
+	}
+}
+
+func (m *MockFluent) serve(conn net.Conn) {
+	defer conn.Close()
+	decoder := msgpack.NewDecoder(conn)
+
+	for {
+		var message []interface{}
+		if err := decoder.Decode(&message); err != nil {
+			if !errors.Is(err, io.EOF) {
+				m.logger.Warnw("Mock fluent decode failed", "error", err.Error())
+			}
+			return
+		}
+		if len(message) < 2 {
+			m.logger.Warnw("Mock fluent received malformed message", "elements", len(message))
+			return
+		}
+
+		tag, _ := message[0].(string)
+		records := 0
+		var option map[string]interface{}
+
+		switch entries := message[1].(type) {
+		case []interface{}:
+			// Forward mode: [tag, [[time, record], ...], option?]
+			records = len(entries)
+			if len(message) > 2 {
+				option, _ = message[2].(map[string]interface{})
+			}
+		default:
+			// Message mode: [tag, time, record, option?]
+			records = 1
+			if len(message) > 3 {
+				option, _ = message[3].(map[string]interface{})
+			}
+		}
+
+		n := m.chunks.Add(1)
+		if m.dropEvery > 0 && n%m.dropEvery == 0 {
+			m.logger.Warnw("Mock fluent dropping connection before ack", "tag", tag, "records", records)
+			return
+		}
+
+		m.records.Add(uint64(records))
+		m.logger.Debugw("Mock fluent accepted chunk", "tag", tag, "records", records)
+
+		if chunk, ok := option["chunk"]; ok {
+			if err := msgpack.NewEncoder(conn).Encode(map[string]interface{}{"ack": chunk}); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/kart-io/logger/core"
+)
+
+const (
+	// maxBatchRecords caps a single JSON batch request
+	maxBatchRecords = 1000
+	// maxLineBytes caps a single NDJSON record
+	maxLineBytes = 64 * 1024
+)
+
+// reservedKeys are written by the forwarder's own logger on every record
+var reservedKeys = map[string]bool{
+	"caller":          true,
+	"engine":          true,
+	"service.name":    true,
+	"service.version": true,
+	"forwarder.host":  true,
+}
+
+// Batch is the JSON body accepted by POST /v1/logs
+type Batch struct {
+	Source  string                   `json:"source"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// IngestResult reports how many records of a request were forwarded
+type IngestResult struct {
+	Source   string   `json:"source"`
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// reject counts a bad record and keeps the first few error messages for the response
+func (r *IngestResult) reject(format string, args ...interface{}) {
+	r.Rejected++
+	if len(r.Errors) < 5 {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// SourceStats are cumulative counters for one source
+type SourceStats struct {
+	Accepted uint64            `json:"accepted"`
+	Rejected uint64            `json:"rejected"`
+	ByLevel  map[string]uint64 `json:"by_level"`
+}
+
+// Forwarder re-emits received records through its own logger and outputs
+type Forwarder struct {
+	output core.Logger
+
+	mu      sync.Mutex
+	sources map[string]core.Logger
+	stats   map[string]*SourceStats
+}
+
+// NewForwarder creates a forwarder writing to the given logger
+func NewForwarder(output core.Logger) *Forwarder {
+	return &Forwarder{
+		output:  output,
+		sources: make(map[string]core.Logger),
+		stats:   make(map[string]*SourceStats),
+	}
+}
+
+// sourceLogger returns a cached child logger tagged with the record source
+func (f *Forwarder) sourceLogger(source string) (core.Logger, *SourceStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sourceLogger, ok := f.sources[source]
+	if !ok {
+		sourceLogger = f.output.With("forwarded.source", source)
+		f.sources[source] = sourceLogger
+		f.stats[source] = &SourceStats{ByLevel: make(map[string]uint64)}
+	}
+	return sourceLogger, f.stats[source]
+}
+
+// IngestBatch forwards every record of a JSON batch
+func (f *Forwarder) IngestBatch(batch Batch, remoteAddr string) IngestResult {
+	result := IngestResult{Source: batch.Source}
+	defer func() { f.addRejected(batch.Source, result.Rejected) }()
+
+	if len(batch.Records) > maxBatchRecords {
+		result.reject("batch has %d records, limit is %d", len(batch.Records), maxBatchRecords)
+		return result
+	}
+
+	for i, record := range batch.Records {
+		if err := f.forward(batch.Source, remoteAddr, record); err != nil {
+			result.reject("record %d: %v", i, err)
+			continue
+		}
+		result.Accepted++
+	}
+	return result
+}
+
+// IngestNDJSON forwards one record per line, streaming so large bodies are never buffered whole
+func (f *Forwarder) IngestNDJSON(source, remoteAddr string, body io.Reader) (IngestResult, error) {
+	result := IngestResult{Source: source}
+	defer func() { f.addRejected(source, result.Rejected) }()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			// Non-JSON lines (banners, plain prints) are common when piping a process's stdout
+			result.reject("line %d: not a JSON object", line)
+			continue
+		}
+		if err := f.forward(source, remoteAddr, record); err != nil {
+			result.reject("line %d: %v", line, err)
+			continue
+		}
+		result.Accepted++
+	}
+	return result, scanner.Err()
+}
+
+// forward normalizes a record and writes it at its original level
+func (f *Forwarder) forward(source, remoteAddr string, record map[string]interface{}) error {
+	sourceLogger, stats := f.sourceLogger(source)
+
+	message := popString(record, "message", "msg")
+	if message == "" {
+		return fmt.Errorf("missing message")
+	}
+
+	level := strings.ToLower(popString(record, "level", "severity"))
+	timestamp := popString(record, "timestamp", "time", "ts")
+
+	keysAndValues := make([]interface{}, 0, len(record)*2+4)
+	keysAndValues = append(keysAndValues, "forwarded.remote_addr", remoteAddr)
+	if timestamp != "" {
+		keysAndValues = append(keysAndValues, "original_timestamp", timestamp)
+	}
+	for key, value := range record {
+		// Keys the forwarder writes itself would be duplicated; keep the sender's values under original_*
+		if reservedKeys[key] || strings.HasPrefix(key, "forwarded.") {
+			key = "original_" + key
+		}
+		keysAndValues = append(keysAndValues, key, value)
+	}
+
+	switch level {
+	case "debug":
+		sourceLogger.Debugw(message, keysAndValues...)
+	case "warn", "warning":
+		sourceLogger.Warnw(message, keysAndValues...)
+	case "error", "fatal", "panic", "dpanic":
+		// Never let a forwarded fatal record terminate the forwarder
+		if level != "error" {
+			keysAndValues = append(keysAndValues, "original_level", level)
+		}
+		level = "error"
+		sourceLogger.Errorw(message, keysAndValues...)
+	default:
+		level = "info"
+		sourceLogger.Infow(message, keysAndValues...)
+	}
+
+	f.mu.Lock()
+	stats.Accepted++
+	stats.ByLevel[level]++
+	f.mu.Unlock()
+	return nil
+}
+
+// addRejected records rejected records for a source once a request is done
+func (f *Forwarder) addRejected(source string, n int) {
+	if n == 0 {
+		return
+	}
+	_, stats := f.sourceLogger(source)
+
+	f.mu.Lock()
+	stats.Rejected += uint64(n)
+	f.mu.Unlock()
+}
+
+// Stats returns a copy of the per-source counters
+func (f *Forwarder) Stats() map[string]SourceStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make(map[string]SourceStats, len(f.stats))
+	for source, stats := range f.stats {
+		byLevel := make(map[string]uint64, len(stats.ByLevel))
+		for level, n := range stats.ByLevel {
+			byLevel[level] = n
+		}
+		result[source] = SourceStats{Accepted: stats.Accepted, Rejected: stats.Rejected, ByLevel: byLevel}
+	}
+	return result
+}
+
+// popString removes the first present key and returns its value as a string
+func popString(record map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := record[key]; ok {
+			delete(record, key)
+			if s, ok := value.(string); ok {
+				return s
+			}
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
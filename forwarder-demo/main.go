@@ -0,0 +1,249 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// maxBatchBytes caps a JSON batch body; NDJSON bodies are streamed instead
+const maxBatchBytes = 8 << 20
+
+func main() {
+	cliflags.Parse()
+	// stdout carries forwarded records only, so the banner goes to stderr
+	console.DefaultStderr()
+	console.Println("=== Log Forwarder Sidecar Demo ===")
+	console.Println("Receives structured log batches over HTTP and re-emits them through its own outputs")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Operational logs about the forwarder itself go to stderr so they never mix with forwarded records
+	opsLogger, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stderr"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "forwarder",
+		},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", opsLogger.Flush)
+	defer crash.Recover(opsLogger)
+	defer resusage.FromEnv(opsLogger)()
+	defer leakwatch.FromEnv(opsLogger)()
+	defer pidfile.FromEnv(opsLogger)()
+
+	outputPath := getEnvOrDefault("FORWARD_OUTPUT", filepath.Join("data", "forwarded.log"))
+
+	// Forwarded records keep the sender's caller, so the forwarder's own caller is disabled
+	logOption := &option.LogOption{
+		Engine:            "zap",
+		Level:             "debug",
+		Format:            "json",
+		OutputPaths:       []string{"stdout", outputPath},
+		DisableCaller:     true,
+		DisableStacktrace: true,
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"forwarder.host":  hostmeta.Get().Hostname,
+		},
+	}
+	cliflags.Apply(logOption)
+	// Creates the directory of FORWARD_OUTPUT, and exits before accepting batches it could not write
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	outputLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(opsLogger, "Failed to create output logger", "error", err.Error())
+	}
+	crash.OnExit("output logger", outputLogger.Flush)
+	summary := runsummary.New()
+	outputLogger = summary.Wrap(outputLogger, logOption)
+
+	forwarder := NewForwarder(outputLogger)
+	token := os.Getenv("FORWARDER_TOKEN")
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	// Handlers log through the request's child of opsLogger, with request_id, method, route
+	// and client_ip; forwarded records go to outputLogger unchanged
+	r.Use(logcontext.GinRequestLogger(opsLogger))
+
+	v1 := r.Group("/v1")
+	if token != "" {
+		v1.Use(bearerAuth(token))
+	}
+
+	v1.POST("/logs", func(c *gin.Context) {
+		start := time.Now()
+		logger := logcontext.MustFromGin(c)
+
+		body, err := requestBody(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer body.Close()
+
+		source := firstNonEmpty(c.Query("source"), c.GetHeader("X-Log-Source"))
+		contentType := c.ContentType()
+
+		var result IngestResult
+		switch contentType {
+		case "application/x-ndjson", "application/jsonl", "text/plain":
+			result, err = forwarder.IngestNDJSON(firstNonEmpty(source, "unknown"), c.ClientIP(), body)
+			if err != nil {
+				logger.Warnw("NDJSON stream ended with error",
+					"source", result.Source,
+					"accepted", result.Accepted,
+					"error", err.Error(),
+				)
+			}
+		default:
+			var batch Batch
+			if err := json.NewDecoder(io.LimitReader(body, maxBatchBytes)).Decode(&batch); err != nil {
+				logger.Warnw("Rejected malformed batch", "error", err.Error())
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch: " + err.Error()})
+				return
+			}
+			batch.Source = firstNonEmpty(source, batch.Source, "unknown")
+			result = forwarder.IngestBatch(batch, c.ClientIP())
+		}
+
+		logFn := logger.Infow
+		if result.Rejected > 0 {
+			logFn = logger.Warnw
+		}
+		logFn("Batch ingested",
+			"source", result.Source,
+			"content_type", contentType,
+			"accepted", result.Accepted,
+			"rejected", result.Rejected,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+
+		status := http.StatusOK
+		if result.Accepted == 0 && result.Rejected > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, result)
+	})
+
+	r.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, forwarder.Stats())
+	})
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	port := getEnvOrDefault("PORT", "8096")
+	outputs.Log(opsLogger)
+	sanitize.Startup(opsLogger, logOption, "port", port, "auth_enabled", token != "")
+	opsLogger.Infow("Starting log forwarder",
+		"port", port,
+		"output_paths", []string{"stdout", outputPath},
+		"auth_enabled", token != "",
+		"max_batch_records", maxBatchRecords,
+		"endpoints", []string{"/v1/logs", "/stats", "/health", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl -X POST http://localhost:%s/v1/logs -H 'Content-Type: application/json' -d '{\"source\":\"billing\",\"records\":[{\"level\":\"warn\",\"message\":\"Invoice overdue\",\"invoice_id\":\"inv-1\"}]}'\n", port)
+	console.Printf("  go run ../saga-demo | curl -T - -X POST -H 'Content-Type: application/x-ndjson' 'http://localhost:%s/v1/logs?source=saga-demo'\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(opsLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		opsLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(opsLogger)
+	outputLogger.Flush()
+}
+
+// requestBody unwraps gzip-encoded bodies
+func requestBody(req *http.Request) (io.ReadCloser, error) {
+	if !strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		return req.Body, nil
+	}
+	gz, err := gzip.NewReader(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	return gz, nil
+}
+
+// bearerAuth rejects requests without the shared ingestion token
+func bearerAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer "+token {
+			logcontext.MustFromGin(c).Warnw("Unauthorized ingestion attempt")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -1,17 +1,47 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"crypto/hmac"
+	"errors"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/allocstats"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/dynamicfields"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/logring"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/routestats"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/go-example/pkg/traceparent"
 	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 )
 
+const adminTokenHeader = "X-Admin-Token"
+
 func main() {
+	cliflags.Parse()
 	// Get version information
 	versionInfo := version.Get()
 
@@ -31,57 +61,269 @@ func main() {
 		// Smart OTLP configuration - will auto-enable if endpoint is available
 		OTLPEndpoint: "localhost:4317", // Jaeger default gRPC endpoint (no http:// prefix for gRPC)
 		OTLP: &option.OTLPOption{
-			// Basic OTLP configuration
+			// Basic OTLP configuration; a local Jaeger serves plaintext gRPC
+			Protocol: "grpc",
+			Insecure: true,
 		},
 	}
 
 	// Create logger with initial fields already included
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
 	serviceLogger, err := logger.New(logOption)
 	if err != nil {
-		panic("Failed to initialize logger: " + err.Error())
+		crash.Fatal(nil, "Failed to initialize logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	// Keep the last LOG_RING_SIZE entries for GET /debug/logs; wrapping here puts every
+	// entry the demo writes in the ring
+	ringSize := logring.DefaultCapacity
+	if value := os.Getenv("LOG_RING_SIZE"); value != "" {
+		ringSize, err = strconv.Atoi(value)
+		if err != nil || ringSize <= 0 {
+			crash.Fatal(serviceLogger, "Invalid LOG_RING_SIZE", "value", value)
+		}
 	}
+	ring := logring.New(ringSize)
+	serviceLogger = ring.Wrap(serviceLogger)
+	// Operators add fields to every entry at run time through POST /admin/fields, e.g.
+	// incident_id during an outage, without a restart
+	dynamic := dynamicfields.New()
+	globals := dynamicfields.NewGlobals(dynamic)
+	serviceLogger = dynamic.Wrap(serviceLogger)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
 
-	// Log OTLP configuration status
-	if logOption.OTLPEndpoint != "" {
-		fmt.Printf("OTLP configured for endpoint: %s (connection may fail if collector is not running)\n", logOption.OTLPEndpoint)
+	// Check the collector before serving instead of guessing whether logs will arrive; the
+	// check repeats so GET /debug/otlp shows whether they still do
+	var otlpMonitor *otelsetup.Monitor
+	if logOption.IsOTLPEnabled() {
+		otlpMonitor = startOTLPMonitor(logOption, serviceLogger)
+		if otlpMonitor != nil {
+			defer otlpMonitor.Stop()
+		}
 	}
 
-	r := gin.Default()
+	// gin.Default prints its route table and request lines to stdout; keep them with the banners
+	gin.DefaultWriter = console.Writer()
+	// Without Prometheus the per-route latency comes from the logs: every
+	// ROUTE_SUMMARY_INTERVAL each route that served requests gets a percentile summary
+	summaryInterval := getDurationEnv("ROUTE_SUMMARY_INTERVAL", time.Minute)
+	summaries := routestats.New(serviceLogger.With("component", "routestats"), summaryInterval)
+	summaries.Start()
+	defer summaries.Stop()
+	r := newRouter(serviceLogger, versionInfo.GitVersion, summary.Middleware(), summaries.Middleware())
 
-	r.GET("/", func(c *gin.Context) {
-		serviceLogger.Infow("Handling root request", "endpoint", "/", "method", "GET")
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Welcome to Go Example API",
-			"version": versionInfo.GitVersion,
-		})
-	})
+	endpoints := []string{"/", "/health", "/version"}
 
-	r.GET("/health", func(c *gin.Context) {
-		serviceLogger.Infow("Health check requested", "endpoint", "/health", "method", "GET")
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"version": versionInfo.GitVersion,
-		})
-	})
+	// The admin and debug routes expose the logs, heap profiles and the fields of every
+	// entry; there is no default token, so without ADMIN_TOKEN they are not served at all
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	// Admin and debug requests, let through or not, go to the audit trail in AUDIT_LOG_FILE
+	auditPath := os.Getenv("AUDIT_LOG_FILE")
+	if auditPath == "" {
+		auditPath = "logs/audit.log"
+	}
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to open audit log", "path", auditPath, "error", err.Error())
+	}
+	defer auditLog.Close()
+	var admin *gin.RouterGroup
+	if adminToken != "" {
+		admin = r.Group("", auditLog.GinMiddleware(audit.ActionAdminRequest, serviceLogger), adminAuth(adminToken))
+		admin.GET("/debug/otlp", otlpStatusHandler(otlpMonitor))
+		endpoints = append(endpoints, "/debug/otlp")
+		admin.GET("/debug/logs", gin.WrapH(ring.Handler()))
+		endpoints = append(endpoints, "/debug/logs")
+		admin.GET("/admin/fields", globals.ListHandler())
+		admin.POST("/admin/fields", globals.UpdateHandler(auditLog, serviceLogger))
+		endpoints = append(endpoints, "/admin/fields")
+	} else {
+		serviceLogger.Warnw("Admin routes disabled", "reason", "ADMIN_TOKEN not set")
+	}
 
-	r.GET("/version", func(c *gin.Context) {
-		serviceLogger.Infow("Version info requested", "endpoint", "/version", "method", "GET")
-		c.JSON(http.StatusOK, versionInfo)
-	})
+	// Allocation profiling mode for evaluating logging overhead, e.g. ALLOC_STATS=5s:
+	// GC and allocation stats are logged every interval and heap profiles are written on demand
+	if value := os.Getenv("ALLOC_STATS"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			crash.Fatal(serviceLogger, "Invalid ALLOC_STATS interval", "value", value)
+		}
+		statsLogger := serviceLogger.With("component", "allocstats")
+		go allocstats.New(statsLogger).Run(context.Background(), interval)
+
+		profileDir := os.Getenv("HEAP_PROFILE_DIR")
+		if profileDir == "" {
+			profileDir = "profiles"
+		}
+		if admin != nil {
+			admin.POST("/admin/heap-profile", allocstats.HeapProfileHandler(profileDir, statsLogger))
+			endpoints = append(endpoints, "/admin/heap-profile")
+		}
+		statsLogger.Infow("Allocation profiling enabled", "interval", interval.String(), "heap_profile_dir", profileDir)
+	}
+
+	// With the goroutine leak watchdog on, POST /admin/leak?count=N parks N goroutines
+	// forever so the watchdog's warning and its top stack can be seen without a real leak
+	if admin != nil && os.Getenv(leakwatch.EnvInterval) != "" {
+		admin.POST("/admin/leak", leakHandler)
+		endpoints = append(endpoints, "/admin/leak")
+	}
 
 	// Log startup with all service information
 	port := ":8082" // Default port
 	if envPort := os.Getenv("PORT"); envPort != "" {
 		port = ":" + envPort
 	}
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port, "alloc_stats", os.Getenv("ALLOC_STATS"), "log_ring_size", ringSize, "route_summary_interval", summaryInterval.String(), "audit_log", auditPath)
 	serviceLogger.Infow("Starting server",
 		"port", port,
-		"endpoints", []string{"/", "/health", "/version"},
+		"endpoints", endpoints,
 		"go_version", versionInfo.GoVersion,
 		"platform", versionInfo.Platform,
 	)
 
-	if err := r.Run(port); err != nil {
-		serviceLogger.Fatalw("Failed to start server", "error", err.Error())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Failed to start server", "error", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// newRouter registers the routes every run serves, behind middleware; the admin routes are
+// added by main depending on the environment
+func newRouter(serviceLogger core.Logger, gitVersion string, middleware ...gin.HandlerFunc) *gin.Engine {
+	r := gin.Default()
+	// Handlers log through the request's child logger, with its request_id, method, route
+	// and client_ip, instead of capturing serviceLogger
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+	// The demo runs no tracing SDK; a caller's traceparent still puts its trace_id in the logs
+	r.Use(traceparent.GinMiddleware())
+	r.Use(middleware...)
+
+	r.GET("/", func(c *gin.Context) {
+		logcontext.MustFromGin(c).Infow("Handling root request")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Welcome to Go Example API",
+			"version": gitVersion,
+		})
+	})
+
+	r.GET("/health", func(c *gin.Context) {
+		logcontext.MustFromGin(c).Infow("Health check requested")
+	}, health.Handler(nil))
+
+	r.GET("/version", buildinfo.Handler())
+	return r
+}
+
+// startOTLPMonitor checks the logger's collector once, within OTLP_PREFLIGHT_TIMEOUT, and
+// then every OTLP_CHECK_INTERVAL. The logger's built-in exporter reports nothing itself, so
+// these handshakes are how the demo knows whether its logs reach the collector.
+func startOTLPMonitor(logOption *option.LogOption, logger core.Logger) *otelsetup.Monitor {
+	cfg := otelsetup.FromLogOption(logOption)
+	cfg.Signals = otelsetup.Logs
+	cfg.Timeout = getDurationEnv("OTLP_PREFLIGHT_TIMEOUT", 2*time.Second)
+	monitor, err := otelsetup.NewMonitor(cfg, logger.With("component", "otlp"), getDurationEnv("OTLP_CHECK_INTERVAL", 30*time.Second))
+	if err != nil {
+		logger.Warnw("OTLP preflight skipped", "error", err.Error())
+		return nil
+	}
+	h := monitor.Check(context.Background())
+	if h.Err != nil {
+		console.Printf("OTLP collector %s unreachable (%s): logs are not exported until it is up, see /debug/otlp\n", h.Endpoint, h.Result)
+	} else {
+		console.Printf("OTLP collector %s reachable over %s (%dms)\n", h.Endpoint, h.Protocol, h.Latency.Milliseconds())
+	}
+	monitor.Start()
+	return monitor
+}
+
+// otlpStatusHandler reports what the last collector checks found; monitor is nil when OTLP
+// is off. The built-in exporter does not expose its queue, so queue_depth is null.
+func otlpStatusHandler(monitor *otelsetup.Monitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if monitor == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		st := monitor.Status()
+		var queueDepth interface{}
+		if st.QueueDepth >= 0 {
+			queueDepth = st.QueueDepth
+		}
+		body := gin.H{
+			"enabled":     true,
+			"protocol":    st.Protocol,
+			"endpoint":    st.Endpoint,
+			"source":      st.Source,
+			"connected":   st.Connected,
+			"failures":    st.Failures,
+			"queue_depth": queueDepth,
+		}
+		if !st.LastSuccess.IsZero() {
+			body["last_success"] = st.LastSuccess.Format(time.RFC3339)
+		}
+		if st.LastError != "" {
+			body["last_error"] = st.LastError
+			body["last_error_at"] = st.LastErrorAt.Format(time.RFC3339)
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// leaked is never closed; the goroutines started by leakHandler block on it for the life
+// of the process
+var leaked = make(chan struct{})
+
+// leakHandler starts count goroutines that never exit
+func leakHandler(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if err != nil || count <= 0 || count > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 10000"})
+		return
+	}
+	for i := 0; i < count; i++ {
+		go func() { <-leaked }()
+	}
+	logcontext.MustFromGin(c).Warnw("Leaked goroutines on request", "count", count, "goroutines", runtime.NumGoroutine())
+	c.JSON(http.StatusOK, gin.H{"leaked": count, "goroutines": runtime.NumGoroutine()})
+}
+
+// adminAuth protects the admin endpoints with a static token
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hmac.Equal([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) {
+			logcontext.MustFromGin(c).Warnw("Unauthorized admin request", "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
 	}
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/dynamicfields"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/logring"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+func serve(r *gin.Engine, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		status  int
+		body    string
+		message string
+		route   string
+	}{
+		{name: "root", target: "/", status: http.StatusOK, body: `"version":"v1.2.3"`, message: "Handling root request", route: "/"},
+		{name: "health", target: "/health", status: http.StatusOK, body: `"status":"healthy"`, message: "Health check requested", route: "/health"},
+		{name: "version", target: "/version", status: http.StatusOK, body: `"go_version"`},
+		{name: "unknown route", target: "/users", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := testlog.New(t)
+			w := serve(newRouter(rec.Logger, "v1.2.3"), http.MethodGet, tt.target, nil)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", w.Body, tt.body)
+			}
+			if tt.message == "" {
+				rec.AssertNotLogged("", "")
+				return
+			}
+			rec.AssertLogged("info", tt.message, "route", tt.route, "method", "GET")
+		})
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	rec := testlog.New(t)
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	serve(newRouter(rec.Logger, "v1.2.3"), http.MethodGet, "/", header)
+	rec.AssertLogged("info", "Handling root request", "route", "/",
+		"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7")
+}
+
+func TestHealthFollowsContract(t *testing.T) {
+	w := serve(newRouter(testlog.New(t).Logger, ""), http.MethodGet, "/health", nil)
+	if problems := health.Validate(w.Body.Bytes()); problems != nil {
+		t.Errorf("/health breaks the contract: %v\n%s", problems, w.Body)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		token   string
+		status  int
+		level   string
+		message string
+		fields  []interface{}
+	}{
+		{name: "missing token", target: "/admin/leak", status: http.StatusUnauthorized,
+			level: "warn", message: "Unauthorized admin request", fields: []interface{}{"path", "/admin/leak"}},
+		{name: "wrong token", target: "/admin/leak", token: "guess", status: http.StatusUnauthorized,
+			level: "warn", message: "Unauthorized admin request"},
+		{name: "invalid count", target: "/admin/leak?count=lots", token: "secret", status: http.StatusBadRequest},
+		{name: "leak", target: "/admin/leak?count=2", token: "secret", status: http.StatusOK,
+			level: "warn", message: "Leaked goroutines on request", fields: []interface{}{"count", 2, "route", "/admin/leak"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := testlog.New(t)
+			r := newRouter(rec.Logger, "")
+			r.POST("/admin/leak", adminAuth("secret"), leakHandler)
+
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set(adminTokenHeader, tt.token)
+			}
+			w := serve(r, http.MethodPost, tt.target, header)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.message == "" {
+				rec.AssertNotLogged("", "")
+				return
+			}
+			rec.AssertLogged(tt.level, tt.message, tt.fields...)
+		})
+	}
+}
+
+func TestOTLPStatus(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer collector.Close()
+	rec := testlog.New(t)
+	monitor, err := otelsetup.NewMonitor(otelsetup.Config{Endpoint: collector.URL, Signals: otelsetup.Logs}, rec.Logger, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor.Check(context.Background())
+
+	tests := []struct {
+		name    string
+		monitor *otelsetup.Monitor
+		token   string
+		status  int
+		body    []string
+	}{
+		{name: "missing token", monitor: monitor, status: http.StatusUnauthorized},
+		{name: "otlp off", token: "secret", status: http.StatusOK, body: []string{`"enabled":false`}},
+		{name: "connected", monitor: monitor, token: "secret", status: http.StatusOK,
+			body: []string{`"connected":true`, `"source":"probe"`, `"queue_depth":null`, `"last_success"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(rec.Logger, "")
+			r.GET("/debug/otlp", adminAuth("secret"), otlpStatusHandler(tt.monitor))
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set(adminTokenHeader, tt.token)
+			}
+			w := serve(r, http.MethodGet, "/debug/otlp", header)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			for _, want := range tt.body {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body = %s, want it to contain %s", w.Body, want)
+				}
+			}
+		})
+	}
+	rec.AssertLogged("info", "OTLP collector reachable", "protocol", otelsetup.ProtocolHTTP, "handshake", "200 OK")
+}
+
+func TestDebugLogs(t *testing.T) {
+	ring := logring.New(10)
+	serviceLogger := ring.Wrap(testlog.New(t).Logger)
+	r := newRouter(serviceLogger, "")
+	r.GET("/debug/logs", adminAuth("secret"), gin.WrapH(ring.Handler()))
+	serve(r, http.MethodGet, "/health", nil)
+
+	if w := serve(r, http.MethodGet, "/debug/logs", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status without token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	header := http.Header{}
+	header.Set(adminTokenHeader, "secret")
+	w := serve(r, http.MethodGet, "/debug/logs?level=warn", header)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	// The rejected request's warning is kept, the health check's info entry filtered out
+	if body := w.Body.String(); !strings.Contains(body, `"message":"Unauthorized admin request"`) || !strings.Contains(body, `"returned":1`) {
+		t.Errorf("body = %s, want only the unauthorized warning", body)
+	}
+}
+
+func TestAdminFields(t *testing.T) {
+	rec := testlog.New(t)
+	dynamic := dynamicfields.New()
+	globals := dynamicfields.NewGlobals(dynamic)
+	serviceLogger := dynamic.Wrap(rec.Logger)
+	r := newRouter(serviceLogger, "")
+	r.POST("/admin/fields", adminAuth("secret"), globals.UpdateHandler(nil, serviceLogger))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/fields", strings.NewReader(`{"set":{"incident_id":"INC-4211"}}`))
+	req.Header.Set(adminTokenHeader, "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// Entries logged after the change carry the field without a restart
+	rec.Reset()
+	serve(r, http.MethodGet, "/health", nil)
+	if n, all := rec.Count("", "", "incident_id", "INC-4211"), len(rec.Entries()); n == 0 || n != all {
+		t.Errorf("%d of %d entries carry incident_id, want all of them", n, all)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ConsumerStats counts what the consumer did with each delivery
+type ConsumerStats struct {
+	Delivered  int
+	Processed  int
+	Duplicates int
+	Redelivery int
+	Invalid    int
+	Restarts   int
+}
+
+// Consumer applies payments to a ledger exactly once per dedup window
+type Consumer struct {
+	brokers []string
+	topic   string
+	group   string
+	store   *DedupStore
+	logger  core.Logger
+
+	ledger map[string]int
+	stats  ConsumerStats
+}
+
+// NewConsumer creates a consumer for the given topic and group
+func NewConsumer(brokers []string, topic, group string, store *DedupStore, logger core.Logger) *Consumer {
+	return &Consumer{
+		brokers: brokers,
+		topic:   topic,
+		group:   group,
+		store:   store,
+		logger:  logger.With("topic", topic, "group", group),
+		ledger:  make(map[string]int),
+	}
+}
+
+// Run consumes until ctx is done. When crashAt is positive the consumer once
+// drops its client after processing a batch without committing offsets, so
+// the group redelivers that batch to the next member.
+func (c *Consumer) Run(ctx context.Context, crashAt time.Duration) error {
+	started := time.Now()
+	for {
+		client, err := c.newClient()
+		if err != nil {
+			return err
+		}
+		c.logger.Infow("Consumer joined group", "restarts", c.stats.Restarts)
+
+		crashed := c.consume(ctx, client, func() bool {
+			return crashAt > 0 && c.stats.Restarts == 0 && time.Since(started) >= crashAt
+		})
+		if !crashed {
+			return nil
+		}
+		c.stats.Restarts++
+	}
+}
+
+func (c *Consumer) newClient() (*kgo.Client, error) {
+	return kgo.NewClient(
+		kgo.SeedBrokers(c.brokers...),
+		kgo.ConsumerGroup(c.group),
+		kgo.ConsumeTopics(c.topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.DisableAutoCommit(),
+		kgo.FetchMaxWait(500*time.Millisecond),
+	)
+}
+
+// consume polls until ctx is done or shouldCrash fires; it reports whether it crashed
+func (c *Consumer) consume(ctx context.Context, client *kgo.Client, shouldCrash func() bool) bool {
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			c.commit(client)
+			client.Close()
+			return false
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if !errors.Is(err, context.Canceled) {
+				c.logger.Warnw("Fetch failed", "partition", partition, "error", err.Error())
+			}
+		})
+		if fetches.NumRecords() == 0 {
+			continue
+		}
+
+		fetches.EachRecord(c.handle)
+
+		if shouldCrash() {
+			c.logger.Warnw("Simulating consumer crash before offset commit, batch will be redelivered",
+				"uncommitted", fetches.NumRecords(),
+			)
+			// Close leaves the group without committing because autocommit is disabled
+			client.Close()
+			return true
+		}
+		c.commit(client)
+	}
+}
+
+func (c *Consumer) commit(client *kgo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.CommitUncommittedOffsets(ctx); err != nil {
+		c.logger.Warnw("Offset commit failed", "error", err.Error())
+	}
+}
+
+func (c *Consumer) handle(record *kgo.Record) {
+	c.stats.Delivered++
+	key := string(record.Key)
+
+	if first, ok := c.store.Lookup(key); ok {
+		// Same partition and offset means the broker delivered the record again;
+		// a different offset means the same message was published twice
+		reason := "duplicate_publish"
+		if first.Partition == record.Partition && first.Offset == record.Offset {
+			reason = "redelivery"
+			c.stats.Redelivery++
+		}
+		c.stats.Duplicates++
+		c.logger.Infow("Duplicate message skipped",
+			"key", key,
+			"reason", reason,
+			"partition", record.Partition,
+			"offset", record.Offset,
+			"first_offset", first.Offset,
+			"first_seen_ago", time.Since(first.SeenAt).Round(time.Millisecond).String(),
+		)
+		return
+	}
+
+	var payment Payment
+	if err := json.Unmarshal(record.Value, &payment); err != nil {
+		c.stats.Invalid++
+		c.logger.Errorw("Message rejected", "key", key, "partition", record.Partition, "offset", record.Offset, "error", err.Error())
+		return
+	}
+
+	// Apply the side effect first, then remember the key; a crash in between
+	// causes one reprocessing, which is why the store belongs next to the ledger
+	c.ledger[payment.Account] += payment.AmountCents
+	c.store.Remember(key, record.Partition, record.Offset)
+	c.stats.Processed++
+	c.logger.Infow("Message processed",
+		"key", key,
+		"partition", record.Partition,
+		"offset", record.Offset,
+		"account", payment.Account,
+		"amount_cents", payment.AmountCents,
+	)
+}
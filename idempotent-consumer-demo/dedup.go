@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// seenEntry records where a key was first processed
+type seenEntry struct {
+	Partition int32
+	Offset    int64
+	SeenAt    time.Time
+}
+
+// DedupStore remembers processed message keys for a TTL window.
+// A production consumer would keep this in Redis (SET NX EX) or in the same
+// database transaction as the side effect; the semantics are the same.
+type DedupStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]seenEntry
+	expired int
+	logger  core.Logger
+}
+
+// NewDedupStore creates an empty store with the given window
+func NewDedupStore(ttl time.Duration, logger core.Logger) *DedupStore {
+	return &DedupStore{
+		ttl:     ttl,
+		entries: make(map[string]seenEntry),
+		logger:  logger,
+	}
+}
+
+// Lookup reports whether key was processed within the window
+func (s *DedupStore) Lookup(key string) (seenEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || time.Since(entry.SeenAt) > s.ttl {
+		return seenEntry{}, false
+	}
+	return entry, true
+}
+
+// Remember marks key as processed; call it only after the side effect succeeded
+func (s *DedupStore) Remember(key string, partition int32, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = seenEntry{Partition: partition, Offset: offset, SeenAt: time.Now()}
+}
+
+// Run sweeps expired keys until ctx is done
+func (s *DedupStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *DedupStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for key, entry := range s.entries {
+		age := time.Since(entry.SeenAt)
+		if age <= s.ttl {
+			continue
+		}
+		delete(s.entries, key)
+		expired++
+		s.logger.Debugw("Dedup window expired", "key", key, "age", age.Round(time.Millisecond).String())
+	}
+	if expired == 0 {
+		return
+	}
+	s.expired += expired
+	s.logger.Infow("Dedup windows expired",
+		"expired", expired,
+		"live_keys", len(s.entries),
+		"ttl", s.ttl.String(),
+	)
+}
+
+// Stats returns the number of live and expired keys
+func (s *DedupStore) Stats() (live, expired int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries), s.expired
+}
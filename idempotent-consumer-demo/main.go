@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Idempotent Consumer Demo ===")
+	console.Println("Kafka consumer that skips duplicate deliveries using a TTL dedup store")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	topic := getEnvOrDefault("KAFKA_TOPIC", "payments")
+	group := getEnvOrDefault("KAFKA_GROUP", "ledger")
+	ttl := getDurationEnv("DEDUP_TTL", 5*time.Second)
+
+	// Without external brokers, run an in-process Kafka cluster so the demo is self-contained
+	var brokers []string
+	if env := os.Getenv("KAFKA_BROKERS"); env != "" {
+		brokers = strings.Split(env, ",")
+	} else {
+		cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topic))
+		if err != nil {
+			crash.Fatal(baseLogger, "Failed to start in-process Kafka", "error", err.Error())
+		}
+		defer cluster.Close()
+		brokers = cluster.ListenAddrs()
+		baseLogger.Infow("Started in-process Kafka cluster", "brokers", brokers, "topic", topic, "partitions", 3)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelRun := context.WithTimeout(ctx, getDurationEnv("DURATION", 20*time.Second))
+	defer cancelRun()
+
+	store := NewDedupStore(ttl, baseLogger.With("component", "dedup-store"))
+	go store.Run(ctx, time.Second)
+
+	consumer := NewConsumer(brokers, topic, group, store, baseLogger.With("component", "consumer"))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := consumer.Run(ctx, getDurationEnv("CRASH_AT", 6*time.Second)); err != nil {
+			baseLogger.Errorw("Consumer stopped", "error", err.Error())
+		}
+	}()
+
+	producerLogger := baseLogger.With("component", "producer", "topic", topic)
+	producerClient, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic(topic))
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to create producer", "error", err.Error())
+	}
+	defer producerClient.Close()
+
+	sanitize.Startup(baseLogger, logOption, "brokers", brokers, "topic", topic, "group", group)
+	baseLogger.Infow("Starting idempotent consumer demo",
+		"brokers", brokers,
+		"topic", topic,
+		"group", group,
+		"dedup_ttl", ttl.String(),
+	)
+	produced := runProducer(ctx, producerClient,
+		getIntEnv("RATE", 5),
+		getFloatEnv("RETRY_RATE", 0.2),
+		getFloatEnv("REPLAY_RATE", 0.1),
+		producerLogger,
+	)
+	wg.Wait()
+
+	live, expired := store.Stats()
+	stats := consumer.stats
+	baseLogger.Infow("Demo finished",
+		"published", produced.Published,
+		"unique_keys", produced.UniqueKeys,
+		"producer_retries", produced.Retries,
+		"upstream_replays", produced.Replays,
+		"delivered", stats.Delivered,
+		"processed", stats.Processed,
+		"duplicates_skipped", stats.Duplicates,
+		"redeliveries_skipped", stats.Redelivery,
+		"consumer_restarts", stats.Restarts,
+		// Replays that arrive after the window has expired are processed again
+		"reprocessed_after_expiry", stats.Processed-produced.UniqueKeys,
+		"dedup_live_keys", live,
+		"dedup_expired_keys", expired,
+		"ledger_cents", consumer.ledger,
+	)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 && f <= 1 {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
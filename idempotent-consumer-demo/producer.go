@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Payment is the message body; Key doubles as the idempotency key
+type Payment struct {
+	Key         string `json:"key"`
+	Account     string `json:"account"`
+	AmountCents int    `json:"amount_cents"`
+}
+
+// ProducerStats counts what was published
+type ProducerStats struct {
+	Published  int
+	UniqueKeys int
+	Retries    int
+	Replays    int
+}
+
+// runProducer publishes payments, re-sending some immediately (a producer retry
+// after a lost ack) and some much later (an upstream replay)
+func runProducer(ctx context.Context, client *kgo.Client, rate int, retryRate, replayRate float64, logger core.Logger) ProducerStats {
+	var stats ProducerStats
+	var history []Payment
+	accounts := []string{"acct-alice", "acct-bob", "acct-carol"}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return stats
+		case <-ticker.C:
+		}
+
+		payment := Payment{
+			Key:         fmt.Sprintf("pay-%05d", len(history)+1),
+			Account:     accounts[rng.Intn(len(accounts))],
+			AmountCents: 100 + rng.Intn(9900),
+		}
+		if !publish(ctx, client, payment, logger) {
+			continue
+		}
+		history = append(history, payment)
+		stats.Published++
+		stats.UniqueKeys++
+
+		if rng.Float64() < retryRate {
+			if publish(ctx, client, payment, logger) {
+				stats.Published++
+				stats.Retries++
+				logger.Infow("Message re-sent after simulated lost ack", "key", payment.Key)
+			}
+		}
+		if len(history) > 1 && rng.Float64() < replayRate {
+			old := history[rng.Intn(len(history)-1)]
+			if publish(ctx, client, old, logger) {
+				stats.Published++
+				stats.Replays++
+				logger.Infow("Old message replayed upstream", "key", old.Key)
+			}
+		}
+	}
+}
+
+func publish(ctx context.Context, client *kgo.Client, payment Payment, logger core.Logger) bool {
+	value, _ := json.Marshal(payment)
+	record := &kgo.Record{Key: []byte(payment.Key), Value: value}
+	if err := client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		if ctx.Err() == nil {
+			logger.Warnw("Publish failed", "key", payment.Key, "error", err.Error())
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// queues maps each queue to its weight; higher weights are polled more often
+var queues = map[string]int{"critical": 6, "default": 3, "low": 1}
+
+var queueOrder = []string{"critical", "default", "low"}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Background Job Queue Demo ===")
+	console.Println("asynq producer and worker with per-task loggers, retries and archival")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	// Without an external Redis, run an in-process one so the demo is self-contained
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		mr, err := miniredis.Run()
+		if err != nil {
+			crash.Fatal(baseLogger, "Failed to start in-process Redis", "error", err.Error())
+		}
+		defer mr.Close()
+		addr = mr.Addr()
+		baseLogger.Infow("Started in-process Redis", "addr", addr)
+	}
+	redisOpt := asynq.RedisClientOpt{Addr: addr}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelRun := context.WithTimeout(ctx, getDurationEnv("DURATION", 20*time.Second))
+	defer cancelRun()
+
+	workerLogger := baseLogger.With("component", "worker")
+	delay := retryDelay(getDurationEnv("RETRY_BASE", time.Second))
+	srv := asynq.NewServer(redisOpt, asynq.Config{
+		Concurrency:              getIntEnv("CONCURRENCY", 4),
+		Queues:                   queues,
+		RetryDelayFunc:           delay,
+		ErrorHandler:             failureLogger(workerLogger, delay),
+		DelayedTaskCheckInterval: time.Second,
+		// core.Logger already has asynq's Debug/Info/Warn/Error/Fatal methods
+		Logger:   baseLogger.With("component", "asynq"),
+		LogLevel: asynq.WarnLevel,
+	})
+	mux := asynq.NewServeMux()
+	mux.Use(taskLogging(workerLogger))
+	handlers := &Handlers{failRate: getFloatEnv("FAIL_RATE", 0.3)}
+	handlers.Register(mux)
+	if err := srv.Start(mux); err != nil {
+		crash.Fatal(baseLogger, "Failed to start worker", "error", err.Error())
+	}
+
+	client := asynq.NewClient(redisOpt)
+	defer client.Close()
+
+	sanitize.Startup(baseLogger, logOption, "redis", addr, "concurrency", getIntEnv("CONCURRENCY", 4))
+	baseLogger.Infow("Starting job queue demo",
+		"redis", addr,
+		"queues", queues,
+		"fail_rate", handlers.failRate,
+	)
+	enqueued := runProducer(ctx, client, getIntEnv("RATE", 3), baseLogger.With("component", "producer"))
+
+	// Give in-flight tasks the shutdown timeout to finish before reading the queues
+	srv.Shutdown()
+	reportQueues(redisOpt, enqueued, baseLogger.With("component", "inspector"))
+}
+
+// runProducer enqueues a mix of tasks until ctx is done and returns how many were accepted
+func runProducer(ctx context.Context, client *asynq.Client, rate int, log core.Logger) int {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	enqueued := 0
+	for n := 1; ; n++ {
+		select {
+		case <-ctx.Done():
+			return enqueued
+		case <-ticker.C:
+		}
+
+		task, err := nextTask(n)
+		if err != nil {
+			log.Errorw("Failed to build task", "error", err.Error())
+			continue
+		}
+		// asynq defaults to UUIDs; prefixed ULIDs match the IDs the other demos log
+		info, err := client.EnqueueContext(ctx, task, asynq.TaskID(requestid.New(requestid.Job)))
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnw("Enqueue failed", "task_type", task.Type(), "error", err.Error())
+			}
+			continue
+		}
+		enqueued++
+		log.Infow("Task enqueued",
+			"task_id", info.ID,
+			"queue", info.Queue,
+			"task_type", info.Type,
+			"max_retry", info.MaxRetry,
+		)
+	}
+}
+
+// nextTask picks the n-th task: mostly healthy work, with some invalid resizes and
+// invoices for a provider that is down
+func nextTask(n int) (*asynq.Task, error) {
+	switch n % 3 {
+	case 0:
+		return NewEmailTask(EmailPayload{To: fmt.Sprintf("user%d@example.com", n), Template: "welcome"})
+	case 1:
+		width := 320
+		if rng.Float64() < 0.25 {
+			width = -1
+		}
+		return NewResizeTask(ResizePayload{ImageID: fmt.Sprintf("img-%04d", n), Width: width})
+	default:
+		provider := "stripe"
+		if rng.Float64() < 0.3 {
+			provider = "legacy-erp"
+		}
+		return NewInvoiceTask(InvoicePayload{InvoiceID: fmt.Sprintf("inv-%04d", n), Provider: provider})
+	}
+}
+
+// reportQueues logs per-queue totals and every archived task with the error that archived it
+func reportQueues(redisOpt asynq.RedisClientOpt, enqueued int, log core.Logger) {
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+
+	for _, queue := range queueOrder {
+		info, err := inspector.GetQueueInfo(queue)
+		if err != nil {
+			log.Warnw("Failed to read queue", "queue", queue, "error", err.Error())
+			continue
+		}
+		log.Infow("Queue summary",
+			"queue", queue,
+			"processed", info.ProcessedTotal,
+			"failed", info.FailedTotal,
+			"pending", info.Pending,
+			"retry", info.Retry,
+			"archived", info.Archived,
+		)
+
+		archived, err := inspector.ListArchivedTasks(queue)
+		if err != nil {
+			log.Warnw("Failed to list archived tasks", "queue", queue, "error", err.Error())
+			continue
+		}
+		for _, task := range archived {
+			log.Warnw("Archived task",
+				"task_id", task.ID,
+				"queue", task.Queue,
+				"task_type", task.Type,
+				"retried", task.Retried,
+				"last_error", task.LastErr,
+				"last_failed_at", task.LastFailedAt.Format(time.RFC3339),
+			)
+		}
+	}
+	log.Infow("Demo finished", "enqueued", enqueued)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 && f <= 1 {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/rng"
+)
+
+// Task types handled by the worker
+const (
+	TypeEmailSend   = "email:send"
+	TypeImageResize = "image:resize"
+	TypeInvoiceSync = "invoice:sync"
+)
+
+// EmailPayload asks the worker to render and send one email
+type EmailPayload struct {
+	To       string `json:"to"`
+	Template string `json:"template"`
+}
+
+// ResizePayload asks the worker to produce a thumbnail of the given width
+type ResizePayload struct {
+	ImageID string `json:"image_id"`
+	Width   int    `json:"width"`
+}
+
+// InvoicePayload asks the worker to push an invoice to an external provider
+type InvoicePayload struct {
+	InvoiceID string `json:"invoice_id"`
+	Provider  string `json:"provider"`
+}
+
+// NewEmailTask creates a high-priority email task; SMTP hiccups are retried a few times
+func NewEmailTask(p EmailPayload) (*asynq.Task, error) {
+	return newTask(TypeEmailSend, p, asynq.Queue("critical"), asynq.MaxRetry(3))
+}
+
+// NewResizeTask creates a low-priority thumbnail task
+func NewResizeTask(p ResizePayload) (*asynq.Task, error) {
+	return newTask(TypeImageResize, p, asynq.Queue("low"), asynq.MaxRetry(3))
+}
+
+// NewInvoiceTask creates an invoice sync task with a short retry budget
+func NewInvoiceTask(p InvoicePayload) (*asynq.Task, error) {
+	return newTask(TypeInvoiceSync, p, asynq.Queue("default"), asynq.MaxRetry(2), asynq.Timeout(5*time.Second))
+}
+
+func newTask(taskType string, payload any, opts ...asynq.Option) (*asynq.Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", taskType, err)
+	}
+	return asynq.NewTask(taskType, data, opts...), nil
+}
+
+// Handlers simulates the work; failRate is the chance a send hits a transient SMTP error
+type Handlers struct {
+	failRate float64
+}
+
+// Register wires every task type into mux
+func (h *Handlers) Register(mux *asynq.ServeMux) {
+	mux.HandleFunc(TypeEmailSend, h.sendEmail)
+	mux.HandleFunc(TypeImageResize, h.resizeImage)
+	mux.HandleFunc(TypeInvoiceSync, h.syncInvoice)
+}
+
+func (h *Handlers) sendEmail(ctx context.Context, t *asynq.Task) error {
+	var p EmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("malformed payload: %v: %w", err, asynq.SkipRetry)
+	}
+	log := logcontext.FromContext(ctx).With("to", p.To, "template", p.Template)
+
+	time.Sleep(time.Duration(50+rng.Intn(150)) * time.Millisecond)
+	if rng.Float64() < h.failRate {
+		return fmt.Errorf("smtp: connection to mail relay timed out")
+	}
+	log.Infow("Email sent")
+	return nil
+}
+
+func (h *Handlers) resizeImage(ctx context.Context, t *asynq.Task) error {
+	var p ResizePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("malformed payload: %v: %w", err, asynq.SkipRetry)
+	}
+	// Retrying cannot fix a bad request, so the task goes straight to the archive
+	if p.Width <= 0 || p.Width > 4096 {
+		return fmt.Errorf("invalid width %d: %w", p.Width, asynq.SkipRetry)
+	}
+
+	time.Sleep(time.Duration(100+rng.Intn(200)) * time.Millisecond)
+	logcontext.Infow(ctx, "Thumbnail written", "image_id", p.ImageID, "width", p.Width)
+	return nil
+}
+
+func (h *Handlers) syncInvoice(ctx context.Context, t *asynq.Task) error {
+	var p InvoicePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("malformed payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	time.Sleep(time.Duration(50+rng.Intn(100)) * time.Millisecond)
+	// The legacy provider is down for the whole demo, so these exhaust their retries
+	if p.Provider == "legacy-erp" {
+		return fmt.Errorf("provider %s returned 502 Bad Gateway", p.Provider)
+	}
+	logcontext.Infow(ctx, "Invoice synced", "invoice_id", p.InvoiceID, "provider", p.Provider)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/logger/core"
+)
+
+// taskLogger derives a logger carrying the task's identity from the metadata asynq puts in ctx
+func taskLogger(ctx context.Context, base core.Logger, t *asynq.Task) core.Logger {
+	taskID, _ := asynq.GetTaskID(ctx)
+	queue, _ := asynq.GetQueueName(ctx)
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	return base.With(
+		"task_id", taskID,
+		"queue", queue,
+		"task_type", t.Type(),
+		"attempt", retried+1,
+		"max_retry", maxRetry,
+	)
+}
+
+// taskLogging gives each handler invocation its own logger and records successful runs;
+// failures are reported by the error handler, which knows whether the task will retry
+func taskLogging(base core.Logger) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			log := taskLogger(ctx, base, t)
+			start := time.Now()
+			log.Debugw("Task started")
+
+			err := next.ProcessTask(logcontext.WithLogger(ctx, log), t)
+			if err == nil {
+				log.Infow("Task succeeded", "duration_ms", time.Since(start).Milliseconds())
+			}
+			return err
+		})
+	}
+}
+
+// retryDelay backs off linearly so retries show up within the demo's run time
+func retryDelay(base time.Duration) asynq.RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		return time.Duration(n) * base
+	}
+}
+
+// failureLogger logs every failed attempt with the reason and what asynq does next:
+// schedule a retry, or archive the task because it opted out of retries or ran out of them
+func failureLogger(base core.Logger, delay asynq.RetryDelayFunc) asynq.ErrorHandlerFunc {
+	return func(ctx context.Context, t *asynq.Task, err error) {
+		log := taskLogger(ctx, base, t)
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+
+		switch {
+		case errors.Is(err, asynq.SkipRetry):
+			log.Errorw("Task archived", "reason", "non_retryable", "error", err.Error())
+		case retried >= maxRetry:
+			log.Errorw("Task archived", "reason", "retries_exhausted", "error", err.Error())
+		default:
+			log.Warnw("Task failed, retry scheduled",
+				"error", err.Error(),
+				"retry_in", delay(retried+1, err, t).String(),
+				"retries_left", maxRetry-retried,
+			)
+		}
+	}
+}
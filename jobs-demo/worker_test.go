@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestTaskLoggingHandsLoggerToHandler(t *testing.T) {
+	rec := testlog.New(t)
+	handler := taskLogging(rec.Logger)(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		logcontext.FromContext(ctx).Infow("Email rendered", "template", "welcome")
+		return nil
+	}))
+
+	if err := handler.ProcessTask(context.Background(), asynq.NewTask(TypeEmailSend, nil)); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	rec.AssertLogged("info", "Email rendered", "task_type", TypeEmailSend, "attempt", 1, "template", "welcome")
+	rec.AssertLogged("info", "Task succeeded", "task_type", TypeEmailSend)
+}
+
+func TestTaskLoggingLeavesFailuresToErrorHandler(t *testing.T) {
+	rec := testlog.New(t)
+	handler := taskLogging(rec.Logger)(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		return errors.New("smtp: connection refused")
+	}))
+
+	if err := handler.ProcessTask(context.Background(), asynq.NewTask(TypeEmailSend, nil)); err == nil {
+		t.Fatal("ProcessTask succeeded, want the handler's error")
+	}
+	rec.AssertNotLogged("", "Task succeeded")
+}
+
+func TestFailureLoggerArchivesNonRetryable(t *testing.T) {
+	rec := testlog.New(t)
+	err := fmt.Errorf("width must be positive: %w", asynq.SkipRetry)
+	failureLogger(rec.Logger, retryDelay(0))(context.Background(), asynq.NewTask(TypeImageResize, nil), err)
+
+	rec.AssertLogged("error", "Task archived", "task_type", TypeImageResize, "reason", "non_retryable", "error", err.Error())
+	rec.AssertNotLogged("", "retry scheduled")
+}
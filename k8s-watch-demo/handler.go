@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podEventHandler logs informer notifications with resource metadata
+type podEventHandler struct {
+	logger core.Logger
+
+	initialAdds atomic.Int64
+	adds        atomic.Int64
+	updates     atomic.Int64
+	resyncs     atomic.Int64
+	deletes     atomic.Int64
+}
+
+// OnAdd is called for new pods and for every object during the initial list
+func (h *podEventHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	h.adds.Add(1)
+	if isInInitialList {
+		h.initialAdds.Add(1)
+		h.logger.Debugw("Pod listed during initial sync", podFields(pod)...)
+		return
+	}
+	h.logger.Infow("Pod added", podFields(pod)...)
+}
+
+// OnUpdate is called on changes and on every periodic resync
+func (h *podEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	oldPod, ok1 := oldObj.(*corev1.Pod)
+	newPod, ok2 := newObj.(*corev1.Pod)
+	if !ok1 || !ok2 {
+		return
+	}
+
+	// A resync re-delivers the cached object unchanged
+	if oldPod.ResourceVersion == newPod.ResourceVersion {
+		h.resyncs.Add(1)
+		return
+	}
+
+	h.updates.Add(1)
+	fields := podFields(newPod)
+	if oldPod.Status.Phase != newPod.Status.Phase {
+		fields = append(fields, "previous_phase", string(oldPod.Status.Phase))
+	}
+
+	oldRestarts, newRestarts := restartCount(oldPod), restartCount(newPod)
+	if newRestarts > oldRestarts {
+		h.logger.Warnw("Pod container restarted", append(fields, "restart_count", newRestarts)...)
+		return
+	}
+	if newPod.DeletionTimestamp != nil && oldPod.DeletionTimestamp == nil {
+		h.logger.Infow("Pod terminating", append(fields, "grace_period_seconds", gracePeriod(newPod))...)
+		return
+	}
+	h.logger.Infow("Pod updated", fields...)
+}
+
+// OnDelete handles both pods and tombstones for deletions missed during a re-list
+func (h *podEventHandler) OnDelete(obj interface{}) {
+	h.deletes.Add(1)
+
+	switch t := obj.(type) {
+	case *corev1.Pod:
+		h.logger.Infow("Pod deleted", podFields(t)...)
+	case cache.DeletedFinalStateUnknown:
+		pod, ok := t.Obj.(*corev1.Pod)
+		if !ok {
+			h.logger.Warnw("Pod deleted (tombstone with unexpected object)", "key", t.Key)
+			return
+		}
+		h.logger.Warnw("Pod deleted while watch was disconnected", append(podFields(pod), "key", t.Key)...)
+	}
+}
+
+// reportResyncs logs how many unchanged objects each resync period re-delivered
+func (h *podEventHandler) reportResyncs(ctx context.Context, period time.Duration) {
+	if period <= 0 {
+		return
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	var last int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			total := h.resyncs.Load()
+			h.logger.Infow("Informer resync observed",
+				"resync_period", period.String(),
+				"resynced_objects", total-last,
+				"resynced_total", total,
+				"updates_total", h.updates.Load(),
+			)
+			last = total
+		}
+	}
+}
+
+func gracePeriod(pod *corev1.Pod) int64 {
+	if pod.DeletionGracePeriodSeconds == nil {
+		return 0
+	}
+	return *pod.DeletionGracePeriodSeconds
+}
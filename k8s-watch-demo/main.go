@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/k8smeta"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func main() {
+	defaultKubeconfig := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		defaultKubeconfig = filepath.Join(home, ".kube", "config")
+	}
+
+	kubeconfig := flag.String("kubeconfig", getEnvOrDefault("KUBECONFIG", defaultKubeconfig), "path to kubeconfig (ignored in-cluster)")
+	// In a pod, default to watching the pod's own namespace
+	podMeta, podErr := k8smeta.Detect()
+	defaultNamespace := "default"
+	if podMeta.Namespace != "" {
+		defaultNamespace = podMeta.Namespace
+	}
+	namespace := flag.String("namespace", getEnvOrDefault("WATCH_NAMESPACE", defaultNamespace), "namespace to watch")
+	resync := flag.Duration("resync", 30*time.Second, "informer resync period")
+	// Adds --log-level and the other shared flags to the ones above
+	cliflags.Parse()
+
+	versionInfo := version.Get()
+
+	console.Println("=== Kubernetes Pod Watcher Demo ===")
+	console.Printf("Watching pods in namespace %q (resync every %s)\n\n", *namespace, *resync)
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "debug",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "pod-watcher",
+			"k8s.namespace":   *namespace,
+		},
+	}
+
+	// The watcher's own pod, namespace and node. Event lines use k8s.pod.name for the pod they
+	// report on, so the watcher's identity goes under watcher.* instead.
+	for key, value := range podMeta.Fields() {
+		logOption.InitialFields["watcher."+key] = value
+	}
+
+	cliflags.Apply(logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	sanitize.Startup(serviceLogger, logOption, "kubeconfig", *kubeconfig, "namespace", *namespace, "resync", resync.String())
+
+	if podErr != nil {
+		serviceLogger.Debugw("No pod metadata found, running outside a cluster")
+	}
+
+	config, source, err := loadConfig(*kubeconfig)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to load Kubernetes config", "kubeconfig", *kubeconfig, "error", err.Error())
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to create Kubernetes client", "error", err.Error())
+	}
+
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to reach API server", "host", config.Host, "error", err.Error())
+	}
+	serviceLogger.Infow("Connected to Kubernetes API server",
+		"host", config.Host,
+		"config_source", source,
+		"server_version", serverVersion.GitVersion,
+	)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, *resync, informers.WithNamespace(*namespace))
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	handler := &podEventHandler{logger: serviceLogger}
+	if _, err := podInformer.AddEventHandler(handler); err != nil {
+		crash.Fatal(serviceLogger, "Failed to register event handler", "error", err.Error())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	start := time.Now()
+	factory.Start(ctx.Done())
+	serviceLogger.Infow("Waiting for informer cache sync")
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		crash.Fatal(serviceLogger, "Informer cache failed to sync")
+	}
+	serviceLogger.Infow("Informer cache synced",
+		"pods", len(podInformer.GetStore().ListKeys()),
+		"initial_adds", handler.initialAdds.Load(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	go handler.reportResyncs(ctx, *resync)
+
+	<-ctx.Done()
+	factory.Shutdown()
+	serviceLogger.Infow("Pod watcher stopped",
+		"adds", handler.adds.Load(),
+		"updates", handler.updates.Load(),
+		"resyncs", handler.resyncs.Load(),
+		"deletes", handler.deletes.Load(),
+	)
+}
+
+// loadConfig prefers in-cluster config and falls back to a kubeconfig file
+func loadConfig(kubeconfig string) (*rest.Config, string, error) {
+	if config, err := rest.InClusterConfig(); err == nil {
+		return config, "in-cluster", nil
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, "", err
+	}
+	return config, "kubeconfig", nil
+}
+
+// podFields returns the resource metadata logged with every pod event
+func podFields(pod *corev1.Pod) []interface{} {
+	fields := []interface{}{
+		"k8s.pod.name", pod.Name,
+		"k8s.pod.uid", string(pod.UID),
+		"k8s.pod.phase", string(pod.Status.Phase),
+		"k8s.node.name", pod.Spec.NodeName,
+		"resource_version", pod.ResourceVersion,
+	}
+	if owner := firstOwner(pod); owner != "" {
+		fields = append(fields, "k8s.owner", owner)
+	}
+	if app := pod.Labels["app"]; app != "" {
+		fields = append(fields, "k8s.label.app", app)
+	}
+	return fields
+}
+
+func firstOwner(pod *corev1.Pod) string {
+	if len(pod.OwnerReferences) == 0 {
+		return ""
+	}
+	ref := pod.OwnerReferences[0]
+	return ref.Kind + "/" + ref.Name
+}
+
+func restartCount(pod *corev1.Pod) int32 {
+	var total int32
+	for _, status := range pod.Status.ContainerStatuses {
+		total += status.RestartCount
+	}
+	return total
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
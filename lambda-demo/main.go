@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// The logger is created once per execution environment (cold start) and reused
+// by every warm invocation that lands on the same container.
+var (
+	baseLogger  core.Logger
+	initStarted = time.Now()
+	initDone    time.Duration
+	invocations atomic.Int64
+)
+
+func init() {
+	// The logger is built here, before main, so the flags are parsed here too
+	cliflags.Parse()
+	// stdout is the function's log stream, so the local simulation's text goes to stderr
+	console.DefaultStderr()
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:        "zap",
+		Level:         getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:        "json",
+		OutputPaths:   []string{"stdout"}, // CloudWatch Logs captures stdout
+		DisableCaller: true,
+		InitialFields: map[string]interface{}{
+			"service.name":       versionInfo.ServiceName,
+			"service.version":    versionInfo.GitVersion,
+			"faas.name":          getEnvOrDefault("AWS_LAMBDA_FUNCTION_NAME", "local-function"),
+			"faas.version":       getEnvOrDefault("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST"),
+			"faas.max_memory_mb": getEnvOrDefault("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "128"),
+			"cloud.region":       getEnvOrDefault("AWS_REGION", "us-east-1"),
+			"log_stream":         getEnvOrDefault("AWS_LAMBDA_LOG_STREAM_NAME", "local"),
+		},
+	}
+
+	cliflags.Apply(logOption)
+
+	var err error
+	baseLogger, err = logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", baseLogger.Flush)
+
+	sanitize.Startup(baseLogger, logOption, "runtime_api", os.Getenv("AWS_LAMBDA_RUNTIME_API") != "")
+
+	initDone = time.Since(initStarted)
+	baseLogger.Infow("Lambda execution environment initialized",
+		"init_duration_ms", initDone.Milliseconds(),
+		"go_version", versionInfo.GoVersion,
+	)
+}
+
+// handleRequest is the Lambda entrypoint for API Gateway proxy events
+func handleRequest(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	start := time.Now()
+	count := invocations.Add(1)
+
+	// Per-invocation child logger with request id and ARN
+	invocationLogger := baseLogger.With("cold_start", count == 1, "invocation", count)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		invocationLogger = invocationLogger.With(
+			"faas.execution", lc.AwsRequestID,
+			"faas.invoked_arn", lc.InvokedFunctionArn,
+		)
+	}
+	if req.RequestContext.RequestID != "" {
+		invocationLogger = invocationLogger.With("apigw_request_id", req.RequestContext.RequestID)
+	}
+
+	// Lambda may freeze the environment as soon as the handler returns, so
+	// buffered entries must be flushed before that happens.
+	defer func() {
+		if err := invocationLogger.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger flush failed: %v\n", err)
+		}
+	}()
+
+	var remaining time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining = time.Until(deadline)
+	}
+	invocationLogger.Infow("Invocation started",
+		"http.method", req.HTTPMethod,
+		"http.path", req.Path,
+		"remaining_ms", remaining.Milliseconds(),
+	)
+
+	orderID := req.PathParameters["id"]
+	if orderID == "" {
+		invocationLogger.Warnw("Missing order id", "path_parameters", req.PathParameters)
+		return respond(invocationLogger, start, http.StatusBadRequest, map[string]string{"error": "missing order id"})
+	}
+
+	order, err := lookupOrder(ctx, orderID)
+	if err != nil {
+		invocationLogger.Errorw("Order lookup failed", "order_id", orderID, "error", err.Error())
+		return respond(invocationLogger, start, http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	invocationLogger.Infow("Order found", "order_id", orderID, "status", order["status"])
+	return respond(invocationLogger, start, http.StatusOK, order)
+}
+
+func respond(l core.Logger, start time.Time, status int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, err
+	}
+
+	l.Infow("Invocation completed",
+		"http.status_code", status,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"response_bytes", len(payload),
+	)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(payload),
+	}, nil
+}
+
+func lookupOrder(ctx context.Context, id string) (map[string]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(15 * time.Millisecond):
+	}
+
+	if id == "missing" {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+	return map[string]string{"id": id, "status": "shipped"}, nil
+}
+
+func main() {
+	defer crash.Recover(baseLogger)
+	// Lambda freezes the environment between invocations, so usage is only logged while one runs
+	defer resusage.FromEnv(baseLogger)()
+
+	// Inside Lambda the runtime API is available; otherwise simulate a few invocations locally
+	if os.Getenv("AWS_LAMBDA_RUNTIME_API") != "" {
+		lambda.Start(handleRequest)
+		return
+	}
+
+	console.Println("AWS_LAMBDA_RUNTIME_API not set - simulating invocations locally")
+	arn := "arn:aws:lambda:us-east-1:123456789012:function:local-function"
+	for i, orderID := range []string{"o-1001", "o-1002", "missing", ""} {
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+			AwsRequestID:       fmt.Sprintf("local-req-%04d", i+1),
+			InvokedFunctionArn: arn,
+		})
+		ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:     http.MethodGet,
+			Path:           "/orders/" + orderID,
+			PathParameters: map[string]string{"id": orderID},
+		}
+		req.RequestContext.RequestID = fmt.Sprintf("apigw-%04d", i+1)
+
+		resp, err := handleRequest(ctx, req)
+		cancel()
+		if err != nil {
+			console.Printf("invocation %d failed: %v\n", i+1, err)
+			continue
+		}
+		console.Printf("invocation %d -> %d %s\n", i+1, resp.StatusCode, resp.Body)
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockLoki imitates Loki's push endpoint, including rate limiting and transient failures
+type MockLoki struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	requests atomic.Uint64
+	lines    atomic.Uint64
+
+	// Every throttleEvery-th request gets a 429, every failEvery-th a 500
+	throttleEvery uint64
+	failEvery     uint64
+	latency       time.Duration
+}
+
+// StartMockLoki listens on addr and serves POST /loki/api/v1/push
+func StartMockLoki(addr string, logger core.Logger) (*MockLoki, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockLoki{
+		logger:        logger,
+		listener:      listener,
+		throttleEvery: 4,
+		failEvery:     7,
+		latency:       50 * time.Millisecond,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loki/api/v1/push", m.handlePush)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// PushURL returns the full push endpoint URL
+func (m *MockLoki) PushURL() string {
+	return "http://" + m.listener.Addr().String() + "/loki/api/v1/push"
+}
+
+// Lines returns how many log lines were accepted
+func (m *MockLoki) Lines() uint64 {
+	return m.lines.Load()
+}
+
+// Close stops the server
+func (m *MockLoki) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockLoki) handlePush(w http.ResponseWriter, r *http.Request) {
+	n := m.requests.Add(1)
+	time.Sleep(m.latency)
+
+	switch {
+	case m.throttleEvery > 0 && n%m.throttleEvery == 0:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "ingestion rate limit exceeded", http.StatusTooManyRequests)
+		return
+	case m.failEvery > 0 && n%m.failEvery == 0:
+		http.Error(w, "ingester unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	var payload struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, stream := range payload.Streams {
+		m.lines.Add(uint64(len(stream.Values)))
+		m.logger.Debugw("Mock Loki accepted stream",
+			"labels", stream.Stream,
+			"entries", len(stream.Values),
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// LokiConfig controls batching, retries and backpressure of the Loki sink
+type LokiConfig struct {
+	PushURL       string
+	Labels        map[string]string
+	BatchSize     int
+	BatchWait     time.Duration
+	QueueSize     int
+	MaxRetries    int
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+	Timeout       time.Duration
+	TenantID      string
+	LevelAsLabel  bool
+	BlockOnFull   bool
+	BlockDeadline time.Duration
+}
+
+// LokiStats are cumulative counters exposed for the demo
+type LokiStats struct {
+	Enqueued  uint64 `json:"enqueued"`
+	Pushed    uint64 `json:"pushed"`
+	Dropped   uint64 `json:"dropped_queue_full"`
+	Failed    uint64 `json:"failed_after_retries"`
+	Retries   uint64 `json:"retries"`
+	Batches   uint64 `json:"batches"`
+	Throttled uint64 `json:"throttled_429"`
+}
+
+type lokiEntry struct {
+	ts    time.Time
+	level string
+	line  string
+}
+
+// LokiSink is a zap sink that batches JSON log lines and pushes them to Loki.
+// Its own diagnostics go to a separate logger so a failing push can never loop back into the sink.
+type LokiSink struct {
+	cfg    LokiConfig
+	client *http.Client
+	logger core.Logger
+
+	queue   chan lokiEntry
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	enqueued  atomic.Uint64
+	pushed    atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+	retries   atomic.Uint64
+	batches   atomic.Uint64
+	throttled atomic.Uint64
+}
+
+// NewLokiSink starts the background shipper
+func NewLokiSink(cfg LokiConfig, diagnostics core.Logger) *LokiSink {
+	s := &LokiSink{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		logger:  diagnostics,
+		queue:   make(chan lokiEntry, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write receives one encoded log line from zap; it never blocks the caller for long
+func (s *LokiSink) Write(p []byte) (int, error) {
+	entry := parseEntry(p)
+
+	select {
+	case s.queue <- entry:
+		s.enqueued.Add(1)
+		return len(p), nil
+	default:
+	}
+
+	if s.cfg.BlockOnFull {
+		timer := time.NewTimer(s.cfg.BlockDeadline)
+		defer timer.Stop()
+		select {
+		case s.queue <- entry:
+			s.enqueued.Add(1)
+			return len(p), nil
+		case <-timer.C:
+		}
+	}
+
+	// Queue full: shed load instead of stalling the application
+	if dropped := s.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+		s.logger.Warnw("Loki queue full, dropping log lines",
+			"queue_size", s.cfg.QueueSize,
+			"dropped_total", dropped,
+		)
+	}
+	return len(p), nil
+}
+
+// Sync blocks until everything queued so far has been pushed or given up on
+func (s *LokiSink) Sync() error {
+	ack := make(chan struct{})
+	select {
+	case s.flushCh <- ack:
+		<-ack
+	case <-s.stopped:
+	}
+	return nil
+}
+
+// Close flushes pending entries and stops the shipper
+func (s *LokiSink) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		<-s.stopped
+	})
+	return nil
+}
+
+// Stats returns a snapshot of the sink counters
+func (s *LokiSink) Stats() LokiStats {
+	return LokiStats{
+		Enqueued:  s.enqueued.Load(),
+		Pushed:    s.pushed.Load(),
+		Dropped:   s.dropped.Load(),
+		Failed:    s.failed.Load(),
+		Retries:   s.retries.Load(),
+		Batches:   s.batches.Load(),
+		Throttled: s.throttled.Load(),
+	}
+}
+
+func (s *LokiSink) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.cfg.BatchWait)
+	defer ticker.Stop()
+
+	batch := make([]lokiEntry, 0, s.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			s.push(batch)
+			batch = make([]lokiEntry, 0, s.cfg.BatchSize)
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case entry := <-s.queue:
+				batch = append(batch, entry)
+				if len(batch) >= s.cfg.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-s.flushCh:
+			drain()
+			close(ack)
+		case <-s.done:
+			drain()
+			return
+		}
+	}
+}
+
+// push sends one batch, retrying 429/5xx/network errors with exponential backoff
+func (s *LokiSink) push(batch []lokiEntry) {
+	body, err := s.encode(batch)
+	if err != nil {
+		s.failed.Add(uint64(len(batch)))
+		s.logger.Errorw("Failed to encode Loki batch", "entries", len(batch), "error", err.Error())
+		return
+	}
+
+	backoff := s.cfg.MinBackoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		status, retryAfter, err := s.send(body)
+		if err == nil {
+			s.pushed.Add(uint64(len(batch)))
+			s.batches.Add(1)
+			s.logger.Debugw("Loki batch pushed",
+				"entries", len(batch),
+				"bytes", len(body),
+				"attempt", attempt,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			return
+		}
+
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if status == http.StatusTooManyRequests {
+			s.throttled.Add(1)
+		}
+		if !retryable || attempt > s.cfg.MaxRetries {
+			s.failed.Add(uint64(len(batch)))
+			s.logger.Errorw("Loki push failed, batch dropped",
+				"entries", len(batch),
+				"status", status,
+				"attempt", attempt,
+				"retryable", retryable,
+				"error", err.Error(),
+			)
+			return
+		}
+
+		// Honour Retry-After from Loki's rate limiter when it is longer than our own backoff
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		s.retries.Add(1)
+		s.logger.Warnw("Loki push failed, retrying",
+			"entries", len(batch),
+			"status", status,
+			"attempt", attempt,
+			"max_retries", s.cfg.MaxRetries,
+			"backoff", wait.String(),
+			"queue_depth", len(s.queue),
+			"error", err.Error(),
+		)
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+func (s *LokiSink) send(body []byte) (status int, retryAfter time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.PushURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.cfg.TenantID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, 0, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("loki returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// encode groups entries into streams by label set, as the push API requires
+func (s *LokiSink) encode(batch []lokiEntry) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	streams := make(map[string]*stream)
+	for _, entry := range batch {
+		labels := make(map[string]string, len(s.cfg.Labels)+1)
+		for k, v := range s.cfg.Labels {
+			labels[k] = v
+		}
+		if s.cfg.LevelAsLabel && entry.level != "" {
+			labels["level"] = entry.level
+		}
+
+		key := labelKey(labels)
+		st, ok := streams[key]
+		if !ok {
+			st = &stream{Stream: labels}
+			streams[key] = st
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(entry.ts.UnixNano(), 10), entry.line})
+	}
+
+	payload := struct {
+		Streams []*stream `json:"streams"`
+	}{}
+	keys := make([]string, 0, len(streams))
+	for key := range streams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+
+	return json.Marshal(payload)
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + labels[k] + ",")
+	}
+	return b.String()
+}
+
+// parseEntry copies the line (zap reuses its buffer) and extracts timestamp and level
+func parseEntry(p []byte) lokiEntry {
+	line := strings.TrimRight(string(p), "\n")
+	entry := lokiEntry{ts: time.Now(), line: line}
+
+	var fields struct {
+		Level     string `json:"level"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal([]byte(line), &fields); err == nil {
+		entry.level = fields.Level
+		if ts, err := time.Parse(time.RFC3339Nano, fields.Timestamp); err == nil {
+			entry.ts = ts
+		}
+	}
+	return entry
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBrokerClosed is returned to waiters when the broker shuts down
+var ErrBrokerClosed = errors.New("broker closed")
+
+// Event is one published item; Seq is the cursor clients poll from
+type Event struct {
+	Seq         uint64         `json:"seq"`
+	Type        string         `json:"type"`
+	Data        map[string]any `json:"data,omitempty"`
+	PublishedAt time.Time      `json:"published_at"`
+}
+
+// Broker keeps a bounded, ordered event log and wakes waiting pollers on publish
+type Broker struct {
+	mu        sync.Mutex
+	events    []Event
+	retention int
+	nextSeq   uint64
+	notify    chan struct{}
+	closed    chan struct{}
+	waiters   int
+}
+
+// NewBroker creates a broker retaining the last retention events
+func NewBroker(retention int) *Broker {
+	return &Broker{
+		retention: retention,
+		nextSeq:   1,
+		notify:    make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Publish appends an event and wakes every waiting poller
+func (b *Broker) Publish(eventType string, data map[string]any) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event := Event{Seq: b.nextSeq, Type: eventType, Data: data, PublishedAt: time.Now()}
+	b.nextSeq++
+	b.events = append(b.events, event)
+	if len(b.events) > b.retention {
+		b.events = b.events[len(b.events)-b.retention:]
+	}
+
+	// Closing the channel broadcasts to all waiters; the next publish uses a fresh one
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return event
+}
+
+// PollResult is what Wait returns to the handler
+type PollResult struct {
+	Events []Event
+	Cursor uint64
+	// Missed counts events that fell out of retention before the client asked for them
+	Missed uint64
+}
+
+// Wait returns events after cursor, blocking until one is published, ctx is done or the broker closes
+func (b *Broker) Wait(ctx context.Context, cursor uint64) (PollResult, error) {
+	for {
+		b.mu.Lock()
+		result, ok := b.since(cursor)
+		if ok {
+			b.mu.Unlock()
+			return result, nil
+		}
+		notify := b.notify
+		b.waiters++
+		b.mu.Unlock()
+
+		var err error
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-b.closed:
+			err = ErrBrokerClosed
+		}
+
+		b.mu.Lock()
+		b.waiters--
+		b.mu.Unlock()
+		if err != nil {
+			return PollResult{Cursor: cursor}, err
+		}
+	}
+}
+
+// since collects events after cursor; callers hold b.mu
+func (b *Broker) since(cursor uint64) (PollResult, bool) {
+	result := PollResult{Cursor: cursor}
+	if len(b.events) == 0 || b.events[len(b.events)-1].Seq <= cursor {
+		return result, false
+	}
+	if oldest := b.events[0].Seq; cursor+1 < oldest {
+		result.Missed = oldest - cursor - 1
+	}
+	for _, event := range b.events {
+		if event.Seq > cursor {
+			result.Events = append(result.Events, event)
+		}
+	}
+	result.Cursor = result.Events[len(result.Events)-1].Seq
+	return result, true
+}
+
+// Waiters returns the number of pollers currently blocked
+func (b *Broker) Waiters() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waiters
+}
+
+// Head returns the latest sequence number
+func (b *Broker) Head() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nextSeq - 1
+}
+
+// Close releases all waiting pollers
+func (b *Broker) Close() {
+	close(b.closed)
+}
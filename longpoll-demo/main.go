@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Long-Polling Demo ===")
+	console.Println("Holds /poll requests until events arrive or the wait times out, logging each outcome")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer leakwatch.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	broker := NewBroker(getIntEnv("RETENTION", 100))
+	defaultTimeout := getDurationEnv("POLL_TIMEOUT", 20*time.Second)
+	maxTimeout := getDurationEnv("MAX_POLL_TIMEOUT", 60*time.Second)
+	pollLogger := baseLogger.With("component", "poll")
+
+	gin.SetMode(gin.ReleaseMode)
+	reg := metrics.New("longpoll-demo")
+	reg.GaugeFunc("longpoll_waiters", "Polls currently held open.", func() float64 { return float64(broker.Waiters()) })
+	reg.GaugeFunc("longpoll_head_seq", "Sequence number of the latest published event.", func() float64 { return float64(broker.Head()) })
+
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery(), reg.RED())
+
+	// Polls get their own ID prefix, so the poll request logger is installed per route
+	// after the poll ID rather than with r.Use
+	r.GET("/poll", requestid.GinMiddleware(requestid.Poll), logcontext.GinRequestLogger(pollLogger),
+		pollHandler(broker, defaultTimeout, maxTimeout))
+	r.POST("/publish", logcontext.GinRequestLogger(baseLogger.With("component", "publisher", "source", "api")), func(c *gin.Context) {
+		var req struct {
+			Type string         `json:"type" binding:"required"`
+			Data map[string]any `json:"data"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		waiters := broker.Waiters()
+		event := broker.Publish(req.Type, req.Data)
+		logcontext.MustFromGin(c).Infow("Event published",
+			"seq", event.Seq,
+			"type", event.Type,
+			"woken_waiters", waiters,
+		)
+		c.JSON(http.StatusAccepted, event)
+	})
+	r.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"head": broker.Head(), "waiters": broker.Waiters()})
+	})
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/metrics", reg.GinHandler())
+	r.GET("/health", health.Handler(nil))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if interval := getDurationEnv("PUBLISH_INTERVAL", 7*time.Second); interval > 0 {
+		go runPublisher(ctx, broker, interval, baseLogger.With("component", "publisher", "source", "ticker"))
+	}
+
+	port := getEnvOrDefault("PORT", "8108")
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 5 * time.Second,
+		// Held polls must finish before the server gives up on writing the response
+		WriteTimeout: maxTimeout + 5*time.Second,
+	}
+
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "poll_timeout", defaultTimeout.String(), "max_poll_timeout", maxTimeout.String())
+	baseLogger.Infow("Starting long-polling server",
+		"component", "http",
+		"port", port,
+		"default_timeout", defaultTimeout.String(),
+		"max_timeout", maxTimeout.String(),
+		"endpoints", []string{"/poll", "/publish", "/stats", "/health", "/version", "/metrics"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl 'http://localhost:%s/poll?timeout=30s'                # waits for the next event\n", port)
+	console.Printf("  curl 'http://localhost:%s/poll?cursor=0&timeout=5s'        # replays retained events\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/publish -d '{\"type\":\"order.created\",\"data\":{\"order_id\":\"ord-1\"}}'\n", port)
+	console.Printf("  curl http://localhost:%s/stats\n", port)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(baseLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	// Release held polls first so Shutdown does not wait for their timeouts
+	waiters := broker.Waiters()
+	broker.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		baseLogger.Errorw("Server shutdown failed", "error", err.Error())
+		return
+	}
+	baseLogger.Infow("Server stopped", "released_waiters", waiters, "head", broker.Head())
+	summary.Log(baseLogger)
+}
+
+// pollHandler serves GET /poll?cursor=N&timeout=D, logging how long each request was held and how it ended
+func pollHandler(broker *Broker, defaultTimeout, maxTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Without a cursor the client only wants events published from now on
+		cursor := broker.Head()
+		if raw := c.Query("cursor"); raw != "" {
+			n, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cursor must be a non-negative integer"})
+				return
+			}
+			cursor = n
+		}
+
+		timeout := defaultTimeout
+		if raw := c.Query("timeout"); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a positive duration such as 30s"})
+				return
+			}
+			timeout = d
+		}
+		requested := timeout
+		if timeout > maxTimeout {
+			timeout = maxTimeout
+		}
+
+		log := logcontext.MustFromGin(c).With(
+			"cursor", cursor,
+			"timeout_ms", timeout.Milliseconds(),
+		)
+		if requested != timeout {
+			log.Infow("Poll timeout clamped", "requested_ms", requested.Milliseconds())
+		}
+		log.Debugw("Poll started", "waiters", broker.Waiters())
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		start := time.Now()
+		result, err := broker.Wait(ctx, cursor)
+		waitMs := time.Since(start).Milliseconds()
+
+		switch {
+		case err == nil:
+			types := make([]string, 0, len(result.Events))
+			for _, event := range result.Events {
+				types = append(types, event.Type)
+			}
+			if result.Missed > 0 {
+				log.Warnw("Poller fell behind retention", "missed", result.Missed, "oldest_seq", result.Events[0].Seq)
+			}
+			log.Infow("Poll delivered events",
+				"wait_ms", waitMs,
+				"events", len(result.Events),
+				"event_types", types,
+				"next_cursor", result.Cursor,
+			)
+			c.JSON(http.StatusOK, gin.H{"events": result.Events, "cursor": result.Cursor, "missed": result.Missed})
+		case errors.Is(err, context.DeadlineExceeded):
+			log.Infow("Poll timed out", "wait_ms", waitMs)
+			c.JSON(http.StatusOK, gin.H{"events": []Event{}, "cursor": cursor, "timed_out": true})
+		case errors.Is(err, context.Canceled):
+			// Nobody is left to read a response, so only the log records this poll
+			log.Infow("Poller disconnected", "wait_ms", waitMs)
+		case errors.Is(err, ErrBrokerClosed):
+			log.Infow("Poll released by shutdown", "wait_ms", waitMs)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server shutting down", "cursor": cursor})
+		default:
+			log.Errorw("Poll failed", "wait_ms", waitMs, "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+	}
+}
+
+// runPublisher emits a sample event at a jittered interval so pollers see a mix of deliveries and timeouts
+func runPublisher(ctx context.Context, broker *Broker, interval time.Duration, log core.Logger) {
+	types := []string{"order.created", "order.shipped", "payment.captured", "inventory.low"}
+	for n := 1; ; n++ {
+		wait := interval/2 + time.Duration(rng.Int63n(int64(interval)))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		waiters := broker.Waiters()
+		event := broker.Publish(types[rng.Intn(len(types))], map[string]any{"n": n})
+		log.Infow("Event published",
+			"seq", event.Seq,
+			"type", event.Type,
+			"woken_waiters", waiters,
+		)
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
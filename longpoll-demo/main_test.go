@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func newPollRouter(rec *testlog.Recorder, broker *Broker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/poll", requestid.GinMiddleware(requestid.Poll), logcontext.GinRequestLogger(rec.Logger),
+		pollHandler(broker, 50*time.Millisecond, 200*time.Millisecond))
+	return r
+}
+
+func TestPollDeliversRetainedEvents(t *testing.T) {
+	rec := testlog.New(t)
+	broker := NewBroker(10)
+	broker.Publish("order.created", nil)
+	broker.Publish("order.shipped", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/poll?cursor=0", nil)
+	req.Header.Set("X-Request-ID", "poll-test")
+	w := httptest.NewRecorder()
+	newPollRouter(rec, broker).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	rec.AssertLogged("info", "Poll delivered events",
+		"request_id", "poll-test", "route", "/poll",
+		"cursor", 0,
+		"events", 2,
+		"event_types", []string{"order.created", "order.shipped"},
+		"next_cursor", 2,
+	)
+}
+
+func TestPollTimesOutAndClampsTimeout(t *testing.T) {
+	rec := testlog.New(t)
+	w := httptest.NewRecorder()
+	newPollRouter(rec, NewBroker(10)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/poll?timeout=1h", nil))
+
+	rec.AssertLogged("info", "Poll timeout clamped", "timeout_ms", 200, "requested_ms", time.Hour.Milliseconds())
+	entry := rec.AssertLogged("info", "Poll timed out", "timeout_ms", 200)
+	if waitMs, _ := entry["wait_ms"].(float64); waitMs < 150 {
+		t.Errorf("wait_ms = %v, want close to the 200ms timeout", entry["wait_ms"])
+	}
+	if id, _ := entry["request_id"].(string); !strings.HasPrefix(id, requestid.Poll) || w.Header().Get("X-Request-ID") != id {
+		t.Errorf("request_id %q does not match response header %q", id, w.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestPollReleasedByShutdown(t *testing.T) {
+	rec := testlog.New(t)
+	broker := NewBroker(10)
+	r := newPollRouter(rec, broker)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/poll?timeout=200ms", nil))
+		done <- w
+	}()
+	for broker.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	broker.Close()
+
+	if w := <-done; w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+	rec.AssertLogged("info", "Poll released by shutdown")
+	rec.AssertNotLogged("", "Poll timed out")
+}
+
+func TestPollRejectsBadCursor(t *testing.T) {
+	rec := testlog.New(t)
+	w := httptest.NewRecorder()
+	newPollRouter(rec, NewBroker(10)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/poll?cursor=-1", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if n := rec.Count("", "Poll"); n != 0 {
+		t.Errorf("rejected poll logged %d entries, want none", n)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kart-io/logger/core"
+	colmetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// MockCollector is a minimal OTLP/HTTP receiver for metrics and traces. For every histogram
+// data point it logs the exemplars, whose trace IDs can be matched against the demo's logs.
+type MockCollector struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	spans     atomic.Uint64
+	exemplars atomic.Uint64
+}
+
+// StartMockCollector listens on addr and serves POST /v1/metrics and /v1/traces
+func StartMockCollector(addr string, logger core.Logger) (*MockCollector, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	c := &MockCollector{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics", c.handleMetrics)
+	mux.HandleFunc("/v1/traces", c.handleTraces)
+	c.server = &http.Server{Handler: mux}
+
+	go c.server.Serve(listener)
+	return c, nil
+}
+
+// Addr returns the host:port the collector listens on
+func (c *MockCollector) Addr() string {
+	return c.listener.Addr().String()
+}
+
+// Spans returns how many spans were received
+func (c *MockCollector) Spans() uint64 {
+	return c.spans.Load()
+}
+
+// Exemplars returns how many histogram exemplars were received
+func (c *MockCollector) Exemplars() uint64 {
+	return c.exemplars.Load()
+}
+
+// Close stops the collector
+func (c *MockCollector) Close() error {
+	return c.server.Close()
+}
+
+func (c *MockCollector) handleTraces(w http.ResponseWriter, r *http.Request) {
+	var req coltracev1.ExportTraceServiceRequest
+	if !c.decode(w, r, &req) {
+		return
+	}
+	for _, resourceSpans := range req.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			c.spans.Add(uint64(len(scopeSpans.GetSpans())))
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *MockCollector) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var req colmetricsv1.ExportMetricsServiceRequest
+	if !c.decode(w, r, &req) {
+		return
+	}
+
+	for _, resourceMetrics := range req.GetResourceMetrics() {
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, m := range scopeMetrics.GetMetrics() {
+				switch data := m.GetData().(type) {
+				case *metricsv1.Metric_Histogram:
+					for _, point := range data.Histogram.GetDataPoints() {
+						c.logHistogramPoint(m.GetName(), point)
+					}
+				case *metricsv1.Metric_Sum:
+					for _, point := range data.Sum.GetDataPoints() {
+						c.logger.Infow("Mock collector received sum",
+							"metric", m.GetName(),
+							"attributes", attributesToMap(point.GetAttributes()),
+							"value", point.GetAsInt(),
+						)
+					}
+				case *metricsv1.Metric_Gauge:
+					for _, point := range data.Gauge.GetDataPoints() {
+						c.logger.Infow("Mock collector received gauge",
+							"metric", m.GetName(),
+							"attributes", attributesToMap(point.GetAttributes()),
+							"value", point.GetAsInt(),
+						)
+					}
+				}
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// logHistogramPoint prints the slowest exemplar of a data point, the one worth clicking through to
+func (c *MockCollector) logHistogramPoint(name string, point *metricsv1.HistogramDataPoint) {
+	exemplars := point.GetExemplars()
+	c.exemplars.Add(uint64(len(exemplars)))
+
+	fields := []interface{}{
+		"metric", name,
+		"attributes", attributesToMap(point.GetAttributes()),
+		"count", point.GetCount(),
+		"sum", point.GetSum(),
+		"exemplars", len(exemplars),
+	}
+	var slowest *metricsv1.Exemplar
+	for _, exemplar := range exemplars {
+		if slowest == nil || exemplar.GetAsDouble() > slowest.GetAsDouble() {
+			slowest = exemplar
+		}
+	}
+	if slowest != nil {
+		fields = append(fields,
+			"slowest_exemplar_value", slowest.GetAsDouble(),
+			"slowest_exemplar_trace_id", hex.EncodeToString(slowest.GetTraceId()),
+			"slowest_exemplar_span_id", hex.EncodeToString(slowest.GetSpanId()),
+		)
+	}
+	c.logger.Infow("Mock collector received histogram", fields...)
+}
+
+// decode reads an optionally gzip-compressed protobuf body
+func (c *MockCollector) decode(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err == nil {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		c.logger.Warnw("Mock collector received undecodable payload", "path", r.URL.Path, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func attributesToMap(kvs []*commonv1.KeyValue) map[string]string {
+	result := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		result[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return result
+}
+
+func anyValueString(v *commonv1.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return value.StringValue
+	case *commonv1.AnyValue_IntValue:
+		return fmt.Sprint(value.IntValue)
+	case *commonv1.AnyValue_DoubleValue:
+		return fmt.Sprint(value.DoubleValue)
+	case *commonv1.AnyValue_BoolValue:
+		return fmt.Sprint(value.BoolValue)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/kart-io/go-example/metrics-demo"
+
+// ErrCorruptInput is returned for jobs whose payload cannot be processed
+var ErrCorruptInput = errors.New("corrupt input")
+
+var jobKinds = []string{"thumbnail", "resize", "transcode"}
+
+// job is a unit of work queued for the worker pool
+type job struct {
+	ID   int
+	Kind string
+}
+
+// instruments holds the synchronous instruments recorded per job
+type instruments struct {
+	processed metric.Int64Counter
+	duration  metric.Float64Histogram
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== OpenTelemetry Metrics Demo ===")
+	console.Println("Counters, histograms and async gauges over OTLP, with exemplars linking latency to trace IDs in the logs")
+	console.Println()
+
+	identity := otelresource.Get()
+
+	logOption := &option.LogOption{
+		Engine:        "zap",
+		Level:         "info",
+		Format:        "json",
+		OutputPaths:   []string{"stdout"},
+		InitialFields: identity.Fields(),
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	serviceLogger := baseLogger.With("component", "worker")
+
+	// Without an external endpoint, run an in-process collector so the demo is self-contained
+	endpoint := os.Getenv("OTLP_ENDPOINT")
+	var collector *MockCollector
+	if endpoint == "" {
+		collector, err = StartMockCollector("127.0.0.1:0", baseLogger.With("component", "mock-collector"))
+		if err != nil {
+			crash.Fatal(serviceLogger, "Failed to start mock collector", "error", err.Error())
+		}
+		defer collector.Close()
+		endpoint = collector.Addr()
+	}
+
+	ctx := context.Background()
+	configPath := getEnvOrDefault("CONFIG_PATH", "metrics.yaml")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to load metrics config", "path", configPath, "error", err.Error())
+	}
+	interval := getDurationEnv("METRICS_INTERVAL", 5*time.Second)
+	shutdownTelemetry, err := initTelemetry(ctx, endpoint, identity, cfg.Sampling, interval, serviceLogger)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to initialize telemetry", "error", err.Error())
+	}
+	crash.OnExit("otel", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTelemetry(ctx)
+	})
+
+	tracer := otel.Tracer(instrumentationName)
+	meter := otel.Meter(instrumentationName)
+	queue := make(chan job, 256)
+
+	inst, err := newInstruments(meter)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to create metric instruments", "error", err.Error())
+	}
+	if err := registerGauges(meter, queue); err != nil {
+		crash.Fatal(serviceLogger, "Failed to register async gauges", "error", err.Error())
+	}
+
+	workers := getIntEnv("WORKERS", 4)
+	rate := getIntEnv("RATE", 20)
+	duration := getDurationEnv("DURATION", 20*time.Second)
+	sanitize.Startup(baseLogger, logOption, "otlp_endpoint", endpoint, "metrics_interval", interval.String(), "sampling", cfg.Sampling.String())
+	serviceLogger.Infow("Starting metrics demo",
+		"otlp_endpoint", endpoint,
+		"mock_collector", collector != nil,
+		"metrics_interval", interval.String(),
+		"sampling", cfg.Sampling.String(),
+		"workers", workers,
+		"rate", rate,
+		"duration", duration.String(),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := range queue {
+				processJob(tracer, inst, serviceLogger.With("worker", worker), j)
+			}
+		}(i)
+	}
+
+	produce(queue, rate, duration)
+	close(queue)
+	wg.Wait()
+
+	// Shutdown runs a final collection, so the last interval's points are exported too
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		serviceLogger.Warnw("Failed to shut down telemetry", "error", err.Error())
+	}
+
+	if collector != nil {
+		serviceLogger.Infow("Metrics demo finished",
+			"spans_received", collector.Spans(),
+			"exemplars_received", collector.Exemplars(),
+		)
+		return
+	}
+	serviceLogger.Infow("Metrics demo finished")
+}
+
+func newInstruments(meter metric.Meter) (*instruments, error) {
+	processed, err := meter.Int64Counter("jobs.processed",
+		metric.WithUnit("{job}"),
+		metric.WithDescription("Jobs processed by kind and outcome"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("job.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time spent processing a job"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &instruments{processed: processed, duration: duration}, nil
+}
+
+// registerGauges reports values that are cheaper to read at collection time than to track
+// on every change: queue depth and runtime statistics
+func registerGauges(meter metric.Meter, queue chan job) error {
+	queueDepth, err := meter.Int64ObservableGauge("jobs.queue.depth",
+		metric.WithUnit("{job}"),
+		metric.WithDescription("Jobs waiting for a worker"),
+	)
+	if err != nil {
+		return err
+	}
+	goroutines, err := meter.Int64ObservableGauge("process.runtime.go.goroutines",
+		metric.WithUnit("{goroutine}"),
+		metric.WithDescription("Number of live goroutines"),
+	)
+	if err != nil {
+		return err
+	}
+	heapAlloc, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_alloc",
+		metric.WithUnit("By"),
+		metric.WithDescription("Bytes of allocated heap objects"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		o.ObserveInt64(queueDepth, int64(len(queue)))
+		o.ObserveInt64(goroutines, int64(runtime.NumGoroutine()))
+		o.ObserveInt64(heapAlloc, int64(stats.HeapAlloc))
+		return nil
+	}, queueDepth, goroutines, heapAlloc)
+	return err
+}
+
+// produce enqueues jobs at a steady rate; a burst halfway through lets the queue depth gauge move
+func produce(queue chan<- job, rate int, duration time.Duration) {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(duration)
+	burstAt := time.Now().Add(duration / 2)
+	id := 0
+	for now := range ticker.C {
+		if now.After(deadline) {
+			return
+		}
+		batch := 1
+		if !burstAt.IsZero() && now.After(burstAt) {
+			batch = rate * 3
+			burstAt = time.Time{}
+		}
+		for i := 0; i < batch; i++ {
+			id++
+			queue <- job{ID: id, Kind: jobKinds[rng.Intn(len(jobKinds))]}
+		}
+	}
+}
+
+// processJob records the duration inside the job span; the span context in ctx is what turns
+// the measurement into an exemplar carrying the trace ID logged below
+func processJob(tracer trace.Tracer, inst *instruments, workerLogger core.Logger, j job) {
+	ctx, span := tracer.Start(context.Background(), "job.process", trace.WithAttributes(
+		attribute.Int("job.id", j.ID),
+		attribute.String("job.kind", j.Kind),
+	))
+	defer span.End()
+	log := traceLogger(ctx, workerLogger)
+
+	start := time.Now()
+	err := simulateWork(j)
+	elapsed := time.Since(start)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	kindAttr := attribute.String("job.kind", j.Kind)
+	inst.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(kindAttr))
+	inst.processed.Add(ctx, 1, metric.WithAttributes(kindAttr, attribute.String("outcome", outcome)))
+
+	switch {
+	case err != nil:
+		log.Errorw("Job failed", "job_id", j.ID, "kind", j.Kind, "duration_ms", elapsed.Milliseconds(), "error", err.Error())
+	case elapsed > 250*time.Millisecond:
+		log.Warnw("Slow job", "job_id", j.ID, "kind", j.Kind, "duration_ms", elapsed.Milliseconds())
+	default:
+		log.Infow("Job processed", "job_id", j.ID, "kind", j.Kind, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// simulateWork sleeps for a kind-dependent time with a ~5% slow tail and ~3% failures
+func simulateWork(j job) error {
+	base := map[string]int{"thumbnail": 10, "resize": 30, "transcode": 80}[j.Kind]
+	delay := time.Duration(base+rng.Intn(base)) * time.Millisecond
+	if rng.Intn(100) < 5 {
+		delay += time.Duration(300+rng.Intn(500)) * time.Millisecond
+	}
+	time.Sleep(delay)
+
+	if rng.Intn(100) < 3 {
+		return ErrCorruptInput
+	}
+	return nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
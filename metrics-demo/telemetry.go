@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// initTelemetry exports spans and metrics over OTLP/HTTP. Only sampled spans can become
+// exemplars, so the sampler also controls how many histogram points link back to a trace.
+func initTelemetry(ctx context.Context, endpoint string, identity otelresource.Identity, sampling otelsetup.SamplingConfig, interval time.Duration, logger core.Logger) (func(context.Context) error, error) {
+	sampler, err := sampling.NewSampler()
+	if err != nil {
+		return nil, err
+	}
+	_, shutdown, err := otelsetup.Setup(ctx, identity.Config(otelsetup.Config{
+		Endpoint:       endpoint,
+		Insecure:       true,
+		TLS:            certwatch.FromEnv("OTLP", logger),
+		ExportInterval: interval,
+		Signals:        otelsetup.Traces | otelsetup.Metrics,
+		Sampler:        sampler,
+		// Logs the share of jobs whose measurements can become exemplars
+		SamplingLogInterval: sampling.LogInterval,
+	}), logger)
+	return shutdown, err
+}
+
+// traceLogger adds the IDs of the span in ctx, the same IDs the exemplars carry
+func traceLogger(ctx context.Context, logger core.Logger) core.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+	return logger.With(
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+		"trace_sampled", spanContext.IsSampled(),
+	)
+}
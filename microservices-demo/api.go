@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+)
+
+// apiGateway is the edge service: it mints request IDs and forwards checkouts to orders
+type apiGateway struct {
+	ordersURL string
+	client    *http.Client
+}
+
+func (a *apiGateway) checkout(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logcontext.MustFromGin(c)
+
+	var req orderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Infow("Checkout received", "sku", req.SKU, "quantity", req.Quantity)
+
+	body, _ := json.Marshal(req)
+	outgoing, err := http.NewRequestWithContext(ctx, http.MethodPost, a.ordersURL+"/orders", bytes.NewReader(body))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	outgoing.Header.Set("Content-Type", "application/json")
+	injectHTTP(ctx, outgoing)
+
+	resp, err := a.client.Do(outgoing)
+	if err != nil {
+		log.Errorw("Orders service unreachable", "error", err.Error())
+		c.JSON(http.StatusBadGateway, gin.H{"error": "orders service unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+	payload, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Warnw("Checkout failed", "upstream_status", resp.StatusCode)
+	} else {
+		log.Infow("Checkout succeeded", "upstream_status", resp.StatusCode)
+	}
+	c.Data(resp.StatusCode, "application/json", payload)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/traceparent"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// propagator carries W3C trace context next to the request ID on every hop
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// correlationMiddleware accepts or mints the request ID, continues the caller's trace and
+// writes the access log. Handlers get the request logger, with logcontext.GinRequestFields
+// plus trace_id and span_id, from logcontext.MustFromGin
+func correlationMiddleware(tracer trace.Tracer, serviceLogger core.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+
+		requestid.SetGin(c, requestid.OrNew(c.GetHeader(requestid.Header), requestid.Request))
+
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Request.Method), semconv.HTTPRoute(route)),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		log := serviceLogger.With(append(logcontext.GinRequestFields(c), traceparent.Fields(ctx)...)...)
+		logcontext.SetGin(c, log)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		log.Infow("Request completed",
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// injectHTTP copies the request ID and trace context onto an outgoing request
+func injectHTTP(ctx context.Context, req *http.Request) {
+	if requestID := requestid.FromContext(ctx); requestID != "" {
+		req.Header.Set(requestid.Header, requestID)
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// metadataCarrier adapts gRPC metadata to the OTel propagator
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	if values := metadata.MD(m).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
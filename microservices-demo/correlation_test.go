@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/testlog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func newCorrelatedRouter(rec *testlog.Recorder, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	r := gin.New()
+	r.Use(correlationMiddleware(tracer, rec.Logger))
+	r.POST("/orders", handler)
+	return r
+}
+
+func TestCorrelationMiddlewareContinuesCaller(t *testing.T) {
+	rec := testlog.New(t)
+	var forwarded http.Header
+	r := newCorrelatedRouter(rec, func(c *gin.Context) {
+		logcontext.MustFromGin(c).Infow("Order priced", "order_id", "ord_1")
+		outgoing := httptest.NewRequest(http.MethodPost, "http://payments/charge", nil)
+		injectHTTP(c.Request.Context(), outgoing)
+		forwarded = outgoing.Header
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(requestid.Header, "req_01JA7RB2Q7X4N9D1VK6TMZ3HCF")
+	req.Header.Set("traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	fields := []interface{}{"request_id", "req_01JA7RB2Q7X4N9D1VK6TMZ3HCF", "trace_id", testTraceID}
+	rec.AssertLogged("info", "Order priced", append(fields, "order_id", "ord_1")...)
+	rec.AssertLogged("info", "Request completed", append(fields, "method", "POST", "route", "/orders", "status", 201)...)
+
+	if got := w.Header().Get(requestid.Header); got != "req_01JA7RB2Q7X4N9D1VK6TMZ3HCF" {
+		t.Errorf("response %s = %q, want the caller's ID", requestid.Header, got)
+	}
+	if got := forwarded.Get(requestid.Header); got != "req_01JA7RB2Q7X4N9D1VK6TMZ3HCF" {
+		t.Errorf("forwarded %s = %q, want the caller's ID", requestid.Header, got)
+	}
+	if got := forwarded.Get("traceparent"); !strings.Contains(got, testTraceID) {
+		t.Errorf("forwarded traceparent = %q, want trace %s", got, testTraceID)
+	}
+}
+
+func TestCorrelationMiddlewareMintsRequestID(t *testing.T) {
+	rec := testlog.New(t)
+	r := newCorrelatedRouter(rec, func(c *gin.Context) { c.Status(http.StatusAccepted) })
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(requestid.Header, "not a valid id")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	minted := w.Header().Get(requestid.Header)
+	if prefix, _, err := requestid.Parse(minted); err != nil || prefix != requestid.Request {
+		t.Fatalf("response %s = %q, want a new req_ ID", requestid.Header, minted)
+	}
+	entry := rec.AssertLogged("info", "Request completed", "request_id", minted, "status", 202)
+	if traceID, _ := entry["trace_id"].(string); len(traceID) != 32 {
+		t.Errorf("trace_id = %q, want a new 32 character trace ID", traceID)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const instrumentationName = "github.com/kart-io/go-example/microservices-demo"
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Microservices Correlation Demo ===")
+	console.Println("api-gateway -> orders (HTTP) -> payments (gRPC), joined by request_id and trace_id")
+	console.Println()
+
+	// --port moves the gateway, the port clients call
+	apiPort := cliflags.PortOr(getEnvOrDefault("API_PORT", "8102"))
+	ordersPort := getEnvOrDefault("ORDERS_PORT", "8103")
+	paymentsAddr := getEnvOrDefault("PAYMENTS_ADDR", "127.0.0.1:9102")
+
+	// One shutdown summary covers the three services; each one's logger is counted in it
+	summary := runsummary.New()
+
+	// payments: gRPC server
+	paymentsLogger, paymentsTracer := newService("payments", summary)
+	defer crash.Recover(paymentsLogger)
+	// All three services share this process, so one resource usage reporter covers them
+	defer resusage.FromEnv(paymentsLogger)()
+	defer leakwatch.FromEnv(paymentsLogger)()
+	defer pidfile.FromEnv(paymentsLogger)()
+	sanitize.Startup(paymentsLogger, newLogOption("payments"),
+		"api_port", apiPort,
+		"orders_port", ordersPort,
+		"payments_addr", paymentsAddr,
+		"otlp_endpoint", os.Getenv("OTLP_ENDPOINT"),
+	)
+	listener, err := net.Listen("tcp", paymentsAddr)
+	if err != nil {
+		crash.Fatal(paymentsLogger, "Failed to listen", "error", err.Error(), "addr", paymentsAddr)
+	}
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		unaryServerInterceptor(paymentsTracer, paymentsLogger),
+		logcontext.UnaryServerInterceptor(paymentsLogger, logcontext.Correlation),
+	))
+	grpcServer.RegisterService(&paymentsServiceDesc, &paymentsServer{})
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			crash.Fatal(paymentsLogger, "Server failed to start", "error", err.Error(), "addr", paymentsAddr)
+		}
+	}()
+	paymentsLogger.Infow("Payments service listening", "protocol", "grpc", "addr", paymentsAddr)
+
+	// orders: HTTP server calling payments over gRPC
+	ordersLogger, ordersTracer := newService("orders", summary)
+	conn, err := grpc.NewClient(paymentsAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(unaryClientInterceptor(ordersTracer)),
+	)
+	if err != nil {
+		crash.Fatal(ordersLogger, "Failed to create payments client", "error", err.Error(), "addr", paymentsAddr)
+	}
+	defer conn.Close()
+	orders := &ordersService{payments: &PaymentsClient{conn: conn}}
+	ordersRouter := newRouter("orders", ordersTracer, ordersLogger, summary)
+	ordersRouter.POST("/orders", orders.createOrder)
+	ordersServer := serve(ordersRouter, ordersPort, ordersLogger)
+
+	// api-gateway: the edge, calling orders over HTTP
+	apiLogger, apiTracer := newService("api-gateway", summary)
+	gateway := &apiGateway{
+		ordersURL: "http://localhost:" + ordersPort,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+	apiRouter := newRouter("api-gateway", apiTracer, apiLogger, summary)
+	apiRouter.POST("/checkout", gateway.checkout)
+
+	if n := getIntEnv("DEMO_REQUESTS", 3); n > 0 {
+		go sendDemoRequests("http://localhost:"+apiPort, n)
+	}
+
+	console.Printf("Starting api-gateway on port %s\n", apiPort)
+	console.Println("Try these, then grep the logs for the X-Request-ID from the response headers:")
+	console.Printf("  curl -i -X POST http://localhost:%s/checkout -d '{\"sku\":\"sku-keyboard\",\"quantity\":2,\"card\":\"4242424242424242\"}'\n", apiPort)
+	console.Printf("  curl -i -X POST http://localhost:%s/checkout -d '{\"sku\":\"sku-monitor\",\"quantity\":1,\"card\":\"4000000000000002\"}'\n", apiPort)
+
+	apiServer := serve(apiRouter, apiPort, apiLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	// Edge first, so no new work reaches the services behind it
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, srv := range []*http.Server{apiServer, ordersServer} {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			paymentsLogger.Errorw("Server shutdown failed", "addr", srv.Addr, "error", err.Error())
+		}
+	}
+	grpcServer.GracefulStop()
+	summary.Log(paymentsLogger)
+}
+
+// newService returns a service's logger and tracer; service.name differs per service while
+// request_id and trace_id are what tie their logs together
+func newService(name string, summary *runsummary.Summary) (core.Logger, trace.Tracer) {
+	versionInfo := version.Get()
+	logOption := newLogOption(name)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit(name+" logger", serviceLogger.Flush)
+	outputs.Log(serviceLogger)
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name), semconv.ServiceVersion(versionInfo.GitVersion))
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	// Trace IDs are generated either way; spans are only exported when a collector is configured
+	if endpoint := os.Getenv("OTLP_ENDPOINT"); endpoint != "" {
+		exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		if err != nil {
+			crash.Fatal(serviceLogger, "Failed to create OTLP trace exporter", "error", err.Error())
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	// A crash exports the spans still in the batcher before the process exits
+	crash.OnExit(name+" tracer provider", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	})
+	return serviceLogger, provider.Tracer(instrumentationName)
+}
+
+// newLogOption is the same for every service apart from service.name
+func newLogOption(name string) *option.LogOption {
+	versionInfo := version.Get()
+	opt := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    name,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(opt)
+	return opt
+}
+
+func newRouter(name string, tracer trace.Tracer, serviceLogger core.Logger, summary *runsummary.Summary) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.ServiceHandler(name, version.Get().GitVersion, nil))
+	r.Use(correlationMiddleware(tracer, serviceLogger))
+	return r
+}
+
+// serve starts r on port in the background; the caller shuts the returned server down
+func serve(r *gin.Engine, port string, serviceLogger core.Logger) *http.Server {
+	serviceLogger.Infow("Service listening", "protocol", "http", "port", port)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+	return srv
+}
+
+// sendDemoRequests produces one successful, one declined and one rejected checkout
+func sendDemoRequests(baseURL string, n int) {
+	bodies := []string{
+		`{"sku":"sku-keyboard","quantity":2,"card":"4242424242424242"}`,
+		`{"sku":"sku-monitor","quantity":1,"card":"4000000000000002"}`,
+		`{"sku":"sku-server","quantity":1,"card":"4242424242424242"}`,
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	time.Sleep(500 * time.Millisecond)
+	for i := 0; i < n; i++ {
+		resp, err := client.Post(baseURL+"/checkout", "application/json", bytes.NewBufferString(bodies[i%len(bodies)]))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		fmt.Fprintf(os.Stderr, "demo request %d: status=%d request_id=%s\n", i+1, resp.StatusCode, resp.Header.Get(requestid.Header))
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
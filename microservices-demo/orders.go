@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// orderRequest is the body accepted by the orders service and the API's /checkout
+type orderRequest struct {
+	SKU      string `json:"sku" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+	Card     string `json:"card" binding:"required"`
+}
+
+var prices = map[string]int64{"sku-keyboard": 8900, "sku-monitor": 32900, "sku-server": 990000}
+
+// ordersService prices the order and charges it through the payments service
+type ordersService struct {
+	payments *PaymentsClient
+	counter  atomic.Uint64
+}
+
+func (s *ordersService) createOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logcontext.MustFromGin(c)
+
+	var req orderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	price, ok := prices[req.SKU]
+	if !ok {
+		log.Warnw("Unknown SKU", "sku", req.SKU)
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown sku"})
+		return
+	}
+
+	orderID := fmt.Sprintf("ord_%06d", s.counter.Add(1))
+	amount := price * int64(req.Quantity)
+	log.Infow("Order priced", "order_id", orderID, "sku", req.SKU, "quantity", req.Quantity, "amount_cents", amount)
+
+	payment, err := s.payments.Charge(ctx, &ChargeRequest{OrderID: orderID, AmountCents: amount, Card: req.Card})
+	if err != nil {
+		st := status.Convert(err)
+		httpStatus := http.StatusBadGateway
+		switch st.Code() {
+		case codes.FailedPrecondition:
+			httpStatus = http.StatusPaymentRequired
+		case codes.InvalidArgument:
+			httpStatus = http.StatusUnprocessableEntity
+		}
+		log.Warnw("Order payment failed", "order_id", orderID, "grpc_code", st.Code().String(), "error", st.Message())
+		c.JSON(httpStatus, gin.H{"order_id": orderID, "error": st.Message()})
+		return
+	}
+
+	log.Infow("Order created", "order_id", orderID, "payment_id", payment.PaymentID)
+	c.JSON(http.StatusCreated, gin.H{"order_id": orderID, "payment_id": payment.PaymentID, "amount_cents": amount})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/rng"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChargeRequest is the payments.v1.Payments/Charge request
+type ChargeRequest struct {
+	OrderID     string `json:"order_id"`
+	AmountCents int64  `json:"amount_cents"`
+	Card        string `json:"card"`
+}
+
+// ChargeResponse is the payments.v1.Payments/Charge response
+type ChargeResponse struct {
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+}
+
+// PaymentsService is the server side of payments.v1.Payments
+type PaymentsService interface {
+	Charge(ctx context.Context, req *ChargeRequest) (*ChargeResponse, error)
+}
+
+const chargeMethod = "/payments.v1.Payments/Charge"
+
+// paymentsServiceDesc is what protoc would generate for a one-method service
+var paymentsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "payments.v1.Payments",
+	HandlerType: (*PaymentsService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Charge",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := new(ChargeRequest)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(PaymentsService).Charge(ctx, req.(*ChargeRequest))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: chargeMethod}, handler)
+		},
+	}},
+}
+
+// PaymentsClient calls payments.v1.Payments over a client connection
+type PaymentsClient struct {
+	conn *grpc.ClientConn
+}
+
+// Charge invokes payments.v1.Payments/Charge
+func (c *PaymentsClient) Charge(ctx context.Context, req *ChargeRequest) (*ChargeResponse, error) {
+	resp := new(ChargeResponse)
+	if err := c.conn.Invoke(ctx, chargeMethod, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// paymentsServer declines the test card ending in 0002 and rejects amounts over the limit
+type paymentsServer struct {
+	counter atomic.Uint64
+}
+
+// Charge simulates the card network
+func (s *paymentsServer) Charge(ctx context.Context, req *ChargeRequest) (*ChargeResponse, error) {
+	log := logcontext.FromContext(ctx)
+	time.Sleep(time.Duration(20+rng.Intn(60)) * time.Millisecond)
+
+	if req.AmountCents > 500000 {
+		log.Warnw("Charge rejected", "order_id", req.OrderID, "amount_cents", req.AmountCents, "reason", "over limit")
+		return nil, status.Errorf(codes.InvalidArgument, "amount %d exceeds the per-charge limit", req.AmountCents)
+	}
+	if strings.HasSuffix(req.Card, "0002") {
+		log.Warnw("Charge declined", "order_id", req.OrderID, "amount_cents", req.AmountCents, "decline_code", "insufficient_funds")
+		return nil, status.Error(codes.FailedPrecondition, "card declined")
+	}
+
+	paymentID := fmt.Sprintf("pay_%06d", s.counter.Add(1))
+	log.Infow("Charge captured", "order_id", req.OrderID, "payment_id", paymentID, "amount_cents", req.AmountCents)
+	return &ChargeResponse{PaymentID: paymentID, Status: "captured"}, nil
+}
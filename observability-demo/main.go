@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/logrules"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/kart-io/go-example/observability-demo"
+	adminTokenHeader    = "X-Admin-Token"
+)
+
+// ErrCardDeclined is a business failure; ErrGatewayUnavailable is an infrastructure failure
+var (
+	ErrCardDeclined       = errors.New("card declined")
+	ErrGatewayUnavailable = errors.New("payment gateway unavailable")
+)
+
+// checkoutRequest is the body accepted by POST /checkout
+type checkoutRequest struct {
+	CartID string `json:"cart_id" binding:"required"`
+}
+
+// otlpRequest is the body accepted by PUT /admin/otlp
+type otlpRequest struct {
+	Protocol string `json:"protocol" binding:"required"`
+	// Endpoint defaults to the current one, moved to the new protocol's standard port
+	Endpoint string `json:"endpoint"`
+}
+
+// instruments holds every metric the request path records
+type instruments struct {
+	requestDuration metric.Float64Histogram
+	activeRequests  metric.Int64UpDownCounter
+	checkouts       metric.Int64Counter
+	orderAmount     metric.Float64Histogram
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Grafana Observability Stack Demo ===")
+	console.Println("One request path emitting logs to Loki, traces to Tempo and metrics to Prometheus")
+	console.Println()
+
+	// The single source of truth for identity across logs, traces and metrics
+	shared := otelresource.Get()
+	initialFields := shared.Fields()
+
+	// Logs: stdout plus Loki's native OTLP endpoint via the logger's built-in exporter
+	lokiURL := getEnvOrDefault("LOKI_OTLP_URL", "http://localhost:3100/otlp/v1/logs")
+	otlpEnabled := os.Getenv("DISABLE_LOKI") != "true"
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		OTLP: &option.OTLPOption{
+			Enabled:  &otlpEnabled,
+			Endpoint: lokiURL,
+			Protocol: "http",
+			Timeout:  5 * time.Second,
+			Insecure: true,
+		},
+		InitialFields: initialFields,
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	// Traces: Tempo's OTLP receiver; the resource carries the same shared identity
+	tempoProtocol := getEnvOrDefault("TEMPO_OTLP_PROTOCOL", otelsetup.ProtocolHTTP)
+	tempoEndpoint := getEnvOrDefault("TEMPO_OTLP_ENDPOINT", "localhost:"+otelsetup.DefaultHTTPPort)
+	if tempoProtocol == otelsetup.ProtocolGRPC {
+		tempoEndpoint = getEnvOrDefault("TEMPO_OTLP_ENDPOINT", "localhost:"+otelsetup.DefaultGRPCPort)
+	}
+	telemetry, shutdownTracing, err := otelsetup.Setup(context.Background(), shared.Config(otelsetup.Config{
+		Endpoint:       tempoEndpoint,
+		Protocol:       tempoProtocol,
+		Insecure:       true,
+		TLS:            certwatch.FromEnv("TEMPO_OTLP", serviceLogger),
+		ExportInterval: 2 * time.Second,
+		Signals:        otelsetup.Traces,
+	}), serviceLogger)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to initialize tracing", "error", err.Error())
+	}
+	crash.OnExit("otel", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+
+	// Metrics: scraped by Prometheus from /metrics
+	meterProvider, metricsHandler, err := initMetrics(telemetry.Resource)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to initialize metrics", "error", err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownTracing(ctx)
+		meterProvider.Shutdown(ctx)
+		serviceLogger.Flush()
+	}()
+
+	tracer := otel.Tracer(instrumentationName)
+	inst, err := newInstruments(otel.Meter(instrumentationName))
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to create metric instruments", "error", err.Error())
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery(), logcontext.GinRequestLogger(serviceLogger))
+
+	r.GET("/metrics", gin.WrapH(metricsHandler))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	// The trace exporter can be moved between OTLP/HTTP and OTLP/gRPC without a restart.
+	// There is no default token: without ADMIN_TOKEN the admin routes are not served at all.
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	// Admin requests, let through or not, go to the audit trail in AUDIT_LOG_FILE
+	auditPath := getEnvOrDefault("AUDIT_LOG_FILE", "logs/audit.log")
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to open audit log", "path", auditPath, "error", err.Error())
+	}
+	defer auditLog.Close()
+	if adminToken != "" {
+		admin := r.Group("/admin", auditLog.GinMiddleware(audit.ActionAdminRequest, serviceLogger), adminAuth(adminToken))
+		admin.GET("/otlp", func(c *gin.Context) {
+			protocol, endpoint := telemetry.Protocol()
+			c.JSON(http.StatusOK, gin.H{"protocol": protocol, "endpoint": endpoint})
+		})
+		admin.PUT("/otlp", otlpSwitchHandler(telemetry, allowedEndpoints(tempoEndpoint)))
+	} else {
+		serviceLogger.Warnw("Admin routes disabled", "reason", "ADMIN_TOKEN not set")
+	}
+
+	// The request logs are checked against logrules.yaml, so a burst of failed checkouts is
+	// flagged in the service's own output as well as in Grafana
+	rulesPath := getEnvOrDefault("LOG_RULES_FILE", "logrules.yaml")
+	rulesCfg, err := logrules.Load(rulesPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to load log rules", "path", rulesPath, "error", err.Error())
+	}
+	rules, err := logrules.New(rulesCfg, serviceLogger.With("component", "logrules"))
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to start log rules", "path", rulesPath, "error", err.Error())
+	}
+	defer rules.Close()
+	// crash.Fatal logs at error level, so webhooks it triggers are still in flight when it exits
+	crash.OnExit("log rules", func() error {
+		rules.Close()
+		return nil
+	})
+
+	instrumented := r.Group("/", telemetryMiddleware(tracer, inst, rules.Wrap(serviceLogger)))
+	instrumented.POST("/checkout", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		log := logcontext.MustFromGin(c)
+
+		var req checkoutRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items := loadCart(ctx, tracer, req.CartID)
+		amount := priceCart(ctx, tracer, items)
+		log.Infow("Cart priced", "cart_id", req.CartID, "items", items, "amount_usd", amount)
+
+		err := chargeCard(ctx, tracer, amount)
+		outcome := "completed"
+		switch {
+		case errors.Is(err, ErrCardDeclined):
+			outcome = "declined"
+			log.Warnw("Checkout declined", "cart_id", req.CartID, "amount_usd", amount, "reason", err.Error())
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		case err != nil:
+			outcome = "failed"
+			log.Errorw("Checkout failed", "cart_id", req.CartID, "amount_usd", amount, "error", err.Error())
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			inst.orderAmount.Record(ctx, amount)
+			log.Infow("Checkout completed", "cart_id", req.CartID, "amount_usd", amount)
+			c.JSON(http.StatusOK, gin.H{"cart_id": req.CartID, "amount_usd": amount, "status": "paid"})
+		}
+		inst.checkouts.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+	})
+
+	port := getEnvOrDefault("PORT", "8099")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port, "tempo_endpoint", tempoEndpoint, "tempo_protocol", tempoProtocol, "log_rules", len(rulesCfg.Rules), "audit_log", auditPath)
+	serviceLogger.Infow("Starting observability demo server",
+		"port", port,
+		"loki_otlp_url", lokiURL,
+		"loki_enabled", otlpEnabled,
+		"tempo_endpoint", tempoEndpoint,
+		"tempo_protocol", tempoProtocol,
+		"metrics_path", "/metrics",
+		"grafana", "http://localhost:3000",
+	)
+
+	if rps := getIntEnv("TRAFFIC_RPS", 2); rps > 0 {
+		go generateTraffic("http://localhost:"+port, rps)
+	}
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl -X POST http://localhost:%s/checkout -H 'Content-Type: application/json' -d '{\"cart_id\":\"c-42\"}'\n", port)
+	console.Printf("  curl -s http://localhost:%s/metrics | grep checkout\n", port)
+	console.Printf("  curl -X PUT http://localhost:%s/admin/otlp -H \"%s: %s\" -d '{\"protocol\":\"grpc\"}'\n", port, adminTokenHeader, adminToken)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+func newInstruments(meter metric.Meter) (*instruments, error) {
+	requestDuration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5),
+	)
+	if err != nil {
+		return nil, err
+	}
+	activeRequests, err := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of in-flight HTTP requests"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	checkouts, err := meter.Int64Counter("checkout.completions",
+		metric.WithUnit("{checkout}"),
+		metric.WithDescription("Checkouts by outcome"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	orderAmount, err := meter.Float64Histogram("checkout.amount",
+		metric.WithUnit("USD"),
+		metric.WithDescription("Value of completed checkouts"),
+		metric.WithExplicitBucketBoundaries(10, 25, 50, 100, 250, 500),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &instruments{
+		requestDuration: requestDuration,
+		activeRequests:  activeRequests,
+		checkouts:       checkouts,
+		orderAmount:     orderAmount,
+	}, nil
+}
+
+// telemetryMiddleware opens the server span, tracks request metrics and writes the access log,
+// all labelled with the same route so the three signals line up in Grafana. The request
+// logger it stores is a child of serviceLogger that also carries the span's IDs
+func telemetryMiddleware(tracer trace.Tracer, inst *instruments, serviceLogger core.Logger) gin.HandlerFunc {
+	propagator := otel.GetTextMapPropagator()
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Request.Method), semconv.HTTPRoute(route)),
+		)
+		defer span.End()
+
+		routeAttrs := metric.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Request.Method), semconv.HTTPRoute(route))
+		inst.activeRequests.Add(ctx, 1, routeAttrs)
+		defer inst.activeRequests.Add(ctx, -1, routeAttrs)
+
+		c.Request = c.Request.WithContext(ctx)
+		log := traceLogger(ctx, serviceLogger.With(logcontext.GinRequestFields(c)...))
+		logcontext.SetGin(c, log)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		duration := time.Since(start)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		inst.requestDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		))
+
+		log.Infow("Request completed",
+			"status", status,
+			"duration_ms", duration.Milliseconds(),
+		)
+	}
+}
+
+func loadCart(ctx context.Context, tracer trace.Tracer, cartID string) int {
+	_, span := tracer.Start(ctx, "cart.load", trace.WithAttributes(attribute.String("cart.id", cartID)))
+	defer span.End()
+
+	time.Sleep(time.Duration(5+rng.Intn(15)) * time.Millisecond)
+	items := 1 + rng.Intn(6)
+	span.SetAttributes(attribute.Int("cart.items", items))
+	return items
+}
+
+func priceCart(ctx context.Context, tracer trace.Tracer, items int) float64 {
+	_, span := tracer.Start(ctx, "pricing.calculate")
+	defer span.End()
+
+	time.Sleep(time.Duration(2+rng.Intn(8)) * time.Millisecond)
+	amount := float64(items) * (5 + rng.Float64()*60)
+	amount = float64(int(amount*100)) / 100
+	span.SetAttributes(attribute.Float64("checkout.amount_usd", amount))
+	return amount
+}
+
+// chargeCard simulates the payment gateway: ~10% declines and ~3% gateway failures
+func chargeCard(ctx context.Context, tracer trace.Tracer, amount float64) error {
+	_, span := tracer.Start(ctx, "payment.charge", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("payment.gateway", "stripe"), attribute.Float64("payment.amount_usd", amount)))
+	defer span.End()
+
+	time.Sleep(time.Duration(40+rng.Intn(160)) * time.Millisecond)
+	switch n := rng.Intn(100); {
+	case n < 3:
+		span.RecordError(ErrGatewayUnavailable)
+		span.SetStatus(codes.Error, ErrGatewayUnavailable.Error())
+		return ErrGatewayUnavailable
+	case n < 13:
+		// A decline is a valid answer from the gateway, so the span is not marked as an error
+		span.SetAttributes(attribute.String("payment.decline_code", "insufficient_funds"))
+		return ErrCardDeclined
+	}
+	return nil
+}
+
+// generateTraffic keeps the dashboards populated without an external load tool
+func generateTraffic(baseURL string, rps int) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+
+	time.Sleep(time.Second)
+	for range ticker.C {
+		body := fmt.Sprintf(`{"cart_id":"c-%d"}`, rng.Intn(1000))
+		resp, err := client.Post(baseURL+"/checkout", "application/json", bytes.NewBufferString(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// otlpSwitchHandler moves the trace exporter to the protocol and endpoint in the request.
+// The exporter sends the spans, their headers and the mTLS client certificate to that
+// endpoint, so only the endpoints in allowed are accepted; an empty one keeps the current.
+func otlpSwitchHandler(telemetry *otelsetup.Providers, allowed map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req otlpRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Endpoint != "" && !allowed[req.Endpoint] {
+			logcontext.MustFromGin(c).Warnw("OTLP endpoint not allowed", "endpoint", req.Endpoint)
+			c.JSON(http.StatusForbidden, gin.H{"error": "endpoint not in " + envAllowedEndpoints, "endpoint": req.Endpoint})
+			return
+		}
+		h, err := telemetry.SwitchProtocol(c.Request.Context(), req.Protocol, req.Endpoint)
+		if errors.Is(err, otelsetup.ErrUnsupportedProtocol) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		body := gin.H{
+			"protocol":     h.Protocol,
+			"endpoint":     h.Endpoint,
+			"handshake":    h.Result,
+			"handshake_ms": h.Latency.Milliseconds(),
+		}
+		if err != nil {
+			body["error"] = err.Error()
+			c.JSON(http.StatusBadGateway, body)
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+// envAllowedEndpoints lists, comma-separated, the endpoints PUT /admin/otlp may switch to
+const envAllowedEndpoints = "TEMPO_OTLP_ALLOWED_ENDPOINTS"
+
+// allowedEndpoints returns the endpoints in TEMPO_OTLP_ALLOWED_ENDPOINTS or, when it is
+// unset, the configured endpoint's host on either standard OTLP port
+func allowedEndpoints(configured string) map[string]bool {
+	allowed := map[string]bool{configured: true}
+	if value := os.Getenv(envAllowedEndpoints); value != "" {
+		allowed = map[string]bool{}
+		for _, endpoint := range strings.Split(value, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				allowed[endpoint] = true
+			}
+		}
+		return allowed
+	}
+	if host, _, err := net.SplitHostPort(configured); err == nil {
+		allowed[net.JoinHostPort(host, otelsetup.DefaultGRPCPort)] = true
+		allowed[net.JoinHostPort(host, otelsetup.DefaultHTTPPort)] = true
+	}
+	return allowed
+}
+
+// adminAuth protects the admin endpoints with a static token
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hmac.Equal([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) {
+			logcontext.MustFromGin(c).Warnw("Unauthorized admin request", "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kart-io/logger/core"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// MockCollector is a minimal OTLP/HTTP logs receiver that prints what each pipeline exported
+type MockCollector struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+}
+
+// StartMockCollector listens on addr and serves POST /v1/logs
+func StartMockCollector(addr string, logger core.Logger) (*MockCollector, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	c := &MockCollector{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", c.handleLogs)
+	c.server = &http.Server{Handler: mux}
+
+	go c.server.Serve(listener)
+	return c, nil
+}
+
+// Addr returns the host:port the collector listens on
+func (c *MockCollector) Addr() string {
+	return c.listener.Addr().String()
+}
+
+// Close stops the collector
+func (c *MockCollector) Close() error {
+	return c.server.Close()
+}
+
+func (c *MockCollector) handleLogs(w http.ResponseWriter, r *http.Request) {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req collogsv1.ExportLogsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		c.logger.Warnw("Mock collector received undecodable payload",
+			"content_type", r.Header.Get("Content-Type"),
+			"error", err.Error(),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, resourceLogs := range req.GetResourceLogs() {
+		resource := attributesToMap(resourceLogs.GetResource().GetAttributes())
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				attrs := attributesToMap(record.GetAttributes())
+				severity := record.GetSeverityText()
+				if severity == "" {
+					severity = record.GetSeverityNumber().String()
+				}
+				c.logger.Infow("Mock collector received log record",
+					"user_agent", r.UserAgent(),
+					"scope", scopeLogs.GetScope().GetName(),
+					"severity", severity,
+					"body", anyValueString(record.GetBody()),
+					"resource", formatAttributes(resource),
+					"attributes", formatAttributes(attrs),
+				)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&collogsv1.ExportLogsServiceResponse{})
+	w.Write(out)
+}
+
+func attributesToMap(kvs []*commonv1.KeyValue) map[string]string {
+	result := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		result[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return result
+}
+
+func anyValueString(v *commonv1.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return value.StringValue
+	case *commonv1.AnyValue_IntValue:
+		return fmt.Sprint(value.IntValue)
+	case *commonv1.AnyValue_DoubleValue:
+		return fmt.Sprint(value.DoubleValue)
+	case *commonv1.AnyValue_BoolValue:
+		return fmt.Sprint(value.BoolValue)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatAttributes renders attributes as sorted key=value pairs for readable output
+func formatAttributes(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + attrs[key]
+	}
+	return strings.Join(parts, " ")
+}
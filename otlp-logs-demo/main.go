@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Native OTLP Logs Exporter Demo ===")
+	console.Println("Built-in logger OTLP option vs OpenTelemetry logs SDK bridge, side by side")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Console logger for the demo itself and the mock collector output
+	logOption := &option.LogOption{
+		Engine:      "slog",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	consoleLogger := baseLogger.With("component", "demo")
+
+	// Without an external endpoint, run an in-process collector so the demo is self-contained
+	endpoint := os.Getenv("OTLP_ENDPOINT")
+	if endpoint == "" {
+		collector, err := StartMockCollector("127.0.0.1:0", baseLogger.With("component", "mock-collector"))
+		if err != nil {
+			crash.Fatal(consoleLogger, "Failed to start mock collector", "error", err.Error())
+		}
+		defer collector.Close()
+		endpoint = collector.Addr()
+		consoleLogger.Infow("Started in-process mock collector", "endpoint", endpoint)
+	}
+
+	// Both pipelines describe the same service instance with identical attribute names,
+	// so a backend can correlate records no matter which path exported them
+	shared := otelresource.Get().Attributes()
+	shared["host.name"] = hostmeta.Get().Hostname
+
+	sanitize.Startup(baseLogger, logOption, "otlp_endpoint", endpoint)
+	consoleLogger.Infow("Exporting logs over OTLP/HTTP",
+		"endpoint", endpoint,
+		"shared_attributes", shared,
+	)
+
+	// Pipeline 1: the logger's built-in OTLP option
+	builtin, err := newBuiltinLogger(endpoint, shared)
+	if err != nil {
+		crash.Fatal(consoleLogger, "Failed to create built-in OTLP logger", "error", err.Error())
+	}
+	crash.OnExit("built-in OTLP logger", builtin.Flush)
+
+	// Pipeline 2: OpenTelemetry logs SDK with the slog bridge
+	ctx := context.Background()
+	provider, bridged, err := newBridgeLogger(ctx, endpoint, shared)
+	if err != nil {
+		crash.Fatal(consoleLogger, "Failed to create OTel bridge logger", "error", err.Error())
+	}
+	crash.OnExit("otel logger provider", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	})
+
+	emitSampleEvents(ctx, builtin, bridged)
+
+	// The SDK batches records; flush both pipelines before exiting
+	if err := builtin.Flush(); err != nil {
+		consoleLogger.Warnw("Built-in logger flush failed", "error", err.Error())
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(shutdownCtx); err != nil {
+		consoleLogger.Warnw("OTel logger provider shutdown failed", "error", err.Error())
+	}
+
+	consoleLogger.Infow("Demo finished", "endpoint", endpoint)
+}
+
+// newBuiltinLogger enables OTLP on the kart-io logger; shared attributes travel as InitialFields
+func newBuiltinLogger(endpoint string, shared map[string]string) (core.Logger, error) {
+	enabled := true
+	fields := map[string]interface{}{"pipeline": "builtin"}
+	for key, value := range shared {
+		fields[key] = value
+	}
+
+	return logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		OTLP: &option.OTLPOption{
+			Enabled:  &enabled,
+			Endpoint: endpoint,
+			Protocol: "http",
+			Timeout:  5 * time.Second,
+			Insecure: true,
+		},
+		InitialFields: fields,
+	})
+}
+
+// newBridgeLogger sets up the OTel logs SDK; shared attributes travel as the OTLP resource
+func newBridgeLogger(ctx context.Context, endpoint string, shared map[string]string) (*sdklog.LoggerProvider, *slog.Logger, error) {
+	exporter, err := otlploghttp.New(ctx,
+		otlploghttp.WithEndpoint(endpoint),
+		otlploghttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(shared))
+	for key, value := range shared {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build resource: %w", err)
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter,
+			sdklog.WithExportInterval(time.Second),
+		)),
+	)
+
+	bridged := otelslog.NewLogger("otlp-logs-demo",
+		otelslog.WithLoggerProvider(provider),
+	).With("pipeline", "bridge")
+
+	return provider, bridged, nil
+}
+
+// emitSampleEvents writes the same events through both pipelines
+func emitSampleEvents(ctx context.Context, builtin core.Logger, bridged *slog.Logger) {
+	events := []struct {
+		level   string
+		message string
+		fields  []interface{}
+	}{
+		{"info", "Order placed", []interface{}{"order_id", "ord-1001", "amount_cents", 4200}},
+		{"warn", "Payment retry scheduled", []interface{}{"order_id", "ord-1001", "attempt", 2}},
+		{"error", "Shipping label creation failed", []interface{}{"order_id", "ord-1001", "carrier", "acme-post"}},
+	}
+
+	for _, event := range events {
+		switch event.level {
+		case "warn":
+			builtin.Warnw(event.message, event.fields...)
+			bridged.WarnContext(ctx, event.message, event.fields...)
+		case "error":
+			builtin.Errorw(event.message, event.fields...)
+			bridged.ErrorContext(ctx, event.message, event.fields...)
+		default:
+			builtin.Infow(event.message, event.fields...)
+			bridged.InfoContext(ctx, event.message, event.fields...)
+		}
+	}
+}
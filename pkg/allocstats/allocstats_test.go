@@ -0,0 +1,103 @@
+package allocstats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+var sink [][]byte
+
+func TestFieldsReportIntervalDeltas(t *testing.T) {
+	r := New(testlog.New(t).Logger)
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+	sink = nil
+	runtime.GC()
+	runtime.GC()
+
+	fields := map[string]interface{}{}
+	kv := r.Fields()
+	for i := 0; i < len(kv); i += 2 {
+		fields[kv[i].(string)] = kv[i+1]
+	}
+	if cycles := fields["gc_cycles"].(uint32); cycles < 2 {
+		t.Errorf("gc_cycles = %d, want at least 2", cycles)
+	}
+	if rate := fields["mallocs_per_sec"].(float64); rate < 1000 {
+		t.Errorf("mallocs_per_sec = %v, want at least 1000 allocations over the interval", rate)
+	}
+	if fields["heap_alloc_bytes"].(uint64) == 0 || fields["gc_pause_max_ms"].(float64) < 0 {
+		t.Errorf("fields = %v", fields)
+	}
+
+	// The next interval starts where this one ended
+	kv = r.Fields()
+	for i := 0; i < len(kv); i += 2 {
+		if kv[i] == "gc_cycles" && kv[i+1].(uint32) != 0 {
+			t.Errorf("second interval gc_cycles = %v, want 0", kv[i+1])
+		}
+	}
+}
+
+func TestRunLogsEveryInterval(t *testing.T) {
+	rec := testlog.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		New(rec.Logger).Run(ctx, 20*time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(110 * time.Millisecond)
+	cancel()
+	<-done
+
+	if n := rec.Count("info", "Allocation stats"); n < 3 {
+		t.Fatalf("logged %d times in 110ms at a 20ms interval", n)
+	}
+	entry := rec.AssertLogged("info", "Allocation stats")
+	for _, key := range []string{"heap_alloc_bytes", "alloc_bytes_per_sec", "gc_cycles", "gc_pause_max_ms", "interval_ms"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("entry has no %s: %v", key, entry)
+		}
+	}
+}
+
+func TestHeapProfileHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	r := gin.New()
+	r.POST("/admin/heap-profile", HeapProfileHandler(dir, rec.Logger))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/heap-profile", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Path      string `json:"path"`
+		SizeBytes int64  `json:"size_bytes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(body.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pprof profiles are gzipped protobuf
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b || int64(len(data)) != body.SizeBytes {
+		t.Errorf("profile %s: %d bytes, reported %d", body.Path, len(data), body.SizeBytes)
+	}
+	rec.AssertLogged("info", "Heap profile written", "path", body.Path)
+}
@@ -0,0 +1,177 @@
+// Package allowlist enforces a registered set of field keys on log calls, so the fields a
+// team's services emit stay the ones dashboards, alert rules and the log schema know
+// about instead of growing a new spelling with every handler.
+//
+// A key that is not registered is kept or dropped from the entry, depending on the mode;
+// either way the first time each unknown key is seen a warning names it and the message
+// it came with, so a violation shows up once in the logs rather than on every request:
+//
+//	mode: report          # report (keep the field) or drop
+//	fields:
+//	  - user_id
+//	  - order_id
+//	  - http.*            # every key under the http. prefix
+//
+// The keys of every logging call and of With are checked, whichever method logs them;
+// InitialFields are set when the logger is built and are the schema's business, not the
+// allowlist's.
+package allowlist
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Modes for unknown keys
+const (
+	// ModeReport keeps unknown keys and warns once per key
+	ModeReport = "report"
+	// ModeDrop removes unknown keys from the entry and warns once per key
+	ModeDrop = "drop"
+)
+
+// ViolationMessage is the warning logged the first time an unknown key is seen
+const ViolationMessage = "Unregistered log field"
+
+// Config is the YAML file
+type Config struct {
+	// Mode is ModeReport or ModeDrop, ModeReport when empty
+	Mode string `yaml:"mode"`
+	// Fields are the registered keys; a key ending in ".*" registers its whole prefix
+	Fields []string `yaml:"fields"`
+}
+
+// Load reads and validates an allowlist file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read field allowlist: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse field allowlist: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports the first setting the Allowlist cannot work with, naming the YAML key
+func (cfg Config) Validate() error {
+	if cfg.Mode != "" && cfg.Mode != ModeReport && cfg.Mode != ModeDrop {
+		return fmt.Errorf("mode must be %s or %s, not %q", ModeReport, ModeDrop, cfg.Mode)
+	}
+	if len(cfg.Fields) == 0 {
+		return fmt.Errorf("no fields: an empty allowlist rejects every field")
+	}
+	for i, field := range cfg.Fields {
+		if field == "" || field == ".*" {
+			return fmt.Errorf("fields[%d]: empty key", i)
+		}
+	}
+	return nil
+}
+
+// Allowlist checks field keys against the registered ones; it is safe for concurrent use
+type Allowlist struct {
+	mode     string
+	keys     map[string]bool
+	prefixes []string
+
+	mu sync.Mutex
+	// unknown counts the calls each unknown key appeared in
+	unknown map[string]int
+}
+
+// New builds an Allowlist from cfg
+func New(cfg Config) (*Allowlist, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Allowlist{mode: cfg.Mode, keys: map[string]bool{}, unknown: map[string]int{}}
+	if a.mode == "" {
+		a.mode = ModeReport
+	}
+	a.Register(cfg.Fields...)
+	return a, nil
+}
+
+// Register adds keys to the allowlist, such as the fields a package logs on its own
+func (a *Allowlist) Register(keys ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, key := range keys {
+		if prefix, ok := strings.CutSuffix(key, "*"); ok && strings.HasSuffix(prefix, ".") {
+			a.prefixes = append(a.prefixes, prefix)
+			continue
+		}
+		a.keys[key] = true
+	}
+}
+
+// Mode returns ModeReport or ModeDrop
+func (a *Allowlist) Mode() string {
+	return a.mode
+}
+
+// Allowed reports whether key is registered
+func (a *Allowlist) Allowed(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allowed(key)
+}
+
+func (a *Allowlist) allowed(key string) bool {
+	if a.keys[key] {
+		return true
+	}
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns keyValues with unknown keys dropped in ModeDrop, and the unknown keys
+// seen here for the first time
+func (a *Allowlist) check(keyValues []interface{}) ([]interface{}, []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var first []string
+	out := keyValues
+	if a.mode == ModeDrop {
+		out = make([]interface{}, 0, len(keyValues))
+	}
+	for i := 0; i < len(keyValues); i += 2 {
+		key := fmt.Sprint(keyValues[i])
+		if a.allowed(key) {
+			if a.mode == ModeDrop {
+				out = append(out, keyValues[i:min(i+2, len(keyValues))]...)
+			}
+			continue
+		}
+		if a.unknown[key] == 0 {
+			first = append(first, key)
+		}
+		a.unknown[key]++
+	}
+	return out, first
+}
+
+// Unknown returns the unknown keys seen so far with the number of log calls each
+// appeared in
+func (a *Allowlist) Unknown() map[string]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]int, len(a.unknown))
+	for key, n := range a.unknown {
+		out[key] = n
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package allowlist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestAllowed(t *testing.T) {
+	a, err := New(Config{Fields: []string{"user_id", "http.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Register("order_id")
+	for key, want := range map[string]bool{
+		"user_id": true, "order_id": true, "http.method": true, "http.request.size": true,
+		"userId": false, "http": false, "https.method": false, "": false,
+	} {
+		if got := a.Allowed(key); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	rec := testlog.New(t)
+	a, _ := New(Config{Fields: []string{"user_id", "order_id"}})
+	log := a.Wrap(rec.Logger).With("userId", "u-1")
+
+	for i := 0; i < 3; i++ {
+		log.Infow("Order created", "order_id", "o-1", "orderId", "o-1")
+	}
+
+	rec.AssertLogged("info", "Order created", "order_id", "o-1", "orderId", "o-1", "userId", "u-1")
+	rec.AssertLogged("warn", ViolationMessage, "field", "orderId", "log_message", "Order created", "mode", ModeReport)
+	rec.AssertLogged("warn", ViolationMessage, "field", "userId", "log_message", "")
+	if n := rec.Count("warn", ViolationMessage); n != 2 {
+		t.Errorf("logged %d violations, want one per unknown key", n)
+	}
+	if unknown := a.Unknown(); unknown["orderId"] != 3 || unknown["userId"] != 1 {
+		t.Errorf("Unknown() = %v", unknown)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	rec := testlog.New(t)
+	a, _ := New(Config{Mode: ModeDrop, Fields: []string{"order_id"}})
+	a.Wrap(rec.Logger).Warnw("Payment declined", "order_id", "o-1", "card_number", "4111111111111111")
+
+	entry := rec.AssertLogged("warn", "Payment declined", "order_id", "o-1")
+	if _, ok := entry["card_number"]; ok {
+		t.Errorf("unknown field kept in drop mode: %v", entry)
+	}
+	rec.AssertLogged("warn", ViolationMessage, "field", "card_number", "mode", ModeDrop)
+}
+
+func TestPlainAndFormatted(t *testing.T) {
+	rec := testlog.New(t)
+	a, _ := New(Config{Mode: ModeDrop, Fields: []string{"order_id"}})
+	log := a.Wrap(rec.Logger).With("order_id", "o-1", "card_number", "4111111111111111")
+
+	log.Info("Payment accepted")
+	log.Infof("Payment %s", "refunded")
+	log.Error("Payment failed")
+	log.WithCtx(context.Background(), "cardHolder", "Jane").Warnf("Payment %s", "held")
+
+	for _, msg := range []string{"Payment accepted", "Payment refunded", "Payment failed", "Payment held"} {
+		entry := rec.AssertLogged("", msg, "order_id", "o-1")
+		for _, key := range []string{"card_number", "cardHolder"} {
+			if _, ok := entry[key]; ok {
+				t.Errorf("unknown field %s kept in drop mode: %v", key, entry)
+			}
+		}
+	}
+	rec.AssertLogged("warn", ViolationMessage, "field", "card_number", "mode", ModeDrop)
+	rec.AssertLogged("warn", ViolationMessage, "field", "cardHolder", "mode", ModeDrop)
+	if n := rec.Count("warn", ViolationMessage); n != 2 {
+		t.Errorf("logged %d violations, want one per unknown key", n)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, tt := range map[string]struct {
+		yaml, err string
+	}{
+		"valid":        {"mode: drop\nfields: [user_id, http.*]\n", ""},
+		"unknown mode": {"mode: strict\nfields: [user_id]\n", "mode must be"},
+		"no fields":    {"mode: report\n", "no fields"},
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".yaml")
+		os.WriteFile(path, []byte(tt.yaml), 0o644)
+		_, err := Load(path)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: err = %v, want %q", name, err, tt.err)
+		}
+	}
+}
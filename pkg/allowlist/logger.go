@@ -0,0 +1,29 @@
+package allowlist
+
+import (
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger that enforces a on every field logged through base, in any
+// logging method, and on the fields added with With, once, when they are attached. The
+// first use of an unknown key is reported at the line that logged it.
+func (a *Allowlist) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		// Both hooks warn themselves, so the warning's caller is the code that logged
+		Entry: func(e *logwrap.Entry, out core.Logger) {
+			var first []string
+			e.Fields, first = a.check(e.Fields)
+			for _, key := range first {
+				out.Warnw(ViolationMessage, "field", key, "log_message", e.Message, "mode", a.mode)
+			}
+		},
+		With: func(keyValues []interface{}, out core.Logger) []interface{} {
+			keyValues, first := a.check(keyValues)
+			for _, key := range first {
+				out.Warnw(ViolationMessage, "field", key, "log_message", "", "mode", a.mode)
+			}
+			return keyValues
+		},
+	})
+}
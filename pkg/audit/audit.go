@@ -0,0 +1,238 @@
+// Package audit writes the audit trail of admin actions and authentication decisions to a
+// file of its own, apart from the operational log, where an entry cannot be changed or
+// removed without it showing.
+//
+// An entry holds a fixed set of fields, so nothing a handler happens to have at hand, such
+// as a token or a request body, ends up in the trail. Each one carries the hash of the
+// entry before it, and its own hash covers that link, so editing, deleting or reordering a
+// line breaks the chain from there on:
+//
+//	{"seq":7,"time":"2026-10-17T09:12:44.1Z","actor":"alice","action":"admin.request",
+//	 "resource":"PUT /admin/chaos","outcome":"success","client_ip":"10.0.0.7",
+//	 "request_id":"req_01JA7Q3YB2K8M4TN6W9CXE5HRD","prev_hash":"9f2c…","hash":"41d0…"}
+//
+// The file is only ever appended to; Open verifies what is already there and continues
+// the chain. Verify, and the cmd/audit-verify command built on it, check a whole file.
+// The chain shows tampering inside the file, not its replacement by a consistent forgery;
+// ship the file, or its last hash, somewhere the service cannot write for that.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Actions the demos record
+const (
+	// ActionAdminRequest is a request to an admin or debug route, denied or handled
+	ActionAdminRequest = "admin.request"
+	// ActionWebhookSignature is a webhook delivery whose signature was checked
+	ActionWebhookSignature = "webhook.signature"
+	// ActionLogFields is a global log field set or removed on a running service
+	ActionLogFields = "log.fields"
+)
+
+// Outcomes an Event can have
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// GenesisHash is the prev_hash of the first entry of a file
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// MaxFieldLength bounds every text field; longer values are cut
+const MaxFieldLength = 256
+
+// Event is what happened; these are the only fields an entry can carry
+type Event struct {
+	// Actor is who acted: the admin user the caller named, or its address
+	Actor string `json:"actor"`
+	// Action is what was attempted, such as admin.request or webhook.signature
+	Action string `json:"action"`
+	// Resource is what it was attempted on, such as "PUT /admin/chaos"
+	Resource string `json:"resource,omitempty"`
+	// Outcome is OutcomeSuccess, OutcomeFailure or OutcomeDenied
+	Outcome   string `json:"outcome"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Reason explains a failure or denial with a short code, never with request data
+	Reason string `json:"reason,omitempty"`
+}
+
+// Entry is one line of the file
+type Entry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Event
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash is the hex SHA-256 of e encoded without its hash
+func (e Entry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Logger appends entries to an audit file; it is safe for concurrent use. A nil Logger
+// records nothing, so code paths can be audited before every caller has a trail.
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	last string
+	now  func() time.Time
+}
+
+// Open verifies the audit file at path and returns a Logger appending to it, creating
+// the file and its directory when missing. A file whose chain is broken is not appended
+// to; a new chain after a forged one would look intact.
+func Open(path string) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	result, err := Verify(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("audit file %s: %w", path, err)
+	}
+	return &Logger{file: file, seq: result.Entries, last: result.LastHash, now: time.Now}, nil
+}
+
+// Record appends e as the next entry and syncs it to disk, so an acknowledged action is
+// in the trail even if the process dies right after
+func (l *Logger) Record(e Event) error {
+	if l == nil {
+		return nil
+	}
+	if e.Action == "" {
+		return errors.New("audit event without action")
+	}
+	switch e.Outcome {
+	case OutcomeSuccess, OutcomeFailure, OutcomeDenied:
+	default:
+		return fmt.Errorf("audit outcome must be %s, %s or %s, not %q", OutcomeSuccess, OutcomeFailure, OutcomeDenied, e.Outcome)
+	}
+	for _, field := range []*string{&e.Actor, &e.Action, &e.Resource, &e.Outcome, &e.ClientIP, &e.RequestID, &e.Reason} {
+		*field = clean(*field)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := Entry{Seq: l.seq + 1, Time: l.now().UTC(), Event: e, PrevHash: l.last}
+	entry.Hash = entry.computeHash()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit file: %w", err)
+	}
+	l.seq, l.last = entry.Seq, entry.Hash
+	return nil
+}
+
+// Close closes the file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// clean cuts s to MaxFieldLength and drops control characters, so a value cannot fake a
+// line of its own in tools that print the fields
+func clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > MaxFieldLength {
+		cut := MaxFieldLength
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut]
+	}
+	return s
+}
+
+// Result is what Verify found in an intact file
+type Result struct {
+	Entries uint64
+	// LastHash is the hash the next entry links to, GenesisHash for an empty file
+	LastHash string
+}
+
+// ChainError reports the first line that breaks the chain
+type ChainError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verify reads an audit file from the start and checks every entry: it must parse, its
+// sequence number must follow the previous one, its prev_hash must be the previous
+// entry's hash and its hash must match its content. The first break is a *ChainError.
+func Verify(r io.Reader) (Result, error) {
+	result := Result{LastHash: GenesisHash}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return result, &ChainError{Line: line, Reason: "not an audit entry: " + err.Error()}
+		}
+		switch {
+		case e.Seq != result.Entries+1:
+			return result, &ChainError{Line: line, Reason: fmt.Sprintf("seq %d follows %d", e.Seq, result.Entries)}
+		case e.PrevHash != result.LastHash:
+			return result, &ChainError{Line: line, Reason: "prev_hash does not match the previous entry"}
+		case e.Hash != e.computeHash():
+			return result, &ChainError{Line: line, Reason: "hash does not match the entry"}
+		}
+		result.Entries, result.LastHash = e.Seq, e.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read audit file: %w", err)
+	}
+	return result, nil
+}
+
+// VerifyFile runs Verify on the file at path
+func VerifyFile(path string) (Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer file.Close()
+	return Verify(file)
+}
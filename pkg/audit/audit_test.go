@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func record(t *testing.T, l *Logger, events ...Event) {
+	t.Helper()
+	for _, e := range events {
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(t, l,
+		Event{Actor: "alice", Action: ActionAdminRequest, Resource: "PUT /admin/chaos", Outcome: OutcomeSuccess},
+		Event{Actor: "10.0.0.9", Action: ActionAdminRequest, Resource: "GET /debug/logs", Outcome: OutcomeDenied, Reason: "http_401"},
+	)
+	l.Close()
+
+	// Reopening continues the chain instead of starting a new one
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(t, l, Event{Actor: "bob", Action: ActionAdminRequest, Outcome: OutcomeFailure})
+	l.Close()
+
+	result, err := VerifyFile(path)
+	if err != nil || result.Entries != 3 {
+		t.Fatalf("VerifyFile() = %+v, %v, want 3 intact entries", result, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	for name, tt := range map[string]struct {
+		tampered string
+		line     int
+	}{
+		"edited field":    {strings.Replace(string(data), `"actor":"alice"`, `"actor":"mallory"`, 1), 1},
+		"deleted entry":   {lines[0] + lines[2], 2},
+		"reordered":       {lines[1] + lines[0] + lines[2], 1},
+		"truncated entry": {string(data[:len(data)-20]), 3},
+	} {
+		_, err := Verify(strings.NewReader(tt.tampered))
+		var chainErr *ChainError
+		if !errors.As(err, &chainErr) || chainErr.Line != tt.line {
+			t.Errorf("%s: err = %v, want a break at line %d", name, err, tt.line)
+		}
+	}
+
+	// A broken file is not appended to
+	os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600)
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Open(broken) err = %v", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Record(Event{Action: ActionAdminRequest, Outcome: "maybe"}); err == nil {
+		t.Error("unknown outcome accepted")
+	}
+	if err := l.Record(Event{Outcome: OutcomeSuccess}); err == nil {
+		t.Error("event without action accepted")
+	}
+	var nilLogger *Logger
+	if err := nilLogger.Record(Event{}); err != nil {
+		t.Errorf("nil Logger: %v", err)
+	}
+
+	if got := clean("alice\n{\"seq\":99}"); got != `alice{"seq":99}` {
+		t.Errorf("clean() = %q, want the newline dropped", got)
+	}
+	if got := clean(strings.Repeat("é", MaxFieldLength)); len(got) > MaxFieldLength || !strings.HasSuffix(got, "é") {
+		t.Errorf("clean() cut to %d bytes ending %q, want whole runes within the limit", len(got), got[len(got)-2:])
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	r := gin.New()
+	admin := r.Group("/admin", l.GinMiddleware(ActionAdminRequest, testlog.New(t).Logger), func(c *gin.Context) {
+		if c.GetHeader("X-Admin-Token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	admin.PUT("/chaos", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, token := range []string{"wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodPut, "/admin/chaos", nil)
+		req.Header.Set("X-Admin-Token", token)
+		req.Header.Set(ActorHeader, "alice")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	for _, want := range []string{
+		`"actor":"alice","action":"admin.request","resource":"PUT /admin/chaos","outcome":"denied"`,
+		`"reason":"http_401"`,
+		`"outcome":"success"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("audit file = %s, want it to contain %s", out, want)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Error("the token reached the audit file")
+	}
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/logger/core"
+)
+
+// ActorHeader names the admin user acting; without it the client address is the actor
+const ActorHeader = "X-Admin-User"
+
+// GinEvent describes the request in c for action; Resource is the method and route
+func GinEvent(c *gin.Context, action, outcome, reason string) Event {
+	actor := c.GetHeader(ActorHeader)
+	if actor == "" {
+		actor = c.ClientIP()
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return Event{
+		Actor:     actor,
+		Action:    action,
+		Resource:  c.Request.Method + " " + route,
+		Outcome:   outcome,
+		ClientIP:  c.ClientIP(),
+		RequestID: requestid.FromGin(c),
+		Reason:    reason,
+	}
+}
+
+// OutcomeOf maps a response status to an outcome: 401 and 403 are denials, other
+// statuses of 400 and above failures
+func OutcomeOf(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	case status >= 400:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// GinMiddleware records every request through the routes it guards once it was handled,
+// with the outcome its status implies and the status as the reason when it was not a
+// success. Install it ahead of the authentication middleware so denied requests are in
+// the trail too. An entry that cannot be written is logged to logger as an error.
+func (l *Logger) GinMiddleware(action string, logger core.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		status := c.Writer.Status()
+		outcome := OutcomeOf(status)
+		reason := ""
+		if outcome != OutcomeSuccess {
+			reason = "http_" + strconv.Itoa(status)
+		}
+		l.RecordOrLog(GinEvent(c, action, outcome, reason), logger)
+	}
+}
+
+// RecordOrLog records e and logs to logger the error writing it, for callers that have
+// already answered the request and cannot fail it any more
+func (l *Logger) RecordOrLog(e Event, logger core.Logger) {
+	if err := l.Record(e); err != nil {
+		logger.Errorw("Failed to write audit entry", "action", e.Action, "outcome", e.Outcome, "error", err.Error())
+	}
+}
@@ -0,0 +1,92 @@
+// Package buildinfo serves what a running demo was built from: the version injected by
+// the Makefile through github.com/kart-io/version, filled in from the Go toolchain's
+// embedded build info when the binary came from plain `go run`, plus runtime facts such
+// as uptime and goroutine count.
+//
+// Handler answers GET /version with JSON, or with a Prometheus build_info gauge when
+// scraped, so dashboards can join any metric on version and commit.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/version"
+)
+
+// Info is version.Get() merged with runtime information
+type Info struct {
+	Service   string  `json:"service"`
+	Version   string  `json:"version"`
+	Commit    string  `json:"commit"`
+	BuildDate string  `json:"build_date"`
+	Module    string  `json:"module,omitempty"`
+	GoVersion string  `json:"go_version"`
+	Platform  string  `json:"platform"`
+	Runtime   Runtime `json:"runtime"`
+}
+
+// Runtime describes the running process
+type Runtime struct {
+	Hostname      string    `json:"hostname"`
+	PID           int       `json:"pid"`
+	StartTime     time.Time `json:"start_time"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Goroutines    int       `json:"goroutines"`
+	NumCPU        int       `json:"num_cpu"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+}
+
+// Get returns the current build and runtime information
+func Get() Info {
+	v := version.Get()
+	info := Info{
+		Service:   v.ServiceName,
+		Version:   v.GitVersion,
+		Commit:    v.GitCommit,
+		BuildDate: v.BuildDate,
+		GoVersion: v.GoVersion,
+		Platform:  v.Platform,
+	}
+
+	// Without -ldflags the version fields are empty; the toolchain still records the
+	// module and, when built inside a checkout, the VCS revision
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Module = bi.Main.Path
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+	if info.Platform == "" {
+		info.Platform = runtime.GOOS + "/" + runtime.GOARCH
+	}
+
+	host := hostmeta.Get()
+	info.Runtime = Runtime{
+		Hostname:      host.Hostname,
+		PID:           host.PID,
+		StartTime:     host.StartTime,
+		UptimeSeconds: int64(time.Since(host.StartTime).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+	}
+	return info
+}
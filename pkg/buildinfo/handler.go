@@ -0,0 +1,65 @@
+package buildinfo
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricName is the gauge exported by Collector; it is always 1 and carries the build as labels
+const MetricName = "build_info"
+
+// Collector returns a collector for the build_info gauge, for services that already
+// expose a Prometheus registry
+func Collector() prometheus.Collector {
+	info := Get()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: MetricName,
+		Help: "Build information of the running binary; always 1.",
+		ConstLabels: prometheus.Labels{
+			"service":    info.Service,
+			"version":    info.Version,
+			"commit":     info.Commit,
+			"build_date": info.BuildDate,
+			"go_version": info.GoVersion,
+			"platform":   info.Platform,
+		},
+	})
+	gauge.Set(1)
+	return gauge
+}
+
+// Handler serves build information as JSON, or as the build_info metric in the
+// Prometheus text format when the request asks for it with ?format=prometheus or an
+// Accept header preferring text/plain or OpenMetrics, as scrapers send
+func Handler() gin.HandlerFunc {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(Collector())
+	metrics := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	return func(c *gin.Context) {
+		if wantsPrometheus(c) {
+			metrics.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+		c.JSON(http.StatusOK, Get())
+	}
+}
+
+func wantsPrometheus(c *gin.Context) bool {
+	switch c.Query("format") {
+	case "prometheus":
+		return true
+	case "json":
+		return false
+	}
+	accept := c.GetHeader("Accept")
+	if strings.Contains(accept, "application/openmetrics-text") {
+		return true
+	}
+	// Browsers and curl send */* or text/html; only an explicit text/plain first means a scraper
+	return strings.HasPrefix(accept, "text/plain")
+}
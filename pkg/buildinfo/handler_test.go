@@ -0,0 +1,58 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serve(t *testing.T, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/version", Handler())
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandlerJSON(t *testing.T) {
+	w := serve(t, "/version", http.Header{"Accept": {"*/*"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var info Info
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("decode: %v\n%s", err, w.Body)
+	}
+	if info.GoVersion == "" || info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("go_version %q, platform %q; want them filled from the runtime", info.GoVersion, info.Platform)
+	}
+	if info.Runtime.PID == 0 || info.Runtime.Goroutines == 0 || info.Runtime.StartTime.IsZero() {
+		t.Errorf("runtime = %+v, want pid, goroutines and start_time", info.Runtime)
+	}
+}
+
+func TestHandlerPrometheus(t *testing.T) {
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"query":  serve(t, "/version?format=prometheus", nil),
+		"accept": serve(t, "/version", http.Header{"Accept": {"text/plain;version=0.0.4;q=0.9,*/*;q=0.1"}}),
+	} {
+		body := w.Body.String()
+		if !strings.Contains(body, "# TYPE build_info gauge") || !strings.Contains(body, `go_version="`+runtime.Version()) {
+			t.Errorf("%s: body is not the build_info metric:\n%s", name, body)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(body), " 1") {
+			t.Errorf("%s: build_info value is not 1:\n%s", name, body)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Package certwatch loads the TLS files of a client connection and keeps them current.
+//
+// A Watcher reads a CA bundle and, for mutual TLS, a client certificate and key. It polls
+// the files' modification times and reloads them when a rotation tool replaces them: new
+// connections present the new certificate and verify against the new bundle, connections
+// already established are left alone. Every load is logged with the certificate's subject
+// and expiry, and a certificate close to or past its expiry is logged as a warning or an
+// error, once per certificate.
+//
+// Demos read the paths with FromEnv: FromEnv("OTLP", logger) reads OTLP_CA_FILE,
+// OTLP_CERT_FILE and OTLP_KEY_FILE.
+package certwatch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// Defaults applied to zero Config fields
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultExpiryWarning = 7 * 24 * time.Hour
+)
+
+// ErrNoFiles is returned by New when Config names no file
+var ErrNoFiles = errors.New("certwatch: no CA bundle or client certificate configured")
+
+// Config names the TLS files of one connection
+type Config struct {
+	// CAFile is a PEM bundle the server certificate is verified against; the system roots
+	// when empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate and key for mutual TLS; both or neither
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name the server certificate is verified for, which is
+	// otherwise the host of the endpoint
+	ServerName string
+
+	// Interval between checks of the files, DefaultInterval when zero
+	Interval time.Duration
+	// ExpiryWarning is how long before its expiry a certificate is warned about,
+	// DefaultExpiryWarning when zero
+	ExpiryWarning time.Duration
+	// Clock defaults to clock.Real
+	Clock clock.Clock
+}
+
+// Enabled reports whether any file is configured
+func (c Config) Enabled() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.KeyFile != ""
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if !c.Enabled() {
+		return c, ErrNoFiles
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return c, errors.New("certwatch: a client certificate needs both a certificate and a key file")
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.ExpiryWarning <= 0 {
+		c.ExpiryWarning = DefaultExpiryWarning
+	}
+	if c.Clock == nil {
+		c.Clock = clock.Real
+	}
+	return c, nil
+}
+
+// Watcher holds the current CA bundle and client certificate
+type Watcher struct {
+	cfg    Config
+	logger core.Logger
+
+	mu    sync.RWMutex
+	roots *x509.CertPool
+	cas   []*x509.Certificate
+	cert  *tls.Certificate
+
+	// checkMu serializes checks of the files and of expiry
+	checkMu sync.Mutex
+	// modTimes are the file times last loaded, or last failed to load, so a broken file is
+	// reported once rather than at every check
+	modTimes map[string]time.Time
+	// warned is the expiry state last logged per certificate
+	warned map[string]string
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New loads the files in cfg and logs each certificate; it fails when they cannot be read
+func New(cfg Config, logger core.Logger) (*Watcher, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		cfg:      cfg,
+		logger:   logger,
+		modTimes: map[string]time.Time{},
+		warned:   map[string]string{},
+	}
+	if _, err := w.reload(true); err != nil {
+		return nil, err
+	}
+	w.CheckExpiry()
+	return w, nil
+}
+
+// Load reads the files in cfg once into a tls.Config that is not reloaded, for short-lived
+// tools such as cmd/otlp-replay
+func Load(cfg Config) (*tls.Config, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.ServerName}
+	if cfg.CAFile != "" {
+		cas, err := readBundle(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool(cas)
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("certwatch: failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// TLSConfig returns a client configuration that always uses the current files. Each call
+// returns a new tls.Config, so callers may set fields on it.
+func (w *Watcher) TLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: w.cfg.ServerName}
+	if w.cfg.CertFile != "" {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			w.mu.RLock()
+			defer w.mu.RUnlock()
+			return w.cert, nil
+		}
+	}
+	if w.cfg.CAFile != "" {
+		// RootCAs cannot change once a tls.Config is in use, so the default verification is
+		// replaced by the same check against the current bundle
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = w.verify
+	}
+	return tlsConfig
+}
+
+func (w *Watcher) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("certwatch: server presented no certificate")
+	}
+	w.mu.RLock()
+	roots := w.roots
+	w.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// Certificate returns the current client certificate, nil without one
+func (w *Watcher) Certificate() *x509.Certificate {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.cert == nil {
+		return nil
+	}
+	return w.cert.Leaf
+}
+
+// Start checks the files every Interval until Stop
+func (w *Watcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := w.cfg.Clock.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				w.Reload()
+				w.CheckExpiry()
+			}
+		}
+	}()
+}
+
+// Stop ends the checks started by Start
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		if w.cancel != nil {
+			w.cancel()
+			<-w.done
+		}
+	})
+}
+
+// Reload loads the files that changed since the last check and reports whether any was
+// loaded. A file that fails to load is
+// logged and the previous certificate kept; it is retried once it changes again.
+func (w *Watcher) Reload() (bool, error) {
+	changed, err := w.reload(false)
+	if err != nil {
+		w.logger.Warnw("TLS certificate reload failed, keeping the previous one", "error", err.Error())
+	}
+	return changed, err
+}
+
+func (w *Watcher) reload(initial bool) (bool, error) {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	caChanged, err := w.modified(w.cfg.CAFile)
+	if err != nil {
+		return false, err
+	}
+	certChanged, err := w.modified(w.cfg.CertFile, w.cfg.KeyFile)
+	if err != nil {
+		return false, err
+	}
+	if !caChanged && !certChanged {
+		return false, nil
+	}
+
+	message := "TLS certificate reloaded"
+	if initial {
+		message = "TLS certificate loaded"
+	}
+	reloaded := false
+	var errs []error
+	if caChanged {
+		if cas, err := readBundle(w.cfg.CAFile); err != nil {
+			errs = append(errs, err)
+		} else {
+			w.mu.Lock()
+			w.roots, w.cas = pool(cas), cas
+			w.mu.Unlock()
+			w.logger.Infow(message, certFields(w.cfg.CAFile, cas[0], "certificate", "ca", "certificates", len(cas))...)
+			reloaded = true
+		}
+	}
+	if certChanged {
+		if cert, err := tls.LoadX509KeyPair(w.cfg.CertFile, w.cfg.KeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("certwatch: failed to load client certificate: %w", err))
+		} else {
+			w.mu.Lock()
+			previous := w.cert
+			w.cert = &cert
+			w.mu.Unlock()
+			fields := certFields(w.cfg.CertFile, cert.Leaf, "certificate", "client")
+			if previous != nil {
+				fields = append(fields, "previous_serial", previous.Leaf.SerialNumber.String(), "previous_not_after", previous.Leaf.NotAfter.Format(time.RFC3339))
+			}
+			w.logger.Infow(message, fields...)
+			reloaded = true
+		}
+	}
+	return reloaded, errors.Join(errs...)
+}
+
+// modified reports whether any of files changed since the last call, recording their times
+func (w *Watcher) modified(files ...string) (bool, error) {
+	changed := false
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("certwatch: %w", err)
+		}
+		if !info.ModTime().Equal(w.modTimes[file]) {
+			w.modTimes[file] = info.ModTime()
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// CheckExpiry logs every certificate that expires within ExpiryWarning, or has expired,
+// unless it was already logged in that state
+func (w *Watcher) CheckExpiry() {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	w.mu.RLock()
+	type loaded struct {
+		file string
+		cert *x509.Certificate
+	}
+	var certs []loaded
+	for _, ca := range w.cas {
+		certs = append(certs, loaded{w.cfg.CAFile, ca})
+	}
+	if w.cert != nil {
+		certs = append(certs, loaded{w.cfg.CertFile, w.cert.Leaf})
+	}
+	w.mu.RUnlock()
+
+	now := w.cfg.Clock.Now()
+	for _, c := range certs {
+		remaining := c.cert.NotAfter.Sub(now)
+		state := ""
+		switch {
+		case remaining <= 0:
+			state = "expired"
+		case remaining < w.cfg.ExpiryWarning:
+			state = "expiring"
+		}
+		key := c.file + "|" + c.cert.SerialNumber.String()
+		if state == "" || w.warned[key] == state {
+			continue
+		}
+		w.warned[key] = state
+		if state == "expired" {
+			w.logger.Errorw("TLS certificate expired", certFields(c.file, c.cert)...)
+			continue
+		}
+		w.logger.Warnw("TLS certificate expires soon",
+			certFields(c.file, c.cert, "expires_in", remaining.Round(time.Minute).String())...)
+	}
+}
+
+func certFields(file string, cert *x509.Certificate, extra ...interface{}) []interface{} {
+	return append([]interface{}{
+		"file", file,
+		"subject", cert.Subject.String(),
+		"serial", cert.SerialNumber.String(),
+		"not_after", cert.NotAfter.Format(time.RFC3339),
+	}, extra...)
+}
+
+// readBundle parses every certificate in a PEM file
+func readBundle(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("certwatch: %w", err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certwatch: %s: %w", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("certwatch: %s holds no PEM certificate", file)
+	}
+	return certs, nil
+}
+
+func pool(certs []*x509.Certificate) *x509.CertPool {
+	p := x509.NewCertPool()
+	for _, cert := range certs {
+		p.AddCert(cert)
+	}
+	return p
+}
@@ -0,0 +1,274 @@
+package certwatch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+var serial int64
+
+// authority issues test certificates
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	t.Helper()
+	ca := &authority{}
+	ca.cert, ca.key = issue(t, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	return ca
+}
+
+// issue signs template with parent, or self-signs it when parent is nil
+func issue(t *testing.T, parent *authority, template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	template.SerialNumber = big.NewInt(serial)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func (ca *authority) client(t *testing.T, name string, notAfter time.Time) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return issue(t, ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		NotAfter:    notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+func (ca *authority) server(t *testing.T) tls.Certificate {
+	cert, key := issue(t, ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "collector"},
+		NotAfter:    time.Now().Add(24 * time.Hour),
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+}
+
+// write stores cert and key as PEM with a modification time after every earlier write
+func write(t *testing.T, dir, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	if key != nil {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyFile = filepath.Join(dir, name+".key")
+		writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	}
+	return certFile, keyFile
+}
+
+var mtime = time.Now()
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Coarse file system timestamps would otherwise hide a rotation
+	mtime = mtime.Add(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mtlsServer answers with the common name of the client certificate
+func mtlsServer(t *testing.T, ca *authority) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{ca.server(t)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool([]*x509.Certificate{ca.cert}),
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, transport *http.Transport, url string) (string, error) {
+	t.Helper()
+	// Every request opens a new connection, as after a rotation
+	transport.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestWatcherRotatesClientCertificate(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	ca := newAuthority(t, "test-ca")
+	server := mtlsServer(t, ca)
+	caFile, _ := write(t, dir, "ca", ca.cert, nil)
+	cert, key := ca.client(t, "client-1", time.Now().Add(90*24*time.Hour))
+	certFile, keyFile := write(t, dir, "client", cert, key)
+
+	w, err := New(Config{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.AssertLogged("info", "TLS certificate loaded", "certificate", "ca", "subject", "CN=test-ca")
+	rec.AssertLogged("info", "TLS certificate loaded", "certificate", "client", "subject", "CN=client-1")
+	transport := &http.Transport{TLSClientConfig: w.TLSConfig()}
+	if got, err := get(t, transport, server.URL); err != nil || got != "client-1" {
+		t.Fatalf("first request: %q, %v", got, err)
+	}
+
+	previous := w.Certificate().SerialNumber.String()
+	cert, key = ca.client(t, "client-2", time.Now().Add(90*24*time.Hour))
+	write(t, dir, "client", cert, key)
+	if changed, err := w.Reload(); !changed || err != nil {
+		t.Fatalf("Reload = %v, %v after a rotation", changed, err)
+	}
+	rec.AssertLogged("info", "TLS certificate reloaded", "subject", "CN=client-2", "previous_serial", previous)
+	if got, err := get(t, transport, server.URL); err != nil || got != "client-2" {
+		t.Fatalf("after rotation: %q, %v", got, err)
+	}
+	if changed, _ := w.Reload(); changed {
+		t.Error("Reload reported a change without one")
+	}
+
+	// A half-written rotation keeps the certificate that works
+	writeFile(t, keyFile, []byte("not a key"))
+	if _, err := w.Reload(); err == nil {
+		t.Fatal("Reload accepted a broken key")
+	}
+	rec.AssertLogged("warn", "TLS certificate reload failed, keeping the previous one")
+	if got, err := get(t, transport, server.URL); err != nil || got != "client-2" {
+		t.Fatalf("after a failed reload: %q, %v", got, err)
+	}
+}
+
+func TestWatcherVerifiesAgainstCurrentBundle(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	ca := newAuthority(t, "test-ca")
+	server := mtlsServer(t, ca)
+	cert, key := ca.client(t, "client-1", time.Now().Add(24*time.Hour))
+	certFile, keyFile := write(t, dir, "client", cert, key)
+	other := newAuthority(t, "other-ca")
+	caFile, _ := write(t, dir, "ca", other.cert, nil)
+
+	w, err := New(Config{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &http.Transport{TLSClientConfig: w.TLSConfig()}
+	var unknown x509.UnknownAuthorityError
+	if _, err := get(t, transport, server.URL); !errors.As(err, &unknown) {
+		t.Fatalf("server signed by another CA: err = %v", err)
+	}
+
+	write(t, dir, "ca", ca.cert, nil)
+	w.Reload()
+	if got, err := get(t, transport, server.URL); err != nil || got != "client-1" {
+		t.Fatalf("after the bundle was replaced: %q, %v", got, err)
+	}
+}
+
+func TestWatcherWarnsBeforeExpiry(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	fake := clock.NewFake(time.Now())
+	ca := newAuthority(t, "test-ca")
+	cert, key := ca.client(t, "client-1", fake.Now().Add(48*time.Hour))
+	certFile, keyFile := write(t, dir, "client", cert, key)
+
+	w, err := New(Config{CertFile: certFile, KeyFile: keyFile, Interval: time.Minute, Clock: fake}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.AssertLogged("warn", "TLS certificate expires soon", "subject", "CN=client-1", "expires_in", "48h0m0s")
+	w.Start()
+	defer w.Stop()
+
+	// Checks in the same state do not repeat the warning
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	fake.Advance(time.Minute)
+	if n := rec.Count("warn", "TLS certificate expires soon"); n != 1 {
+		t.Errorf("warned %d times, want once", n)
+	}
+
+	fake.Advance(48 * time.Hour)
+	fake.Advance(time.Minute)
+	rec.AssertLogged("error", "TLS certificate expired", "subject", "CN=client-1")
+
+	// A renewed certificate is picked up by the next check
+	cert, key = ca.client(t, "client-1", fake.Now().Add(90*24*time.Hour))
+	write(t, dir, "client", cert, key)
+	fake.Advance(time.Minute)
+	fake.Advance(time.Minute)
+	rec.AssertLogged("info", "TLS certificate reloaded", "subject", "CN=client-1", "serial", cert.SerialNumber.String())
+}
+
+func TestConfig(t *testing.T) {
+	if _, err := New(Config{}, testlog.New(t).Logger); !errors.Is(err, ErrNoFiles) {
+		t.Errorf("no files: err = %v", err)
+	}
+	if _, err := New(Config{CertFile: "client.crt"}, testlog.New(t).Logger); err == nil {
+		t.Error("certificate without a key accepted")
+	}
+
+	rec := testlog.New(t)
+	t.Setenv("OTLP_CA_FILE", "/etc/otlp/ca.crt")
+	t.Setenv("OTLP_CERT_CHECK_INTERVAL", "10s")
+	t.Setenv("OTLP_CERT_EXPIRY_WARNING", "soon")
+	cfg := FromEnv("OTLP", rec.Logger)
+	if cfg.CAFile != "/etc/otlp/ca.crt" || cfg.Interval != 10*time.Second || cfg.ExpiryWarning != 0 || !cfg.Enabled() {
+		t.Errorf("FromEnv = %+v", cfg)
+	}
+	rec.AssertLogged("warn", "Invalid TLS certificate setting, using default", "env", "OTLP_CERT_EXPIRY_WARNING")
+}
@@ -0,0 +1,44 @@
+package certwatch
+
+import (
+	"os"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Suffixes of the variables FromEnv reads, after its prefix and an underscore
+const (
+	EnvCAFile        = "CA_FILE"
+	EnvCertFile      = "CERT_FILE"
+	EnvKeyFile       = "KEY_FILE"
+	EnvServerName    = "SERVER_NAME"
+	EnvInterval      = "CERT_CHECK_INTERVAL"
+	EnvExpiryWarning = "CERT_EXPIRY_WARNING"
+)
+
+// FromEnv reads a Config from prefix_CA_FILE, prefix_CERT_FILE, prefix_KEY_FILE,
+// prefix_SERVER_NAME, prefix_CERT_CHECK_INTERVAL and prefix_CERT_EXPIRY_WARNING. The
+// result is not Enabled when no file is set:
+//
+//	TLS: certwatch.FromEnv("OTLP", logger),
+func FromEnv(prefix string, logger core.Logger) Config {
+	cfg := Config{
+		CAFile:     os.Getenv(prefix + "_" + EnvCAFile),
+		CertFile:   os.Getenv(prefix + "_" + EnvCertFile),
+		KeyFile:    os.Getenv(prefix + "_" + EnvKeyFile),
+		ServerName: os.Getenv(prefix + "_" + EnvServerName),
+	}
+	for suffix, field := range map[string]*time.Duration{EnvInterval: &cfg.Interval, EnvExpiryWarning: &cfg.ExpiryWarning} {
+		env := prefix + "_" + suffix
+		if value := os.Getenv(env); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				logger.Warnw("Invalid TLS certificate setting, using default", "env", env, "value", value)
+				continue
+			}
+			*field = d
+		}
+	}
+	return cfg
+}
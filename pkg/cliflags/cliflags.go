@@ -0,0 +1,165 @@
+// Package cliflags gives every demo the same command-line flags:
+//
+//	--port           listen port, the same as PORT
+//	--config         configuration file, the same as CONFIG_PATH
+//	--log-level      debug, info, warn, error or fatal, the same as LOG_LEVEL
+//	--log-format     json or console, the same as LOG_FORMAT
+//	--otlp-endpoint  OTLP collector address, the same as OTLP_ENDPOINT
+//	--seed           seed for simulated data, the same as RANDOM_SEED (see pkg/rng)
+//	--quiet          drop banners and hints, the same as CONSOLE_OUTPUT=off (see pkg/console)
+//
+// A flag that is given sets its environment variable, so it wins over the environment
+// everywhere a demo reads that variable, including helpers such as pkg/otelsetup that the
+// demo passes it to. Apply then overrides the level, format and OTLP endpoint that the demo
+// resolved for its logger, which covers demos that hardcode them. Demos call Parse first
+// thing in main, after defining any flags of their own:
+//
+//	go run ./webhook-demo --port 9090 --log-level debug
+package cliflags
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/option"
+)
+
+// Environment variables the flags set
+const (
+	EnvPort         = "PORT"
+	EnvConfig       = "CONFIG_PATH"
+	EnvLogLevel     = "LOG_LEVEL"
+	EnvLogFormat    = "LOG_FORMAT"
+	EnvOTLPEndpoint = "OTLP_ENDPOINT"
+	EnvSeed         = rng.EnvSeed
+	EnvConsole      = console.EnvOutput
+)
+
+// Flags are the values given on the command line; empty means not given
+type Flags struct {
+	Port         string
+	Config       string
+	LogLevel     string
+	LogFormat    string
+	OTLPEndpoint string
+	Seed         string
+	Quiet        bool
+}
+
+// parsed is what Parse read, for Apply, PortOr and ConfigOr
+var parsed Flags
+
+// Register defines the flags on fs and returns where their values go
+func Register(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.Func("port", "listen port (overrides "+EnvPort+")", func(value string) error {
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("not a port number: %q", value)
+		}
+		f.Port = value
+		return nil
+	})
+	fs.StringVar(&f.Config, "config", "", "configuration file (overrides "+EnvConfig+")")
+	fs.Func("log-level", "debug, info, warn, error or fatal (overrides "+EnvLogLevel+")",
+		oneOf(&f.LogLevel, "debug", "info", "warn", "error", "fatal"))
+	fs.Func("log-format", "json or console (overrides "+EnvLogFormat+")",
+		oneOf(&f.LogFormat, "json", "console"))
+	fs.StringVar(&f.OTLPEndpoint, "otlp-endpoint", "", "OTLP collector endpoint (overrides "+EnvOTLPEndpoint+")")
+	fs.Func("seed", "seed for simulated data, to repeat a run (overrides "+EnvSeed+")", func(value string) error {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("not an integer: %q", value)
+		}
+		f.Seed = value
+		return nil
+	})
+	fs.BoolVar(&f.Quiet, "quiet", false, "drop banners and hints so stdout carries only JSON logs (same as "+EnvConsole+"=off)")
+	return f
+}
+
+// oneOf returns a flag.Func setter that accepts only the given values
+func oneOf(dst *string, values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				*dst = value
+				return nil
+			}
+		}
+		return fmt.Errorf("want one of %v", values)
+	}
+}
+
+// Export sets the environment variable of every flag that was given
+func (f *Flags) Export() {
+	for env, value := range map[string]string{
+		EnvPort:         f.Port,
+		EnvConfig:       f.Config,
+		EnvLogLevel:     f.LogLevel,
+		EnvLogFormat:    f.LogFormat,
+		EnvOTLPEndpoint: f.OTLPEndpoint,
+		EnvSeed:         f.Seed,
+	} {
+		if value != "" {
+			os.Setenv(env, value)
+		}
+	}
+	if f.Quiet {
+		os.Setenv(EnvConsole, "off")
+	}
+}
+
+// Parse registers the flags on flag.CommandLine, parses the command line and exports the
+// flags that were given; --quiet also silences pkg/console. An invalid value prints the
+// usage and exits with status 2.
+func Parse() {
+	f := Register(flag.CommandLine)
+	flag.Parse()
+	f.Export()
+	if f.Quiet {
+		console.SetQuiet(true)
+	}
+	parsed = *f
+}
+
+// Apply overrides opt's level, format and OTLP endpoint with the flags that were given
+func Apply(opt *option.LogOption) {
+	parsed.Apply(opt)
+}
+
+// Apply overrides opt's level, format and OTLP endpoint with the flags in f that are set
+func (f *Flags) Apply(opt *option.LogOption) {
+	if f.LogLevel != "" {
+		opt.Level = f.LogLevel
+	}
+	if f.LogFormat != "" {
+		opt.Format = f.LogFormat
+	}
+	if f.OTLPEndpoint != "" {
+		opt.OTLPEndpoint = f.OTLPEndpoint
+		if opt.OTLP != nil {
+			opt.OTLP.Endpoint = f.OTLPEndpoint
+		}
+	}
+}
+
+// PortOr returns --port if it was given, otherwise port; for demos whose port variable is
+// not PORT, such as API_PORT in microservices-demo
+func PortOr(port string) string {
+	if parsed.Port != "" {
+		return parsed.Port
+	}
+	return port
+}
+
+// ConfigOr returns --config if it was given, otherwise path; for demos whose configuration
+// file variable is not CONFIG_PATH, such as FLAGS_FILE in featureflags-demo
+func ConfigOr(path string) string {
+	if parsed.Config != "" {
+		return parsed.Config
+	}
+	return path
+}
@@ -0,0 +1,67 @@
+package cliflags
+
+import (
+	"flag"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kart-io/logger/option"
+)
+
+func parse(t *testing.T, args ...string) (*Flags, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f := Register(fs)
+	return f, fs.Parse(args)
+}
+
+func TestRegister(t *testing.T) {
+	f, err := parse(t, "--port", "9090", "--log-level=debug", "--log-format", "console", "--otlp-endpoint", "collector:4317", "--config", "prod.yaml", "--seed", "42", "--quiet", "extra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Flags{Port: "9090", Config: "prod.yaml", LogLevel: "debug", LogFormat: "console", OTLPEndpoint: "collector:4317", Seed: "42", Quiet: true}
+	if *f != want {
+		t.Errorf("flags = %+v, want %+v", *f, want)
+	}
+
+	for _, args := range [][]string{{"--port", "http"}, {"--port", "70000"}, {"--log-level", "verbose"}, {"--log-format", "text"}, {"--seed", "0x2a"}} {
+		if _, err := parse(t, args...); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Setenv(EnvPort, "8090")
+	t.Setenv(EnvLogLevel, "info")
+	t.Setenv(EnvConsole, "stdout")
+	f, _ := parse(t, "--port", "9090", "--quiet")
+	f.Export()
+	if got := os.Getenv(EnvPort); got != "9090" {
+		t.Errorf("%s = %q, want the flag", EnvPort, got)
+	}
+	if got := os.Getenv(EnvLogLevel); got != "info" {
+		t.Errorf("%s = %q, want it left alone", EnvLogLevel, got)
+	}
+	if got := os.Getenv(EnvConsole); got != "off" {
+		t.Errorf("%s = %q, want off for --quiet", EnvConsole, got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	opt := &option.LogOption{Engine: "zap", Level: "info", Format: "json", OTLP: &option.OTLPOption{Endpoint: "localhost:4317"}}
+	f, _ := parse(t, "--log-level", "warn", "--otlp-endpoint", "collector:4317")
+	f.Apply(opt)
+	if opt.Level != "warn" || opt.Format != "json" || opt.OTLPEndpoint != "collector:4317" || opt.OTLP.Endpoint != "collector:4317" {
+		t.Errorf("option = %+v (otlp %+v)", opt, opt.OTLP)
+	}
+
+	defer func(f Flags) { parsed = f }(parsed)
+	parsed = Flags{Config: "prod.yaml"}
+	if PortOr("8102") != "8102" || ConfigOr("flags.yaml") != "prod.yaml" {
+		t.Errorf("PortOr = %q, ConfigOr = %q", PortOr("8102"), ConfigOr("flags.yaml"))
+	}
+}
@@ -0,0 +1,195 @@
+// Package clock lets code read the time through an interface so tests can control it.
+//
+// Anything that puts the current time into a business field, names a file after it or
+// schedules work on a ticker takes a Clock. Production code passes Real; tests pass a
+// Fake, set it to a fixed instant and move it forward with Advance, so timestamps,
+// durations, file names and the order of scheduled work come out the same on every run
+// and log output can be compared against a golden file.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the subset of the time package that demos depend on
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers the time at intervals, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real reads the system clock
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent use.
+//
+// Ticks are handed over synchronously: Advance does not return until each due tick has
+// been received or the ticker stopped. A loop that reads a ticker has therefore finished
+// handling one tick by the time the next Advance can deliver another, which keeps the
+// order of its log entries fixed. After channels are buffered and never block Advance.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	when   time.Time
+	period time.Duration
+	ch     chan time.Time
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewFake returns a Fake reading start
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel that receives the fake time once Advance has moved it d ahead
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{when: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- f.now
+		return w.ch
+	}
+	f.add(w)
+	return w.ch
+}
+
+// NewTicker returns a ticker driven by Advance
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{when: f.now.Add(d), period: d, ch: make(chan time.Time), stop: make(chan struct{})}
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+// Advance moves the fake time forward by d, firing every timer and tick that falls due
+// on the way in time order
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	f.mu.Unlock()
+
+	for {
+		f.mu.Lock()
+		if len(f.waiters) == 0 || f.waiters[0].when.After(target) {
+			f.now = target
+			f.mu.Unlock()
+			return
+		}
+		w := f.waiters[0]
+		f.now = w.when
+		now := f.now
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+			f.sort()
+		} else {
+			f.remove(w)
+		}
+		f.mu.Unlock()
+
+		if w.period == 0 {
+			w.ch <- now
+			continue
+		}
+		select {
+		case w.ch <- now:
+		case <-w.stop:
+		}
+	}
+}
+
+// BlockUntil waits until at least n timers and tickers are pending. Call it before
+// Advance when the code under test creates its ticker on another goroutine.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+func (f *Fake) add(w *waiter) {
+	f.waiters = append(f.waiters, w)
+	f.sort()
+	f.changed.Broadcast()
+}
+
+func (f *Fake) remove(w *waiter) {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return
+		}
+	}
+}
+
+// sort orders waiters by due time, keeping creation order for ties
+func (f *Fake) sort() {
+	sort.SliceStable(f.waiters, func(i, j int) bool { return f.waiters[i].when.Before(f.waiters[j].when) })
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTicker) Stop() {
+	t.w.once.Do(func() { close(t.w.stop) })
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
@@ -0,0 +1,67 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func TestFakeAfter(t *testing.T) {
+	f := NewFake(start)
+	ch := f.After(2 * time.Second)
+
+	f.Advance(time.Second)
+	select {
+	case <-ch:
+		t.Fatal("After fired early")
+	default:
+	}
+	f.Advance(time.Second)
+	if got := <-ch; !got.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("After delivered %v", got)
+	}
+	if got := f.Since(start); got != 2*time.Second {
+		t.Errorf("Since() = %v", got)
+	}
+}
+
+func TestFakeTickerHandsOverEveryTick(t *testing.T) {
+	f := NewFake(start)
+	ticks := make(chan time.Time, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := f.NewTicker(time.Second)
+		defer ticker.Stop()
+		for i := 0; i < 3; i++ {
+			ticks <- <-ticker.C()
+		}
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(3500 * time.Millisecond)
+	<-done
+	for i := 1; i <= 3; i++ {
+		if got := <-ticks; !got.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("tick %d = %v", i, got)
+		}
+	}
+	if !f.Now().Equal(start.Add(3500 * time.Millisecond)) {
+		t.Errorf("Now() = %v", f.Now())
+	}
+
+	// The ticker is stopped, so nothing is left to block on
+	f.Advance(time.Hour)
+}
+
+func TestRealClock(t *testing.T) {
+	before := time.Now()
+	if now := Real.Now(); now.Before(before) {
+		t.Errorf("Real.Now() = %v, before %v", now, before)
+	}
+	ticker := Real.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	<-ticker.C()
+	<-Real.After(time.Millisecond)
+}
@@ -0,0 +1,151 @@
+// Package cloudmeta detects which cloud a process runs on and where, for InitialFields.
+//
+// Detect asks the AWS, GCP and Azure instance metadata services in parallel under a short
+// timeout and keeps the first answer, so a service logs its real region, availability zone
+// and instance instead of values someone hardcoded into the deployment. The result is
+// cached: metadata does not change for the life of a process, and off-cloud the probes
+// would otherwise cost a timeout on every call.
+package cloudmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Providers, as reported in the cloud.provider field
+const (
+	AWS   = "aws"
+	GCP   = "gcp"
+	Azure = "azure"
+)
+
+// DefaultTimeout bounds the whole detection; metadata services answer in milliseconds,
+// so a process that waits longer is not on a cloud
+const DefaultTimeout = time.Second
+
+// ErrNotDetected is returned when no metadata service answered and the environment names
+// no region
+var ErrNotDetected = errors.New("cloudmeta: no cloud metadata service found")
+
+// Metadata describes the instance the process runs on
+type Metadata struct {
+	Provider         string `json:"provider"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availability_zone,omitempty"`
+	InstanceID       string `json:"instance_id,omitempty"`
+	InstanceType     string `json:"instance_type,omitempty"`
+}
+
+// Fields returns the non-empty values under OpenTelemetry resource keys, ready to merge
+// into InitialFields
+func (m Metadata) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"cloud.provider":          m.Provider,
+		"cloud.region":            m.Region,
+		"cloud.availability_zone": m.AvailabilityZone,
+		"host.id":                 m.InstanceID,
+		"host.type":               m.InstanceType,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// Detector queries metadata services and caches the outcome. The zero value uses the
+// well-known endpoints and DefaultTimeout.
+type Detector struct {
+	Client  *http.Client
+	Timeout time.Duration
+	// AWSEndpoint, GCPEndpoint and AzureEndpoint override the metadata service base URLs
+	AWSEndpoint   string
+	GCPEndpoint   string
+	AzureEndpoint string
+
+	once sync.Once
+	meta Metadata
+	err  error
+}
+
+var defaultDetector Detector
+
+// Detect returns the metadata of the current instance, detected once per process
+func Detect(ctx context.Context) (Metadata, error) {
+	return defaultDetector.Detect(ctx)
+}
+
+// Fields returns Detect's result as InitialFields, or an empty map off-cloud
+func Fields(ctx context.Context) map[string]interface{} {
+	meta, _ := Detect(ctx)
+	return meta.Fields()
+}
+
+// Detect probes all providers on the first call and returns the cached result after
+func (d *Detector) Detect(ctx context.Context) (Metadata, error) {
+	d.once.Do(func() {
+		d.meta, d.err = d.detect(ctx)
+	})
+	return d.meta, d.err
+}
+
+type probe func(ctx context.Context, client *http.Client, base string) (Metadata, error)
+
+func (d *Detector) detect(ctx context.Context) (Metadata, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	probes := []struct {
+		fn   probe
+		base string
+	}{
+		{probeAWS, orDefault(d.AWSEndpoint, "http://169.254.169.254")},
+		{probeGCP, orDefault(d.GCPEndpoint, "http://metadata.google.internal")},
+		{probeAzure, orDefault(d.AzureEndpoint, "http://169.254.169.254")},
+	}
+
+	results := make(chan Metadata, len(probes))
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if meta, err := p.fn(ctx, client, p.base); err == nil {
+				results <- meta
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	if meta, ok := <-results; ok {
+		return meta, nil
+	}
+	// Lambda and Fargate have no instance metadata but name the region in the environment
+	if region := orDefault(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")); region != "" {
+		return Metadata{Provider: AWS, Region: region}, nil
+	}
+	return Metadata{}, ErrNotDetected
+}
+
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
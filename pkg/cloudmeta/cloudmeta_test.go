@@ -0,0 +1,131 @@
+package cloudmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// notFound stands in for a provider the test is not on
+func notFound(t *testing.T) string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestDetectAWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			w.Write([]byte("session-token"))
+		case "/latest/dynamic/instance-identity/document":
+			if r.Header.Get("X-aws-ec2-metadata-token") != "session-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"region":"eu-west-1","availabilityZone":"eu-west-1b","instanceId":"i-0abc","instanceType":"m6i.large"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	d := &Detector{AWSEndpoint: srv.URL, GCPEndpoint: notFound(t), AzureEndpoint: notFound(t)}
+	meta, err := d.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Provider: AWS, Region: "eu-west-1", AvailabilityZone: "eu-west-1b", InstanceID: "i-0abc", InstanceType: "m6i.large"}
+	if meta != want {
+		t.Errorf("Detect() = %+v, want %+v", meta, want)
+	}
+	if fields := meta.Fields(); fields["cloud.region"] != "eu-west-1" || fields["host.type"] != "m6i.large" {
+		t.Errorf("Fields() = %v", fields)
+	}
+}
+
+func TestDetectGCP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"id":4520031799277581759,"zone":"projects/123/zones/us-central1-a","machineType":"projects/123/machineTypes/e2-medium"}`))
+	}))
+	defer srv.Close()
+
+	d := &Detector{AWSEndpoint: notFound(t), GCPEndpoint: srv.URL, AzureEndpoint: notFound(t)}
+	meta, err := d.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Provider: GCP, Region: "us-central1", AvailabilityZone: "us-central1-a", InstanceID: "4520031799277581759", InstanceType: "e2-medium"}
+	if meta != want {
+		t.Errorf("Detect() = %+v, want %+v", meta, want)
+	}
+}
+
+func TestDetectAzure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Path != "/metadata/instance/compute" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"location":"eastus","zone":"2","vmId":"02aab8a4-74ef","vmSize":"Standard_D2s_v3"}`))
+	}))
+	defer srv.Close()
+
+	d := &Detector{AWSEndpoint: notFound(t), GCPEndpoint: notFound(t), AzureEndpoint: srv.URL}
+	meta, err := d.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Provider: Azure, Region: "eastus", AvailabilityZone: "eastus-2", InstanceID: "02aab8a4-74ef", InstanceType: "Standard_D2s_v3"}
+	if meta != want {
+		t.Errorf("Detect() = %+v, want %+v", meta, want)
+	}
+}
+
+func TestDetectOffCloud(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	var calls atomic.Int32
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer hang.Close()
+
+	d := &Detector{Timeout: 50 * time.Millisecond, AWSEndpoint: hang.URL, GCPEndpoint: hang.URL, AzureEndpoint: hang.URL}
+	if _, err := d.Detect(context.Background()); !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("Detect() err = %v, want ErrNotDetected", err)
+	}
+	probed := calls.Load()
+	if _, err := d.Detect(context.Background()); !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("cached Detect() err = %v", err)
+	}
+	if calls.Load() != probed {
+		t.Error("second Detect() probed again instead of using the cached result")
+	}
+	if len((Metadata{}).Fields()) != 0 {
+		t.Error("empty Metadata produced fields")
+	}
+}
+
+func TestDetectRegionFromEnv(t *testing.T) {
+	t.Setenv("AWS_REGION", "ap-south-1")
+
+	d := &Detector{AWSEndpoint: notFound(t), GCPEndpoint: notFound(t), AzureEndpoint: notFound(t)}
+	meta, err := d.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != (Metadata{Provider: AWS, Region: "ap-south-1"}) {
+		t.Errorf("Detect() = %+v", meta)
+	}
+}
@@ -0,0 +1,138 @@
+package cloudmeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// probeAWS reads the EC2 instance identity document, using an IMDSv2 session token when
+// the instance hands one out
+func probeAWS(ctx context.Context, client *http.Client, base string) (Metadata, error) {
+	header := http.Header{}
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, base+"/latest/api/token", nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if token, err := fetch(client, tokenReq); err == nil {
+		header.Set("X-aws-ec2-metadata-token", string(token))
+	}
+
+	var doc struct {
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+	}
+	if err := getJSON(ctx, client, base+"/latest/dynamic/instance-identity/document", header, &doc); err != nil {
+		return Metadata{}, err
+	}
+	if doc.Region == "" {
+		return Metadata{}, errors.New("cloudmeta: aws identity document has no region")
+	}
+	return Metadata{
+		Provider:         AWS,
+		Region:           doc.Region,
+		AvailabilityZone: doc.AvailabilityZone,
+		InstanceID:       doc.InstanceID,
+		InstanceType:     doc.InstanceType,
+	}, nil
+}
+
+// probeGCP reads the instance document; zone and machine type come back as resource paths
+// such as projects/123/zones/us-central1-a
+func probeGCP(ctx context.Context, client *http.Client, base string) (Metadata, error) {
+	var doc struct {
+		ID          json.Number `json:"id"`
+		Zone        string      `json:"zone"`
+		MachineType string      `json:"machineType"`
+	}
+	header := http.Header{"Metadata-Flavor": {"Google"}}
+	if err := getJSON(ctx, client, base+"/computeMetadata/v1/instance/?recursive=true", header, &doc); err != nil {
+		return Metadata{}, err
+	}
+	zone := path.Base(doc.Zone)
+	region, _, ok := cutLast(zone, "-")
+	if doc.Zone == "" || !ok {
+		return Metadata{}, fmt.Errorf("cloudmeta: gcp zone %q has no region", doc.Zone)
+	}
+	return Metadata{
+		Provider:         GCP,
+		Region:           region,
+		AvailabilityZone: zone,
+		InstanceID:       doc.ID.String(),
+		InstanceType:     path.Base(doc.MachineType),
+	}, nil
+}
+
+// probeAzure reads the compute section of the Instance Metadata Service. Azure numbers
+// zones per region, so zone 1 in eastus is reported as eastus-1; VMs outside a zone
+// have none.
+func probeAzure(ctx context.Context, client *http.Client, base string) (Metadata, error) {
+	var doc struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+	}
+	header := http.Header{"Metadata": {"true"}}
+	if err := getJSON(ctx, client, base+"/metadata/instance/compute?api-version=2021-02-01", header, &doc); err != nil {
+		return Metadata{}, err
+	}
+	if doc.Location == "" {
+		return Metadata{}, errors.New("cloudmeta: azure compute metadata has no location")
+	}
+	zone := doc.Zone
+	if zone != "" {
+		zone = doc.Location + "-" + zone
+	}
+	return Metadata{
+		Provider:         Azure,
+		Region:           doc.Location,
+		AvailabilityZone: zone,
+		InstanceID:       doc.VMID,
+		InstanceType:     doc.VMSize,
+	}, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	body, err := fetch(client, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func fetch(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloudmeta: %s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	// Metadata documents are a few KB; the limit guards against something else on the address
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+}
+
+// cutLast splits s around the last sep, turning us-central1-a into us-central1
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,94 @@
+// Package console is where the demos print their human-oriented text: banners, the curl
+// commands to try and end-of-run summaries.
+//
+// That text used to go to stdout with fmt.Println, between the JSON log lines, so piping a
+// demo into jq, Vector or cmd/logschema meant filtering it out first. By default it still
+// goes to stdout. Running a demo with --quiet (a pkg/cliflags flag), or with
+// CONSOLE_OUTPUT=off, drops it and leaves stdout as pure NDJSON; CONSOLE_OUTPUT=stderr
+// keeps it visible in the terminal while stdout is piped elsewhere:
+//
+//	go run ./gin-demo --quiet | go run ./cmd/logschema
+//	CONSOLE_OUTPUT=stderr go run ./secretscan-demo | jq .message
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// EnvOutput selects where the text goes: stdout (the default), stderr or off
+const EnvOutput = "CONSOLE_OUTPUT"
+
+var out = fromEnv()
+
+// fromEnv returns the writer CONSOLE_OUTPUT selects
+func fromEnv() io.Writer {
+	w, err := writerFor(os.Getenv(EnvOutput))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "console: %v, using stdout\n", err)
+	}
+	return w
+}
+
+// SetQuiet drops the text when quiet is true, and otherwise goes back to the writer
+// CONSOLE_OUTPUT selects. cliflags.Parse calls it for --quiet; call it before anything
+// is printed.
+func SetQuiet(quiet bool) {
+	if quiet {
+		out = io.Discard
+		return
+	}
+	out = fromEnv()
+}
+
+// DefaultStderr moves the text to stderr unless CONSOLE_OUTPUT or --quiet chose where it
+// goes, for demos whose stdout is data of its own, such as forwarded records. Call it
+// after cliflags.Parse.
+func DefaultStderr() {
+	if out == os.Stdout && os.Getenv(EnvOutput) == "" {
+		out = os.Stderr
+	}
+}
+
+// writerFor maps a CONSOLE_OUTPUT value to its writer
+func writerFor(mode string) (io.Writer, error) {
+	switch mode {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	case "off", "none", "quiet":
+		return io.Discard, nil
+	}
+	return os.Stdout, fmt.Errorf("unknown %s %q (want stdout, stderr or off)", EnvOutput, mode)
+}
+
+// Writer returns where the text goes, for libraries that print on their own, such as
+// gin's route table and request lines:
+//
+//	gin.DefaultWriter = console.Writer()
+func Writer() io.Writer {
+	return out
+}
+
+// Structured reports whether stdout is kept for JSON logs, with the text on stderr or
+// dropped. Loggers that write the console format to stdout switch to JSON then.
+func Structured() bool {
+	return out != os.Stdout
+}
+
+// Print is fmt.Print to Writer
+func Print(a ...interface{}) {
+	fmt.Fprint(out, a...)
+}
+
+// Printf is fmt.Printf to Writer
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(out, format, a...)
+}
+
+// Println is fmt.Println to Writer
+func Println(a ...interface{}) {
+	fmt.Fprintln(out, a...)
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestSetQuiet(t *testing.T) {
+	defer func(w io.Writer) { out = w }(out)
+	t.Setenv(EnvOutput, "stderr")
+	SetQuiet(true)
+	if Writer() != io.Discard {
+		t.Errorf("quiet: writer = %v", Writer())
+	}
+	SetQuiet(false)
+	if Writer() != os.Stderr {
+		t.Errorf("not quiet: writer = %v, want %s", Writer(), EnvOutput)
+	}
+}
+
+func TestDefaultStderr(t *testing.T) {
+	defer func(w io.Writer) { out = w }(out)
+	t.Setenv(EnvOutput, "")
+	out = os.Stdout
+	DefaultStderr()
+	if Writer() != os.Stderr {
+		t.Errorf("default: writer = %v, want stderr", Writer())
+	}
+	SetQuiet(true)
+	DefaultStderr()
+	if Writer() != io.Discard {
+		t.Errorf("quiet: writer = %v", Writer())
+	}
+	t.Setenv(EnvOutput, "stdout")
+	SetQuiet(false)
+	DefaultStderr()
+	if Writer() != os.Stdout {
+		t.Errorf("%s=stdout: writer = %v", EnvOutput, Writer())
+	}
+}
+
+func TestWriterFor(t *testing.T) {
+	for mode, want := range map[string]io.Writer{"": os.Stdout, "stdout": os.Stdout, "stderr": os.Stderr, "off": io.Discard} {
+		if w, err := writerFor(mode); err != nil || w != want {
+			t.Errorf("%q: writer = %v, err = %v", mode, w, err)
+		}
+	}
+	if w, err := writerFor("syslog"); err == nil || w != os.Stdout {
+		t.Errorf("unknown mode: writer = %v, err = %v", w, err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	defer func(w io.Writer) { out = w }(out)
+	var buf bytes.Buffer
+	out = &buf
+	Println("=== Demo ===")
+	Printf("port %s\n", "8080")
+	Print("done")
+	if got := buf.String(); got != "=== Demo ===\nport 8080\ndone" {
+		t.Errorf("output = %q", got)
+	}
+
+	if !Structured() {
+		t.Error("Structured() = false with output off stdout")
+	}
+	out = os.Stdout
+	if Structured() {
+		t.Error("Structured() = true with output on stdout")
+	}
+}
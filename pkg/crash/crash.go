@@ -0,0 +1,144 @@
+// Package crash ends a process that cannot go on with one structured entry instead of a
+// raw panic or a Fatalw that exits before anything is cleaned up.
+//
+// Fatal logs the entry, runs the exit hooks registered with OnExit (newest first: flush a
+// file logger, shut down the OTLP providers so the entry itself is exported, remove a PID
+// file), flushes the logger and exits with the code set by SetExitCode. Recover, deferred
+// at the top of main or a goroutine, does the same for a panic, with its value and stack:
+//
+//	serviceLogger, err := logger.New(logOption)
+//	if err != nil {
+//		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+//	}
+//	defer serviceLogger.Flush()
+//	defer crash.Recover(serviceLogger)
+//	crash.OnExit("otel", func() error { return shutdown(context.Background()) })
+//
+// The entry is written at error level with exit_code set, not with Fatalw: the logger
+// exits with status 1 as soon as a Fatalw entry is written, before any hook could run.
+// Hooks only run on these exits; a normal return still relies on the usual defers.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// DefaultExitCode is the exit code until SetExitCode is called, the one the Go runtime
+// uses for an unrecovered panic
+const DefaultExitCode = 2
+
+// PanicMessage is the message Recover logs
+const PanicMessage = "Unhandled panic"
+
+type hook struct {
+	name string
+	fn   func() error
+}
+
+var (
+	mu       sync.Mutex
+	hooks    []hook
+	exitCode = DefaultExitCode
+
+	// exiting is held from the first Fatal on, so a second crash in another goroutine
+	// waits for the process to end instead of running the hooks again
+	exiting sync.Mutex
+
+	// Replaced in tests
+	exit             = os.Exit
+	stderr io.Writer = os.Stderr
+)
+
+// OnExit registers fn to run before Fatal or Recover exits the process. Hooks run newest
+// first; a failing hook is logged and the rest still run.
+func OnExit(name string, fn func() error) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook{name: name, fn: fn})
+}
+
+// SetExitCode sets the code Fatal and Recover exit with
+func SetExitCode(code int) {
+	mu.Lock()
+	defer mu.Unlock()
+	exitCode = code
+}
+
+// Fatal logs msg with keysAndValues and exit_code, runs the exit hooks, flushes logger and
+// exits. With a nil logger, such as when creating the logger is what failed, the entry is
+// written to stderr as one JSON line with level fatal.
+func Fatal(logger core.Logger, msg string, keysAndValues ...interface{}) {
+	exiting.Lock()
+	defer exiting.Unlock()
+
+	mu.Lock()
+	code := exitCode
+	pending := make([]hook, len(hooks))
+	copy(pending, hooks)
+	mu.Unlock()
+
+	keysAndValues = append(keysAndValues, "exit_code", code)
+	if logger == nil {
+		writeStderr(msg, keysAndValues)
+	} else {
+		logger = logger.WithCallerSkip(1)
+		logger.Errorw(msg, keysAndValues...)
+	}
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		if err := pending[i].fn(); err != nil {
+			if logger == nil {
+				writeStderr("Exit hook failed", []interface{}{"hook", pending[i].name, "error", err.Error()})
+			} else {
+				logger.Warnw("Exit hook failed", "hook", pending[i].name, "error", err.Error())
+			}
+		}
+	}
+	if logger != nil {
+		logger.Flush()
+	}
+	exit(code)
+}
+
+// Recover turns a panic into Fatal with the panic value and stack added to keysAndValues.
+// It must be deferred directly: defer crash.Recover(logger).
+func Recover(logger core.Logger, keysAndValues ...interface{}) {
+	if r := recover(); r != nil {
+		Fatal(logger, PanicMessage, append(keysAndValues, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))...)
+	}
+}
+
+// Go runs fn in a new goroutine under Recover, for goroutines a panic should not take
+// down without a log entry
+func Go(logger core.Logger, fn func(), keysAndValues ...interface{}) {
+	go func() {
+		defer Recover(logger, keysAndValues...)
+		fn()
+	}()
+}
+
+// writeStderr writes one JSON entry for a process that has no logger
+func writeStderr(msg string, keysAndValues []interface{}) {
+	entry := map[string]interface{}{
+		"level":     "fatal",
+		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+		"message":   msg,
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		entry[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", msg, keysAndValues)
+		return
+	}
+	fmt.Fprintf(stderr, "%s\n", line)
+}
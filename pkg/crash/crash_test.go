@@ -0,0 +1,84 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+// stubExit resets the package state and records the exit code instead of exiting
+func stubExit(t *testing.T) *int {
+	t.Helper()
+	code := -1
+	exit = func(c int) { code = c }
+	hooks = nil
+	exitCode = DefaultExitCode
+	t.Cleanup(func() {
+		exit = nil
+		hooks = nil
+		exitCode = DefaultExitCode
+	})
+	return &code
+}
+
+func TestFatalRunsHooksNewestFirst(t *testing.T) {
+	code := stubExit(t)
+	rec := testlog.New(t)
+	var order []string
+	OnExit("otel", func() error { order = append(order, "otel"); return nil })
+	OnExit("file", func() error { order = append(order, "file"); return errors.New("disk full") })
+	SetExitCode(3)
+
+	Fatal(rec.Logger, "Config invalid", "error", "port missing")
+
+	if *code != 3 {
+		t.Fatalf("exit code = %d, want 3", *code)
+	}
+	if strings.Join(order, ",") != "file,otel" {
+		t.Fatalf("hooks ran as %v, want file then otel", order)
+	}
+	rec.AssertLogged("error", "Config invalid", "error", "port missing", "exit_code", 3)
+	rec.AssertLogged("warn", "Exit hook failed", "hook", "file", "error", "disk full")
+}
+
+func TestRecover(t *testing.T) {
+	code := stubExit(t)
+	rec := testlog.New(t)
+
+	func() {
+		defer Recover(rec.Logger, "component", "worker")
+		panic("index out of range")
+	}()
+
+	if *code != DefaultExitCode {
+		t.Fatalf("exit code = %d, want %d", *code, DefaultExitCode)
+	}
+	entry := rec.AssertLogged("error", PanicMessage, "component", "worker", "panic", "index out of range")
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "TestRecover") {
+		t.Fatalf("stack does not name the panicking function: %q", stack)
+	}
+}
+
+func TestFatalWithoutLogger(t *testing.T) {
+	code := stubExit(t)
+	var buf bytes.Buffer
+	stderr = &buf
+	t.Cleanup(func() { stderr = nil })
+
+	Fatal(nil, "Failed to create logger", "error", "unknown engine")
+
+	if *code != DefaultExitCode {
+		t.Fatalf("exit code = %d, want %d", *code, DefaultExitCode)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("stderr is not one JSON entry: %v: %q", err, buf.String())
+	}
+	if entry["level"] != "fatal" || entry["message"] != "Failed to create logger" || entry["error"] != "unknown engine" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+}
@@ -0,0 +1,246 @@
+// Package dataclass filters log fields by their data classification, so what a service may
+// log in production follows from the data_classification InitialField it already carries
+// instead of from each handler remembering what not to log.
+//
+// A policy tags field names with a classification level and says, for every level a
+// service can be classified at, what happens to fields of each level: they are kept,
+// their value is masked, or they are dropped. The service's row is chosen by its
+// data_classification InitialField, and the policy is only enforced in the environments
+// it names, so development logs keep every field:
+//
+//	environments: [production]    # matched against the environment InitialField
+//	fields:
+//	  email: confidential
+//	  client_ip: confidential
+//	  card_number: restricted
+//	classifications:              # keyed by the service's data_classification
+//	  internal:
+//	    confidential: drop        # an internal service has no business logging these
+//	    restricted: drop
+//	  confidential:
+//	    confidential: mask
+//	    restricted: drop
+//
+// Untagged fields, and levels a row leaves out, are kept. Field names match exactly, at
+// the top level of an entry and inside map values.
+package dataclass
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Classification levels, from least to most sensitive
+const (
+	Public       = "public"
+	Internal     = "internal"
+	Confidential = "confidential"
+	Restricted   = "restricted"
+)
+
+// Levels are the classification levels a policy can use
+var Levels = []string{Public, Internal, Confidential, Restricted}
+
+// Actions for the fields of a level
+const (
+	Keep = "keep"
+	Mask = "mask"
+	Drop = "drop"
+)
+
+// DefaultMask replaces masked values
+const DefaultMask = "[CLASSIFIED]"
+
+// DefaultEnvironments are where a policy without environments is enforced
+var DefaultEnvironments = []string{"production"}
+
+// InitialFields read by New
+const (
+	ClassificationField = "data_classification"
+	EnvironmentField    = "environment"
+)
+
+// Config is the YAML policy
+type Config struct {
+	// Environments enforce the policy, DefaultEnvironments when empty
+	Environments []string `yaml:"environments"`
+	// Fields maps a field name to its level
+	Fields map[string]string `yaml:"fields"`
+	// Classifications maps a service level to the action for each field level
+	Classifications map[string]map[string]string `yaml:"classifications"`
+	// Mask replaces masked values, DefaultMask when empty
+	Mask string `yaml:"mask"`
+}
+
+// Load reads and validates a policy file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read data classification policy: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse data classification policy: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports the first unknown level or action, naming the YAML key
+func (cfg Config) Validate() error {
+	for field, level := range cfg.Fields {
+		if !knownLevel(level) {
+			return fmt.Errorf("fields.%s: unknown level %q, want one of %v", field, level, Levels)
+		}
+	}
+	for service, actions := range cfg.Classifications {
+		if !knownLevel(service) {
+			return fmt.Errorf("classifications.%s: unknown level, want one of %v", service, Levels)
+		}
+		for level, action := range actions {
+			if !knownLevel(level) {
+				return fmt.Errorf("classifications.%s.%s: unknown level, want one of %v", service, level, Levels)
+			}
+			if action != Keep && action != Mask && action != Drop {
+				return fmt.Errorf("classifications.%s.%s: action must be %s, %s or %s, not %q", service, level, Keep, Mask, Drop, action)
+			}
+		}
+	}
+	return nil
+}
+
+func knownLevel(level string) bool {
+	for _, l := range Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter masks and drops classified fields; it is safe for concurrent use. A nil Filter
+// keeps every field.
+type Filter struct {
+	// actions maps a field name to Mask or Drop; kept fields are not in it
+	actions map[string]string
+	mask    string
+}
+
+// New returns the Filter cfg gives a service with initialFields, nil when the policy is
+// not enforced in its environment or leaves every field alone. A service without a
+// data_classification, or with one the policy has no row for, is an error: it would
+// otherwise log everything unfiltered.
+func New(cfg Config, initialFields map[string]interface{}) (*Filter, error) {
+	environments := cfg.Environments
+	if len(environments) == 0 {
+		environments = DefaultEnvironments
+	}
+	environment := fmt.Sprint(initialFields[EnvironmentField])
+	enforced := false
+	for _, env := range environments {
+		enforced = enforced || env == environment
+	}
+	if !enforced {
+		return nil, nil
+	}
+
+	service, ok := initialFields[ClassificationField].(string)
+	if !ok || service == "" {
+		return nil, fmt.Errorf("no %s InitialField to select the policy by", ClassificationField)
+	}
+	row, ok := cfg.Classifications[service]
+	if !ok {
+		return nil, fmt.Errorf("classifications has no entry for %s services", service)
+	}
+	f := &Filter{actions: map[string]string{}, mask: cfg.Mask}
+	if f.mask == "" {
+		f.mask = DefaultMask
+	}
+	for field, level := range cfg.Fields {
+		if action := row[level]; action == Mask || action == Drop {
+			f.actions[field] = action
+		}
+	}
+	if len(f.actions) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Action returns what happens to values logged under field: Keep, Mask or Drop
+func (f *Filter) Action(field string) string {
+	if f == nil {
+		return Keep
+	}
+	if action, ok := f.actions[field]; ok {
+		return action
+	}
+	return Keep
+}
+
+// Fields returns how many fields the Filter masks or drops
+func (f *Filter) Fields() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.actions)
+}
+
+// KeyValues returns a copy of a key/value list with masked values replaced and dropped
+// pairs left out
+func (f *Filter) KeyValues(keyValues []interface{}) []interface{} {
+	if f == nil {
+		return keyValues
+	}
+	out := make([]interface{}, 0, len(keyValues))
+	for i := 0; i < len(keyValues); i += 2 {
+		key := fmt.Sprint(keyValues[i])
+		if i+1 >= len(keyValues) {
+			out = append(out, keyValues[i])
+			break
+		}
+		switch f.Action(key) {
+		case Drop:
+		case Mask:
+			out = append(out, keyValues[i], f.mask)
+		default:
+			out = append(out, keyValues[i], f.value(keyValues[i+1]))
+		}
+	}
+	return out
+}
+
+// value applies the Filter to the keys of map values, recursively
+func (f *Filter) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			switch f.Action(k) {
+			case Drop:
+			case Mask:
+				out[k] = f.mask
+			default:
+				out[k] = f.value(item)
+			}
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for k, s := range v {
+			switch f.Action(k) {
+			case Drop:
+			case Mask:
+				out[k] = f.mask
+			default:
+				out[k] = s
+			}
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,154 @@
+package dataclass
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+)
+
+var policy = Config{
+	Fields: map[string]string{
+		"email":       Confidential,
+		"client_ip":   Confidential,
+		"card_number": Restricted,
+		"region":      Internal,
+	},
+	Classifications: map[string]map[string]string{
+		Internal:     {Confidential: Drop, Restricted: Drop},
+		Confidential: {Confidential: Mask, Restricted: Drop},
+		Public:       {},
+	},
+}
+
+func service(environment, classification string) map[string]interface{} {
+	return map[string]interface{}{EnvironmentField: environment, ClassificationField: classification}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialFields map[string]interface{}
+		email, card   string
+		err           string
+	}{
+		{name: "development keeps everything", initialFields: service("development", Confidential), email: Keep, card: Keep},
+		{name: "confidential in production", initialFields: service("production", Confidential), email: Mask, card: Drop},
+		{name: "internal in production", initialFields: service("production", Internal), email: Drop, card: Drop},
+		{name: "public row without actions", initialFields: service("production", Public), email: Keep, card: Keep},
+		{name: "no classification", initialFields: map[string]interface{}{EnvironmentField: "production"}, err: "no data_classification"},
+		{name: "no row", initialFields: service("production", Restricted), err: "no entry for restricted"},
+	}
+	for _, tt := range tests {
+		f, err := New(policy, tt.initialFields)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if f.Action("email") != tt.email || f.Action("card_number") != tt.card || f.Action("region") != Keep {
+			t.Errorf("%s: email %s, card_number %s, region %s", tt.name, f.Action("email"), f.Action("card_number"), f.Action("region"))
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, tt := range map[string]struct {
+		yaml, err string
+	}{
+		"valid":          {"fields: {email: confidential}\nclassifications: {confidential: {confidential: mask}}\n", ""},
+		"unknown level":  {"fields: {email: secret}\n", "fields.email: unknown level"},
+		"unknown row":    {"classifications: {top: {}}\n", "classifications.top: unknown level"},
+		"unknown action": {"classifications: {internal: {confidential: hide}}\n", "classifications.internal.confidential: action must be"},
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".yaml")
+		os.WriteFile(path, []byte(tt.yaml), 0o644)
+		_, err := Load(path)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: err = %v, want %q", name, err, tt.err)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	rec := testlog.New(t)
+	f, err := New(policy, service("production", Confidential))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := f.Wrap(rec.Logger).With("client_ip", "10.0.0.7")
+
+	log.Infow("Payment accepted",
+		"email", "jane@example.com",
+		"card_number", "4111111111111111",
+		"region", "eu-west-1",
+		"customer", map[string]interface{}{"email": "jane@example.com", "tier": "gold", "card_number": "4111111111111111"},
+	)
+
+	entry := rec.AssertLogged("info", "Payment accepted",
+		"client_ip", DefaultMask,
+		"email", DefaultMask,
+		"region", "eu-west-1",
+		"customer", map[string]interface{}{"email": DefaultMask, "tier": "gold"},
+	)
+	if _, ok := entry["card_number"]; ok {
+		t.Errorf("card_number logged: %v", entry)
+	}
+
+	var nilFilter *Filter
+	if nilFilter.Wrap(rec.Logger) != rec.Logger {
+		t.Error("a nil Filter wraps the logger")
+	}
+}
+
+func TestWrapPlainAndFormatted(t *testing.T) {
+	rec := testlog.New(t)
+	f, err := New(policy, service("production", Confidential))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := f.Wrap(rec.Logger).With("email", "jane@example.com", "card_number", "4111111111111111")
+
+	log.Info("Payment accepted")
+	log.Infof("Payment %s", "refunded")
+	log.Error("Payment failed")
+	log.WithCtx(context.Background(), "client_ip", "10.0.0.7").Warnf("Payment %s", "held")
+
+	for _, msg := range []string{"Payment accepted", "Payment refunded", "Payment failed", "Payment held"} {
+		entry := rec.AssertLogged("", msg, "email", DefaultMask)
+		if _, ok := entry["card_number"]; ok {
+			t.Errorf("card_number logged: %v", entry)
+		}
+	}
+	rec.AssertLogged("warn", "Payment held", "client_ip", DefaultMask)
+}
+
+func TestInitialFields(t *testing.T) {
+	initialFields := service("production", Confidential)
+	initialFields["email"] = "ops@example.com"
+	initialFields["card_number"] = "4111111111111111"
+	initialFields["region"] = "eu-west-1"
+	f, err := New(policy, initialFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := testlog.NewWithOption(t, option.LogOption{Engine: "zap", Level: "debug", InitialFields: f.InitialFields(initialFields)})
+	f.Wrap(rec.Logger).Info("Service started")
+	entry := rec.AssertLogged("info", "Service started", "email", DefaultMask, "region", "eu-west-1", ClassificationField, Confidential)
+	if _, ok := entry["card_number"]; ok {
+		t.Errorf("card_number logged: %v", entry)
+	}
+	if initialFields["email"] != "ops@example.com" {
+		t.Error("InitialFields changed the map it was given")
+	}
+}
@@ -0,0 +1,34 @@
+package dataclass
+
+import (
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger that applies f to the fields of every entry logged through base
+// and to the fields added with With, or base itself when f is nil. Messages pass through
+// unchanged: classification is about fields, and masking free text is the redact
+// package's job. base's InitialFields are set when it is built; pass them through
+// InitialFields first.
+func (f *Filter) Wrap(base core.Logger) core.Logger {
+	if f == nil {
+		return base
+	}
+	return logwrap.New(base, logwrap.Hooks{
+		Entry: func(e *logwrap.Entry, _ core.Logger) {
+			e.Fields = f.KeyValues(e.Fields)
+		},
+		With: func(keyValues []interface{}, _ core.Logger) []interface{} {
+			return f.KeyValues(keyValues)
+		},
+	})
+}
+
+// InitialFields returns a copy of a logger's InitialFields with masked values replaced and
+// dropped fields left out, to build the logger Wrap is given with
+func (f *Filter) InitialFields(fields map[string]interface{}) map[string]interface{} {
+	if f == nil || fields == nil {
+		return fields
+	}
+	return f.value(fields).(map[string]interface{})
+}
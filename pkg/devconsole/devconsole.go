@@ -0,0 +1,186 @@
+// Package devconsole renders log entries for a developer's terminal: the level in color,
+// the message padded so that the fields line up, and the fields as key=value pairs in the
+// colors of a theme.
+//
+// The logger's console format prints the fields as a JSON object after the message, which
+// is hard to scan when a demo logs a dozen entries a second. With the pretty format in
+// development, New renders stdout itself and leaves every other output, and OTLP, to the
+// logger in the console format:
+//
+//	logOption.Development = true
+//	logOption.Format = devconsole.Format
+//	serviceLogger, err := devconsole.New(logOption, devconsole.ThemeFromEnv())
+//
+//	15:04:05.123 INFO  Order created                            order_id=ord-1 amount=42.5  orders/main.go:88
+//
+// An error field that wraps other errors, or whose message spans lines, is also written
+// out below the entry, one wrapped error per indented line, and error and fatal entries
+// get their stack as in a panic, unless the option disables stacktraces:
+//
+//	15:04:06.456 ERROR Payment failed                           order_id=ord-1 error="charge: gateway timeout"  orders/main.go:97
+//	    error:
+//	        charge: gateway timeout
+//	            caused by: gateway timeout
+//	    stacktrace:
+//	        main.chargeOrder
+//	            /src/orders/main.go:97
+//
+// Outside development the pretty format falls back to console, so a production config
+// never writes ANSI codes; when stdout is kept for JSON lines (see pkg/console) it falls
+// back to json.
+package devconsole
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+const (
+	// Format is the LogOption.Format that New renders; the logger itself does not know it
+	Format = "pretty"
+	// EnvTheme names the theme ThemeFromEnv returns: dark (the default), light or mono
+	EnvTheme = "LOG_THEME"
+	// EnvNoColor set to anything turns colors off, see https://no-color.org
+	EnvNoColor = "NO_COLOR"
+	// MessageWidth is the width messages are padded to, so that short ones line their
+	// fields up
+	MessageWidth = 40
+	// DefaultTheme is the theme used when none is named
+	DefaultTheme = "dark"
+)
+
+// stackLevel is the index in levels of the lowest level rendered with its stack
+const stackLevel = 3
+
+// indent is one level of the lines under an entry
+const indent = "    "
+
+// Theme holds the ANSI SGR parameters, such as "32" or "1;31", of each part of a line;
+// an empty one leaves that part uncolored
+type Theme struct {
+	Levels map[string]string
+	Time   string
+	Key    string
+	Caller string
+}
+
+// Themes are the themes LOG_THEME and Lookup can name
+var Themes = map[string]Theme{
+	"dark": {
+		Levels: map[string]string{"debug": "35", "info": "32", "warn": "33", "error": "31", "fatal": "1;31"},
+		Time:   "90",
+		Key:    "36",
+		Caller: "90",
+	},
+	"light": {
+		Levels: map[string]string{"debug": "35", "info": "34", "warn": "1;33", "error": "31", "fatal": "1;31"},
+		Time:   "2",
+		Key:    "2;34",
+		Caller: "2",
+	},
+	"mono": {},
+}
+
+// levels are the names of option.LogOption.Level, from the lowest
+var levels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Lookup returns the theme called name
+func Lookup(name string) (Theme, error) {
+	theme, ok := Themes[name]
+	if !ok {
+		names := make([]string, 0, len(Themes))
+		for n := range Themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Theme{}, fmt.Errorf("unknown theme %q (want one of %s)", name, strings.Join(names, ", "))
+	}
+	return theme, nil
+}
+
+// ThemeFromEnv returns the theme LOG_THEME names, or mono when NO_COLOR is set. Without
+// LOG_THEME it is dark on a terminal and mono when stdout is piped to a file or a program.
+func ThemeFromEnv() Theme {
+	if os.Getenv(EnvNoColor) != "" {
+		return Themes["mono"]
+	}
+	name := os.Getenv(EnvTheme)
+	if name == "" {
+		name = DefaultTheme
+		if !terminal(os.Stdout) {
+			name = "mono"
+		}
+	}
+	theme, err := Lookup(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "devconsole: %v, using %s\n", err, DefaultTheme)
+		return Themes[DefaultTheme]
+	}
+	return theme
+}
+
+// terminal reports whether f is a character device, such as a terminal
+func terminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// New returns logger.New(opt), except that with the pretty format in development stdout
+// is rendered in theme. The other outputs of opt use the console format.
+func New(opt *option.LogOption, theme Theme) (core.Logger, error) {
+	if opt.Format != Format {
+		return logger.New(opt)
+	}
+	base := *opt
+	base.Format = "console"
+	if console.Structured() {
+		base.Format = "json"
+	}
+	if !opt.Development || console.Structured() || !slices.Contains(opt.OutputPaths, "stdout") {
+		return logger.New(&base)
+	}
+	level := slices.Index(levels, strings.ToLower(opt.Level))
+	if level < 0 {
+		return nil, fmt.Errorf("devconsole: invalid level %q", opt.Level)
+	}
+
+	base.OutputPaths = slices.DeleteFunc(slices.Clone(opt.OutputPaths), func(output string) bool {
+		return output == "stdout"
+	})
+	if len(base.OutputPaths) == 0 {
+		// The logger needs an output for OTLP; this one is discarded
+		base.OutputPaths = []string{os.DevNull}
+	}
+	inner, err := logger.New(&base)
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{w: os.Stdout, theme: theme, clock: clock.Real, caller: !opt.DisableCaller, stacktrace: !opt.DisableStacktrace}
+	p.level.Store(int32(level))
+	return newPrettyLogger(inner, p), nil
+}
+
+// printer writes the rendered lines; it is shared by a logger and its children
+type printer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	theme  Theme
+	clock  clock.Clock
+	caller bool
+	// stacktrace renders the stack under error and fatal entries
+	stacktrace bool
+	// level is the index in levels of the lowest level rendered
+	level atomic.Int32
+}
@@ -0,0 +1,113 @@
+package devconsole
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// newTestLogger returns a pretty logger rendering into buf, with a file as the wrapped
+// logger's output
+func newTestLogger(t *testing.T, buf *bytes.Buffer, theme Theme, level int) core.Logger {
+	t.Helper()
+	inner, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       "debug",
+		Format:      "json",
+		OutputPaths: []string{filepath.Join(t.TempDir(), "app.log")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &printer{w: buf, theme: theme, clock: clock.NewFake(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)), caller: true, stacktrace: true}
+	p.level.Store(int32(level))
+	return newPrettyLogger(inner, p)
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(t, &buf, Themes["mono"], 1).With("component", "orders")
+	log.Debugw("Cache miss", "key", "order:1")
+	log.Infow("Order created", "order_id", "ord-1", "note", "two words", "err", errors.New("x=1"))
+	log.Warn("Retrying")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want 2 (debug dropped):\n%s", len(lines), buf.String())
+	}
+	want := "09:30:00.000 INFO  Order created" + strings.Repeat(" ", MessageWidth-len("Order created")) +
+		` component=orders order_id=ord-1 note="two words" err="x=1"  devconsole/devconsole_test.go:`
+	if !strings.HasPrefix(lines[0], want) {
+		t.Errorf("line = %q\nwant prefix %q", lines[0], want)
+	}
+	want = "09:30:00.000 WARN  Retrying" + strings.Repeat(" ", MessageWidth-len("Retrying")) + " component=orders  devconsole/"
+	if !strings.HasPrefix(lines[1], want) {
+		t.Errorf("line = %q\nwant prefix %q", lines[1], want)
+	}
+}
+
+func TestErrorAndStack(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(t, &buf, Themes["mono"], 0)
+	log.Warnw("Retrying payment", "error", errors.New("gateway timeout"))
+	err := fmt.Errorf("charge order: %w", errors.Join(errors.New("gateway timeout"), errors.New("card declined\nby issuer")))
+	log.Errorw("Payment failed", "error", err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !strings.Contains(lines[0], `error="gateway timeout"`) || !strings.Contains(lines[1], "ERROR Payment failed") {
+		t.Fatalf("entry lines:\n%s", buf.String())
+	}
+	want := []string{
+		"    error:",
+		"        charge order: gateway timeout",
+		"        card declined",
+		"        by issuer",
+		"            caused by: gateway timeout",
+		"                       card declined",
+		"                       by issuer",
+		"                - gateway timeout",
+		"                - card declined",
+		"                  by issuer",
+		"    stacktrace:",
+		"        github.com/kart-io/go-example/pkg/devconsole.TestErrorAndStack",
+	}
+	if len(lines) < 2+len(want) {
+		t.Fatalf("%d lines, want at least %d:\n%s", len(lines), 2+len(want), buf.String())
+	}
+	for i, line := range want {
+		if got := lines[2+i]; got != line {
+			t.Errorf("line %d = %q, want %q", 2+i, got, line)
+		}
+	}
+	if !strings.Contains(lines[2+len(want)], "devconsole_test.go:") {
+		t.Errorf("first frame at %q, want the Errorw call", lines[2+len(want)])
+	}
+}
+
+func TestTheme(t *testing.T) {
+	var buf bytes.Buffer
+	newTestLogger(t, &buf, Themes["dark"], 0).Errorw("Payment failed", "order_id", "ord-1")
+	for _, part := range []string{"\x1b[90m09:30:00.000\x1b[0m", "\x1b[31mERROR\x1b[0m", "\x1b[36morder_id\x1b[0m=ord-1"} {
+		if !strings.Contains(buf.String(), part) {
+			t.Errorf("line %q lacks %q", buf.String(), part)
+		}
+	}
+
+	if _, err := Lookup("solarized"); err == nil {
+		t.Error("unknown theme: no error")
+	}
+	t.Setenv(EnvTheme, "light")
+	t.Setenv(EnvNoColor, "1")
+	if theme := ThemeFromEnv(); theme.Key != "" {
+		t.Errorf("NO_COLOR: theme = %+v, want mono", theme)
+	}
+}
@@ -0,0 +1,174 @@
+package devconsole
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// newPrettyLogger returns a logger that passes entries to inner, which writes the other
+// outputs, and renders them on p, fields added with With included. Fatal entries are
+// rendered before they are logged, as inner exits the process.
+func newPrettyLogger(inner core.Logger, p *printer) core.Logger {
+	return logwrap.New(inner, logwrap.Hooks{Logged: p.print, SetLevel: p.setLevel})
+}
+
+// setLevel sets the level of the rendered lines by the level's name; a level without one
+// of the names of option.LogOption.Level leaves it as it is
+func (p *printer) setLevel(level core.Level) {
+	for i, name := range levels {
+		if strings.EqualFold(fmt.Sprint(level), name) {
+			p.level.Store(int32(i))
+		}
+	}
+}
+
+// print renders an entry, if the printer takes its level. It is the Logged hook, so the
+// code that logged is logwrap.Depth frames up.
+func (p *printer) print(e logwrap.Entry) {
+	level := slices.Index(levels, e.Level)
+	if int32(level) < p.level.Load() {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(p.paint(p.theme.Time, p.clock.Now().Format("15:04:05.000")))
+	b.WriteByte(' ')
+	name := levels[level]
+	b.WriteString(p.paint(p.theme.Levels[name], fmt.Sprintf("%-5s", strings.ToUpper(name))))
+	b.WriteByte(' ')
+	b.WriteString(e.Message)
+
+	fields := append(slices.Clip(e.With), e.Fields...)
+	if len(fields) > 0 {
+		if pad := MessageWidth - len(e.Message); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+		for i := 0; i < len(fields); i += 2 {
+			key, value := fmt.Sprint(fields[i]), interface{}(nil)
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			} else {
+				// A value without a key, as slog renders it
+				key, value = "!BADKEY", fields[i]
+			}
+			b.WriteByte(' ')
+			b.WriteString(p.paint(p.theme.Key, key))
+			b.WriteByte('=')
+			b.WriteString(quote(value))
+		}
+	}
+	if p.caller {
+		if _, file, line, ok := runtime.Caller(logwrap.Depth + e.Skip); ok {
+			b.WriteString("  ")
+			b.WriteString(p.paint(p.theme.Caller, filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file)+":"+strconv.Itoa(line)))
+		}
+	}
+	b.WriteByte('\n')
+
+	// Errors that wrap others or span lines, then the stack, go on indented lines below
+	for i := 0; i+1 < len(fields); i += 2 {
+		if err, ok := fields[i+1].(error); ok && multiline(err) {
+			b.WriteString(indent)
+			b.WriteString(p.paint(p.theme.Key, fmt.Sprint(fields[i])))
+			b.WriteString(":\n")
+			writeError(&b, err, indent+indent, "")
+		}
+	}
+	if p.stacktrace && level >= stackLevel {
+		b.WriteString(indent)
+		b.WriteString(p.paint(p.theme.Key, "stacktrace"))
+		b.WriteString(":\n")
+		p.writeStack(&b, logwrap.Depth+1+e.Skip)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.w.Write([]byte(b.String()))
+}
+
+// paint wraps s in the SGR parameters sgr, or returns it as is if sgr is empty
+func (p *printer) paint(sgr, s string) string {
+	if sgr == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m" + s + "\x1b[0m"
+}
+
+// multiline reports whether err wraps other errors or its message spans lines, so that
+// the key=value pair does not tell the whole story
+func multiline(err error) bool {
+	switch err.(type) {
+	case interface{ Unwrap() error }, interface{ Unwrap() []error }:
+		return true
+	}
+	return strings.Contains(err.Error(), "\n")
+}
+
+// writeError writes the message of err, one line per line of it, then the errors it wraps,
+// each indented one level further; prefix marks the wrapped ones
+func writeError(b *strings.Builder, err error, at, prefix string) {
+	for i, line := range strings.Split(err.Error(), "\n") {
+		b.WriteString(at)
+		if i == 0 {
+			b.WriteString(prefix)
+		} else {
+			b.WriteString(strings.Repeat(" ", len(prefix)))
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			writeError(b, inner, at+indent, "caused by: ")
+		}
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			writeError(b, inner, at+indent, "- ")
+		}
+	}
+}
+
+// writeStack writes the goroutine's stack from skip frames above it, a function and its
+// file:line per frame as in a panic, without the runtime's own frames
+func (p *printer) writeStack(b *strings.Builder, skip int) {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+1, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			b.WriteString(indent + indent)
+			b.WriteString(frame.Function)
+			b.WriteByte('\n')
+			b.WriteString(p.paint(p.theme.Caller, indent+indent+indent+frame.File+":"+strconv.Itoa(frame.Line)))
+			b.WriteByte('\n')
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+// quote renders a field value, quoted when it is empty or has spaces, quotes or an equals
+// sign, so that every pair on the line stays one word
+func quote(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package dynamicfields
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/logger/core"
+)
+
+// FieldsRequest is the body of POST /admin/fields
+type FieldsRequest struct {
+	// Set adds or replaces fields
+	Set map[string]string `json:"set"`
+	// Remove removes fields that are set
+	Remove []string `json:"remove"`
+}
+
+// ListHandler serves the global fields as {"fields": {...}}
+func (g *Globals) ListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"fields": g.Fields()})
+	}
+}
+
+// UpdateHandler applies a FieldsRequest and responds with the fields as they are now. A
+// request with any invalid change is refused with 400 and changes nothing. Every field
+// set or removed is recorded in auditLog as a log.fields event whose resource names the
+// change, and logged to logger with its value; the audit entry carries only the key.
+// Mount it behind the admin authentication of the demo.
+func (g *Globals) UpdateHandler(auditLog *audit.Logger, logger core.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req FieldsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+		if len(req.Set) == 0 && len(req.Remove) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to change: give set, remove or both"})
+			return
+		}
+		set, removed, err := g.Update(req.Set, req.Remove)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		event := audit.GinEvent(c, audit.ActionLogFields, audit.OutcomeSuccess, "")
+		for _, key := range removed {
+			event.Resource = "remove " + key
+			auditLog.RecordOrLog(event, logger)
+			logger.Infow("Global log field removed", "field", key, "actor", event.Actor)
+		}
+		for _, key := range set {
+			event.Resource = "set " + key
+			auditLog.RecordOrLog(event, logger)
+			logger.Infow("Global log field set", "field", key, "value", req.Set[key], "actor", event.Actor)
+		}
+		c.JSON(http.StatusOK, gin.H{"fields": g.Fields()})
+	}
+}
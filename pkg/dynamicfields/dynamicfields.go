@@ -0,0 +1,147 @@
+// Package dynamicfields adds fields whose values are computed when an entry is logged.
+//
+// InitialFields and With freeze a value when the logger is built. Some context only makes
+// sense as of the moment a line is written: goroutine count, heap in use, uptime, the depth
+// of a queue. A Registry holds named providers for such values and Wrap returns a logger
+// that evaluates them on every entry. Providers that are too costly to run per entry can be
+// wrapped with Periodic, which refreshes the value in the background instead.
+//
+// Globals hold constant fields operators add and remove at run time, such as incident_id
+// during an outage; UpdateHandler serves them as an audited admin endpoint.
+package dynamicfields
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/hostmeta"
+)
+
+// Provider computes a field value at log time. It runs on the logging goroutine, so it
+// must be cheap and safe for concurrent use.
+type Provider func() interface{}
+
+// Registry holds providers by field key, evaluated in registration order
+type Registry struct {
+	mu        sync.RWMutex
+	keys      []string
+	providers map[string]Provider
+}
+
+// New returns an empty Registry
+func New() *Registry {
+	return &Registry{providers: map[string]Provider{}}
+}
+
+// Default returns a Registry with goroutines, heap_inuse_bytes and uptime_seconds
+func Default() *Registry {
+	r := New()
+	r.Register("goroutines", Goroutines())
+	r.Register("heap_inuse_bytes", HeapInUse())
+	r.Register("uptime_seconds", Uptime())
+	return r
+}
+
+// Register adds or replaces the provider for key
+func (r *Registry) Register(key string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.providers[key] = p
+}
+
+// Unregister removes the provider for key, if any
+func (r *Registry) Unregister(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[key]; !ok {
+		return
+	}
+	delete(r.providers, key)
+	for i, k := range r.keys {
+		if k == key {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// KeyValues evaluates every provider and returns the results as alternating keys and
+// values, skipping keys already present in existing
+func (r *Registry) KeyValues(existing []interface{}) []interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kv := make([]interface{}, 0, 2*len(r.keys))
+	for _, key := range r.keys {
+		if hasKey(existing, key) {
+			continue
+		}
+		kv = append(kv, key, r.providers[key]())
+	}
+	return kv
+}
+
+func hasKey(keysAndValues []interface{}, key string) bool {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if k, ok := keysAndValues[i].(string); ok && k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Goroutines reports runtime.NumGoroutine
+func Goroutines() Provider {
+	return func() interface{} { return runtime.NumGoroutine() }
+}
+
+// heapObjectsMetric is the live heap as tracked by the runtime; reading it does not stop
+// the world the way runtime.ReadMemStats does
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// HeapInUse reports the bytes occupied by live and not yet swept heap objects
+func HeapInUse() Provider {
+	return func() interface{} {
+		sample := []metrics.Sample{{Name: heapObjectsMetric}}
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return uint64(0)
+		}
+		return sample[0].Value.Uint64()
+	}
+}
+
+// Uptime reports whole seconds since the process started
+func Uptime() Provider {
+	start := hostmeta.Get().StartTime
+	return func() interface{} { return int64(time.Since(start).Seconds()) }
+}
+
+// Periodic runs p once immediately and then every interval until ctx is done, and returns
+// a provider serving the latest result. Use it for values too costly to compute per entry.
+func Periodic(ctx context.Context, interval time.Duration, p Provider) Provider {
+	var latest atomic.Value
+	latest.Store(valueBox{p()})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				latest.Store(valueBox{p()})
+			}
+		}
+	}()
+	return func() interface{} { return latest.Load().(valueBox).v }
+}
+
+// valueBox lets atomic.Value hold results of differing concrete types
+type valueBox struct{ v interface{} }
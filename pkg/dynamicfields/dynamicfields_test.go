@@ -0,0 +1,95 @@
+package dynamicfields
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestWrapEvaluatesPerEntry(t *testing.T) {
+	rec := testlog.New(t)
+	var depth atomic.Int64
+	r := New()
+	r.Register("queue_depth", func() interface{} { return depth.Load() })
+	log := r.Wrap(rec.Logger)
+
+	depth.Store(3)
+	log.Infow("Job enqueued", "job_id", "job-1")
+	depth.Store(7)
+	log.With("component", "worker").Warn("Queue growing")
+	log.Infof("Drained %d jobs", 7)
+
+	rec.AssertLogged("info", "Job enqueued", "job_id", "job-1", "queue_depth", 3)
+	rec.AssertLogged("warn", "Queue growing", "component", "worker", "queue_depth", 7)
+	rec.AssertLogged("info", "Drained 7 jobs", "queue_depth", 7)
+}
+
+func TestCallSiteFieldWins(t *testing.T) {
+	rec := testlog.New(t)
+	r := New()
+	r.Register("queue_depth", func() interface{} { return 1 })
+	r.Wrap(rec.Logger).Infow("Snapshot", "queue_depth", 99)
+
+	// Provider fields are appended after the call site's, so a duplicate key would decode as 1
+	rec.AssertLogged("info", "Snapshot", "queue_depth", 99)
+}
+
+func TestRegisterAndUnregister(t *testing.T) {
+	r := New()
+	r.Register("a", func() interface{} { return 1 })
+	r.Register("b", func() interface{} { return 2 })
+	r.Register("a", func() interface{} { return 3 })
+	if kv := r.KeyValues(nil); len(kv) != 4 || kv[0] != "a" || kv[1] != 3 || kv[2] != "b" {
+		t.Errorf("KeyValues() = %v, want a=3 then b=2", kv)
+	}
+	r.Unregister("a")
+	r.Unregister("missing")
+	if kv := r.KeyValues(nil); len(kv) != 2 || kv[0] != "b" {
+		t.Errorf("after Unregister KeyValues() = %v", kv)
+	}
+}
+
+func TestDefaultProviders(t *testing.T) {
+	kv := Default().KeyValues(nil)
+	values := map[interface{}]interface{}{}
+	for i := 0; i < len(kv); i += 2 {
+		values[kv[i]] = kv[i+1]
+	}
+	if n, ok := values["goroutines"].(int); !ok || n < 1 {
+		t.Errorf("goroutines = %v", values["goroutines"])
+	}
+	if n, ok := values["heap_inuse_bytes"].(uint64); !ok || n == 0 {
+		t.Errorf("heap_inuse_bytes = %v", values["heap_inuse_bytes"])
+	}
+	if _, ok := values["uptime_seconds"].(int64); !ok {
+		t.Errorf("uptime_seconds = %v", values["uptime_seconds"])
+	}
+}
+
+func TestPeriodic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int64
+	p := Periodic(ctx, 10*time.Millisecond, func() interface{} { return calls.Add(1) })
+
+	if p() != int64(1) {
+		t.Fatalf("first value = %v, want the immediate evaluation", p())
+	}
+	deadline := time.Now().Add(time.Second)
+	for p().(int64) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("Periodic did not refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	before := calls.Load()
+	for i := 0; i < 100; i++ {
+		p()
+	}
+	if calls.Load() > before+1 {
+		t.Error("reading the provider evaluated it instead of serving the cached value")
+	}
+}
@@ -0,0 +1,135 @@
+package dynamicfields
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Limits on the fields Globals holds, so an admin call cannot bloat every entry
+const (
+	MaxGlobals     = 16
+	MaxValueLength = 256
+)
+
+// globalKey is the shape of a global field key: lower case, digits, '_' and '.'
+var globalKey = regexp.MustCompile(`^[a-z][a-z0-9_.]{0,63}$`)
+
+// reservedKeys are written by the engine or the service itself; a global field under one
+// of them would shadow the real value or produce a duplicate key
+var reservedKeys = map[string]bool{
+	"level": true, "message": true, "msg": true, "timestamp": true, "time": true,
+	"caller": true, "stacktrace": true, "engine": true, "error": true,
+	"service.name": true, "service.version": true, "environment": true,
+	"trace_id": true, "span_id": true, "request_id": true,
+}
+
+// Globals are fields operators add to every entry of a running service, such as
+// incident_id during an outage, and remove again when they no longer apply. They live in
+// a Registry as constant providers, so a logger wrapped before a field was set carries it
+// from the next entry on.
+type Globals struct {
+	r      *Registry
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewGlobals returns Globals whose fields are added to the entries of loggers wrapped by r
+func NewGlobals(r *Registry) *Globals {
+	return &Globals{r: r, values: map[string]string{}}
+}
+
+// CheckKey reports why key cannot be a global field, or nil when it can
+func CheckKey(key string) error {
+	if !globalKey.MatchString(key) {
+		return fmt.Errorf("field %q must start with a lower-case letter and hold only a-z, 0-9, '_' and '.', at most 64 characters", key)
+	}
+	if reservedKeys[key] {
+		return fmt.Errorf("field %q is reserved", key)
+	}
+	return nil
+}
+
+func checkValue(key, value string) error {
+	if !utf8.ValidString(value) || len(value) > MaxValueLength || strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("field %q: value must be a single line of at most %d bytes", key, MaxValueLength)
+	}
+	return nil
+}
+
+// Set adds or replaces the global field key
+func (g *Globals) Set(key, value string) error {
+	_, _, err := g.Update(map[string]string{key: value}, nil)
+	return err
+}
+
+// Remove removes the global field key and reports whether it was set
+func (g *Globals) Remove(key string) bool {
+	_, removed, err := g.Update(nil, []string{key})
+	return err == nil && len(removed) == 1
+}
+
+// Fields returns a copy of the global fields
+func (g *Globals) Fields() map[string]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make(map[string]string, len(g.values))
+	for k, v := range g.values {
+		out[k] = v
+	}
+	return out
+}
+
+// Update checks every change first and then removes the keys in remove and sets those in
+// set, so a request with one bad field changes nothing. It returns the keys set and
+// removed, sorted.
+func (g *Globals) Update(set map[string]string, remove []string) (setKeys, removed []string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	count := len(g.values)
+	removing := map[string]bool{}
+	for _, key := range remove {
+		if removing[key] {
+			continue
+		}
+		removing[key] = true
+		if _, ok := g.values[key]; !ok {
+			return nil, nil, fmt.Errorf("field %q is not set", key)
+		}
+		if _, ok := set[key]; ok {
+			return nil, nil, fmt.Errorf("field %q is both set and removed", key)
+		}
+		count--
+	}
+	for key, value := range set {
+		if err := CheckKey(key); err != nil {
+			return nil, nil, err
+		}
+		if err := checkValue(key, value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := g.values[key]; !ok {
+			count++
+		}
+	}
+	if count > MaxGlobals {
+		return nil, nil, fmt.Errorf("at most %d global fields", MaxGlobals)
+	}
+
+	for key := range removing {
+		delete(g.values, key)
+		g.r.Unregister(key)
+		removed = append(removed, key)
+	}
+	for key, value := range set {
+		g.values[key] = value
+		g.r.Register(key, func() interface{} { return value })
+		setKeys = append(setKeys, key)
+	}
+	sort.Strings(setKeys)
+	sort.Strings(removed)
+	return setKeys, removed, nil
+}
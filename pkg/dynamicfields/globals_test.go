@@ -0,0 +1,110 @@
+package dynamicfields
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestGlobals(t *testing.T) {
+	rec := testlog.New(t)
+	r := New()
+	g := NewGlobals(r)
+	log := r.Wrap(rec.Logger).With("component", "orders")
+
+	if err := g.Set("incident_id", "INC-4211"); err != nil {
+		t.Fatal(err)
+	}
+	log.Infow("Order created", "order_id", "o-1")
+	g.Remove("incident_id")
+	log.Infow("Order created", "order_id", "o-2")
+
+	rec.AssertLogged("info", "Order created", "order_id", "o-1", "incident_id", "INC-4211", "component", "orders")
+	if entry := rec.AssertLogged("info", "Order created", "order_id", "o-2"); entry["incident_id"] != nil {
+		t.Errorf("removed field still logged: %v", entry)
+	}
+
+	for _, bad := range []struct{ key, value string }{
+		{"Incident", "x"}, {"level", "debug"}, {"service.name", "other"}, {"note", "two\nlines"},
+		{"note", strings.Repeat("x", MaxValueLength+1)},
+	} {
+		if err := g.Set(bad.key, bad.value); err == nil {
+			t.Errorf("Set(%q, %q) accepted", bad.key, bad.value)
+		}
+	}
+	if g.Remove("missing") {
+		t.Error("Remove(missing) = true")
+	}
+
+	// One bad change refuses the whole update
+	if _, _, err := g.Update(map[string]string{"incident_id": "INC-1", "level": "x"}, nil); err == nil || len(g.Fields()) != 0 {
+		t.Errorf("Update() = %v, fields %v, want nothing applied", err, g.Fields())
+	}
+	for i := 0; i < MaxGlobals; i++ {
+		g.Set("field_"+string(rune('a'+i)), "v")
+	}
+	if err := g.Set("one_more", "v"); err == nil {
+		t.Errorf("set %d fields, want at most %d", len(g.Fields()), MaxGlobals)
+	}
+}
+
+func TestUpdateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	g := NewGlobals(New())
+	g.Set("region_override", "eu-west-1")
+
+	router := gin.New()
+	router.GET("/admin/fields", g.ListHandler())
+	router.POST("/admin/fields", g.UpdateHandler(auditLog, rec.Logger))
+
+	tests := []struct {
+		body   string
+		status int
+		want   string
+	}{
+		{`{"set":{"incident_id":"INC-4211"},"remove":["region_override"]}`, http.StatusOK, `{"fields":{"incident_id":"INC-4211"}}`},
+		{`{"set":{"level":"debug"}}`, http.StatusBadRequest, `reserved`},
+		{`{"remove":["missing"]}`, http.StatusBadRequest, `not set`},
+		{`{}`, http.StatusBadRequest, `nothing to change`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/admin/fields", strings.NewReader(tt.body))
+		req.Header.Set(audit.ActorHeader, "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("POST %s = %d %s, want %d with %s", tt.body, w.Code, w.Body, tt.status, tt.want)
+		}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/fields", nil))
+	if w.Body.String() != `{"fields":{"incident_id":"INC-4211"}}` {
+		t.Errorf("GET = %s", w.Body)
+	}
+
+	rec.AssertLogged("info", "Global log field set", "field", "incident_id", "value", "INC-4211", "actor", "alice")
+	rec.AssertLogged("info", "Global log field removed", "field", "region_override", "actor", "alice")
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`"actor":"alice","action":"log.fields","resource":"remove region_override"`, `"resource":"set incident_id"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("audit file = %s, want it to contain %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "INC-4211") {
+		t.Error("the field value reached the audit file")
+	}
+}
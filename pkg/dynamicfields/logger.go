@@ -0,0 +1,23 @@
+package dynamicfields
+
+import (
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger that appends r's fields to everything logged through base, plain
+// and formatted calls such as Info and Infof included. Fields passed at the call site win
+// over a provider with the same key.
+func (r *Registry) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		Entry: func(e *logwrap.Entry, _ core.Logger) {
+			dynamic := r.KeyValues(e.Fields)
+			if len(dynamic) == 0 {
+				return
+			}
+			// Copy rather than append in place; the caller may still own the fields' array
+			fields := make([]interface{}, 0, len(e.Fields)+len(dynamic))
+			e.Fields = append(append(fields, e.Fields...), dynamic...)
+		},
+	})
+}
@@ -0,0 +1,189 @@
+// Package fieldconv renames log fields to a semantic convention when lines are written.
+//
+// The demos log with their own mix of names: service.name and k8s.pod.name from
+// OpenTelemetry, trace_id and request_id in snake case, plus the engine's level, message and
+// timestamp. Backends expect one convention: Elasticsearch dashboards are built on ECS
+// (log.level, trace.id, service.environment), OTLP pipelines on OpenTelemetry
+// (severity_text, deployment.environment), Loki and Prometheus labels on names without
+// dots. A Convention maps keys at output time, so code keeps logging one set of names and
+// each output path picks the convention its backend wants.
+package fieldconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Convention maps field keys. Keys are looked up in Keys first, then passed to Transform;
+// a key neither changes is written as is. Static fields are added to every line that does
+// not already carry them.
+type Convention struct {
+	Name      string
+	Keys      map[string]string
+	Transform func(string) string
+	Static    map[string]interface{}
+}
+
+// OTel renames to OpenTelemetry semantic conventions and log data model names
+var OTel = Convention{
+	Name: "otel",
+	Keys: map[string]string{
+		"level":       "severity_text",
+		"message":     "body",
+		"environment": "deployment.environment",
+		"error":       "exception.message",
+		"user_id":     "enduser.id",
+		"method":      "http.request.method",
+		"status":      "http.response.status_code",
+		"route":       "http.route",
+		"path":        "url.path",
+		"client_ip":   "client.address",
+	},
+}
+
+// ECS renames to Elastic Common Schema field names
+var ECS = Convention{
+	Name: "ecs",
+	Keys: map[string]string{
+		"timestamp":              "@timestamp",
+		"level":                  "log.level",
+		"caller":                 "log.origin.file.name",
+		"environment":            "service.environment",
+		"deployment.environment": "service.environment",
+		"trace_id":               "trace.id",
+		"span_id":                "span.id",
+		"request_id":             "http.request.id",
+		"error":                  "error.message",
+		"user_id":                "user.id",
+		"method":                 "http.request.method",
+		"status":                 "http.response.status_code",
+		"path":                   "url.path",
+		"client_ip":              "client.ip",
+		"k8s.pod.name":           "kubernetes.pod.name",
+		"k8s.pod.uid":            "kubernetes.pod.uid",
+		"k8s.namespace.name":     "kubernetes.namespace",
+		"k8s.node.name":          "kubernetes.node.name",
+	},
+	Static: map[string]interface{}{"ecs.version": "8.11.0"},
+}
+
+// Flat replaces dots with underscores, e.g. service.name becomes service_name, for backends
+// that treat dots specially such as Loki labels and Prometheus
+var Flat = Convention{
+	Name:      "flat",
+	Transform: func(key string) string { return strings.ReplaceAll(key, ".", "_") },
+}
+
+// Conventions lists the built-in conventions by name
+var Conventions = map[string]Convention{
+	OTel.Name: OTel,
+	ECS.Name:  ECS,
+	Flat.Name: Flat,
+}
+
+// Lookup returns the built-in convention called name
+func Lookup(name string) (Convention, bool) {
+	c, ok := Conventions[strings.ToLower(name)]
+	return c, ok
+}
+
+// Key returns the name key is written under
+func (c Convention) Key(key string) string {
+	if mapped, ok := c.Keys[key]; ok {
+		return mapped
+	}
+	if c.Transform != nil {
+		return c.Transform(key)
+	}
+	return key
+}
+
+// Fields returns a copy of fields with renamed keys, for InitialFields and other maps.
+// When two keys map to the same name, the one sorting first wins.
+func (c Convention) Fields(fields map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]interface{}, len(fields))
+	for _, key := range keys {
+		if mapped := c.Key(key); !has(out, mapped) {
+			out[mapped] = fields[key]
+		}
+	}
+	return out
+}
+
+func has(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// Rewrite renames the top-level keys of one JSON object line, keeping their order and
+// leaving values untouched. When two keys map to the same name the first one wins. Input
+// that is not a JSON object is returned unchanged.
+func (c Convention) Rewrite(line []byte) []byte {
+	trimmed := bytes.TrimRight(line, "\r\n")
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return line
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(line) + 64)
+	out.WriteByte('{')
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return line
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return line
+		}
+		mapped := c.Key(key)
+		if seen[mapped] {
+			continue
+		}
+		seen[mapped] = true
+		writeField(&out, mapped, value)
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return line
+	}
+
+	statics := make([]string, 0, len(c.Static))
+	for key := range c.Static {
+		if !seen[key] {
+			statics = append(statics, key)
+		}
+	}
+	sort.Strings(statics)
+	for _, key := range statics {
+		value, err := json.Marshal(c.Static[key])
+		if err != nil {
+			continue
+		}
+		writeField(&out, key, value)
+	}
+
+	out.WriteByte('}')
+	out.Write(line[len(trimmed):])
+	return out.Bytes()
+}
+
+func writeField(out *bytes.Buffer, key string, value []byte) {
+	if out.Len() > 1 {
+		out.WriteByte(',')
+	}
+	name, _ := json.Marshal(key)
+	out.Write(name)
+	out.WriteByte(':')
+	out.Write(value)
+}
@@ -0,0 +1,71 @@
+package fieldconv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRewriteKeepsOrderAndValues(t *testing.T) {
+	line := []byte(`{"level":"info","timestamp":"2024-05-01T10:00:00Z","message":"Order paid","service.name":"shop","trace_id":"4bf92f3577b34da6","nested":{"level":"x"}}` + "\n")
+
+	got := string(ECS.Rewrite(line))
+	want := `{"log.level":"info","@timestamp":"2024-05-01T10:00:00Z","message":"Order paid","service.name":"shop","trace.id":"4bf92f3577b34da6","nested":{"level":"x"},"ecs.version":"8.11.0"}` + "\n"
+	if got != want {
+		t.Errorf("ECS.Rewrite()\n got %s\nwant %s", got, want)
+	}
+
+	got = string(Flat.Rewrite(line))
+	want = `{"level":"info","timestamp":"2024-05-01T10:00:00Z","message":"Order paid","service_name":"shop","trace_id":"4bf92f3577b34da6","nested":{"level":"x"}}` + "\n"
+	if got != want {
+		t.Errorf("Flat.Rewrite()\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRewriteFirstKeyWins(t *testing.T) {
+	line := []byte(`{"environment":"prod","deployment.environment":"staging"}`)
+	if got := string(ECS.Rewrite(line)); got != `{"service.environment":"prod","ecs.version":"8.11.0"}` {
+		t.Errorf("ECS.Rewrite() = %s", got)
+	}
+}
+
+func TestRewritePassesThroughNonObjects(t *testing.T) {
+	for _, line := range []string{"plain text\n", "[1,2]\n", `{"truncated":` + "\n", ""} {
+		if got := string(OTel.Rewrite([]byte(line))); got != line {
+			t.Errorf("OTel.Rewrite(%q) = %q, want it unchanged", line, got)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	got := OTel.Fields(map[string]interface{}{"service.name": "shop", "environment": "prod"})
+	if len(got) != 2 || got["service.name"] != "shop" || got["deployment.environment"] != "prod" {
+		t.Errorf("OTel.Fields() = %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if c, ok := Lookup("ECS"); !ok || c.Name != "ecs" {
+		t.Errorf("Lookup(ECS) = %v, %v", c.Name, ok)
+	}
+	if _, ok := Lookup("gelf"); ok {
+		t.Error("Lookup(gelf) found a convention")
+	}
+}
+
+func TestSinkJoinsSplitWrites(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSink(OTel, &out)
+	_, _ = s.Write([]byte(`{"level":"warn","mess`))
+	_, _ = s.Write([]byte(`age":"Slow query"}` + "\n" + `{"level":"info"}` + "\n" + `{"error":"boom"}`))
+
+	want := `{"severity_text":"warn","body":"Slow query"}` + "\n" + `{"severity_text":"info"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("after Write got %q, want %q", out.String(), want)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want += `{"exception.message":"boom"}`; out.String() != want {
+		t.Errorf("after Close got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,82 @@
+package fieldconv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Scheme is the zap sink scheme registered by RegisterSink
+const Scheme = "fieldconv"
+
+// Sink rewrites each JSON line with a convention before writing it to the underlying writer
+type Sink struct {
+	mu  sync.Mutex
+	c   Convention
+	dst io.Writer
+	buf []byte
+}
+
+// NewSink returns a Sink that writes lines renamed by c to dst
+func NewSink(c Convention, dst io.Writer) *Sink {
+	return &Sink{c: c, dst: dst}
+}
+
+// Write rewrites every complete line in p. A trailing partial line is held until the
+// rest of it arrives, so callers that split one entry across writes still get it renamed.
+func (s *Sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := s.dst.Write(s.c.Rewrite(s.buf[:i+1])); err != nil {
+			s.buf = s.buf[i+1:]
+			return len(p), err
+		}
+		s.buf = s.buf[i+1:]
+	}
+}
+
+// Sync writes out a held partial line and syncs the underlying writer when it supports it
+func (s *Sink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buf) > 0 {
+		line := s.c.Rewrite(s.buf)
+		s.buf = nil
+		if _, err := s.dst.Write(line); err != nil {
+			return err
+		}
+	}
+	if syncer, ok := s.dst.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// Close flushes the sink; the underlying writer is left open because the sink does not own it
+func (s *Sink) Close() error {
+	return s.Sync()
+}
+
+// RegisterSink registers the fieldconv zap sink scheme. An output path such as
+// "fieldconv://ecs" then writes to dst with the named built-in convention.
+func RegisterSink(dst io.Writer) error {
+	return zap.RegisterSink(Scheme, func(u *url.URL) (zap.Sink, error) {
+		c, ok := Lookup(u.Host)
+		if !ok {
+			return nil, fmt.Errorf("fieldconv: unknown convention %q in %s", u.Host, u)
+		}
+		return NewSink(c, dst), nil
+	})
+}
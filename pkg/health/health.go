@@ -0,0 +1,199 @@
+// Package health is the GET /health endpoint every demo that serves HTTP shares.
+//
+// The demos used to answer with whatever they liked: most with {"status":"healthy"},
+// some with a count next to it, viper-config-demo with its service, version and
+// environment. A probe or dashboard had to know which demo it was looking at. Every
+// response now has the shape described in health.schema.json:
+//
+//	{"status":"healthy","service":"webhook-demo","version":"v1.2.0",
+//	 "checks":{"event_store":{"status":"healthy","details":{"stored_events":3}}}}
+//
+// status is the worst status of any check, and an unhealthy one answers 503 so plain HTTP
+// probes see it. Anything a demo wants to report goes in a check's details:
+//
+//	r.GET("/health", health.Handler(map[string]health.Checker{
+//		"event_store": func(ctx context.Context) health.Check {
+//			return health.Healthy(map[string]interface{}{"stored_events": store.Count()})
+//		},
+//	}))
+//
+// cmd/e2e validates every /health response it requests against the schema.
+package health
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Schema is the content of health.schema.json
+//
+//go:embed health.schema.json
+var Schema []byte
+
+// SchemaURL is the schema's $id
+const SchemaURL = "https://github.com/kart-io/go-example/pkg/health/health.schema.json"
+
+// Status of a service or one of its checks, from best to worst
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusDegraded  Status = "degraded"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// rank orders statuses so the worst one wins
+func (s Status) rank() int {
+	switch s {
+	case StatusHealthy:
+		return 0
+	case StatusDegraded:
+		return 1
+	}
+	return 2
+}
+
+// Check is the result of checking one component
+type Check struct {
+	Status  Status                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Healthy returns a passing check with optional details
+func Healthy(details map[string]interface{}) Check {
+	return Check{Status: StatusHealthy, Details: details}
+}
+
+// Degraded returns a check that works but not as it should, such as a fallback in use
+func Degraded(err error, details map[string]interface{}) Check {
+	return Check{Status: StatusDegraded, Error: errorString(err), Details: details}
+}
+
+// Unhealthy returns a failing check
+func Unhealthy(err error, details map[string]interface{}) Check {
+	return Check{Status: StatusUnhealthy, Error: errorString(err), Details: details}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Checker checks one component when /health is requested; it should return quickly
+type Checker func(ctx context.Context) Check
+
+// Response is the body of GET /health
+type Response struct {
+	Status  Status           `json:"status"`
+	Service string           `json:"service"`
+	Version string           `json:"version"`
+	Checks  map[string]Check `json:"checks"`
+}
+
+// Handler serves GET /health for the service the binary was built as, with the service
+// name and version from pkg/buildinfo
+func Handler(checks map[string]Checker) gin.HandlerFunc {
+	info := buildinfo.Get()
+	return ServiceHandler(info.Service, info.Version, checks)
+}
+
+// ServiceHandler serves GET /health for a named service, for demos that run several
+// services in one binary or read their name from configuration. An empty service falls
+// back to the binary's name.
+func ServiceHandler(service, version string, checks map[string]Checker) gin.HandlerFunc {
+	if service == "" {
+		service = filepath.Base(os.Args[0])
+	}
+	return func(c *gin.Context) {
+		resp := Run(c.Request.Context(), checks)
+		resp.Service, resp.Version = service, version
+		code := http.StatusOK
+		if resp.Status == StatusUnhealthy {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, resp)
+	}
+}
+
+// Run runs every check and returns the response without the service and version
+func Run(ctx context.Context, checks map[string]Checker) Response {
+	resp := Response{Status: StatusHealthy, Checks: make(map[string]Check, len(checks))}
+	for name, checker := range checks {
+		check := checker(ctx)
+		if check.Status == "" {
+			check.Status = StatusHealthy
+		}
+		resp.Checks[name] = check
+		if check.Status.rank() > resp.Status.rank() {
+			resp.Status = check.Status
+		}
+	}
+	return resp
+}
+
+var (
+	schemaOnce sync.Once
+	schema     *jsonschema.Schema
+)
+
+func compiled() *jsonschema.Schema {
+	schemaOnce.Do(func() {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(Schema))
+		if err != nil {
+			panic("health: embedded schema: " + err.Error())
+		}
+		c := jsonschema.NewCompiler()
+		if err := c.AddResource(SchemaURL, doc); err != nil {
+			panic("health: embedded schema: " + err.Error())
+		}
+		schema = c.MustCompile(SchemaURL)
+	})
+	return schema
+}
+
+// Validate checks a /health response body against the schema and returns what is wrong
+// with it, sorted; nil means it satisfies the contract
+func Validate(body []byte) []string {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return []string{"not JSON: " + err.Error()}
+	}
+	err = compiled().Validate(doc)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		if location := strings.TrimPrefix(unit.InstanceLocation, "/"); location != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", location, unit.Error))
+		} else {
+			problems = append(problems, unit.Error.String())
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serve(t *testing.T, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if problems := Validate(w.Body.Bytes()); problems != nil {
+		t.Errorf("response breaks the schema: %v\n%s", problems, w.Body)
+	}
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	w := serve(t, ServiceHandler("", "", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != StatusHealthy || resp.Service == "" || resp.Checks == nil {
+		t.Errorf("response = %+v; want healthy, the binary's name and empty checks", resp)
+	}
+}
+
+func TestHandlerWorstCheckWins(t *testing.T) {
+	checks := map[string]Checker{
+		"store": func(context.Context) Check { return Healthy(map[string]interface{}{"events": 3}) },
+		"cache": func(context.Context) Check { return Degraded(errors.New("using local copy"), nil) },
+	}
+	w := serve(t, ServiceHandler("orders", "v1.0.0", checks))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"degraded"`) {
+		t.Errorf("degraded check: %d %s", w.Code, w.Body)
+	}
+
+	checks["queue"] = func(context.Context) Check { return Unhealthy(errors.New("connection refused"), nil) }
+	w = serve(t, ServiceHandler("orders", "v1.0.0", checks))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unhealthy check: status = %d, want 503", w.Code)
+	}
+	var resp Response
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Status != StatusUnhealthy || resp.Checks["queue"].Error != "connection refused" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"status":"healthy"}`: "missing properties",
+		`{"status":"ok","service":"gin-demo","version":"","checks":{}}`:                              "status",
+		`{"status":"healthy","service":"gin-demo","version":"","checks":{},"stored_events":3}`:       "additional properties",
+		`{"status":"healthy","service":"gin-demo","version":"","checks":{"db":{"state":"healthy"}}}`: "checks/db",
+		`not json`: "not JSON",
+	} {
+		problems := Validate([]byte(body))
+		if len(problems) == 0 || !strings.Contains(strings.Join(problems, "; "), want) {
+			t.Errorf("Validate(%s) = %v, want a problem mentioning %q", body, problems, want)
+		}
+	}
+	if problems := Validate([]byte(`{"status":"healthy","service":"gin-demo","version":"v1","checks":{}}`)); problems != nil {
+		t.Errorf("valid body: %v", problems)
+	}
+}
@@ -0,0 +1,155 @@
+// Package hostmeta describes the host and process a demo runs in, for InitialFields.
+//
+// Hostname, addresses, platform and runtime facts were read ad hoc in each demo, often with
+// made-up fallbacks such as "container-abc123". Get reads them once, with no invented
+// values: a field that cannot be determined is left empty and omitted from Fields.
+package hostmeta
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// startTime approximates process start; the package is initialised before main runs
+var startTime = time.Now()
+
+// Metadata describes the host and the current process
+type Metadata struct {
+	Hostname    string    `json:"hostname"`
+	IPs         []string  `json:"ips,omitempty"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	NumCPU      int       `json:"num_cpu"`
+	GoVersion   string    `json:"go_version"`
+	PID         int       `json:"pid"`
+	StartTime   time.Time `json:"start_time"`
+	ContainerID string    `json:"container_id,omitempty"`
+}
+
+var get = sync.OnceValue(func() Metadata {
+	hostname, _ := os.Hostname()
+	return Metadata{
+		Hostname:    hostname,
+		IPs:         primaryIPs(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		NumCPU:      runtime.NumCPU(),
+		GoVersion:   runtime.Version(),
+		PID:         os.Getpid(),
+		StartTime:   startTime.UTC(),
+		ContainerID: containerID(),
+	}
+})
+
+// Get returns the host and process metadata, read on first use
+func Get() Metadata {
+	return get()
+}
+
+// Fields returns Get's result as InitialFields
+func Fields() map[string]interface{} {
+	return Get().Fields()
+}
+
+// InstanceID identifies this process among replicas as hostname-pid, the value the demos
+// use for service.instance.id
+func InstanceID() string {
+	m := Get()
+	return fmt.Sprintf("%s-%d", m.Hostname, m.PID)
+}
+
+// Fields returns the non-empty values under OpenTelemetry resource keys
+func (m Metadata) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"os.type":                 m.OS,
+		"host.arch":               m.Arch,
+		"host.cpu.count":          m.NumCPU,
+		"process.pid":             m.PID,
+		"process.runtime.version": m.GoVersion,
+		"process.creation.time":   m.StartTime.Format(time.RFC3339),
+	}
+	if m.Hostname != "" {
+		fields["host.name"] = m.Hostname
+	}
+	if len(m.IPs) > 0 {
+		fields["host.ip"] = m.IPs
+	}
+	if m.ContainerID != "" {
+		fields["container.id"] = m.ContainerID
+	}
+	return fields
+}
+
+// primaryIPs lists the unicast addresses of interfaces that are up, IPv4 first, skipping
+// loopback and link-local addresses nobody else can reach
+func primaryIPs() []string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var v4, v6 []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip4 := ipNet.IP.To4(); ip4 != nil {
+				v4 = append(v4, ip4.String())
+			} else {
+				v6 = append(v6, ipNet.IP.String())
+			}
+		}
+	}
+	return append(v4, v6...)
+}
+
+// containerIDPattern matches the 64 hex digit ID container runtimes put in cgroup paths
+// and mount sources, e.g. /docker/<id> or cri-containerd-<id>.scope
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerID reads the ID of the enclosing container from /proc. cgroup v1 names it in
+// /proc/self/cgroup; under cgroup v2 that file only holds "0::/", but the runtime's bind
+// mount of /etc/hostname still comes from a directory named after it. Only that mount is
+// considered, since a host's own mountinfo lists overlay layers with IDs of the same shape.
+func containerID() string {
+	if id := findContainerID("/proc/self/cgroup", ""); id != "" {
+		return id
+	}
+	return findContainerID("/proc/self/mountinfo", " /etc/hostname ")
+}
+
+// findContainerID returns the first ID on a line of path that contains marker
+func findContainerID(path, marker string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, marker) {
+			continue
+		}
+		if id := containerIDPattern.FindString(line); id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package hostmeta
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	m := Get()
+	if m.OS != runtime.GOOS || m.Arch != runtime.GOARCH || m.GoVersion != runtime.Version() {
+		t.Errorf("platform = %s/%s %s", m.OS, m.Arch, m.GoVersion)
+	}
+	if m.PID != os.Getpid() || m.NumCPU < 1 {
+		t.Errorf("process = pid %d, %d CPUs", m.PID, m.NumCPU)
+	}
+	if m.StartTime.IsZero() || m.StartTime.After(time.Now()) {
+		t.Errorf("StartTime = %v", m.StartTime)
+	}
+	for _, ip := range m.IPs {
+		if strings.HasPrefix(ip, "127.") || ip == "::1" || strings.HasPrefix(ip, "fe80:") {
+			t.Errorf("IPs contains loopback or link-local address %s", ip)
+		}
+	}
+	if want := m.Hostname + "-" + strconv.Itoa(m.PID); InstanceID() != want {
+		t.Errorf("InstanceID() = %q, want %q", InstanceID(), want)
+	}
+}
+
+func TestFields(t *testing.T) {
+	m := Metadata{
+		Hostname:  "web-1",
+		IPs:       []string{"10.0.0.5"},
+		OS:        "linux",
+		Arch:      "amd64",
+		NumCPU:    4,
+		GoVersion: "go1.25.0",
+		PID:       42,
+		StartTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	fields := m.Fields()
+	if fields["host.name"] != "web-1" || fields["process.creation.time"] != "2025-01-02T03:04:05Z" || fields["host.cpu.count"] != 4 {
+		t.Errorf("Fields() = %v", fields)
+	}
+	if _, ok := fields["container.id"]; ok {
+		t.Error("empty container ID produced a field")
+	}
+}
+
+func TestFindContainerID(t *testing.T) {
+	id := strings.Repeat("3f2a9c", 10) + "abcd"
+	dir := t.TempDir()
+
+	cgroupV1 := filepath.Join(dir, "cgroup")
+	os.WriteFile(cgroupV1, []byte("12:pids:/docker/"+id+"\n11:cpu:/docker/"+id+"\n"), 0o600)
+	if got := findContainerID(cgroupV1, ""); got != id {
+		t.Errorf("cgroup v1: got %q", got)
+	}
+
+	layer := strings.Repeat("0", 64)
+	mountinfo := filepath.Join(dir, "mountinfo")
+	os.WriteFile(mountinfo, []byte(
+		"612 590 0:52 / / rw - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/"+layer+"\n"+
+			"630 612 254:1 /var/lib/docker/containers/"+id+"/hostname /etc/hostname rw - ext4 /dev/vda1 rw\n"), 0o600)
+	if got := findContainerID(mountinfo, " /etc/hostname "); got != id {
+		t.Errorf("mountinfo: got %q, want the /etc/hostname mount's ID", got)
+	}
+}
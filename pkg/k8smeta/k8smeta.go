@@ -0,0 +1,181 @@
+// Package k8smeta tells a process which pod, namespace and node it runs on, for InitialFields.
+//
+// Kubernetes hands this out through several channels and a deployment rarely wires up all of
+// them, so Detect reads each in turn and keeps the first value found for every field:
+//
+//  1. environment variables set from the downward API (POD_NAME, POD_NAMESPACE, NODE_NAME, ...)
+//  2. files of a downward API volume mounted at /etc/podinfo
+//  3. the mounted service account: its namespace file and the claims of its bound token,
+//     which name the pod, service account and, on recent clusters, the node
+//  4. the hostname, which is the pod name unless the pod sets hostname explicitly
+//
+// Nothing here talks to the API server; every source is a local read.
+package k8smeta
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Default locations of the downward API volume and the service account mount
+const (
+	DefaultDownwardAPIDir    = "/etc/podinfo"
+	DefaultServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// ErrNotInCluster is returned when the process shows no sign of running in a pod
+var ErrNotInCluster = errors.New("k8smeta: not running in a Kubernetes pod")
+
+// Metadata identifies the pod the process runs in
+type Metadata struct {
+	PodName        string `json:"pod_name"`
+	PodUID         string `json:"pod_uid,omitempty"`
+	PodIP          string `json:"pod_ip,omitempty"`
+	Namespace      string `json:"namespace"`
+	NodeName       string `json:"node_name,omitempty"`
+	ServiceAccount string `json:"service_account,omitempty"`
+	ClusterName    string `json:"cluster_name,omitempty"`
+}
+
+// Fields returns the non-empty values under OpenTelemetry resource keys, ready to merge
+// into InitialFields
+func (m Metadata) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"k8s.pod.name":            m.PodName,
+		"k8s.pod.uid":             m.PodUID,
+		"k8s.pod.ip":              m.PodIP,
+		"k8s.namespace.name":      m.Namespace,
+		"k8s.node.name":           m.NodeName,
+		"k8s.serviceaccount.name": m.ServiceAccount,
+		"k8s.cluster.name":        m.ClusterName,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// Detector reads pod metadata from the environment and the filesystem. The zero value
+// uses the process environment and the default mount paths.
+type Detector struct {
+	// Getenv replaces os.Getenv, for tests
+	Getenv            func(string) string
+	DownwardAPIDir    string
+	ServiceAccountDir string
+}
+
+// Detect reads the metadata of the current pod with the default Detector
+func Detect() (Metadata, error) {
+	return Detector{}.Detect()
+}
+
+// Fields returns Detect's result as InitialFields, or an empty map outside a pod
+func Fields() map[string]interface{} {
+	meta, _ := Detect()
+	return meta.Fields()
+}
+
+// InCluster reports whether the process runs in a pod, judged by the API server address
+// the kubelet injects into every container
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// Detect merges every source, earlier sources winning per field
+func (d Detector) Detect() (Metadata, error) {
+	getenv := d.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	downward := orDefault(d.DownwardAPIDir, DefaultDownwardAPIDir)
+	serviceAccount := orDefault(d.ServiceAccountDir, DefaultServiceAccountDir)
+	claims := readTokenClaims(filepath.Join(serviceAccount, "token"))
+
+	meta := Metadata{
+		PodName: first(getenv("POD_NAME"), readFile(downward, "name"), claims.Pod.Name),
+		PodUID:  first(getenv("POD_UID"), readFile(downward, "uid"), claims.Pod.UID),
+		PodIP:   getenv("POD_IP"),
+		Namespace: first(getenv("POD_NAMESPACE"), readFile(downward, "namespace"),
+			readFile(serviceAccount, "namespace"), claims.Namespace),
+		NodeName:       first(getenv("NODE_NAME"), claims.Node.Name),
+		ServiceAccount: first(getenv("SERVICE_ACCOUNT"), claims.ServiceAccount.Name),
+		ClusterName:    getenv("CLUSTER_NAME"),
+	}
+
+	// Without any pod source this is a laptop or a VM, where the hostname is not a pod name
+	if meta.PodName == "" && meta.Namespace == "" && getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return Metadata{}, ErrNotInCluster
+	}
+	if meta.PodName == "" {
+		meta.PodName, _ = os.Hostname()
+	}
+	return meta, nil
+}
+
+// tokenClaims is the kubernetes.io section of a bound service account token
+type tokenClaims struct {
+	Namespace string `json:"namespace"`
+	Pod       struct {
+		Name string `json:"name"`
+		UID  string `json:"uid"`
+	} `json:"pod"`
+	Node struct {
+		Name string `json:"name"`
+	} `json:"node"`
+	ServiceAccount struct {
+		Name string `json:"name"`
+	} `json:"serviceaccount"`
+}
+
+// readTokenClaims decodes the token payload without verifying it; the kubelet wrote the
+// file, and the claims are only used as labels, never for authorization
+func readTokenClaims(path string) tokenClaims {
+	var claims struct {
+		Kubernetes tokenClaims `json:"kubernetes.io"`
+	}
+	token, err := os.ReadFile(path)
+	if err != nil {
+		return claims.Kubernetes
+	}
+	parts := strings.Split(strings.TrimSpace(string(token)), ".")
+	if len(parts) != 3 {
+		return claims.Kubernetes
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims.Kubernetes
+	}
+	_ = json.Unmarshal(payload, &claims)
+	return claims.Kubernetes
+}
+
+// readFile returns the trimmed content of dir/name, or "" when it cannot be read
+func readFile(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,105 @@
+package k8smeta
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// boundToken builds a token shaped like the kubelet's; the signature is never checked
+func boundToken(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".c2ln"
+}
+
+func TestDetectFromServiceAccount(t *testing.T) {
+	serviceAccount := writeFiles(t, map[string]string{
+		"namespace": "shop\n",
+		"token": boundToken(`{"kubernetes.io":{"namespace":"shop","node":{"name":"kind-worker"},` +
+			`"pod":{"name":"orders-7d9f-abcde","uid":"3f1c"},"serviceaccount":{"name":"orders"}}}`),
+	})
+
+	meta, err := Detector{
+		Getenv:            env(map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}),
+		DownwardAPIDir:    t.TempDir(),
+		ServiceAccountDir: serviceAccount,
+	}.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{PodName: "orders-7d9f-abcde", PodUID: "3f1c", Namespace: "shop", NodeName: "kind-worker", ServiceAccount: "orders"}
+	if meta != want {
+		t.Errorf("Detect() = %+v, want %+v", meta, want)
+	}
+}
+
+func TestDetectPrecedence(t *testing.T) {
+	downward := writeFiles(t, map[string]string{"name": "from-file", "namespace": "file-ns", "uid": "file-uid"})
+	serviceAccount := writeFiles(t, map[string]string{"namespace": "sa-ns"})
+
+	meta, err := Detector{
+		Getenv:            env(map[string]string{"POD_NAME": "from-env", "NODE_NAME": "node-1", "POD_IP": "10.1.2.3", "CLUSTER_NAME": "prod"}),
+		DownwardAPIDir:    downward,
+		ServiceAccountDir: serviceAccount,
+	}.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{PodName: "from-env", PodUID: "file-uid", PodIP: "10.1.2.3", Namespace: "file-ns", NodeName: "node-1", ClusterName: "prod"}
+	if meta != want {
+		t.Errorf("Detect() = %+v, want %+v", meta, want)
+	}
+
+	fields := meta.Fields()
+	if fields["k8s.pod.name"] != "from-env" || fields["k8s.namespace.name"] != "file-ns" || fields["k8s.cluster.name"] != "prod" {
+		t.Errorf("Fields() = %v", fields)
+	}
+	if _, ok := fields["k8s.serviceaccount.name"]; ok {
+		t.Error("empty service account produced a field")
+	}
+}
+
+func TestDetectOutsideCluster(t *testing.T) {
+	_, err := Detector{
+		Getenv:            env(nil),
+		DownwardAPIDir:    t.TempDir(),
+		ServiceAccountDir: t.TempDir(),
+	}.Detect()
+	if !errors.Is(err, ErrNotInCluster) {
+		t.Fatalf("Detect() err = %v, want ErrNotInCluster", err)
+	}
+}
+
+func TestDetectHostnameFallback(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	meta, err := Detector{
+		Getenv:            env(map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}),
+		DownwardAPIDir:    t.TempDir(),
+		ServiceAccountDir: t.TempDir(),
+	}.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.PodName != hostname {
+		t.Errorf("PodName = %q, want hostname %q", meta.PodName, hostname)
+	}
+}
@@ -0,0 +1,276 @@
+// Package leakwatch is a goroutine leak watchdog for long-running services.
+//
+// It snapshots the goroutine count and stacks every interval. When the count has grown at
+// every sample across a window and by at least MinGrowth overall, it logs a "Goroutine
+// count growing" warning with the stack signatures that grew the most, so the leaking call
+// site is in the log next to the symptom. A burst that settles back down never warns.
+//
+// Demos call FromEnv after creating their logger; GOROUTINE_WATCH_INTERVAL=10s turns the
+// watchdog on.
+package leakwatch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+const (
+	// EnvInterval is the sampling interval FromEnv reads; empty or 0 disables the watchdog
+	EnvInterval = "GOROUTINE_WATCH_INTERVAL"
+	// EnvWindow overrides Config.Window
+	EnvWindow = "GOROUTINE_WATCH_WINDOW"
+	// EnvMinGrowth overrides Config.MinGrowth
+	EnvMinGrowth = "GOROUTINE_WATCH_MIN_GROWTH"
+)
+
+// Config controls how much growth counts as a leak
+type Config struct {
+	// Interval between snapshots
+	Interval time.Duration
+	// Window is the number of consecutive snapshots the count must grow across, 6 by default
+	Window int
+	// MinGrowth is the least overall growth across the window that warns, 10 by default
+	MinGrowth int
+	// Top is the number of stack signatures in a warning, 5 by default
+	Top int
+	// Frames is the number of non-runtime frames in a signature, 3 by default
+	Frames int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window < 2 {
+		c.Window = 6
+	}
+	if c.MinGrowth <= 0 {
+		c.MinGrowth = 10
+	}
+	if c.Top <= 0 {
+		c.Top = 5
+	}
+	if c.Frames <= 0 {
+		c.Frames = 3
+	}
+	return c
+}
+
+// Snapshot is the goroutine count and the number of goroutines per stack signature
+type Snapshot struct {
+	At         time.Time
+	Goroutines int
+	Stacks     map[string]int
+}
+
+// Offender is a stack signature whose goroutine count grew across the window
+type Offender struct {
+	Signature string `json:"signature"`
+	Count     int    `json:"count"`
+	Growth    int    `json:"growth"`
+}
+
+// Watchdog keeps the snapshots of the current window
+type Watchdog struct {
+	logger core.Logger
+	cfg    Config
+	window []Snapshot
+}
+
+// New returns a Watchdog; zero Config fields take their defaults
+func New(logger core.Logger, cfg Config) *Watchdog {
+	return &Watchdog{logger: logger, cfg: cfg.withDefaults()}
+}
+
+// Take snapshots the goroutines of this process
+func Take(frames int) Snapshot {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	snap := Snapshot{At: time.Now(), Stacks: Signatures(buf, frames)}
+	for _, n := range snap.Stacks {
+		snap.Goroutines += n
+	}
+	return snap
+}
+
+// Signatures groups a runtime.Stack(buf, true) dump by signature: the first frames outside
+// the runtime package, innermost first, and the function that started the goroutine.
+// Arguments, addresses and goroutine IDs are dropped so identical goroutines group together.
+func Signatures(dump []byte, frames int) map[string]int {
+	stacks := map[string]int{}
+	for _, g := range bytes.Split(dump, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(g)), "\n")
+		if len(lines) == 0 || !strings.HasPrefix(lines[0], "goroutine ") {
+			continue
+		}
+		var funcs []string
+		createdBy := ""
+		for _, line := range lines[1:] {
+			if line == "" || line[0] == '\t' {
+				continue
+			}
+			if rest, ok := strings.CutPrefix(line, "created by "); ok {
+				createdBy, _, _ = strings.Cut(rest, " in goroutine ")
+				continue
+			}
+			fn := funcName(line)
+			if strings.HasPrefix(fn, "runtime.") || len(funcs) == frames {
+				continue
+			}
+			funcs = append(funcs, fn)
+		}
+		sig := strings.Join(funcs, " < ")
+		if sig == "" {
+			sig = funcName(lines[1])
+		}
+		if createdBy != "" {
+			sig += " [created by " + createdBy + "]"
+		}
+		stacks[sig]++
+	}
+	return stacks
+}
+
+// funcName strips the argument list from a stack frame line
+func funcName(line string) string {
+	if i := strings.LastIndex(line, "("); i > 0 && strings.HasSuffix(line, ")") {
+		return line[:i]
+	}
+	return line
+}
+
+// Observe adds a snapshot to the window and reports the offenders when the count grew at
+// every snapshot in a full window by at least MinGrowth. The window restarts after a
+// report, so a steady leak warns once per window rather than at every snapshot.
+func (w *Watchdog) Observe(snap Snapshot) ([]Offender, bool) {
+	if n := len(w.window); n > 0 && snap.Goroutines <= w.window[n-1].Goroutines {
+		// Growth has to be monotonic; any dip starts a new window
+		w.window = w.window[:0]
+	}
+	w.window = append(w.window, snap)
+	if len(w.window) < w.cfg.Window {
+		return nil, false
+	}
+	first := w.window[0]
+	w.window = w.window[len(w.window)-w.cfg.Window+1:]
+	if snap.Goroutines-first.Goroutines < w.cfg.MinGrowth {
+		return nil, false
+	}
+	w.window = nil
+
+	var offenders []Offender
+	for sig, count := range snap.Stacks {
+		if growth := count - first.Stacks[sig]; growth > 0 {
+			offenders = append(offenders, Offender{Signature: sig, Count: count, Growth: growth})
+		}
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Growth != offenders[j].Growth {
+			return offenders[i].Growth > offenders[j].Growth
+		}
+		return offenders[i].Signature < offenders[j].Signature
+	})
+	if len(offenders) > w.cfg.Top {
+		offenders = offenders[:w.cfg.Top]
+	}
+	return offenders, true
+}
+
+// Check takes a snapshot and logs a warning when it completes a window of growth
+func (w *Watchdog) Check() {
+	snap := Take(w.cfg.Frames)
+	start := snap
+	if len(w.window) > 0 {
+		start = w.window[0]
+	}
+	offenders, leaking := w.Observe(snap)
+	if !leaking {
+		return
+	}
+	w.logger.Warnw("Goroutine count growing",
+		"goroutines", snap.Goroutines,
+		"growth", snap.Goroutines-start.Goroutines,
+		"window_seconds", snap.At.Sub(start.At).Seconds(),
+		"samples", w.cfg.Window,
+		"top_stacks", offenders,
+	)
+}
+
+// Run checks every interval until ctx is done
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Start runs a Watchdog in the background and returns the function that stops it
+func Start(logger core.Logger, cfg Config) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(logger, cfg).Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// FromEnv starts a Watchdog when GOROUTINE_WATCH_INTERVAL is set and returns the function
+// that stops it, a no-op when the watchdog is off:
+//
+//	defer leakwatch.FromEnv(serviceLogger)()
+func FromEnv(logger core.Logger) func() {
+	value := os.Getenv(EnvInterval)
+	if value == "" {
+		return func() {}
+	}
+	logger = logger.With("component", "leakwatch")
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		logger.Warnw("Invalid goroutine watch interval, watchdog disabled", "env", EnvInterval, "value", value)
+		return func() {}
+	}
+	if interval == 0 {
+		return func() {}
+	}
+
+	cfg := Config{Interval: interval}
+	for env, field := range map[string]*int{EnvWindow: &cfg.Window, EnvMinGrowth: &cfg.MinGrowth} {
+		if value := os.Getenv(env); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				logger.Warnw("Invalid goroutine watch setting, using default", "env", env, "value", value)
+				continue
+			}
+			*field = n
+		}
+	}
+	cfg = cfg.withDefaults()
+	logger.Infow("Goroutine leak watchdog started",
+		"interval", interval.String(),
+		"window", cfg.Window,
+		"min_growth", cfg.MinGrowth,
+	)
+	return Start(logger, cfg)
+}
@@ -0,0 +1,121 @@
+package leakwatch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+const dump = `goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x1d
+
+goroutine 18 [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:402 +0xce
+runtime.chanrecv1(0xc000020180?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:442 +0x12
+main.(*server).wait(...)
+	/app/server.go:40
+main.(*server).handle.func1()
+	/app/server.go:31 +0x25
+created by main.(*server).handle in goroutine 7
+	/app/server.go:30 +0x6a
+
+goroutine 19 [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:402 +0xce
+main.(*server).wait(...)
+	/app/server.go:40
+main.(*server).handle.func1()
+	/app/server.go:31 +0x25
+created by main.(*server).handle in goroutine 9
+	/app/server.go:30 +0x6a
+`
+
+func TestSignatures(t *testing.T) {
+	got := Signatures([]byte(dump), 3)
+	want := map[string]int{
+		"main.main": 1,
+		"main.(*server).wait < main.(*server).handle.func1 [created by main.(*server).handle]": 2,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("signatures = %v", got)
+	}
+	for sig, n := range want {
+		if got[sig] != n {
+			t.Errorf("%q = %d, want %d (all: %v)", sig, got[sig], n, got)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	w := New(testlog.New(t).Logger, Config{Window: 3, MinGrowth: 4})
+	snap := func(leaked int) Snapshot {
+		return Snapshot{Goroutines: 10 + leaked, Stacks: map[string]int{"steady": 10, "leak": leaked}}
+	}
+
+	// A dip restarts the window
+	for _, n := range []int{0, 5, 3, 4} {
+		if _, leaking := w.Observe(snap(n)); leaking {
+			t.Fatalf("reported a leak at %d", n)
+		}
+	}
+	offenders, leaking := w.Observe(snap(8))
+	if !leaking || len(offenders) != 1 || offenders[0] != (Offender{Signature: "leak", Count: 8, Growth: 5}) {
+		t.Fatalf("offenders = %v, leaking = %v", offenders, leaking)
+	}
+	// The window restarts after a report
+	if _, leaking := w.Observe(snap(9)); leaking {
+		t.Error("reported again on the next snapshot")
+	}
+
+	// Slow growth slides the window without reporting
+	w = New(testlog.New(t).Logger, Config{Window: 3, MinGrowth: 4})
+	for n := 0; n < 6; n++ {
+		if _, leaking := w.Observe(snap(n)); leaking {
+			t.Fatalf("reported growth of 1 per snapshot at %d", n)
+		}
+	}
+}
+
+func TestCheckLogsLeakingStack(t *testing.T) {
+	rec := testlog.New(t)
+	w := New(rec.Logger, Config{Window: 3, MinGrowth: 10})
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		w.Check()
+		for j := 0; j < 10; j++ {
+			go leak(block)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Check()
+
+	entry := rec.AssertLogged("warn", "Goroutine count growing", "samples", 3)
+	top := entry["top_stacks"].([]interface{})[0].(map[string]interface{})
+	if sig := top["signature"].(string); !strings.Contains(sig, "leakwatch.leak") || top["growth"].(float64) < 20 {
+		t.Errorf("top offender = %v", top)
+	}
+}
+
+func leak(block chan struct{}) { <-block }
+
+func TestFromEnv(t *testing.T) {
+	rec := testlog.New(t)
+	t.Setenv(EnvInterval, "")
+	FromEnv(rec.Logger)()
+	if entries := rec.Entries(); len(entries) != 0 {
+		t.Fatalf("disabled watchdog logged %v", entries)
+	}
+
+	t.Setenv(EnvInterval, "1h")
+	t.Setenv(EnvWindow, "many")
+	t.Setenv(EnvMinGrowth, "50")
+	FromEnv(rec.Logger)()
+	rec.AssertLogged("warn", "Invalid goroutine watch setting", "env", EnvWindow, "component", "leakwatch")
+	rec.AssertLogged("info", "Goroutine leak watchdog started", "interval", "1h0m0s", "window", 6, "min_growth", 50)
+}
@@ -0,0 +1,247 @@
+// Package logbench measures what a log entry costs under different logger configurations.
+//
+// The demos pick an engine (zap or slog), a format (json or console), a set of
+// InitialFields and optionally OTLP export, mostly without knowing what each choice costs.
+// Matrix lists every combination and Measure writes the same representative entry through
+// each one, reporting entries per second and allocations per entry. Output goes to
+// os.DevNull by default so the numbers show the engine rather than the disk, and OTLP
+// configurations export to an in-process Sink so no collector is needed.
+package logbench
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// Engines and Formats are the values Matrix combines
+var (
+	Engines = []string{"zap", "slog"}
+	Formats = []string{"json", "console"}
+)
+
+// InitialFields is the service identity the demos attach to every entry
+var InitialFields = map[string]interface{}{
+	"service.name":           "logbench",
+	"service.version":        "v1.4.2",
+	"service.instance.id":    "logbench-7f9c4d",
+	"environment":            "production",
+	"deployment.environment": "production",
+	"host.name":              "ip-10-0-12-34",
+	"region":                 "eu-west-1",
+}
+
+// Config is one logger configuration to measure
+type Config struct {
+	Engine string
+	Format string
+	// InitialFields attaches InitialFields to the logger
+	InitialFields bool
+	// OTLP enables OTLP/HTTP export next to the regular output
+	OTLP bool
+}
+
+// Name identifies the configuration, e.g. zap/json+fields+otlp
+func (c Config) Name() string {
+	name := c.Engine + "/" + c.Format
+	if c.InitialFields {
+		name += "+fields"
+	}
+	if c.OTLP {
+		name += "+otlp"
+	}
+	return name
+}
+
+// Matrix returns every combination of Engines, Formats, InitialFields and OTLP
+func Matrix() []Config {
+	var configs []Config
+	for _, engine := range Engines {
+		for _, format := range Formats {
+			for _, fields := range []bool{false, true} {
+				for _, otlp := range []bool{false, true} {
+					configs = append(configs, Config{Engine: engine, Format: format, InitialFields: fields, OTLP: otlp})
+				}
+			}
+		}
+	}
+	return configs
+}
+
+// Option builds the LogOption for c, writing to output and, when c.OTLP is set, exporting
+// to endpoint over OTLP/HTTP
+func (c Config) Option(output, endpoint string) *option.LogOption {
+	opt := &option.LogOption{
+		Engine:      c.Engine,
+		Level:       "info",
+		Format:      c.Format,
+		OutputPaths: []string{output},
+	}
+	if c.InitialFields {
+		opt.InitialFields = InitialFields
+	}
+	if c.OTLP {
+		enabled := true
+		opt.OTLP = &option.OTLPOption{
+			Enabled:  &enabled,
+			Endpoint: endpoint,
+			Protocol: "http",
+			Timeout:  5 * time.Second,
+			Insecure: true,
+		}
+	}
+	return opt
+}
+
+// Entry writes the entry every configuration is measured with: a completed request with
+// the handful of fields a typical access log carries
+func Entry(l core.Logger, i int) {
+	l.Infow("Request completed",
+		"method", "GET",
+		"route", "/api/orders/:id",
+		"status", 200,
+		"duration_ms", 12.5,
+		"request_id", "req_01HZX3Q9J7K2M4N6P8R0T2V4W6",
+		"user_id", i,
+	)
+}
+
+// Result is the outcome of one Measure call
+type Result struct {
+	Config  Config
+	Entries int
+	Elapsed time.Duration
+	Allocs  uint64
+	Bytes   uint64
+}
+
+// PerSecond is the throughput in entries per second
+func (r Result) PerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Entries) / r.Elapsed.Seconds()
+}
+
+// NsPerEntry is the average time per entry
+func (r Result) NsPerEntry() float64 {
+	if r.Entries == 0 {
+		return 0
+	}
+	return float64(r.Elapsed.Nanoseconds()) / float64(r.Entries)
+}
+
+// AllocsPerEntry is the average number of heap allocations per entry
+func (r Result) AllocsPerEntry() float64 {
+	if r.Entries == 0 {
+		return 0
+	}
+	return float64(r.Allocs) / float64(r.Entries)
+}
+
+// BytesPerEntry is the average number of heap bytes allocated per entry
+func (r Result) BytesPerEntry() float64 {
+	if r.Entries == 0 {
+		return 0
+	}
+	return float64(r.Bytes) / float64(r.Entries)
+}
+
+// Measure writes n entries through a logger built from c and reports the time and
+// allocations they took. The final Flush is included, so configurations that batch, such
+// as OTLP export, are charged for what they queued. An empty output means os.DevNull.
+func Measure(c Config, n int, output, endpoint string) (Result, error) {
+	if output == "" {
+		output = os.DevNull
+	}
+	l, err := logger.New(c.Option(output, endpoint))
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", c.Name(), err)
+	}
+
+	// Warm up pools and buffers so the first entries do not skew the allocation count
+	for i := 0; i < 100; i++ {
+		Entry(l, i)
+	}
+	_ = l.Flush()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		Entry(l, i)
+	}
+	if err := l.Flush(); err != nil {
+		return Result{}, fmt.Errorf("%s: flush: %w", c.Name(), err)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Config:  c,
+		Entries: n,
+		Elapsed: elapsed,
+		Allocs:  after.Mallocs - before.Mallocs,
+		Bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// Sink is a minimal OTLP/HTTP receiver that accepts and discards every export request, so
+// OTLP configurations can be measured without a collector
+type Sink struct {
+	listener net.Listener
+	server   *http.Server
+	requests atomic.Int64
+	bytes    atomic.Int64
+}
+
+// StartSink listens on a free loopback port
+func StartSink() (*Sink, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{listener: listener}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.handle), ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = s.server.Serve(listener) }()
+	return s, nil
+}
+
+func (s *Sink) handle(w http.ResponseWriter, r *http.Request) {
+	n, _ := io.Copy(io.Discard, r.Body)
+	s.requests.Add(1)
+	s.bytes.Add(n)
+	// An empty protobuf body is a valid Export*ServiceResponse with no partial success
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Addr is the host:port to use as the OTLP endpoint
+func (s *Sink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Requests is the number of export requests received so far
+func (s *Sink) Requests() int64 {
+	return s.requests.Load()
+}
+
+// Bytes is the total size of the export requests received so far
+func (s *Sink) Bytes() int64 {
+	return s.bytes.Load()
+}
+
+// Close stops the sink
+func (s *Sink) Close() error {
+	return s.server.Close()
+}
@@ -0,0 +1,113 @@
+package logbench
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/logger"
+)
+
+// BenchmarkLogger runs every configuration in the matrix:
+//
+//	go test -run '^$' -bench . -benchmem ./pkg/logbench
+//	go test -run '^$' -bench 'Logger/slog/json' -benchmem ./pkg/logbench
+func BenchmarkLogger(b *testing.B) {
+	sink, err := StartSink()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer sink.Close()
+
+	for _, c := range Matrix() {
+		b.Run(c.Name(), func(b *testing.B) {
+			l, err := logger.New(c.Option(os.DevNull, sink.Addr()))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				Entry(l, i)
+			}
+			if err := l.Flush(); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "entries/s")
+		})
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	configs := Matrix()
+	if len(configs) != 16 {
+		t.Fatalf("len(Matrix()) = %d, want 16", len(configs))
+	}
+	names := map[string]bool{}
+	for _, c := range configs {
+		names[c.Name()] = true
+	}
+	if len(names) != len(configs) {
+		t.Errorf("names are not unique: %v", names)
+	}
+	if !names["zap/json"] || !names["slog/console+fields+otlp"] {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
+
+func TestOption(t *testing.T) {
+	opt := Config{Engine: "slog", Format: "json"}.Option("out.log", "127.0.0.1:4318")
+	if opt.OTLP != nil || opt.InitialFields != nil || opt.OutputPaths[0] != "out.log" {
+		t.Errorf("plain option = %+v", opt)
+	}
+
+	opt = Config{Engine: "zap", Format: "console", InitialFields: true, OTLP: true}.Option("out.log", "127.0.0.1:4318")
+	if opt.OTLP == nil || opt.OTLP.Enabled == nil || !*opt.OTLP.Enabled || opt.OTLP.Endpoint != "127.0.0.1:4318" {
+		t.Errorf("OTLP option = %+v", opt.OTLP)
+	}
+	if opt.InitialFields["service.name"] != "logbench" {
+		t.Errorf("InitialFields = %v", opt.InitialFields)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bench.log")
+	c := Config{Engine: "zap", Format: "json", InitialFields: true}
+	result, err := Measure(c, 50, output, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entries != 50 || result.Elapsed <= 0 || result.PerSecond() <= 0 || result.AllocsPerEntry() <= 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 100 warm-up entries plus the measured ones, each with the initial fields
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 150 || !strings.Contains(lines[149], `"service.name":"logbench"`) {
+		t.Errorf("wrote %d lines, last %s", len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestSinkAcceptsExports(t *testing.T) {
+	sink, err := StartSink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	resp, err := http.Post("http://"+sink.Addr()+"/v1/logs", "application/x-protobuf", bytes.NewReader(make([]byte, 64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || sink.Requests() != 1 || sink.Bytes() != 64 {
+		t.Errorf("status = %d, requests = %d, bytes = %d", resp.StatusCode, sink.Requests(), sink.Bytes())
+	}
+}
@@ -0,0 +1,53 @@
+package logcontext
+
+import (
+	"context"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Helper returns the logger of ctx for a function that logs on behalf of its caller, so
+// entries report the caller's file:line instead of the helper's. skip is the number of
+// helper frames between the logging call and the line that should be reported: 1 for a
+// helper called directly, 2 for a helper called through another, and so on. Like
+// FromContext it returns nil when ctx carries no logger and no default is set.
+//
+//	func rejectOrder(ctx context.Context, orderID, reason string) {
+//		logcontext.Helper(ctx, 1).Warnw("Order rejected", "order_id", orderID, "reason", reason)
+//	}
+func Helper(ctx context.Context, skip int) core.Logger {
+	logger := FromContext(ctx)
+	if logger == nil || skip == 0 {
+		return logger
+	}
+	return logger.WithCallerSkip(skip)
+}
+
+// Debugw logs through the logger of ctx, reporting the line that called Debugw. It does
+// nothing when ctx carries no logger and no default is set.
+func Debugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if logger := Helper(ctx, 1); logger != nil {
+		logger.Debugw(msg, keysAndValues...)
+	}
+}
+
+// Infow is Debugw at info level
+func Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if logger := Helper(ctx, 1); logger != nil {
+		logger.Infow(msg, keysAndValues...)
+	}
+}
+
+// Warnw is Debugw at warn level
+func Warnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if logger := Helper(ctx, 1); logger != nil {
+		logger.Warnw(msg, keysAndValues...)
+	}
+}
+
+// Errorw is Debugw at error level
+func Errorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if logger := Helper(ctx, 1); logger != nil {
+		logger.Errorw(msg, keysAndValues...)
+	}
+}
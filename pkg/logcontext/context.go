@@ -0,0 +1,62 @@
+// Package logcontext carries a request-scoped logger in context.Context, so handlers,
+// workers and the helpers they call log with the request's fields without having the
+// logger threaded through every signature or captured in closures.
+//
+// Middleware derives the logger once per request (request_id, trace_id, user, ...) and
+// stores it with WithLogger or one of the gin/gRPC adapters; everything downstream calls
+// FromContext. Helpers that log on behalf of their caller take the logger from Helper
+// instead, so entries keep the caller's file:line.
+package logcontext
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kart-io/logger/core"
+)
+
+type loggerKey struct{}
+
+var (
+	mu            sync.RWMutex
+	defaultLogger core.Logger
+)
+
+// SetDefault sets the logger FromContext returns when ctx carries none, typically the
+// service's base logger. Until it is called, FromContext returns nil for such contexts.
+func SetDefault(logger core.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLogger = logger
+}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger core.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger if there is none
+func FromContext(ctx context.Context) core.Logger {
+	if logger, ok := Lookup(ctx); ok {
+		return logger
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLogger
+}
+
+// Lookup returns the logger stored in ctx and whether there was one
+func Lookup(ctx context.Context) (core.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(core.Logger)
+	return logger, ok && logger != nil
+}
+
+// With returns a copy of ctx whose logger also carries keyValues, for fields learned
+// partway through a request such as an order ID
+func With(ctx context.Context, keyValues ...interface{}) context.Context {
+	logger := FromContext(ctx)
+	if logger == nil || len(keyValues) == 0 {
+		return ctx
+	}
+	return WithLogger(ctx, logger.With(keyValues...))
+}
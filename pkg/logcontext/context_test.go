@@ -0,0 +1,167 @@
+package logcontext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFromContextDefault(t *testing.T) {
+	rec := testlog.New(t)
+	SetDefault(rec.Logger)
+	t.Cleanup(func() { SetDefault(nil) })
+
+	if _, ok := Lookup(context.Background()); ok {
+		t.Fatal("Lookup found a logger in an empty context")
+	}
+	FromContext(context.Background()).Infow("Default logger used")
+	rec.AssertLogged("info", "Default logger used")
+}
+
+func TestWith(t *testing.T) {
+	rec := testlog.New(t)
+	ctx := WithLogger(context.Background(), rec.Logger.With("request_id", "req_1"))
+	ctx = With(ctx, "order_id", "ord-7")
+
+	FromContext(ctx).Warnw("Order held", "reason", "fraud_check")
+	rec.AssertLogged("warn", "Order held", "request_id", "req_1", "order_id", "ord-7", "reason", "fraud_check")
+}
+
+// thisLine returns the line it is called from, logged next to the entry to compare with
+// its caller field
+func thisLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestHelperReportsCaller(t *testing.T) {
+	rec := testlog.New(t)
+	ctx := WithLogger(context.Background(), rec.Logger)
+	rejectOrder := func(ctx context.Context, line int) {
+		Helper(ctx, 1).Warnw("Order rejected", "line", line)
+	}
+	retryOrder := func(ctx context.Context, line int) {
+		// A helper over a helper skips both
+		Helper(ctx, 2).Infow("Order retried", "line", line)
+	}
+	viaRetry := func(ctx context.Context, line int) { retryOrder(ctx, line) }
+
+	rejectOrder(ctx, thisLine())
+	viaRetry(ctx, thisLine())
+	Errorw(ctx, "Order failed", "line", thisLine())
+	for _, entry := range []testlog.Entry{
+		rec.AssertLogged("warn", "Order rejected"),
+		rec.AssertLogged("info", "Order retried"),
+		rec.AssertLogged("error", "Order failed"),
+	} {
+		want := fmt.Sprintf("logcontext/context_test.go:%v", entry["line"])
+		if caller, _ := entry["caller"].(string); !strings.HasSuffix(caller, want) {
+			t.Errorf("%s: caller %q, want %q", entry.Message(), caller, want)
+		}
+	}
+
+	// Without a logger the level helpers do nothing
+	Infow(context.Background(), "Dropped")
+	if Helper(context.Background(), 1) != nil {
+		t.Error("Helper returned a logger for an empty context")
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	r := gin.New()
+	r.Use(GinMiddleware(rec.Logger, func(c *gin.Context) []interface{} {
+		return []interface{}{"method", c.Request.Method, "route", c.FullPath()}
+	}))
+	r.GET("/orders/:id", func(c *gin.Context) {
+		// Helpers that only get the request context must see the same logger
+		FromContext(c.Request.Context()).Infow("Order loaded", "order_id", c.Param("id"))
+		c.Status(http.StatusNoContent)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+	rec.AssertLogged("info", "Order loaded", "method", "GET", "route", "/orders/:id", "order_id", "42")
+}
+
+func TestGinRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	r := gin.New()
+	r.Use(GinRequestLogger(rec.Logger))
+	r.GET("/orders/:id", func(c *gin.Context) {
+		MustFromGin(c).Infow("Order loaded", "order_id", c.Param("id"))
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set("X-Request-ID", "req_caller")
+	req.RemoteAddr = "10.0.0.7:51234"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	rec.AssertLogged("info", "Order loaded",
+		"request_id", "req_caller", "method", "GET", "route", "/orders/:id", "client_ip", "10.0.0.7", "order_id", "42")
+
+	// Without a caller's ID one is minted, and the response carries it
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/7", nil))
+	if id := w.Header().Get("X-Request-ID"); id == "" || rec.Count("info", "Order loaded", "request_id", id) != 1 {
+		t.Errorf("minted request ID %q not logged", id)
+	}
+}
+
+func TestMustFromGinWithoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	defer func() {
+		if recover() == nil {
+			t.Error("MustFromGin without the middleware did not panic")
+		}
+	}()
+	MustFromGin(c)
+}
+
+func TestCorrelated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := testlog.New(t)
+	access := testlog.New(t)
+	r := gin.New()
+	r.Use(GinRequestLogger(app.Logger), func(c *gin.Context) {
+		c.Next()
+		Correlated(c.Request.Context(), access.Logger).Infow("HTTP request", "status", c.Writer.Status())
+	})
+	r.GET("/orders/:id", func(c *gin.Context) {
+		// Stands in for a tracing middleware or traceparent.GinMiddleware
+		ctx := trace.ContextWithSpanContext(c.Request.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{0x4b, 0xf9},
+			SpanID:  trace.SpanID{0x00, 0xf0},
+		}))
+		c.Request = c.Request.WithContext(ctx)
+		MustFromGin(c).Infow("Order loaded")
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set("X-Request-ID", "req_caller")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	app.AssertLogged("info", "Order loaded", "request_id", "req_caller")
+	access.AssertLogged("info", "HTTP request",
+		"request_id", "req_caller",
+		"trace_id", "4bf90000000000000000000000000000",
+		"span_id", "00f0000000000000",
+		"status", http.StatusNoContent,
+	)
+
+	if got := Correlated(context.Background(), access.Logger); got != access.Logger {
+		t.Error("Correlated derived a logger without correlation fields")
+	}
+}
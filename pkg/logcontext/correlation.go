@@ -0,0 +1,35 @@
+package logcontext
+
+import (
+	"context"
+
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Correlation returns the fields that join the entries one request leaves in different log
+// streams, such as an access log and an application log written to separate files:
+// request_id, and trace_id and span_id when ctx carries a span context, whether from a
+// tracing middleware or an incoming traceparent header
+func Correlation(ctx context.Context) []interface{} {
+	fields := []interface{}{}
+	if id := requestid.FromContext(ctx); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields, "trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+	}
+	return fields
+}
+
+// Correlated returns a child of logger with the Correlation fields of ctx, for a logger
+// kept apart from the request logger, such as an access logger. The request logger of a
+// service that uses GinRequestLogger and traceparent.GinMiddleware carries the same
+// values, so entries in both streams join on request_id and trace_id.
+func Correlated(ctx context.Context, logger core.Logger) core.Logger {
+	if fields := Correlation(ctx); len(fields) > 0 {
+		return logger.With(fields...)
+	}
+	return logger
+}
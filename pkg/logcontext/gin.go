@@ -0,0 +1,81 @@
+package logcontext
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/logger/core"
+)
+
+// GinKey is the key the request-scoped logger is stored under with c.Set
+const GinKey = "logger"
+
+// GinFieldsFunc returns the fields the request-scoped logger should carry
+type GinFieldsFunc func(c *gin.Context) []interface{}
+
+// GinRequestFields are the fields every demo's request logger carries: request_id,
+// method, route and client_ip. The route is the registered pattern, such as
+// /orders/:id, or "unmatched" when no route matched.
+func GinRequestFields(c *gin.Context) []interface{} {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	return []interface{}{
+		"request_id", requestid.FromGin(c),
+		"method", c.Request.Method,
+		"route", route,
+		"client_ip", c.ClientIP(),
+	}
+}
+
+// GinRequestLogger is the standard request logger middleware: it gives the request an ID
+// when earlier middleware has not, derives a child of base with GinRequestFields and
+// stores it for MustFromGin. Install it ahead of the routes and of any middleware that
+// logs through the request logger.
+func GinRequestLogger(base core.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requestid.FromGin(c) == "" {
+			requestid.SetGin(c, requestid.OrNew(c.GetHeader(requestid.Header), requestid.Request))
+		}
+		SetGin(c, base.With(GinRequestFields(c)...))
+		c.Next()
+	}
+}
+
+// GinMiddleware derives a logger from base with the fields for each request and stores it
+// like GinRequestLogger. Middleware that computes its own logger, e.g. after extracting a
+// trace, can call SetGin instead.
+func GinMiddleware(base core.Logger, fields GinFieldsFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := base
+		if fields != nil {
+			if kv := fields(c); len(kv) > 0 {
+				logger = base.With(kv...)
+			}
+		}
+		SetGin(c, logger)
+		c.Next()
+	}
+}
+
+// SetGin stores logger for c's request, under GinKey for MustFromGin and in the request
+// context so code that only receives c.Request.Context() sees the same logger
+func SetGin(c *gin.Context, logger core.Logger) {
+	c.Set(GinKey, logger)
+	c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+}
+
+// MustFromGin returns the logger stored for c's request. It panics when none was, so a
+// route registered outside the request logger middleware fails on its first request
+// instead of logging without the request's fields.
+func MustFromGin(c *gin.Context) core.Logger {
+	return c.MustGet(GinKey).(core.Logger)
+}
+
+// FromGin returns the logger stored for c's request, or the default logger
+func FromGin(c *gin.Context) core.Logger {
+	if logger, ok := c.Get(GinKey); ok {
+		return logger.(core.Logger)
+	}
+	return FromContext(c.Request.Context())
+}
@@ -0,0 +1,47 @@
+package logcontext
+
+import (
+	"context"
+
+	"github.com/kart-io/logger/core"
+	"google.golang.org/grpc"
+)
+
+// FieldsFunc returns the fields the call-scoped logger should carry, read from the
+// incoming context (metadata, trace context, values set by earlier interceptors)
+type FieldsFunc func(ctx context.Context) []interface{}
+
+// UnaryServerInterceptor derives a logger from base for each call, tagged with the full
+// method name and the fields from fields, and stores it in the handler's context. Chain it
+// after interceptors that restore request IDs or traces so fields can see them.
+func UnaryServerInterceptor(base core.Logger, fields FieldsFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(WithLogger(ctx, callLogger(ctx, base, info.FullMethod, fields)), req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls
+func StreamServerInterceptor(base core.Logger, fields FieldsFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := WithLogger(ss.Context(), callLogger(ss.Context(), base, info.FullMethod, fields))
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func callLogger(ctx context.Context, base core.Logger, method string, fields FieldsFunc) core.Logger {
+	kv := []interface{}{"grpc.method", method}
+	if fields != nil {
+		kv = append(kv, fields(ctx)...)
+	}
+	return base.With(kv...)
+}
+
+// serverStream overrides Context so stream handlers see the logger
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,19 @@
+package logring
+
+import (
+	"time"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger whose entries are also kept in r, each with the fields added
+// with With. Fatal entries are kept before they are logged, as the wrapped logger exits
+// the process.
+func (r *Ring) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		Logged: func(e logwrap.Entry) {
+			r.Add(Entry{Time: time.Now(), Level: e.Level, Message: e.Message, Fields: fields(e.With, e.Fields)})
+		},
+	})
+}
@@ -0,0 +1,173 @@
+// Package logring keeps the most recent log entries in memory so an operator can look at
+// what a service just did through an HTTP endpoint, without access to its files or log
+// backend.
+//
+// A Ring is a fixed-size buffer; the loggers it wraps write every entry through as usual
+// and add a copy to the ring, the oldest being overwritten once it is full. Handler serves
+// the entries as JSON, optionally only those at or above a level:
+//
+//	GET /debug/logs?level=warn&limit=50
+//
+// The endpoint shows whatever was logged, so it belongs behind the same authentication as
+// the other admin routes, and the ring must wrap the logger underneath any redacting
+// wrapper so it only holds what was written out.
+package logring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries a Ring keeps when New is given zero
+const DefaultCapacity = 500
+
+// levels orders the level names; an entry's Level is one of them
+var levels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3, "fatal": 4}
+
+// Entry is one kept log entry
+type Entry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Ring holds the last entries written through the loggers it wraps; it is safe for
+// concurrent use
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	// next is where the next entry goes; total counts every entry ever added
+	next  int
+	total uint64
+}
+
+// New returns a Ring keeping capacity entries, DefaultCapacity when capacity is not positive
+func New(capacity int) *Ring {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Ring{entries: make([]Entry, 0, capacity)}
+}
+
+// Add keeps e, overwriting the oldest entry when the ring is full
+func (r *Ring) Add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, e)
+	} else {
+		r.entries[r.next] = e
+	}
+	r.next = (r.next + 1) % cap(r.entries)
+	r.total++
+}
+
+// Entries returns, oldest first, the last limit entries at or above level; an empty level
+// matches every entry and a limit that is not positive returns all that match
+func (r *Ring) Entries(level string, limit int) []Entry {
+	minLevel := levels[level]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Entry
+	// Walk from the newest back so limit keeps the most recent entries
+	for i := 0; i < len(r.entries) && (limit <= 0 || len(out) < limit); i++ {
+		e := r.entries[(r.next-1-i+2*len(r.entries))%len(r.entries)]
+		if levels[e.Level] >= minLevel {
+			out = append(out, e)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Stats returns how many entries the ring can hold and how many were ever added
+func (r *Ring) Stats() (capacity int, total uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return cap(r.entries), r.total
+}
+
+// Handler serves the entries as JSON. The level query parameter keeps entries at or above
+// it, limit the most recent ones; both are checked, an unknown level or a limit that is
+// not a positive number is a 400.
+func (r *Ring) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		level := req.URL.Query().Get("level")
+		if _, ok := levels[level]; level != "" && !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "level must be debug, info, warn, error or fatal"})
+			return
+		}
+		limit := 0
+		if value := req.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive number"})
+				return
+			}
+			limit = n
+		}
+
+		entries := r.Entries(level, limit)
+		if entries == nil {
+			entries = []Entry{}
+		}
+		capacity, total := r.Stats()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"capacity": capacity,
+			"total":    total,
+			"returned": len(entries),
+			"entries":  entries,
+		})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// fields copies log fields into a map the handler can encode: numbers, bools, strings and
+// times keep their type, errors and durations become text, and so does anything else that
+// is not a string slice, since a value kept for later must not change or fail to encode
+func fields(kvs ...[]interface{}) map[string]interface{} {
+	n := 0
+	for _, kv := range kvs {
+		n += len(kv) / 2
+	}
+	if n == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, n)
+	for _, kv := range kvs {
+		for i := 0; i+1 < len(kv); i += 2 {
+			m[fmt.Sprint(kv[i])] = value(kv[i+1])
+		}
+	}
+	return m
+}
+
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Time:
+		return v
+	case []string:
+		return append([]string(nil), v...)
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
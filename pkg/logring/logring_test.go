@@ -0,0 +1,126 @@
+package logring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func messages(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Message)
+	}
+	return out
+}
+
+func TestRing(t *testing.T) {
+	r := New(3)
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		level := "info"
+		if msg == "b" || msg == "d" {
+			level = "error"
+		}
+		r.Add(Entry{Level: level, Message: msg})
+	}
+
+	tests := []struct {
+		level string
+		limit int
+		want  string
+	}{
+		{"", 0, "[c d e]"},
+		{"", 2, "[d e]"},
+		{"warn", 0, "[d]"},
+		{"debug", 1, "[e]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(messages(r.Entries(tt.level, tt.limit))); got != tt.want {
+			t.Errorf("Entries(%q, %d) = %s, want %s", tt.level, tt.limit, got, tt.want)
+		}
+	}
+	if capacity, total := r.Stats(); capacity != 3 || total != 5 {
+		t.Errorf("Stats() = %d, %d, want 3, 5", capacity, total)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	rec := testlog.New(t)
+	r := New(10)
+	log := r.Wrap(rec.Logger).With("component", "orders")
+
+	log.Infow("Order created", "order_id", 42, "took", 150*time.Millisecond)
+	log.Errorw("Order failed", "error", errors.New("inventory unavailable"))
+	log.Warnf("retry %d", 2)
+
+	// Entries still reach the wrapped logger
+	rec.AssertLogged("info", "Order created", "component", "orders", "order_id", 42)
+	rec.AssertLogged("error", "Order failed")
+
+	entries := r.Entries("", 0)
+	if len(entries) != 3 {
+		t.Fatalf("kept %d entries, want 3", len(entries))
+	}
+	created := entries[0]
+	if created.Level != "info" || created.Fields["component"] != "orders" || created.Fields["order_id"] != 42 || created.Fields["took"] != "150ms" {
+		t.Errorf("kept %+v", created)
+	}
+	if got := entries[1].Fields["error"]; got != "inventory unavailable" {
+		t.Errorf("error field = %v, want the error text", got)
+	}
+	if entries[2].Level != "warn" || entries[2].Message != "retry 2" {
+		t.Errorf("kept %+v, want warn retry 2", entries[2])
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := New(10)
+	r.Add(Entry{Level: "info", Message: "Request completed"})
+	r.Add(Entry{Level: "warn", Message: "Slow query"})
+	r.Add(Entry{Level: "error", Message: "Checkout failed"})
+
+	tests := []struct {
+		target string
+		status int
+		want   string
+	}{
+		{"/debug/logs", http.StatusOK, "[Request completed Slow query Checkout failed]"},
+		{"/debug/logs?level=warn", http.StatusOK, "[Slow query Checkout failed]"},
+		{"/debug/logs?level=warn&limit=1", http.StatusOK, "[Checkout failed]"},
+		{"/debug/logs?level=fatal", http.StatusOK, "[]"},
+		{"/debug/logs?level=loud", http.StatusBadRequest, ""},
+		{"/debug/logs?limit=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var body struct {
+				Capacity int     `json:"capacity"`
+				Total    uint64  `json:"total"`
+				Entries  []Entry `json:"entries"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Capacity != 10 || body.Total != 3 || body.Entries == nil {
+				t.Errorf("body = %s", w.Body)
+			}
+			if got := fmt.Sprint(messages(body.Entries)); got != tt.want {
+				t.Errorf("entries = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
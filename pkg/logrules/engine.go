@@ -0,0 +1,222 @@
+package logrules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/kart-io/logger/core"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TriggeredMessage is the warning the log action writes
+const TriggeredMessage = "Log rule triggered"
+
+// Trigger is what a rule's actions receive, and the webhook body. The body carries every
+// field of the sample entry, With fields included, as the wrapped logger received them;
+// wrap a redacting logger around the Engine's, redact.Default().Wrap(engine.Wrap(base)),
+// to keep secrets and personal data out of it.
+type Trigger struct {
+	Rule      string    `json:"rule"`
+	Count     int       `json:"count"`
+	Threshold int       `json:"threshold"`
+	Window    string    `json:"window"`
+	FiredAt   time.Time `json:"fired_at"`
+	// Sample is the entry that reached the threshold
+	Sample Sample `json:"sample"`
+}
+
+// Sample is one matched entry
+type Sample struct {
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// ruleState holds the times of a rule's recent matches
+type ruleState struct {
+	rule    Rule
+	matches []time.Time
+	quiet   time.Time
+}
+
+// Engine evaluates rules against the entries written through the loggers it wraps; it is
+// safe for concurrent use
+type Engine struct {
+	logger core.Logger
+	clock  clock.Clock
+	client *http.Client
+
+	// minLevel is the lowest level any rule matches; lower entries are not evaluated
+	minLevel int
+
+	mu     sync.Mutex
+	states []*ruleState
+
+	triggers atomic.Uint64
+	counter  atomic.Pointer[prometheus.CounterVec]
+
+	// webhooks are the calls still in flight, waited for by Close
+	webhooks sync.WaitGroup
+}
+
+// New builds an Engine for cfg. logger receives the log action's warnings and webhook
+// failures; it must not be a logger the Engine wraps, or a rule matching warnings would
+// feed on its own output.
+func New(cfg Config, logger core.Logger) (*Engine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	e := &Engine{logger: logger, clock: clock.Real, client: &http.Client{}, minLevel: levels["fatal"] + 1}
+	for _, rule := range cfg.Rules {
+		e.minLevel = min(e.minLevel, levels[strings.ToLower(rule.Match.Level)])
+		if rule.Cooldown == 0 {
+			rule.Cooldown = rule.Window
+		}
+		e.states = append(e.states, &ruleState{rule: rule})
+	}
+	return e, nil
+}
+
+// Instrument counts the metric actions in go_example_log_rule_triggers_total, labelled by rule
+func (e *Engine) Instrument(reg *metrics.Registry) {
+	e.counter.Store(reg.Counter("log_rule_triggers_total", "Times a log rule reached its threshold.", "rule"))
+}
+
+// Triggers returns how many times any rule triggered
+func (e *Engine) Triggers() uint64 {
+	return e.triggers.Load()
+}
+
+// wants reports whether an entry at level can match any rule
+func (e *Engine) wants(level string) bool {
+	rank, ok := levels[level]
+	return ok && rank >= e.minLevel
+}
+
+// Observe evaluates one entry; level is debug, info, warn, error or fatal. The wrapping
+// loggers call it for every entry, other sources of entries can too.
+func (e *Engine) Observe(level, msg string, fields map[string]string) {
+	if !e.wants(level) {
+		return
+	}
+	rank := levels[level]
+	var fired []*ruleState
+	var triggers []Trigger
+	e.mu.Lock()
+	now := e.clock.Now()
+	for _, s := range e.states {
+		if !s.rule.Match.matches(rank, msg, fields) {
+			continue
+		}
+		s.matches = append(s.matches, now)
+		cutoff := now.Add(-s.rule.Window)
+		for len(s.matches) > 0 && !s.matches[0].After(cutoff) {
+			s.matches = s.matches[1:]
+		}
+		if len(s.matches) < s.rule.Threshold || now.Before(s.quiet) {
+			continue
+		}
+		fired = append(fired, s)
+		triggers = append(triggers, Trigger{
+			Rule:      s.rule.Name,
+			Count:     len(s.matches),
+			Threshold: s.rule.Threshold,
+			Window:    s.rule.Window.String(),
+			FiredAt:   now,
+			Sample:    Sample{Level: level, Message: msg, Fields: fields},
+		})
+		// The next trigger needs a full threshold of new matches after the cooldown
+		s.matches = nil
+		s.quiet = now.Add(s.rule.Cooldown)
+	}
+	e.mu.Unlock()
+
+	for i, s := range fired {
+		e.fire(s.rule.Actions, triggers[i])
+	}
+}
+
+// fire runs a rule's actions for t; webhooks are posted in the background so the entry
+// that triggered does not wait for them, except for a fatal entry: the process exits as
+// soon as it is logged, so its webhooks are posted before, each within its timeout
+func (e *Engine) fire(actions []Action, t Trigger) {
+	e.triggers.Add(1)
+	for _, action := range actions {
+		switch action.Type {
+		case ActionLog:
+			e.logger.Warnw(TriggeredMessage,
+				"rule", t.Rule,
+				"count", t.Count,
+				"threshold", t.Threshold,
+				"window", t.Window,
+				"sample_level", t.Sample.Level,
+				"sample_message", t.Sample.Message,
+			)
+		case ActionMetric:
+			if counter := e.counter.Load(); counter != nil {
+				counter.WithLabelValues(t.Rule).Inc()
+			}
+		case ActionWebhook:
+			if t.Sample.Level == "fatal" {
+				e.webhook(action, t)
+				continue
+			}
+			e.webhooks.Add(1)
+			go func(action Action) {
+				defer e.webhooks.Done()
+				e.webhook(action, t)
+			}(action)
+		}
+	}
+}
+
+// webhook posts t, logging a failure
+func (e *Engine) webhook(action Action, t Trigger) {
+	if err := e.post(action, t); err != nil {
+		e.logger.Warnw("Log rule webhook failed", "rule", t.Rule, "url", action.URL, "error", err.Error())
+	}
+}
+
+// post sends t to the webhook; any status other than 2xx is an error
+func (e *Engine) post(action Action, t Trigger) error {
+	timeout := action.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	body, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Close waits for the webhooks still in flight. Register it with crash.OnExit as well, so
+// an exit through crash.Fatal, which logs at error level, still delivers them.
+func (e *Engine) Close() {
+	e.webhooks.Wait()
+}
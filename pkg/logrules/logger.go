@@ -0,0 +1,30 @@
+package logrules
+
+import (
+	"fmt"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger whose entries e evaluates, fields added with With included so
+// rules can match them. Fatal entries are evaluated before they are logged, as the wrapped
+// logger exits the process.
+func (e *Engine) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{Logged: e.observe})
+}
+
+// observe hands one entry to the engine, converting the fields only when a rule can
+// match its level
+func (e *Engine) observe(entry logwrap.Entry) {
+	if !e.wants(entry.Level) {
+		return
+	}
+	fields := make(map[string]string, (len(entry.With)+len(entry.Fields))/2)
+	for _, kv := range [][]interface{}{entry.With, entry.Fields} {
+		for i := 0; i+1 < len(kv); i += 2 {
+			fields[fmt.Sprint(kv[i])] = fmt.Sprint(kv[i+1])
+		}
+	}
+	e.Observe(entry.Level, entry.Message, fields)
+}
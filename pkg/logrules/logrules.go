@@ -0,0 +1,164 @@
+// Package logrules alerts on a service's own log stream without shipping it anywhere first.
+//
+// Rules are read from YAML. Each one matches entries by level, message and field values and
+// triggers when Threshold matching entries arrive within Window; its actions then log a
+// warning, post a webhook or count the trigger in a Prometheus metric:
+//
+//	rules:
+//	  - name: error_burst
+//	    match:
+//	      level: error        # this level and above
+//	    threshold: 10
+//	    window: 30s
+//	    cooldown: 2m          # quiet period after triggering, the window when left out
+//	    actions:
+//	      - type: log
+//	      - type: metric
+//	      - type: webhook
+//	        url: https://hooks.example.com/log-alerts
+//
+// An Engine sees the entries written through the loggers it wraps. It is a local safety net
+// for a single process, not a replacement for alerting-demo style evaluation over the
+// aggregated stream.
+package logrules
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action types
+const (
+	// ActionLog logs TriggeredMessage with the rule and a sample entry
+	ActionLog = "log"
+	// ActionWebhook posts the Trigger, the sample entry's fields included, as JSON to URL
+	ActionWebhook = "webhook"
+	// ActionMetric counts the trigger in go_example_log_rule_triggers_total once the
+	// Engine is instrumented
+	ActionMetric = "metric"
+)
+
+// DefaultWebhookTimeout bounds a webhook call when Action.Timeout is zero
+const DefaultWebhookTimeout = 5 * time.Second
+
+// levels orders the level names a Match may use
+var levels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3, "fatal": 4}
+
+// Config is the YAML file
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule triggers when Threshold entries matching Match arrive within Window
+type Rule struct {
+	Name      string        `yaml:"name"`
+	Match     Match         `yaml:"match"`
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	// Cooldown is how long the rule stays quiet after triggering; Window when zero
+	Cooldown time.Duration `yaml:"cooldown"`
+	Actions  []Action      `yaml:"actions"`
+}
+
+// Match selects entries; an empty Match selects every entry
+type Match struct {
+	// Level is the lowest level matched: debug, info, warn, error or fatal
+	Level string `yaml:"level"`
+	// Message, when set, must equal the entry's message
+	Message string `yaml:"message"`
+	// Fields must all be present with these values, compared as text; fields added with
+	// With count as well
+	Fields map[string]string `yaml:"fields"`
+}
+
+// Action is what happens when a rule triggers
+type Action struct {
+	Type    string        `yaml:"type"`
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Load reads and validates a rules file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read log rules: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse log rules: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports the first rule that cannot be evaluated, by name
+func (cfg Config) Validate() error {
+	seen := map[string]bool{}
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rules[%d]: name is required", i)
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("rule %s: defined twice", rule.Name)
+		}
+		seen[rule.Name] = true
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	if _, ok := levels[strings.ToLower(r.Match.Level)]; r.Match.Level != "" && !ok {
+		return fmt.Errorf("match.level must be debug, info, warn, error or fatal, not %q", r.Match.Level)
+	}
+	if r.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("window must be a positive duration")
+	}
+	if r.Cooldown < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("no actions")
+	}
+	for _, action := range r.Actions {
+		switch action.Type {
+		case ActionLog, ActionMetric:
+		case ActionWebhook:
+			if u, err := url.Parse(action.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("webhook url must be an http or https URL, not %q", action.URL)
+			}
+		default:
+			return fmt.Errorf("action type must be %s, %s or %s, not %q", ActionLog, ActionWebhook, ActionMetric, action.Type)
+		}
+	}
+	return nil
+}
+
+// matches reports whether an entry at level with msg and fields is selected
+func (m Match) matches(level int, msg string, fields map[string]string) bool {
+	if m.Level != "" && level < levels[strings.ToLower(m.Level)] {
+		return false
+	}
+	if m.Message != "" && m.Message != msg {
+		return false
+	}
+	for key, want := range m.Fields {
+		if got, ok := fields[key]; !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
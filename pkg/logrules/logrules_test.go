@@ -0,0 +1,165 @@
+package logrules
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logrules.yaml")
+	os.WriteFile(path, []byte(`
+rules:
+  - name: error_burst
+    match:
+      level: error
+      fields: {route: /orders}
+    threshold: 5
+    window: 10s
+    actions:
+      - type: log
+      - type: webhook
+        url: http://localhost:9000/hooks
+`), 0o644)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := cfg.Rules[0]; rule.Window != 10*time.Second || rule.Match.Fields["route"] != "/orders" || rule.Actions[1].URL != "http://localhost:9000/hooks" {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	valid := Rule{Name: "error_burst", Threshold: 5, Window: time.Second, Actions: []Action{{Type: ActionLog}}}
+	for name, mutate := range map[string]func(*Rule){
+		"unknown level":   func(r *Rule) { r.Match.Level = "critical" },
+		"no threshold":    func(r *Rule) { r.Threshold = 0 },
+		"no window":       func(r *Rule) { r.Window = 0 },
+		"no actions":      func(r *Rule) { r.Actions = nil },
+		"unknown action":  func(r *Rule) { r.Actions = []Action{{Type: "page"}} },
+		"webhook without": func(r *Rule) { r.Actions = []Action{{Type: ActionWebhook}} },
+	} {
+		rule := valid
+		mutate(&rule)
+		if err := (Config{Rules: []Rule{rule}}).Validate(); err == nil || !strings.Contains(err.Error(), "error_burst") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if err := (Config{Rules: []Rule{valid, valid}}).Validate(); err == nil {
+		t.Error("duplicate rule names accepted")
+	}
+}
+
+func TestEngine(t *testing.T) {
+	rec := testlog.New(t)
+	diagnostics := testlog.New(t)
+	fake := clock.NewFake(time.Now())
+	e, err := New(Config{Rules: []Rule{{
+		Name:      "error_burst",
+		Match:     Match{Level: "warn", Fields: map[string]string{"route": "/orders"}},
+		Threshold: 3,
+		Window:    10 * time.Second,
+		Cooldown:  time.Minute,
+		Actions:   []Action{{Type: ActionLog}, {Type: ActionMetric}},
+	}}}, diagnostics.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.clock = fake
+	reg := metrics.New("orders")
+	e.Instrument(reg)
+	log := e.Wrap(rec.Logger).With("route", "/orders")
+
+	// Entries below the level, on other routes or outside the window do not count
+	log.Infow("Order created")
+	e.Wrap(rec.Logger).Errorw("Checkout failed", "route", "/checkout")
+	log.Errorw("Checkout failed")
+	fake.Advance(11 * time.Second)
+	log.Errorw("Checkout failed")
+	log.Warnw("Checkout retried")
+	if e.Triggers() != 0 {
+		t.Fatal("triggered before the threshold")
+	}
+	log.Errorw("Checkout failed", "status", 502)
+	diagnostics.AssertLogged("warn", TriggeredMessage, "rule", "error_burst", "count", 3, "threshold", 3, "window", "10s",
+		"sample_level", "error", "sample_message", "Checkout failed")
+	rec.AssertLogged("error", "Checkout failed", "route", "/orders", "status", 502)
+
+	// The cooldown holds back a second trigger, however many entries match
+	for range 5 {
+		log.Errorw("Checkout failed")
+	}
+	fake.Advance(time.Minute)
+	for range 3 {
+		log.Errorw("Checkout failed")
+	}
+	if n := diagnostics.Count("warn", TriggeredMessage); n != 2 || e.Triggers() != 2 {
+		t.Errorf("triggered %d times (logged %d), want twice", e.Triggers(), n)
+	}
+	counter := reg.Counter("log_rule_triggers_total", "Times a log rule reached its threshold.", "rule")
+	if n := testutil.ToFloat64(counter.WithLabelValues("error_burst")); n != 2 {
+		t.Errorf("go_example_log_rule_triggers_total = %v, want 2", n)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Trigger, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var trigger Trigger
+		json.NewDecoder(r.Body).Decode(&trigger)
+		received <- trigger
+	}))
+	defer hook.Close()
+	diagnostics := testlog.New(t)
+	e, err := New(Config{Rules: []Rule{
+		{Name: "fatal", Match: Match{Level: "error"}, Threshold: 1, Window: time.Second, Actions: []Action{{Type: ActionWebhook, URL: hook.URL}}},
+		{Name: "unreachable", Match: Match{Level: "error"}, Threshold: 1, Window: time.Second, Actions: []Action{{Type: ActionWebhook, URL: "http://127.0.0.1:1"}}},
+	}}, diagnostics.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Wrap(testlog.New(t).Logger).Errorw("Database unreachable", "attempt", 3)
+	e.Close()
+	trigger := <-received
+	if trigger.Rule != "fatal" || trigger.Count != 1 || trigger.Sample.Message != "Database unreachable" || trigger.Sample.Fields["attempt"] != "3" {
+		t.Errorf("webhook received %+v", trigger)
+	}
+	diagnostics.AssertLogged("warn", "Log rule webhook failed", "rule", "unreachable")
+}
+
+// TestFatalWebhook posts before Observe returns: the logger exits right after a fatal entry
+func TestFatalWebhook(t *testing.T) {
+	received := make(chan Trigger, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var trigger Trigger
+		json.NewDecoder(r.Body).Decode(&trigger)
+		received <- trigger
+	}))
+	defer hook.Close()
+	e, err := New(Config{Rules: []Rule{
+		{Name: "fatal", Match: Match{Level: "fatal"}, Threshold: 1, Window: time.Second, Actions: []Action{{Type: ActionWebhook, URL: hook.URL}}},
+	}}, testlog.New(t).Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Observe("fatal", "Database unreachable", nil)
+	select {
+	case trigger := <-received:
+		if trigger.Sample.Level != "fatal" {
+			t.Errorf("webhook received %+v", trigger)
+		}
+	default:
+		t.Fatal("fatal webhook still in flight after Observe returned")
+	}
+}
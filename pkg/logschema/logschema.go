@@ -0,0 +1,194 @@
+// Package logschema checks log lines against the JSON Schema every demo's output must
+// satisfy.
+//
+// The schema in log-entry.schema.json requires the fields a log pipeline relies on:
+// an RFC 3339 timestamp, a known level, the message and the service.name and
+// service.version resource fields, with service.name non-empty. It also fixes the type
+// of well-known fields whose shape dashboards and alert rules depend on, such as
+// trace_id, span_id, error and duration_ms, and leaves every other field free-form.
+//
+//	v := logschema.Default()
+//	for _, violation := range v.ValidateLine(line) {
+//		fmt.Println(violation)
+//	}
+//
+// cmd/e2e validates every line each demo writes; cmd/logschema does the same for any
+// output piped into it.
+package logschema
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Schema is the content of log-entry.schema.json
+//
+//go:embed log-entry.schema.json
+var Schema []byte
+
+// SchemaURL is the schema's $id
+const SchemaURL = "https://github.com/kart-io/go-example/pkg/logschema/log-entry.schema.json"
+
+// Violation is one way a line breaks the schema
+type Violation struct {
+	// Field is the offending field, or "" when the problem is with the entry as a whole,
+	// such as a missing required field
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+	return v.Field + ": " + v.Message
+}
+
+// Validator checks log lines against a compiled schema; it is safe for concurrent use
+type Validator struct {
+	schema *jsonschema.Schema
+}
+
+// New compiles a schema document. Formats such as date-time are asserted, not just
+// annotated.
+func New(schema []byte) (*Validator, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(SchemaURL, doc); err != nil {
+		return nil, fmt.Errorf("add schema: %w", err)
+	}
+	compiled, err := c.Compile(SchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema: %w", err)
+	}
+	return &Validator{schema: compiled}, nil
+}
+
+var (
+	defaultOnce      sync.Once
+	defaultValidator *Validator
+)
+
+// Default returns a Validator for the embedded Schema
+func Default() *Validator {
+	defaultOnce.Do(func() {
+		v, err := New(Schema)
+		if err != nil {
+			panic("logschema: embedded schema: " + err.Error())
+		}
+		defaultValidator = v
+	})
+	return defaultValidator
+}
+
+// ValidateLine checks one log line and returns what is wrong with it, sorted by field. A
+// line that is not a JSON object is a single violation.
+func (v *Validator) ValidateLine(line []byte) []Violation {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(line))
+	if err != nil {
+		return []Violation{{Message: "not JSON: " + err.Error()}}
+	}
+	err = v.schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []Violation{{Message: err.Error()}}
+	}
+
+	var violations []Violation
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		violations = append(violations, Violation{Field: field(unit.InstanceLocation), Message: unit.Error.String()})
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return violations
+}
+
+// field turns a JSON pointer such as /service.name into the field name, keeping the
+// slashes of nested values, e.g. shipping/carrier
+func field(location string) string {
+	if location == "" || location == "/" {
+		return ""
+	}
+	return jsonPointerUnescape(location[1:])
+}
+
+func jsonPointerUnescape(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && i+1 < len(s) {
+			switch s[i+1] {
+			case '0':
+				out = append(out, '~')
+				i++
+				continue
+			case '1':
+				out = append(out, '/')
+				i++
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// IsEntry reports whether a line of mixed output is a log entry: a JSON object with a
+// level or message. Banners, tables and pretty-printed payloads between entries are not.
+func IsEntry(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return false
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(line, &keys); err != nil {
+		return false
+	}
+	_, level := keys["level"]
+	_, message := keys["message"]
+	return level || message
+}
+
+// LineResult holds the violations found on one line of a stream
+type LineResult struct {
+	// Line is 1-based
+	Line       int
+	Violations []Violation
+}
+
+// Validate checks every log entry in r, skipping lines that are not entries. It returns
+// how many entries there were and the ones that failed.
+func (v *Validator) Validate(r io.Reader) (int, []LineResult, error) {
+	entries := 0
+	var failed []LineResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if !IsEntry(line) {
+			continue
+		}
+		entries++
+		if violations := v.ValidateLine(line); len(violations) > 0 {
+			failed = append(failed, LineResult{Line: n, Violations: violations})
+		}
+	}
+	return entries, failed, scanner.Err()
+}
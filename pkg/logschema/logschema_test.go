@@ -0,0 +1,105 @@
+package logschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+)
+
+const valid = `{"timestamp":"2024-05-01T10:00:00.123Z","level":"info","message":"Order paid","service.name":"shop","service.version":"v1.2.3",` +
+	`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","duration_ms":12.5,"order_id":42}`
+
+func TestValidateLine(t *testing.T) {
+	v := Default()
+	if violations := v.ValidateLine([]byte(valid)); len(violations) != 0 {
+		t.Fatalf("valid line: %v", violations)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"missing service.name", `{"timestamp":"2024-05-01T10:00:00Z","level":"info","message":"x","service.version":""}`, "missing property 'service.name'"},
+		{"empty service.name", `{"timestamp":"2024-05-01T10:00:00Z","level":"info","message":"x","service.name":"","service.version":""}`, "service.name: minLength"},
+		{"malformed timestamp", `{"timestamp":"01/05/2024 10:00","level":"info","message":"x","service.name":"a","service.version":""}`, "timestamp: '01/05/2024 10:00' is not valid date-time"},
+		{"numeric timestamp", `{"timestamp":1714557600,"level":"info","message":"x","service.name":"a","service.version":""}`, "timestamp: got number, want string"},
+		{"unknown level", `{"timestamp":"2024-05-01T10:00:00Z","level":"INFO","message":"x","service.name":"a","service.version":""}`, "level: value must be one of"},
+		{"short trace_id", `{"timestamp":"2024-05-01T10:00:00Z","level":"info","message":"x","service.name":"a","service.version":"","trace_id":"4bf92f35"}`, "trace_id: '4bf92f35' does not match pattern"},
+		{"error object", `{"timestamp":"2024-05-01T10:00:00Z","level":"error","message":"x","service.name":"a","service.version":"","error":{}}`, "error: got object, want string"},
+		{"not an object", `["info","x"]`, "got array, want object"},
+		{"not JSON", `level=info msg=x`, "not JSON"},
+	}
+	for _, tt := range tests {
+		violations := v.ValidateLine([]byte(tt.line))
+		if len(violations) != 1 || !strings.HasPrefix(violations[0].String(), tt.want) {
+			t.Errorf("%s: violations = %v, want one starting with %q", tt.name, violations, tt.want)
+		}
+	}
+}
+
+func TestValidateSkipsNonEntries(t *testing.T) {
+	output := strings.Join([]string{
+		"=== Secret Scan Demo ===",
+		valid,
+		"{",
+		`  "order_id": 42`,
+		"}",
+		`{"order_id":42,"status":"paid"}`,
+		`{"level":"warn","message":"No timestamp"}`,
+	}, "\n")
+
+	entries, failed, err := Default().Validate(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != 2 || len(failed) != 1 || failed[0].Line != 7 {
+		t.Errorf("entries = %d, failed = %+v; want 2 entries and line 7 failing", entries, failed)
+	}
+}
+
+// TestEnginesProduceValidEntries checks the real zap and slog output, with the fields the
+// demos set up, against the schema; a timestamp format change in either engine fails here
+func TestEnginesProduceValidEntries(t *testing.T) {
+	for _, engine := range []string{"zap", "slog"} {
+		rec := testlog.NewWithOption(t, option.LogOption{
+			Engine: engine,
+			Level:  "debug",
+			InitialFields: map[string]interface{}{
+				"service.name":    "logschema-test",
+				"service.version": "v1.2.3",
+				"environment":     "test",
+			},
+		})
+		rec.Logger.Debugw("Cache lookup", "key", "user:42")
+		rec.Logger.With("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7").
+			Infow("Request completed", "duration_ms", 12, "status", 200)
+		rec.Logger.Errorw("Payment capture failed", "error", "card declined")
+
+		entries := rec.Entries()
+		if len(entries) != 3 {
+			t.Fatalf("%s: captured %d entries, want 3", engine, len(entries))
+		}
+		for _, entry := range entries {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if violations := Default().ValidateLine(line); len(violations) != 0 {
+				t.Errorf("%s: %v\n%s", engine, violations, line)
+			}
+		}
+	}
+}
+
+func TestNewRejectsInvalidSchema(t *testing.T) {
+	if _, err := New([]byte(`{"type": 42}`)); err == nil {
+		t.Error("New accepted a schema with a numeric type")
+	}
+	if _, err := New([]byte(`not json`)); err == nil {
+		t.Error("New accepted a document that is not JSON")
+	}
+}
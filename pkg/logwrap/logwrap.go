@@ -0,0 +1,211 @@
+// Package logwrap is the base of the logger wrappers in pkg: redact, dataclass, allowlist,
+// secretscan and dynamicfields rewrite entries before they are logged, while logring,
+// logrules, runsummary, slo, spanevents and devconsole observe them.
+//
+// A wrapper supplies Hooks and New implements every method of core.Logger on top of
+// them. Plain and formatted calls such as Info and Infof go through the same hooks as
+// Infow, with no fields, so no method can reach the wrapped logger without passing the
+// wrapper's policy:
+//
+//	func (r *Redactor) Wrap(base core.Logger) core.Logger {
+//		return logwrap.New(base, logwrap.Hooks{
+//			Entry: func(e *logwrap.Entry, _ core.Logger) {
+//				e.Message, e.Fields = r.String(e.Message), r.KeyValues(e.Fields)
+//			},
+//			With: func(keyValues []interface{}, _ core.Logger) []interface{} { return r.KeyValues(keyValues) },
+//		})
+//	}
+//
+// The wrapped logger skips the wrapper's frames, so the caller field of every entry still
+// points at the code that logged.
+package logwrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Levels of Entry.Level
+const (
+	Debug = "debug"
+	Info  = "info"
+	Warn  = "warn"
+	Error = "error"
+	Fatal = "fatal"
+)
+
+// Depth is the number of frames between a hook and the code that logged:
+// runtime.Caller(Depth + e.Skip) called in the hook itself returns that code
+const Depth = 3
+
+// Entry is one log call as the hooks see it
+type Entry struct {
+	// Level is Debug, Info, Warn, Error or Fatal
+	Level string
+	// Message is the message, formatted for Infof and the like
+	Message string
+	// Fields are the key/value pairs of the call; plain and formatted calls have none
+	Fields []interface{}
+	// With are the fields added through the wrapper's With and WithCtx, as the With hook
+	// returned them. The wrapped logger already carries them; they are here for hooks
+	// that look at whole entries.
+	With []interface{}
+	// Skip is the number of frames added with WithCallerSkip
+	Skip int
+}
+
+// Hooks are what a wrapper adds; every hook is optional
+type Hooks struct {
+	// Entry rewrites an entry before it is logged. out logs at the line that made the call,
+	// for warnings about the entry, when called from the hook itself.
+	Entry func(e *Entry, out core.Logger)
+	// Logged observes an entry after it was logged, or before for Fatal, since the wrapped
+	// logger exits the process
+	Logged func(e Entry)
+	// With rewrites the fields given to With and WithCtx, once, before they are attached.
+	// out logs at the line that called With, when called from the hook itself.
+	With func(keyValues []interface{}, out core.Logger) []interface{}
+	// Context returns the hooks of a child made by WithCtx, for wrappers that keep state
+	// from the context such as the current span; nil keeps the parent's hooks
+	Context func(ctx context.Context) *Hooks
+	// SetLevel is called after the wrapped logger's level is set
+	SetLevel func(level core.Level)
+}
+
+// wrapper implements core.Logger on top of the wrapped logger and the hooks
+type wrapper struct {
+	inner core.Logger
+	// out logs the entries; it skips log and the logging method
+	out core.Logger
+	// note is out for the Entry hook, which is one frame deeper
+	note  core.Logger
+	hooks *Hooks
+	with  []interface{}
+	skip  int
+}
+
+// New returns a logger that logs through inner with hooks applied
+func New(inner core.Logger, hooks Hooks) core.Logger {
+	return newWrapper(inner, &hooks, nil, 0)
+}
+
+func newWrapper(inner core.Logger, hooks *Hooks, with []interface{}, skip int) *wrapper {
+	w := &wrapper{inner: inner, out: inner.WithCallerSkip(2), hooks: hooks, with: with, skip: skip}
+	w.note = w.out
+	if hooks.Entry != nil {
+		w.note = inner.WithCallerSkip(Depth)
+	}
+	return w
+}
+
+// log runs the hooks around one entry; every logging method calls it directly, so the
+// frames it and the method add are known
+func (w *wrapper) log(level, msg string, fields []interface{}) {
+	e := Entry{Level: level, Message: msg, Fields: fields, With: w.with, Skip: w.skip}
+	if w.hooks.Entry != nil {
+		w.hooks.Entry(&e, w.note)
+	}
+	if level == Fatal && w.hooks.Logged != nil {
+		w.hooks.Logged(e)
+	}
+	switch level {
+	case Debug:
+		w.out.Debugw(e.Message, e.Fields...)
+	case Info:
+		w.out.Infow(e.Message, e.Fields...)
+	case Warn:
+		w.out.Warnw(e.Message, e.Fields...)
+	case Error:
+		w.out.Errorw(e.Message, e.Fields...)
+	case Fatal:
+		w.out.Fatalw(e.Message, e.Fields...)
+	}
+	if level != Fatal && w.hooks.Logged != nil {
+		w.hooks.Logged(e)
+	}
+}
+
+func (w *wrapper) Debug(args ...interface{}) { w.log(Debug, fmt.Sprint(args...), nil) }
+func (w *wrapper) Info(args ...interface{})  { w.log(Info, fmt.Sprint(args...), nil) }
+func (w *wrapper) Warn(args ...interface{})  { w.log(Warn, fmt.Sprint(args...), nil) }
+func (w *wrapper) Error(args ...interface{}) { w.log(Error, fmt.Sprint(args...), nil) }
+func (w *wrapper) Fatal(args ...interface{}) { w.log(Fatal, fmt.Sprint(args...), nil) }
+
+func (w *wrapper) Debugf(template string, args ...interface{}) {
+	w.log(Debug, fmt.Sprintf(template, args...), nil)
+}
+
+func (w *wrapper) Infof(template string, args ...interface{}) {
+	w.log(Info, fmt.Sprintf(template, args...), nil)
+}
+
+func (w *wrapper) Warnf(template string, args ...interface{}) {
+	w.log(Warn, fmt.Sprintf(template, args...), nil)
+}
+
+func (w *wrapper) Errorf(template string, args ...interface{}) {
+	w.log(Error, fmt.Sprintf(template, args...), nil)
+}
+
+func (w *wrapper) Fatalf(template string, args ...interface{}) {
+	w.log(Fatal, fmt.Sprintf(template, args...), nil)
+}
+
+func (w *wrapper) Debugw(msg string, keysAndValues ...interface{}) { w.log(Debug, msg, keysAndValues) }
+func (w *wrapper) Infow(msg string, keysAndValues ...interface{})  { w.log(Info, msg, keysAndValues) }
+func (w *wrapper) Warnw(msg string, keysAndValues ...interface{})  { w.log(Warn, msg, keysAndValues) }
+func (w *wrapper) Errorw(msg string, keysAndValues ...interface{}) { w.log(Error, msg, keysAndValues) }
+func (w *wrapper) Fatalw(msg string, keysAndValues ...interface{}) { w.log(Fatal, msg, keysAndValues) }
+
+// With returns a child logger carrying keyValues, as the With hook rewrote them
+func (w *wrapper) With(keyValues ...interface{}) core.Logger {
+	if w.hooks.With != nil {
+		keyValues = w.hooks.With(keyValues, w.inner.WithCallerSkip(2))
+	}
+	return newWrapper(w.inner.With(keyValues...), w.hooks, w.attach(keyValues), w.skip)
+}
+
+// WithCtx is With for a context, with the hooks the Context hook returns for it
+func (w *wrapper) WithCtx(ctx context.Context, keyValues ...interface{}) core.Logger {
+	if w.hooks.With != nil {
+		keyValues = w.hooks.With(keyValues, w.inner.WithCallerSkip(2))
+	}
+	hooks := w.hooks
+	if w.hooks.Context != nil {
+		if child := w.hooks.Context(ctx); child != nil {
+			hooks = child
+		}
+	}
+	return newWrapper(w.inner.WithCtx(ctx, keyValues...), hooks, w.attach(keyValues), w.skip)
+}
+
+// WithCallerSkip returns a child logger with extra frames skipped
+func (w *wrapper) WithCallerSkip(skip int) core.Logger {
+	return newWrapper(w.inner.WithCallerSkip(skip), w.hooks, w.with, w.skip+skip)
+}
+
+// SetLevel sets the level of the wrapped logger
+func (w *wrapper) SetLevel(level core.Level) {
+	w.inner.SetLevel(level)
+	if w.hooks.SetLevel != nil {
+		w.hooks.SetLevel(level)
+	}
+}
+
+// Flush flushes the wrapped logger
+func (w *wrapper) Flush() error {
+	return w.inner.Flush()
+}
+
+// attach returns the wrapper's fields followed by keyValues, in a new slice so siblings
+// do not share an array
+func (w *wrapper) attach(keyValues []interface{}) []interface{} {
+	if len(keyValues) == 0 {
+		return w.with
+	}
+	fields := make([]interface{}, 0, len(w.with)+len(keyValues))
+	fields = append(fields, w.with...)
+	return append(fields, keyValues...)
+}
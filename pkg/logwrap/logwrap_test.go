@@ -0,0 +1,137 @@
+package logwrap
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/core"
+)
+
+// thisLine returns the line it is called from, logged next to an entry to compare with
+// its caller field
+func thisLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestEveryMethodRunsHooks(t *testing.T) {
+	rec := testlog.New(t)
+	var logged []string
+	log := New(rec.Logger, Hooks{
+		Entry: func(e *Entry, _ core.Logger) {
+			e.Message = strings.ToUpper(e.Message)
+			e.Fields = append(e.Fields, "hooked", true)
+		},
+		Logged: func(e Entry) { logged = append(logged, e.Level+" "+e.Message) },
+	})
+
+	log.Debug("plain ", 1)
+	log.Info("plain ", 2)
+	log.Warn("plain ", 3)
+	log.Error("plain ", 4)
+	log.Debugf("formatted %d", 1)
+	log.Infof("formatted %d", 2)
+	log.Warnf("formatted %d", 3)
+	log.Errorf("formatted %d", 4)
+	log.Debugw("structured", "n", 1)
+	log.Infow("structured", "n", 2)
+	log.Warnw("structured", "n", 3)
+	log.Errorw("structured", "n", 4)
+
+	want := []string{"debug PLAIN 1", "info PLAIN 2", "warn PLAIN 3", "error PLAIN 4",
+		"debug FORMATTED 1", "info FORMATTED 2", "warn FORMATTED 3", "error FORMATTED 4",
+		"debug STRUCTURED", "info STRUCTURED", "warn STRUCTURED", "error STRUCTURED"}
+	if fmt.Sprint(logged) != fmt.Sprint(want) {
+		t.Errorf("Logged saw %q\nwant %q", logged, want)
+	}
+	if n := rec.Count("", "", "hooked", true); n != len(want) {
+		t.Errorf("%d entries passed the Entry hook, want %d:\n%s", n, len(want), rec.Normalized())
+	}
+}
+
+func TestWith(t *testing.T) {
+	rec := testlog.New(t)
+	var seen Entry
+	log := New(rec.Logger, Hooks{
+		With: func(keyValues []interface{}, _ core.Logger) []interface{} {
+			return append(keyValues, "attached", true)
+		},
+		Logged: func(e Entry) { seen = e },
+	})
+
+	log.With("request_id", "req_1").WithCtx(context.Background(), "order_id", "ord-7").Info("Order held")
+	rec.AssertLogged("info", "Order held", "request_id", "req_1", "order_id", "ord-7", "attached", true)
+	if got := fmt.Sprint(seen.With); got != "[request_id req_1 attached true order_id ord-7 attached true]" {
+		t.Errorf("Entry.With = %s", got)
+	}
+}
+
+type spanKey struct{}
+
+func TestContext(t *testing.T) {
+	rec := testlog.New(t)
+	var got []string
+	hooks := func(name string) *Hooks {
+		return &Hooks{Logged: func(e Entry) { got = append(got, name) }}
+	}
+	parent := hooks("parent")
+	parent.Context = func(ctx context.Context) *Hooks {
+		if ctx.Value(spanKey{}) == nil {
+			return nil
+		}
+		return hooks("child")
+	}
+	log := New(rec.Logger, *parent)
+
+	log.WithCtx(context.Background()).Info("kept")
+	log.WithCtx(context.WithValue(context.Background(), spanKey{}, 1)).Info("replaced")
+	if fmt.Sprint(got) != "[parent child]" {
+		t.Errorf("hooks run = %v", got)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	rec := testlog.New(t)
+	log := New(rec.Logger, Hooks{
+		Entry: func(e *Entry, out core.Logger) {
+			if e.Message == "Warned" {
+				out.Warnw("Entry warning", "line", e.Fields[1])
+			}
+		},
+		With: func(keyValues []interface{}, out core.Logger) []interface{} {
+			out.Warnw("With warning", "line", keyValues[1])
+			return keyValues
+		},
+	})
+	helper := func(line int) { log.WithCallerSkip(1).Infow("Via helper", "line", line) }
+
+	log.Infow("Structured", "line", thisLine())
+	log.Infof("Formatted %d", thisLine())
+	log.Infow("Warned", "line", thisLine())
+	log.With("line", thisLine())
+	helper(thisLine())
+
+	entries := []testlog.Entry{
+		rec.AssertLogged("info", "Structured"),
+		rec.AssertLogged("warn", "Entry warning"),
+		rec.AssertLogged("warn", "With warning"),
+		rec.AssertLogged("info", "Via helper"),
+	}
+	for _, entry := range entries {
+		checkCaller(t, entry, fmt.Sprint(entry["line"]))
+	}
+	formatted := rec.AssertLogged("info", "Formatted")
+	checkCaller(t, formatted, strings.TrimPrefix(formatted.Message(), "Formatted "))
+}
+
+func checkCaller(t *testing.T, entry testlog.Entry, line string) {
+	t.Helper()
+	want := "logwrap/logwrap_test.go:" + line
+	if caller, _ := entry["caller"].(string); !strings.HasSuffix(caller, want) {
+		t.Errorf("%s: caller %q, want %q", entry.Message(), caller, want)
+	}
+}
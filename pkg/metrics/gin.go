@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RED records rate, errors and duration for every request handled by the router:
+//
+//	go_example_http_requests_total{method,route,status}
+//	go_example_http_request_duration_seconds{method,route}
+//	go_example_http_requests_in_flight
+//
+// route is the gin route pattern, so /orders/42 and /orders/43 share a series; requests
+// that match no route are recorded as "unmatched". When the request carries a sampled span,
+// put there by a tracing middleware before or after this one, its duration is observed with
+// the trace_id as an exemplar, so a latency spike in Grafana links to a trace, and through
+// the same trace_id to its logs.
+func (r *Registry) RED() gin.HandlerFunc {
+	requests := r.Counter("http_requests_total", "HTTP requests by route and status code.", "method", "route", "status")
+	duration := r.Histogram("http_request_duration_seconds", "HTTP request duration by route.", nil, "method", "route")
+	inFlight := r.Gauge("http_requests_in_flight", "HTTP requests currently being served.").WithLabelValues()
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		observe(c.Request.Context(), duration.WithLabelValues(c.Request.Method, route), time.Since(start).Seconds())
+	}
+}
+
+// observe records value with the sampled trace in ctx as its exemplar; an unsampled trace
+// was not exported, so there would be nothing to link to
+func observe(ctx context.Context, o prometheus.Observer, value float64) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if exemplars, ok := o.(prometheus.ExemplarObserver); ok && spanContext.IsSampled() {
+		exemplars.ObserveWithExemplar(value, prometheus.Labels{"trace_id": spanContext.TraceID().String()})
+		return
+	}
+	o.Observe(value)
+}
+
+// GinHandler serves the registry from a gin route, usually GET /metrics
+func (r *Registry) GinHandler() gin.HandlerFunc {
+	return gin.WrapH(r.Handler())
+}
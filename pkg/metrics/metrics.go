@@ -0,0 +1,116 @@
+// Package metrics is a thin wrapper over the Prometheus client that gives every demo the
+// same metric names.
+//
+// All series share the go_example namespace and a constant service label, so a dashboard
+// built for one demo works for the others by switching the label. Demos ask the Registry
+// for counters, gauges and histograms by short name, and RED adds the request rate,
+// errors and duration metrics for a gin router, with trace exemplars on the durations.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric created through a Registry
+const Namespace = "go_example"
+
+// DefaultBuckets suit request and task durations in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry creates namespaced collectors and serves them for scraping
+type Registry struct {
+	reg    *prometheus.Registry
+	labels prometheus.Labels
+}
+
+// New returns a Registry whose metrics carry service as a label. Go runtime, process and
+// build_info metrics are registered up front.
+func New(service string) *Registry {
+	r := &Registry{
+		reg:    prometheus.NewRegistry(),
+		labels: prometheus.Labels{"service": service},
+	}
+	r.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildinfo.Collector(),
+	)
+	return r
+}
+
+// Counter returns the counter vector go_example_<name>, creating it on first use
+func (r *Registry) Counter(name, help string, labels ...string) *prometheus.CounterVec {
+	return register(r, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.labels,
+	}, labels))
+}
+
+// Gauge returns the gauge vector go_example_<name>, creating it on first use
+func (r *Registry) Gauge(name, help string, labels ...string) *prometheus.GaugeVec {
+	return register(r, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.labels,
+	}, labels))
+}
+
+// GaugeFunc registers go_example_<name>, read from fn at scrape time; use it for values
+// the demo already tracks, such as queue depth
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	register(r, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.labels,
+	}, fn))
+}
+
+// Histogram returns the histogram vector go_example_<name>; nil buckets means DefaultBuckets
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return register(r, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   Namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: r.labels,
+		Buckets:     buckets,
+	}, labels))
+}
+
+// Handler serves the registry in the Prometheus exposition format, or in OpenMetrics to a
+// scraper that asks for it, which is the only format that carries exemplars
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg, EnableOpenMetrics: true})
+}
+
+// Prometheus returns the underlying registry, for collectors this package does not wrap
+func (r *Registry) Prometheus() *prometheus.Registry {
+	return r.reg
+}
+
+// register adds c to the registry, or returns the collector already registered under the
+// same name so constructors can be called from several places
+func register[C prometheus.Collector](r *Registry, c C) C {
+	if err := r.reg.Register(c); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if errors.As(err, &already) {
+			if existing, ok := already.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRED(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := New("test-demo")
+	r := gin.New()
+	r.Use(reg.RED())
+	r.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", reg.GinHandler())
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	requests := reg.Counter("http_requests_total", "HTTP requests by route and status code.", "method", "route", "status")
+	if n := testutil.ToFloat64(requests.WithLabelValues("GET", "/orders/:id", "200")); n != 2 {
+		t.Errorf("requests for /orders/:id = %v, want 2", n)
+	}
+	if n := testutil.ToFloat64(requests.WithLabelValues("GET", "unmatched", "404")); n != 1 {
+		t.Errorf("unmatched requests = %v, want 1", n)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`go_example_http_requests_total{method="GET",route="/orders/:id",service="test-demo",status="200"} 2`,
+		`go_example_http_request_duration_seconds_count{method="GET",route="/orders/:id",service="test-demo"} 2`,
+		`go_example_http_requests_in_flight{service="test-demo"} 1`,
+		"build_info{",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %s", want)
+		}
+	}
+}
+
+func TestConstructorsReuseCollectors(t *testing.T) {
+	reg := New("test-demo")
+	a := reg.Counter("tasks_total", "Tasks processed.", "outcome")
+	b := reg.Counter("tasks_total", "Tasks processed.", "outcome")
+	a.WithLabelValues("ok").Inc()
+	if n := testutil.ToFloat64(b.WithLabelValues("ok")); n != 1 {
+		t.Errorf("second Counter call returned a different collector: count %v, want 1", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering tasks_total with different labels did not panic")
+		}
+	}()
+	reg.Counter("tasks_total", "Tasks processed.", "queue")
+}
+
+func TestREDExemplars(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := New("test-demo")
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	r := gin.New()
+	r.Use(reg.RED())
+	// Stands in for a tracing middleware, which runs after RED here
+	r.Use(func(c *gin.Context) {
+		flags := trace.TraceFlags(0)
+		if c.Query("sampled") == "true" {
+			flags = trace.FlagsSampled
+		}
+		c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: flags,
+		})))
+	})
+	r.GET("/traced", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/untraced", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", reg.GinHandler())
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/traced?sampled=true", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/untraced", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var traced, untraced []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "go_example_http_request_duration_seconds_bucket") {
+			continue
+		}
+		if strings.Contains(line, `route="/traced"`) {
+			traced = append(traced, line)
+		} else {
+			untraced = append(untraced, line)
+		}
+	}
+	if !strings.Contains(strings.Join(traced, "\n"), `# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"}`) {
+		t.Errorf("sampled request has no exemplar:\n%s", strings.Join(traced, "\n"))
+	}
+	if strings.Contains(strings.Join(untraced, "\n"), "trace_id") {
+		t.Errorf("unsampled request has an exemplar:\n%s", strings.Join(untraced, "\n"))
+	}
+}
@@ -0,0 +1,161 @@
+// Package otelresource derives the OpenTelemetry resource identity of a demo, the
+// service.name, service.version, service.instance.id and deployment.environment every
+// signal it exports should carry, from the version package and the environment.
+//
+// The OTLP demos each built that identity by hand, once as InitialFields for the logger
+// and again for otelsetup.Config, so the two could drift apart. Get builds it once:
+//
+//	identity := otelresource.Get()
+//	logOption.InitialFields = identity.Fields()
+//	cfg := identity.Config(otelsetup.Config{Endpoint: endpoint})
+//
+// service.name and service.version come from version.Get(), service.instance.id is
+// hostmeta.InstanceID() and deployment.environment is DEPLOY_ENV, "development" when
+// unset. The standard OTEL_RESOURCE_ATTRIBUTES (key=value pairs separated by commas)
+// and OTEL_SERVICE_NAME override them, as they would for any OpenTelemetry SDK.
+package otelresource
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// Resource attribute keys
+const (
+	ServiceName           = "service.name"
+	ServiceVersion        = "service.version"
+	ServiceInstanceID     = "service.instance.id"
+	DeploymentEnvironment = "deployment.environment"
+)
+
+// DefaultEnvironment is deployment.environment when DEPLOY_ENV is not set
+const DefaultEnvironment = "development"
+
+// Identity is the resource a demo exports its signals under
+type Identity struct {
+	ServiceName    string
+	ServiceVersion string
+	InstanceID     string
+	Environment    string
+	// Extra holds the other attributes of OTEL_RESOURCE_ATTRIBUTES, such as
+	// k8s.namespace.name set by a deployment
+	Extra map[string]string
+}
+
+// Get returns the identity of this process
+func Get() Identity {
+	v := version.Get()
+	id := Identity{
+		ServiceName:    v.ServiceName,
+		ServiceVersion: v.GitVersion,
+		InstanceID:     hostmeta.InstanceID(),
+		Environment:    os.Getenv("DEPLOY_ENV"),
+	}
+	if id.Environment == "" {
+		id.Environment = DefaultEnvironment
+	}
+	for key, value := range parseAttributes(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")) {
+		switch key {
+		case ServiceName:
+			id.ServiceName = value
+		case ServiceVersion:
+			id.ServiceVersion = value
+		case ServiceInstanceID:
+			id.InstanceID = value
+		case DeploymentEnvironment:
+			id.Environment = value
+		default:
+			if id.Extra == nil {
+				id.Extra = make(map[string]string)
+			}
+			id.Extra[key] = value
+		}
+	}
+	// The specification gives OTEL_SERVICE_NAME precedence over OTEL_RESOURCE_ATTRIBUTES
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		id.ServiceName = name
+	}
+	return id
+}
+
+// parseAttributes reads the key1=value1,key2=value2 format of OTEL_RESOURCE_ATTRIBUTES,
+// whose values are percent-encoded; malformed pairs are ignored
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if decoded, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			attrs[key] = decoded
+		}
+	}
+	return attrs
+}
+
+// Attributes returns the non-empty attributes of id by resource key
+func (id Identity) Attributes() map[string]string {
+	attrs := make(map[string]string, 4+len(id.Extra))
+	for key, value := range id.Extra {
+		attrs[key] = value
+	}
+	for key, value := range map[string]string{
+		ServiceName:           id.ServiceName,
+		ServiceVersion:        id.ServiceVersion,
+		ServiceInstanceID:     id.InstanceID,
+		DeploymentEnvironment: id.Environment,
+	} {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
+
+// Fields returns Attributes as InitialFields, a new map the caller may add to
+func (id Identity) Fields() map[string]interface{} {
+	attrs := id.Attributes()
+	fields := make(map[string]interface{}, len(attrs))
+	for key, value := range attrs {
+		fields[key] = value
+	}
+	return fields
+}
+
+// Apply adds id to opt.InitialFields; fields opt already sets are kept
+func (id Identity) Apply(opt *option.LogOption) {
+	if opt.InitialFields == nil {
+		opt.InitialFields = make(map[string]interface{})
+	}
+	for key, value := range id.Attributes() {
+		if _, ok := opt.InitialFields[key]; !ok {
+			opt.InitialFields[key] = value
+		}
+	}
+}
+
+// Config returns cfg with id as its resource: ServiceName, ServiceVersion and Environment
+// are set, and service.instance.id and the extra attributes are added to Attributes, to
+// which cfg's own attributes take precedence
+func (id Identity) Config(cfg otelsetup.Config) otelsetup.Config {
+	cfg.ServiceName = id.ServiceName
+	cfg.ServiceVersion = id.ServiceVersion
+	cfg.Environment = id.Environment
+	attrs := id.Attributes()
+	for _, key := range []string{ServiceName, ServiceVersion, DeploymentEnvironment} {
+		delete(attrs, key)
+	}
+	for key, value := range cfg.Attributes {
+		attrs[key] = value
+	}
+	cfg.Attributes = attrs
+	return cfg
+}
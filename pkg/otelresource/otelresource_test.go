@@ -0,0 +1,76 @@
+package otelresource
+
+import (
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/logger/option"
+)
+
+func TestGet(t *testing.T) {
+	t.Setenv("DEPLOY_ENV", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	id := Get()
+	if id.Environment != DefaultEnvironment || id.InstanceID != hostmeta.InstanceID() || id.Extra != nil {
+		t.Errorf("defaults = %+v", id)
+	}
+
+	t.Setenv("DEPLOY_ENV", "staging")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=billing, deployment.environment=prod,k8s.namespace.name=pay%2Dments,broken")
+	t.Setenv("OTEL_SERVICE_NAME", "orders")
+	id = Get()
+	if id.ServiceName != "orders" {
+		t.Errorf("OTEL_SERVICE_NAME not preferred: %q", id.ServiceName)
+	}
+	if id.Environment != "prod" {
+		t.Errorf("OTEL_RESOURCE_ATTRIBUTES not preferred over DEPLOY_ENV: %q", id.Environment)
+	}
+	if len(id.Extra) != 1 || id.Extra["k8s.namespace.name"] != "pay-ments" {
+		t.Errorf("extra = %v", id.Extra)
+	}
+}
+
+func TestApplyAndConfig(t *testing.T) {
+	id := Identity{
+		ServiceName:    "orders",
+		ServiceVersion: "v1.2.0",
+		InstanceID:     "host-42",
+		Environment:    "staging",
+		Extra:          map[string]string{"k8s.namespace.name": "shop"},
+	}
+
+	opt := &option.LogOption{InitialFields: map[string]interface{}{"service.name": "orders-canary", "component": "api"}}
+	id.Apply(opt)
+	want := map[string]interface{}{
+		"service.name":           "orders-canary",
+		"service.version":        "v1.2.0",
+		"service.instance.id":    "host-42",
+		"deployment.environment": "staging",
+		"k8s.namespace.name":     "shop",
+		"component":              "api",
+	}
+	if len(opt.InitialFields) != len(want) {
+		t.Errorf("InitialFields = %v", opt.InitialFields)
+	}
+	for key, value := range want {
+		if opt.InitialFields[key] != value {
+			t.Errorf("InitialFields[%s] = %v, want %v", key, opt.InitialFields[key], value)
+		}
+	}
+
+	cfg := id.Config(otelsetup.Config{Endpoint: "collector:4318", Attributes: map[string]string{"host.name": "vm"}})
+	if cfg.ServiceName != "orders" || cfg.ServiceVersion != "v1.2.0" || cfg.Environment != "staging" || cfg.Endpoint != "collector:4318" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if len(cfg.Attributes) != 3 || cfg.Attributes["service.instance.id"] != "host-42" || cfg.Attributes["host.name"] != "vm" || cfg.Attributes["k8s.namespace.name"] != "shop" {
+		t.Errorf("attributes = %v", cfg.Attributes)
+	}
+
+	// The logger and the SDK resource describe the same service
+	fromLogger := otelsetup.FromLogOption(&option.LogOption{InitialFields: id.Fields()})
+	if fromLogger.ServiceName != cfg.ServiceName || fromLogger.Environment != cfg.Environment || fromLogger.Attributes["service.instance.id"] != "host-42" {
+		t.Errorf("FromLogOption = %+v", fromLogger)
+	}
+}
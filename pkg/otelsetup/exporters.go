@@ -0,0 +1,147 @@
+package otelsetup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/secretref"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+func newTraceExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(cfg.Endpoint),
+			otlptracegrpc.WithTimeout(cfg.Timeout),
+			otlptracegrpc.WithHeaders(cfg.Headers),
+		}
+		switch {
+		case cfg.tlsConfig != nil:
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(cfg.tlsConfig)))
+		case cfg.Insecure:
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(cfg.Endpoint),
+		otlptracehttp.WithTimeout(cfg.Timeout),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	}
+	switch {
+	case cfg.tlsConfig != nil:
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(cfg.tlsConfig))
+	case cfg.Insecure:
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+func newMetricExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithTimeout(cfg.Timeout),
+			otlpmetricgrpc.WithHeaders(cfg.Headers),
+		}
+		switch {
+		case cfg.tlsConfig != nil:
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(cfg.tlsConfig)))
+		case cfg.Insecure:
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+		otlpmetrichttp.WithTimeout(cfg.Timeout),
+		otlpmetrichttp.WithHeaders(cfg.Headers),
+	}
+	switch {
+	case cfg.tlsConfig != nil:
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(cfg.tlsConfig))
+	case cfg.Insecure:
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
+
+// LogExporter returns the OTLP log exporter Setup would use for cfg, for tools that build
+// their own LoggerProvider, such as cmd/otlp-replay. The TLS files and the secrets the
+// headers reference are read once, not watched.
+func LogExporter(ctx context.Context, cfg Config) (sdklog.Exporter, error) {
+	cfg, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	return newLogExporter(ctx, cfg)
+}
+
+// load applies the defaults and reads the TLS files and header secrets once, for a
+// connection that is not kept current
+func (cfg Config) load() (Config, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.TLS.Enabled() && cfg.tlsConfig == nil {
+		if cfg.tlsConfig, err = certwatch.Load(cfg.TLS); err != nil {
+			return cfg, err
+		}
+	}
+	if secretref.HasRefs(cfg.Headers) {
+		if cfg.Headers, err = secretref.Resolve(cfg.Headers); err != nil {
+			return cfg, fmt.Errorf("failed to resolve OTLP headers: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+func newLogExporter(ctx context.Context, cfg Config) (sdklog.Exporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlploggrpc.Option{
+			otlploggrpc.WithEndpoint(cfg.Endpoint),
+			otlploggrpc.WithTimeout(cfg.Timeout),
+			otlploggrpc.WithHeaders(cfg.Headers),
+		}
+		switch {
+		case cfg.tlsConfig != nil:
+			opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(cfg.tlsConfig)))
+		case cfg.Insecure:
+			opts = append(opts, otlploggrpc.WithInsecure())
+		}
+		if cfg.SpoolDir != "" {
+			// The spool retries with its own backoff, and keeps the batch meanwhile
+			opts = append(opts, otlploggrpc.WithRetry(otlploggrpc.RetryConfig{Enabled: false}))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	}
+
+	opts := []otlploghttp.Option{
+		otlploghttp.WithEndpoint(cfg.Endpoint),
+		otlploghttp.WithTimeout(cfg.Timeout),
+		otlploghttp.WithHeaders(cfg.Headers),
+	}
+	switch {
+	case cfg.tlsConfig != nil:
+		opts = append(opts, otlploghttp.WithTLSClientConfig(cfg.tlsConfig))
+	case cfg.Insecure:
+		opts = append(opts, otlploghttp.WithInsecure())
+	}
+	if cfg.SpoolDir != "" {
+		opts = append(opts, otlploghttp.WithRetry(otlploghttp.RetryConfig{Enabled: false}))
+	}
+	return otlploghttp.New(ctx, opts...)
+}
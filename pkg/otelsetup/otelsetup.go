@@ -0,0 +1,372 @@
+// Package otelsetup builds the OpenTelemetry tracer, meter and logger providers from one
+// collector configuration.
+//
+// The demos used to repeat the same exporter, resource, propagator and error handler
+// wiring. Setup does it once, from a Config that can be read off the option.LogOption the
+// logger already uses, so logs, traces and metrics go to the same endpoint with the same
+// protocol and headers and carry the same service identity.
+package otelsetup
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/otlpspool"
+	"github.com/kart-io/go-example/pkg/secretref"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Protocols accepted in Config.Protocol, matching option.OTLPOption.Protocol
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// Signal selects which providers Setup builds
+type Signal uint8
+
+const (
+	Traces Signal = 1 << iota
+	Metrics
+	Logs
+
+	// AllSignals is what a zero Config.Signals means
+	AllSignals = Traces | Metrics | Logs
+)
+
+// Defaults applied by Setup to zero Config fields
+const (
+	DefaultTimeout        = 5 * time.Second
+	DefaultExportInterval = 5 * time.Second
+)
+
+// ErrNoEndpoint is returned by Setup when Config.Endpoint is empty
+var ErrNoEndpoint = errors.New("otelsetup: no OTLP endpoint configured")
+
+// ErrUnsupportedProtocol is returned for a protocol other than http, http/protobuf or grpc
+var ErrUnsupportedProtocol = errors.New("otelsetup: unsupported OTLP protocol")
+
+// Config is the collector connection and service identity shared by every signal
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Attributes are extra resource attributes, such as service.instance.id
+	Attributes map[string]string
+
+	// Endpoint is host:port; an http:// prefix also turns on Insecure
+	Endpoint string
+	// Protocol is ProtocolHTTP (the default) or ProtocolGRPC
+	Protocol string
+	Insecure bool
+	// TLS, when it names a CA bundle or client certificate, secures the connection with
+	// those files, reloaded when they are rotated; Insecure is ignored then
+	TLS certwatch.Config
+	// Headers are sent with every export. A value may reference a secret instead of holding
+	// it, as ${file:PATH} or ${env:NAME}; Setup re-reads referenced files when they change
+	// and reconnects with the new values, see pkg/secretref
+	Headers map[string]string
+	Timeout time.Duration
+	// ExportInterval is the span batch timeout, metric push interval and log batch interval
+	ExportInterval time.Duration
+
+	// Signals defaults to AllSignals; a Jaeger-only setup wants Traces
+	Signals Signal
+	// Sampler defaults to ParentBased(AlwaysSample); SamplingConfig builds one from YAML
+	Sampler sdktrace.Sampler
+	// SamplingLogInterval, when set, counts the Sampler's decisions and logs the rate it
+	// samples at every interval; see SamplingStats
+	SamplingLogInterval time.Duration
+
+	// SpoolDir, when set, keeps log batches the collector did not accept there and
+	// replays them once it is reachable; see pkg/otlpspool
+	SpoolDir string
+	// SpoolMaxAge is how long a spooled batch is retried before it is dead-lettered;
+	// otlpspool.DefaultMaxAge when zero
+	SpoolMaxAge time.Duration
+	// DeadLetterDir, when set, keeps log batches that will not be retried any more there
+	// for cmd/otlp-replay: with SpoolDir, those the spool gives up on, otherwise those the
+	// exporter failed after its own retries
+	DeadLetterDir string
+
+	// tlsConfig is built from TLS by Setup or LogExporter
+	tlsConfig *tls.Config
+}
+
+// FromLogOption reads the collector settings from a logger option, preferring the OTLP
+// block over the OTLPEndpoint shorthand, and the identity from its service.name,
+// service.version, deployment.environment and service.instance.id InitialFields
+func FromLogOption(opt *option.LogOption) Config {
+	cfg := Config{Endpoint: opt.OTLPEndpoint}
+	if otlp := opt.OTLP; otlp != nil {
+		if otlp.Endpoint != "" {
+			cfg.Endpoint = otlp.Endpoint
+		}
+		cfg.Protocol = otlp.Protocol
+		cfg.Insecure = otlp.Insecure
+		cfg.Headers = otlp.Headers
+		cfg.Timeout = otlp.Timeout
+	}
+	cfg.ServiceName, _ = opt.InitialFields["service.name"].(string)
+	cfg.ServiceVersion, _ = opt.InitialFields["service.version"].(string)
+	cfg.Environment, _ = opt.InitialFields["deployment.environment"].(string)
+	if cfg.Environment == "" {
+		cfg.Environment, _ = opt.InitialFields["environment"].(string)
+	}
+	if instanceID, _ := opt.InitialFields["service.instance.id"].(string); instanceID != "" {
+		cfg.Attributes = map[string]string{"service.instance.id": instanceID}
+	}
+	return cfg
+}
+
+// Providers holds what Setup built; a provider is nil when its signal was not requested
+type Providers struct {
+	Resource       *resource.Resource
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+	LoggerProvider *sdklog.LoggerProvider
+	// Spool is set when Config.SpoolDir is
+	Spool *otlpspool.Spool
+	// Certs is set when Config.TLS names a file
+	Certs *certwatch.Watcher
+	// Secrets is set when Config.Headers reference a secret file or variable
+	Secrets *secretref.Watcher
+	// Sampling is set when Config.SamplingLogInterval is and traces are built
+	Sampling *SamplingStats
+
+	// The exporters under the providers, which SwitchProtocol replaces
+	mu             sync.Mutex
+	cfg            Config
+	logger         core.Logger
+	state          exportState
+	spanExporter   *swapSpanExporter
+	metricExporter *swapMetricExporter
+	logExporter    *swapLogExporter
+}
+
+// Setup builds the providers for cfg.Signals, installs the tracer and meter providers,
+// the W3C propagator and an error handler that logs export failures to logger. The
+// returned function flushes and stops everything; logs go last so records written while
+// traces and metrics flush are still exported. With a spool, whatever it holds is
+// replayed first if the collector is reachable; otherwise it stays for the next run.
+func Setup(ctx context.Context, cfg Config, logger core.Logger) (*Providers, func(context.Context) error, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := cfg.resource()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build resource: %w", err)
+	}
+	p := &Providers{Resource: res, logger: logger}
+	if cfg.TLS.Enabled() {
+		if p.Certs, err = certwatch.New(cfg.TLS, logger); err != nil {
+			return nil, nil, err
+		}
+		p.Certs.Start()
+		cfg.tlsConfig = p.Certs.TLSConfig()
+	}
+	if secretref.HasRefs(cfg.Headers) {
+		if p.Secrets, err = secretref.New(cfg.Headers, logger, secretref.Config{OnChange: p.reconnect}); err != nil {
+			p.shutdown(ctx)
+			return nil, nil, fmt.Errorf("failed to resolve OTLP headers: %w", err)
+		}
+		cfg.Headers = p.Secrets.Values()
+	}
+	p.cfg = cfg
+
+	if cfg.Signals&Traces != 0 {
+		exporter, err := newTraceExporter(ctx, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		p.spanExporter = &swapSpanExporter{next: exporter, state: &p.state}
+		if cfg.SamplingLogInterval > 0 {
+			p.Sampling = NewSamplingStats(cfg.Sampler, logger, cfg.SamplingLogInterval)
+			cfg.Sampler = p.Sampling
+		}
+		p.TracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(p.spanExporter, sdktrace.WithBatchTimeout(cfg.ExportInterval)),
+			sdktrace.WithSampler(cfg.Sampler),
+			sdktrace.WithResource(res),
+		)
+	}
+	if cfg.Signals&Metrics != 0 {
+		exporter, err := newMetricExporter(ctx, cfg)
+		if err != nil {
+			p.shutdown(ctx)
+			return nil, nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		p.metricExporter = &swapMetricExporter{next: exporter, state: &p.state}
+		p.MeterProvider = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(p.metricExporter, sdkmetric.WithInterval(cfg.ExportInterval))),
+			// Only measurements taken inside a sampled span become exemplars
+			sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter),
+			sdkmetric.WithResource(res),
+		)
+	}
+	if cfg.Signals&Logs != 0 {
+		base, err := newLogExporter(ctx, cfg)
+		if err != nil {
+			p.shutdown(ctx)
+			return nil, nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+		}
+		p.logExporter = &swapLogExporter{next: base, state: &p.state}
+		var exporter sdklog.Exporter = p.logExporter
+		var deadLetter *otlpspool.DeadLetter
+		if cfg.DeadLetterDir != "" {
+			if deadLetter, err = otlpspool.NewDeadLetter(cfg.DeadLetterDir, logger); err != nil {
+				p.shutdown(ctx)
+				return nil, nil, err
+			}
+		}
+		switch {
+		case cfg.SpoolDir != "":
+			// logger reports the spool's depth; it must not be the one exporting through it
+			p.Spool, err = otlpspool.New(exporter, logger, otlpspool.Config{
+				Dir:        cfg.SpoolDir,
+				DeadLetter: deadLetter,
+				MaxAge:     cfg.SpoolMaxAge,
+			})
+			if err != nil {
+				p.shutdown(ctx)
+				return nil, nil, err
+			}
+			exporter = p.Spool
+		case deadLetter != nil:
+			exporter = deadLetter.Exporter(exporter)
+		}
+		p.LoggerProvider = sdklog.NewLoggerProvider(
+			sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, sdklog.WithExportInterval(cfg.ExportInterval))),
+			sdklog.WithResource(res),
+		)
+		if p.Spool != nil {
+			p.Spool.Start(p.LoggerProvider, cfg.Endpoint)
+		}
+	}
+
+	// Globals are only installed once every exporter was created
+	if p.TracerProvider != nil {
+		otel.SetTracerProvider(p.TracerProvider)
+	}
+	if p.MeterProvider != nil {
+		otel.SetMeterProvider(p.MeterProvider)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		// Export failures (e.g. no collector running) surface here instead of on stderr
+		logger.Warnw("OpenTelemetry error", "error", err.Error())
+	}))
+	if p.Secrets != nil {
+		p.Secrets.Start()
+	}
+	if p.Sampling != nil {
+		p.Sampling.Start()
+	}
+	return p, p.shutdown, nil
+}
+
+// shutdown stops the providers that were built, logs last
+func (p *Providers) shutdown(ctx context.Context) error {
+	if p.Secrets != nil {
+		// No reconnect while the exporters are shut down
+		p.Secrets.Stop()
+	}
+	var errs []error
+	if p.TracerProvider != nil {
+		errs = append(errs, p.TracerProvider.Shutdown(ctx))
+	}
+	if p.Sampling != nil {
+		// The decisions since the last report would otherwise go unlogged
+		p.Sampling.Stop()
+		p.Sampling.Report()
+	}
+	if p.MeterProvider != nil {
+		errs = append(errs, p.MeterProvider.Shutdown(ctx))
+	}
+	if p.Spool != nil {
+		if err := p.Spool.Replay(ctx); !errors.Is(err, otlpspool.ErrUnreachable) {
+			errs = append(errs, err)
+		}
+	}
+	if p.LoggerProvider != nil {
+		errs = append(errs, p.LoggerProvider.Shutdown(ctx))
+	}
+	if p.Certs != nil {
+		p.Certs.Stop()
+	}
+	return errors.Join(errs...)
+}
+
+// withDefaults fills zero fields and normalizes the endpoint and protocol
+func (cfg Config) withDefaults() (Config, error) {
+	switch {
+	case strings.HasPrefix(cfg.Endpoint, "http://"):
+		cfg.Endpoint = strings.TrimPrefix(cfg.Endpoint, "http://")
+		cfg.Insecure = true
+	case strings.HasPrefix(cfg.Endpoint, "https://"):
+		cfg.Endpoint = strings.TrimPrefix(cfg.Endpoint, "https://")
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if cfg.Endpoint == "" {
+		return cfg, ErrNoEndpoint
+	}
+	if cfg.TLS.Enabled() {
+		cfg.Insecure = false
+	}
+
+	switch strings.ToLower(cfg.Protocol) {
+	case "", ProtocolHTTP, "http/protobuf":
+		cfg.Protocol = ProtocolHTTP
+	case ProtocolGRPC:
+		cfg.Protocol = ProtocolGRPC
+	default:
+		return cfg, fmt.Errorf("%w %q", ErrUnsupportedProtocol, cfg.Protocol)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ExportInterval <= 0 {
+		cfg.ExportInterval = DefaultExportInterval
+	}
+	if cfg.Signals == 0 {
+		cfg.Signals = AllSignals
+	}
+	if cfg.Sampler == nil {
+		cfg.Sampler = sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+	return cfg, nil
+}
+
+// resource describes the service with the same keys the logger uses in InitialFields
+func (cfg Config) resource() (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{semconv.ServiceName(cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.ServiceVersion))
+	}
+	if cfg.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.Environment))
+	}
+	for key, value := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	return resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+}
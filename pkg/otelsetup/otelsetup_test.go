@@ -0,0 +1,301 @@
+package otelsetup
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+func TestFromLogOption(t *testing.T) {
+	cfg := FromLogOption(&option.LogOption{
+		OTLPEndpoint: "ignored:4317",
+		OTLP: &option.OTLPOption{
+			Endpoint: "collector:4317",
+			Protocol: "grpc",
+			Insecure: true,
+			Headers:  map[string]string{"authorization": "Bearer x"},
+			Timeout:  3 * time.Second,
+		},
+		InitialFields: map[string]interface{}{
+			"service.name":        "orders",
+			"service.version":     "v1.2.0",
+			"environment":         "staging",
+			"service.instance.id": "orders-7f9c-1",
+		},
+	})
+
+	if cfg.Endpoint != "collector:4317" || cfg.Protocol != ProtocolGRPC || !cfg.Insecure || cfg.Timeout != 3*time.Second {
+		t.Errorf("connection not copied: %+v", cfg)
+	}
+	if cfg.Headers["authorization"] != "Bearer x" {
+		t.Errorf("headers = %v", cfg.Headers)
+	}
+	if cfg.ServiceName != "orders" || cfg.ServiceVersion != "v1.2.0" || cfg.Environment != "staging" || cfg.Attributes["service.instance.id"] != "orders-7f9c-1" {
+		t.Errorf("identity not copied: %+v", cfg)
+	}
+
+	if cfg := FromLogOption(&option.LogOption{OTLPEndpoint: "localhost:4318"}); cfg.Endpoint != "localhost:4318" {
+		t.Errorf("OTLPEndpoint shorthand not used: %q", cfg.Endpoint)
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg, err := Config{Endpoint: "http://localhost:4318/"}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Endpoint != "localhost:4318" || !cfg.Insecure || cfg.Protocol != ProtocolHTTP {
+		t.Errorf("endpoint not normalized: %+v", cfg)
+	}
+	if cfg.Signals != AllSignals || cfg.Timeout != DefaultTimeout || cfg.ExportInterval != DefaultExportInterval || cfg.Sampler == nil {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+
+	if _, err := (Config{}).withDefaults(); !errors.Is(err, ErrNoEndpoint) {
+		t.Errorf("empty endpoint: err = %v", err)
+	}
+	if _, err := (Config{Endpoint: "localhost:4318", Protocol: "thrift"}).withDefaults(); err == nil {
+		t.Error("unsupported protocol accepted")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	rec := testlog.New(t)
+	for _, protocol := range []string{ProtocolHTTP, ProtocolGRPC} {
+		providers, shutdown, err := Setup(context.Background(), Config{
+			ServiceName: "orders",
+			Attributes:  map[string]string{"service.instance.id": "host-1"},
+			Endpoint:    "127.0.0.1:1",
+			Protocol:    protocol,
+			Insecure:    true,
+			Signals:     Traces | Logs,
+		}, rec.Logger)
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		if providers.TracerProvider == nil || providers.LoggerProvider == nil || providers.MeterProvider != nil {
+			t.Errorf("%s: wrong providers built: %+v", protocol, providers)
+		}
+		if v, ok := providers.Resource.Set().Value("service.instance.id"); !ok || v != attribute.StringValue("host-1") {
+			t.Errorf("%s: resource attribute missing: %v", protocol, providers.Resource)
+		}
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("%s: shutdown: %v", protocol, err)
+		}
+	}
+}
+
+func TestSetupSpool(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    "127.0.0.1:1",
+		Insecure:    true,
+		Signals:     Logs,
+		SpoolDir:    dir,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providers.Spool == nil {
+		t.Fatal("SpoolDir set but no spool built")
+	}
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("Order completed"))
+	providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+
+	// Nothing listens on port 1, so the final flush lands in the spool instead of failing
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if depth := providers.Spool.Depth(); depth.Batches != 1 || depth.Records != 1 {
+		t.Errorf("depth = %+v, want the unsent record spooled", depth)
+	}
+	rec.AssertLogged("warn", "OTLP export failed, batch spooled", "spool_records", 1)
+}
+
+func TestSetupDeadLetter(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName:   "orders",
+		Endpoint:      "127.0.0.1:1",
+		Insecure:      true,
+		Signals:       Logs,
+		DeadLetterDir: dir,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("Order completed"))
+	providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+
+	// Without a spool, the batch the exporter gives up on is dead-lettered
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	rec.AssertLogged("error", "OTLP batch dead-lettered", "records", 1, "dead_letter_dir", dir)
+}
+
+// certificate issues a certificate for name signed by parent, self-signed without one, and
+// writes it and its key to dir
+func certificate(t *testing.T, dir, name string, parent *tls.Certificate, template x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	signer, signerKey := &template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestSetupMutualTLS(t *testing.T) {
+	rec := testlog.New(t)
+	dir := t.TempDir()
+	ca := certificate(t, dir, "ca", nil, x509.Certificate{IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign})
+	serverCert := certificate(t, dir, "collector", &ca, x509.Certificate{IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}})
+	certificate(t, dir, "client", &ca, x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+
+	var exports atomic.Int64
+	collector := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/logs" && r.TLS.PeerCertificates[0].Subject.CommonName == "client" {
+			exports.Add(1)
+		}
+	}))
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	collector.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	}
+	collector.StartTLS()
+	defer collector.Close()
+
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    collector.Listener.Addr().String(),
+		Insecure:    true,
+		TLS: certwatch.Config{
+			CAFile:   filepath.Join(dir, "ca.crt"),
+			CertFile: filepath.Join(dir, "client.crt"),
+			KeyFile:  filepath.Join(dir, "client.key"),
+		},
+		Signals: Logs,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+	rec.AssertLogged("info", "TLS certificate loaded", "certificate", "client", "subject", "CN=client")
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("Order completed"))
+	providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+	if err := providers.LoggerProvider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := exports.Load(); n != 1 {
+		t.Errorf("collector accepted %d exports over mTLS, want 1", n)
+	}
+}
+
+func TestSetupSecretHeaders(t *testing.T) {
+	rec := testlog.New(t)
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("token-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var authorization atomic.Value
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+	}))
+	defer collector.Close()
+
+	if _, _, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    collector.URL,
+		Headers:     map[string]string{"authorization": "Bearer ${file:/nonexistent/token}"},
+		Signals:     Logs,
+	}, rec.Logger); err == nil {
+		t.Fatal("Setup accepted a header whose secret is missing")
+	}
+
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    collector.URL,
+		Headers:     map[string]string{"authorization": "Bearer ${file:" + token + "}"},
+		Signals:     Logs,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+	export := func() string {
+		t.Helper()
+		var record otellog.Record
+		record.SetBody(otellog.StringValue("Order completed"))
+		providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+		if err := providers.LoggerProvider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := authorization.Load().(string)
+		return got
+	}
+	if got := export(); got != "Bearer token-1" {
+		t.Fatalf("Authorization = %q, want the token from the file", got)
+	}
+
+	if err := os.WriteFile(token, []byte("token-2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(token, later, later)
+	if changed, err := providers.Secrets.Reload(); !changed || err != nil {
+		t.Fatalf("Reload = %v, %v after a rotation", changed, err)
+	}
+	rec.AssertLogged("info", "OTLP exporters reconnected with reloaded headers", "headers", []string{"authorization"})
+	if got := export(); got != "Bearer token-2" {
+		t.Errorf("Authorization = %q after a rotation, want the new token", got)
+	}
+}
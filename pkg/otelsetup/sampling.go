@@ -0,0 +1,204 @@
+package otelsetup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Samplers a SamplingConfig can name
+const (
+	// SamplerAlways records every trace
+	SamplerAlways = "always"
+	// SamplerNever records no trace
+	SamplerNever = "never"
+	// SamplerRatio keeps Ratio of the traces by trace ID, whatever the caller decided
+	SamplerRatio = "ratio"
+	// SamplerParentBased follows the caller's decision and keeps Ratio of the root traces
+	SamplerParentBased = "parent_based"
+)
+
+// SamplingConfig is head-based sampling as a demo's YAML file describes it:
+//
+//	sampling:
+//	  sampler: parent_based  # always, never, ratio or parent_based
+//	  ratio: 0.25            # share of traces ratio and parent_based keep, 1 when left out
+//	  log_interval: 1m       # how often the decisions are logged, never when left out
+type SamplingConfig struct {
+	// Sampler defaults to SamplerParentBased
+	Sampler string   `yaml:"sampler"`
+	Ratio   *float64 `yaml:"ratio"`
+	// LogInterval becomes Config.SamplingLogInterval
+	LogInterval time.Duration `yaml:"log_interval"`
+}
+
+// Validate reports a sampler otelsetup does not know or a ratio outside 0..1, naming the
+// YAML keys
+func (c SamplingConfig) Validate() error {
+	switch c.Sampler {
+	case "", SamplerAlways, SamplerNever, SamplerRatio, SamplerParentBased:
+	default:
+		return fmt.Errorf("sampling.sampler must be %s, %s, %s or %s, not %q",
+			SamplerAlways, SamplerNever, SamplerRatio, SamplerParentBased, c.Sampler)
+	}
+	if c.Ratio != nil && (*c.Ratio < 0 || *c.Ratio > 1) {
+		return fmt.Errorf("sampling.ratio must be between 0 and 1, not %g", *c.Ratio)
+	}
+	if c.LogInterval < 0 {
+		return fmt.Errorf("sampling.log_interval must not be negative")
+	}
+	return nil
+}
+
+// NewSampler builds the sampler c describes, for Config.Sampler
+func (c SamplingConfig) NewSampler() (sdktrace.Sampler, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	ratio := 1.0
+	if c.Ratio != nil {
+		ratio = *c.Ratio
+	}
+	switch c.Sampler {
+	case SamplerAlways:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerNever:
+		return sdktrace.NeverSample(), nil
+	case SamplerRatio:
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	default:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	}
+}
+
+// String names the sampler and its ratio for a startup log, such as parent_based(0.25)
+func (c SamplingConfig) String() string {
+	sampler := c.Sampler
+	if sampler == "" {
+		sampler = SamplerParentBased
+	}
+	if sampler == SamplerAlways || sampler == SamplerNever {
+		return sampler
+	}
+	ratio := 1.0
+	if c.Ratio != nil {
+		ratio = *c.Ratio
+	}
+	return fmt.Sprintf("%s(%g)", sampler, ratio)
+}
+
+// SamplingStats wraps a sampler, counts its decisions and logs every interval the share
+// of spans it sampled, so the rate a service really exports can be compared with the one
+// configured: with a parent-based sampler it follows the callers, not the ratio.
+type SamplingStats struct {
+	sampler  sdktrace.Sampler
+	logger   core.Logger
+	interval time.Duration
+	clock    clock.Clock
+
+	spans, sampled, roots, rootsSampled atomic.Uint64
+
+	// reportMu serializes reports; last holds the counts at the previous one
+	reportMu sync.Mutex
+	last     [4]uint64
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewSamplingStats counts the decisions of sampler; Setup makes one when
+// Config.SamplingLogInterval is set
+func NewSamplingStats(sampler sdktrace.Sampler, logger core.Logger, interval time.Duration) *SamplingStats {
+	return &SamplingStats{sampler: sampler, logger: logger, interval: interval, clock: clock.Real}
+}
+
+// ShouldSample asks the wrapped sampler and counts its answer; a span without a valid
+// parent is a root
+func (s *SamplingStats) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.sampler.ShouldSample(p)
+	sampled := result.Decision == sdktrace.RecordAndSample
+	s.spans.Add(1)
+	if sampled {
+		s.sampled.Add(1)
+	}
+	if !trace.SpanContextFromContext(p.ParentContext).IsValid() {
+		s.roots.Add(1)
+		if sampled {
+			s.rootsSampled.Add(1)
+		}
+	}
+	return result
+}
+
+// Description is the wrapped sampler's
+func (s *SamplingStats) Description() string {
+	return s.sampler.Description()
+}
+
+// Report logs the decisions since the previous report, unless there were none
+func (s *SamplingStats) Report() {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	now := [4]uint64{s.spans.Load(), s.sampled.Load(), s.roots.Load(), s.rootsSampled.Load()}
+	spans, sampled, roots, rootsSampled := now[0]-s.last[0], now[1]-s.last[1], now[2]-s.last[2], now[3]-s.last[3]
+	s.last = now
+	if spans == 0 {
+		return
+	}
+	fields := []interface{}{
+		"sampler", s.Description(),
+		"spans", spans,
+		"sampled", sampled,
+		"rate", rate(sampled, spans),
+		"root_spans", roots,
+	}
+	if roots > 0 {
+		fields = append(fields, "root_rate", rate(rootsSampled, roots))
+	}
+	s.logger.Infow("Trace sampling rate", fields...)
+}
+
+// rate is part/total rounded to three decimals
+func rate(part, total uint64) float64 {
+	return math.Round(float64(part)/float64(total)*1000) / 1000
+}
+
+// Start reports every interval until Stop
+func (s *SamplingStats) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := s.clock.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				s.Report()
+			}
+		}
+	}()
+}
+
+// Stop ends the reports started by Start
+func (s *SamplingStats) Stop() {
+	s.stopOnce.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+			<-s.done
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package otelsetup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSamplingConfig(t *testing.T) {
+	for sampler, want := range map[string]string{
+		"":                 "ParentBased{root:TraceIDRatioBased{0.25}",
+		SamplerParentBased: "ParentBased{root:TraceIDRatioBased{0.25}",
+		SamplerRatio:       "TraceIDRatioBased{0.25}",
+		SamplerAlways:      "AlwaysOnSampler",
+		SamplerNever:       "AlwaysOffSampler",
+	} {
+		var c SamplingConfig
+		if err := yaml.Unmarshal([]byte("sampler: \""+sampler+"\"\nratio: 0.25\nlog_interval: 1m\n"), &c); err != nil {
+			t.Fatal(err)
+		}
+		s, err := c.NewSampler()
+		if err != nil || !strings.HasPrefix(s.Description(), want) {
+			t.Errorf("sampler %q: %v, %v, want %s", sampler, s, err, want)
+		}
+	}
+	if s, _ := (SamplingConfig{}).NewSampler(); !strings.HasPrefix(s.Description(), "ParentBased{root:AlwaysOnSampler") {
+		t.Errorf("zero config = %s, want every root sampled", s.Description())
+	}
+
+	ratio := 1.5
+	for name, c := range map[string]SamplingConfig{
+		"unknown sampler": {Sampler: "probabilistic"},
+		"ratio above 1":   {Sampler: SamplerRatio, Ratio: &ratio},
+	} {
+		if _, err := c.NewSampler(); err == nil || !strings.Contains(err.Error(), "sampling.") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestSamplingStats(t *testing.T) {
+	rec := testlog.New(t)
+	ratio := 0.0
+	sampler, err := SamplingConfig{Sampler: SamplerParentBased, Ratio: &ratio}.NewSampler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := NewSamplingStats(sampler, rec.Logger, time.Minute)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(stats))
+	defer provider.Shutdown(context.Background())
+	tracer := provider.Tracer("orders")
+
+	// Four roots, none sampled at ratio 0; one child of a sampled caller, which is kept
+	for range 4 {
+		_, span := tracer.Start(context.Background(), "GET /orders/:id")
+		span.End()
+	}
+	caller := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	_, span := tracer.Start(caller, "GET /orders/:id")
+	span.End()
+
+	stats.Report()
+	rec.AssertLogged("info", "Trace sampling rate", "sampler", sampler.Description(),
+		"spans", uint64(5), "sampled", uint64(1), "rate", 0.2, "root_spans", uint64(4), "root_rate", 0.0)
+
+	// Only the decisions since the last report count, and an idle interval is not logged
+	stats.Report()
+	if n := rec.Count("info", "Trace sampling rate"); n != 1 {
+		t.Errorf("logged %d times, want once", n)
+	}
+}
+
+func TestSetupSamplingLog(t *testing.T) {
+	rec := testlog.New(t)
+	collector, _ := flakyCollector(t)
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName:         "orders",
+		Endpoint:            collector.URL,
+		Signals:             Traces,
+		SamplingLogInterval: time.Hour,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providers.Sampling == nil {
+		t.Fatal("no sampling stats with SamplingLogInterval set")
+	}
+	_, span := providers.TracerProvider.Tracer("orders").Start(context.Background(), "GET /orders/:id")
+	span.End()
+
+	// Shutdown logs what the interval had not yet reported
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec.AssertLogged("info", "Trace sampling rate", "spans", uint64(1), "rate", 1.0)
+}
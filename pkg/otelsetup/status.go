@@ -0,0 +1,179 @@
+package otelsetup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// Sources of a Status
+const (
+	// SourceExports is the outcome of the exports of the pipeline Setup built
+	SourceExports = "exports"
+	// SourceProbe is the outcome of a Monitor's handshakes, for a pipeline otelsetup cannot
+	// look into
+	SourceProbe = "probe"
+)
+
+// Status is the state of an OTLP pipeline, for a debug endpoint
+type Status struct {
+	Protocol string
+	Endpoint string
+	Source   string
+	// Connected is true when the last export or handshake reached the collector
+	Connected   bool
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+	// Failures counts the exports or handshakes that failed since the last success
+	Failures int
+	// QueueDepth is the records waiting in the spool, 0 without one since failed batches
+	// are not kept; -1 when the queue cannot be read, as with a Monitor
+	QueueDepth int
+}
+
+// exportState is what the exports, or handshakes, of a pipeline last returned
+type exportState struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
+	failures    int
+}
+
+// record notes the outcome of an export and returns err
+func (s *exportState) record(err error) error {
+	if s == nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.lastSuccess = time.Now()
+		s.failures = 0
+		return nil
+	}
+	s.lastError, s.lastErrorAt = err.Error(), time.Now()
+	s.failures++
+	return err
+}
+
+// fill copies the state into st
+func (s *exportState) fill(st Status) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.Connected = !s.lastSuccess.IsZero() && s.failures == 0
+	st.LastSuccess = s.lastSuccess
+	st.LastError, st.LastErrorAt = s.lastError, s.lastErrorAt
+	st.Failures = s.failures
+	return st
+}
+
+// Status reports whether the exporters reach the collector and what the spool holds.
+// Before the first export it is not connected and has no error.
+func (p *Providers) Status() Status {
+	protocol, endpoint := p.Protocol()
+	st := p.state.fill(Status{Protocol: protocol, Endpoint: endpoint, Source: SourceExports})
+	if p.Spool != nil {
+		st.QueueDepth = p.Spool.Depth().Records
+	}
+	return st
+}
+
+// Preflight sends the collector in cfg an empty export request and waits at most
+// cfg.Timeout for the answer, so a service can say at startup whether its telemetry will
+// arrive instead of finding out from missing data. The error is the handshake's, or why
+// cfg is not usable.
+func Preflight(ctx context.Context, cfg Config) (Handshake, error) {
+	cfg, err := cfg.load()
+	if err != nil {
+		return Handshake{}, err
+	}
+	h := handshake(ctx, cfg)
+	return h, h.Err
+}
+
+// Monitor repeats the Preflight handshake for a pipeline otelsetup did not build, such as
+// the logger's built-in OTLP exporter, whose exports cannot be observed. It logs the first
+// result and every change between reachable and unreachable.
+type Monitor struct {
+	cfg      Config
+	logger   core.Logger
+	interval time.Duration
+	clock    clock.Clock
+	state    exportState
+
+	checkMu sync.Mutex
+	checked bool
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewMonitor checks cfg every interval once started; the TLS files and header secrets are
+// read once, here
+func NewMonitor(cfg Config, logger core.Logger, interval time.Duration) (*Monitor, error) {
+	cfg, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	return &Monitor{cfg: cfg, logger: logger, interval: interval, clock: clock.Real}, nil
+}
+
+// Check runs one handshake and records its outcome
+func (m *Monitor) Check(ctx context.Context) Handshake {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	h := handshake(ctx, m.cfg)
+	wasConnected := m.state.fill(Status{}).Connected
+	m.state.record(h.Err)
+	first := !m.checked
+	m.checked = true
+	switch {
+	case h.Err != nil && (first || wasConnected):
+		m.logger.Warnw("OTLP collector unreachable", h.fields()...)
+	case h.Err == nil && (first || !wasConnected):
+		m.logger.Infow("OTLP collector reachable", h.fields()...)
+	}
+	return h
+}
+
+// Status reports the outcome of the handshakes so far
+func (m *Monitor) Status() Status {
+	return m.state.fill(Status{Protocol: m.cfg.Protocol, Endpoint: m.cfg.Endpoint, Source: SourceProbe, QueueDepth: -1})
+}
+
+// Start checks every interval until Stop
+func (m *Monitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := m.clock.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the checks started by Start
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() {
+		if m.cancel != nil {
+			m.cancel()
+			<-m.done
+		}
+	})
+}
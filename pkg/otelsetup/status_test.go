@@ -0,0 +1,112 @@
+package otelsetup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+// flakyCollector accepts exports until reject is set, then answers 400, which the
+// exporters do not retry
+func flakyCollector(t *testing.T) (*httptest.Server, *atomic.Bool) {
+	t.Helper()
+	var reject atomic.Bool
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if reject.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(collector.Close)
+	return collector, &reject
+}
+
+func TestPreflight(t *testing.T) {
+	collector, _ := flakyCollector(t)
+	h, err := Preflight(context.Background(), Config{Endpoint: collector.URL, Signals: Logs})
+	if err != nil || h.Result != "200 OK" {
+		t.Errorf("reachable collector: %+v, %v", h, err)
+	}
+	if h, err := Preflight(context.Background(), Config{Endpoint: "http://127.0.0.1:1"}); err == nil || h.Result != "unreachable" {
+		t.Errorf("unreachable collector: %+v, %v", h, err)
+	}
+	if _, err := Preflight(context.Background(), Config{}); !errors.Is(err, ErrNoEndpoint) {
+		t.Errorf("no endpoint: err = %v", err)
+	}
+}
+
+func TestProvidersStatus(t *testing.T) {
+	collector, reject := flakyCollector(t)
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    collector.URL,
+		Signals:     Logs,
+	}, testlog.New(t).Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+	if st := providers.Status(); st.Connected || st.LastError != "" || st.Source != SourceExports {
+		t.Errorf("status before any export = %+v", st)
+	}
+
+	export := func() {
+		var record otellog.Record
+		record.SetBody(otellog.StringValue("Order completed"))
+		providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+		providers.LoggerProvider.ForceFlush(context.Background())
+	}
+	export()
+	if st := providers.Status(); !st.Connected || st.LastSuccess.IsZero() || st.Failures != 0 {
+		t.Errorf("status after an export = %+v", st)
+	}
+
+	reject.Store(true)
+	export()
+	export()
+	st := providers.Status()
+	if st.Connected || st.Failures != 2 || st.LastError == "" || st.LastErrorAt.Before(st.LastSuccess) {
+		t.Errorf("status after failed exports = %+v", st)
+	}
+	if st.QueueDepth != 0 {
+		t.Errorf("queue depth = %d without a spool", st.QueueDepth)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	rec := testlog.New(t)
+	collector, reject := flakyCollector(t)
+	m, err := NewMonitor(Config{Endpoint: collector.URL, Signals: Logs}, rec.Logger, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Check(context.Background())
+	rec.AssertLogged("info", "OTLP collector reachable", "protocol", ProtocolHTTP, "handshake", "200 OK")
+	if st := m.Status(); !st.Connected || st.Source != SourceProbe || st.QueueDepth != -1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	// Only the change is logged, not every failed check
+	reject.Store(true)
+	m.Check(context.Background())
+	m.Check(context.Background())
+	if n := rec.Count("warn", "OTLP collector unreachable"); n != 1 {
+		t.Errorf("unreachable logged %d times, want once", n)
+	}
+	if st := m.Status(); st.Connected || st.Failures != 2 {
+		t.Errorf("status = %+v", st)
+	}
+
+	reject.Store(false)
+	m.Check(context.Background())
+	if n := rec.Count("info", "OTLP collector reachable"); n != 2 {
+		t.Errorf("reachable logged %d times, want twice", n)
+	}
+}
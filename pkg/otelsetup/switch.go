@@ -0,0 +1,386 @@
+package otelsetup
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Standard OTLP ports; SwitchProtocol moves an endpoint on one to the other
+const (
+	DefaultGRPCPort = "4317"
+	DefaultHTTPPort = "4318"
+)
+
+// Handshake is the outcome of checking a collector over one protocol: an empty export
+// request, which a collector answers like any other
+type Handshake struct {
+	Protocol string
+	Endpoint string
+	// Result is the HTTP status or gRPC code the collector answered with
+	Result  string
+	Latency time.Duration
+	Err     error
+}
+
+// fields returns the handshake as key-value pairs for a log entry
+func (h Handshake) fields() []interface{} {
+	fields := []interface{}{
+		"protocol", h.Protocol,
+		"endpoint", h.Endpoint,
+		"handshake", h.Result,
+		"handshake_ms", h.Latency.Milliseconds(),
+	}
+	if h.Err != nil {
+		fields = append(fields, "error", h.Err.Error())
+	}
+	return fields
+}
+
+// Protocol returns the protocol and endpoint the exporters currently use
+func (p *Providers) Protocol() (protocol, endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cfg.Protocol, p.cfg.Endpoint
+}
+
+// SwitchProtocol re-establishes every exporter over protocol. An empty endpoint keeps the
+// current one, except that the standard port of the current protocol becomes the standard
+// port of the new one. The collector is checked with a handshake first; when that fails
+// the pipeline is left as it was. Either way the result is logged.
+//
+// The providers, and the tracers, meters and loggers taken from them, stay the same: only
+// the exporters underneath are replaced, after the exports in flight on the old ones
+// finished.
+func (p *Providers) SwitchProtocol(ctx context.Context, protocol, endpoint string) (Handshake, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.cfg
+	cfg := p.cfg
+	cfg.Protocol = protocol
+	if endpoint != "" {
+		cfg.Endpoint = endpoint
+	}
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return Handshake{}, err
+	}
+	if endpoint == "" {
+		cfg.Endpoint = switchPort(previous.Endpoint, previous.Protocol, cfg.Protocol)
+	}
+
+	h := handshake(ctx, cfg)
+	fields := append([]interface{}{"previous_protocol", previous.Protocol, "previous_endpoint", previous.Endpoint}, h.fields()...)
+	if h.Err != nil {
+		p.logger.Warnw("OTLP protocol switch failed, keeping the current exporters", fields...)
+		return h, h.Err
+	}
+	if err := p.swapExporters(ctx, cfg); err != nil {
+		p.logger.Warnw("OTLP protocol switch failed, keeping the current exporters", append(fields, "error", err.Error())...)
+		return h, err
+	}
+	p.cfg = cfg
+	p.state.record(nil)
+	if p.Spool != nil {
+		p.Spool.SetEndpoint(cfg.Endpoint)
+	}
+	p.logger.Infow("OTLP protocol switched", fields...)
+	return h, nil
+}
+
+// reconnect replaces the exporters with ones that send headers, after the secrets they
+// reference were rotated. Only the header names are logged.
+func (p *Providers) reconnect(headers map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cfg := p.cfg
+	cfg.Headers = headers
+	names := slices.Sorted(maps.Keys(headers))
+	if err := p.swapExporters(context.Background(), cfg); err != nil {
+		p.logger.Warnw("OTLP exporters not reconnected, keeping the previous headers", "headers", names, "error", err.Error())
+		return
+	}
+	p.cfg = cfg
+	p.logger.Infow("OTLP exporters reconnected with reloaded headers", "headers", names, "protocol", cfg.Protocol, "endpoint", cfg.Endpoint)
+}
+
+// swapExporters creates every exporter for cfg before replacing any, so a failure leaves
+// all signals on the old protocol
+func (p *Providers) swapExporters(ctx context.Context, cfg Config) error {
+	var (
+		spans   sdktrace.SpanExporter
+		metrics sdkmetric.Exporter
+		logs    sdklog.Exporter
+		err     error
+	)
+	if p.spanExporter != nil {
+		if spans, err = newTraceExporter(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+	}
+	if p.metricExporter != nil {
+		if metrics, err = newMetricExporter(ctx, cfg); err != nil {
+			if spans != nil {
+				spans.Shutdown(ctx)
+			}
+			return fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+	}
+	if p.logExporter != nil {
+		if logs, err = newLogExporter(ctx, cfg); err != nil {
+			if spans != nil {
+				spans.Shutdown(ctx)
+			}
+			if metrics != nil {
+				metrics.Shutdown(ctx)
+			}
+			return fmt.Errorf("failed to create OTLP log exporter: %w", err)
+		}
+	}
+
+	// The old exporters have nothing queued of their own: the processors and readers
+	// hold the pending data and hand it to the new ones
+	if spans != nil {
+		p.spanExporter.swap(spans).Shutdown(ctx)
+	}
+	if metrics != nil {
+		p.metricExporter.swap(metrics).Shutdown(ctx)
+	}
+	if logs != nil {
+		p.logExporter.swap(logs).Shutdown(ctx)
+	}
+	return nil
+}
+
+// switchPort moves endpoint from the standard port of one protocol to the other's
+func switchPort(endpoint, from, to string) string {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || from == to {
+		return endpoint
+	}
+	switch {
+	case from == ProtocolGRPC && port == DefaultGRPCPort:
+		return net.JoinHostPort(host, DefaultHTTPPort)
+	case from == ProtocolHTTP && port == DefaultHTTPPort:
+		return net.JoinHostPort(host, DefaultGRPCPort)
+	}
+	return endpoint
+}
+
+// handshake sends an empty export request for the first signal cfg exports
+func handshake(ctx context.Context, cfg Config) Handshake {
+	h := Handshake{Protocol: cfg.Protocol, Endpoint: cfg.Endpoint}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if cfg.Protocol == ProtocolGRPC {
+		h.Result, h.Err = grpcHandshake(ctx, cfg)
+	} else {
+		h.Result, h.Err = httpHandshake(ctx, cfg)
+	}
+	h.Latency = time.Since(start)
+	return h
+}
+
+func httpHandshake(ctx context.Context, cfg Config) (string, error) {
+	scheme := "https"
+	if cfg.Insecure {
+		scheme = "http"
+	}
+	path := "/v1/logs"
+	switch {
+	case cfg.Signals&Traces != 0:
+		path = "/v1/traces"
+	case cfg.Signals&Metrics != 0:
+		path = "/v1/metrics"
+	}
+
+	// An empty protobuf body is an export request without data
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+cfg.Endpoint+path, bytes.NewReader(nil))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	client := http.DefaultClient
+	if cfg.tlsConfig != nil {
+		transport := &http.Transport{TLSClientConfig: cfg.tlsConfig}
+		defer transport.CloseIdleConnections()
+		client = &http.Client{Transport: transport}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "unreachable", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return resp.Status, fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return resp.Status, nil
+}
+
+func grpcHandshake(ctx context.Context, cfg Config) (string, error) {
+	creds := credentials.NewTLS(&tls.Config{})
+	switch {
+	case cfg.tlsConfig != nil:
+		creds = credentials.NewTLS(cfg.tlsConfig)
+	case cfg.Insecure:
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(cfg.Headers))
+	switch {
+	case cfg.Signals&Traces != 0:
+		_, err = coltracev1.NewTraceServiceClient(conn).Export(ctx, &coltracev1.ExportTraceServiceRequest{})
+	case cfg.Signals&Metrics != 0:
+		_, err = colmetricsv1.NewMetricsServiceClient(conn).Export(ctx, &colmetricsv1.ExportMetricsServiceRequest{})
+	default:
+		_, err = collogsv1.NewLogsServiceClient(conn).Export(ctx, &collogsv1.ExportLogsServiceRequest{})
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "timeout", err
+		}
+		return status.Code(err).String(), err
+	}
+	return "OK", nil
+}
+
+// swapSpanExporter forwards to an exporter that SwitchProtocol can replace. Exports hold
+// the read lock, so a swap waits for the ones in flight.
+type swapSpanExporter struct {
+	mu    sync.RWMutex
+	next  sdktrace.SpanExporter
+	state *exportState
+}
+
+func (e *swapSpanExporter) swap(next sdktrace.SpanExporter) sdktrace.SpanExporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.next
+	e.next = next
+	return old
+}
+
+func (e *swapSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.state.record(e.next.ExportSpans(ctx, spans))
+}
+
+func (e *swapSpanExporter) Shutdown(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.Shutdown(ctx)
+}
+
+// swapMetricExporter is swapSpanExporter for metrics; the OTLP exporters of both protocols
+// use the same temporality and aggregation
+type swapMetricExporter struct {
+	mu    sync.RWMutex
+	next  sdkmetric.Exporter
+	state *exportState
+}
+
+func (e *swapMetricExporter) swap(next sdkmetric.Exporter) sdkmetric.Exporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.next
+	e.next = next
+	return old
+}
+
+func (e *swapMetricExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.Temporality(kind)
+}
+
+func (e *swapMetricExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.Aggregation(kind)
+}
+
+func (e *swapMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.state.record(e.next.Export(ctx, rm))
+}
+
+func (e *swapMetricExporter) ForceFlush(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *swapMetricExporter) Shutdown(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.Shutdown(ctx)
+}
+
+// swapLogExporter is swapSpanExporter for logs; it sits under the spool, so spooled
+// batches replay over whichever protocol is current
+type swapLogExporter struct {
+	mu    sync.RWMutex
+	next  sdklog.Exporter
+	state *exportState
+}
+
+func (e *swapLogExporter) swap(next sdklog.Exporter) sdklog.Exporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.next
+	e.next = next
+	return old
+}
+
+func (e *swapLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.state.record(e.next.Export(ctx, records))
+}
+
+func (e *swapLogExporter) ForceFlush(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *swapLogExporter) Shutdown(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.next.Shutdown(ctx)
+}
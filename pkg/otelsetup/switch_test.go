@@ -0,0 +1,130 @@
+package otelsetup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	otellog "go.opentelemetry.io/otel/log"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// grpcCollector counts the log records it is sent over OTLP/gRPC
+type grpcCollector struct {
+	coltracev1.UnimplementedTraceServiceServer
+	collogsv1.UnimplementedLogsServiceServer
+	records atomic.Int64
+}
+
+func (c *grpcCollector) Export(context.Context, *coltracev1.ExportTraceServiceRequest) (*coltracev1.ExportTraceServiceResponse, error) {
+	return &coltracev1.ExportTraceServiceResponse{}, nil
+}
+
+type grpcLogs struct{ *grpcCollector }
+
+func (c grpcLogs) Export(_ context.Context, req *collogsv1.ExportLogsServiceRequest) (*collogsv1.ExportLogsServiceResponse, error) {
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			c.records.Add(int64(len(sl.GetLogRecords())))
+		}
+	}
+	return &collogsv1.ExportLogsServiceResponse{}, nil
+}
+
+func startGRPCCollector(t *testing.T) (*grpcCollector, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &grpcCollector{}
+	server := grpc.NewServer()
+	coltracev1.RegisterTraceServiceServer(server, c)
+	collogsv1.RegisterLogsServiceServer(server, grpcLogs{c})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return c, listener.Addr().String()
+}
+
+func TestSwitchProtocol(t *testing.T) {
+	rec := testlog.New(t)
+	var httpExports atomic.Int64
+	httpCollector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		httpExports.Add(1)
+	}))
+	defer httpCollector.Close()
+	grpcCollector, grpcEndpoint := startGRPCCollector(t)
+
+	providers, shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "orders",
+		Endpoint:    httpCollector.URL,
+		Signals:     Traces | Logs,
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	if _, err := providers.SwitchProtocol(context.Background(), "thrift", ""); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("unsupported protocol: err = %v", err)
+	}
+
+	h, err := providers.SwitchProtocol(context.Background(), ProtocolGRPC, grpcEndpoint)
+	if err != nil {
+		t.Fatalf("switch to grpc: %v", err)
+	}
+	if h.Result != "OK" || h.Protocol != ProtocolGRPC {
+		t.Errorf("handshake = %+v", h)
+	}
+	if protocol, endpoint := providers.Protocol(); protocol != ProtocolGRPC || endpoint != grpcEndpoint {
+		t.Errorf("protocol = %s %s after the switch", protocol, endpoint)
+	}
+	rec.AssertLogged("info", "OTLP protocol switched", "previous_protocol", ProtocolHTTP, "protocol", ProtocolGRPC, "handshake", "OK")
+
+	// The same LoggerProvider now exports over gRPC
+	before := httpExports.Load()
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("Order completed"))
+	providers.LoggerProvider.Logger("orders").Emit(context.Background(), record)
+	if err := providers.LoggerProvider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := grpcCollector.records.Load(); n != 1 {
+		t.Errorf("grpc collector received %d records, want 1", n)
+	}
+	if n := httpExports.Load(); n != before {
+		t.Errorf("http collector still receiving exports")
+	}
+
+	// A collector that does not answer leaves the pipeline as it was
+	if _, err := providers.SwitchProtocol(context.Background(), ProtocolHTTP, "127.0.0.1:1"); err == nil {
+		t.Fatal("switch to an unreachable collector succeeded")
+	}
+	if protocol, _ := providers.Protocol(); protocol != ProtocolGRPC {
+		t.Errorf("protocol = %s after a failed switch", protocol)
+	}
+	rec.AssertLogged("warn", "OTLP protocol switch failed, keeping the current exporters", "protocol", ProtocolHTTP, "handshake", "unreachable")
+}
+
+func TestSwitchPort(t *testing.T) {
+	tests := []struct {
+		endpoint, from, to, want string
+	}{
+		{"localhost:4317", ProtocolGRPC, ProtocolHTTP, "localhost:4318"},
+		{"localhost:4318", ProtocolHTTP, ProtocolGRPC, "localhost:4317"},
+		{"collector:9000", ProtocolHTTP, ProtocolGRPC, "collector:9000"},
+		{"localhost:4318", ProtocolHTTP, ProtocolHTTP, "localhost:4318"},
+	}
+	for _, tt := range tests {
+		if got := switchPort(tt.endpoint, tt.from, tt.to); got != tt.want {
+			t.Errorf("switchPort(%s, %s, %s) = %s, want %s", tt.endpoint, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
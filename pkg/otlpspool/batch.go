@@ -0,0 +1,200 @@
+package otlpspool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// File is one batch file in a spool or dead-letter directory. Files are named
+// <unix nanos>-<sequence>-<records>.json, so a listing is oldest first and a directory's
+// depth can be counted without reading them.
+type File struct {
+	Name    string
+	Records int
+	Size    int64
+	// Time is when the oldest record of the batch was first observed, which a replayed
+	// record keeps; a batch spooled again after a failed replay stays as old as it was
+	Time time.Time
+}
+
+// List returns the batch files in dir, oldest first
+func List(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, entry := range entries {
+		name := entry.Name()
+		parts := strings.Split(strings.TrimSuffix(name, ".json"), "-")
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || len(parts) != 3 {
+			continue
+		}
+		f := File{Name: name}
+		if nanos, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+			f.Time = time.Unix(0, nanos)
+		}
+		f.Records, _ = strconv.Atoi(parts[2])
+		if info, err := entry.Info(); err == nil {
+			f.Size = info.Size()
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// depth sums the files in dir
+func depth(dir string) Depth {
+	var d Depth
+	files, _ := List(dir)
+	for _, f := range files {
+		d.Batches++
+		d.Records += f.Records
+		d.Bytes += f.Size
+	}
+	return d
+}
+
+// batchFile is the content of a batch file: one resource, as a batch comes from one
+// provider, and its records
+type batchFile struct {
+	Resource spooledResource `json:"resource"`
+	Records  []spooledRecord `json:"records"`
+}
+
+// Batch is a batch file read back
+type Batch struct {
+	file batchFile
+}
+
+// ReadBatch reads the batch file at path
+func ReadBatch(path string) (*Batch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Batch
+	if err := json.Unmarshal(data, &b.file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &b, nil
+}
+
+// Len returns the number of records in the batch
+func (b *Batch) Len() int {
+	return len(b.file.Records)
+}
+
+// Resource returns the resource the batch was exported with
+func (b *Batch) Resource() *resource.Resource {
+	return b.file.Resource.resource()
+}
+
+// Emit hands every record to provider, in order, under its original scope and trace.
+// The records take the provider's resource; give it Resource to keep the original.
+func (b *Batch) Emit(ctx context.Context, provider *sdklog.LoggerProvider) {
+	for _, r := range b.file.Records {
+		r.emit(ctx, provider)
+	}
+}
+
+// seq tells apart batches written in the same nanosecond, across spools and dead letters
+var seq atomic.Uint32
+
+// writeBatch stores records in dir under a new name. The file is written aside and
+// renamed, so a crash never leaves half a batch to replay.
+func writeBatch(dir string, records []sdklog.Record) (File, error) {
+	file := batchFile{Records: make([]spooledRecord, len(records))}
+	for i, r := range records {
+		file.Records[i] = toSpooled(r)
+	}
+	if len(records) > 0 {
+		file.Resource = toSpooledResource(records[0].Resource())
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return File{}, err
+	}
+
+	created := firstObserved(records)
+	f := File{
+		Name:    fmt.Sprintf("%020d-%06d-%d.json", created.UnixNano(), seq.Add(1)%1000000, len(records)),
+		Records: len(records),
+		Size:    int64(len(data)),
+		Time:    created,
+	}
+	tmp, err := os.CreateTemp(dir, ".batch-*")
+	if err != nil {
+		return File{}, err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return File{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return File{}, err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, f.Name)); err != nil {
+		os.Remove(tmp.Name())
+		return File{}, err
+	}
+	return f, nil
+}
+
+// firstObserved returns the earliest observed time of records, or now if none has one.
+// The SDK sets it when a record is first emitted and Emit hands it back on replay.
+func firstObserved(records []sdklog.Record) time.Time {
+	first := time.Now()
+	for _, r := range records {
+		if t := r.ObservedTimestamp(); !t.IsZero() && t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
+
+// moveBatch moves a batch file to another directory under the same name, copying when
+// the two are on different filesystems
+func moveBatch(from, toDir string) error {
+	to := filepath.Join(toDir, filepath.Base(from))
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(toDir, ".batch-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), to); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Remove(from)
+}
@@ -0,0 +1,94 @@
+package otlpspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kart-io/logger/core"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// DeadLetter keeps batches that will not be retried any more, in the same file format as
+// the spool, until cmd/otlp-replay sends them to a collector. Every batch written is logged
+// at error level with the dead-letter depth, so nothing is lost without a trace.
+type DeadLetter struct {
+	dir    string
+	logger core.Logger
+}
+
+// NewDeadLetter keeps dead-lettered batches in dir, creating it
+func NewDeadLetter(dir string, logger core.Logger) (*DeadLetter, error) {
+	if dir == "" {
+		return nil, errors.New("otlpspool: no dead-letter directory configured")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("otlpspool: failed to create dead-letter directory: %w", err)
+	}
+	return &DeadLetter{dir: dir, logger: logger}, nil
+}
+
+// Dir returns the directory batches are kept in
+func (d *DeadLetter) Dir() string {
+	return d.dir
+}
+
+// Depth returns what the dead-letter directory holds
+func (d *DeadLetter) Depth() Depth {
+	return depth(d.dir)
+}
+
+// Write dead-letters records, which failed with reason
+func (d *DeadLetter) Write(records []sdklog.Record, reason error) error {
+	f, err := writeBatch(d.dir, records)
+	if err != nil {
+		return fmt.Errorf("otlpspool: failed to dead-letter batch: %w", err)
+	}
+	d.logged(f, reason.Error())
+	return nil
+}
+
+// adopt moves a spooled batch file in; fields are logged with the reason
+func (d *DeadLetter) adopt(spoolDir string, f File, reason string, fields ...interface{}) error {
+	if err := moveBatch(filepath.Join(spoolDir, f.Name), d.dir); err != nil {
+		return fmt.Errorf("otlpspool: failed to dead-letter %s: %w", f.Name, err)
+	}
+	d.logged(f, reason, fields...)
+	return nil
+}
+
+func (d *DeadLetter) logged(f File, reason string, fields ...interface{}) {
+	depth := d.Depth()
+	d.logger.Errorw("OTLP batch dead-lettered", append([]interface{}{
+		"reason", reason,
+		"file", f.Name,
+		"records", f.Records,
+		"dead_letter_dir", d.dir,
+		"dead_letter_batches", depth.Batches,
+		"dead_letter_records", depth.Records,
+	}, fields...)...)
+}
+
+// Exporter wraps next, which should retry on its own, so a batch it gives up on is
+// dead-lettered instead of dropped
+func (d *DeadLetter) Exporter(next sdklog.Exporter) sdklog.Exporter {
+	return &deadLetterExporter{Exporter: next, deadLetter: d}
+}
+
+type deadLetterExporter struct {
+	sdklog.Exporter
+	deadLetter *DeadLetter
+}
+
+func (e *deadLetterExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	err := e.Exporter.Export(ctx, records)
+	if err == nil || len(records) == 0 {
+		return err
+	}
+	if werr := e.deadLetter.Write(records, err); werr != nil {
+		return errors.Join(err, werr)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package otlpspool
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func newDeadLetter(t *testing.T) (*DeadLetter, *testlog.Recorder) {
+	t.Helper()
+	rec := testlog.New(t)
+	deadLetter, err := NewDeadLetter(t.TempDir(), rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deadLetter, rec
+}
+
+func TestDeadLetterKeepsResource(t *testing.T) {
+	deadLetter, rec := newDeadLetter(t)
+	next := &fakeExporter{down: true}
+	res := resource.NewWithAttributes("https://opentelemetry.io/schemas/1.26.0",
+		attribute.String("service.name", "orders"),
+		attribute.Int64("service.shard", 3),
+		attribute.StringSlice("host.ip", []string{"10.0.0.1", "10.0.0.2"}),
+	)
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(deadLetter.Exporter(next))),
+		sdklog.WithResource(res),
+	)
+	defer provider.Shutdown(context.Background())
+
+	emit(context.Background(), provider, "Order completed")
+	rec.AssertLogged("error", "OTLP batch dead-lettered", "reason", "connection refused", "records", 1, "dead_letter_batches", 1)
+
+	files, err := List(deadLetter.Dir())
+	if err != nil || len(files) != 1 {
+		t.Fatalf("dead-letter files = %v (%v)", files, err)
+	}
+	b, err := ReadBatch(filepath.Join(deadLetter.Dir(), files[0].Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Resource(); !got.Equal(res) {
+		t.Errorf("resource = %v, want %v", got, res)
+	}
+
+	// What cmd/otlp-replay does: a provider with the batch's own resource
+	replayed := &fakeExporter{}
+	replay := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(replayed)), sdklog.WithResource(b.Resource()))
+	defer replay.Shutdown(context.Background())
+	b.Emit(context.Background(), replay)
+	got := replayed.records()
+	if len(got) != 1 || got[0].Body().AsString() != "Order completed" {
+		t.Fatalf("replayed %v", got)
+	}
+	if r := got[0].Resource(); !r.Equal(res) {
+		t.Errorf("replayed resource = %v", r)
+	}
+}
+
+func TestSpoolHandsOverToDeadLetter(t *testing.T) {
+	deadLetter, dlRec := newDeadLetter(t)
+	spool, _, provider, _ := newSpool(t, Config{DeadLetter: deadLetter, MaxAge: time.Hour, MaxBytes: 1})
+	emit(context.Background(), provider, "first")
+	emit(context.Background(), provider, "second")
+
+	// A full spool pushes the oldest batch out to the dead letter rather than deleting it
+	dlRec.AssertLogged("error", "OTLP batch dead-lettered", "reason", "spool full", "records", 1, "max_bytes", 1)
+	if d := deadLetter.Depth(); d.Batches != 1 || spool.Depth().Batches != 1 {
+		t.Fatalf("dead letter %+v, spool %+v", d, spool.Depth())
+	}
+
+	// A batch past MaxAge is handed over on the next replay, reachable or not
+	spool.cfg.MaxAge = time.Nanosecond
+	spool.Start(provider, "127.0.0.1:1")
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dlRec.AssertLogged("error", "OTLP batch dead-lettered", "reason", "not delivered within 1ns", "dead_letter_batches", 2, "max_age", "1ns")
+	if d := spool.Depth(); d.Batches != 0 {
+		t.Errorf("spool still holds %+v", d)
+	}
+}
@@ -0,0 +1,361 @@
+// Package otlpspool keeps OTLP log batches the collector did not accept on local disk and
+// replays them once it is reachable again.
+//
+// Without it, a collector that is down (gin-demo's "connection may fail" case) costs every
+// batch exported meanwhile: the SDK reports the error and drops the records. A Spool wraps
+// the log exporter instead. A failed batch is written to Config.Dir as one JSON file and
+// the export reports success, so the batch processor carries on. A background loop probes
+// the endpoint with backoff and, once it answers, emits the spooled records back through
+// the LoggerProvider, oldest first, deleting each file as it goes. Every spool, replay and
+// drop is logged with the spool depth, so a backlog building up is visible.
+//
+// Batches the spool gives up on, because they stayed undelivered for Config.MaxAge or a
+// full spool pushed them out, go to a DeadLetter directory when one is configured. A
+// DeadLetter can also wrap a retrying exporter on its own. Its files keep the resource
+// they were exported with, and cmd/otlp-replay sends them to a collector later.
+//
+// The logger given to New must not export through the spooled provider, or a collector
+// outage would feed its own warnings back into the spool. Replayed records keep their
+// timestamps, scope, attributes and trace context but take the provider's resource.
+package otlpspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// Defaults applied by New to zero Config fields
+const (
+	DefaultMaxBytes   = 64 << 20
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+	DefaultProbe      = 2 * time.Second
+	DefaultMaxAge     = time.Hour
+)
+
+// ErrUnreachable is returned by Replay when the collector does not accept a connection
+var ErrUnreachable = errors.New("otlpspool: collector unreachable")
+
+// Config says where batches are kept and how hard the replay loop retries
+type Config struct {
+	// Dir is created if missing; batches left there by a previous run are replayed too
+	Dir string
+	// MaxBytes caps the spool; the oldest batches are dropped to stay under it
+	MaxBytes int64
+	// MinBackoff and MaxBackoff bound the wait between replay attempts, which doubles
+	// after each unreachable probe
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Probe is the dial timeout used to check the collector before replaying
+	Probe time.Duration
+	// DeadLetter, when set, receives batches instead of them being deleted: those still
+	// undelivered after MaxAge, those pushed out by MaxBytes and unreadable ones
+	DeadLetter *DeadLetter
+	MaxAge     time.Duration
+}
+
+// Depth is what the spool holds
+type Depth struct {
+	Batches int
+	Records int
+	Bytes   int64
+}
+
+// fields returns the depth as key-value pairs for a log entry
+func (d Depth) fields() []interface{} {
+	return []interface{}{"spool_batches", d.Batches, "spool_records", d.Records, "spool_bytes", d.Bytes}
+}
+
+// Spool is an sdklog.Exporter that keeps what the wrapped exporter failed to send
+type Spool struct {
+	next   sdklog.Exporter
+	logger core.Logger
+	cfg    Config
+
+	// mu serializes writes, so the MaxBytes check sees every batch
+	mu sync.Mutex
+	// failures counts spooled batches, so a replay notices the collector dropping again
+	failures atomic.Int64
+
+	// replayMu keeps the loop and a final Replay from emitting the same batch twice
+	replayMu sync.Mutex
+	provider *sdklog.LoggerProvider
+	endpoint string
+
+	spooled   chan struct{}
+	recovered chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// New wraps next with a spool in cfg.Dir, creating the directory
+func New(next sdklog.Exporter, logger core.Logger, cfg Config) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("otlpspool: no spool directory configured")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+	}
+	if cfg.Probe <= 0 {
+		cfg.Probe = DefaultProbe
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("otlpspool: failed to create spool directory: %w", err)
+	}
+	return &Spool{
+		next:      next,
+		logger:    logger,
+		cfg:       cfg,
+		spooled:   make(chan struct{}, 1),
+		recovered: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Export sends records through the wrapped exporter and spools them if that fails. It only
+// returns an error when the batch could not be written to disk either.
+func (s *Spool) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	err := s.next.Export(ctx, records)
+	if err == nil {
+		signal(s.recovered)
+		return nil
+	}
+
+	if werr := s.write(records); werr != nil {
+		return errors.Join(err, fmt.Errorf("otlpspool: failed to spool batch: %w", werr))
+	}
+	s.logger.Warnw("OTLP export failed, batch spooled",
+		append([]interface{}{"error", err.Error(), "records", len(records)}, s.Depth().fields()...)...)
+	s.failures.Add(1)
+	signal(s.spooled)
+	return nil
+}
+
+// ForceFlush flushes the wrapped exporter
+func (s *Spool) ForceFlush(ctx context.Context) error {
+	return s.next.ForceFlush(ctx)
+}
+
+// Shutdown stops the replay loop and the wrapped exporter. Batches still spooled stay on
+// disk for the next run.
+func (s *Spool) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		if s.cancel == nil {
+			close(s.done)
+			return
+		}
+		s.cancel()
+	})
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+	}
+	return s.next.Shutdown(ctx)
+}
+
+// Start begins replaying into provider, the one this Spool exports for, whenever endpoint
+// accepts connections. An empty endpoint skips the probe.
+func (s *Spool) Start(provider *sdklog.LoggerProvider, endpoint string) {
+	s.startOnce.Do(func() {
+		s.replayMu.Lock()
+		s.provider, s.endpoint = provider, endpoint
+		s.replayMu.Unlock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		s.logger.Infow("OTLP spool enabled", append([]interface{}{"dir", s.cfg.Dir}, s.Depth().fields()...)...)
+		go s.loop(ctx)
+	})
+}
+
+// SetEndpoint changes the endpoint probed before a replay, for when the exporter it wraps
+// was pointed elsewhere; the next replay attempt is made right away
+func (s *Spool) SetEndpoint(endpoint string) {
+	s.replayMu.Lock()
+	s.endpoint = endpoint
+	s.replayMu.Unlock()
+	signal(s.recovered)
+}
+
+func (s *Spool) loop(ctx context.Context) {
+	defer close(s.done)
+	backoff := s.cfg.MinBackoff
+	for {
+		if s.Depth().Batches == 0 {
+			select {
+			case <-s.spooled:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		err := s.Replay(ctx)
+		if err == nil {
+			backoff = s.cfg.MinBackoff
+			continue
+		}
+		if !errors.Is(err, ErrUnreachable) {
+			s.logger.Warnw("OTLP spool replay failed", append([]interface{}{"error", err.Error()}, s.Depth().fields()...)...)
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff = min(2*backoff, s.cfg.MaxBackoff)
+		case <-s.recovered:
+			// A live export proved the collector is back
+			backoff = s.cfg.MinBackoff
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Replay emits every spooled batch back through the provider, oldest first, and deletes
+// it. Batches older than MaxAge go to the DeadLetter first, reachable or not. It returns
+// ErrUnreachable without replaying anything when the probe fails.
+func (s *Spool) Replay(ctx context.Context) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	files, err := List(s.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	files = s.expire(files)
+	if len(files) == 0 {
+		return nil
+	}
+	if s.provider == nil {
+		return errors.New("otlpspool: Replay called before Start")
+	}
+	if s.endpoint != "" {
+		conn, err := net.DialTimeout("tcp", s.endpoint, s.cfg.Probe)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnreachable, err)
+		}
+		conn.Close()
+	}
+
+	replayed, failures := 0, s.failures.Load()
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		path := filepath.Join(s.cfg.Dir, f.Name)
+		b, err := ReadBatch(path)
+		if err != nil {
+			s.discard(f, "unreadable spooled batch", "error", err.Error())
+			continue
+		}
+		b.Emit(ctx, s.provider)
+		// One batch at a time, so a large spool never overruns the processor's queue; a
+		// batch that fails again is spooled again under a new name, dated by its records'
+		// observed time so it keeps its age
+		if err := s.provider.ForceFlush(ctx); err != nil {
+			return fmt.Errorf("otlpspool: failed to flush replayed batch: %w", err)
+		}
+		os.Remove(path)
+		replayed += b.Len()
+		if s.failures.Load() != failures {
+			return fmt.Errorf("%w: export failed during replay", ErrUnreachable)
+		}
+	}
+	s.logger.Infow("OTLP spool replayed", append([]interface{}{"replayed_records", replayed}, s.Depth().fields()...)...)
+	return ctx.Err()
+}
+
+// Depth returns what the spool holds, read from the batch file names and sizes
+func (s *Spool) Depth() Depth {
+	return depth(s.cfg.Dir)
+}
+
+// expire hands batches older than MaxAge to the DeadLetter and returns the rest
+func (s *Spool) expire(files []File) []File {
+	if s.cfg.DeadLetter == nil {
+		return files
+	}
+	cutoff := time.Now().Add(-s.cfg.MaxAge)
+	kept := files[:0]
+	for _, f := range files {
+		if f.Time.Before(cutoff) {
+			s.discard(f, fmt.Sprintf("not delivered within %s", s.cfg.MaxAge), "max_age", s.cfg.MaxAge.String())
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// discard takes a batch out of the spool: to the DeadLetter when there is one, otherwise
+// it is deleted with a warning. fields carry what the reason refers to, such as the limit
+// the batch went over.
+func (s *Spool) discard(f File, reason string, fields ...interface{}) {
+	if s.cfg.DeadLetter != nil {
+		err := s.cfg.DeadLetter.adopt(s.cfg.Dir, f, reason, fields...)
+		if err == nil {
+			return
+		}
+		fields = append(fields, "dead_letter_error", err.Error())
+	}
+	os.Remove(filepath.Join(s.cfg.Dir, f.Name))
+	s.logger.Warnw("OTLP spool dropped batch", append([]interface{}{"reason", reason, "dropped_records", f.Records}, fields...)...)
+}
+
+// write stores records as a new batch, then discards the oldest batches over MaxBytes
+func (s *Spool) write(records []sdklog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := writeBatch(s.cfg.Dir, records); err != nil {
+		return err
+	}
+
+	files, err := List(s.cfg.Dir)
+	if err != nil {
+		return nil
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	// The newest batch is kept even when it alone is over the limit
+	for _, f := range files[:len(files)-1] {
+		if total <= s.cfg.MaxBytes {
+			break
+		}
+		s.discard(f, "spool full", "max_bytes", s.cfg.MaxBytes)
+		total -= f.Size
+	}
+	return nil
+}
+
+// signal wakes the loop without blocking when it is already awake
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
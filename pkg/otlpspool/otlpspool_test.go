@@ -0,0 +1,237 @@
+package otlpspool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeExporter fails while down is set and keeps what it accepted otherwise
+type fakeExporter struct {
+	mu       sync.Mutex
+	down     bool
+	exported []sdklog.Record
+}
+
+func (e *fakeExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.down {
+		return errors.New("connection refused")
+	}
+	for _, r := range records {
+		e.exported = append(e.exported, r.Clone())
+	}
+	return nil
+}
+
+func (e *fakeExporter) setDown(down bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = down
+}
+
+func (e *fakeExporter) records() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdklog.Record(nil), e.exported...)
+}
+
+func (e *fakeExporter) ForceFlush(context.Context) error { return nil }
+func (e *fakeExporter) Shutdown(context.Context) error   { return nil }
+
+func newSpool(t *testing.T, cfg Config) (*Spool, *fakeExporter, *sdklog.LoggerProvider, *testlog.Recorder) {
+	t.Helper()
+	rec := testlog.New(t)
+	next := &fakeExporter{down: true}
+	cfg.Dir = t.TempDir()
+	spool, err := New(next, rec.Logger, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The simple processor exports on Emit, so every record is its own batch
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(spool)))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return spool, next, provider, rec
+}
+
+func emit(ctx context.Context, provider *sdklog.LoggerProvider, body string, attrs ...log.KeyValue) {
+	var r log.Record
+	r.SetTimestamp(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC))
+	r.SetSeverity(log.SeverityWarn)
+	r.SetSeverityText("warn")
+	r.SetBody(log.StringValue(body))
+	r.AddAttributes(attrs...)
+	provider.Logger("orders", log.WithInstrumentationVersion("v1.2.0")).Emit(ctx, r)
+}
+
+func TestExportSpoolsAndReplays(t *testing.T) {
+	spool, next, provider, rec := newSpool(t, Config{})
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	attrs := []log.KeyValue{
+		log.String("order_id", "ord-0001"),
+		log.Int64("attempt", 3),
+		log.Float64("ratio", math.Inf(1)),
+		log.Bool("retry", true),
+		log.Bytes("payload", []byte{0, 1, 2}),
+		log.Slice("tags", log.StringValue("a"), log.Int64Value(1)),
+		log.Map("customer", log.String("tier", "gold"), log.Empty("note")),
+	}
+	emit(trace.ContextWithSpanContext(context.Background(), spanContext), provider, "Order failed", attrs...)
+	emit(context.Background(), provider, "Order completed")
+
+	if depth := spool.Depth(); depth.Batches != 2 || depth.Records != 2 || depth.Bytes == 0 {
+		t.Fatalf("depth = %+v, want 2 batches of 1 record", depth)
+	}
+	rec.AssertLogged("warn", "OTLP export failed, batch spooled", "error", "connection refused", "spool_batches", 2)
+
+	next.setDown(false)
+	spool.Start(provider, "")
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if depth := spool.Depth(); depth.Batches != 0 {
+		t.Errorf("depth after replay = %+v", depth)
+	}
+	rec.AssertLogged("info", "OTLP spool replayed", "spool_batches", 0)
+
+	got := next.records()
+	if len(got) != 2 {
+		t.Fatalf("replayed %d records, want 2", len(got))
+	}
+	first := got[0]
+	if first.Body().AsString() != "Order failed" || got[1].Body().AsString() != "Order completed" {
+		t.Errorf("replayed out of order: %q, %q", first.Body().AsString(), got[1].Body().AsString())
+	}
+	if first.Severity() != log.SeverityWarn || first.SeverityText() != "warn" || !first.Timestamp().Equal(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("record fields lost: %v %q %v", first.Severity(), first.SeverityText(), first.Timestamp())
+	}
+	if scope := first.InstrumentationScope(); scope.Name != "orders" || scope.Version != "v1.2.0" {
+		t.Errorf("scope = %+v", scope)
+	}
+	if first.TraceID() != spanContext.TraceID() || first.SpanID() != spanContext.SpanID() || first.TraceFlags() != trace.FlagsSampled {
+		t.Errorf("trace context lost: %v %v %v", first.TraceID(), first.SpanID(), first.TraceFlags())
+	}
+	i := 0
+	first.WalkAttributes(func(kv log.KeyValue) bool {
+		if i >= len(attrs) || !kv.Equal(attrs[i]) {
+			t.Errorf("attribute %d = %v", i, kv)
+		}
+		i++
+		return true
+	})
+	if i != len(attrs) {
+		t.Errorf("replayed %d attributes, want %d", i, len(attrs))
+	}
+}
+
+func TestReplayUnreachable(t *testing.T) {
+	spool, next, provider, _ := newSpool(t, Config{Probe: 100 * time.Millisecond})
+	emit(context.Background(), provider, "Order completed")
+
+	// A port that was just released refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := listener.Addr().String()
+	listener.Close()
+
+	spool.Start(provider, endpoint)
+	if err := spool.Replay(context.Background()); !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("err = %v, want ErrUnreachable", err)
+	}
+	if depth := spool.Depth(); depth.Batches != 1 || len(next.records()) != 0 {
+		t.Errorf("unreachable replay touched the spool: %+v", depth)
+	}
+}
+
+func TestMaxBytesDropsOldest(t *testing.T) {
+	spool, _, provider, rec := newSpool(t, Config{MaxBytes: 1})
+	for _, body := range []string{"first", "second", "third"} {
+		emit(context.Background(), provider, body)
+	}
+
+	// The newest batch is always kept, even alone over the cap
+	if depth := spool.Depth(); depth.Batches != 1 || depth.Records != 1 {
+		t.Errorf("depth = %+v, want only the newest batch", depth)
+	}
+	rec.AssertLogged("warn", "OTLP spool dropped batch", "reason", "spool full", "dropped_records", 1, "max_bytes", 1)
+	files, _ := List(spool.cfg.Dir)
+	b, err := ReadBatch(filepath.Join(spool.cfg.Dir, files[0].Name))
+	if err != nil || b.file.Records[0].Body.Str != "third" {
+		t.Errorf("kept %+v (%v), want the third record", b, err)
+	}
+}
+
+func TestFailedReplayKeepsBatchAge(t *testing.T) {
+	spool, _, provider, _ := newSpool(t, Config{})
+	emit(context.Background(), provider, "Order completed")
+	before, _ := List(spool.cfg.Dir)
+
+	// The collector drops again as the batch is replayed, so it is spooled a second time
+	spool.Start(provider, "")
+	if err := spool.Replay(context.Background()); !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("err = %v, want ErrUnreachable", err)
+	}
+	after, _ := List(spool.cfg.Dir)
+	if len(before) != 1 || len(after) != 1 || after[0].Name == before[0].Name {
+		t.Fatalf("spool before %+v, after %+v", before, after)
+	}
+	if !after[0].Time.Equal(before[0].Time) {
+		t.Errorf("respooled batch dated %v, want the original %v", after[0].Time, before[0].Time)
+	}
+}
+
+func TestUnreadableBatchDropped(t *testing.T) {
+	spool, next, provider, rec := newSpool(t, Config{})
+	os.WriteFile(filepath.Join(spool.cfg.Dir, "00000000000000000001-000001-1.json"), []byte("{"), 0o644)
+	next.setDown(false)
+
+	spool.Start(provider, "")
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entry := rec.AssertLogged("warn", "OTLP spool dropped batch", "reason", "unreadable spooled batch", "dropped_records", 1)
+	if _, ok := entry["error"]; !ok {
+		t.Errorf("no error logged: %v", entry)
+	}
+	if _, ok := entry["max_bytes"]; ok {
+		t.Errorf("unreadable batch blamed on max_bytes: %v", entry)
+	}
+}
+
+func TestLoopReplaysWhenCollectorReturns(t *testing.T) {
+	spool, next, provider, rec := newSpool(t, Config{MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	spool.Start(provider, "")
+	emit(context.Background(), provider, "Order completed")
+	rec.AssertLogged("info", "OTLP spool enabled", "spool_batches", 0)
+
+	next.setDown(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(next.records()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("loop did not replay; depth = %+v", spool.Depth())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := spool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
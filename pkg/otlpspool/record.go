@@ -0,0 +1,256 @@
+package otlpspool
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spooledScope is the instrumentation scope a record was emitted under
+type spooledScope struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	SchemaURL string `json:"schema_url,omitempty"`
+}
+
+// spooledResource is the resource a batch was exported with
+type spooledResource struct {
+	SchemaURL  string        `json:"schema_url,omitempty"`
+	Attributes []spooledAttr `json:"attributes,omitempty"`
+}
+
+// spooledAttr is a resource attribute tagged with its attribute.Type
+type spooledAttr struct {
+	Key   string       `json:"k"`
+	Type  string       `json:"t"`
+	Value spooledValue `json:"v"`
+}
+
+// spooledRecord is one log record as it is kept on disk
+type spooledRecord struct {
+	Scope             spooledScope `json:"scope"`
+	Timestamp         time.Time    `json:"timestamp"`
+	ObservedTimestamp time.Time    `json:"observed_timestamp"`
+	Severity          log.Severity `json:"severity"`
+	SeverityText      string       `json:"severity_text,omitempty"`
+	Body              spooledValue `json:"body"`
+	Attributes        []spooledKV  `json:"attributes,omitempty"`
+	TraceID           string       `json:"trace_id,omitempty"`
+	SpanID            string       `json:"span_id,omitempty"`
+	TraceFlags        byte         `json:"trace_flags,omitempty"`
+}
+
+type spooledKV struct {
+	Key   string       `json:"k"`
+	Value spooledValue `json:"v"`
+}
+
+// spooledValue is a log.Value tagged with its kind. Floats are kept as text because JSON
+// has no NaN or Inf.
+type spooledValue struct {
+	Kind  string         `json:"t,omitempty"`
+	Bool  bool           `json:"b,omitempty"`
+	Int   int64          `json:"i,omitempty"`
+	Float string         `json:"f,omitempty"`
+	Str   string         `json:"s,omitempty"`
+	Bytes []byte         `json:"y,omitempty"`
+	Slice []spooledValue `json:"l,omitempty"`
+	Map   []spooledKV    `json:"m,omitempty"`
+}
+
+func toSpooled(r sdklog.Record) spooledRecord {
+	scope := r.InstrumentationScope()
+	s := spooledRecord{
+		Scope:             spooledScope{Name: scope.Name, Version: scope.Version, SchemaURL: scope.SchemaURL},
+		Timestamp:         r.Timestamp(),
+		ObservedTimestamp: r.ObservedTimestamp(),
+		Severity:          r.Severity(),
+		SeverityText:      r.SeverityText(),
+		Body:              encodeValue(r.Body()),
+	}
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		s.Attributes = append(s.Attributes, spooledKV{Key: kv.Key, Value: encodeValue(kv.Value)})
+		return true
+	})
+	if traceID := r.TraceID(); traceID.IsValid() {
+		s.TraceID = traceID.String()
+		s.SpanID = r.SpanID().String()
+		s.TraceFlags = byte(r.TraceFlags())
+	}
+	return s
+}
+
+// emit hands the record back to provider, under its original scope and trace
+func (s spooledRecord) emit(ctx context.Context, provider *sdklog.LoggerProvider) {
+	var r log.Record
+	r.SetTimestamp(s.Timestamp)
+	r.SetObservedTimestamp(s.ObservedTimestamp)
+	r.SetSeverity(s.Severity)
+	r.SetSeverityText(s.SeverityText)
+	r.SetBody(decodeValue(s.Body))
+	r.AddAttributes(decodeKVs(s.Attributes)...)
+
+	var sc trace.SpanContextConfig
+	if b, err := hex.DecodeString(s.TraceID); err == nil && len(b) == len(sc.TraceID) {
+		copy(sc.TraceID[:], b)
+	}
+	if b, err := hex.DecodeString(s.SpanID); err == nil && len(b) == len(sc.SpanID) {
+		copy(sc.SpanID[:], b)
+	}
+	sc.TraceFlags = trace.TraceFlags(s.TraceFlags)
+	if spanContext := trace.NewSpanContext(sc); spanContext.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, spanContext)
+	}
+
+	provider.Logger(s.Scope.Name,
+		log.WithInstrumentationVersion(s.Scope.Version),
+		log.WithSchemaURL(s.Scope.SchemaURL),
+	).Emit(ctx, r)
+}
+
+func encodeValue(v log.Value) spooledValue {
+	switch v.Kind() {
+	case log.KindBool:
+		return spooledValue{Kind: "bool", Bool: v.AsBool()}
+	case log.KindInt64:
+		return spooledValue{Kind: "int", Int: v.AsInt64()}
+	case log.KindFloat64:
+		return spooledValue{Kind: "float", Float: strconv.FormatFloat(v.AsFloat64(), 'g', -1, 64)}
+	case log.KindString:
+		return spooledValue{Kind: "string", Str: v.AsString()}
+	case log.KindBytes:
+		return spooledValue{Kind: "bytes", Bytes: v.AsBytes()}
+	case log.KindSlice:
+		values := v.AsSlice()
+		slice := make([]spooledValue, len(values))
+		for i, value := range values {
+			slice[i] = encodeValue(value)
+		}
+		return spooledValue{Kind: "slice", Slice: slice}
+	case log.KindMap:
+		kvs := v.AsMap()
+		m := make([]spooledKV, len(kvs))
+		for i, kv := range kvs {
+			m[i] = spooledKV{Key: kv.Key, Value: encodeValue(kv.Value)}
+		}
+		return spooledValue{Kind: "map", Map: m}
+	default:
+		return spooledValue{}
+	}
+}
+
+func decodeValue(v spooledValue) log.Value {
+	switch v.Kind {
+	case "bool":
+		return log.BoolValue(v.Bool)
+	case "int":
+		return log.Int64Value(v.Int)
+	case "float":
+		f, _ := strconv.ParseFloat(v.Float, 64)
+		return log.Float64Value(f)
+	case "string":
+		return log.StringValue(v.Str)
+	case "bytes":
+		return log.BytesValue(v.Bytes)
+	case "slice":
+		values := make([]log.Value, len(v.Slice))
+		for i, value := range v.Slice {
+			values[i] = decodeValue(value)
+		}
+		return log.SliceValue(values...)
+	case "map":
+		return log.MapValue(decodeKVs(v.Map)...)
+	default:
+		return log.Value{}
+	}
+}
+
+func decodeKVs(kvs []spooledKV) []log.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	result := make([]log.KeyValue, len(kvs))
+	for i, kv := range kvs {
+		result[i] = log.KeyValue{Key: kv.Key, Value: decodeValue(kv.Value)}
+	}
+	return result
+}
+
+func toSpooledResource(res resource.Resource) spooledResource {
+	s := spooledResource{SchemaURL: res.SchemaURL()}
+	for _, kv := range res.Attributes() {
+		var v log.Value
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			v = log.BoolValue(kv.Value.AsBool())
+		case attribute.INT64:
+			v = log.Int64Value(kv.Value.AsInt64())
+		case attribute.FLOAT64:
+			v = log.Float64Value(kv.Value.AsFloat64())
+		case attribute.STRING:
+			v = log.StringValue(kv.Value.AsString())
+		case attribute.BOOLSLICE:
+			v = sliceOf(kv.Value.AsBoolSlice(), log.BoolValue)
+		case attribute.INT64SLICE:
+			v = sliceOf(kv.Value.AsInt64Slice(), log.Int64Value)
+		case attribute.FLOAT64SLICE:
+			v = sliceOf(kv.Value.AsFloat64Slice(), log.Float64Value)
+		case attribute.STRINGSLICE:
+			v = sliceOf(kv.Value.AsStringSlice(), log.StringValue)
+		default:
+			continue
+		}
+		s.Attributes = append(s.Attributes, spooledAttr{Key: string(kv.Key), Type: kv.Value.Type().String(), Value: encodeValue(v)})
+	}
+	return s
+}
+
+func (s spooledResource) resource() *resource.Resource {
+	attrs := make([]attribute.KeyValue, 0, len(s.Attributes))
+	for _, a := range s.Attributes {
+		v := decodeValue(a.Value)
+		switch a.Type {
+		case attribute.BOOL.String():
+			attrs = append(attrs, attribute.Bool(a.Key, v.AsBool()))
+		case attribute.INT64.String():
+			attrs = append(attrs, attribute.Int64(a.Key, v.AsInt64()))
+		case attribute.FLOAT64.String():
+			attrs = append(attrs, attribute.Float64(a.Key, v.AsFloat64()))
+		case attribute.STRING.String():
+			attrs = append(attrs, attribute.String(a.Key, v.AsString()))
+		case attribute.BOOLSLICE.String():
+			attrs = append(attrs, attribute.BoolSlice(a.Key, sliceFrom(v, log.Value.AsBool)))
+		case attribute.INT64SLICE.String():
+			attrs = append(attrs, attribute.Int64Slice(a.Key, sliceFrom(v, log.Value.AsInt64)))
+		case attribute.FLOAT64SLICE.String():
+			attrs = append(attrs, attribute.Float64Slice(a.Key, sliceFrom(v, log.Value.AsFloat64)))
+		case attribute.STRINGSLICE.String():
+			attrs = append(attrs, attribute.StringSlice(a.Key, sliceFrom(v, log.Value.AsString)))
+		}
+	}
+	return resource.NewWithAttributes(s.SchemaURL, attrs...)
+}
+
+func sliceOf[T any](values []T, convert func(T) log.Value) log.Value {
+	result := make([]log.Value, len(values))
+	for i, value := range values {
+		result[i] = convert(value)
+	}
+	return log.SliceValue(result...)
+}
+
+func sliceFrom[T any](v log.Value, convert func(log.Value) T) []T {
+	values := v.AsSlice()
+	result := make([]T, len(values))
+	for i, value := range values {
+		result[i] = convert(value)
+	}
+	return result
+}
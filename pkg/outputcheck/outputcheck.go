@@ -0,0 +1,226 @@
+// Package outputcheck verifies, before a demo creates its logger and serves traffic, that
+// every output the logger is configured with can take its entries.
+//
+// A log file whose directory cannot be created or written, or that cannot be rotated, does
+// not stop a service: the logger either fails on the first write or drops entries later,
+// and nobody notices until the logs are needed. Check tries each output up front:
+//
+//   - stdout and stderr are always usable
+//   - a file path gets its directory created, is opened for appending, and a scratch file
+//     is created and renamed next to it, which is what rotation does
+//   - the OTLP collector, when opt exports to one, gets an otelsetup.Preflight handshake
+//   - registered sinks (otel://, fluent://, ...) are skipped; they report their own errors
+//
+// Verify runs Check and, when any output failed, ends the process through crash.Fatal
+// with the whole report as one entry:
+//
+//	report := outputcheck.Verify(context.Background(), logOption)
+//	serviceLogger, err := logger.New(logOption)
+//	...
+//	report.Log(serviceLogger)
+//
+// An unreachable collector is reported but does not fail the check: the exporter retries,
+// so a collector that is down at startup delays logs, while an unwritable file loses them.
+package outputcheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// Message is the entry Verify exits with and Log writes
+const Message = "Log output self-test"
+
+// DefaultOTLPTimeout bounds the collector handshake when the OTLP block sets no timeout
+const DefaultOTLPTimeout = 2 * time.Second
+
+// Statuses of a Result
+const (
+	OK          = "ok"
+	Failed      = "failed"
+	Unreachable = "unreachable"
+	Skipped     = "skipped"
+)
+
+// Kinds of output
+const (
+	KindStream = "stream"
+	KindFile   = "file"
+	KindSink   = "sink"
+	KindOTLP   = "otlp"
+)
+
+// Result is the outcome of checking one output
+type Result struct {
+	// Output is the output path or collector endpoint, with credentials masked
+	Output string `json:"output"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Detail is why the output failed or was skipped
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the outcome of checking every output of one or more loggers
+type Report struct {
+	Results []Result
+}
+
+// OK reports whether no output failed
+func (r Report) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the outputs that cannot be written
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Status == Failed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Fields returns the report as key-value pairs for a log entry
+func (r Report) Fields() []interface{} {
+	unreachable := 0
+	for _, res := range r.Results {
+		if res.Status == Unreachable {
+			unreachable++
+		}
+	}
+	return []interface{}{
+		"outputs", r.Results,
+		"failed", len(r.Failed()),
+		"unreachable", unreachable,
+	}
+}
+
+// Log writes the report as one entry, at warn level when a collector was unreachable
+func (r Report) Log(logger core.Logger) {
+	for _, res := range r.Results {
+		if res.Status != OK && res.Status != Skipped {
+			logger.Warnw(Message, r.Fields()...)
+			return
+		}
+	}
+	logger.Infow(Message, r.Fields()...)
+}
+
+// Check tries every output of opts; an output shared by several of them is checked once
+func Check(ctx context.Context, opts ...*option.LogOption) Report {
+	var report Report
+	seen := make(map[string]bool)
+	for _, opt := range opts {
+		for _, path := range opt.OutputPaths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			report.Results = append(report.Results, checkPath(path))
+		}
+		if opt.IsOTLPEnabled() {
+			res := checkOTLP(ctx, opt)
+			if !seen[KindOTLP+":"+res.Output] {
+				seen[KindOTLP+":"+res.Output] = true
+				report.Results = append(report.Results, res)
+			}
+		}
+	}
+	return report
+}
+
+// Verify runs Check and exits through crash.Fatal, before any entry is lost, when an
+// output failed
+func Verify(ctx context.Context, opts ...*option.LogOption) Report {
+	report := Check(ctx, opts...)
+	if !report.OK() {
+		crash.Fatal(nil, Message, report.Fields()...)
+	}
+	return report
+}
+
+func checkPath(path string) Result {
+	switch path {
+	case "stdout", "stderr":
+		return Result{Output: path, Kind: KindStream, Status: OK}
+	}
+	if strings.HasPrefix(path, "file://") {
+		path = strings.TrimPrefix(path, "file://")
+	} else if strings.Contains(path, "://") {
+		return Result{Output: sanitize.URL(path), Kind: KindSink, Status: Skipped, Detail: "registered sink"}
+	}
+	res := Result{Output: path, Kind: KindFile, Status: OK}
+	if err := checkFile(path); err != nil {
+		res.Status = Failed
+		res.Detail = err.Error()
+	}
+	return res
+}
+
+// checkFile creates path's directory, opens path for appending and renames a scratch file
+// in the directory, the steps of writing to and rotating a log file
+func checkFile(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("open: %s is a directory", path)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	scratch, err := os.CreateTemp(dir, ".outputcheck-*")
+	if err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	scratch.Close()
+	rotated := scratch.Name() + ".1"
+	err = os.Rename(scratch.Name(), rotated)
+	if err != nil {
+		os.Remove(scratch.Name())
+		return fmt.Errorf("rotate: %w", err)
+	}
+	if err := os.Remove(rotated); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	return nil
+}
+
+func checkOTLP(ctx context.Context, opt *option.LogOption) Result {
+	cfg := otelsetup.FromLogOption(opt)
+	cfg.Signals = otelsetup.Logs
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultOTLPTimeout
+	}
+	res := Result{Output: sanitize.URL(cfg.Endpoint), Kind: KindOTLP, Status: OK}
+	h, err := otelsetup.Preflight(ctx, cfg)
+	switch {
+	case err == nil:
+		res.Detail = fmt.Sprintf("%s %s in %dms", h.Protocol, h.Result, h.Latency.Milliseconds())
+	case h.Protocol == "":
+		// Preflight did not get as far as a handshake: the configuration itself is wrong
+		res.Status = Failed
+		res.Detail = err.Error()
+	default:
+		res.Status = Unreachable
+		res.Detail = err.Error()
+	}
+	return res
+}
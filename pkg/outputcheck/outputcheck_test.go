@@ -0,0 +1,78 @@
+package outputcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+)
+
+func TestCheckPaths(t *testing.T) {
+	dir := t.TempDir()
+	// A regular file where a directory is expected: nothing can be created below it
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := Check(context.Background(),
+		&option.LogOption{OutputPaths: []string{"stdout", filepath.Join(dir, "logs", "app.log"), "otel://collector"}},
+		&option.LogOption{OutputPaths: []string{"stdout", filepath.Join(blocker, "app.log"), dir}},
+	)
+	want := []struct{ kind, status string }{
+		{KindStream, OK},
+		{KindFile, OK},
+		{KindSink, Skipped},
+		{KindFile, Failed},
+		{KindFile, Failed},
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("results = %+v, want %d", report.Results, len(want))
+	}
+	for i, w := range want {
+		if got := report.Results[i]; got.Kind != w.kind || got.Status != w.status {
+			t.Errorf("result %d = %+v, want %s %s", i, got, w.kind, w.status)
+		}
+	}
+	if report.OK() || len(report.Failed()) != 2 {
+		t.Errorf("OK = %v, failed = %+v", report.OK(), report.Failed())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs", "app.log")); err != nil {
+		t.Errorf("log file not created: %v", err)
+	}
+	scratch, _ := filepath.Glob(filepath.Join(dir, "logs", ".outputcheck-*"))
+	if len(scratch) != 0 {
+		t.Errorf("scratch files left behind: %v", scratch)
+	}
+}
+
+func TestCheckOTLP(t *testing.T) {
+	report := Check(context.Background(), &option.LogOption{
+		OutputPaths:  []string{"stdout"},
+		OTLPEndpoint: "http://127.0.0.1:1",
+	})
+	if got := report.Results[1]; got.Kind != KindOTLP || got.Status != Unreachable {
+		t.Errorf("unreachable collector = %+v", got)
+	}
+	if !report.OK() {
+		t.Error("an unreachable collector failed the check")
+	}
+
+	report = Check(context.Background(), &option.LogOption{
+		OTLPEndpoint: "127.0.0.1:4317",
+		OTLP:         &option.OTLPOption{Protocol: "thrift"},
+	})
+	if got := report.Results[0]; got.Status != Failed || !strings.Contains(got.Detail, "unsupported") {
+		t.Errorf("unsupported protocol = %+v", got)
+	}
+}
+
+func TestLog(t *testing.T) {
+	rec := testlog.New(t)
+	Check(context.Background(), &option.LogOption{OutputPaths: []string{"stderr"}}).Log(rec.Logger)
+	rec.AssertLogged("info", Message, "failed", 0, "unreachable", 0)
+}
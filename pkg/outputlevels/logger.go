@@ -0,0 +1,173 @@
+package outputlevels
+
+import (
+	"context"
+
+	"github.com/kart-io/logger/core"
+)
+
+// teeLogger writes every entry to each of its loggers, which filter it by their own level;
+// a logger whose max rank is below the entry's level is not passed it at all
+type teeLogger struct {
+	loggers []core.Logger
+	maxes   []int
+	// out log on behalf of the caller; they skip this wrapper's frame so the caller field
+	// still points at the code that logged
+	out []core.Logger
+	// byRank holds, for each rank of Levels, the loggers of out that take that level
+	byRank [][]core.Logger
+}
+
+// newTee tees loggers; maxes[i] is the rank of the highest level passed to loggers[i],
+// and the last logger must take every level
+func newTee(loggers []core.Logger, maxes []int) *teeLogger {
+	out := make([]core.Logger, len(loggers))
+	for i, l := range loggers {
+		out[i] = l.WithCallerSkip(1)
+	}
+	byRank := make([][]core.Logger, len(Levels))
+	for r := range byRank {
+		for i, l := range out {
+			if maxes[i] >= r {
+				byRank[r] = append(byRank[r], l)
+			}
+		}
+	}
+	return &teeLogger{loggers: loggers, maxes: maxes, out: out, byRank: byRank}
+}
+
+func (t *teeLogger) Debug(args ...interface{}) {
+	for _, l := range t.byRank[rankDebug] {
+		l.Debug(args...)
+	}
+}
+
+func (t *teeLogger) Info(args ...interface{}) {
+	for _, l := range t.byRank[rankInfo] {
+		l.Info(args...)
+	}
+}
+
+func (t *teeLogger) Warn(args ...interface{}) {
+	for _, l := range t.byRank[rankWarn] {
+		l.Warn(args...)
+	}
+}
+
+func (t *teeLogger) Error(args ...interface{}) {
+	for _, l := range t.byRank[rankError] {
+		l.Error(args...)
+	}
+}
+
+// Fatal is written at error level to the loggers that take errors but the last, whose
+// Fatal exits the process; the others are flushed first
+func (t *teeLogger) Fatal(args ...interface{}) {
+	last := len(t.out) - 1
+	for _, l := range t.byRank[rankError][:len(t.byRank[rankError])-1] {
+		l.Error(args...)
+	}
+	flushAll(t.loggers[:last])
+	t.out[last].Fatal(args...)
+}
+
+func (t *teeLogger) Debugf(template string, args ...interface{}) {
+	for _, l := range t.byRank[rankDebug] {
+		l.Debugf(template, args...)
+	}
+}
+
+func (t *teeLogger) Infof(template string, args ...interface{}) {
+	for _, l := range t.byRank[rankInfo] {
+		l.Infof(template, args...)
+	}
+}
+
+func (t *teeLogger) Warnf(template string, args ...interface{}) {
+	for _, l := range t.byRank[rankWarn] {
+		l.Warnf(template, args...)
+	}
+}
+
+func (t *teeLogger) Errorf(template string, args ...interface{}) {
+	for _, l := range t.byRank[rankError] {
+		l.Errorf(template, args...)
+	}
+}
+
+func (t *teeLogger) Fatalf(template string, args ...interface{}) {
+	last := len(t.out) - 1
+	for _, l := range t.byRank[rankError][:len(t.byRank[rankError])-1] {
+		l.Errorf(template, args...)
+	}
+	flushAll(t.loggers[:last])
+	t.out[last].Fatalf(template, args...)
+}
+
+func (t *teeLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	for _, l := range t.byRank[rankDebug] {
+		l.Debugw(msg, keysAndValues...)
+	}
+}
+
+func (t *teeLogger) Infow(msg string, keysAndValues ...interface{}) {
+	for _, l := range t.byRank[rankInfo] {
+		l.Infow(msg, keysAndValues...)
+	}
+}
+
+func (t *teeLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	for _, l := range t.byRank[rankWarn] {
+		l.Warnw(msg, keysAndValues...)
+	}
+}
+
+func (t *teeLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	for _, l := range t.byRank[rankError] {
+		l.Errorw(msg, keysAndValues...)
+	}
+}
+
+func (t *teeLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	last := len(t.out) - 1
+	for _, l := range t.byRank[rankError][:len(t.byRank[rankError])-1] {
+		l.Errorw(msg, keysAndValues...)
+	}
+	flushAll(t.loggers[:last])
+	t.out[last].Fatalw(msg, keysAndValues...)
+}
+
+// With returns a child logger whose entries carry keyValues on every output
+func (t *teeLogger) With(keyValues ...interface{}) core.Logger {
+	return t.derive(func(l core.Logger) core.Logger { return l.With(keyValues...) })
+}
+
+// WithCtx returns a child logger whose entries carry keyValues on every output
+func (t *teeLogger) WithCtx(ctx context.Context, keyValues ...interface{}) core.Logger {
+	return t.derive(func(l core.Logger) core.Logger { return l.WithCtx(ctx, keyValues...) })
+}
+
+// WithCallerSkip returns a tee logger with extra frames skipped
+func (t *teeLogger) WithCallerSkip(skip int) core.Logger {
+	return t.derive(func(l core.Logger) core.Logger { return l.WithCallerSkip(skip) })
+}
+
+// SetLevel sets one level on every output, replacing the per-output levels
+func (t *teeLogger) SetLevel(level core.Level) {
+	for _, l := range t.loggers {
+		l.SetLevel(level)
+	}
+}
+
+// Flush flushes every output
+func (t *teeLogger) Flush() error {
+	return flushAll(t.loggers)
+}
+
+func (t *teeLogger) derive(fn func(core.Logger) core.Logger) *teeLogger {
+	loggers := make([]core.Logger, len(t.loggers))
+	for i, l := range t.loggers {
+		loggers[i] = fn(l)
+	}
+	return newTee(loggers, t.maxes)
+}
@@ -0,0 +1,224 @@
+// Package outputlevels gives each output of a logger its own minimum level, such as info
+// on stdout, debug in a file and warn over OTLP, from one option.LogOption.
+//
+// option.LogOption has a single Level for all of its outputs, so a demo that wanted a
+// verbose file next to a quiet console created one logger per level and had to log every
+// entry to the right ones itself. New does that split once: it creates a logger for each
+// distinct level with the outputs that use it, and returns a logger that writes every
+// entry to all of them, each one dropping what is below its level.
+//
+//	serviceLogger, err := outputlevels.New(logOption, map[string]string{
+//		"stdout":          "info",
+//		"logs/app.log":    "debug",
+//		outputlevels.OTLP: "warn",
+//	})
+//
+// Outputs missing from the map keep logOption.Level. Output paths wrapped by pkg/timefmt
+// are matched by the output they wrap. Split is the console variant: debug
+// and info go to stdout, warn and above to stderr and optionally an error log file.
+package outputlevels
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/devconsole"
+	"github.com/kart-io/go-example/pkg/timefmt"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// OTLP is the key that sets the level of the logger's OTLP exporter
+const OTLP = "otlp"
+
+// Stdout and Stderr are the console output paths Split routes by level
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Levels accepted in the map, the ones of option.LogOption.Level
+var Levels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Ranks of Levels, so that levels compare as numbers
+const (
+	rankDebug = iota
+	rankInfo
+	rankWarn
+	rankError
+	rankFatal
+)
+
+// Validate reports an output in levels that opt does not write to, or an unknown level
+func Validate(opt *option.LogOption, levels map[string]string) error {
+	for output, level := range levels {
+		if !slices.Contains(Levels, strings.ToLower(level)) {
+			return fmt.Errorf("outputlevels: invalid level %q for %s", level, output)
+		}
+		if output == OTLP {
+			if !opt.IsOTLPEnabled() {
+				return fmt.Errorf("outputlevels: level set for %s, but OTLP export is not enabled", OTLP)
+			}
+			continue
+		}
+		if !slices.ContainsFunc(opt.OutputPaths, func(path string) bool { return timefmt.Output(path) == output }) {
+			return fmt.Errorf("outputlevels: %q is not one of the output paths %v", output, opt.OutputPaths)
+		}
+	}
+	return nil
+}
+
+// New returns a logger writing to every output of opt at the level levels gives it, or
+// opt.Level. Without levels it is logger.New(opt), or devconsole.New for its format.
+func New(opt *option.LogOption, levels map[string]string) (core.Logger, error) {
+	if len(levels) == 0 {
+		return devconsole.New(opt, devconsole.ThemeFromEnv())
+	}
+	if err := Validate(opt, levels); err != nil {
+		return nil, err
+	}
+	levelOf := func(output string) string {
+		if level, ok := levels[timefmt.Output(output)]; ok {
+			return strings.ToLower(level)
+		}
+		return opt.Level
+	}
+
+	// One logger per level, in the order the outputs are listed; the OTLP exporter goes
+	// with the outputs of its level, or gets a logger of its own
+	var order []string
+	paths := make(map[string][]string)
+	for _, output := range opt.OutputPaths {
+		level := levelOf(output)
+		if _, ok := paths[level]; !ok {
+			order = append(order, level)
+		}
+		paths[level] = append(paths[level], output)
+	}
+	otlpLevel := ""
+	if opt.IsOTLPEnabled() {
+		otlpLevel = levelOf(OTLP)
+		if _, ok := paths[otlpLevel]; !ok {
+			order = append(order, otlpLevel)
+			// The logger needs a local output; this one is discarded
+			paths[otlpLevel] = []string{os.DevNull}
+		}
+	}
+
+	groups := make([]group, len(order))
+	for i, level := range order {
+		groups[i] = group{level: level, max: rankFatal, paths: paths[level], otlp: level == otlpLevel}
+	}
+	return build(opt, groups)
+}
+
+// Split returns a logger that writes debug and info entries to stdout, and warn, error
+// and fatal entries to stderr and, unless it is empty, errorLog, so that platforms treating
+// stderr as the error stream classify them right. stdout and stderr in opt.OutputPaths are
+// replaced by the pair; the other outputs, and OTLP, get every entry at opt.Level.
+func Split(opt *option.LogOption, errorLog string) (core.Logger, error) {
+	level := strings.ToLower(opt.Level)
+	if !slices.Contains(Levels, level) {
+		return nil, fmt.Errorf("outputlevels: invalid level %q", opt.Level)
+	}
+
+	// The outputs Split adds get the timestamps of the ones opt has
+	wrap := func(output string) string { return output }
+	if c, ok := timefmt.Of(opt); ok {
+		wrap = c.Path
+	}
+
+	var groups []group
+	if rank(level) <= rankInfo {
+		groups = append(groups, group{level: level, max: rankInfo, paths: []string{wrap(Stdout)}})
+	}
+	var others []string
+	for _, path := range opt.OutputPaths {
+		if output := timefmt.Output(path); output != Stdout && output != Stderr && output != errorLog {
+			others = append(others, path)
+		}
+	}
+	if len(others) == 0 && opt.IsOTLPEnabled() {
+		others = []string{os.DevNull}
+	}
+	if len(others) > 0 {
+		groups = append(groups, group{level: level, max: rankFatal, paths: others, otlp: true})
+	}
+	// Last, as Fatal exits through the last logger
+	errorPaths := []string{wrap(Stderr)}
+	if errorLog != "" {
+		errorPaths = append(errorPaths, wrap(errorLog))
+	}
+	groups = append(groups, group{level: Levels[max(rank(level), rankWarn)], max: rankFatal, paths: errorPaths})
+	return build(opt, groups)
+}
+
+// group is one logger of a tee: its outputs, its level, and the highest level passed to
+// it; otlp keeps the OTLP exporter of the option on it
+type group struct {
+	level string
+	max   int
+	paths []string
+	otlp  bool
+}
+
+// build creates a logger per group from copies of opt and tees them, or returns the only
+// one if it takes every level. devconsole.New creates them, so with its format the group
+// that writes stdout renders it for the terminal.
+func build(opt *option.LogOption, groups []group) (core.Logger, error) {
+	theme := devconsole.ThemeFromEnv()
+	loggers := make([]core.Logger, 0, len(groups))
+	maxes := make([]int, 0, len(groups))
+	for _, g := range groups {
+		child := *opt
+		child.Level = g.level
+		child.OutputPaths = g.paths
+		if !g.otlp {
+			withoutOTLP(&child)
+		}
+		l, err := devconsole.New(&child, theme)
+		if err != nil {
+			for _, created := range loggers {
+				created.Flush()
+			}
+			return nil, fmt.Errorf("outputlevels: %s logger for %v: %w", g.level, child.OutputPaths, err)
+		}
+		loggers = append(loggers, l)
+		maxes = append(maxes, g.max)
+	}
+	if len(loggers) == 1 && maxes[0] == rankFatal {
+		return loggers[0], nil
+	}
+	return newTee(loggers, maxes), nil
+}
+
+// rank is the position of level in Levels, so that levels compare as numbers
+func rank(level string) int {
+	return slices.Index(Levels, level)
+}
+
+// withoutOTLP turns the OTLP exporter of opt off without changing the option it was
+// copied from
+func withoutOTLP(opt *option.LogOption) {
+	opt.OTLPEndpoint = ""
+	if opt.OTLP != nil {
+		otlp := *opt.OTLP
+		disabled := false
+		otlp.Enabled = &disabled
+		opt.OTLP = &otlp
+	}
+}
+
+// flushAll flushes every logger and joins their errors
+func flushAll(loggers []core.Logger) error {
+	var errs []error
+	for _, l := range loggers {
+		if err := l.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package outputlevels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/timefmt"
+	"github.com/kart-io/logger/option"
+)
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	debugFile := filepath.Join(dir, "debug.log")
+	errorFile := filepath.Join(dir, "error.log")
+	infoFile := filepath.Join(dir, "info.log")
+
+	log, err := New(&option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{debugFile, errorFile, infoFile},
+	}, map[string]string{debugFile: "debug", errorFile: "ERROR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log = log.With("component", "orders")
+	log.Debugw("Cache miss", "key", "order:1")
+	log.Infow("Order created", "order_id", "ord-1")
+	log.Errorw("Payment failed", "order_id", "ord-1")
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string][]string{
+		debugFile: {"Cache miss", "Order created", "Payment failed"},
+		errorFile: {"Payment failed"},
+		infoFile:  {"Order created", "Payment failed"},
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(want) {
+			t.Errorf("%s: %d lines, want %d:\n%s", filepath.Base(path), len(lines), len(want), data)
+			continue
+		}
+		for i, msg := range want {
+			if !strings.Contains(lines[i], msg) || !strings.Contains(lines[i], `"component":"orders"`) {
+				t.Errorf("%s line %d = %s, want %q with component", filepath.Base(path), i, lines[i], msg)
+			}
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+	allFile := filepath.Join(dir, "all.log")
+	errorFile := filepath.Join(dir, "error.log")
+
+	log, err := Split(&option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{Stdout, allFile},
+	}, errorFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Debugw("Cache miss")
+	log.Infow("Order created")
+	log.Warnw("Retrying payment")
+	log.Errorw("Payment failed")
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string][]string{
+		allFile:   {"Order created", "Retrying payment", "Payment failed"},
+		errorFile: {"Retrying payment", "Payment failed"},
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(want) {
+			t.Errorf("%s: %d lines, want %d:\n%s", filepath.Base(path), len(lines), len(want), data)
+			continue
+		}
+		for i, msg := range want {
+			if !strings.Contains(lines[i], msg) {
+				t.Errorf("%s line %d = %s, want %q", filepath.Base(path), i, lines[i], msg)
+			}
+		}
+	}
+
+	if _, err := Split(&option.LogOption{Level: "verbose"}, ""); err == nil {
+		t.Error("invalid level: no error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	opt := &option.LogOption{Level: "info", OutputPaths: []string{"stdout", "logs/app.log"}}
+	if err := Validate(opt, map[string]string{"stdout": "warn", "logs/app.log": "debug"}); err != nil {
+		t.Errorf("valid levels: %v", err)
+	}
+	for name, levels := range map[string]map[string]string{
+		"unknown output": {"logs/other.log": "debug"},
+		"unknown level":  {"stdout": "verbose"},
+		"otlp disabled":  {OTLP: "warn"},
+	} {
+		if err := Validate(opt, levels); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if err := Validate(&option.LogOption{OutputPaths: []string{timefmt.Config{Layout: timefmt.EpochMillis}.Path("stdout")}},
+		map[string]string{"stdout": "warn"}); err != nil {
+		t.Errorf("wrapped output: %v", err)
+	}
+	opt.OTLPEndpoint = "localhost:4317"
+	if err := Validate(opt, map[string]string{OTLP: "warn"}); err != nil {
+		t.Errorf("otlp enabled: %v", err)
+	}
+}
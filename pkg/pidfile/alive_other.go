@@ -0,0 +1,16 @@
+//go:build !unix
+
+package pidfile
+
+import "os"
+
+// alive reports whether a process with the PID exists; on Windows FindProcess opens the
+// process and fails when there is none
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,205 @@
+// Package pidfile writes, validates and removes a PID file for deployments under a
+// traditional process manager (init scripts, supervisord, monit) that track a service by
+// the PID it writes.
+//
+// Acquire refuses to start a second instance while the process named in an existing file
+// is alive, and takes over a stale file left behind by a crash. Demos call FromEnv after
+// creating their logger; PID_FILE=/var/run/gin-demo.pid turns it on.
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/logger/core"
+)
+
+// EnvPath is the variable FromEnv reads the PID file path from; empty disables it
+const EnvPath = "PID_FILE"
+
+// ErrRunning is returned by Acquire when the PID file belongs to a live process
+var ErrRunning = errors.New("another instance is running")
+
+// File is an acquired PID file
+type File struct {
+	Path string
+	PID  int
+	// Stale is set when a file left by a dead process, or holding no PID, was replaced
+	Stale bool
+	// StalePID is the PID of the dead process, 0 if the file held none
+	StalePID int
+}
+
+// Acquire writes the current PID to path, creating its directory. An existing file whose
+// process is still running fails with ErrRunning; one whose process is gone, or that does
+// not hold a PID, is stale and is replaced.
+//
+// The PID is written to a temporary file that is hard-linked into place, so the file never
+// holds a partial PID and two instances starting together cannot both create it. A stale
+// file is renamed aside before the link is retried; if another instance replaced it in the
+// meantime, the file moved aside is no longer the stale one and is put back.
+func Acquire(path string) (*File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create PID file directory: %w", err)
+	}
+	f := &File{Path: path, PID: os.Getpid()}
+	tmp, err := writeTemp(dir, filepath.Base(path), f.PID)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	// Three attempts: the later ones follow moving a stale file aside or finding the file
+	// released between the link and the read
+	for attempt := 0; attempt < 3; attempt++ {
+		err := os.Link(tmp, path)
+		if err == nil {
+			// Another instance taking over at the same moment would have found this PID alive
+			// and put the file back; make sure it is still this process's
+			if pid, err := Read(path); err != nil || pid != f.PID {
+				return nil, fmt.Errorf("create PID file %s: replaced by another process", path)
+			}
+			return f, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("create PID file: %w", err)
+		}
+
+		pid, err := Read(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil && pid != f.PID && alive(pid) {
+			return nil, fmt.Errorf("%s held by pid %d: %w", path, pid, ErrRunning)
+		}
+		if err != nil && !errors.Is(err, errInvalid) {
+			return nil, err
+		}
+		if err := moveStale(path, tmp+".stale", pid); err != nil {
+			return nil, err
+		}
+		f.Stale, f.StalePID = true, pid
+	}
+	return nil, fmt.Errorf("create PID file %s: recreated by another process", path)
+}
+
+// writeTemp writes pid to a new file next to the PID file and returns its path
+func writeTemp(dir, base string, pid int) (string, error) {
+	out, err := os.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return "", fmt.Errorf("create PID file: %w", err)
+	}
+	// CreateTemp makes the file private; process managers run as other users
+	err = out.Chmod(0o644)
+	if err == nil {
+		_, err = fmt.Fprintf(out, "%d\n", pid)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("write PID file: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// moveStale renames the stale file holding stalePID (0 for one holding no PID) to aside
+// and removes it. A file that no longer holds stalePID was written by an instance that got
+// there first; it is linked back and ErrRunning returned.
+func moveStale(path, aside string, stalePID int) error {
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("move stale PID file: %w", err)
+	}
+	defer os.Remove(aside)
+	pid, _ := Read(aside)
+	if pid == stalePID {
+		return nil
+	}
+	if err := os.Link(aside, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("restore PID file of pid %d: %w", pid, err)
+	}
+	return fmt.Errorf("%s held by pid %d: %w", path, pid, ErrRunning)
+}
+
+var errInvalid = errors.New("PID file does not hold a PID")
+
+// Read returns the PID stored in path
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read PID file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: %w", path, errInvalid)
+	}
+	return pid, nil
+}
+
+// Release removes the file if it still holds this process's PID, so a file taken over by
+// another instance is left alone
+func (f *File) Release() error {
+	pid, err := Read(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pid != f.PID {
+		return fmt.Errorf("%s now holds pid %d, not removing it", f.Path, pid)
+	}
+	return os.Remove(f.Path)
+}
+
+// FromEnv acquires the PID file named by PID_FILE and returns the function that releases
+// it, a no-op when PID_FILE is empty. The process exits through crash.Fatal if another
+// instance holds the file. The release is also registered with crash.OnExit, so a crash
+// that skips the deferred call still removes the file:
+//
+//	defer pidfile.FromEnv(serviceLogger)()
+func FromEnv(logger core.Logger) func() {
+	path := os.Getenv(EnvPath)
+	if path == "" {
+		return func() {}
+	}
+	logger = logger.With("component", "pidfile", "path", path)
+	f, err := Acquire(path)
+	if errors.Is(err, ErrRunning) {
+		crash.Fatal(logger, "Another instance is running", "error", err.Error())
+	}
+	if err != nil {
+		crash.Fatal(logger, "Failed to acquire PID file", "error", err.Error())
+	}
+	if f.Stale {
+		logger.Warnw("Replaced stale PID file", "stale_pid", f.StalePID)
+	}
+	logger.Infow("PID file acquired", "pid", f.PID)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if err := f.Release(); err != nil {
+				logger.Errorw("Failed to release PID file", "error", err.Error())
+				return
+			}
+			logger.Infow("PID file released", "pid", f.PID)
+		})
+	}
+	crash.OnExit("pidfile", func() error {
+		release()
+		return nil
+	})
+	return release
+}
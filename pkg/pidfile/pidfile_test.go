@@ -0,0 +1,191 @@
+package pidfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestAcquireAndRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "demo.pid")
+	f, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := Read(path); err != nil || pid != os.Getpid() || f.Stale {
+		t.Fatalf("Read = %d, %v; file %+v", pid, err, f)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PID file still there after release: %v", err)
+	}
+	// Releasing twice is harmless
+	if err := f.Release(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAcquireRefusesLiveProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.pid")
+	// The parent (go test) outlives this test
+	os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())+"\n"), 0o644)
+	if _, err := Acquire(path); !errors.Is(err, ErrRunning) {
+		t.Fatalf("err = %v, want ErrRunning", err)
+	}
+	if pid, _ := Read(path); pid != os.Getppid() {
+		t.Errorf("file overwritten with %d", pid)
+	}
+}
+
+func TestAcquireReplacesStaleFile(t *testing.T) {
+	// A PID that just exited
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dead := cmd.Process.Pid
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"dead.pid": strconv.Itoa(dead), "garbage.pid": "not a pid"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o644)
+		f, err := Acquire(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if pid, _ := Read(path); pid != os.Getpid() {
+			t.Errorf("%s holds %d after acquire", name, pid)
+		}
+		if !f.Stale || name == "dead.pid" && f.StalePID != dead {
+			t.Errorf("StalePID = %d, want %d", f.StalePID, dead)
+		}
+	}
+}
+
+// TestAcquireHelper acquires PIDFILE_TEST_PATH in a child process started by
+// TestConcurrentTakeover, reports the result and holds the file until stdin is closed
+func TestAcquireHelper(t *testing.T) {
+	path := os.Getenv("PIDFILE_TEST_PATH")
+	if path == "" {
+		t.Skip("run by TestConcurrentTakeover")
+	}
+	f, err := Acquire(path)
+	switch {
+	case err == nil:
+		fmt.Println("acquired")
+	case errors.Is(err, ErrRunning):
+		fmt.Println("running")
+	default:
+		fmt.Println(err)
+	}
+	io.Copy(io.Discard, os.Stdin)
+	if f != nil {
+		f.Release()
+	}
+}
+
+func TestConcurrentTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.pid")
+	os.WriteFile(path, []byte("garbage"), 0o644)
+
+	type child struct {
+		cmd   *exec.Cmd
+		stdin io.Closer
+		out   *bufio.Reader
+	}
+	var children []child
+	for i := 0; i < 8; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAcquireHelper$")
+		cmd.Env = append(os.Environ(), "PIDFILE_TEST_PATH="+path)
+		stdin, _ := cmd.StdinPipe()
+		stdout, _ := cmd.StdoutPipe()
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		children = append(children, child{cmd, stdin, bufio.NewReader(stdout)})
+	}
+	holder := 0
+	for _, c := range children {
+		line, _ := c.out.ReadString('\n')
+		switch line = strings.TrimSpace(line); line {
+		case "acquired":
+			if holder != 0 {
+				t.Errorf("pids %d and %d both acquired the file", holder, c.cmd.Process.Pid)
+			}
+			holder = c.cmd.Process.Pid
+		case "running":
+		default:
+			t.Errorf("pid %d: %s", c.cmd.Process.Pid, line)
+		}
+	}
+	if pid, err := Read(path); err != nil || pid != holder {
+		t.Errorf("file holds %d, %v; acquired by %d", pid, err, holder)
+	}
+	for _, c := range children {
+		c.stdin.Close()
+		c.cmd.Wait()
+	}
+}
+
+func TestMoveStaleRestoresLiveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "demo.pid")
+	// Another instance replaced the stale file after it was read
+	os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())+"\n"), 0o644)
+	if err := moveStale(path, filepath.Join(dir, "aside"), 0); !errors.Is(err, ErrRunning) {
+		t.Fatalf("err = %v, want ErrRunning", err)
+	}
+	if pid, _ := Read(path); pid != os.Getppid() {
+		t.Errorf("file holds %d after the move was undone", pid)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
+
+func TestReleaseKeepsTakenOverFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.pid")
+	f, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("12345\n"), 0o644)
+	if err := f.Release(); err == nil {
+		t.Error("released a file holding another PID")
+	}
+	if pid, _ := Read(path); pid != 12345 {
+		t.Errorf("file now holds %d", pid)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	rec := testlog.New(t)
+	path := filepath.Join(t.TempDir(), "demo.pid")
+	t.Setenv(EnvPath, path)
+	os.WriteFile(path, []byte("garbage"), 0o644)
+
+	release := FromEnv(rec.Logger)
+	rec.AssertLogged("warn", "Replaced stale PID file", "stale_pid", 0)
+	rec.AssertLogged("info", "PID file acquired", "path", path, "pid", os.Getpid(), "component", "pidfile")
+	release()
+	rec.AssertLogged("info", "PID file released", "path", path)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PID file still there: %v", err)
+	}
+	// The crash.OnExit hook shares the release, so a second call is a no-op
+	release()
+	if n := rec.Count("info", "PID file released"); n != 1 {
+		t.Errorf("released %d times", n)
+	}
+}
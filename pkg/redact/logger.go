@@ -0,0 +1,19 @@
+package redact
+
+import (
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger that applies r to everything logged through base: the message and
+// fields of every entry, and the fields added with With, once, when they are attached
+func (r *Redactor) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		Entry: func(e *logwrap.Entry, _ core.Logger) {
+			e.Message, e.Fields = r.String(e.Message), r.KeyValues(e.Fields)
+		},
+		With: func(keyValues []interface{}, _ core.Logger) []interface{} {
+			return r.KeyValues(keyValues)
+		},
+	})
+}
@@ -0,0 +1,235 @@
+// Package redact masks personal data and credentials in log fields before they reach any
+// output.
+//
+// A Redactor has two kinds of rules: field names whose values are always replaced, such
+// as password or authorization, and patterns matched inside any string value or message,
+// such as emails, card numbers and bearer tokens. Wrap applies them to everything logged
+// through a core.Logger, so a handler that logs a whole request body still cannot leak a
+// token into stdout, files or an OTLP collector.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultMask replaces values of sensitive fields and matches of patterns without a Mask func
+const DefaultMask = "[REDACTED]"
+
+// DefaultFields are field names whose values are never logged
+var DefaultFields = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey",
+	"authorization", "cookie", "set-cookie", "card_number", "cvv", "ssn",
+}
+
+// Pattern masks every match of Regexp inside string values
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Mask returns the replacement for one match; nil uses the Redactor's mask
+	Mask func(match string) string
+}
+
+// Built-in patterns
+var (
+	Email = Pattern{
+		Name:   "email",
+		Regexp: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Mask:   maskEmail,
+	}
+	CreditCard = Pattern{
+		Name:   "credit_card",
+		Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Mask:   maskCard,
+	}
+	BearerToken = Pattern{
+		Name:   "bearer_token",
+		Regexp: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		Mask:   func(string) string { return "Bearer " + DefaultMask },
+	}
+	JWT = Pattern{
+		Name:   "jwt",
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	}
+)
+
+// DefaultPatterns are applied by Default
+var DefaultPatterns = []Pattern{BearerToken, JWT, Email, CreditCard}
+
+// NewPattern compiles a pattern from configuration; matches are replaced with the mask
+func NewPattern(name, expr string) (Pattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("redact: pattern %s: %w", name, err)
+	}
+	return Pattern{Name: name, Regexp: re}, nil
+}
+
+// Config selects what a Redactor masks
+type Config struct {
+	// Fields are matched case-insensitively against the whole key or its trailing
+	// segments, with '.', '_' and '-' treated alike, so "api_key" also covers
+	// "X-Api-Key" and "token" covers "access_token" and "auth.token"
+	Fields   []string
+	Patterns []Pattern
+	// Mask replaces sensitive field values; empty means DefaultMask
+	Mask string
+}
+
+// Redactor applies a Config to strings, values and key/value lists; it is safe for
+// concurrent use
+type Redactor struct {
+	fields   map[string]bool
+	patterns []Pattern
+	mask     string
+}
+
+// New builds a Redactor from cfg
+func New(cfg Config) *Redactor {
+	r := &Redactor{
+		fields:   make(map[string]bool, len(cfg.Fields)),
+		patterns: cfg.Patterns,
+		mask:     cfg.Mask,
+	}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+	for _, field := range cfg.Fields {
+		r.fields[normalizeKey(field)] = true
+	}
+	return r
+}
+
+// Default returns a Redactor with DefaultFields and DefaultPatterns
+func Default() *Redactor {
+	return New(Config{Fields: DefaultFields, Patterns: DefaultPatterns})
+}
+
+// SensitiveField reports whether values logged under key are masked entirely
+func (r *Redactor) SensitiveField(key string) bool {
+	key = normalizeKey(key)
+	for {
+		if r.fields[key] {
+			return true
+		}
+		i := strings.IndexByte(key, '_')
+		if i < 0 {
+			return false
+		}
+		key = key[i+1:]
+	}
+}
+
+var keySeparators = strings.NewReplacer(".", "_", "-", "_")
+
+func normalizeKey(key string) string {
+	return keySeparators.Replace(strings.ToLower(key))
+}
+
+// String masks every pattern match in s
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		if p.Mask == nil {
+			s = p.Regexp.ReplaceAllLiteralString(s, r.mask)
+			continue
+		}
+		s = p.Regexp.ReplaceAllStringFunc(s, p.Mask)
+	}
+	return s
+}
+
+// Value returns v as it may be logged under key. Strings, errors and string slices and
+// maps are scanned for patterns; other types pass through unchanged so numbers and
+// durations keep their encoding.
+func (r *Redactor) Value(key string, v interface{}) interface{} {
+	if r.SensitiveField(key) {
+		return r.mask
+	}
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case error:
+		return r.String(v.Error())
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = r.String(s)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.Value("", item)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for k, s := range v {
+			if r.SensitiveField(k) {
+				out[k] = r.mask
+			} else {
+				out[k] = r.String(s)
+			}
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = r.Value(k, item)
+		}
+		return out
+	}
+	return v
+}
+
+// KeyValues returns a copy of a key/value list with every value redacted
+func (r *Redactor) KeyValues(keyValues []interface{}) []interface{} {
+	out := make([]interface{}, len(keyValues))
+	for i := 0; i < len(keyValues); i += 2 {
+		out[i] = keyValues[i]
+		if i+1 < len(keyValues) {
+			out[i+1] = r.Value(fmt.Sprint(keyValues[i]), keyValues[i+1])
+		}
+	}
+	return out
+}
+
+// maskEmail keeps the first character of the local part and the domain: j***@example.com
+func maskEmail(addr string) string {
+	local, domain, _ := strings.Cut(addr, "@")
+	if local == "" {
+		return "***@" + domain
+	}
+	return local[:1] + "***@" + domain
+}
+
+// maskCard keeps the last four digits of numbers that pass the Luhn check; other digit
+// runs, such as timestamps and order numbers, are left alone
+func maskCard(match string) string {
+	digits := make([]byte, 0, len(match))
+	for i := 0; i < len(match); i++ {
+		if match[i] >= '0' && match[i] <= '9' {
+			digits = append(digits, match[i])
+		}
+	}
+	if !luhn(digits) {
+		return match
+	}
+	return "****" + string(digits[len(digits)-4:])
+}
+
+func luhn(digits []byte) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,26 @@
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware accepts the caller's X-Request-ID or mints one with prefix, echoes it in
+// the response and stores it in the request context. Install it before logging middleware
+// so the request logger can pick the ID up with FromGin.
+func GinMiddleware(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		SetGin(c, OrNew(c.GetHeader(Header), prefix))
+		c.Next()
+	}
+}
+
+// SetGin stores id for c's request and sets the response header
+func SetGin(c *gin.Context, id string) {
+	c.Header(Header, id)
+	c.Request = c.Request.WithContext(WithContext(c.Request.Context(), id))
+}
+
+// FromGin returns the ID stored for c's request, or "" if there is none
+func FromGin(c *gin.Context) string {
+	return FromContext(c.Request.Context())
+}
@@ -0,0 +1,96 @@
+// Package requestid mints and carries the IDs that correlate log lines across middleware,
+// workers and outgoing calls.
+//
+// IDs are a short prefix naming what they identify plus a ULID, e.g. req_01JA2X3M9Q8N5TB7VWD4KZ6C0E.
+// ULIDs sort by creation time and are unique across processes without coordination, so
+// any service may mint them and a log search on one ID finds every hop.
+package requestid
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Header carries the ID over HTTP; MetadataKey is the gRPC metadata equivalent
+const (
+	Header      = "X-Request-ID"
+	MetadataKey = "x-request-id"
+)
+
+// Prefixes used across the demos
+const (
+	Request  = "req"
+	Job      = "job"
+	Poll     = "poll"
+	Report   = "rpt"
+	Workflow = "wf"
+	Saga     = "saga"
+	Message  = "msg"
+)
+
+// MaxLength bounds IDs accepted from callers so a client cannot bloat every log line
+const MaxLength = 128
+
+// ErrInvalid is returned by Parse for IDs not minted by New
+var ErrInvalid = errors.New("requestid: not a prefixed ULID")
+
+type idKey struct{}
+
+// New returns a fresh ID such as req_01JA2X3M9Q8N5TB7VWD4KZ6C0E. IDs minted by one process
+// are strictly increasing, even within the same millisecond.
+func New(prefix string) string {
+	return prefix + "_" + ulid.Make().String()
+}
+
+// Parse splits an ID minted by New into its prefix and creation time
+func Parse(id string) (prefix string, created time.Time, err error) {
+	i := strings.LastIndexByte(id, '_')
+	if i < 0 {
+		return "", time.Time{}, ErrInvalid
+	}
+	u, err := ulid.ParseStrict(id[i+1:])
+	if err != nil {
+		return "", time.Time{}, ErrInvalid
+	}
+	return id[:i], ulid.Time(u.Time()), nil
+}
+
+// Valid reports whether an ID received from a caller is safe to log and forward. Any
+// format is accepted so IDs minted by upstream systems survive the hop unchanged.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// OrNew returns id if it is Valid, or a new ID with prefix otherwise
+func OrNew(id, prefix string) string {
+	if Valid(id) {
+		return id
+	}
+	return New(prefix)
+}
+
+// WithContext returns a copy of ctx carrying id
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the ID stored in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
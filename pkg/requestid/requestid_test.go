@@ -0,0 +1,109 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewParse(t *testing.T) {
+	before := time.Now().Add(-time.Millisecond)
+	id := New(Job)
+	if !strings.HasPrefix(id, "job_") || len(id) != len("job_")+26 {
+		t.Fatalf("New(Job) = %q, want job_ followed by a 26 character ULID", id)
+	}
+
+	prefix, created, err := Parse(id)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", id, err)
+	}
+	if prefix != Job {
+		t.Errorf("prefix = %q, want %q", prefix, Job)
+	}
+	if created.Before(before) || created.After(time.Now().Add(time.Millisecond)) {
+		t.Errorf("created = %v, want around %v", created, before)
+	}
+}
+
+func TestNewIncreasing(t *testing.T) {
+	previous := New(Request)
+	for i := 0; i < 1000; i++ {
+		id := New(Request)
+		if id <= previous {
+			t.Fatalf("ID %q does not sort after %q", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, id := range []string{"", "req-000001", "req_not-a-ulid", "01JA7Q3YB2K8M4TN6W9CXE5HRD"} {
+		if _, _, err := Parse(id); err != ErrInvalid {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalid", id, err)
+		}
+	}
+}
+
+func TestOrNew(t *testing.T) {
+	tests := []struct {
+		in   string
+		keep bool
+	}{
+		{"req_01JA7Q3YB2K8M4TN6W9CXE5HRD", true},
+		{"client-7", true},
+		{"trace:abc.123", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", MaxLength+1), false},
+	}
+	for _, tt := range tests {
+		got := OrNew(tt.in, Request)
+		if tt.keep && got != tt.in {
+			t.Errorf("OrNew(%q) = %q, want it kept", tt.in, got)
+		}
+		if !tt.keep && (got == tt.in || !strings.HasPrefix(got, "req_")) {
+			t.Errorf("OrNew(%q) = %q, want a new req_ ID", tt.in, got)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("FromContext(empty) = %q, want empty", id)
+	}
+	ctx := WithContext(context.Background(), "req_1")
+	if id := FromContext(ctx); id != "req_1" {
+		t.Errorf("FromContext = %q, want req_1", id)
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(Poll))
+	var seen string
+	r.GET("/", func(c *gin.Context) {
+		seen = FromContext(c.Request.Context())
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	minted := w.Header().Get(Header)
+	if !strings.HasPrefix(minted, "poll_") || seen != minted {
+		t.Errorf("minted header %q, handler saw %q; want the same poll_ ID", minted, seen)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "upstream-42")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(Header); got != "upstream-42" || seen != "upstream-42" {
+		t.Errorf("header %q, handler saw %q; want the caller's upstream-42", got, seen)
+	}
+}
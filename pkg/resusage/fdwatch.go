@@ -0,0 +1,136 @@
+package resusage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// EnvFDInterval is the variable FromEnv reads the descriptor check interval from; empty or
+// 0 disables the monitor
+const EnvFDInterval = "FD_WATCH_INTERVAL"
+
+// FDLevel is how close the process is to its descriptor limit
+type FDLevel int
+
+const (
+	FDNormal FDLevel = iota
+	FDWarning
+	FDCritical
+)
+
+func (l FDLevel) String() string {
+	switch l {
+	case FDWarning:
+		return "warning"
+	case FDCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// FDMonitor compares the open descriptor count with RLIMIT_NOFILE. It logs when usage
+// crosses into a higher level and once when it drops back below Warn minus Hysteresis,
+// rather than at every check. The Go runtime raises the soft limit to the hard limit at
+// startup, so the limit checked is usually the hard one.
+type FDMonitor struct {
+	// Warn and Critical are the usage fractions that log a warning and an error, 0.8 and
+	// 0.95 by default
+	Warn, Critical float64
+	// Hysteresis keeps usage hovering at Warn from logging on every check, 0.05 by default
+	Hysteresis float64
+
+	logger core.Logger
+	level  FDLevel
+	// sample is replaced in tests
+	sample func() (open int, limit uint64, ok bool)
+}
+
+// NewFDMonitor returns an FDMonitor with the default thresholds
+func NewFDMonitor(logger core.Logger) *FDMonitor {
+	return &FDMonitor{
+		Warn:       0.8,
+		Critical:   0.95,
+		Hysteresis: 0.05,
+		logger:     logger,
+		sample: func() (int, uint64, bool) {
+			open, ok := openFDs()
+			limit, limitOK := fdLimit()
+			return open, limit, ok && limitOK && limit > 0
+		},
+	}
+}
+
+// Check samples the descriptor count and logs a level change; it returns the current
+// level. Platforms without descriptor counts always report FDNormal.
+func (m *FDMonitor) Check() FDLevel {
+	open, limit, ok := m.sample()
+	if !ok {
+		return m.level
+	}
+	usage := float64(open) / float64(limit)
+	level := FDNormal
+	switch {
+	case usage >= m.Critical:
+		level = FDCritical
+	case usage >= m.Warn:
+		level = FDWarning
+	case m.level != FDNormal && usage >= m.Warn-m.Hysteresis:
+		// Not far enough below the threshold to call it recovered
+		level = FDWarning
+	}
+	if level == m.level {
+		return level
+	}
+
+	previous := m.level
+	m.level = level
+	fields := []interface{}{
+		"open_fds", open,
+		"max_fds", limit,
+		"usage_percent", float64(int64(usage*1000+0.5)) / 10,
+		"previous_level", previous.String(),
+	}
+	switch level {
+	case FDCritical:
+		m.logger.Errorw("Open file descriptors near limit", fields...)
+	case FDWarning:
+		if previous == FDNormal {
+			m.logger.Warnw("Open file descriptors approaching limit", fields...)
+		}
+	default:
+		m.logger.Infow("Open file descriptor usage back to normal", fields...)
+	}
+	return level
+}
+
+// Run checks every interval until ctx is done
+func (m *FDMonitor) Run(ctx context.Context, interval time.Duration) {
+	m.Check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// StartFDMonitor runs an FDMonitor in the background and returns the function that stops it
+func StartFDMonitor(logger core.Logger, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewFDMonitor(logger).Run(ctx, interval)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
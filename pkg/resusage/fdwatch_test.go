@@ -0,0 +1,65 @@
+package resusage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestFDMonitorLogsLevelChanges(t *testing.T) {
+	rec := testlog.New(t)
+	m := NewFDMonitor(rec.Logger)
+	open := 0
+	m.sample = func() (int, uint64, bool) { return open, 100, true }
+
+	for _, step := range []struct {
+		open int
+		want FDLevel
+	}{
+		{50, FDNormal},
+		{80, FDWarning},
+		{90, FDWarning},
+		{96, FDCritical},
+		{85, FDWarning},
+		// Within the hysteresis band of the warning threshold
+		{77, FDWarning},
+		{70, FDNormal},
+		{60, FDNormal},
+	} {
+		open = step.open
+		if got := m.Check(); got != step.want {
+			t.Errorf("%d of 100 open: level %v, want %v", step.open, got, step.want)
+		}
+	}
+
+	if n := rec.Count("warn", "Open file descriptors approaching limit"); n != 1 {
+		t.Errorf("logged %d warnings, want 1", n)
+	}
+	rec.AssertLogged("warn", "Open file descriptors approaching limit", "open_fds", 80, "max_fds", 100, "usage_percent", 80.0)
+	rec.AssertLogged("error", "Open file descriptors near limit", "open_fds", 96, "previous_level", "warning")
+	rec.AssertLogged("info", "Open file descriptor usage back to normal", "open_fds", 70)
+	if n := len(rec.Entries()); n != 3 {
+		t.Errorf("logged %d entries, want one per level change: %v", n, rec.Entries())
+	}
+}
+
+func TestFDMonitorWithoutCounts(t *testing.T) {
+	rec := testlog.New(t)
+	m := NewFDMonitor(rec.Logger)
+	m.sample = func() (int, uint64, bool) { return 0, 0, false }
+	if level := m.Check(); level != FDNormal || len(rec.Entries()) != 0 {
+		t.Errorf("level %v, entries %v", level, rec.Entries())
+	}
+}
+
+func TestFromEnvStartsFDMonitor(t *testing.T) {
+	rec := testlog.New(t)
+	t.Setenv(EnvInterval, "")
+	t.Setenv(EnvFDInterval, "20ms")
+	stop := FromEnv(rec.Logger)
+	time.Sleep(30 * time.Millisecond)
+	stop()
+	rec.AssertLogged("info", "File descriptor monitor started", "interval", "20ms", "component", "resusage")
+	rec.AssertNotLogged("info", "Resource usage reporter started")
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package resusage
+
+import "time"
+
+func cpuTime() (time.Duration, bool) { return 0, false }
+
+func openFDs() (int, bool) { return 0, false }
+
+func fdLimit() (uint64, bool) { return 0, false }
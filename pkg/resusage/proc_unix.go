@@ -0,0 +1,38 @@
+//go:build unix
+
+package resusage
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// cpuTime is the user plus system CPU time the process has used
+func cpuTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
+
+// openFDs counts the entries of /proc/self/fd on Linux or /dev/fd elsewhere, minus the
+// descriptor used to read the directory itself
+func openFDs() (int, bool) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries) - 1, true
+		}
+	}
+	return 0, false
+}
+
+// fdLimit is the soft limit on open descriptors
+func fdLimit() (uint64, bool) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, false
+	}
+	return uint64(limit.Cur), true
+}
@@ -0,0 +1,148 @@
+// Package resusage logs the process's resource usage in the background: goroutines, heap,
+// open file descriptors and CPU.
+//
+// Every demo calls FromEnv after creating its logger, so setting RESOURCE_LOG_INTERVAL
+// (e.g. 10s) turns on a "Resource usage" entry at that interval in any of them without a
+// code change. A goroutine or descriptor count that only grows shows up as a trend in the
+// log backend long before the process falls over. FD_WATCH_INTERVAL turns on an FDMonitor,
+// which warns as open descriptors approach RLIMIT_NOFILE.
+package resusage
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/dynamicfields"
+	"github.com/kart-io/logger/core"
+)
+
+// EnvInterval is the variable FromEnv reads the interval from; empty or 0 disables the
+// reporter
+const EnvInterval = "RESOURCE_LOG_INTERVAL"
+
+var (
+	goroutines = dynamicfields.Goroutines()
+	heapInUse  = dynamicfields.HeapInUse()
+	uptime     = dynamicfields.Uptime()
+)
+
+// Reporter samples resource usage; CPU is reported as a percentage of one core over the
+// time since the previous sample
+type Reporter struct {
+	logger core.Logger
+
+	mu      sync.Mutex
+	prevCPU time.Duration
+	prevAt  time.Time
+}
+
+// New returns a Reporter whose first CPU interval starts now
+func New(logger core.Logger) *Reporter {
+	r := &Reporter{logger: logger, prevAt: time.Now()}
+	r.prevCPU, _ = cpuTime()
+	return r
+}
+
+// Fields samples the process and returns the usage as key-value pairs. Values the
+// platform cannot provide, such as open descriptors outside Unix, are left out.
+func (r *Reporter) Fields() []interface{} {
+	fields := []interface{}{
+		"goroutines", goroutines(),
+		"heap_inuse_bytes", heapInUse(),
+	}
+	if n, ok := openFDs(); ok {
+		fields = append(fields, "open_fds", n)
+	}
+	if limit, ok := fdLimit(); ok {
+		fields = append(fields, "max_fds", limit)
+	}
+
+	if cpu, ok := cpuTime(); ok {
+		now := time.Now()
+		r.mu.Lock()
+		used, elapsed := cpu-r.prevCPU, now.Sub(r.prevAt)
+		r.prevCPU, r.prevAt = cpu, now
+		r.mu.Unlock()
+
+		percent := 0.0
+		if elapsed > 0 {
+			percent = float64(used) / float64(elapsed) * 100
+		}
+		fields = append(fields,
+			"cpu_percent", float64(int64(percent*10+0.5))/10,
+			"cpu_seconds_total", float64(cpu.Milliseconds())/1000,
+		)
+	}
+	return append(fields, "uptime_seconds", uptime())
+}
+
+// Run logs the usage every interval until ctx is done
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.logger.Infow("Resource usage", r.Fields()...)
+		}
+	}
+}
+
+// Start runs a Reporter in the background and returns the function that stops it
+func Start(logger core.Logger, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(logger).Run(ctx, interval)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// FromEnv starts a Reporter when RESOURCE_LOG_INTERVAL is set and an FDMonitor when
+// FD_WATCH_INTERVAL is set, and returns the function that stops them, a no-op when both
+// are off:
+//
+//	defer resusage.FromEnv(baseLogger)()
+func FromEnv(logger core.Logger) func() {
+	if os.Getenv(EnvInterval) == "" && os.Getenv(EnvFDInterval) == "" {
+		return func() {}
+	}
+	logger = logger.With("component", "resusage")
+
+	var stops []func()
+	if interval, ok := envInterval(logger, EnvInterval); ok {
+		logger.Infow("Resource usage reporter started", "interval", interval.String())
+		stops = append(stops, Start(logger, interval))
+	}
+	if interval, ok := envInterval(logger, EnvFDInterval); ok {
+		logger.Infow("File descriptor monitor started", "interval", interval.String())
+		stops = append(stops, StartFDMonitor(logger, interval))
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// envInterval parses an interval variable, warning about values that are not a duration
+func envInterval(logger core.Logger, env string) (time.Duration, bool) {
+	value := os.Getenv(env)
+	if value == "" {
+		return 0, false
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		logger.Warnw("Invalid resource usage interval, reporter disabled", "env", env, "value", value)
+		return 0, false
+	}
+	return interval, interval > 0
+}
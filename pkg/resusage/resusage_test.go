@@ -0,0 +1,93 @@
+package resusage
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func fieldMap(kv []interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for i := 0; i < len(kv); i += 2 {
+		fields[kv[i].(string)] = kv[i+1]
+	}
+	return fields
+}
+
+func TestFields(t *testing.T) {
+	r := New(testlog.New(t).Logger)
+	// Burn some CPU so the first interval has something to report
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for n := 0; time.Now().Before(deadline); n++ {
+		_ = n * n
+	}
+
+	fields := fieldMap(r.Fields())
+	if fields["goroutines"].(int) < 1 || fields["heap_inuse_bytes"].(uint64) == 0 {
+		t.Errorf("fields = %v", fields)
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		before := fields["open_fds"].(int)
+		f, err := os.Open(os.Args[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if after := fieldMap(r.Fields())["open_fds"].(int); after != before+1 {
+			t.Errorf("open_fds = %d after opening a file, want %d", after, before+1)
+		}
+		if cpu := fields["cpu_percent"].(float64); cpu <= 0 {
+			t.Errorf("cpu_percent = %v after a busy loop", cpu)
+		}
+		if fields["max_fds"].(uint64) == 0 {
+			t.Errorf("max_fds = %v", fields["max_fds"])
+		}
+	}
+}
+
+func TestStartLogsUntilStopped(t *testing.T) {
+	rec := testlog.New(t)
+	stop := Start(rec.Logger, 20*time.Millisecond)
+	time.Sleep(110 * time.Millisecond)
+	stop()
+	n := rec.Count("info", "Resource usage")
+	if n < 3 {
+		t.Fatalf("logged %d times in 110ms at a 20ms interval", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := rec.Count("info", "Resource usage"); after != n {
+		t.Errorf("logged %d more times after stop", after-n)
+	}
+	entry := rec.AssertLogged("info", "Resource usage")
+	for _, key := range []string{"goroutines", "heap_inuse_bytes", "uptime_seconds"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("entry has no %s: %v", key, entry)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	rec := testlog.New(t)
+
+	t.Setenv(EnvInterval, "")
+	FromEnv(rec.Logger)()
+	t.Setenv(EnvInterval, "0")
+	FromEnv(rec.Logger)()
+	if entries := rec.Entries(); len(entries) != 0 {
+		t.Fatalf("disabled reporter logged %v", entries)
+	}
+
+	t.Setenv(EnvInterval, "often")
+	FromEnv(rec.Logger)()
+	rec.AssertLogged("warn", "Invalid resource usage interval", "value", "often", "component", "resusage")
+
+	t.Setenv(EnvInterval, "20ms")
+	stop := FromEnv(rec.Logger)
+	time.Sleep(70 * time.Millisecond)
+	stop()
+	rec.AssertLogged("info", "Resource usage reporter started", "interval", "20ms")
+	rec.AssertLogged("info", "Resource usage", "component", "resusage")
+}
@@ -0,0 +1,99 @@
+// Package rng is the random source for simulated data in the demos: latencies, jitter,
+// failure injection, picked routes and amounts.
+//
+// The demos used the math/rand globals, so two runs never logged the same thing and a
+// README capture could not be regenerated. Everything here derives from one seed, read
+// from RANDOM_SEED (or --seed, see pkg/cliflags) on first use, or taken from the clock when
+// neither is set. The seed is in every demo's "Startup configuration" entry, so a run can
+// be repeated with the same values:
+//
+//	go run ./alerting-demo --seed 42
+//
+// Output only repeats where it does not depend on timing: goroutines drawing from the
+// shared source interleave differently between runs, so a component that needs its own
+// reproducible sequence takes a stream from New. Timestamps and ULIDs are not covered.
+// Nothing here is suitable for secrets or tokens; use crypto/rand for those.
+package rng
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnvSeed is the variable the seed is read from
+const EnvSeed = "RANDOM_SEED"
+
+var (
+	once   sync.Once
+	seed   int64
+	mu     sync.Mutex
+	shared *rand.Rand
+)
+
+func setup() {
+	once.Do(func() {
+		// Short enough to type back in and to survive JSON tools that read numbers as float64
+		seed = time.Now().UnixNano() % 1_000_000_000
+		if value := os.Getenv(EnvSeed); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "rng: invalid %s %q, using %d\n", EnvSeed, value, seed)
+			} else {
+				seed = n
+			}
+		}
+		shared = rand.New(rand.NewSource(seed))
+	})
+}
+
+// Seed returns the seed every value derives from
+func Seed() int64 {
+	setup()
+	return seed
+}
+
+// New returns a stream of its own for one component, derived from the seed and name. Its
+// values do not depend on what other goroutines draw from the shared source. The stream
+// is not safe for concurrent use.
+func New(name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewSource(Seed() ^ int64(h.Sum64())))
+}
+
+// Intn returns a value in [0, n) from the shared source
+func Intn(n int) int {
+	setup()
+	mu.Lock()
+	defer mu.Unlock()
+	return shared.Intn(n)
+}
+
+// Int63n returns a value in [0, n) from the shared source
+func Int63n(n int64) int64 {
+	setup()
+	mu.Lock()
+	defer mu.Unlock()
+	return shared.Int63n(n)
+}
+
+// Float64 returns a value in [0.0, 1.0) from the shared source
+func Float64() float64 {
+	setup()
+	mu.Lock()
+	defer mu.Unlock()
+	return shared.Float64()
+}
+
+// Read fills b from the shared source
+func Read(b []byte) {
+	setup()
+	mu.Lock()
+	defer mu.Unlock()
+	shared.Read(b)
+}
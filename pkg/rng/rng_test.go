@@ -0,0 +1,64 @@
+package rng
+
+import (
+	"sync"
+	"testing"
+)
+
+// reset forgets the seed so the next call reads RANDOM_SEED again
+func reset() {
+	once = sync.Once{}
+}
+
+func draw() []int64 {
+	var values []int64
+	for i := 0; i < 5; i++ {
+		values = append(values, int64(Intn(1000)), Int63n(1<<40))
+	}
+	return values
+}
+
+func TestSeedRepeats(t *testing.T) {
+	t.Cleanup(reset)
+	t.Setenv(EnvSeed, "42")
+	reset()
+	first := draw()
+	if Seed() != 42 {
+		t.Fatalf("Seed() = %d", Seed())
+	}
+	reset()
+	if second := draw(); len(first) != len(second) || first[0] != second[0] || first[9] != second[9] {
+		t.Errorf("seed 42 gave %v, then %v", first, second)
+	}
+
+	t.Setenv(EnvSeed, "43")
+	reset()
+	if other := draw(); other[0] == first[0] && other[1] == first[1] && other[3] == first[3] {
+		t.Errorf("seeds 42 and 43 gave the same values %v", other)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Cleanup(reset)
+	t.Setenv(EnvSeed, "7")
+	reset()
+	a, b := New("caching"), New("caching")
+	Intn(10) // the shared source does not affect streams
+	for i := 0; i < 5; i++ {
+		if x, y := a.Int63(), b.Int63(); x != y {
+			t.Fatalf("same name gave %d and %d", x, y)
+		}
+	}
+	if New("caching").Int63() == New("jobs").Int63() {
+		t.Error("different names gave the same stream")
+	}
+}
+
+func TestInvalidSeed(t *testing.T) {
+	t.Cleanup(reset)
+	t.Setenv(EnvSeed, "forty-two")
+	reset()
+	if Seed() == 0 {
+		t.Error("invalid seed left the seed at 0")
+	}
+}
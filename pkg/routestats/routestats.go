@@ -0,0 +1,195 @@
+// Package routestats logs a latency summary per route at a fixed interval, so a service
+// without Prometheus still shows how fast each endpoint answers, from its logs alone:
+//
+//	{"message":"Route latency summary","method":"GET","route":"/orders/:id","interval":"1m0s",
+//	 "count":1204,"errors":3,"error_rate":0.002,"p50_ms":12.4,"p95_ms":48.9,"p99_ms":130.2,"max_ms":402.7}
+//
+// Middleware records every request after the handlers ran; Report, called every interval
+// by Start, logs one entry per route that served requests since the previous report and
+// starts over. Errors are responses of 500 and above. The percentiles are exact up to
+// MaxSamples requests per route and interval and come from a uniform sample beyond that.
+package routestats
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+)
+
+// SummaryMessage is the message of the entries Report writes
+const SummaryMessage = "Route latency summary"
+
+// MaxSamples bounds the durations kept per route and interval
+const MaxSamples = 10000
+
+// key identifies a route
+type key struct {
+	method, route string
+}
+
+// routeStats collects one route's requests of the current interval
+type routeStats struct {
+	count, errors uint64
+	max           time.Duration
+	samples       []time.Duration
+}
+
+// Summarizer collects request durations by route and logs their summaries; it is safe for
+// concurrent use
+type Summarizer struct {
+	logger   core.Logger
+	interval time.Duration
+	clock    clock.Clock
+
+	mu     sync.Mutex
+	routes map[key]*routeStats
+	// rand picks the samples replaced once a route has MaxSamples
+	rand *rand.Rand
+	// since is when the current interval started
+	since time.Time
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New returns a Summarizer logging to logger every interval once started
+func New(logger core.Logger, interval time.Duration) *Summarizer {
+	s := &Summarizer{logger: logger, interval: interval, clock: clock.Real, routes: map[key]*routeStats{}, rand: rng.New("routestats")}
+	s.since = s.clock.Now()
+	return s
+}
+
+// Middleware records each request's route, status and duration. Install it before the
+// routes it should cover; unknown paths are recorded as the route "unmatched".
+func (s *Summarizer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := s.clock.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		s.Observe(c.Request.Method, route, c.Writer.Status(), s.clock.Since(start))
+	}
+}
+
+// Observe records one request; Middleware calls it, other servers can too
+func (s *Summarizer) Observe(method, route string, status int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key{method, route}
+	rs := s.routes[k]
+	if rs == nil {
+		rs = &routeStats{}
+		s.routes[k] = rs
+	}
+	rs.count++
+	if status >= 500 {
+		rs.errors++
+	}
+	rs.max = max(rs.max, duration)
+	// Reservoir sampling keeps every request equally likely to be among the samples
+	if len(rs.samples) < MaxSamples {
+		rs.samples = append(rs.samples, duration)
+	} else if i := s.rand.Int63n(int64(rs.count)); i < MaxSamples {
+		rs.samples[i] = duration
+	}
+}
+
+// Report logs the summary of every route that served requests since the previous report,
+// ordered by route and method, and starts a new interval
+func (s *Summarizer) Report() {
+	s.mu.Lock()
+	routes := s.routes
+	now := s.clock.Now()
+	elapsed := now.Sub(s.since)
+	s.routes = map[key]*routeStats{}
+	s.since = now
+	s.mu.Unlock()
+
+	keys := make([]key, 0, len(routes))
+	for k := range routes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	for _, k := range keys {
+		rs := routes[k]
+		sort.Slice(rs.samples, func(i, j int) bool { return rs.samples[i] < rs.samples[j] })
+		s.logger.Infow(SummaryMessage,
+			"method", k.method,
+			"route", k.route,
+			"interval", elapsed.Round(time.Millisecond).String(),
+			"count", rs.count,
+			"errors", rs.errors,
+			"error_rate", round(float64(rs.errors)/float64(rs.count)),
+			"p50_ms", millis(percentile(rs.samples, 0.50)),
+			"p95_ms", millis(percentile(rs.samples, 0.95)),
+			"p99_ms", millis(percentile(rs.samples, 0.99)),
+			"max_ms", millis(rs.max),
+		)
+	}
+}
+
+// percentile is the nearest-rank percentile p of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// millis is d in milliseconds with three decimals
+func millis(d time.Duration) float64 {
+	return round(float64(d) / float64(time.Millisecond))
+}
+
+// round keeps three decimals
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// Start reports every interval until Stop
+func (s *Summarizer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := s.clock.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				s.Report()
+			}
+		}
+	}()
+}
+
+// Stop ends the reports started by Start
+func (s *Summarizer) Stop() {
+	s.stopOnce.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+			<-s.done
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package routestats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func newSummarizer(t *testing.T) (*Summarizer, *clock.Fake, *testlog.Recorder) {
+	t.Helper()
+	rec := testlog.New(t)
+	s := New(rec.Logger, time.Minute)
+	fake := clock.NewFake(time.Now())
+	s.clock = fake
+	s.since = fake.Now()
+	return s, fake, rec
+}
+
+func TestReport(t *testing.T) {
+	s, fake, rec := newSummarizer(t)
+	// 1ms..100ms, the last two answered 503
+	for i := 1; i <= 100; i++ {
+		status := http.StatusOK
+		if i > 98 {
+			status = http.StatusServiceUnavailable
+		}
+		s.Observe(http.MethodGet, "/orders/:id", status, time.Duration(i)*time.Millisecond)
+	}
+	s.Observe(http.MethodPost, "/orders", http.StatusCreated, 2500*time.Microsecond)
+	fake.Advance(time.Minute)
+	s.Report()
+
+	rec.AssertLogged("info", SummaryMessage,
+		"method", "GET", "route", "/orders/:id", "interval", "1m0s",
+		"count", 100, "errors", 2, "error_rate", 0.02,
+		"p50_ms", 50, "p95_ms", 95, "p99_ms", 99, "max_ms", 100,
+	)
+	rec.AssertLogged("info", SummaryMessage, "method", "POST", "route", "/orders", "count", 1, "p99_ms", 2.5)
+	if entries := rec.Entries(); entries[0]["method"] != "POST" {
+		t.Errorf("first summary is for %v, want routes in order", entries[0]["route"])
+	}
+
+	// Each report covers its own interval only
+	rec.Reset()
+	s.Report()
+	if n := rec.Count("info", SummaryMessage); n != 0 {
+		t.Errorf("logged %d summaries for an idle interval, want none", n)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	s, _, rec := newSummarizer(t)
+	for i := 0; i < 3*MaxSamples; i++ {
+		s.Observe(http.MethodGet, "/health", http.StatusOK, time.Millisecond)
+	}
+	s.Observe(http.MethodGet, "/health", http.StatusOK, time.Second)
+	if n := len(s.routes[key{http.MethodGet, "/health"}].samples); n != MaxSamples {
+		t.Errorf("kept %d samples, want %d", n, MaxSamples)
+	}
+	s.Report()
+	rec.AssertLogged("info", SummaryMessage, "count", 3*MaxSamples+1, "p50_ms", 1, "max_ms", 1000)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, _, rec := newSummarizer(t)
+	r := gin.New()
+	r.Use(s.Middleware())
+	r.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for _, target := range []string{"/orders/1", "/orders/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	s.Report()
+	rec.AssertLogged("info", SummaryMessage, "route", "/orders/:id", "count", 2, "errors", 2, "error_rate", 1)
+	rec.AssertLogged("info", SummaryMessage, "route", "unmatched", "count", 1, "errors", 0)
+}
@@ -0,0 +1,48 @@
+package runsummary
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// levels orders the level names of LogOption.Level
+var levels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3, "fatal": 4}
+
+// Wrap returns a logger whose entries are counted in s, each with the fields added with
+// With. base was created with opt: entries below opt.Level are not counted, and Log
+// flushes base and reports opt.OutputPaths.
+func (s *Summary) Wrap(base core.Logger, opt *option.LogOption) core.Logger {
+	s.mu.Lock()
+	s.wrapped = append(s.wrapped, wrappedLogger{logger: base, outputs: opt.OutputPaths})
+	s.mu.Unlock()
+	level := levels[strings.ToLower(opt.Level)]
+	return logwrap.New(base, logwrap.Hooks{
+		Logged: func(e logwrap.Entry) {
+			// Fatal entries are not counted: the wrapped logger exits and no summary follows
+			if e.Level != logwrap.Fatal && levels[e.Level] >= level {
+				s.count(e)
+			}
+		},
+	})
+}
+
+// count adds one entry and its size as a JSON line
+func (s *Summary) count(e logwrap.Entry) {
+	entry := map[string]interface{}{"level": e.Level, "message": e.Message}
+	for _, kv := range [][]interface{}{e.With, e.Fields} {
+		for i := 0; i+1 < len(kv); i += 2 {
+			entry[fmt.Sprint(kv[i])] = kv[i+1]
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line = []byte(fmt.Sprint(entry))
+	}
+	s.entries.Add(1)
+	s.bytes.Add(uint64(len(line) + 1))
+}
@@ -0,0 +1,108 @@
+// Package runsummary logs one entry when a service shuts down gracefully, with what it did
+// since it started, so the end of a run can be read from the logs without a metrics
+// backend:
+//
+//	{"message":"Shutdown summary","uptime_seconds":3605.2,"requests":1204,"errors":3,
+//	 "entries_logged":5877,"bytes_logged":1630544,"outputs_flushed":["stdout"]}
+//
+// Middleware counts the requests a gin router served and the errors among them, responses
+// of 500 and above. Wrap counts the entries written through a logger at or above the level
+// it was created with, and their size as JSON: message, level and fields, without the
+// timestamp, caller and InitialFields the engine adds, so bytes_logged is a lower bound of
+// what reached each output. Log, called after the server stopped, flushes the wrapped
+// loggers and writes the summary, through one of them or a separate logger for a service
+// whose counted output must not carry it; outputs_flushed lists the output paths of the
+// loggers that flushed and flush_error is set when any did not.
+package runsummary
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// Message is the message of the entry Log writes
+const Message = "Shutdown summary"
+
+// Summary counts requests and log entries from New to Log; it is safe for concurrent use
+type Summary struct {
+	clock   clock.Clock
+	started time.Time
+
+	requests, errors atomic.Uint64
+	entries, bytes   atomic.Uint64
+
+	mu sync.Mutex
+	// wrapped are the loggers passed to Wrap, flushed by Log
+	wrapped []wrappedLogger
+}
+
+// wrappedLogger is a logger passed to Wrap and the outputs of the options it was created with
+type wrappedLogger struct {
+	logger  core.Logger
+	outputs []string
+}
+
+// New returns a Summary started now
+func New() *Summary {
+	s := &Summary{clock: clock.Real}
+	s.started = s.clock.Now()
+	return s
+}
+
+// Middleware counts every request once the handlers ran. Install it first, ahead of
+// gin.Recovery, so a request that panicked is counted as the 500 it was answered with.
+func (s *Summary) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		s.requests.Add(1)
+		if c.Writer.Status() >= 500 {
+			s.errors.Add(1)
+		}
+	}
+}
+
+// Fields returns the summary fields as of now, without flushing
+func (s *Summary) Fields() []interface{} {
+	return []interface{}{
+		"uptime_seconds", s.clock.Since(s.started).Seconds(),
+		"requests", s.requests.Load(),
+		"errors", s.errors.Load(),
+		"entries_logged", s.entries.Load(),
+		"bytes_logged", s.bytes.Load(),
+	}
+}
+
+// Log flushes the loggers passed to Wrap and writes the summary through logger. Call it
+// once the server has stopped, so no request is still being served or logged.
+func (s *Summary) Log(logger core.Logger) {
+	fields := s.Fields()
+	s.mu.Lock()
+	wrapped := s.wrapped
+	s.mu.Unlock()
+
+	flushed := []string{}
+	var errs []error
+	for _, w := range wrapped {
+		if err := w.logger.Flush(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, output := range w.outputs {
+			if !slices.Contains(flushed, output) {
+				flushed = append(flushed, output)
+			}
+		}
+	}
+	fields = append(fields, "outputs_flushed", flushed)
+	if err := errors.Join(errs...); err != nil {
+		fields = append(fields, "flush_error", err.Error())
+	}
+	logger.Infow(Message, fields...)
+}
@@ -0,0 +1,51 @@
+package runsummary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+)
+
+func TestLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	s := New()
+	fake := clock.NewFake(time.Now())
+	s.clock = fake
+	s.started = fake.Now()
+	log := s.Wrap(rec.Logger, &option.LogOption{Level: "info", OutputPaths: []string{"stdout", "logs/app.log"}}).With("component", "http")
+
+	r := gin.New()
+	r.Use(s.Middleware())
+	r.GET("/orders/:id", func(c *gin.Context) {
+		log.Infow("Order loaded", "order_id", c.Param("id"))
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		log.Debugw("Not counted below info")
+		c.Status(http.StatusBadGateway)
+	})
+	for _, path := range []string{"/orders/1", "/orders/2", "/fail"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	fake.Advance(90 * time.Second)
+
+	s.Log(rec.Logger)
+	entry := rec.AssertLogged("info", Message,
+		"uptime_seconds", 90,
+		"requests", 3,
+		"errors", 1,
+		"entries_logged", 2,
+		"outputs_flushed", []string{"stdout", "logs/app.log"},
+	)
+	// {"level":"info","message":"Order loaded","component":"http","order_id":"1"} and a newline
+	if bytes, _ := entry["bytes_logged"].(float64); bytes != 2*76 {
+		t.Fatalf("bytes_logged = %v, want %d", entry["bytes_logged"], 2*76)
+	}
+}
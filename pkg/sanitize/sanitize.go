@@ -0,0 +1,145 @@
+// Package sanitize renders a demo's resolved configuration in a form that is safe to log.
+//
+// Every demo calls Startup once its logger and settings are known, which writes a single
+// "Startup configuration" entry: the logger's engine, level, format and outputs, whether
+// OTLP export is on and where it goes, the pkg/rng seed that repeats the run, and the
+// demo's own settings such as its ports.
+// Header values, credentials in URLs and sensitive field names are masked with the rules
+// of pkg/redact, so the entry answers "what was this process actually running with"
+// without putting an API key in the log backend.
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/kart-io/go-example/pkg/redact"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+var redactor = redact.Default()
+
+// LogOption returns the logger settings of opt with credentials masked
+func LogOption(opt *option.LogOption) map[string]interface{} {
+	outputs := make([]string, len(opt.OutputPaths))
+	for i, path := range opt.OutputPaths {
+		outputs[i] = URL(path)
+	}
+	result := map[string]interface{}{
+		"engine":             opt.Engine,
+		"level":              opt.Level,
+		"format":             opt.Format,
+		"output_paths":       outputs,
+		"development":        opt.Development,
+		"disable_caller":     opt.DisableCaller,
+		"disable_stacktrace": opt.DisableStacktrace,
+	}
+	if len(opt.InitialFields) > 0 {
+		result["initial_fields"] = redactor.Value("", opt.InitialFields)
+	}
+	return result
+}
+
+// OTLP returns whether opt exports over OTLP and, when it does, where to: OTLP.Endpoint
+// when set, otherwise OTLPEndpoint
+func OTLP(opt *option.LogOption) map[string]interface{} {
+	enabled := opt.IsOTLPEnabled()
+	result := map[string]interface{}{"enabled": enabled}
+	if !enabled {
+		return result
+	}
+	endpoint := opt.OTLPEndpoint
+	if opt.OTLP != nil {
+		if opt.OTLP.Endpoint != "" {
+			endpoint = opt.OTLP.Endpoint
+		}
+		protocol := opt.OTLP.Protocol
+		if protocol == "" {
+			protocol = "grpc"
+		}
+		result["protocol"] = protocol
+		result["insecure"] = opt.OTLP.Insecure
+		if opt.OTLP.Timeout > 0 {
+			result["timeout"] = opt.OTLP.Timeout.String()
+		}
+		if len(opt.OTLP.Headers) > 0 {
+			result["headers"] = Headers(opt.OTLP.Headers)
+		}
+	}
+	result["endpoint"] = URL(endpoint)
+	return result
+}
+
+// Headers returns a copy of headers with the values of sensitive names, such as
+// Authorization and X-Api-Key, masked
+func Headers(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if redactor.SensitiveField(name) {
+			out[name] = redact.DefaultMask
+			continue
+		}
+		out[name] = redactor.String(value)
+	}
+	return out
+}
+
+// URL masks the password and credential-looking query parameters of a URL, and a user name
+// without a password, which is how Sentry DSNs and token-in-URL remotes carry their key.
+// Strings that are not URLs, such as file paths and host:port endpoints, are returned
+// unchanged.
+func URL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redact.DefaultMask)
+	} else if u.User != nil && u.User.Username() != "" {
+		u.User = url.User(redact.DefaultMask)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if redactor.SensitiveField(name) {
+				query.Set(name, redact.DefaultMask)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// Fields returns the startup entry's fields: the logger settings, OTLP status, random seed
+// and the demo's own settings in keysAndValues, redacted by key
+func Fields(opt *option.LogOption, keysAndValues ...interface{}) []interface{} {
+	fields := []interface{}{
+		"logger", LogOption(opt),
+		"otlp", OTLP(opt),
+		"random_seed", rng.Seed(),
+	}
+	if len(keysAndValues) > 0 {
+		settings := make(map[string]interface{}, len(keysAndValues)/2)
+		for i := 0; i+1 < len(keysAndValues); i += 2 {
+			key, _ := keysAndValues[i].(string)
+			settings[key] = redactor.Value(key, keysAndValues[i+1])
+			if s, ok := settings[key].(string); ok {
+				settings[key] = URL(s)
+			}
+		}
+		fields = append(fields, "settings", settings)
+	}
+	return fields
+}
+
+// Startup logs the "Startup configuration" entry:
+//
+//	sanitize.Startup(serviceLogger, logOption, "port", port)
+func Startup(logger core.Logger, opt *option.LogOption, keysAndValues ...interface{}) {
+	logger.Infow("Startup configuration", Fields(opt, keysAndValues...)...)
+}
@@ -0,0 +1,248 @@
+// Package secretref resolves configuration values that point at a secret instead of
+// holding it, so an API key or bearer token stays out of YAML files and process listings.
+//
+// A value may contain ${file:PATH}, replaced by the file's contents without surrounding
+// whitespace (the way Kubernetes and Docker mount secrets), and ${env:NAME}, replaced by
+// the variable. Anything else is kept as written:
+//
+//	authorization: "Bearer ${file:/run/secrets/otlp-token}"
+//	x-api-key: "${env:OTLP_API_KEY}"
+//
+// A Watcher re-reads the referenced files when they change, so a rotated token is picked up
+// without a restart. Values are never logged, only which keys changed and a fingerprint.
+package secretref
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// DefaultInterval is how often a Watcher checks the referenced files
+const DefaultInterval = 30 * time.Second
+
+var refPattern = regexp.MustCompile(`\$\{(file|env):([^}]+)\}`)
+
+// HasRefs reports whether any value references a secret
+func HasRefs(values map[string]string) bool {
+	for _, value := range values {
+		if refPattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns a copy of values with every reference replaced. A missing file, an empty
+// file or an unset variable is an error: sending the header without its secret would only
+// fail later, at the collector.
+func Resolve(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(values))
+	for key, value := range values {
+		var err error
+		resolved[key] = refPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			m := refPattern.FindStringSubmatch(ref)
+			secret, rerr := resolveRef(m[1], m[2])
+			if rerr != nil && err == nil {
+				err = fmt.Errorf("secretref: %s: %w", key, rerr)
+			}
+			return secret
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+func resolveRef(kind, name string) (string, error) {
+	if kind == "env" {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", name)
+	}
+	return value, nil
+}
+
+// files returns the files values reference, sorted
+func files(values map[string]string) []string {
+	var paths []string
+	for _, value := range values {
+		for _, m := range refPattern.FindAllStringSubmatch(value, -1) {
+			if m[1] == "file" && !slices.Contains(paths, m[2]) {
+				paths = append(paths, m[2])
+			}
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// Fingerprint identifies a secret in logs without revealing it
+func Fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Config controls a Watcher
+type Config struct {
+	// Interval between checks of the files, DefaultInterval when zero
+	Interval time.Duration
+	// OnChange receives the resolved values after a reload changed any of them
+	OnChange func(map[string]string)
+	// Clock defaults to clock.Real
+	Clock clock.Clock
+}
+
+// Watcher keeps values resolved while the files they reference are rotated
+type Watcher struct {
+	raw    map[string]string
+	cfg    Config
+	logger core.Logger
+
+	mu     sync.RWMutex
+	values map[string]string
+
+	// checkMu serializes reloads; modTimes are the file times last read
+	checkMu  sync.Mutex
+	modTimes map[string]time.Time
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New resolves values and fails when a reference cannot be resolved
+func New(values map[string]string, logger core.Logger, cfg Config) (*Watcher, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
+	w := &Watcher{raw: maps.Clone(values), cfg: cfg, logger: logger, modTimes: map[string]time.Time{}}
+	w.modified()
+	resolved, err := Resolve(values)
+	if err != nil {
+		return nil, err
+	}
+	w.values = resolved
+	return w, nil
+}
+
+// Values returns a copy of the current resolved values
+func (w *Watcher) Values() map[string]string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return maps.Clone(w.values)
+}
+
+// Start checks the files every Interval until Stop
+func (w *Watcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := w.cfg.Clock.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				w.Reload()
+			}
+		}
+	}()
+}
+
+// Stop ends the checks started by Start
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		if w.cancel != nil {
+			w.cancel()
+			<-w.done
+		}
+	})
+}
+
+// Reload re-resolves the values when a referenced file changed and reports whether any
+// value did. A reference that no longer resolves is logged and the previous values kept;
+// it is retried once a file changes again.
+func (w *Watcher) Reload() (bool, error) {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	changedFiles := w.modified()
+	if len(changedFiles) == 0 {
+		return false, nil
+	}
+	resolved, err := Resolve(w.raw)
+	if err != nil {
+		w.logger.Warnw("Secret reload failed, keeping the previous values", "files", changedFiles, "error", err.Error())
+		return false, err
+	}
+
+	w.mu.Lock()
+	var keys []string
+	fingerprints := map[string]string{}
+	for key, value := range resolved {
+		if w.values[key] != value {
+			keys = append(keys, key)
+			fingerprints[key] = Fingerprint(value)
+		}
+	}
+	w.values = resolved
+	w.mu.Unlock()
+	if len(keys) == 0 {
+		// Touched or rewritten with the same contents
+		return false, nil
+	}
+
+	slices.Sort(keys)
+	w.logger.Infow("Secret reloaded", "keys", keys, "files", changedFiles, "fingerprints", fingerprints)
+	if w.cfg.OnChange != nil {
+		w.cfg.OnChange(maps.Clone(resolved))
+	}
+	return true, nil
+}
+
+// modified returns the referenced files whose modification time changed, recording the
+// new times. A file that disappeared has a zero time, so it counts as changed once, when
+// Resolve reports it, and again when it is back.
+func (w *Watcher) modified() []string {
+	var changed []string
+	for _, file := range files(w.raw) {
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		if last, seen := w.modTimes[file]; !seen || !modTime.Equal(last) {
+			changed = append(changed, file)
+		}
+		w.modTimes[file] = modTime
+	}
+	return changed
+}
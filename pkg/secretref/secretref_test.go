@@ -0,0 +1,119 @@
+package secretref
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+var mtime = time.Now()
+
+// writeSecret writes value the way a mounted secret looks, with a modification time after
+// every earlier write
+func writeSecret(t *testing.T, path, value string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime = mtime.Add(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	writeSecret(t, token, "s3cr3t")
+	t.Setenv("TEST_OTLP_API_KEY", "k-123")
+
+	values := map[string]string{
+		"authorization": "Bearer ${file:" + token + "}",
+		"x-api-key":     "${env:TEST_OTLP_API_KEY}",
+		"x-environment": "production",
+	}
+	if !HasRefs(values) {
+		t.Fatal("references not found")
+	}
+	got, err := Resolve(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["authorization"] != "Bearer s3cr3t" || got["x-api-key"] != "k-123" || got["x-environment"] != "production" {
+		t.Errorf("Resolve = %v", got)
+	}
+	if values["authorization"] != "Bearer ${file:"+token+"}" {
+		t.Error("Resolve changed its argument")
+	}
+
+	for name, value := range map[string]string{
+		"unset variable": "${env:TEST_OTLP_UNSET}",
+		"missing file":   "${file:/nonexistent/token}",
+	} {
+		if _, err := Resolve(map[string]string{"x-api-key": value}); err == nil || !strings.Contains(err.Error(), "x-api-key") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if HasRefs(map[string]string{"x-api-key": "${OTLP_API_KEY}"}) {
+		t.Error("plain ${NAME} taken for a reference")
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	rec := testlog.New(t)
+	token := filepath.Join(t.TempDir(), "token")
+	writeSecret(t, token, "token-1")
+
+	var received map[string]string
+	w, err := New(map[string]string{"authorization": "Bearer ${file:" + token + "}"}, rec.Logger, Config{
+		OnChange: func(values map[string]string) { received = values },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := w.Reload(); changed || err != nil {
+		t.Fatalf("Reload = %v, %v without a change", changed, err)
+	}
+
+	writeSecret(t, token, "token-2")
+	if changed, err := w.Reload(); !changed || err != nil {
+		t.Fatalf("Reload = %v, %v after a rotation", changed, err)
+	}
+	if received["authorization"] != "Bearer token-2" || w.Values()["authorization"] != "Bearer token-2" {
+		t.Errorf("received %v, values %v", received, w.Values())
+	}
+	rec.AssertLogged("info", "Secret reloaded", "keys", []string{"authorization"}, "files", []string{token},
+		"fingerprints", map[string]string{"authorization": Fingerprint("Bearer token-2")})
+
+	// Rewriting the same token is not a change
+	writeSecret(t, token, "token-2")
+	if changed, _ := w.Reload(); changed {
+		t.Error("same contents reported as a change")
+	}
+
+	// A secret that is briefly gone keeps the last token, and is reported once
+	os.Remove(token)
+	if _, err := w.Reload(); err == nil {
+		t.Fatal("missing secret not reported")
+	}
+	w.Reload()
+	if n := rec.Count("warn", "Secret reload failed, keeping the previous values"); n != 1 {
+		t.Errorf("missing secret reported %d times, want once", n)
+	}
+	if w.Values()["authorization"] != "Bearer token-2" {
+		t.Errorf("values = %v after a failed reload", w.Values())
+	}
+	writeSecret(t, token, "token-3")
+	if changed, err := w.Reload(); !changed || err != nil {
+		t.Fatalf("Reload = %v, %v after the secret came back", changed, err)
+	}
+
+	for _, e := range rec.Entries() {
+		if strings.Contains(e.String(), "token-") {
+			t.Errorf("secret logged: %s", e)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package secretscan
+
+import (
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// LeakMessage is the warning logged after an entry had secrets masked
+const LeakMessage = "Secret masked in log entry"
+
+// Wrap returns a logger that applies s to everything logged through base. An entry that
+// had secrets masked is preceded by a LeakMessage warning; fields added with With are
+// scanned once, when they are attached.
+func (s *Scanner) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		// Both hooks warn themselves, so the warning's caller is the code that leaked
+		Entry: func(e *logwrap.Entry, out core.Logger) {
+			var findings []Finding
+			e.Message, e.Fields, findings = s.entry(e.Message, e.Fields)
+			if len(findings) > 0 {
+				out.Warnw(LeakMessage, s.report(findings)...)
+			}
+		},
+		With: func(keyValues []interface{}, out core.Logger) []interface{} {
+			_, keyValues, findings := s.entry("", keyValues)
+			if len(findings) > 0 {
+				out.Warnw(LeakMessage, s.report(findings)...)
+			}
+			return keyValues
+		},
+	})
+}
+
+// entry scans a message and its fields
+func (s *Scanner) entry(msg string, keysAndValues []interface{}) (string, []interface{}, []Finding) {
+	masked, rules := s.String(msg)
+	keysAndValues, findings := s.KeyValues(keysAndValues)
+	for _, rule := range rules {
+		findings = append(findings, Finding{Rule: rule, Field: "message"})
+	}
+	return masked, keysAndValues, findings
+}
+
+// report counts findings and returns the fields of their warning, which name the rules
+// and fields only, never the values
+func (s *Scanner) report(findings []Finding) []interface{} {
+	s.record(findings)
+	return []interface{}{
+		"secret_rules", distinct(findings, func(f Finding) string { return f.Rule }),
+		"secret_fields", distinct(findings, func(f Finding) string { return f.Field }),
+		"secret_count", len(findings),
+	}
+}
+
+func distinct(findings []Finding, field func(Finding) string) []string {
+	var out []string
+	seen := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		if v := field(f); !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
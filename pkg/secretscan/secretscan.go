@@ -0,0 +1,300 @@
+// Package secretscan finds credentials in log entries by what they look like rather than
+// by the field they are logged under.
+//
+// pkg/redact masks fields with sensitive names and a few well-known formats, which misses
+// the common leak: a token inside an upstream response body, a config dump or an error
+// string logged under an innocent key. A Scanner checks every string value and message
+// for provider key formats (AWS, GitHub, Slack, Stripe, Google), JWTs and PEM private
+// keys, and for long mixed-case strings whose Shannon entropy is typical of random
+// secrets. Matches are masked, and the wrapping logger reports each leak as a warning and
+// a Prometheus counter so the code that logged it can be found and fixed.
+package secretscan
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RuleEntropy names findings from the entropy check rather than a Rule
+const RuleEntropy = "high_entropy"
+
+// Defaults for Config fields left zero
+const (
+	DefaultMinLength  = 20
+	DefaultMinEntropy = 4.0
+)
+
+// Rule recognises one credential format
+type Rule struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Mask returns the replacement for one match; nil keeps a short prefix, e.g. AKIA****
+	Mask func(match string) string
+}
+
+// Built-in rules
+var (
+	AWSAccessKey = Rule{
+		Name:   "aws_access_key",
+		Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}
+	GitHubToken = Rule{
+		Name:   "github_token",
+		Regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	}
+	SlackToken = Rule{
+		Name:   "slack_token",
+		Regexp: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	}
+	StripeKey = Rule{
+		Name:   "stripe_key",
+		Regexp: regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`),
+	}
+	GoogleAPIKey = Rule{
+		Name:   "google_api_key",
+		Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`),
+	}
+	JWT = Rule{
+		Name:   "jwt",
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}
+	PrivateKey = Rule{
+		Name:   "private_key",
+		Regexp: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?)(?:-----END [A-Z ]*PRIVATE KEY-----|$)`),
+		Mask:   func(string) string { return "[PRIVATE KEY]" },
+	}
+)
+
+// DefaultRules are applied by Default
+var DefaultRules = []Rule{PrivateKey, JWT, AWSAccessKey, GitHubToken, SlackToken, StripeKey, GoogleAPIKey}
+
+// DefaultAllow are fields holding random-looking IDs that are not secrets
+var DefaultAllow = []string{"trace_id", "span_id", "request_id", "job_id", "service.instance.id"}
+
+// Config selects what a Scanner reports
+type Config struct {
+	Rules []Rule
+	// DisableEntropy turns off the check for random-looking strings
+	DisableEntropy bool
+	// MinLength and MinEntropy (bits per character) tune the entropy check; candidates
+	// must also mix lower case, upper case and digits
+	MinLength  int
+	MinEntropy float64
+	// Allow lists fields whose values are never scanned
+	Allow []string
+}
+
+// Finding is one suspected secret
+type Finding struct {
+	Rule string
+	// Field is the key the value was logged under, or "message"
+	Field string
+}
+
+// Scanner masks suspected secrets; it is safe for concurrent use
+type Scanner struct {
+	rules      []Rule
+	candidate  *regexp.Regexp
+	minEntropy float64
+	allow      map[string]bool
+
+	leaks   atomic.Uint64
+	counter atomic.Pointer[prometheus.CounterVec]
+}
+
+// New builds a Scanner from cfg
+func New(cfg Config) *Scanner {
+	s := &Scanner{
+		rules:      cfg.Rules,
+		minEntropy: cfg.MinEntropy,
+		allow:      make(map[string]bool, len(cfg.Allow)),
+	}
+	if s.minEntropy == 0 {
+		s.minEntropy = DefaultMinEntropy
+	}
+	if !cfg.DisableEntropy {
+		minLength := cfg.MinLength
+		if minLength <= 0 {
+			minLength = DefaultMinLength
+		}
+		s.candidate = regexp.MustCompile(fmt.Sprintf(`[A-Za-z0-9+/_\-]{%d,}=*`, minLength))
+	}
+	for _, field := range cfg.Allow {
+		s.allow[strings.ToLower(field)] = true
+	}
+	return s
+}
+
+// Default returns a Scanner with DefaultRules, the entropy check and DefaultAllow
+func Default() *Scanner {
+	return New(Config{Rules: DefaultRules, Allow: DefaultAllow})
+}
+
+// Instrument counts findings in go_example_secret_leaks_total, labelled by rule
+func (s *Scanner) Instrument(reg *metrics.Registry) {
+	s.counter.Store(reg.Counter("secret_leaks_total", "Suspected secrets masked in log entries.", "rule"))
+}
+
+// Leaks returns how many findings the scanner has reported
+func (s *Scanner) Leaks() uint64 {
+	return s.leaks.Load()
+}
+
+func (s *Scanner) record(findings []Finding) {
+	s.leaks.Add(uint64(len(findings)))
+	if counter := s.counter.Load(); counter != nil {
+		for _, f := range findings {
+			counter.WithLabelValues(f.Rule).Inc()
+		}
+	}
+}
+
+// String masks suspected secrets in str and returns the names of the rules that matched
+func (s *Scanner) String(str string) (string, []string) {
+	var rules []string
+	for _, rule := range s.rules {
+		str = rule.Regexp.ReplaceAllStringFunc(str, func(match string) string {
+			rules = append(rules, rule.Name)
+			if rule.Mask != nil {
+				return rule.Mask(match)
+			}
+			return maskPrefix(match)
+		})
+	}
+	if s.candidate != nil {
+		str = s.candidate.ReplaceAllStringFunc(str, func(match string) string {
+			if !randomLooking(match, s.minEntropy) {
+				return match
+			}
+			rules = append(rules, RuleEntropy)
+			return maskPrefix(match)
+		})
+	}
+	return str, rules
+}
+
+// Value returns v with suspected secrets masked, appending what was found under key to
+// findings. Strings, errors and string slices and maps are scanned; other types pass
+// through unchanged.
+func (s *Scanner) Value(key string, v interface{}, findings []Finding) (interface{}, []Finding) {
+	if s.allow[strings.ToLower(key)] {
+		return v, findings
+	}
+	scan := func(str string) string {
+		masked, rules := s.String(str)
+		for _, rule := range rules {
+			findings = append(findings, Finding{Rule: rule, Field: key})
+		}
+		return masked
+	}
+	switch v := v.(type) {
+	case string:
+		return scan(v), findings
+	case error:
+		return scan(v.Error()), findings
+	case []byte:
+		return scan(string(v)), findings
+	case []string:
+		out := make([]string, len(v))
+		for i, str := range v {
+			out[i] = scan(str)
+		}
+		return out, findings
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for k, str := range v {
+			if s.allow[strings.ToLower(k)] {
+				out[k] = str
+				continue
+			}
+			out[k] = scan(str)
+		}
+		return out, findings
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k], findings = s.Value(k, item, findings)
+		}
+		return out, findings
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i], findings = s.Value(key, item, findings)
+		}
+		return out, findings
+	}
+	return v, findings
+}
+
+// KeyValues returns a copy of a key/value list with suspected secrets masked, and what
+// was found
+func (s *Scanner) KeyValues(keyValues []interface{}) ([]interface{}, []Finding) {
+	var findings []Finding
+	out := make([]interface{}, len(keyValues))
+	for i := 0; i < len(keyValues); i += 2 {
+		out[i] = keyValues[i]
+		if i+1 < len(keyValues) {
+			out[i+1], findings = s.Value(fmt.Sprint(keyValues[i]), keyValues[i+1], findings)
+		}
+	}
+	return out, findings
+}
+
+// maskPrefix keeps up to four leading characters, enough to tell an AKIA key from a
+// ghp_ token when tracking down the leak
+func maskPrefix(match string) string {
+	keep := len(match) / 5
+	if keep > 4 {
+		keep = 4
+	}
+	return match[:keep] + "****"
+}
+
+// idPrefix matches type prefixes such as req_ and job_ in front of generated IDs
+var idPrefix = regexp.MustCompile(`^[a-z]{1,8}_`)
+
+// randomLooking reports whether s mixes lower case, upper case and digits and carries at
+// least minEntropy bits per character. Hex trace IDs, ULIDs (including prefixed ones such
+// as req_01HWX...) and identifiers in one case fail the first test; words joined in camel
+// case usually fail the second.
+func randomLooking(s string, minEntropy float64) bool {
+	s = s[len(idPrefix.FindString(s)):]
+	var lower, upper, digit bool
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return lower && upper && digit && Entropy(s) >= minEntropy
+}
+
+// Entropy returns the Shannon entropy of s in bits per byte
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
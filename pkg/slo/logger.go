@@ -0,0 +1,76 @@
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+)
+
+// Wrap returns a logger whose access-log entries are counted by t as requests, the
+// status and duration looked up in the fields added with With as well
+func (t *Tracker) Wrap(base core.Logger) core.Logger {
+	return logwrap.New(base, logwrap.Hooks{
+		Logged: func(e logwrap.Entry) {
+			if e.Level != logwrap.Fatal {
+				t.observe(e)
+			}
+		},
+	})
+}
+
+// observe counts an access-log entry; one without a readable status is not a request
+func (t *Tracker) observe(e logwrap.Entry) {
+	if e.Message != t.cfg.Message {
+		return
+	}
+	var status, duration interface{}
+	for _, kv := range [][]interface{}{e.With, e.Fields} {
+		for i := 0; i+1 < len(kv); i += 2 {
+			switch fmt.Sprint(kv[i]) {
+			case t.cfg.StatusField:
+				status = kv[i+1]
+			case t.cfg.DurationField:
+				duration = kv[i+1]
+			}
+		}
+	}
+	code, ok := toFloat(status)
+	if !ok {
+		return
+	}
+	var d time.Duration
+	if v, ok := duration.(time.Duration); ok {
+		d = v
+	} else if ms, ok := toFloat(duration); ok {
+		d = time.Duration(ms * float64(time.Millisecond))
+	}
+	t.Observe(int(code), d)
+}
+
+// toFloat reads a number logged as any numeric type or as text
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
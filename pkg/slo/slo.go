@@ -0,0 +1,160 @@
+// Package slo tracks service level objectives from a service's own access log, so the
+// error budget is known in process before any dashboard is built on top of it.
+//
+// Every access-log entry the wrapped logger writes is one request: a status of 500 or
+// above counts against availability, a duration over the threshold against latency. The
+// Tracker keeps the counts over a rolling window, computes each objective's SLI and the
+// share of its error budget left, and warns when the budget burns faster than a burn alert
+// allows:
+//
+//	message: Request completed   # the access-log message, with status and duration_ms fields
+//	window: 1h                   # rolling window the SLIs cover, at most 24h
+//	availability:
+//	  objective: 0.99            # share of requests answered below 500
+//	latency:
+//	  threshold: 250ms
+//	  objective: 0.95            # share of requests answered within threshold
+//	burn_alerts:
+//	  - window: 5m
+//	    burn_rate: 14.4          # budget spent 14.4 times faster than the objective allows
+//	    min_requests: 20
+//	check_interval: 10s
+//
+// A burn rate of 1 spends the budget exactly over the window; with the example above a
+// rate of 14.4 sustained for 5 minutes has used 2% of the hour's budget.
+package slo
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for the fields a Config leaves out
+const (
+	DefaultMessage       = "Request completed"
+	DefaultStatusField   = "status"
+	DefaultDurationField = "duration_ms"
+	DefaultCheckInterval = 10 * time.Second
+)
+
+// MaxWindow bounds Config.Window; the counts are kept per second
+const MaxWindow = 24 * time.Hour
+
+// Objective names, as they appear in logs and the status
+const (
+	Availability = "availability"
+	Latency      = "latency"
+)
+
+// Config is the YAML file
+type Config struct {
+	// Message selects the access-log entries, DefaultMessage when empty
+	Message string `yaml:"message"`
+	// StatusField and DurationField name the entry's fields, DefaultStatusField and
+	// DefaultDurationField when empty; the duration is in milliseconds unless it is a
+	// time.Duration
+	StatusField   string        `yaml:"status_field"`
+	DurationField string        `yaml:"duration_field"`
+	Window        time.Duration `yaml:"window"`
+	// Availability and Latency are tracked when set; at least one is required
+	Availability  *Objective    `yaml:"availability"`
+	Latency       *Objective    `yaml:"latency"`
+	BurnAlerts    []BurnAlert   `yaml:"burn_alerts"`
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// Objective is the share of requests that must be good
+type Objective struct {
+	Objective float64 `yaml:"objective"`
+	// Threshold is the slowest good request, for Latency only
+	Threshold time.Duration `yaml:"threshold"`
+}
+
+// BurnAlert warns when an objective's burn rate over Window reaches BurnRate
+type BurnAlert struct {
+	Window   time.Duration `yaml:"window"`
+	BurnRate float64       `yaml:"burn_rate"`
+	// MinRequests keeps a handful of requests from raising the alert
+	MinRequests int `yaml:"min_requests"`
+}
+
+// Load reads and validates an SLO file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read SLO config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse SLO config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports the first setting the Tracker cannot work with, naming the YAML key
+func (cfg Config) Validate() error {
+	if cfg.Window < time.Second || cfg.Window > MaxWindow {
+		return fmt.Errorf("window must be between 1s and %s", MaxWindow)
+	}
+	if cfg.Availability == nil && cfg.Latency == nil {
+		return fmt.Errorf("no objectives: set availability, latency or both")
+	}
+	if cfg.Availability != nil {
+		if err := cfg.Availability.validate(); err != nil {
+			return fmt.Errorf("availability.%w", err)
+		}
+	}
+	if cfg.Latency != nil {
+		if err := cfg.Latency.validate(); err != nil {
+			return fmt.Errorf("latency.%w", err)
+		}
+		if cfg.Latency.Threshold <= 0 {
+			return fmt.Errorf("latency.threshold must be a positive duration")
+		}
+	}
+	for i, alert := range cfg.BurnAlerts {
+		if alert.Window < time.Second || alert.Window > cfg.Window {
+			return fmt.Errorf("burn_alerts[%d].window must be between 1s and window", i)
+		}
+		if alert.BurnRate <= 0 {
+			return fmt.Errorf("burn_alerts[%d].burn_rate must be positive", i)
+		}
+		if alert.MinRequests < 0 {
+			return fmt.Errorf("burn_alerts[%d].min_requests must not be negative", i)
+		}
+	}
+	if cfg.CheckInterval < 0 {
+		return fmt.Errorf("check_interval must not be negative")
+	}
+	return nil
+}
+
+func (o Objective) validate() error {
+	if o.Objective <= 0 || o.Objective >= 1 {
+		return fmt.Errorf("objective must be between 0 and 1, not %g", o.Objective)
+	}
+	return nil
+}
+
+// withDefaults fills in the fields left out
+func (cfg Config) withDefaults() Config {
+	if cfg.Message == "" {
+		cfg.Message = DefaultMessage
+	}
+	if cfg.StatusField == "" {
+		cfg.StatusField = DefaultStatusField
+	}
+	if cfg.DurationField == "" {
+		cfg.DurationField = DefaultDurationField
+	}
+	if cfg.CheckInterval == 0 {
+		cfg.CheckInterval = DefaultCheckInterval
+	}
+	return cfg
+}
@@ -0,0 +1,165 @@
+package slo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/testlog"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slo.yaml")
+	os.WriteFile(path, []byte(`
+window: 1h
+availability:
+  objective: 0.99
+latency:
+  threshold: 250ms
+  objective: 0.95
+burn_alerts:
+  - window: 5m
+    burn_rate: 14.4
+    min_requests: 20
+`), 0o644)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Latency.Threshold != 250*time.Millisecond || cfg.BurnAlerts[0].BurnRate != 14.4 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	valid := func() Config {
+		return Config{
+			Window:       time.Hour,
+			Availability: &Objective{Objective: 0.99},
+			Latency:      &Objective{Objective: 0.95, Threshold: time.Second},
+			BurnAlerts:   []BurnAlert{{Window: time.Minute, BurnRate: 10}},
+		}
+	}
+	for name, tt := range map[string]struct {
+		mutate func(*Config)
+		want   string
+	}{
+		"no window":         {func(c *Config) { c.Window = 0 }, "window"},
+		"window too long":   {func(c *Config) { c.Window = 48 * time.Hour }, "window"},
+		"no objectives":     {func(c *Config) { c.Availability, c.Latency = nil, nil }, "no objectives"},
+		"objective of 1":    {func(c *Config) { c.Availability.Objective = 1 }, "availability.objective"},
+		"no threshold":      {func(c *Config) { c.Latency.Threshold = 0 }, "latency.threshold"},
+		"alert beyond SLO":  {func(c *Config) { c.BurnAlerts[0].Window = 2 * time.Hour }, "burn_alerts[0].window"},
+		"no burn rate":      {func(c *Config) { c.BurnAlerts[0].BurnRate = 0 }, "burn_alerts[0].burn_rate"},
+		"negative interval": {func(c *Config) { c.CheckInterval = -time.Second }, "check_interval"},
+	} {
+		cfg := valid()
+		tt.mutate(&cfg)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to mention %s", name, err, tt.want)
+		}
+	}
+}
+
+func newTracker(t *testing.T) (*Tracker, *clock.Fake, *testlog.Recorder) {
+	t.Helper()
+	rec := testlog.New(t)
+	tracker, err := New(Config{
+		Window:       time.Hour,
+		Availability: &Objective{Objective: 0.99},
+		Latency:      &Objective{Objective: 0.9, Threshold: 250 * time.Millisecond},
+		BurnAlerts:   []BurnAlert{{Window: time.Minute, BurnRate: 10, MinRequests: 10}},
+	}, rec.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := clock.NewFake(time.Now())
+	tracker.clock = fake
+	return tracker, fake, rec
+}
+
+func TestTracker(t *testing.T) {
+	tracker, fake, diagnostics := newTracker(t)
+	access := testlog.New(t)
+	log := tracker.Wrap(access.Logger).With("component", "http")
+
+	// 20 requests: 4 answered 503, 2 slow, one of them among the errors
+	for i := 0; i < 20; i++ {
+		status, duration := 200, int64(40)
+		if i < 4 {
+			status = 503
+		}
+		if i == 3 || i == 10 {
+			duration = 400
+		}
+		log.Infow("Request completed", "route", "/orders", "status", status, "duration_ms", duration)
+	}
+	log.Infow("Cache warmed", "status", 500)
+	access.AssertLogged("info", "Request completed", "component", "http", "status", 503)
+
+	status := tracker.Status()
+	if status.Requests != 20 {
+		t.Fatalf("requests = %d, want 20", status.Requests)
+	}
+	availability, latency := status.Objectives[0], status.Objectives[1]
+	if *availability.SLI != 0.8 || availability.Bad != 4 || availability.BudgetRemaining != -19 {
+		t.Errorf("availability = %+v", availability)
+	}
+	if *latency.SLI != 0.9 || latency.Bad != 2 || latency.BudgetRemaining != 0 || latency.Threshold != "250ms" {
+		t.Errorf("latency = %+v", latency)
+	}
+	if burn := availability.BurnRates[0]; burn.BurnRate != 20 || !burn.Burning || burn.Requests != 20 {
+		t.Errorf("availability burn = %+v", burn)
+	}
+	if burn := latency.BurnRates[0]; burn.BurnRate != 1 || burn.Burning {
+		t.Errorf("latency burn = %+v", burn)
+	}
+
+	tracker.Check()
+	tracker.Check()
+	if n := diagnostics.Count("warn", BurnMessage); n != 1 {
+		t.Errorf("logged %d burn warnings, want one per alert that started", n)
+	}
+	diagnostics.AssertLogged("warn", BurnMessage, "slo", Availability, "window", "1m0s", "burn_rate", 20.0)
+
+	// The errors leave the alert window, not the SLO window
+	fake.Advance(2 * time.Minute)
+	tracker.Check()
+	diagnostics.AssertLogged("info", RecoveredMessage, "slo", Availability)
+	if status := tracker.Status(); status.Requests != 20 || status.Objectives[0].BurnRates[0].Requests != 0 {
+		t.Errorf("status after 2m = %+v", status)
+	}
+
+	fake.Advance(time.Hour)
+	if status := tracker.Status(); status.Requests != 0 || status.Objectives[0].SLI != nil || status.Objectives[0].BudgetRemaining != 1 {
+		t.Errorf("status after the window = %+v", status)
+	}
+}
+
+func TestMinRequests(t *testing.T) {
+	tracker, _, diagnostics := newTracker(t)
+	for i := 0; i < 5; i++ {
+		tracker.Observe(500, 0)
+	}
+	tracker.Check()
+	diagnostics.AssertNotLogged("warn", BurnMessage)
+}
+
+func TestHandler(t *testing.T) {
+	tracker, _, _ := newTracker(t)
+	tracker.Observe(200, 10*time.Millisecond)
+	w := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slo", nil))
+
+	var status Status
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Window != "1h0m0s" || status.Requests != 1 || len(status.Objectives) != 2 || *status.Objectives[0].SLI != 1 {
+		t.Errorf("body = %s", w.Body)
+	}
+}
@@ -0,0 +1,278 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/logger/core"
+)
+
+// BurnMessage is the warning logged when a burn alert starts, RecoveredMessage the entry
+// logged when it ends
+const (
+	BurnMessage      = "SLO error budget burning"
+	RecoveredMessage = "SLO burn rate recovered"
+)
+
+// bucket counts the requests of one second
+type bucket struct {
+	second              int64
+	total, errors, slow uint64
+}
+
+// counts are the requests of a window
+type counts struct {
+	total, errors, slow uint64
+}
+
+// bad returns the requests that missed the named objective
+func (c counts) bad(name string) uint64 {
+	if name == Latency {
+		return c.slow
+	}
+	return c.errors
+}
+
+// Tracker computes the SLIs of the requests it observes; it is safe for concurrent use
+type Tracker struct {
+	cfg    Config
+	logger core.Logger
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	buckets []bucket
+	// burning holds the burn alerts that were firing at the last Check, by objective and
+	// alert window
+	burning map[string]bool
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New builds a Tracker for cfg. logger receives the burn warnings; it must not write the
+// access log the Tracker observes.
+func New(cfg Config, logger core.Logger) (*Tracker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	return &Tracker{
+		cfg:     cfg,
+		logger:  logger,
+		clock:   clock.Real,
+		buckets: make([]bucket, int(cfg.Window/time.Second)),
+		burning: map[string]bool{},
+	}, nil
+}
+
+// Observe counts one request. The wrapping loggers call it for every access-log entry,
+// other sources of requests can too.
+func (t *Tracker) Observe(status int, duration time.Duration) {
+	second := t.clock.Now().Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := &t.buckets[second%int64(len(t.buckets))]
+	if b.second != second {
+		*b = bucket{second: second}
+	}
+	b.total++
+	if status >= 500 {
+		b.errors++
+	}
+	if t.cfg.Latency != nil && duration > t.cfg.Latency.Threshold {
+		b.slow++
+	}
+}
+
+// counts sums the buckets of the last window, the current second included
+func (t *Tracker) counts(now time.Time, window time.Duration) counts {
+	last := now.Unix()
+	first := last - int64(window/time.Second) + 1
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var c counts
+	for _, b := range t.buckets {
+		if b.second >= first && b.second <= last {
+			c.total += b.total
+			c.errors += b.errors
+			c.slow += b.slow
+		}
+	}
+	return c
+}
+
+// Status is what the handler serves
+type Status struct {
+	Window     string            `json:"window"`
+	Requests   uint64            `json:"requests"`
+	Objectives []ObjectiveStatus `json:"objectives"`
+}
+
+// ObjectiveStatus is one objective over the window
+type ObjectiveStatus struct {
+	Name      string  `json:"name"`
+	Objective float64 `json:"objective"`
+	Threshold string  `json:"threshold,omitempty"`
+	// SLI is the share of good requests, nil before the first request
+	SLI *float64 `json:"sli"`
+	Bad uint64   `json:"bad"`
+	// BudgetRemaining is the share of the window's error budget left; it goes below zero
+	// once the objective is missed
+	BudgetRemaining float64      `json:"error_budget_remaining"`
+	BurnRates       []BurnStatus `json:"burn_rates"`
+}
+
+// BurnStatus is one burn alert of an objective
+type BurnStatus struct {
+	Window    string  `json:"window"`
+	Requests  uint64  `json:"requests"`
+	BurnRate  float64 `json:"burn_rate"`
+	Threshold float64 `json:"threshold"`
+	Burning   bool    `json:"burning"`
+}
+
+// namedObjective is a tracked objective with its name
+type namedObjective struct {
+	name string
+	obj  Objective
+}
+
+// objectives returns the tracked objectives, availability first
+func (t *Tracker) objectives() []namedObjective {
+	var out []namedObjective
+	if t.cfg.Availability != nil {
+		out = append(out, namedObjective{Availability, *t.cfg.Availability})
+	}
+	if t.cfg.Latency != nil {
+		out = append(out, namedObjective{Latency, *t.cfg.Latency})
+	}
+	return out
+}
+
+// Status computes every objective over the window and its burn alerts
+func (t *Tracker) Status() Status {
+	now := t.clock.Now()
+	total := t.counts(now, t.cfg.Window)
+	alerts := make([]counts, len(t.cfg.BurnAlerts))
+	for i, alert := range t.cfg.BurnAlerts {
+		alerts[i] = t.counts(now, alert.Window)
+	}
+
+	status := Status{Window: t.cfg.Window.String(), Requests: total.total}
+	for _, o := range t.objectives() {
+		s := ObjectiveStatus{
+			Name:            o.name,
+			Objective:       o.obj.Objective,
+			Bad:             total.bad(o.name),
+			BudgetRemaining: 1,
+			BurnRates:       []BurnStatus{},
+		}
+		if o.name == Latency {
+			s.Threshold = o.obj.Threshold.String()
+		}
+		if total.total > 0 {
+			sli := round(1 - float64(s.Bad)/float64(total.total))
+			s.SLI = &sli
+			s.BudgetRemaining = round(1 - burnRate(s.Bad, total.total, o.obj.Objective))
+		}
+		for i, alert := range t.cfg.BurnAlerts {
+			c := alerts[i]
+			rate := burnRate(c.bad(o.name), c.total, o.obj.Objective)
+			s.BurnRates = append(s.BurnRates, BurnStatus{
+				Window:    alert.Window.String(),
+				Requests:  c.total,
+				BurnRate:  round(rate),
+				Threshold: alert.BurnRate,
+				Burning:   c.total > 0 && c.total >= uint64(alert.MinRequests) && rate >= alert.BurnRate,
+			})
+		}
+		status.Objectives = append(status.Objectives, s)
+	}
+	return status
+}
+
+// burnRate is how many times faster than the objective allows the budget is spent
+func burnRate(bad, total uint64, objective float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - objective)
+}
+
+// round keeps three decimals
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// Check logs the burn alerts that started or ended since the previous Check
+func (t *Tracker) Check() {
+	status := t.Status()
+	for _, o := range status.Objectives {
+		for _, b := range o.BurnRates {
+			key := o.Name + "/" + b.Window
+			t.mu.Lock()
+			was := t.burning[key]
+			t.burning[key] = b.Burning
+			t.mu.Unlock()
+
+			switch {
+			case b.Burning && !was:
+				t.logger.Warnw(BurnMessage,
+					"slo", o.Name,
+					"window", b.Window,
+					"burn_rate", b.BurnRate,
+					"threshold", b.Threshold,
+					"requests", b.Requests,
+					"objective", o.Objective,
+					"error_budget_remaining", o.BudgetRemaining,
+				)
+			case !b.Burning && was:
+				t.logger.Infow(RecoveredMessage, "slo", o.Name, "window", b.Window, "burn_rate", b.BurnRate)
+			}
+		}
+	}
+}
+
+// Start checks the burn alerts every check interval until Stop
+func (t *Tracker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := t.clock.NewTicker(t.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				t.Check()
+			}
+		}
+	}()
+}
+
+// Stop ends the checks started by Start
+func (t *Tracker) Stop() {
+	t.stopOnce.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+			<-t.done
+		}
+	})
+}
+
+// Handler serves the current Status as JSON
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.Status())
+	})
+}
@@ -0,0 +1,18 @@
+package spanevents
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+)
+
+// GinMiddleware wraps the request-scoped logger so the handlers' Warn and Error entries
+// become events on the request's span. Install it after both the middleware that starts
+// the span and the one that stores the logger.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if logger, found := logcontext.Lookup(c.Request.Context()); found {
+			logcontext.SetGin(c, Wrap(c.Request.Context(), logger))
+		}
+		c.Next()
+	}
+}
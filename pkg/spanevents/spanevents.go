@@ -0,0 +1,118 @@
+// Package spanevents records a request's Warn and Error log entries as events on its span,
+// so the trace shows what went wrong where it happened without an AddEvent next to every
+// log call:
+//
+//	log := spanevents.Wrap(ctx, logger)
+//	log.Warnw("Inventory lookup failed", "sku", sku, "error", err)  // also a span event
+//
+// The event is named after the message and carries log.severity plus the entry's fields,
+// and the fields added through the wrapper with With, as attributes. Fields the logger had
+// before Wrap, typically trace_id and span_id, are left out since the span already is that
+// context. Debug and Info entries only go to the log, as does everything logged when ctx
+// holds no recording span; Fatal ends the process before the span could be exported.
+package spanevents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/logwrap"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SeverityKey is the attribute holding the level of the entry an event came from
+const SeverityKey = "log.severity"
+
+// Wrap returns a logger that also records Warn and Error entries on the span in ctx, or
+// logger itself when that span is not recording
+func Wrap(ctx context.Context, logger core.Logger) core.Logger {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return logger
+	}
+	return logwrap.New(logger, *hooks(span))
+}
+
+// hooks record the Warn and Error entries on span; a child made by WithCtx records on the
+// span in its context, or on span when that one is not recording
+func hooks(span trace.Span) *logwrap.Hooks {
+	return &logwrap.Hooks{
+		Logged: func(e logwrap.Entry) {
+			if e.Level == logwrap.Warn || e.Level == logwrap.Error {
+				record(span, strings.ToUpper(e.Level), e.Message, e.With, e.Fields)
+			}
+		},
+		Context: func(ctx context.Context) *logwrap.Hooks {
+			if child := trace.SpanFromContext(ctx); child.IsRecording() {
+				return hooks(child)
+			}
+			return nil
+		},
+	}
+}
+
+// record adds the event, unless the span ended in the meantime
+func record(span trace.Span, severity, msg string, fields, keysAndValues []interface{}) {
+	if !span.IsRecording() {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, 1+(len(fields)+len(keysAndValues))/2)
+	attrs = append(attrs, attribute.String(SeverityKey, severity))
+	attrs = append(attrs, Attributes(fields...)...)
+	attrs = append(attrs, Attributes(keysAndValues...)...)
+	span.AddEvent(msg, trace.WithAttributes(attrs...))
+}
+
+// Attributes converts log fields to span attributes. Numbers, bools, strings and their
+// slices keep their type; errors, durations and anything else become strings. A key
+// without a value is dropped.
+func Attributes(keysAndValues ...interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		attrs = append(attrs, attr(fmt.Sprint(keysAndValues[i]), keysAndValues[i+1]))
+	}
+	return attrs
+}
+
+func attr(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case []int:
+		return attribute.IntSlice(key, v)
+	case []int64:
+		return attribute.Int64Slice(key, v)
+	case []float64:
+		return attribute.Float64Slice(key, v)
+	case []bool:
+		return attribute.BoolSlice(key, v)
+	case time.Duration:
+		return attribute.String(key, v.String())
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,115 @@
+package spanevents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// startSpan starts a span recorded by the returned recorder
+func startSpan(t *testing.T) (context.Context, func() sdktrace.ReadOnlySpan) {
+	t.Helper()
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	ctx, span := provider.Tracer("orders").Start(context.Background(), "GET /orders/:id")
+	return ctx, func() sdktrace.ReadOnlySpan {
+		span.End()
+		ended := spans.Ended()
+		if len(ended) != 1 {
+			t.Fatalf("%d spans ended, want 1", len(ended))
+		}
+		return ended[0]
+	}
+}
+
+func attrs(event sdktrace.Event) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range event.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestWrap(t *testing.T) {
+	rec := testlog.New(t)
+	ctx, end := startSpan(t)
+	log := Wrap(ctx, rec.Logger.With("trace_id", "abc")).With("order_id", "o-1002")
+
+	log.Infow("Order loaded")
+	log.Warnw("Inventory lookup failed", "sku", "sku-monitor", "attempts", 3, "error", errors.New("timeout"), "elapsed", 1500*time.Millisecond)
+	log.Errorf("Failed to load order %s", "o-1002")
+
+	// The log entries are unchanged
+	rec.AssertLogged("warn", "Inventory lookup failed", "trace_id", "abc", "order_id", "o-1002", "sku", "sku-monitor")
+	rec.AssertLogged("error", "Failed to load order o-1002")
+
+	events := end().Events()
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want the warn and the error", events)
+	}
+	if events[0].Name != "Inventory lookup failed" || events[1].Name != "Failed to load order o-1002" {
+		t.Errorf("event names = %q, %q", events[0].Name, events[1].Name)
+	}
+	got := attrs(events[0])
+	for key, want := range map[attribute.Key]attribute.Value{
+		SeverityKey: attribute.StringValue("WARN"),
+		"order_id":  attribute.StringValue("o-1002"),
+		"sku":       attribute.StringValue("sku-monitor"),
+		"attempts":  attribute.IntValue(3),
+		"error":     attribute.StringValue("timeout"),
+		"elapsed":   attribute.StringValue("1.5s"),
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key].Emit(), want.Emit())
+		}
+	}
+	if _, ok := got["trace_id"]; ok {
+		t.Error("field from before Wrap copied to the event")
+	}
+	if severity := attrs(events[1])[SeverityKey].AsString(); severity != "ERROR" {
+		t.Errorf("error event severity = %q", severity)
+	}
+}
+
+func TestWrapWithoutSpan(t *testing.T) {
+	rec := testlog.New(t)
+	if log := Wrap(context.Background(), rec.Logger); log != rec.Logger {
+		t.Error("logger wrapped without a recording span")
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	ctx, end := startSpan(t)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		// Stands in for the tracing middleware
+		c.Request = c.Request.WithContext(ctx)
+	})
+	r.Use(logcontext.GinMiddleware(rec.Logger, nil))
+	r.Use(GinMiddleware())
+	r.POST("/orders", func(c *gin.Context) {
+		logcontext.FromGin(c).Warnw("Order rejected", "reason", "insufficient stock")
+		c.Status(http.StatusConflict)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	rec.AssertLogged("warn", "Order rejected", "reason", "insufficient stock")
+	events := end().Events()
+	if len(events) != 1 || events[0].Name != "Order rejected" || attrs(events[0])["reason"].AsString() != "insufficient stock" {
+		t.Errorf("events = %+v", events)
+	}
+}
@@ -0,0 +1,129 @@
+package testlog
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// engines are the LogOption engines the demos are built with
+var engines = []string{"zap", "slog"}
+
+// demoOption mirrors the LogOption most demos build in main
+func demoOption(engine, level string) option.LogOption {
+	return option.LogOption{
+		Engine: engine,
+		Level:  level,
+		InitialFields: map[string]interface{}{
+			"service.name":    "engine-test",
+			"service.version": "v1.2.3",
+			"environment":     "test",
+		},
+	}
+}
+
+// engineCases are logging sequences written the way the demos write them: Infow with
+// snake_case keys, errors as err.Error(), durations in milliseconds and times as RFC 3339
+var engineCases = []struct {
+	name  string
+	level string
+	log   func(l core.Logger)
+}{
+	{
+		name:  "levels",
+		level: "info",
+		log: func(l core.Logger) {
+			l.Debug("Dropped below the configured level")
+			l.Debugw("Dropped below the configured level", "attempt", 1)
+			l.Info("Server starting")
+			l.Infof("Listening on port %d", 8080)
+			l.Warn("Cache miss rate high")
+			l.Warnw("Slow request", "route", "/orders/:id", "duration_ms", 1250)
+			l.Errorf("Upstream returned %d", 503)
+			l.Errorw("Payment capture failed", "order_id", "ord-1042", "error", errors.New("card declined").Error())
+		},
+	},
+	{
+		name:  "fields",
+		level: "info",
+		log: func(l core.Logger) {
+			l.Infow("Order paid",
+				"order_id", "ord-1042",
+				"amount_cents", int64(129900),
+				"quantity", 3,
+				"discount", 0.15,
+				"gift", false,
+				"skus", []string{"sku-keyboard", "sku-mouse"},
+				"shipping", map[string]interface{}{"carrier": "acme-post", "express": true, "days": 2},
+				"paid_at", "2024-05-01T10:00:00Z",
+				"duration_ms", 42,
+			)
+			l.Infow("Unicode and escaping", "customer", "Zoë \"Z\" Müller", "note", "line1\nline2 <tag> & more")
+			l.Infow("Empty values", "coupon", "", "tags", []string{})
+		},
+	},
+	{
+		name:  "with",
+		level: "debug",
+		log: func(l core.Logger) {
+			worker := l.With("component", "worker")
+			job := worker.With("job_id", "job-7", "queue", "default")
+			job.Debugw("Job started", "attempt", 1)
+			job.Infow("Job finished", "duration_ms", 87)
+			worker.Warnw("Queue backlog", "depth", 120)
+			l.WithCtx(context.Background(), "request_id", "req-1").Infow("Request completed", "status", 200)
+			l.Infow("Parent logger unchanged")
+		},
+	},
+}
+
+// TestEnginesMatchGolden runs each sequence through zap and slog. Both must produce the
+// golden file's lines, and the two outputs are also compared directly so that -update
+// cannot paper over a difference between the engines.
+func TestEnginesMatchGolden(t *testing.T) {
+	for _, tc := range engineCases {
+		t.Run(tc.name, func(t *testing.T) {
+			golden := filepath.Join("testdata", "engines", tc.name+".golden")
+			outputs := make(map[string][]byte, len(engines))
+			for _, engine := range engines {
+				t.Run(engine, func(t *testing.T) {
+					rec := NewWithOption(t, demoOption(engine, tc.level))
+					tc.log(rec.Logger)
+					outputs[engine] = rec.Normalized()
+					rec.AssertGolden(golden)
+				})
+			}
+
+			zap, slog := string(outputs["zap"]), string(outputs["slog"])
+			if zap != slog {
+				t.Errorf("zap and slog output differ (want: zap, got: slog)\n%s", lineDiff(zap, slog))
+			}
+		})
+	}
+}
+
+// TestNormalizedDropsEngineKeys checks that what differs by engine or by run never reaches
+// a golden file
+func TestNormalizedDropsEngineKeys(t *testing.T) {
+	for _, engine := range engines {
+		rec := NewWithOption(t, demoOption(engine, "info"))
+		rec.Logger.Errorw("Boom", "request_id", "req-1")
+
+		entries := rec.Entries()
+		if len(entries) != 1 {
+			t.Fatalf("%s: captured %d entries, want 1", engine, len(entries))
+		}
+		got := string(rec.Normalized("request_id"))
+		want := `{"environment":"test","level":"error","message":"Boom","service.name":"engine-test","service.version":"v1.2.3"}` + "\n"
+		if got != want {
+			t.Errorf("%s: Normalized() = %s, want %s", engine, got, want)
+		}
+		if _, ok := entries[0]["timestamp"]; !ok {
+			t.Errorf("%s: entry has no timestamp: %s", engine, entries[0])
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package testlog captures what code under test logs so tests can assert on it.
+//
+// Recorder builds a real logger with the same engine and JSON format the demos use,
+// writing to a file in the test's temp dir instead of stdout. Assertions parse those
+// lines, so they check exactly what would reach a log pipeline: level, message and the
+// structured fields, including the ones added with With.
+//
+//	rec := testlog.New(t)
+//	handler := newHandler(rec.Logger)
+//	...
+//	rec.AssertLogged("warn", "Chaos fault injected", "rule_id", "inventory-503", "status", 503)
+package testlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+)
+
+// update rewrites golden files instead of comparing against them: go test ./... -update
+var update = flag.Bool("update", false, "rewrite golden files checked by testlog.AssertGolden")
+
+// volatileKeys are filled in by the engine from the wall clock and call site, or name the
+// engine itself, so golden files leave them out and hold for zap and slog alike
+var volatileKeys = []string{"timestamp", "caller", "stacktrace", "engine"}
+
+// Entry is one decoded log line
+type Entry map[string]interface{}
+
+// Level returns the entry's level, e.g. "info"
+func (e Entry) Level() string {
+	level, _ := e["level"].(string)
+	return level
+}
+
+// Message returns the entry's message
+func (e Entry) Message() string {
+	message, _ := e["message"].(string)
+	return message
+}
+
+// String renders the entry as it was logged, for failure messages
+func (e Entry) String() string {
+	b, err := json.Marshal(map[string]interface{}(e))
+	if err != nil {
+		return fmt.Sprint(map[string]interface{}(e))
+	}
+	return string(b)
+}
+
+// Recorder owns a logger whose output is kept for assertions
+type Recorder struct {
+	t      testing.TB
+	path   string
+	Logger core.Logger
+}
+
+// New returns a Recorder logging through zap at debug level. The logger is flushed when
+// the test ends.
+func New(t testing.TB) *Recorder {
+	t.Helper()
+	return NewWithOption(t, option.LogOption{Engine: "zap", Level: "debug"})
+}
+
+// NewWithOption returns a Recorder for a logger built from opt, for tests that need another
+// engine, level or InitialFields. Format and OutputPaths are overridden so the output is
+// JSON in the test's temp dir.
+func NewWithOption(t testing.TB, opt option.LogOption) *Recorder {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	opt.Format = "json"
+	opt.OutputPaths = []string{path}
+	log, err := logger.New(&opt)
+	if err != nil {
+		t.Fatalf("testlog: create logger: %v", err)
+	}
+	t.Cleanup(func() { _ = log.Flush() })
+	return &Recorder{t: t, path: path, Logger: log}
+}
+
+// Entries returns every line logged so far, oldest first
+func (r *Recorder) Entries() []Entry {
+	r.t.Helper()
+	_ = r.Logger.Flush()
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		r.t.Fatalf("testlog: read captured output: %v", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			r.t.Fatalf("testlog: captured line is not JSON: %v\n%s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Find returns the first entry at level whose message contains msgSubstring and whose
+// fields match. An empty level matches any level.
+func (r *Recorder) Find(level, msgSubstring string, fields ...interface{}) (Entry, bool) {
+	r.t.Helper()
+	for _, entry := range r.Entries() {
+		if matches(entry, level, msgSubstring, fields) {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Count returns how many entries match, with the same rules as Find
+func (r *Recorder) Count(level, msgSubstring string, fields ...interface{}) int {
+	r.t.Helper()
+	n := 0
+	for _, entry := range r.Entries() {
+		if matches(entry, level, msgSubstring, fields) {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertLogged fails the test unless an entry matches, and returns that entry so callers
+// can check fields whose values are not known up front, such as durations or IDs.
+//
+// fields are key/value pairs compared after a JSON round trip, so 503 matches a logged
+// 503 and []string{"a"} matches a logged array.
+func (r *Recorder) AssertLogged(level, msgSubstring string, fields ...interface{}) Entry {
+	r.t.Helper()
+	if entry, ok := r.Find(level, msgSubstring, fields...); ok {
+		return entry
+	}
+	r.t.Errorf("testlog: no %s entry containing %q with fields %v\ncaptured:\n%s",
+		levelName(level), msgSubstring, fields, r.dump())
+	return nil
+}
+
+// AssertNotLogged fails the test if any entry matches
+func (r *Recorder) AssertNotLogged(level, msgSubstring string, fields ...interface{}) {
+	r.t.Helper()
+	if entry, ok := r.Find(level, msgSubstring, fields...); ok {
+		r.t.Errorf("testlog: unexpected %s entry containing %q: %s", levelName(level), msgSubstring, entry)
+	}
+}
+
+// Normalized returns everything captured one entry per line with keys sorted, the form
+// golden files hold. The engine's timestamp, caller, stacktrace and engine name are left
+// out, as are the ignore keys, for values such as generated IDs that change between runs.
+func (r *Recorder) Normalized(ignore ...string) []byte {
+	r.t.Helper()
+	drop := append(append([]string{}, volatileKeys...), ignore...)
+	var out bytes.Buffer
+	for _, entry := range r.Entries() {
+		for _, key := range drop {
+			delete(entry, key)
+		}
+		line, err := json.Marshal(map[string]interface{}(entry))
+		if err != nil {
+			r.t.Fatalf("testlog: encode entry: %v", err)
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// AssertGolden compares the Normalized output with the golden file at path. Run the test
+// with -update to write the file from the current output.
+//
+// Pair it with a clock.Fake so timestamps and durations in the fields are reproducible.
+func (r *Recorder) AssertGolden(path string, ignore ...string) {
+	r.t.Helper()
+	got := r.Normalized(ignore...)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatalf("testlog: create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatalf("testlog: write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("testlog: read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("testlog: output differs from %s (run with -update to accept it)\n%s", path, lineDiff(string(want), string(got)))
+	}
+}
+
+// lineDiff lists the lines that differ between want and got, by position
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	var out []string
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			out = append(out, fmt.Sprintf("  line %d\n    want: %s\n    got:  %s", i+1, w, g))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// Reset discards everything captured so far
+func (r *Recorder) Reset() {
+	r.t.Helper()
+	_ = r.Logger.Flush()
+	if err := os.Truncate(r.path, 0); err != nil && !os.IsNotExist(err) {
+		r.t.Fatalf("testlog: reset captured output: %v", err)
+	}
+}
+
+func (r *Recorder) dump() string {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return "  (nothing)"
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, "  "+entry.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func matches(entry Entry, level, msgSubstring string, fields []interface{}) bool {
+	if level != "" && !strings.EqualFold(entry.Level(), level) {
+		return false
+	}
+	if !strings.Contains(entry.Message(), msgSubstring) {
+		return false
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		got, ok := entry[key]
+		if !ok || !sameJSON(got, fields[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameJSON compares a decoded value with an expected Go value by their JSON encodings
+func sameJSON(got, want interface{}) bool {
+	if err, ok := want.(error); ok {
+		want = err.Error()
+	}
+	g, err := json.Marshal(got)
+	if err != nil {
+		return false
+	}
+	w, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(g, w)
+}
+
+func levelName(level string) string {
+	if level == "" {
+		return "log"
+	}
+	return level
+}
@@ -0,0 +1,109 @@
+package timefmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Sink rewrites the timestamp of each JSON line before writing it to the underlying writer
+type Sink struct {
+	mu  sync.Mutex
+	c   Config
+	dst io.Writer
+	buf []byte
+}
+
+// NewSink returns a Sink that writes lines rewritten by c to dst
+func NewSink(c Config, dst io.Writer) *Sink {
+	return &Sink{c: c, dst: dst}
+}
+
+// Write rewrites every complete line in p. A trailing partial line is held until the
+// rest of it arrives, so callers that split one entry across writes still get it rewritten.
+func (s *Sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := s.dst.Write(s.c.Rewrite(s.buf[:i+1])); err != nil {
+			s.buf = s.buf[i+1:]
+			return len(p), err
+		}
+		s.buf = s.buf[i+1:]
+	}
+}
+
+// Sync writes out a held partial line and syncs the underlying writer when it supports it
+func (s *Sink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buf) > 0 {
+		line := s.c.Rewrite(s.buf)
+		s.buf = nil
+		if _, err := s.dst.Write(line); err != nil {
+			return err
+		}
+	}
+	if syncer, ok := s.dst.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// Close flushes the sink and closes the underlying file; stdout and stderr stay open
+func (s *Sink) Close() error {
+	err := s.Sync()
+	if f, ok := s.dst.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// RegisterSink registers the timefmt zap sink scheme; Apply calls it, and so can code that
+// builds paths with Config.Path itself. Calls after the first return its result.
+func RegisterSink() error {
+	registerOnce.Do(func() {
+		registerErr = zap.RegisterSink(Scheme, func(u *url.URL) (zap.Sink, error) {
+			c, output, _ := Parse(u.String())
+			if err := c.Validate(); err != nil {
+				return nil, err
+			}
+			dst, err := open(output)
+			if err != nil {
+				return nil, fmt.Errorf("timefmt: %s: %w", u, err)
+			}
+			return NewSink(c, dst), nil
+		})
+	})
+	return registerErr
+}
+
+// open returns the writer of an output path: stdout, stderr or a file appended to
+func open(output string) (io.Writer, error) {
+	switch output {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	return os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+}
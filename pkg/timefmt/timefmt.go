@@ -0,0 +1,218 @@
+// Package timefmt rewrites the timestamp of each JSON log line to a configured layout and
+// time zone when lines are written.
+//
+// The engines write "timestamp" as an RFC 3339 string in their own zone. A collector that
+// indexes on epoch milliseconds, or a developer reading local wall-clock times, wants
+// something else, and neither engine takes a layout. Apply wraps each output path of a zap
+// LogOption in the timefmt sink, which rewrites that one field and leaves the rest of the
+// line, and the order of its fields, as the engine wrote it:
+//
+//	if err := timefmt.Apply(logOption, timefmt.Config{Layout: timefmt.EpochMillis}); err != nil {
+//		...
+//	}
+//	// logOption.OutputPaths: [timefmt://epoch_millis?path=stdout]
+//
+// Like pkg/fieldconv it needs zap, which opens output paths through registered sinks.
+package timefmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kart-io/logger/option"
+)
+
+// Scheme is the zap sink scheme Apply wraps output paths in
+const Scheme = "timefmt"
+
+// Layouts
+const (
+	// RFC3339Nano is 2024-05-01T10:00:00.123456789Z, trailing zeros dropped
+	RFC3339Nano = "rfc3339nano"
+	// RFC3339Millis is 2024-05-01T10:00:00.123Z, always three digits
+	RFC3339Millis = "rfc3339millis"
+	// EpochMillis is milliseconds since the Unix epoch as a JSON number; the time zone
+	// does not apply to it
+	EpochMillis = "epoch_millis"
+)
+
+// Time zones besides the IANA names, such as Europe/Berlin, that time.LoadLocation takes
+const (
+	UTC   = "utc"
+	Local = "local"
+)
+
+// Layouts are the names Config.Layout takes
+var Layouts = []string{RFC3339Nano, RFC3339Millis, EpochMillis}
+
+// Config is how timestamps are written; the zero Config leaves them as the engine wrote them
+type Config struct {
+	Layout   string `mapstructure:"layout" yaml:"layout" json:"layout,omitempty"`
+	Timezone string `mapstructure:"timezone" yaml:"timezone" json:"timezone,omitempty"`
+}
+
+// IsZero reports whether c leaves timestamps unchanged
+func (c Config) IsZero() bool {
+	return c.Layout == "" && c.Timezone == ""
+}
+
+// Validate reports an unknown layout or time zone
+func (c Config) Validate() error {
+	if c.Layout != "" && !slices.Contains(Layouts, c.Layout) {
+		return fmt.Errorf("timefmt: unknown layout %q (want one of %s)", c.Layout, strings.Join(Layouts, ", "))
+	}
+	_, err := c.location()
+	return err
+}
+
+// location returns the zone of c; UTC when it names none
+func (c Config) location() (*time.Location, error) {
+	switch strings.ToLower(c.Timezone) {
+	case "", UTC:
+		return time.UTC, nil
+	case Local:
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timefmt: unknown time zone %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// Format returns t as the JSON value c writes: a string, or a number for EpochMillis
+func (c Config) Format(t time.Time) []byte {
+	loc, err := c.location()
+	if err != nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	switch c.Layout {
+	case EpochMillis:
+		return strconv.AppendInt(nil, t.UnixMilli(), 10)
+	case RFC3339Millis:
+		return strconv.AppendQuote(nil, t.Format("2006-01-02T15:04:05.000Z07:00"))
+	default:
+		return strconv.AppendQuote(nil, t.Format(time.RFC3339Nano))
+	}
+}
+
+// Rewrite replaces the top-level timestamp of one JSON object line, keeping the other
+// fields and their order as they are. A line without an RFC 3339 timestamp, or that is
+// not a JSON object, is returned unchanged.
+func (c Config) Rewrite(line []byte) []byte {
+	trimmed := bytes.TrimRight(line, "\r\n")
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return line
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return line
+		}
+		start := dec.InputOffset()
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return line
+		}
+		if tok != "timestamp" {
+			continue
+		}
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			return line
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return line
+		}
+		// The decoder's offset is before the colon; the value is the last thing up to end
+		end := dec.InputOffset()
+		at := int(end) - len(value)
+		if at < int(start) {
+			return line
+		}
+		out := make([]byte, 0, len(line)+8)
+		out = append(out, line[:at]...)
+		out = append(out, c.Format(t)...)
+		return append(out, line[end:]...)
+	}
+	return line
+}
+
+// Path returns output wrapped in the timefmt sink with c
+func (c Config) Path(output string) string {
+	query := url.Values{"path": {output}}
+	if c.Timezone != "" {
+		query.Set("tz", c.Timezone)
+	}
+	layout := c.Layout
+	if layout == "" {
+		layout = RFC3339Nano
+	}
+	return (&url.URL{Scheme: Scheme, Host: layout, RawQuery: query.Encode()}).String()
+}
+
+// Parse returns the config and the output of a path made by Path; ok is false for any
+// other path
+func Parse(path string) (c Config, output string, ok bool) {
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme != Scheme {
+		return Config{}, path, false
+	}
+	query := u.Query()
+	return Config{Layout: u.Host, Timezone: query.Get("tz")}, query.Get("path"), true
+}
+
+// Output returns the output path wraps, or path itself when it is not wrapped
+func Output(path string) string {
+	_, output, _ := Parse(path)
+	return output
+}
+
+// Of returns the config of the first wrapped output path of opt, so that code adding
+// outputs to a copy of opt can wrap them the same way
+func Of(opt *option.LogOption) (Config, bool) {
+	for _, path := range opt.OutputPaths {
+		if c, _, ok := Parse(path); ok {
+			return c, true
+		}
+	}
+	return Config{}, false
+}
+
+// Apply wraps every output path of opt in the timefmt sink with c, registering the sink
+// first. Paths that already have a scheme, such as other sinks, are left alone. The zero
+// Config leaves opt unchanged; engines other than zap are refused, as they do not open
+// sinks.
+func Apply(opt *option.LogOption, c Config) error {
+	if c.IsZero() {
+		return nil
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if opt.Engine != "zap" {
+		return fmt.Errorf("timefmt: the %s engine does not open sinks; timestamps can only be rewritten with zap", opt.Engine)
+	}
+	if err := RegisterSink(); err != nil {
+		return err
+	}
+	paths := make([]string, len(opt.OutputPaths))
+	for i, path := range opt.OutputPaths {
+		if strings.Contains(path, "://") {
+			paths[i] = path
+			continue
+		}
+		paths[i] = c.Path(path)
+	}
+	opt.OutputPaths = paths
+	return nil
+}
@@ -0,0 +1,76 @@
+package timefmt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kart-io/logger/option"
+)
+
+func TestRewrite(t *testing.T) {
+	line := []byte(`{"level":"info","timestamp": "2024-05-01T10:00:00.1234567Z","message":"Order paid"}` + "\n")
+	for _, tc := range []struct {
+		c    Config
+		want string
+	}{
+		{Config{Layout: RFC3339Nano}, `"2024-05-01T10:00:00.1234567Z"`},
+		{Config{Layout: RFC3339Millis, Timezone: "Asia/Tokyo"}, `"2024-05-01T19:00:00.123+09:00"`},
+		{Config{Layout: EpochMillis, Timezone: "Asia/Tokyo"}, `1714557600123`},
+	} {
+		want := `{"level":"info","timestamp": ` + tc.want + `,"message":"Order paid"}` + "\n"
+		if got := string(tc.c.Rewrite(line)); got != want {
+			t.Errorf("%+v:\n got %s\nwant %s", tc.c, got, want)
+		}
+	}
+
+	for _, line := range []string{"plain text\n", `{"timestamp":1714557600}` + "\n", `{"message":"x"}`, `{"timestamp":"yesterday"}`} {
+		if got := string(Config{Layout: EpochMillis}.Rewrite([]byte(line))); got != line {
+			t.Errorf("Rewrite(%q) = %q, want it unchanged", line, got)
+		}
+	}
+}
+
+func TestSinkHoldsPartialLines(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSink(Config{Layout: EpochMillis}, &buf)
+	s.Write([]byte(`{"timestamp":"2024-05-01T10:00:00Z",`))
+	if buf.Len() != 0 {
+		t.Fatalf("partial line written: %q", buf.String())
+	}
+	s.Write([]byte(`"message":"x"}` + "\n"))
+	if got := buf.String(); got != `{"timestamp":1714557600000,"message":"x"}`+"\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	opt := &option.LogOption{Engine: "zap", OutputPaths: []string{"stdout", "logs/app.log", "fieldconv://ecs"}}
+	c := Config{Layout: EpochMillis, Timezone: Local}
+	if err := Apply(opt, c); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"stdout", "logs/app.log"} {
+		got, output, ok := Parse(opt.OutputPaths[i])
+		if !ok || got != c || output != want {
+			t.Errorf("path %q: config %+v, output %q", opt.OutputPaths[i], got, output)
+		}
+	}
+	if opt.OutputPaths[2] != "fieldconv://ecs" {
+		t.Errorf("other sink rewrapped: %q", opt.OutputPaths[2])
+	}
+	if got, ok := Of(opt); !ok || got != c {
+		t.Errorf("Of = %+v, %v", got, ok)
+	}
+
+	if err := Apply(&option.LogOption{Engine: "slog", OutputPaths: []string{"stdout"}}, c); err == nil {
+		t.Error("slog engine: no error")
+	}
+	for _, bad := range []Config{{Layout: "unix"}, {Timezone: "Mars/Olympus"}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("zero config: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package traceparent
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+)
+
+// GinMiddleware extracts the caller's trace context into the request context and adds its
+// IDs to the request-scoped logger. Install it after logcontext.GinRequestLogger or
+// GinMiddleware, or before the latter with Fields in the GinFieldsFunc, since those derive
+// their logger from scratch.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, ok := Extract(c.Request.Context(), c.Request.Header)
+		if ok {
+			c.Request = c.Request.WithContext(ctx)
+			if logger, found := logcontext.Lookup(ctx); found {
+				logcontext.SetGin(c, logger.With(Fields(ctx)...))
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+// Package traceparent continues the caller's W3C trace context in services that do not run
+// the OpenTelemetry SDK, so their logs still correlate with upstream traces.
+//
+// The traceparent and tracestate headers are parsed with the API's TraceContext
+// propagator, which works without a TracerProvider, and the remote span context is stored
+// in the request context. Log lines get the caller's trace_id and span_id; a tracing
+// middleware further down, or an SDK enabled later, starts its spans as children of it,
+// and outgoing calls that inject the context forward the same trace.
+package traceparent
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header names defined by W3C Trace Context
+const (
+	Header      = "traceparent"
+	StateHeader = "tracestate"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Extract returns ctx carrying the span context in header and whether there was a valid
+// one. A context that already holds a valid span, started by a tracing middleware, is
+// returned unchanged. Malformed headers are ignored, as the specification requires; an
+// invalid tracestate is dropped but the traceparent kept.
+func Extract(ctx context.Context, header http.Header) (context.Context, bool) {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, false
+	}
+	ctx = propagator.Extract(ctx, propagation.HeaderCarrier(header))
+	return ctx, trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// Fields returns trace_id and span_id of the span in ctx as key-value pairs for a logger,
+// or nil without one. For a remote span context span_id is the caller's span.
+func Fields(ctx context.Context) []interface{} {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []interface{}{"trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String()}
+}
@@ -0,0 +1,103 @@
+package traceparent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID  = "00f067aa0ba902b7"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		tracestate  string
+		valid       bool
+		state       string
+	}{
+		{name: "sampled", traceparent: "00-" + traceID + "-" + spanID + "-01", valid: true},
+		{name: "not sampled", traceparent: "00-" + traceID + "-" + spanID + "-00", valid: true},
+		{name: "with tracestate", traceparent: "00-" + traceID + "-" + spanID + "-01", tracestate: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", valid: true, state: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		{name: "invalid tracestate dropped", traceparent: "00-" + traceID + "-" + spanID + "-01", tracestate: "not a list member", valid: true},
+		{name: "future version", traceparent: "01-" + traceID + "-" + spanID + "-01-extra", valid: true},
+		{name: "missing"},
+		{name: "zero trace id", traceparent: "00-00000000000000000000000000000000-" + spanID + "-01"},
+		{name: "zero span id", traceparent: "00-" + traceID + "-0000000000000000-01"},
+		{name: "version ff", traceparent: "ff-" + traceID + "-" + spanID + "-01"},
+		{name: "truncated", traceparent: "00-" + traceID + "-" + spanID},
+		{name: "garbage", traceparent: "req_01JA2X3M9Q8N5TB7VWD4KZ6C0E"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.traceparent != "" {
+				header.Set(Header, tt.traceparent)
+			}
+			if tt.tracestate != "" {
+				header.Set(StateHeader, tt.tracestate)
+			}
+			ctx, ok := Extract(context.Background(), header)
+			if ok != tt.valid {
+				t.Fatalf("Extract = %v, want %v", ok, tt.valid)
+			}
+			if !ok {
+				if Fields(ctx) != nil {
+					t.Errorf("Fields = %v without a trace", Fields(ctx))
+				}
+				return
+			}
+			spanContext := trace.SpanContextFromContext(ctx)
+			if !spanContext.IsRemote() || spanContext.TraceState().String() != tt.state {
+				t.Errorf("span context = %+v, tracestate %q", spanContext, spanContext.TraceState())
+			}
+			fields := Fields(ctx)
+			if len(fields) != 4 || fields[1] != traceID || fields[3] != spanID {
+				t.Errorf("Fields = %v", fields)
+			}
+		})
+	}
+}
+
+func TestExtractKeepsLocalSpan(t *testing.T) {
+	local := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+	ctx := trace.ContextWithSpanContext(context.Background(), local)
+	header := http.Header{}
+	header.Set(Header, "00-"+traceID+"-"+spanID+"-01")
+	if ctx, ok := Extract(ctx, header); ok || !trace.SpanContextFromContext(ctx).Equal(local) {
+		t.Error("Extract replaced the span a tracing middleware started")
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := testlog.New(t)
+	r := gin.New()
+	r.Use(logcontext.GinMiddleware(rec.Logger, func(c *gin.Context) []interface{} {
+		return []interface{}{"route", c.FullPath()}
+	}), GinMiddleware())
+	r.GET("/orders/:id", func(c *gin.Context) {
+		logcontext.FromGin(c).Infow("Order loaded", "order_id", c.Param("id"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set(Header, "00-"+traceID+"-"+spanID+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	rec.AssertLogged("info", "Order loaded", "route", "/orders/:id", "trace_id", traceID, "span_id", spanID)
+
+	// Without a traceparent the logger is left as it was
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/43", nil))
+	entry := rec.AssertLogged("info", "Order loaded", "order_id", "43")
+	if _, ok := entry["trace_id"]; ok {
+		t.Errorf("trace_id logged without a traceparent: %s", entry)
+	}
+}
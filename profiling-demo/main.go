@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"runtime"
+	rpprof "runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Continuous Profiling Demo ===")
+	console.Println("Pyroscope push or Parca pull profiling, labelled with the same service fields as the logs")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// The identity shared by logs and profiles
+	identity := map[string]string{
+		"service.name":    versionInfo.ServiceName,
+		"service.version": versionInfo.GitVersion,
+		"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+	}
+	initialFields := make(map[string]interface{}, len(identity))
+	for key, value := range identity {
+		initialFields[key] = value
+	}
+
+	logOption := &option.LogOption{
+		Engine:        "zap",
+		Level:         getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:        "json",
+		OutputPaths:   []string{"stdout"},
+		InitialFields: initialFields,
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer leakwatch.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	serviceLogger := baseLogger.With("component", "api")
+	profilerLogger := baseLogger.With("component", "profiler")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if d := getDurationEnv("DURATION", 0); d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	// Mutex profiles are empty unless sampling is switched on
+	runtime.SetMutexProfileFraction(5)
+
+	tags := profileLabels(identity)
+	mode := getEnvOrDefault("PROFILER", "pyroscope")
+	var stopProfiling func()
+	switch mode {
+	case "pyroscope":
+		serverAddress := os.Getenv("PYROSCOPE_URL")
+		if serverAddress == "" {
+			mock, err := StartMockPyroscope("127.0.0.1:0", baseLogger.With("component", "mock-pyroscope"))
+			if err != nil {
+				crash.Fatal(profilerLogger, "Failed to start mock Pyroscope", "error", err.Error())
+			}
+			defer mock.Close()
+			serverAddress = mock.URL()
+		}
+		profiler, err := StartProfiler(versionInfo.ServiceName, ProfilerConfig{
+			ServerAddress: serverAddress,
+			UploadRate:    getDurationEnv("UPLOAD_RATE", 10*time.Second),
+			Tags:          tags,
+		}, profilerLogger)
+		if err != nil {
+			crash.Fatal(profilerLogger, "Failed to start profiler", "error", err.Error())
+		}
+		stopProfiling = profiler.Stop
+	case "parca":
+		stopProfiling = startPprofServer(getEnvOrDefault("PPROF_ADDR", ":6060"), tags, profilerLogger)
+	case "none":
+		profilerLogger.Infow("Profiling disabled")
+		stopProfiling = func() {}
+	default:
+		crash.Fatal(profilerLogger, "Unknown profiler", "profiler", mode, "supported", []string{"pyroscope", "parca", "none"})
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery(), profileLabelMiddleware(), logcontext.GinRequestLogger(serviceLogger))
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+	r.GET("/hash", func(c *gin.Context) {
+		rounds, _ := strconv.Atoi(c.DefaultQuery("rounds", "200000"))
+		start := time.Now()
+		digest := hashRounds(rounds)
+		logcontext.MustFromGin(c).Debugw("Hash computed", "rounds", rounds, "duration_ms", time.Since(start).Milliseconds())
+		c.JSON(http.StatusOK, gin.H{"rounds": rounds, "digest": digest})
+	})
+	r.GET("/report", func(c *gin.Context) {
+		rows, _ := strconv.Atoi(c.DefaultQuery("rows", "20000"))
+		start := time.Now()
+		summary := buildReport(rows)
+		logcontext.MustFromGin(c).Debugw("Report built", "rows", rows, "duration_ms", time.Since(start).Milliseconds())
+		c.JSON(http.StatusOK, summary)
+	})
+
+	port := getEnvOrDefault("PORT", "8100")
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "profiler", mode)
+	serviceLogger.Infow("Starting profiling demo server", "port", port, "profiler", mode)
+
+	if rps := getIntEnv("TRAFFIC_RPS", 5); rps > 0 {
+		go generateTraffic(ctx, "http://localhost:"+port, rps)
+	}
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl 'http://localhost:%s/hash?rounds=1000000'\n", port)
+	console.Printf("  curl 'http://localhost:%s/report?rows=100000'\n", port)
+
+	<-ctx.Done()
+	serviceLogger.Infow("Shutting down")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	srv.Shutdown(shutdownCtx)
+	stopProfiling()
+	summary.Log(baseLogger)
+}
+
+// profileLabels derives profile labels from the logger identity. Profile label names cannot
+// contain dots, so service.name becomes service_name; values stay identical.
+func profileLabels(identity map[string]string) map[string]string {
+	labels := make(map[string]string, len(identity))
+	for key, value := range identity {
+		labels[strings.ReplaceAll(key, ".", "_")] = value
+	}
+	return labels
+}
+
+// profileLabelMiddleware tags samples taken while a request runs with its route, so flame
+// graphs can be filtered per endpoint in both Pyroscope and Parca
+func profileLabelMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rpprof.Do(c.Request.Context(), rpprof.Labels("http_route", c.FullPath()), func(context.Context) {
+			c.Next()
+		})
+	}
+}
+
+// startPprofServer exposes the pprof endpoints for Parca to scrape and logs every scrape
+func startPprofServer(addr string, labels map[string]string, logger core.Logger) func() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+
+	srv := &http.Server{Addr: addr, Handler: scrapeLogger(mux, logger)}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(logger, "pprof server failed to start", "error", err.Error(), "addr", addr)
+		}
+	}()
+
+	// Parca attaches labels from its scrape config; these are the values to put there
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	scrapeLabels := make([]string, 0, len(keys))
+	for _, key := range keys {
+		scrapeLabels = append(scrapeLabels, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	logger.Infow("Profiler started", "mode", "parca", "pprof_addr", addr, "scrape_labels", scrapeLabels)
+
+	started := time.Now()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		logger.Infow("Profiler stopped", "mode", "parca", "uptime", time.Since(started).Round(time.Second).String())
+	}
+}
+
+// scrapeLogger logs each pprof request, the pull-model counterpart of an upload
+func scrapeLogger(next http.Handler, logger core.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		logger.Infow("Profile scraped",
+			"profile", strings.TrimPrefix(r.URL.Path, "/debug/pprof/"),
+			"scraper", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
+
+// hashRounds is the CPU hot spot
+func hashRounds(rounds int) string {
+	sum := sha256.Sum256([]byte("seed"))
+	for i := 0; i < rounds; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+	return hex.EncodeToString(sum[:8])
+}
+
+// buildReport is the allocation hot spot
+func buildReport(rows int) gin.H {
+	lines := make([]string, 0, rows)
+	totals := map[string]int{}
+	for i := 0; i < rows; i++ {
+		region := []string{"eu", "us", "apac"}[rng.Intn(3)]
+		amount := rng.Intn(1000)
+		lines = append(lines, fmt.Sprintf("%d,%s,%d", i, region, amount))
+		totals[region] += amount
+	}
+	return gin.H{"rows": len(lines), "totals": totals}
+}
+
+// generateTraffic keeps both hot spots busy so profiles are not empty
+func generateTraffic(ctx context.Context, baseURL string, rps int) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+
+	paths := []string{"/hash", "/report"}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resp, err := client.Get(baseURL + paths[rng.Intn(len(paths))])
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockPyroscope accepts /ingest uploads and logs what arrived
+type MockPyroscope struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+}
+
+// StartMockPyroscope listens on addr and serves POST /ingest
+func StartMockPyroscope(addr string, logger core.Logger) (*MockPyroscope, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockPyroscope{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ingest", m.handleIngest)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// URL returns the server address to configure the agent with
+func (m *MockPyroscope) URL() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+// Close stops the server
+func (m *MockPyroscope) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockPyroscope) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("profile")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	io.Copy(io.Discard, file)
+
+	application, labels := parseProfileName(r.URL.Query().Get("name"))
+	m.logger.Debugw("Mock Pyroscope received profile",
+		"application", application,
+		"labels", labels,
+		"spy", r.URL.Query().Get("spyName"),
+		"bytes", header.Size,
+	)
+	w.WriteHeader(http.StatusOK)
+}
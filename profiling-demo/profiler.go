@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/pyroscope-go"
+	"github.com/kart-io/logger/core"
+)
+
+// ProfilerConfig selects where profiles go and how they are labelled
+type ProfilerConfig struct {
+	ServerAddress string
+	UploadRate    time.Duration
+	Tags          map[string]string
+}
+
+// Profiler wraps a Pyroscope session and logs its lifecycle and every upload
+type Profiler struct {
+	session *pyroscope.Profiler
+	logger  core.Logger
+	client  *uploadClient
+	started time.Time
+}
+
+// profileTypes are what the Go agent collects; goroutine and mutex profiles are cheap enough to keep on
+var profileTypes = []pyroscope.ProfileType{
+	pyroscope.ProfileCPU,
+	pyroscope.ProfileAllocObjects,
+	pyroscope.ProfileAllocSpace,
+	pyroscope.ProfileInuseObjects,
+	pyroscope.ProfileInuseSpace,
+	pyroscope.ProfileGoroutines,
+	pyroscope.ProfileMutexCount,
+	pyroscope.ProfileMutexDuration,
+}
+
+// StartProfiler starts continuous profiling and pushes to a Pyroscope server
+func StartProfiler(appName string, cfg ProfilerConfig, logger core.Logger) (*Profiler, error) {
+	client := &uploadClient{next: &http.Client{Timeout: 10 * time.Second}, logger: logger}
+	session, err := pyroscope.Start(pyroscope.Config{
+		ApplicationName: appName,
+		ServerAddress:   cfg.ServerAddress,
+		Tags:            cfg.Tags,
+		UploadRate:      cfg.UploadRate,
+		ProfileTypes:    profileTypes,
+		Logger:          agentLogger{logger: logger.WithCallerSkip(1)},
+		HTTPClient:      client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start profiler: %w", err)
+	}
+
+	types := make([]string, 0, len(profileTypes))
+	for _, t := range profileTypes {
+		types = append(types, string(t))
+	}
+	logger.Infow("Profiler started",
+		"server", cfg.ServerAddress,
+		"application", appName,
+		"tags", cfg.Tags,
+		"profile_types", types,
+		"upload_rate", cfg.UploadRate.String(),
+	)
+	return &Profiler{session: session, logger: logger, client: client, started: time.Now()}, nil
+}
+
+// Stop uploads the last partial interval and stops the session
+func (p *Profiler) Stop() {
+	p.logger.Infow("Stopping profiler, uploading final profiles")
+	p.session.Stop()
+	p.logger.Infow("Profiler stopped",
+		"uptime", time.Since(p.started).Round(time.Second).String(),
+		"uploads", p.client.uploads.Load(),
+		"upload_failures", p.client.failures.Load(),
+		"uploaded_bytes", p.client.bytes.Load(),
+	)
+}
+
+// uploadClient sits between the agent and the network so each upload is logged with its
+// outcome; the agent itself only reports failures
+type uploadClient struct {
+	next   *http.Client
+	logger core.Logger
+
+	uploads  atomic.Uint64
+	failures atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// Do performs one /ingest request
+func (c *uploadClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	fields := uploadFields(req.URL.Query())
+	size := req.ContentLength
+
+	resp, err := c.next.Do(req)
+	fields = append(fields, "bytes", size, "duration_ms", time.Since(start).Milliseconds())
+	switch {
+	case err != nil:
+		c.failures.Add(1)
+		c.logger.Warnw("Profile upload failed", append(fields, "error", err.Error())...)
+	case resp.StatusCode != http.StatusOK:
+		c.failures.Add(1)
+		c.logger.Warnw("Profile upload rejected", append(fields, "status", resp.StatusCode)...)
+	default:
+		c.uploads.Add(1)
+		c.bytes.Add(uint64(size))
+		c.logger.Infow("Profile uploaded", fields...)
+	}
+	return resp, err
+}
+
+// uploadFields describes an upload from its query: the collector scope, the profiled window
+// and the user tags, leaving out the agent's own bookkeeping labels
+func uploadFields(query url.Values) []interface{} {
+	application, labels := parseProfileName(query.Get("name"))
+	tags := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if !strings.HasPrefix(key, "__") && !strings.HasPrefix(key, "otel.") && !strings.HasPrefix(key, "process.") {
+			tags[key] = value
+		}
+	}
+
+	fields := []interface{}{"application", application, "scope", labels["otel.scope.name"], "tags", tags}
+	from, errFrom := strconv.ParseInt(query.Get("from"), 10, 64)
+	until, errUntil := strconv.ParseInt(query.Get("until"), 10, 64)
+	if errFrom == nil && errUntil == nil {
+		fields = append(fields, "window", time.Duration(until-from).Round(time.Millisecond).String())
+	}
+	return fields
+}
+
+// parseProfileName splits "app{env=prod,version=v1}" into the application and its labels
+func parseProfileName(name string) (string, map[string]string) {
+	application, rest, _ := strings.Cut(name, "{")
+	labels := map[string]string{}
+	for _, pair := range strings.Split(strings.TrimSuffix(rest, "}"), ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			labels[key] = value
+		}
+	}
+	return application, labels
+}
+
+// agentLogger adapts core.Logger to the Pyroscope agent's printf-style logger. The agent's
+// info output is a multi-line config dump, so it is demoted to debug; Profiler logs the summary.
+type agentLogger struct {
+	logger core.Logger
+}
+
+func (l agentLogger) Infof(format string, args ...interface{})  { l.logger.Debugf(format, args...) }
+func (l agentLogger) Debugf(format string, args ...interface{}) { l.logger.Debugf(format, args...) }
+
+// Errorf skips upload failures, which uploadClient already logged with more context
+func (l agentLogger) Errorf(format string, args ...interface{}) {
+	if strings.HasPrefix(format, "upload profile:") {
+		return
+	}
+	l.logger.Errorf(format, args...)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Rate-Limited Producer Demo ===")
+	console.Println("Caps the publish rate, buffers bursts and reports dropped/deferred counts per interval")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	topic := getEnvOrDefault("KAFKA_TOPIC", "events")
+
+	// Without external brokers, run an in-process Kafka cluster so the demo is self-contained
+	var brokers []string
+	if env := os.Getenv("KAFKA_BROKERS"); env != "" {
+		brokers = strings.Split(env, ",")
+	} else {
+		cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topic))
+		if err != nil {
+			crash.Fatal(baseLogger, "Failed to start in-process Kafka", "error", err.Error())
+		}
+		defer cluster.Close()
+		brokers = cluster.ListenAddrs()
+		baseLogger.Infow("Started in-process Kafka cluster", "brokers", brokers, "topic", topic)
+	}
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic(topic))
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to create Kafka client", "error", err.Error())
+	}
+	defer client.Close()
+
+	maxRate := getFloatEnv("MAX_RATE", 100)
+	tokenBurst := getIntEnv("TOKEN_BURST", 20)
+	bufferSize := getIntEnv("BUFFER_SIZE", 300)
+	producerLogger := baseLogger.With("component", "producer", "topic", topic)
+	producer := NewRateLimitedProducer(maxRate, tokenBurst, bufferSize,
+		func(ctx context.Context, key string, value []byte) error {
+			return client.ProduceSync(ctx, &kgo.Record{Key: []byte(key), Value: value}).FirstErr()
+		},
+		producerLogger,
+	)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelRun := context.WithTimeout(ctx, getDurationEnv("DURATION", 15*time.Second))
+	defer cancelRun()
+
+	// The sender keeps its own context so the buffer can drain after the load stops
+	sendCtx, cancelSend := context.WithCancel(context.Background())
+	defer cancelSend()
+	go producer.Run(sendCtx)
+	reportCtx, cancelReport := context.WithCancel(context.Background())
+	go producer.Report(reportCtx, getDurationEnv("REPORT_INTERVAL", time.Second))
+
+	loadRate := getIntEnv("LOAD_RATE", 60)
+	burstEvery := getDurationEnv("BURST_EVERY", 4*time.Second)
+	burstSize := getIntEnv("BURST_SIZE", 500)
+	sanitize.Startup(baseLogger, logOption, "brokers", brokers, "topic", topic)
+	producerLogger.Infow("Starting rate-limited producer",
+		"brokers", brokers,
+		"max_rate", maxRate,
+		"token_burst", tokenBurst,
+		"buffer_size", bufferSize,
+		"load_rate", loadRate,
+		"burst_every", burstEvery.String(),
+		"burst_size", burstSize,
+	)
+
+	generateLoad(ctx, producer, loadRate, burstEvery, burstSize)
+
+	// Stop accepting and drain what is already buffered
+	drainTimeout := getDurationEnv("DRAIN_TIMEOUT", 3*time.Second)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+	buffered := producer.Buffered()
+	drainStart := time.Now()
+	if err := producer.Close(drainCtx); err != nil {
+		cancelSend()
+		producerLogger.Warnw("Drain timed out, buffered messages were not published",
+			"drain_timeout", drainTimeout.String(),
+			"buffered_at_shutdown", buffered,
+		)
+	} else {
+		producerLogger.Infow("Producer drained",
+			"buffered_at_shutdown", buffered,
+			"drain_duration", time.Since(drainStart).Round(time.Millisecond).String(),
+		)
+	}
+	cancelReport()
+
+	totals := producer.Totals()
+	producerLogger.Infow("Demo finished",
+		"accepted", totals.Accepted,
+		"published", totals.Published,
+		"deferred", totals.Deferred,
+		"dropped", totals.Dropped,
+		"failed", totals.Failed,
+		"undelivered", totals.Accepted-totals.Published-totals.Failed,
+		"offered", totals.Accepted+totals.Dropped,
+	)
+}
+
+// generateLoad offers a steady stream with periodic bursts well above the limit
+func generateLoad(ctx context.Context, producer *RateLimitedProducer, rate int, burstEvery time.Duration, burstSize int) {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	burst := time.NewTicker(burstEvery)
+	defer burst.Stop()
+
+	seq := 0
+	offer := func() {
+		seq++
+		key := fmt.Sprintf("evt-%06d", seq)
+		// Dropped messages are counted by the producer and reported per interval
+		_ = producer.Enqueue(key, []byte(fmt.Sprintf(`{"id":%q,"ts":%d}`, key, time.Now().UnixMilli())))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			offer()
+		case <-burst.C:
+			for i := 0; i < burstSize; i++ {
+				offer()
+			}
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
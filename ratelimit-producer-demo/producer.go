@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	"golang.org/x/time/rate"
+)
+
+// ErrBufferFull is returned when a message is dropped because the burst buffer is full
+var ErrBufferFull = errors.New("publish buffer full")
+
+// PublishFunc delivers one message to the broker
+type PublishFunc func(ctx context.Context, key string, value []byte) error
+
+// Message is a buffered publish request
+type Message struct {
+	Key        string
+	Value      []byte
+	EnqueuedAt time.Time
+}
+
+// intervalCounters are reset after every report
+type intervalCounters struct {
+	accepted  atomic.Int64
+	published atomic.Int64
+	deferred  atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+	maxWaitNS atomic.Int64
+}
+
+// ProducerTotals are the counters for the whole run
+type ProducerTotals struct {
+	Accepted  int64
+	Published int64
+	Deferred  int64
+	Dropped   int64
+	Failed    int64
+}
+
+// RateLimitedProducer caps the publish rate, absorbs bursts in a bounded buffer
+// and drops what does not fit. Drops are counted and reported once per interval
+// instead of being logged one by one, so the logger never adds to the overload.
+type RateLimitedProducer struct {
+	limiter *rate.Limiter
+	buffer  chan Message
+	publish PublishFunc
+	logger  core.Logger
+
+	interval  intervalCounters
+	totals    ProducerTotals
+	lastError string
+	totalsMu  sync.Mutex
+
+	highWatermark int
+	backpressured bool
+	done          chan struct{}
+}
+
+// NewRateLimitedProducer creates a producer allowing limit messages per second with the given token burst and buffer size
+func NewRateLimitedProducer(limit float64, burst, bufferSize int, publish PublishFunc, logger core.Logger) *RateLimitedProducer {
+	return &RateLimitedProducer{
+		limiter:       rate.NewLimiter(rate.Limit(limit), burst),
+		buffer:        make(chan Message, bufferSize),
+		publish:       publish,
+		logger:        logger,
+		highWatermark: bufferSize * 8 / 10,
+		done:          make(chan struct{}),
+	}
+}
+
+// Enqueue buffers a message without blocking; it returns ErrBufferFull when the message is dropped
+func (p *RateLimitedProducer) Enqueue(key string, value []byte) error {
+	select {
+	case p.buffer <- Message{Key: key, Value: value, EnqueuedAt: time.Now()}:
+		p.interval.accepted.Add(1)
+		return nil
+	default:
+		p.interval.dropped.Add(1)
+		return ErrBufferFull
+	}
+}
+
+// Run sends buffered messages at the allowed rate until Close is called and the buffer is drained
+func (p *RateLimitedProducer) Run(ctx context.Context) {
+	defer close(p.done)
+	for msg := range p.buffer {
+		reservation := p.limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			// No token available: the message waits instead of being sent immediately
+			p.interval.deferred.Add(1)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				reservation.Cancel()
+				p.interval.failed.Add(1)
+				continue
+			}
+		}
+
+		wait := time.Since(msg.EnqueuedAt).Nanoseconds()
+		for {
+			current := p.interval.maxWaitNS.Load()
+			if wait <= current || p.interval.maxWaitNS.CompareAndSwap(current, wait) {
+				break
+			}
+		}
+
+		if err := p.publish(ctx, msg.Key, msg.Value); err != nil {
+			p.interval.failed.Add(1)
+			p.totalsMu.Lock()
+			p.lastError = err.Error()
+			p.totalsMu.Unlock()
+			continue
+		}
+		p.interval.published.Add(1)
+	}
+}
+
+// Buffered returns the number of messages waiting for a token
+func (p *RateLimitedProducer) Buffered() int {
+	return len(p.buffer)
+}
+
+// Close stops accepting messages and waits for the buffer to drain or ctx to expire
+func (p *RateLimitedProducer) Close(ctx context.Context) error {
+	close(p.buffer)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Report logs per-interval counts every interval until ctx is done
+func (p *RateLimitedProducer) Report(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.report(interval)
+		}
+	}
+}
+
+// intervalSnapshot is one interval's counters
+type intervalSnapshot struct {
+	accepted, published, deferred, dropped, failed int64
+	maxWait                                        time.Duration
+	lastError                                      string
+}
+
+// flush resets the interval counters and adds them to the totals
+func (p *RateLimitedProducer) flush() intervalSnapshot {
+	snap := intervalSnapshot{
+		accepted:  p.interval.accepted.Swap(0),
+		published: p.interval.published.Swap(0),
+		deferred:  p.interval.deferred.Swap(0),
+		dropped:   p.interval.dropped.Swap(0),
+		failed:    p.interval.failed.Swap(0),
+		maxWait:   time.Duration(p.interval.maxWaitNS.Swap(0)),
+	}
+
+	p.totalsMu.Lock()
+	defer p.totalsMu.Unlock()
+	snap.lastError, p.lastError = p.lastError, ""
+	p.totals.Accepted += snap.accepted
+	p.totals.Published += snap.published
+	p.totals.Deferred += snap.deferred
+	p.totals.Dropped += snap.dropped
+	p.totals.Failed += snap.failed
+	return snap
+}
+
+func (p *RateLimitedProducer) report(interval time.Duration) {
+	snap := p.flush()
+	buffered := p.Buffered()
+	if buffered >= p.highWatermark && !p.backpressured {
+		p.backpressured = true
+		p.logger.Warnw("Backpressure started, publish buffer above high watermark",
+			"buffered", buffered,
+			"high_watermark", p.highWatermark,
+		)
+	} else if buffered < p.highWatermark && p.backpressured {
+		p.backpressured = false
+		p.logger.Infow("Backpressure cleared", "buffered", buffered)
+	}
+
+	if snap.accepted+snap.published+snap.dropped+snap.failed == 0 {
+		return
+	}
+	fields := []interface{}{
+		"interval", interval.String(),
+		"accepted", snap.accepted,
+		"published", snap.published,
+		"deferred", snap.deferred,
+		"dropped", snap.dropped,
+		"failed", snap.failed,
+		"buffered", buffered,
+		"buffer_capacity", cap(p.buffer),
+		"rate_limit", float64(p.limiter.Limit()),
+		"publish_rate", float64(snap.published) / interval.Seconds(),
+		"max_queue_wait", snap.maxWait.Round(time.Millisecond).String(),
+	}
+	if snap.dropped > 0 || snap.failed > 0 {
+		if snap.lastError != "" {
+			fields = append(fields, "last_error", snap.lastError)
+		}
+		p.logger.Warnw("Publish interval stats", fields...)
+		return
+	}
+	p.logger.Infow("Publish interval stats", fields...)
+}
+
+// Totals adds the unreported interval to the run totals and returns them
+func (p *RateLimitedProducer) Totals() ProducerTotals {
+	p.flush()
+	p.totalsMu.Lock()
+	defer p.totalsMu.Unlock()
+	return p.totals
+}
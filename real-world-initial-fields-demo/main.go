@@ -1,20 +1,40 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/allowlist"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/cloudmeta"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/dataclass"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
 	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 )
 
 func main() {
-	fmt.Println("=== Real-World Initial Fields Demo ===")
-	fmt.Println("Web service with comprehensive initial fields\n")
+	cliflags.Parse()
+	console.Println("=== Real-World Initial Fields Demo ===")
+	console.Printf("Web service with comprehensive initial fields\n\n")
 
 	// Get version and environment info
 	versionInfo := version.Get()
@@ -33,8 +53,8 @@ func main() {
 			
 			// === Environment context ===
 			"environment": getEnvOrDefault("ENVIRONMENT", "development"),
-			"region":      getEnvOrDefault("AWS_REGION", "us-west-2"),
-			"az":          getEnvOrDefault("AWS_AZ", "us-west-2a"),
+			// cloud.region, cloud.availability_zone and host.id are added below from the
+			// instance metadata service instead of hardcoded defaults
 			
 			// === Kubernetes/Container context ===
 			"pod_name":      getEnvOrDefault("POD_NAME", "local-pod"),
@@ -89,24 +109,113 @@ func main() {
 		},
 	}
 
+	// Ask AWS/GCP/Azure instance metadata where we run; off-cloud no fields are added
+	for key, value := range cloudmeta.Fields(context.Background()) {
+		logOption.InitialFields[key] = value
+	}
+
+	// In production the data_classification InitialField picks what may be logged:
+	// fields data-policy.yaml tags confidential are masked, restricted ones dropped. The
+	// InitialFields are filtered before the logger is built, the rest by the wrapper.
+	policyPath := getEnvOrDefault("DATA_POLICY_FILE", "data-policy.yaml")
+	policy, err := dataclass.Load(policyPath)
+	if err != nil {
+		crash.Fatal(nil, "Failed to load data classification policy", "path", policyPath, "error", err.Error())
+	}
+	filter, err := dataclass.New(policy, logOption.InitialFields)
+	if err != nil {
+		crash.Fatal(nil, "Invalid data classification policy", "path", policyPath, "error", err.Error())
+	}
+	logOption.InitialFields = filter.InitialFields(logOption.InitialFields)
+
 	// Create logger - all fields above will be in every log entry
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
 	appLogger, err := logger.New(logOption)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create logger: %v", err))
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	appLogger = summary.Wrap(appLogger, logOption)
+	appLogger = filter.Wrap(appLogger)
+	crash.OnExit("logger", appLogger.Flush)
+	defer crash.Recover(appLogger)
+	defer resusage.FromEnv(appLogger)()
+	defer leakwatch.FromEnv(appLogger)()
+	defer pidfile.FromEnv(appLogger)()
+
+	// The handlers may only log the keys log-fields.yaml registers; the first use of any
+	// other key is reported once as an unregistered field
+	fieldsPath := getEnvOrDefault("FIELD_ALLOWLIST_FILE", "log-fields.yaml")
+	fieldsCfg, err := allowlist.Load(fieldsPath)
+	if err != nil {
+		crash.Fatal(appLogger, "Failed to load field allowlist", "path", fieldsPath, "error", err.Error())
+	}
+	fields, err := allowlist.New(fieldsCfg)
+	if err != nil {
+		crash.Fatal(appLogger, "Invalid field allowlist", "path", fieldsPath, "error", err.Error())
+	}
+
+	// Create Gin router; its debug-mode route table goes with the banners
+	gin.DefaultWriter = console.Writer()
+	r := newRouter(fields.Wrap(appLogger), versionInfo.GitVersion, summary.Middleware())
+
+	// Start the server
+	port := getEnvOrDefault("PORT", "8080")
+	
+	outputs.Log(appLogger)
+	sanitize.Startup(appLogger, logOption, "port", port, "data_policy", policyPath, "classified_fields", filter.Fields(), "field_allowlist", fieldsPath, "field_allowlist_mode", fields.Mode())
+	appLogger.Infow("Server starting",
+		"startup_time", time.Now().Format(time.RFC3339),
+		"pid", os.Getpid(),
+		"available_endpoints", []string{"/", "/users/:id", "/users", "/health"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these endpoints:")
+	console.Printf("  curl http://localhost:%s/\n", port)
+	console.Printf("  curl http://localhost:%s/users/123\n", port)
+	console.Printf("  curl http://localhost:%s/users/999\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/users\n", port)
+	console.Printf("  curl http://localhost:%s/health\n", port)
+	console.Println("\nNotice how EVERY log entry contains all the InitialFields!")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(appLogger, "Server failed to start",
+				"error", err.Error(),
+				"port", port,
+			)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		appLogger.Errorw("Server shutdown failed", "error", err.Error())
 	}
+	summary.Log(appLogger)
+}
 
-	// Create Gin router
+// newRouter serves the customer API behind middleware. Every route logs through a request
+// logger derived from appLogger, so each entry carries the InitialFields and the request's
+// fields.
+func newRouter(appLogger core.Logger, gitVersion string, middleware ...gin.HandlerFunc) *gin.Engine {
 	r := gin.New()
+	r.Use(middleware...)
+	r.Use(logcontext.GinRequestLogger(appLogger))
 	
 	// Use our logger for Gin middleware
 	r.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Log HTTP requests with our structured logger
-		appLogger.Infow("HTTP request",
-			"method", param.Method,
+		// Log HTTP requests with the request logger, which already has method and client_ip
+		param.Keys[logcontext.GinKey].(core.Logger).Infow("HTTP request",
 			"path", param.Path,
 			"status", param.StatusCode,
 			"latency_ms", param.Latency.Milliseconds(),
-			"client_ip", param.ClientIP,
 			"user_agent", param.Request.UserAgent(),
 		)
 		return ""
@@ -114,24 +223,26 @@ func main() {
 
 	// Routes with different log scenarios
 	r.GET("/", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
 		// Business logic log - all InitialFields will be included
-		appLogger.Infow("Homepage accessed",
+		log.Infow("Homepage accessed",
 			"user_type", "anonymous",
 			"referrer", c.Request.Header.Get("Referer"),
 		)
 		
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Customer API",
-			"version": versionInfo.GitVersion,
+			"version": gitVersion,
 			"status":  "healthy",
 		})
 	})
 
 	r.GET("/users/:id", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
 		userID := c.Param("id")
 		
 		// Simulate user lookup with detailed logging
-		appLogger.Infow("User lookup started",
+		log.Infow("User lookup started",
 			"user_id", userID,
 			"operation", "get_user",
 			"cache_enabled", true,
@@ -141,7 +252,7 @@ func main() {
 		time.Sleep(10 * time.Millisecond)
 		
 		if userID == "123" {
-			appLogger.Infow("User found",
+			log.Infow("User found",
 				"user_id", userID,
 				"user_status", "active",
 				"last_login", "2025-09-01T10:30:00Z",
@@ -155,7 +266,7 @@ func main() {
 			})
 		} else {
 			// Error case - still includes all InitialFields
-			appLogger.Warnw("User not found",
+			log.Warnw("User not found",
 				"user_id", userID,
 				"lookup_duration_ms", 10,
 				"searched_indexes", []string{"primary", "email", "username"},
@@ -169,14 +280,15 @@ func main() {
 	})
 
 	r.POST("/users", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
 		// Simulate user creation with error handling
-		appLogger.Infow("User creation started",
+		log.Infow("User creation started",
 			"operation", "create_user",
 			"request_size_bytes", c.Request.ContentLength,
 		)
 		
 		// Simulate validation error
-		appLogger.Errorw("User creation failed",
+		log.Errorw("User creation failed",
 			"error", "email already exists",
 			"validation_errors", []string{"email", "username"},
 			"retry_recommended", true,
@@ -187,9 +299,11 @@ func main() {
 		})
 	})
 
+	r.GET("/version", buildinfo.Handler())
 	r.GET("/health", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
 		// Health check with system status
-		appLogger.Debugw("Health check performed",
+		log.Debugw("Health check performed",
 			"check_type", "http",
 			"response_time_ms", 1,
 			"dependencies", map[string]string{
@@ -198,34 +312,13 @@ func main() {
 				"queue":    "healthy",
 			},
 		)
-		
-		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
-	})
-
-	// Start the server
-	port := getEnvOrDefault("PORT", "8080")
-	
-	appLogger.Infow("Server starting",
-		"startup_time", time.Now().Format(time.RFC3339),
-		"pid", os.Getpid(),
-		"available_endpoints", []string{"/", "/users/:id", "/users", "/health"},
-	)
-
-	fmt.Printf("Starting server on port %s\n", port)
-	fmt.Println("Try these endpoints:")
-	fmt.Printf("  curl http://localhost:%s/\n", port)
-	fmt.Printf("  curl http://localhost:%s/users/123\n", port)
-	fmt.Printf("  curl http://localhost:%s/users/999\n", port)
-	fmt.Printf("  curl -X POST http://localhost:%s/users\n", port)
-	fmt.Printf("  curl http://localhost:%s/health\n", port)
-	fmt.Println("\nNotice how EVERY log entry contains all the InitialFields!")
-
-	if err := r.Run(":" + port); err != nil {
-		appLogger.Fatalw("Server failed to start",
-			"error", err.Error(),
-			"port", port,
-		)
-	}
+	}, health.Handler(map[string]health.Checker{
+		// Simulated dependencies, always up
+		"database": func(ctx context.Context) health.Check { return health.Healthy(nil) },
+		"redis":    func(ctx context.Context) health.Check { return health.Healthy(nil) },
+		"queue":    func(ctx context.Context) health.Check { return health.Healthy(nil) },
+	}))
+	return r
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/logger/option"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		status  int
+		body    string
+		level   string
+		message string
+		fields  []interface{}
+	}{
+		{name: "homepage", method: http.MethodGet, target: "/", status: http.StatusOK, body: `"version":"v2.1.0"`,
+			level: "info", message: "Homepage accessed", fields: []interface{}{"user_type", "anonymous"}},
+		{name: "user found", method: http.MethodGet, target: "/users/123", status: http.StatusOK, body: `"name":"John Doe"`,
+			level: "info", message: "User found", fields: []interface{}{"user_id", "123", "user_status", "active"}},
+		{name: "user not found", method: http.MethodGet, target: "/users/999", status: http.StatusNotFound, body: `"error":"User not found"`,
+			level: "warn", message: "User not found", fields: []interface{}{"user_id", "999", "searched_indexes", []string{"primary", "email", "username"}}},
+		{name: "create user", method: http.MethodPost, target: "/users", status: http.StatusBadRequest, body: `"error":"Email already exists"`,
+			level: "error", message: "User creation failed", fields: []interface{}{"error", "email already exists", "retry_recommended", true}},
+		{name: "health", method: http.MethodGet, target: "/health", status: http.StatusOK, body: `"database":{"status":"healthy"}`,
+			level: "debug", message: "Health check performed", fields: []interface{}{"check_type", "http"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := testlog.NewWithOption(t, option.LogOption{
+				Engine:        "slog",
+				Level:         "debug",
+				InitialFields: map[string]interface{}{"app_name": "customer-api", "team": "platform"},
+			})
+			w := httptest.NewRecorder()
+			newRouter(rec.Logger, "v2.1.0").ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", w.Body, tt.body)
+			}
+			// Handler entries and the access log come from the same request logger
+			id := w.Header().Get(requestid.Header)
+			rec.AssertLogged(tt.level, tt.message, append([]interface{}{"request_id", id}, tt.fields...)...)
+			rec.AssertLogged("info", "HTTP request", "request_id", id, "method", tt.method, "path", tt.target, "status", tt.status)
+
+			// The point of the demo: the initial fields are on every entry, not just the first
+			for _, entry := range rec.Entries() {
+				if entry["app_name"] != "customer-api" || entry["team"] != "platform" {
+					t.Errorf("entry without the initial fields: %s", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthFollowsContract(t *testing.T) {
+	w := httptest.NewRecorder()
+	newRouter(testlog.New(t).Logger, "").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if problems := health.Validate(w.Body.Bytes()); problems != nil {
+		t.Errorf("/health breaks the contract: %v\n%s", problems, w.Body)
+	}
+}
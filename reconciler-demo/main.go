@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+const (
+	// specPollInterval controls how often the spec file is checked for changes
+	specPollInterval = 2 * time.Second
+	// defaultResync re-reconciles every key to detect out-of-band drift
+	defaultResync = 30 * time.Second
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Reconciler Controller Demo ===")
+	console.Println("Converges a directory towards spec.json with requeue and backoff logging")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"controller":      "file-reconciler",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	specPath := cliflags.ConfigOr(getEnvOrDefault("SPEC_PATH", "spec.json"))
+	managedDir := getEnvOrDefault("MANAGED_DIR", filepath.Join("data", "managed"))
+	resync := getDurationEnv("RESYNC_INTERVAL", defaultResync)
+	workers := getIntEnv("WORKERS", 2)
+
+	if err := os.MkdirAll(managedDir, 0755); err != nil {
+		crash.Fatal(serviceLogger, "Failed to create managed directory", "dir", managedDir, "error", err.Error())
+	}
+
+	spec := NewSpecSource(specPath)
+	if _, err := spec.Reload(); err != nil {
+		crash.Fatal(serviceLogger, "Failed to load spec", "spec_path", specPath, "error", err.Error())
+	}
+
+	reconciler := NewFileReconciler(managedDir, spec, resync)
+	queue := NewWorkQueue(500*time.Millisecond, 30*time.Second)
+	controller := NewController(reconciler, queue, serviceLogger)
+
+	sanitize.Startup(serviceLogger, logOption, "spec_path", specPath, "managed_dir", managedDir, "workers", workers)
+	serviceLogger.Infow("Controller starting",
+		"spec_path", specPath,
+		"managed_dir", managedDir,
+		"desired_files", len(spec.Names()),
+		"resync_interval", resync.String(),
+		"workers", workers,
+	)
+
+	console.Printf("Managing %s from %s\n", managedDir, specPath)
+	console.Println("Try these while it runs:")
+	console.Printf("  echo tampered > %s        # drift is repaired on the next resync\n", filepath.Join(managedDir, "app.conf"))
+	console.Printf("  touch %s            # unmanaged files are deleted\n", filepath.Join(managedDir, "stray.txt"))
+	console.Printf("  edit %s                                 # changes are picked up within %s\n", specPath, specPollInterval)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	controller.EnqueueAll("startup")
+	go watchSpec(ctx, spec, controller)
+	go func() {
+		ticker := time.NewTicker(resync)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				controller.EnqueueAll("resync")
+			}
+		}
+	}()
+
+	controller.Run(ctx, workers)
+	serviceLogger.Infow("Controller stopped", "reconcile_loops", controller.loops)
+	serviceLogger.Flush()
+}
+
+// watchSpec polls the spec file and enqueues every key when it changes
+func watchSpec(ctx context.Context, spec *SpecSource, controller *Controller) {
+	ticker := time.NewTicker(specPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := spec.Reload()
+			if err != nil {
+				// Keep reconciling against the last good spec
+				controller.logger.Warnw("Spec reload failed, keeping previous spec", "error", err.Error())
+				continue
+			}
+			if changed {
+				controller.logger.Infow("Spec changed", "spec_version", spec.Version(), "desired_files", len(spec.Names()))
+				controller.EnqueueAll("spec_changed")
+			}
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// WorkQueue is a deduplicating queue of keys with per-key exponential backoff
+type WorkQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []string
+	queued   map[string]bool
+	failures map[string]int
+	waiting  map[string]time.Time
+	shutdown bool
+
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// NewWorkQueue creates a queue whose retry delay doubles from baseDelay up to maxDelay
+func NewWorkQueue(baseDelay, maxDelay time.Duration) *WorkQueue {
+	q := &WorkQueue{
+		queued:    make(map[string]bool),
+		failures:  make(map[string]int),
+		waiting:   make(map[string]time.Time),
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add enqueues a key unless it is already waiting
+func (q *WorkQueue) Add(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shutdown || q.queued[key] {
+		return
+	}
+	q.queued[key] = true
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// AddAfter enqueues a key once the delay has elapsed; an earlier pending add for the same key wins
+func (q *WorkQueue) AddAfter(key string, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(key)
+		return
+	}
+
+	deadline := time.Now().Add(delay)
+	q.mu.Lock()
+	if pending, ok := q.waiting[key]; ok && !pending.After(deadline) {
+		q.mu.Unlock()
+		return
+	}
+	q.waiting[key] = deadline
+	q.mu.Unlock()
+
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		current := q.waiting[key].Equal(deadline)
+		if current {
+			delete(q.waiting, key)
+		}
+		q.mu.Unlock()
+
+		if current {
+			q.Add(key)
+		}
+	})
+}
+
+// AddRateLimited enqueues a key after its backoff delay and returns that delay
+func (q *WorkQueue) AddRateLimited(key string) time.Duration {
+	q.mu.Lock()
+	failures := q.failures[key]
+	q.failures[key] = failures + 1
+	q.mu.Unlock()
+
+	delay := q.baseDelay << failures
+	if delay > q.maxDelay || delay <= 0 {
+		delay = q.maxDelay
+	}
+	q.AddAfter(key, delay)
+	return delay
+}
+
+// Forget resets the backoff for a key after a successful reconcile
+func (q *WorkQueue) Forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.failures, key)
+}
+
+// Retries returns how many consecutive failures a key has had
+func (q *WorkQueue) Retries(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.failures[key]
+}
+
+// Get blocks until a key is available; ok is false after ShutDown
+func (q *WorkQueue) Get() (key string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.queue) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if q.shutdown {
+		return "", false
+	}
+
+	key = q.queue[0]
+	q.queue = q.queue[1:]
+	delete(q.queued, key)
+	return key, true
+}
+
+// Len returns the number of keys waiting to be processed
+func (q *WorkQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown wakes up all waiting workers and stops accepting keys
+func (q *WorkQueue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Result tells the controller whether and when to reconcile a key again
+type Result struct {
+	Requeue      bool
+	RequeueAfter time.Duration
+}
+
+// Reconcile actions
+const (
+	ActionNone    = "none"
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionChmod   = "chmod"
+	ActionDelete  = "delete"
+	ActionWaiting = "waiting"
+)
+
+// FileReconciler converges files in a directory towards the spec
+type FileReconciler struct {
+	dir    string
+	spec   *SpecSource
+	resync time.Duration
+
+	mu       sync.Mutex
+	injected map[string]int
+}
+
+// NewFileReconciler creates a reconciler for the managed directory
+func NewFileReconciler(dir string, spec *SpecSource, resync time.Duration) *FileReconciler {
+	return &FileReconciler{
+		dir:      dir,
+		spec:     spec,
+		resync:   resync,
+		injected: make(map[string]int),
+	}
+}
+
+// Reconcile compares desired and actual state for one file and fixes any drift
+func (r *FileReconciler) Reconcile(ctx context.Context, name string) (string, Result, error) {
+	if err := ctx.Err(); err != nil {
+		return ActionNone, Result{}, err
+	}
+
+	path := filepath.Join(r.dir, name)
+	desired, wanted := r.spec.Get(name)
+
+	info, statErr := os.Stat(path)
+	exists := statErr == nil
+	if statErr != nil && !errors.Is(statErr, os.ErrNotExist) {
+		return ActionNone, Result{}, fmt.Errorf("failed to stat %s: %w", name, statErr)
+	}
+
+	if !wanted {
+		if !exists {
+			return ActionNone, Result{}, nil
+		}
+		if err := os.Remove(path); err != nil {
+			return ActionDelete, Result{}, fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+		return ActionDelete, Result{}, nil
+	}
+
+	if err := r.injectFailure(desired); err != nil {
+		return ActionNone, Result{}, err
+	}
+
+	if desired.DependsOn != "" {
+		if _, err := os.Stat(filepath.Join(r.dir, desired.DependsOn)); err != nil {
+			// Not an error: the dependency is reconciled separately, check back shortly
+			return ActionWaiting, Result{Requeue: true}, nil
+		}
+	}
+
+	mode, err := desired.FileMode()
+	if err != nil {
+		return ActionNone, Result{}, err
+	}
+
+	action := ActionNone
+	switch {
+	case !exists:
+		action = ActionCreate
+	case !r.contentMatches(path, desired.Content):
+		action = ActionUpdate
+	case info.Mode().Perm() != mode:
+		action = ActionChmod
+	}
+
+	switch action {
+	case ActionCreate, ActionUpdate:
+		if err := writeFileAtomic(path, []byte(desired.Content), mode); err != nil {
+			return action, Result{}, err
+		}
+	case ActionChmod:
+		if err := os.Chmod(path, mode); err != nil {
+			return action, Result{}, fmt.Errorf("failed to chmod %s: %w", name, err)
+		}
+	}
+
+	// Periodic requeue catches drift made outside the controller
+	return action, Result{RequeueAfter: r.resync}, nil
+}
+
+func (r *FileReconciler) contentMatches(path, content string) bool {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(current, []byte(content))
+}
+
+// injectFailure returns a transient error for the first FailTimes reconciles of a file
+func (r *FileReconciler) injectFailure(desired FileSpec) error {
+	if desired.FailTimes == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.injected[desired.Name] >= desired.FailTimes {
+		return nil
+	}
+	r.injected[desired.Name]++
+	return fmt.Errorf("simulated transient error %d/%d", r.injected[desired.Name], desired.FailTimes)
+}
+
+// ActualNames lists the files currently present in the managed directory
+func (r *FileReconciler) ActualNames() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.dir, err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) != ".tmp" {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	// WriteFile applies umask, so set the exact mode explicitly
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to chmod %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename %s: %w", tmp, err)
+	}
+	return nil
+}
+
+// Controller drives the reconcile loop with a work queue
+type Controller struct {
+	reconciler *FileReconciler
+	queue      *WorkQueue
+	logger     core.Logger
+
+	requeueDelay time.Duration
+	maxRetries   int
+
+	loops  uint64
+	loopMu sync.Mutex
+}
+
+// NewController wires a reconciler to a backoff work queue
+func NewController(reconciler *FileReconciler, queue *WorkQueue, logger core.Logger) *Controller {
+	return &Controller{
+		reconciler:   reconciler,
+		queue:        queue,
+		logger:       logger,
+		requeueDelay: time.Second,
+		maxRetries:   8,
+	}
+}
+
+// EnqueueAll queues every key in desired or actual state
+func (c *Controller) EnqueueAll(trigger string) {
+	keys := make(map[string]struct{})
+	for _, name := range c.reconciler.spec.Names() {
+		keys[name] = struct{}{}
+	}
+
+	actual, err := c.reconciler.ActualNames()
+	if err != nil {
+		c.logger.Errorw("Failed to list actual state", "error", err.Error())
+	}
+	for _, name := range actual {
+		keys[name] = struct{}{}
+	}
+
+	for key := range keys {
+		c.queue.Add(key)
+	}
+	c.logger.Infow("Keys enqueued", "trigger", trigger, "keys", len(keys), "queue_depth", c.queue.Len())
+}
+
+// Run starts workers and blocks until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			c.worker(ctx, worker)
+		}(i)
+	}
+
+	<-ctx.Done()
+	c.queue.ShutDown()
+	wg.Wait()
+}
+
+func (c *Controller) worker(ctx context.Context, worker int) {
+	for {
+		key, ok := c.queue.Get()
+		if !ok {
+			return
+		}
+		c.processNext(ctx, worker, key)
+	}
+}
+
+func (c *Controller) processNext(ctx context.Context, worker int, key string) {
+	c.loopMu.Lock()
+	c.loops++
+	loop := c.loops
+	c.loopMu.Unlock()
+
+	reconcileLogger := c.logger.With("key", key, "reconcile_id", loop, "worker", worker)
+	start := time.Now()
+	attempt := c.queue.Retries(key) + 1
+
+	reconcileLogger.Debugw("Reconcile started", "attempt", attempt, "spec_version", c.reconciler.spec.Version())
+
+	action, result, err := c.reconciler.Reconcile(ctx, key)
+	duration := time.Since(start).Milliseconds()
+
+	if err != nil {
+		if attempt >= c.maxRetries {
+			reconcileLogger.Errorw("Reconcile failed, giving up until next resync",
+				"action", action,
+				"attempt", attempt,
+				"max_retries", c.maxRetries,
+				"error", err.Error(),
+				"duration_ms", duration,
+			)
+			c.queue.Forget(key)
+			return
+		}
+
+		backoff := c.queue.AddRateLimited(key)
+		reconcileLogger.Warnw("Reconcile failed, requeue with backoff",
+			"action", action,
+			"attempt", attempt,
+			"error", err.Error(),
+			"requeue", true,
+			"backoff", backoff.String(),
+			"duration_ms", duration,
+		)
+		return
+	}
+
+	c.queue.Forget(key)
+
+	switch {
+	case result.Requeue:
+		c.queue.AddAfter(key, c.requeueDelay)
+		reconcileLogger.Infow("Reconcile requeued",
+			"action", action,
+			"requeue", true,
+			"requeue_after", c.requeueDelay.String(),
+			"duration_ms", duration,
+		)
+	case result.RequeueAfter > 0:
+		c.queue.AddAfter(key, result.RequeueAfter)
+		logFn := reconcileLogger.Debugw
+		if action != ActionNone {
+			logFn = reconcileLogger.Infow
+		}
+		logFn("Reconcile succeeded",
+			"action", action,
+			"requeue", false,
+			"requeue_after", result.RequeueAfter.String(),
+			"duration_ms", duration,
+		)
+	default:
+		reconcileLogger.Infow("Reconcile succeeded",
+			"action", action,
+			"requeue", false,
+			"duration_ms", duration,
+		)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileSpec is the desired state of a single managed file
+type FileSpec struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	Mode    string `json:"mode,omitempty"`
+
+	// DependsOn delays creation until another managed file exists
+	DependsOn string `json:"depends_on,omitempty"`
+	// FailTimes injects transient errors to demonstrate backoff
+	FailTimes int `json:"fail_times,omitempty"`
+}
+
+// FileMode parses Mode as an octal permission, defaulting to 0644
+func (f FileSpec) FileMode() (os.FileMode, error) {
+	if f.Mode == "" {
+		return 0644, nil
+	}
+	mode, err := strconv.ParseUint(f.Mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %q for %s: %w", f.Mode, f.Name, err)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Spec is the desired state of the managed directory
+type Spec struct {
+	Files []FileSpec `json:"files"`
+}
+
+// SpecSource loads the spec file and reports when it changes on disk
+type SpecSource struct {
+	path string
+
+	mu      sync.RWMutex
+	files   map[string]FileSpec
+	modTime time.Time
+	version int
+}
+
+// NewSpecSource creates a source for the given spec path
+func NewSpecSource(path string) *SpecSource {
+	return &SpecSource{path: path, files: make(map[string]FileSpec)}
+}
+
+// Reload re-reads the spec when its modification time changed and reports whether it did
+func (s *SpecSource) Reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat spec: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return false, fmt.Errorf("failed to parse spec: %w", err)
+	}
+
+	files := make(map[string]FileSpec, len(spec.Files))
+	for _, file := range spec.Files {
+		if file.Name == "" {
+			return false, fmt.Errorf("spec entry without name")
+		}
+		if _, err := file.FileMode(); err != nil {
+			return false, err
+		}
+		files[file.Name] = file
+	}
+
+	s.mu.Lock()
+	s.files = files
+	s.modTime = info.ModTime()
+	s.version++
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Get returns the desired state for a file
+func (s *SpecSource) Get(name string) (FileSpec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[name]
+	return file, ok
+}
+
+// Names returns all file names in the spec
+func (s *SpecSource) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Version increments every time a changed spec is loaded
+func (s *SpecSource) Version() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
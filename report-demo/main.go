@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Report Generation Demo ===")
+	console.Println("Generates CSV/PDF reports in stages with per-step timings and one summary entry per report")
+	console.Println()
+
+	versionInfo := version.Get()
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer baseLogger.Flush()
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	outputDir := getEnvOrDefault("REPORT_DIR", "reports")
+	generator := NewGenerator(outputDir, getFloatEnv("NOTIFY_FAIL_RATE", 0.2), clock.Real, baseLogger.With("component", "report"))
+
+	end := clock.Real.Now().UTC().Truncate(24 * time.Hour)
+	days := getIntEnv("PERIOD_DAYS", 7)
+	var recipients []string
+	if env := getEnvOrDefault("RECIPIENTS", "finance@example.com,ops@example.com"); env != "-" {
+		recipients = strings.Split(env, ",")
+	}
+
+	formats := strings.Split(getEnvOrDefault("REPORT_FORMATS", "csv,pdf"), ",")
+	sanitize.Startup(baseLogger, logOption, "output_dir", outputDir, "recipients", recipients)
+	baseLogger.Infow("Starting report generation",
+		"formats", formats,
+		"period_days", days,
+		"output_dir", outputDir,
+	)
+
+	var generated, failed []string
+	for _, format := range formats {
+		id, err := generator.Generate(ctx, ReportRequest{
+			Name:        "Weekly sales",
+			Format:      strings.TrimSpace(format),
+			PeriodStart: end.AddDate(0, 0, -days+1),
+			PeriodEnd:   end,
+			Recipients:  recipients,
+		})
+		if err != nil {
+			failed = append(failed, id)
+			continue
+		}
+		generated = append(generated, id)
+	}
+
+	console.Printf("\nGenerated: %v\nFailed: %v\nFiles in %s/\n", generated, failed, outputDir)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 && f <= 1 {
+			return f
+		}
+	}
+	return defaultValue
+}
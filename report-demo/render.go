@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func renderCSV(rows []SalesRow) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"region", "product", "units", "revenue"}); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := []string{row.Region, row.Product, strconv.Itoa(row.Units), strconv.FormatFloat(row.Revenue, 'f', 2, 64)}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// renderPDF writes a single-page PDF with one line of text per row. It uses only the
+// built-in Courier font so the demo needs no PDF library.
+func renderPDF(title, reportID string, rows []SalesRow) ([]byte, error) {
+	lines := []string{title, "report_id: " + reportID, ""}
+	lines = append(lines, fmt.Sprintf("%-8s %-8s %8s %12s", "region", "product", "units", "revenue"))
+	var units int
+	var revenue float64
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf("%-8s %-8s %8d %12.2f", row.Region, row.Product, row.Units, row.Revenue))
+		units += row.Units
+		revenue += row.Revenue
+	}
+	lines = append(lines, "", fmt.Sprintf("%-17s %8d %12.2f", "total", units, revenue))
+	if len(lines) > 60 {
+		return nil, fmt.Errorf("%d lines do not fit on one page", len(lines))
+	}
+
+	var content bytes.Buffer
+	content.WriteString("BT /F1 10 Tf 12 TL 50 800 Td\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes(), nil
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
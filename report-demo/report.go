@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/clock"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+)
+
+// Report statuses
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ReportRequest describes one report to generate
+type ReportRequest struct {
+	Name        string
+	Format      string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Recipients  []string
+}
+
+// SalesRow is one line of the report
+type SalesRow struct {
+	Region  string
+	Product string
+	Units   int
+	Revenue float64
+}
+
+// StepResult is the timing of one completed or failed step, kept for the summary
+type StepResult struct {
+	Name       string
+	DurationMs int64
+	Err        error
+}
+
+// Generator runs reports through query, render, store and notify
+type Generator struct {
+	outputDir  string
+	notifyFail float64
+	clock      clock.Clock
+	logger     core.Logger
+}
+
+// NewGenerator creates a generator writing reports into outputDir. Step timings and
+// simulated latency are measured on clk.
+func NewGenerator(outputDir string, notifyFail float64, clk clock.Clock, logger core.Logger) *Generator {
+	return &Generator{outputDir: outputDir, notifyFail: notifyFail, clock: clk, logger: logger}
+}
+
+// reportRun carries a report's state from one step to the next
+type reportRun struct {
+	id      string
+	req     ReportRequest
+	logger  core.Logger
+	rows    []SalesRow
+	content []byte
+	path    string
+	steps   []StepResult
+}
+
+// Generate runs every step in order, stopping at the first failure, and always emits
+// exactly one summary entry carrying the report_id
+func (g *Generator) Generate(ctx context.Context, req ReportRequest) (string, error) {
+	run := &reportRun{id: requestid.New(requestid.Report), req: req}
+	run.logger = g.logger.With("report_id", run.id, "report", req.Name, "format", req.Format)
+	run.logger.Infow("Report started",
+		"period_start", req.PeriodStart.Format("2006-01-02"),
+		"period_end", req.PeriodEnd.Format("2006-01-02"),
+	)
+
+	start := g.clock.Now()
+	steps := []struct {
+		name string
+		fn   func(context.Context, *reportRun) ([]interface{}, error)
+	}{
+		{"query", g.query},
+		{"render", g.render},
+		{"store", g.store},
+		{"notify", g.notify},
+	}
+	var err error
+	for _, step := range steps {
+		if err = g.runStep(ctx, run, step.name, step.fn); err != nil {
+			break
+		}
+	}
+
+	g.summarize(run, g.clock.Since(start), err)
+	return run.id, err
+}
+
+// runStep times one step and logs its outcome with whatever fields the step reports
+func (g *Generator) runStep(ctx context.Context, run *reportRun, name string, fn func(context.Context, *reportRun) ([]interface{}, error)) error {
+	log := run.logger.With("step", name)
+	log.Debugw("Report step started")
+
+	start := g.clock.Now()
+	fields, err := fn(ctx, run)
+	if err == nil {
+		err = ctx.Err()
+	}
+	result := StepResult{Name: name, DurationMs: g.clock.Since(start).Milliseconds(), Err: err}
+	run.steps = append(run.steps, result)
+
+	fields = append(fields, "duration_ms", result.DurationMs)
+	if err != nil {
+		log.Errorw("Report step failed", append(fields, "error", err.Error())...)
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	log.Infow("Report step completed", fields...)
+	return nil
+}
+
+// summarize writes the single entry that ties the whole report together
+func (g *Generator) summarize(run *reportRun, total time.Duration, err error) {
+	durations := make(map[string]int64, len(run.steps))
+	for _, step := range run.steps {
+		durations[step.Name] = step.DurationMs
+	}
+	fields := []interface{}{
+		"total_ms", total.Milliseconds(),
+		"step_durations_ms", durations,
+		"rows", len(run.rows),
+		"bytes", len(run.content),
+	}
+
+	if err != nil {
+		failed := run.steps[len(run.steps)-1]
+		fields = append(fields, "status", StatusFailed, "failed_step", failed.Name, "error", failed.Err.Error())
+		if run.path != "" {
+			fields = append(fields, "path", run.path)
+		}
+		run.logger.Errorw("Report summary", fields...)
+		return
+	}
+	fields = append(fields, "status", StatusCompleted, "path", run.path, "recipients", len(run.req.Recipients))
+	run.logger.Infow("Report summary", fields...)
+}
+
+var (
+	regions  = []string{"north", "south", "east", "west"}
+	products = []string{"widget", "gadget", "gizmo"}
+)
+
+// query stands in for the database: latency grows with the number of days covered
+func (g *Generator) query(ctx context.Context, run *reportRun) ([]interface{}, error) {
+	days := int(run.req.PeriodEnd.Sub(run.req.PeriodStart).Hours()/24) + 1
+	if days <= 0 {
+		return nil, fmt.Errorf("empty period %s..%s", run.req.PeriodStart.Format("2006-01-02"), run.req.PeriodEnd.Format("2006-01-02"))
+	}
+	if err := g.sleep(ctx, time.Duration(20*days+rng.Intn(100))*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	for _, region := range regions {
+		for _, product := range products {
+			units := days * (5 + rng.Intn(20))
+			run.rows = append(run.rows, SalesRow{
+				Region:  region,
+				Product: product,
+				Units:   units,
+				Revenue: math.Round(float64(units)*(9.5+rng.Float64()*10)*100) / 100,
+			})
+		}
+	}
+	return []interface{}{"rows", len(run.rows), "days", days}, nil
+}
+
+func (g *Generator) render(ctx context.Context, run *reportRun) ([]interface{}, error) {
+	var err error
+	switch run.req.Format {
+	case "csv":
+		run.content, err = renderCSV(run.rows)
+	case "pdf":
+		title := fmt.Sprintf("%s (%s to %s)", run.req.Name,
+			run.req.PeriodStart.Format("2006-01-02"), run.req.PeriodEnd.Format("2006-01-02"))
+		run.content, err = renderPDF(title, run.id, run.rows)
+	default:
+		err = fmt.Errorf("unsupported format %q", run.req.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{"bytes", len(run.content)}, nil
+}
+
+func (g *Generator) store(ctx context.Context, run *reportRun) ([]interface{}, error) {
+	if err := os.MkdirAll(g.outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	path := filepath.Join(g.outputDir, run.id+"."+run.req.Format)
+	if err := os.WriteFile(path, run.content, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write report: %w", err)
+	}
+	run.path = path
+
+	sum := sha256.Sum256(run.content)
+	return []interface{}{"path", path, "sha256", hex.EncodeToString(sum[:])}, nil
+}
+
+// errNotifyUnavailable simulates the mail relay rejecting the notification
+var errNotifyUnavailable = errors.New("notification service unavailable")
+
+func (g *Generator) notify(ctx context.Context, run *reportRun) ([]interface{}, error) {
+	if len(run.req.Recipients) == 0 {
+		return []interface{}{"recipients", 0, "skipped", true}, nil
+	}
+	if err := g.sleep(ctx, time.Duration(30+rng.Intn(70))*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if rng.Float64() < g.notifyFail {
+		return []interface{}{"recipients", len(run.req.Recipients)}, errNotifyUnavailable
+	}
+	return []interface{}{"recipients", len(run.req.Recipients), "channel", "email"}, nil
+}
+
+func (g *Generator) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-g.clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
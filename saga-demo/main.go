@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// OrderState is the data shared between saga steps
+type OrderState struct {
+	OrderID        string   `json:"order_id"`
+	Items          []string `json:"items"`
+	Amount         int64    `json:"amount"`
+	ReservationID  string   `json:"reservation_id,omitempty"`
+	PaymentID      string   `json:"payment_id,omitempty"`
+	TrackingNumber string   `json:"tracking_number,omitempty"`
+
+	// Failure injection for the demo
+	FailAt               string `json:"fail_at,omitempty"`
+	FlakyCompensation    string `json:"flaky_compensation,omitempty"`
+	compensationFailures int
+}
+
+// orderRequest is the body accepted by POST /orders
+type orderRequest struct {
+	OrderID           string   `json:"order_id" binding:"required"`
+	Items             []string `json:"items" binding:"required"`
+	Amount            int64    `json:"amount" binding:"required"`
+	FailAt            string   `json:"fail_at"`
+	FlakyCompensation string   `json:"flaky_compensation"`
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Saga / Compensation Demo ===")
+	console.Println("Order flow: reserve -> charge -> ship, with compensations on failure")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "slog",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "order-saga",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	orchestrator := NewOrchestrator(
+		Step{Name: "reserve_inventory", Action: reserveInventory, Compensate: releaseInventory},
+		Step{Name: "charge_payment", Action: chargePayment, Compensate: refundPayment},
+		Step{Name: "ship_order", Action: shipOrder, Compensate: cancelShipment},
+	)
+
+	var (
+		mu      sync.RWMutex
+		results = make(map[string]SagaResult)
+	)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery(), logcontext.GinRequestLogger(serviceLogger))
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.POST("/orders", func(c *gin.Context) {
+		var req orderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		state := &OrderState{
+			OrderID:           req.OrderID,
+			Items:             req.Items,
+			Amount:            req.Amount,
+			FailAt:            req.FailAt,
+			FlakyCompensation: req.FlakyCompensation,
+		}
+
+		// The saga's entries carry the request's fields as well as saga_id
+		result := orchestrator.Execute(c.Request.Context(), logcontext.MustFromGin(c), requestid.New(requestid.Saga), state)
+
+		mu.Lock()
+		results[result.SagaID] = result
+		mu.Unlock()
+
+		status := http.StatusOK
+		if result.Status != StatusCompleted {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"saga": result, "order": state})
+	})
+
+	r.GET("/sagas/:id", func(c *gin.Context) {
+		mu.RLock()
+		result, ok := results[c.Param("id")]
+		mu.RUnlock()
+
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saga not found"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+
+	port := getEnvOrDefault("PORT", "8092")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port)
+	serviceLogger.Infow("Starting saga orchestrator",
+		"port", port,
+		"steps", orchestrator.stepNames(),
+		"endpoints", []string{"/orders", "/sagas/:id", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these scenarios:")
+	console.Printf("  curl -X POST http://localhost:%s/orders -d '{\"order_id\":\"o-1\",\"items\":[\"sku-1\"],\"amount\":4200}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/orders -d '{\"order_id\":\"o-2\",\"items\":[\"sku-1\"],\"amount\":4200,\"fail_at\":\"ship_order\"}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/orders -d '{\"order_id\":\"o-3\",\"items\":[\"sku-1\"],\"amount\":4200,\"fail_at\":\"ship_order\",\"flaky_compensation\":\"charge_payment\"}'\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// Simulated service calls. Each one can be forced to fail via OrderState.FailAt.
+
+func reserveInventory(ctx context.Context, state *OrderState) error {
+	if err := simulateCall(ctx, state, "reserve_inventory", "inventory", "insufficient stock"); err != nil {
+		return err
+	}
+	state.ReservationID = "res-" + randomHex(4)
+	return nil
+}
+
+func releaseInventory(ctx context.Context, state *OrderState) error {
+	if err := simulateCompensation(ctx, state, "reserve_inventory", "inventory"); err != nil {
+		return err
+	}
+	state.ReservationID = ""
+	return nil
+}
+
+func chargePayment(ctx context.Context, state *OrderState) error {
+	if err := simulateCall(ctx, state, "charge_payment", "payments", "card declined"); err != nil {
+		return err
+	}
+	state.PaymentID = "pay-" + randomHex(4)
+	return nil
+}
+
+func refundPayment(ctx context.Context, state *OrderState) error {
+	if err := simulateCompensation(ctx, state, "charge_payment", "payments"); err != nil {
+		return err
+	}
+	state.PaymentID = ""
+	return nil
+}
+
+func shipOrder(ctx context.Context, state *OrderState) error {
+	if err := simulateCall(ctx, state, "ship_order", "shipping", "carrier unavailable"); err != nil {
+		return err
+	}
+	state.TrackingNumber = "trk-" + randomHex(6)
+	return nil
+}
+
+func cancelShipment(ctx context.Context, state *OrderState) error {
+	if err := simulateCompensation(ctx, state, "ship_order", "shipping"); err != nil {
+		return err
+	}
+	state.TrackingNumber = ""
+	return nil
+}
+
+func simulateCall(ctx context.Context, state *OrderState, step, service, reason string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if state.FailAt == step {
+		return &StepError{Service: service, Reason: reason}
+	}
+	return nil
+}
+
+// simulateCompensation fails the first compensation attempt when flaky_compensation targets the step
+func simulateCompensation(ctx context.Context, state *OrderState, step, service string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if state.FlakyCompensation == step && state.compensationFailures < 1 {
+		state.compensationFailures++
+		return &StepError{Service: service, Reason: "timeout while compensating"}
+	}
+	return nil
+}
+
+// randomHex makes the simulated reservation, payment and tracking IDs, repeatable with --seed
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rng.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Step is a single saga step with its compensating action
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context, state *OrderState) error
+	Compensate func(ctx context.Context, state *OrderState) error
+}
+
+// SagaResult summarizes the outcome of a saga execution
+type SagaResult struct {
+	SagaID      string   `json:"saga_id"`
+	Status      string   `json:"status"`
+	Completed   []string `json:"completed_steps"`
+	Compensated []string `json:"compensated_steps"`
+	FailedStep  string   `json:"failed_step,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	DurationMs  int64    `json:"duration_ms"`
+}
+
+// Saga statuses
+const (
+	StatusCompleted          = "completed"
+	StatusCompensated        = "compensated"
+	StatusCompensationFailed = "compensation_failed"
+)
+
+// Orchestrator runs saga steps in order and compensates completed steps on failure
+type Orchestrator struct {
+	steps                []Step
+	compensationAttempts int
+	retryDelay           time.Duration
+	// compensationTimeout bounds the whole rollback, which no longer follows the caller's context
+	compensationTimeout time.Duration
+}
+
+// NewOrchestrator creates an orchestrator for the given steps
+func NewOrchestrator(steps ...Step) *Orchestrator {
+	return &Orchestrator{
+		steps:                steps,
+		compensationAttempts: 3,
+		retryDelay:           100 * time.Millisecond,
+		compensationTimeout:  10 * time.Second,
+	}
+}
+
+// Execute runs the saga, logging through a child of log; every log entry carries the shared saga_id
+func (o *Orchestrator) Execute(ctx context.Context, log core.Logger, sagaID string, state *OrderState) SagaResult {
+	start := time.Now()
+	sagaLogger := log.With("saga_id", sagaID, "order_id", state.OrderID)
+	result := SagaResult{SagaID: sagaID}
+
+	sagaLogger.Infow("Saga started",
+		"steps", o.stepNames(),
+		"amount", state.Amount,
+		"items", len(state.Items),
+	)
+
+	var completed []Step
+	for i, step := range o.steps {
+		stepLogger := sagaLogger.With("step", step.Name, "step_index", i+1)
+		stepStart := time.Now()
+
+		stepLogger.Infow("Saga step started")
+		if err := step.Action(ctx, state); err != nil {
+			stepLogger.Errorw("Saga step failed",
+				"error", err.Error(),
+				"duration_ms", time.Since(stepStart).Milliseconds(),
+			)
+			result.FailedStep = step.Name
+			result.Error = err.Error()
+			result.Status = o.compensate(ctx, sagaLogger, completed, state, &result)
+			result.DurationMs = time.Since(start).Milliseconds()
+
+			sagaLogger.Warnw("Saga aborted",
+				"status", result.Status,
+				"failed_step", step.Name,
+				"compensated_steps", result.Compensated,
+				"duration_ms", result.DurationMs,
+			)
+			return result
+		}
+
+		stepLogger.Infow("Saga step completed", "duration_ms", time.Since(stepStart).Milliseconds())
+		completed = append(completed, step)
+		result.Completed = append(result.Completed, step.Name)
+	}
+
+	result.Status = StatusCompleted
+	result.DurationMs = time.Since(start).Milliseconds()
+	sagaLogger.Infow("Saga completed",
+		"status", result.Status,
+		"tracking_number", state.TrackingNumber,
+		"duration_ms", result.DurationMs,
+	)
+	return result
+}
+
+// compensate undoes completed steps in reverse order, retrying each compensation
+func (o *Orchestrator) compensate(ctx context.Context, sagaLogger core.Logger, completed []Step, state *OrderState, result *SagaResult) string {
+	if len(completed) == 0 {
+		sagaLogger.Infow("No completed steps to compensate")
+		return StatusCompensated
+	}
+
+	sagaLogger.Infow("Compensation started", "steps_to_compensate", len(completed))
+
+	// A client that disconnects cancels the request context; the rollback must still finish,
+	// or the order is left half done. Values such as the trace context are kept.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.compensationTimeout)
+	defer cancel()
+
+	status := StatusCompensated
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := completed[i]
+		compLogger := sagaLogger.With("step", step.Name, "phase", "compensation")
+
+		var err error
+		for attempt := 1; attempt <= o.compensationAttempts; attempt++ {
+			err = step.Compensate(ctx, state)
+			if err == nil {
+				compLogger.Infow("Compensation succeeded", "attempt", attempt)
+				result.Compensated = append(result.Compensated, step.Name)
+				break
+			}
+
+			compLogger.Warnw("Compensation attempt failed",
+				"attempt", attempt,
+				"max_attempts", o.compensationAttempts,
+				"error", err.Error(),
+			)
+			time.Sleep(o.retryDelay * time.Duration(attempt))
+		}
+
+		if err != nil {
+			// Keep compensating the remaining steps; this one needs manual intervention
+			compLogger.Errorw("Compensation exhausted retries, manual intervention required",
+				"error", err.Error(),
+			)
+			status = StatusCompensationFailed
+		}
+	}
+
+	return status
+}
+
+func (o *Orchestrator) stepNames() []string {
+	names := make([]string, len(o.steps))
+	for i, step := range o.steps {
+		names[i] = step.Name
+	}
+	return names
+}
+
+// StepError is returned by simulated services when a failure is injected
+type StepError struct {
+	Service string
+	Reason  string
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Service, e.Reason)
+}
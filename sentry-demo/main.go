@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// ErrOrderNotFound is returned for unknown order IDs
+var ErrOrderNotFound = errors.New("order not found")
+
+// PaymentError carries the gateway decline code
+type PaymentError struct {
+	Code string
+}
+
+func (e *PaymentError) Error() string {
+	return "payment declined: " + e.Code
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Sentry Integration Demo ===")
+	console.Println("Reports Error/Fatal log entries to Sentry with fields, stack traces and a message fingerprint")
+	console.Println()
+
+	versionInfo := version.Get()
+	environment := getEnvOrDefault("DEPLOY_ENV", "development")
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     environment,
+		},
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	baseLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	baseLogger = summary.Wrap(baseLogger, logOption)
+	defer resusage.FromEnv(baseLogger)()
+	defer leakwatch.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		mock, err := StartMockSentry("127.0.0.1:0", baseLogger.With("component", "mock-sentry"))
+		if err != nil {
+			crash.Fatal(baseLogger, "Failed to start mock Sentry", "error", err.Error())
+		}
+		defer mock.Close()
+		dsn = mock.DSN()
+		baseLogger.Infow("Started in-process mock Sentry", "dsn", dsn)
+	}
+
+	// Sentry's release convention is name@version, so issues link to the exact build
+	release := fmt.Sprintf("%s@%s", versionInfo.ServiceName, versionInfo.GitVersion)
+	sampleRate := getFloatEnv("SENTRY_SAMPLE_RATE", 1.0)
+
+	serviceLogger, err := NewSentryLogger(baseLogger, SentryConfig{
+		DSN:          dsn,
+		Release:      release,
+		Environment:  environment,
+		SampleRate:   sampleRate,
+		FlushTimeout: 2 * time.Second,
+	})
+	if err != nil {
+		crash.Fatal(baseLogger, "Failed to initialize Sentry", "error", err.Error())
+	}
+	defer serviceLogger.Flush()
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	// The request logger's fields end up both in the log line and in the Sentry event
+	r.Use(logcontext.GinRequestLogger(serviceLogger))
+
+	r.GET("/orders/:id", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
+		orderID := c.Param("id")
+
+		if strings.HasPrefix(orderID, "x") {
+			// Every unknown order groups into one Sentry issue because the message does not contain the ID
+			log.Errorw("Order lookup failed", "order_id", orderID, "error", ErrOrderNotFound)
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrOrderNotFound.Error()})
+			return
+		}
+		log.Infow("Order fetched", "order_id", orderID)
+		c.JSON(http.StatusOK, gin.H{"order_id": orderID, "status": "paid"})
+	})
+
+	r.POST("/payments", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
+		amount := rng.Intn(50000)
+
+		if amount > 30000 {
+			codes := []string{"insufficient_funds", "card_expired", "do_not_honor"}
+			err := &PaymentError{Code: codes[rng.Intn(len(codes))]}
+			log.Errorw("Payment declined", "amount_cents", amount, "gateway", "stripe", "error", err)
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
+		}
+		if amount > 20000 {
+			// Warn entries stay in the logs only
+			log.Warnw("Payment required manual review", "amount_cents", amount)
+		}
+		log.Infow("Payment captured", "amount_cents", amount)
+		c.JSON(http.StatusOK, gin.H{"amount_cents": amount, "status": "captured"})
+	})
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	port := getEnvOrDefault("PORT", "8097")
+	outputs.Log(baseLogger)
+	sanitize.Startup(baseLogger, logOption, "port", port, "sentry_dsn", dsn, "sample_rate", sampleRate)
+	serviceLogger.Infow("Starting Sentry demo server",
+		"port", port,
+		"release", release,
+		"sample_rate", sampleRate,
+		"reported_levels", []string{"error", "fatal"},
+		"endpoints", []string{"/orders/:id", "/payments", "/health", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these:")
+	console.Printf("  curl http://localhost:%s/orders/o-100\n", port)
+	console.Printf("  curl http://localhost:%s/orders/x-404\n", port)
+	console.Printf("  for i in $(seq 10); do curl -s -X POST http://localhost:%s/payments; echo; done\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			// The error entry is reported to Sentry; the logger flush hook sends it before the process exits
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err, "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 && f <= 1 {
+			return f
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockSentry accepts envelopes on /api/<project>/envelope/ and logs a summary of each event
+type MockSentry struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	events atomic.Uint64
+}
+
+// sentryEvent is the subset of the event payload the mock prints
+type sentryEvent struct {
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Release     string                 `json:"release"`
+	Environment string                 `json:"environment"`
+	Fingerprint []string               `json:"fingerprint"`
+	Extra       map[string]interface{} `json:"extra"`
+	Exception   []struct {
+		Type       string `json:"type"`
+		Value      string `json:"value"`
+		Stacktrace struct {
+			Frames []struct {
+				Function string `json:"function"`
+				Lineno   int    `json:"lineno"`
+			} `json:"frames"`
+		} `json:"stacktrace"`
+	} `json:"exception"`
+}
+
+// StartMockSentry listens on addr
+func StartMockSentry(addr string, logger core.Logger) (*MockSentry, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockSentry{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/1/envelope/", m.handleEnvelope)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// DSN points the SDK at the mock, project 1
+func (m *MockSentry) DSN() string {
+	return "http://public@" + m.listener.Addr().String() + "/1"
+}
+
+// Events returns how many events were received
+func (m *MockSentry) Events() uint64 {
+	return m.events.Load()
+}
+
+// Close stops the server
+func (m *MockSentry) Close() error {
+	return m.server.Close()
+}
+
+// handleEnvelope reads the newline-delimited envelope: header, then item header/payload pairs
+func (m *MockSentry) handleEnvelope(w http.ResponseWriter, r *http.Request) {
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+
+	scanner.Scan() // envelope header
+	for scanner.Scan() {
+		var item struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil || !scanner.Scan() {
+			break
+		}
+		if item.Type != "event" {
+			continue
+		}
+
+		var event sentryEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			m.logger.Warnw("Mock Sentry received malformed event", "error", err.Error())
+			continue
+		}
+		m.events.Add(1)
+		m.log(event)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockSentry) log(event sentryEvent) {
+	fields := []interface{}{
+		"event_message", event.Message,
+		"sentry_level", event.Level,
+		"sentry_release", event.Release,
+		"sentry_environment", event.Environment,
+		"fingerprint", event.Fingerprint,
+		"extra", event.Extra,
+	}
+	if len(event.Exception) > 0 {
+		exception := event.Exception[0]
+		frames := exception.Stacktrace.Frames
+		fields = append(fields, "exception_type", exception.Type, "exception_value", exception.Value, "frames", len(frames))
+		if len(frames) > 0 {
+			top := frames[len(frames)-1]
+			fields = append(fields, "top_frame", fmt.Sprintf("%s:%d", top.Function, top.Lineno))
+		}
+	}
+	m.logger.Infow("Mock Sentry received event", fields...)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/kart-io/logger/core"
+)
+
+// SentryConfig controls which entries are reported and how
+type SentryConfig struct {
+	DSN          string
+	Release      string
+	Environment  string
+	SampleRate   float64
+	FlushTimeout time.Duration
+}
+
+// SentryLogger wraps a core.Logger and additionally reports Error/Fatal entries to Sentry.
+// Everything else passes straight through to the wrapped logger.
+type SentryLogger struct {
+	core.Logger
+	// wrapped sits one caller frame further out, for the methods this type overrides
+	wrapped      core.Logger
+	hub          *sentry.Hub
+	fields       []interface{}
+	flushTimeout time.Duration
+}
+
+// NewSentryLogger creates a dedicated Sentry client so the global hub stays untouched
+func NewSentryLogger(base core.Logger, cfg SentryConfig) (*SentryLogger, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Release:          cfg.Release,
+		Environment:      cfg.Environment,
+		SampleRate:       cfg.SampleRate,
+		AttachStacktrace: false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %w", err)
+	}
+
+	return &SentryLogger{
+		Logger:       base,
+		wrapped:      base.WithCallerSkip(1),
+		hub:          sentry.NewHub(client, sentry.NewScope()),
+		flushTimeout: cfg.FlushTimeout,
+	}, nil
+}
+
+func (l *SentryLogger) Error(args ...interface{}) {
+	l.wrapped.Error(args...)
+	msg := fmt.Sprint(args...)
+	l.report(sentry.LevelError, msg, msg, nil)
+}
+
+func (l *SentryLogger) Errorf(template string, args ...interface{}) {
+	l.wrapped.Errorf(template, args...)
+	// The template, not the formatted text, groups "user 1 not found" and "user 2 not found" together
+	l.report(sentry.LevelError, fmt.Sprintf(template, args...), template, nil)
+}
+
+func (l *SentryLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.wrapped.Errorw(msg, keysAndValues...)
+	l.report(sentry.LevelError, msg, msg, keysAndValues)
+}
+
+// Fatal variants report and flush first, because the wrapped logger exits the process
+func (l *SentryLogger) Fatal(args ...interface{}) {
+	msg := fmt.Sprint(args...)
+	l.report(sentry.LevelFatal, msg, msg, nil)
+	l.hub.Flush(l.flushTimeout)
+	l.wrapped.Fatal(args...)
+}
+
+func (l *SentryLogger) Fatalf(template string, args ...interface{}) {
+	l.report(sentry.LevelFatal, fmt.Sprintf(template, args...), template, nil)
+	l.hub.Flush(l.flushTimeout)
+	l.wrapped.Fatalf(template, args...)
+}
+
+func (l *SentryLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.report(sentry.LevelFatal, msg, msg, keysAndValues)
+	l.hub.Flush(l.flushTimeout)
+	l.wrapped.Fatalw(msg, keysAndValues...)
+}
+
+func (l *SentryLogger) With(keyValues ...interface{}) core.Logger {
+	return l.derive(l.Logger.With(keyValues...), keyValues)
+}
+
+func (l *SentryLogger) WithCtx(ctx context.Context, keyValues ...interface{}) core.Logger {
+	return l.derive(l.Logger.WithCtx(ctx, keyValues...), keyValues)
+}
+
+func (l *SentryLogger) WithCallerSkip(skip int) core.Logger {
+	return l.derive(l.Logger.WithCallerSkip(skip), nil)
+}
+
+// Flush drains both the wrapped logger and pending Sentry events
+func (l *SentryLogger) Flush() error {
+	l.hub.Flush(l.flushTimeout)
+	return l.Logger.Flush()
+}
+
+func (l *SentryLogger) derive(inner core.Logger, keyValues []interface{}) *SentryLogger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyValues))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyValues...)
+	return &SentryLogger{
+		Logger:       inner,
+		wrapped:      inner.WithCallerSkip(1),
+		hub:          l.hub,
+		fields:       fields,
+		flushTimeout: l.flushTimeout,
+	}
+}
+
+// report builds one Sentry event: fields become extra data, error values become the exception,
+// and the fingerprint is the message so varying field values do not split the issue
+func (l *SentryLogger) report(level sentry.Level, message, fingerprint string, keysAndValues []interface{}) {
+	event := sentry.NewEvent()
+	event.Level = level
+	event.Message = message
+	event.Logger = "kart-io/logger"
+	event.Fingerprint = []string{fingerprint}
+
+	var cause error
+	addFields := func(kv []interface{}) {
+		for i := 0; i+1 < len(kv); i += 2 {
+			key := fmt.Sprint(kv[i])
+			value := kv[i+1]
+			if err, ok := value.(error); ok {
+				cause = err
+				value = err.Error()
+			}
+			event.Extra[key] = value
+		}
+	}
+	addFields(l.fields)
+	addFields(keysAndValues)
+
+	exception := sentry.Exception{Type: message, Value: message, Stacktrace: callerStacktrace()}
+	if cause != nil {
+		exception.Type = reflect.TypeOf(cause).String()
+		exception.Value = cause.Error()
+	}
+	event.Exception = []sentry.Exception{exception}
+
+	l.hub.CaptureEvent(event)
+}
+
+// callerStacktrace drops the wrapper's own frames so the innermost frame is the log call site
+func callerStacktrace() *sentry.Stacktrace {
+	stacktrace := sentry.NewStacktrace()
+	if stacktrace == nil {
+		return nil
+	}
+	frames := stacktrace.Frames
+	for len(frames) > 0 && isWrapperFrame(frames[len(frames)-1]) {
+		frames = frames[:len(frames)-1]
+	}
+	stacktrace.Frames = frames
+	return stacktrace
+}
+
+func isWrapperFrame(frame sentry.Frame) bool {
+	return strings.HasPrefix(frame.Function, "(*SentryLogger).") || frame.Function == "callerStacktrace"
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// minPartSize is the smallest part size S3 accepts (except for the last part)
+const minPartSize = 5 * 1024 * 1024
+
+func main() {
+	cliflags.Parse()
+	versionInfo := version.Get()
+
+	console.Println("=== S3 / MinIO Storage Demo ===")
+	console.Println("Upload, download and list against an S3-compatible endpoint")
+	console.Println()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "object-storage",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	endpoint := getEnvOrDefault("S3_ENDPOINT", "localhost:9000")
+	bucket := getEnvOrDefault("S3_BUCKET", "go-example-demo")
+	useSSL := getEnvOrDefault("S3_USE_SSL", "false") == "true"
+
+	sanitize.Startup(serviceLogger, logOption, "s3_endpoint", endpoint, "bucket", bucket, "use_ssl", useSSL, "region", os.Getenv("S3_REGION"))
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(getEnvOrDefault("S3_ACCESS_KEY", "minioadmin"), getEnvOrDefault("S3_SECRET_KEY", "minioadmin"), ""),
+		Secure: useSSL,
+		Region: os.Getenv("S3_REGION"),
+	})
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to create S3 client", "endpoint", endpoint, "error", err.Error())
+	}
+
+	storage := &Storage{
+		client: client,
+		core:   &minio.Core{Client: client},
+		bucket: bucket,
+		logger: serviceLogger.With("bucket", bucket, "endpoint", endpoint),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Demo 1: Make sure the bucket exists
+	console.Println("=== Demo 1: Ensure Bucket ===")
+	if err := storage.EnsureBucket(ctx); err != nil {
+		crash.Fatal(serviceLogger, "Bucket setup failed (is MinIO running?)", "endpoint", endpoint, "error", err.Error())
+	}
+
+	// Demo 2: Single-request upload of a small object
+	console.Println("\n=== Demo 2: Simple Upload ===")
+	small := []byte(`{"report":"daily","generated_at":"` + time.Now().UTC().Format(time.RFC3339) + `"}`)
+	if err := storage.Upload(ctx, "reports/daily.json", small, "application/json"); err != nil {
+		serviceLogger.Errorw("Simple upload failed", "error", err.Error())
+	}
+
+	// Demo 3: Multipart upload with per-part progress
+	console.Println("\n=== Demo 3: Multipart Upload ===")
+	sizeMB, _ := strconv.Atoi(getEnvOrDefault("MULTIPART_SIZE_MB", "17"))
+	large := make([]byte, sizeMB*1024*1024)
+	rand.Read(large)
+	largeKey := "backups/archive-" + time.Now().Format("20060102-150405") + ".bin"
+	if err := storage.MultipartUpload(ctx, largeKey, large, minPartSize); err != nil {
+		serviceLogger.Errorw("Multipart upload failed", "error", err.Error())
+	}
+
+	// Demo 4: List objects
+	console.Println("\n=== Demo 4: List Objects ===")
+	storage.List(ctx, "")
+
+	// Demo 5: Download and verify
+	console.Println("\n=== Demo 5: Download & Verify ===")
+	storage.DownloadAndVerify(ctx, largeKey, checksum(large))
+
+	console.Println("\n✅ Storage demo completed")
+}
+
+// Storage wraps the S3 client with operation logging
+type Storage struct {
+	client *minio.Client
+	core   *minio.Core
+	bucket string
+	logger core.Logger
+}
+
+// EnsureBucket creates the bucket if it does not exist
+func (s *Storage) EnsureBucket(ctx context.Context) error {
+	start := time.Now()
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		s.logger.Infow("Bucket exists", "duration_ms", time.Since(start).Milliseconds())
+		return nil
+	}
+
+	if err := s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{}); err != nil {
+		return err
+	}
+	s.logger.Infow("Bucket created", "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+// Upload stores a small object in a single request
+func (s *Storage) Upload(ctx context.Context, key string, data []byte, contentType string) error {
+	start := time.Now()
+	info, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		s.logger.Errorw("Object upload failed",
+			"operation", "put_object",
+			"object_key", key,
+			"size_bytes", len(data),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"error", err.Error(),
+		)
+		return err
+	}
+
+	s.logger.Infow("Object uploaded",
+		"operation", "put_object",
+		"object_key", key,
+		"size_bytes", info.Size,
+		"etag", info.ETag,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+// MultipartUpload uploads data in parts, logging progress after each part
+func (s *Storage) MultipartUpload(ctx context.Context, key string, data []byte, partSize int) error {
+	start := time.Now()
+	totalParts := (len(data) + partSize - 1) / partSize
+	uploadLogger := s.logger.With("operation", "multipart_upload", "object_key", key)
+
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, key, minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		uploadLogger.Errorw("Failed to initiate multipart upload", "error", err.Error())
+		return err
+	}
+	uploadLogger = uploadLogger.With("upload_id", uploadID)
+	uploadLogger.Infow("Multipart upload initiated",
+		"size_bytes", len(data),
+		"part_size_bytes", partSize,
+		"total_parts", totalParts,
+	)
+
+	var parts []minio.CompletePart
+	var uploaded int
+	for partNumber := 1; partNumber <= totalParts; partNumber++ {
+		offset := (partNumber - 1) * partSize
+		end := offset + partSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := data[offset:end]
+
+		partStart := time.Now()
+		part, err := s.core.PutObjectPart(ctx, s.bucket, key, uploadID, partNumber,
+			bytes.NewReader(chunk), int64(len(chunk)), minio.PutObjectPartOptions{})
+		if err != nil {
+			uploadLogger.Errorw("Part upload failed, aborting",
+				"part_number", partNumber,
+				"error", err.Error(),
+			)
+			if abortErr := s.core.AbortMultipartUpload(ctx, s.bucket, key, uploadID); abortErr != nil {
+				uploadLogger.Warnw("Failed to abort multipart upload", "error", abortErr.Error())
+			}
+			return err
+		}
+
+		uploaded += len(chunk)
+		parts = append(parts, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+
+		partDuration := time.Since(partStart)
+		uploadLogger.Infow("Part uploaded",
+			"part_number", partNumber,
+			"total_parts", totalParts,
+			"part_bytes", len(chunk),
+			"uploaded_bytes", uploaded,
+			"progress_percent", fmt.Sprintf("%.1f", float64(uploaded)*100/float64(len(data))),
+			"duration_ms", partDuration.Milliseconds(),
+			"throughput_mbps", fmt.Sprintf("%.2f", float64(len(chunk))/1024/1024/partDuration.Seconds()),
+		)
+	}
+
+	info, err := s.core.CompleteMultipartUpload(ctx, s.bucket, key, uploadID, parts, minio.PutObjectOptions{})
+	if err != nil {
+		uploadLogger.Errorw("Failed to complete multipart upload", "error", err.Error())
+		return err
+	}
+
+	uploadLogger.Infow("Multipart upload completed",
+		"size_bytes", len(data),
+		"parts", len(parts),
+		"etag", info.ETag,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+// List logs every object under prefix and a summary
+func (s *Storage) List(ctx context.Context, prefix string) {
+	start := time.Now()
+	var count int
+	var totalBytes int64
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			s.logger.Errorw("Object listing failed", "operation", "list_objects", "prefix", prefix, "error", object.Err.Error())
+			return
+		}
+		count++
+		totalBytes += object.Size
+		s.logger.Infow("Object listed",
+			"operation", "list_objects",
+			"object_key", object.Key,
+			"size_bytes", object.Size,
+			"last_modified", object.LastModified.Format(time.RFC3339),
+		)
+	}
+
+	s.logger.Infow("Object listing completed",
+		"operation", "list_objects",
+		"prefix", prefix,
+		"objects", count,
+		"total_bytes", totalBytes,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+// DownloadAndVerify downloads an object and compares its SHA-256 with the expected value
+func (s *Storage) DownloadAndVerify(ctx context.Context, key, expectedSHA256 string) {
+	start := time.Now()
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		s.logger.Errorw("Object download failed", "operation", "get_object", "object_key", key, "error", err.Error())
+		return
+	}
+	defer object.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, object)
+	if err != nil {
+		s.logger.Errorw("Object download failed", "operation", "get_object", "object_key", key, "error", err.Error())
+		return
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	fields := []interface{}{
+		"operation", "get_object",
+		"object_key", key,
+		"size_bytes", size,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"sha256", actual[:16],
+	}
+	if actual != expectedSHA256 {
+		s.logger.Errorw("Downloaded object checksum mismatch", append(fields, "expected_sha256", expectedSHA256[:16])...)
+		return
+	}
+	s.logger.Infow("Object downloaded and verified", fields...)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
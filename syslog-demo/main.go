@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// maxDatagramBytes is large enough for RFC5424 messages over UDP
+const maxDatagramBytes = 8192
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== UDP Syslog Receiver Demo ===")
+	console.Println("Parses RFC3164/RFC5424 syslog and re-emits it as structured logs")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "debug",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"ingest.protocol": "syslog/udp",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	addr := ":" + getEnvOrDefault("PORT", "5514")
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to listen", "addr", addr, "error", err.Error())
+	}
+
+	receiver := &Receiver{
+		conn:      conn,
+		logger:    serviceLogger.With("component", "syslog-receiver"),
+		forwarded: serviceLogger.With("component", "syslog-ingest"),
+	}
+
+	sanitize.Startup(serviceLogger, logOption, "addr", addr)
+	serviceLogger.Infow("Syslog receiver listening", "addr", conn.LocalAddr().String(), "max_datagram_bytes", maxDatagramBytes)
+
+	console.Printf("Listening on udp %s\n", conn.LocalAddr())
+	console.Println("Send test messages:")
+	port := strings.TrimPrefix(addr, ":")
+	console.Printf("  logger -d -n 127.0.0.1 -P %s --rfc3164 -t myapp \"disk almost full\"\n", port)
+	console.Printf("  logger -d -n 127.0.0.1 -P %s --rfc5424 -p local0.err --sd-id meta@32473 --sd-param 'tenant=\"acme\"' \"payment failed\"\n", port)
+	console.Printf("  echo '<34>Oct 11 22:14:15 mymachine su: su root failed for lonvick' | nc -u -w1 localhost %s\n", port)
+
+	go receiver.ReportStats(30 * time.Second)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		serviceLogger.Infow("Shutdown signal received", "signal", sig.String())
+		receiver.closing.Store(true)
+		conn.Close()
+	}()
+
+	receiver.Serve()
+	serviceLogger.Infow("Syslog receiver stopped",
+		"received", receiver.received.Load(),
+		"parse_errors", receiver.parseErrors.Load(),
+	)
+}
+
+// Receiver reads syslog datagrams and re-emits them through the kart-io logger
+type Receiver struct {
+	conn      net.PacketConn
+	logger    core.Logger
+	forwarded core.Logger
+	closing   atomic.Bool
+
+	received    atomic.Uint64
+	parseErrors atomic.Uint64
+}
+
+// Serve reads datagrams until the connection is closed
+func (r *Receiver) Serve() {
+	buf := make([]byte, maxDatagramBytes)
+	for {
+		n, peer, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if r.closing.Load() {
+				return
+			}
+			r.logger.Errorw("Read failed", "error", err.Error())
+			continue
+		}
+
+		r.received.Add(1)
+		r.handle(buf[:n], peer)
+	}
+}
+
+func (r *Receiver) handle(datagram []byte, peer net.Addr) {
+	msg, err := ParseSyslog(datagram)
+	if err != nil {
+		r.parseErrors.Add(1)
+		r.logger.Warnw("Failed to parse syslog message",
+			"peer", peer.String(),
+			"error", err.Error(),
+			"bytes", len(datagram),
+			"raw", truncate(string(datagram), 200),
+		)
+		return
+	}
+
+	fields := []interface{}{
+		"syslog.format", msg.Format,
+		"syslog.facility", msg.FacilityName(),
+		"syslog.severity", msg.SeverityName(),
+		"syslog.hostname", msg.Hostname,
+		"syslog.app_name", msg.AppName,
+		"peer", peer.String(),
+	}
+	if !msg.Timestamp.IsZero() {
+		fields = append(fields, "syslog.timestamp", msg.Timestamp.Format(time.RFC3339Nano))
+	}
+	if msg.ProcID != "" {
+		fields = append(fields, "syslog.proc_id", msg.ProcID)
+	}
+	if msg.MsgID != "" {
+		fields = append(fields, "syslog.msg_id", msg.MsgID)
+	}
+	for id, params := range msg.StructuredData {
+		for name, value := range params {
+			fields = append(fields, "syslog.sd."+id+"."+name, value)
+		}
+	}
+
+	// Map syslog severities onto logger levels
+	switch {
+	case msg.Severity <= 3:
+		r.forwarded.Errorw(msg.Message, fields...)
+	case msg.Severity == 4:
+		r.forwarded.Warnw(msg.Message, fields...)
+	case msg.Severity <= 6:
+		r.forwarded.Infow(msg.Message, fields...)
+	default:
+		r.forwarded.Debugw(msg.Message, fields...)
+	}
+}
+
+// ReportStats periodically logs ingestion counters
+func (r *Receiver) ReportStats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastReceived uint64
+	for range ticker.C {
+		received := r.received.Load()
+		r.logger.Infow("Syslog ingestion stats",
+			"received_total", received,
+			"received_interval", received-lastReceived,
+			"parse_errors_total", r.parseErrors.Load(),
+			"interval", interval.String(),
+		)
+		lastReceived = received
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
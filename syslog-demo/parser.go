@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogMessage is a parsed RFC3164 or RFC5424 message
+type SyslogMessage struct {
+	Format         string
+	Facility       int
+	Severity       int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// FacilityName returns the symbolic facility name
+func (m *SyslogMessage) FacilityName() string {
+	if m.Facility >= 0 && m.Facility < len(facilityNames) {
+		return facilityNames[m.Facility]
+	}
+	return strconv.Itoa(m.Facility)
+}
+
+// SeverityName returns the symbolic severity name
+func (m *SyslogMessage) SeverityName() string {
+	if m.Severity >= 0 && m.Severity < len(severityNames) {
+		return severityNames[m.Severity]
+	}
+	return strconv.Itoa(m.Severity)
+}
+
+// ParseSyslog detects the message format and parses it
+func ParseSyslog(raw []byte) (*SyslogMessage, error) {
+	line := strings.TrimRight(string(raw), "\r\n\x00")
+
+	pri, rest, err := parsePriority(line)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &SyslogMessage{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+
+	// RFC5424 messages carry a version number right after the PRI
+	if strings.HasPrefix(rest, "1 ") {
+		msg.Format = "rfc5424"
+		return msg, parseRFC5424(msg, rest[2:])
+	}
+
+	msg.Format = "rfc3164"
+	return msg, parseRFC3164(msg, rest)
+}
+
+func parsePriority(line string) (int, string, error) {
+	if !strings.HasPrefix(line, "<") {
+		return 0, "", errors.New("missing PRI")
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errors.New("malformed PRI")
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", fmt.Errorf("invalid PRI value %q", line[1:end])
+	}
+	return pri, line[end+1:], nil
+}
+
+// parseRFC3164 handles "Mmm dd hh:mm:ss HOST TAG[PID]: MSG"
+func parseRFC3164(msg *SyslogMessage, rest string) error {
+	const stampLen = len(time.Stamp)
+
+	if len(rest) >= stampLen {
+		if ts, err := time.Parse(time.Stamp, rest[:stampLen]); err == nil {
+			now := time.Now()
+			msg.Timestamp = time.Date(now.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), 0, time.Local)
+			rest = strings.TrimPrefix(rest[stampLen:], " ")
+
+			if host, after, ok := strings.Cut(rest, " "); ok {
+				msg.Hostname = host
+				rest = after
+			}
+		}
+	}
+
+	// TAG is up to 32 alphanumeric chars, optionally followed by [PID], then ':'
+	if colon := strings.Index(rest, ": "); colon > 0 && colon <= 48 && !strings.ContainsAny(rest[:colon], " ") {
+		tag := rest[:colon]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.AppName = tag
+		rest = rest[colon+2:]
+	}
+
+	msg.Message = rest
+	return nil
+}
+
+// parseRFC5424 handles "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG"
+func parseRFC5424(msg *SyslogMessage, rest string) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], rest, ok = strings.Cut(rest, " ")
+		if !ok && i < 4 {
+			return fmt.Errorf("truncated RFC5424 header at field %d", i+1)
+		}
+	}
+
+	if fields[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid RFC5424 timestamp: %w", err)
+		}
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.ProcID = nilValue(fields[3])
+	msg.MsgID = nilValue(fields[4])
+
+	sd, remaining, err := parseStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	msg.StructuredData = sd
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(remaining, " "), "\ufeff")
+	return nil
+}
+
+// parseStructuredData parses "-" or one or more [id key="value" ...] elements
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	if strings.HasPrefix(s, "-") {
+		return nil, s[1:], nil
+	}
+
+	result := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		i := 1
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[1:i]
+		params := make(map[string]string)
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+			eq := strings.IndexByte(s[i:], '=')
+			if eq < 0 || i+eq+1 >= len(s) || s[i+eq+1] != '"' {
+				return nil, "", fmt.Errorf("malformed structured data param in %q", id)
+			}
+			name := s[i : i+eq]
+			i += eq + 2
+
+			var value strings.Builder
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, "", fmt.Errorf("unterminated structured data value in %q", id)
+			}
+			params[name] = value.String()
+			i++
+		}
+
+		if i >= len(s) || s[i] != ']' {
+			return nil, "", fmt.Errorf("unterminated structured data element %q", id)
+		}
+		result[id] = params
+		s = s[i+1:]
+	}
+
+	return result, s, nil
+}
+
+func nilValue(v string) string {
+	if v == "-" {
+		return ""
+	}
+	return v
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+const (
+	// idleTimeout closes connections that send nothing for this long
+	idleTimeout = 30 * time.Second
+	// maxLineBytes limits a single protocol line
+	maxLineBytes = 4096
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== TCP Echo Server Demo ===")
+	console.Println("Line-based echo protocol with connection lifecycle logging")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"protocol":        "tcp",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	addr := ":" + getEnvOrDefault("PORT", "9000")
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to listen", "addr", addr, "error", err.Error())
+	}
+
+	server := &EchoServer{
+		listener:    listener,
+		logger:      serviceLogger,
+		idleTimeout: getDurationEnv("IDLE_TIMEOUT", idleTimeout),
+		conns:       make(map[uint64]net.Conn),
+	}
+
+	sanitize.Startup(serviceLogger, logOption, "addr", addr, "idle_timeout", server.idleTimeout.String())
+	serviceLogger.Infow("TCP server listening",
+		"addr", listener.Addr().String(),
+		"idle_timeout", server.idleTimeout.String(),
+		"max_line_bytes", maxLineBytes,
+	)
+
+	console.Printf("Listening on %s\n", listener.Addr())
+	console.Println("Try it:")
+	console.Printf("  nc localhost %s\n", strings.TrimPrefix(addr, ":"))
+	console.Println("  Type lines to echo them back, 'STATS' for connection counters, 'QUIT' to disconnect")
+
+	// Graceful shutdown on SIGINT/SIGTERM
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		serviceLogger.Infow("Shutdown signal received", "signal", sig.String())
+		server.Shutdown()
+	}()
+
+	server.Serve()
+	serviceLogger.Infow("TCP server stopped",
+		"total_connections", server.totalConns.Load(),
+		"bytes_in", server.bytesIn.Load(),
+		"bytes_out", server.bytesOut.Load(),
+	)
+}
+
+// EchoServer is a line-based TCP echo server
+type EchoServer struct {
+	listener    net.Listener
+	logger      core.Logger
+	idleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[uint64]net.Conn
+	wg    sync.WaitGroup
+	// closing is set under mu, so Serve never adds a connection Shutdown has missed
+	closing atomic.Bool
+	nextID  atomic.Uint64
+
+	totalConns atomic.Uint64
+	bytesIn    atomic.Uint64
+	bytesOut   atomic.Uint64
+}
+
+// Serve accepts connections until the listener is closed
+func (s *EchoServer) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.closing.Load() {
+				break
+			}
+			s.logger.Errorw("Accept failed", "error", err.Error())
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		// Shutdown sets closing under the lock before closing what is in conns, so a
+		// connection accepted while it runs is either in the map in time or closed here
+		s.mu.Lock()
+		if s.closing.Load() {
+			s.mu.Unlock()
+			conn.Close()
+			s.logger.Infow("Connection rejected, server shutting down", "remote_addr", conn.RemoteAddr().String())
+			break
+		}
+		id := s.nextID.Add(1)
+		s.totalConns.Add(1)
+		s.conns[id] = conn
+		active := len(s.conns)
+		s.mu.Unlock()
+
+		connLogger := s.logger.With(
+			"conn_id", id,
+			"remote_addr", conn.RemoteAddr().String(),
+		)
+		connLogger.Infow("Connection accepted", "active_connections", active)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(id, conn, connLogger)
+		}()
+	}
+
+	s.wg.Wait()
+}
+
+// Shutdown stops accepting and closes all active connections
+func (s *EchoServer) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing.Store(true)
+	s.listener.Close()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *EchoServer) handle(id uint64, conn net.Conn, connLogger core.Logger) {
+	counted := &countingConn{Conn: conn}
+	start := time.Now()
+	lines := 0
+	reason := "client_closed"
+
+	defer func() {
+		conn.Close()
+
+		s.mu.Lock()
+		delete(s.conns, id)
+		active := len(s.conns)
+		s.mu.Unlock()
+
+		s.bytesIn.Add(counted.bytesIn)
+		s.bytesOut.Add(counted.bytesOut)
+
+		connLogger.Infow("Connection closed",
+			"reason", reason,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"lines", lines,
+			"bytes_in", counted.bytesIn,
+			"bytes_out", counted.bytesOut,
+			"active_connections", active,
+		)
+	}()
+
+	reader := bufio.NewReaderSize(counted, maxLineBytes)
+	writer := bufio.NewWriter(counted)
+
+	writer.WriteString("WELCOME echo server, idle timeout " + s.idleTimeout.String() + "\n")
+	writer.Flush()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+
+		line, err := reader.ReadSlice('\n')
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, bufio.ErrBufferFull):
+				connLogger.Warnw("Line too long, closing connection", "max_line_bytes", maxLineBytes)
+				reason = "line_too_long"
+			case errors.As(err, &netErr) && netErr.Timeout():
+				connLogger.Warnw("Idle timeout reached", "idle_timeout", s.idleTimeout.String())
+				writer.WriteString("BYE idle timeout\n")
+				writer.Flush()
+				reason = "idle_timeout"
+			case errors.Is(err, io.EOF):
+				reason = "client_closed"
+			case s.closing.Load():
+				reason = "server_shutdown"
+			default:
+				connLogger.Errorw("Read failed", "error", err.Error())
+				reason = "read_error"
+			}
+			return
+		}
+
+		lines++
+		text := strings.TrimRight(string(line), "\r\n")
+		connLogger.Debugw("Line received", "line_bytes", len(line), "line_number", lines)
+
+		switch strings.ToUpper(text) {
+		case "QUIT":
+			writer.WriteString("BYE\n")
+			writer.Flush()
+			reason = "client_quit"
+			return
+		case "STATS":
+			fmt.Fprintf(writer, "STATS lines=%d bytes_in=%d bytes_out=%d uptime=%s\n",
+				lines, counted.bytesIn, counted.bytesOut, time.Since(start).Round(time.Millisecond))
+		default:
+			writer.WriteString(text + "\n")
+		}
+
+		if err := writer.Flush(); err != nil {
+			connLogger.Errorw("Write failed", "error", err.Error())
+			reason = "write_error"
+			return
+		}
+	}
+}
+
+// countingConn tracks bytes transferred in each direction
+type countingConn struct {
+	net.Conn
+	bytesIn  uint64
+	bytesOut uint64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.bytesIn += uint64(n)
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.bytesOut += uint64(n)
+	return n, err
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
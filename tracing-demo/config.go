@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"gopkg.in/yaml.v3"
+)
+
+// Config mirrors tracing.yaml
+type Config struct {
+	Sampling otelsetup.SamplingConfig `yaml:"sampling"`
+}
+
+// LoadConfig reads the YAML file. OTEL_SAMPLE_RATIO, which the demo read before it had a
+// file, still overrides sampling.ratio.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if value := os.Getenv("OTEL_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_SAMPLE_RATIO is not a number: %q", value)
+		}
+		cfg.Sampling.Ratio = &ratio
+	}
+	if err := cfg.Sampling.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/kart-io/go-example/tracing-demo"
+
+// createOrderRequest is the body accepted by POST /orders
+type createOrderRequest struct {
+	SKU      string `json:"sku" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Jaeger Tracing Demo ===")
+	console.Println("OpenTelemetry spans around handlers and DB calls, exported to Jaeger, with trace_id in every request log line")
+	console.Println()
+
+	identity := otelresource.Get()
+
+	logOption := &option.LogOption{
+		Engine:        "zap",
+		Level:         getEnvOrDefault("LOG_LEVEL", "debug"),
+		Format:        "json",
+		OutputPaths:   []string{"stdout"},
+		InitialFields: identity.Fields(),
+	}
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	endpoint := getEnvOrDefault("OTLP_ENDPOINT", "localhost:4318")
+	configPath := getEnvOrDefault("CONFIG_PATH", "tracing.yaml")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to load tracing config", "path", configPath, "error", err.Error())
+	}
+	shutdownTracing, err := initTracing(context.Background(), endpoint, identity, cfg.Sampling, serviceLogger)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to initialize tracing", "error", err.Error())
+	}
+	crash.OnExit("otel", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+	defer func() {
+		// Flush buffered spans so the last requests still show up in Jaeger
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			serviceLogger.Warnw("Failed to flush spans on shutdown", "error", err.Error())
+		}
+	}()
+
+	tracer := otel.Tracer(tracerName)
+	store := NewStore(tracer, serviceLogger.With("component", "store"))
+	port := getEnvOrDefault("PORT", "8098")
+
+	// The inventory call goes over HTTP so the trace shows context propagating between services
+	inventory := &InventoryClient{
+		baseURL: "http://localhost:" + port,
+		client:  &http.Client{Timeout: 3 * time.Second},
+		tracer:  tracer,
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.Use(requestid.GinMiddleware(requestid.Request), tracingMiddleware(tracer, serviceLogger))
+
+	r.GET("/orders/:id", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		log := logcontext.MustFromGin(c)
+
+		order, err := store.GetOrder(ctx, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			log.Infow("Order not found", "order_id", c.Param("id"))
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		if err != nil {
+			log.Errorw("Failed to load order", "order_id", c.Param("id"), "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		available, err := inventory.Stock(ctx, order.SKU)
+		if err != nil {
+			// Degrade instead of failing the whole request; the error is still visible on the client span
+			log.Warnw("Inventory lookup failed, returning order without stock", "sku", order.SKU, "error", err.Error())
+			c.JSON(http.StatusOK, gin.H{"order": order})
+			return
+		}
+
+		log.Infow("Order fetched", "order_id", order.ID, "sku", order.SKU, "available", available)
+		c.JSON(http.StatusOK, gin.H{"order": order, "available": available})
+	})
+
+	r.POST("/orders", func(c *gin.Context) {
+		log := logcontext.MustFromGin(c)
+
+		var req createOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		order, err := store.CreateOrder(c.Request.Context(), req.SKU, req.Quantity)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown sku"})
+			return
+		case errors.Is(err, ErrInsufficientStock):
+			log.Warnw("Order rejected", "sku", req.SKU, "quantity", req.Quantity, "reason", err.Error())
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			log.Errorw("Failed to create order", "sku", req.SKU, "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		log.Infow("Order created", "order_id", order.ID, "sku", order.SKU, "quantity", order.Quantity)
+		c.JSON(http.StatusCreated, order)
+	})
+
+	r.GET("/inventory/:sku", func(c *gin.Context) {
+		available, err := store.GetStock(c.Request.Context(), c.Param("sku"))
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown sku"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sku": c.Param("sku"), "available": available})
+	})
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port, "otlp_endpoint", endpoint, "sampling", cfg.Sampling.String())
+	serviceLogger.Infow("Starting tracing demo server",
+		"port", port,
+		"otlp_endpoint", endpoint,
+		"sampling", cfg.Sampling.String(),
+		"jaeger_ui", "http://localhost:16686",
+		"endpoints", []string{"/orders/:id", "/orders", "/inventory/:sku", "/health", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these, then open http://localhost:16686 and search for the trace_id from the logs:")
+	console.Printf("  curl http://localhost:%s/orders/o-1002\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/orders -H 'Content-Type: application/json' -d '{\"sku\":\"sku-keyboard\",\"quantity\":2}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/orders -H 'Content-Type: application/json' -d '{\"sku\":\"sku-mouse\",\"quantity\":1}'\n", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// tracingMiddleware starts a server span per request (continuing an incoming traceparent),
+// stores the request logger, logcontext.GinRequestFields plus the trace fields, for
+// logcontext.MustFromGin and writes one access line per request
+func tracingMiddleware(tracer trace.Tracer, serviceLogger core.Logger) gin.HandlerFunc {
+	propagator := otel.GetTextMapPropagator()
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		log := traceLogger(ctx, serviceLogger.With(logcontext.GinRequestFields(c)...))
+		logcontext.SetGin(c, log)
+		c.Header("X-Trace-ID", span.SpanContext().TraceID().String())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		log.Infow("Request completed",
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// InventoryClient calls the inventory endpoint with the trace context injected into the headers
+type InventoryClient struct {
+	baseURL string
+	client  *http.Client
+	tracer  trace.Tracer
+}
+
+// Stock returns the available quantity for a SKU
+func (c *InventoryClient) Stock(ctx context.Context, sku string) (int, error) {
+	ctx, span := c.tracer.Start(ctx, "GET /inventory/:sku", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/inventory/"+sku, nil)
+	if err != nil {
+		return 0, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	span.SetAttributes(semconv.HTTPRequestMethodGet, semconv.URLFull(req.URL.String()), attribute.String("inventory.sku", sku))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("inventory returned %d", resp.StatusCode)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	var body struct {
+		Available int `json:"available"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.Available, nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNotFound is returned when a row does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInsufficientStock aborts an order transaction
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// Order is a row in the orders table
+type Order struct {
+	ID       string `json:"id"`
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	Status   string `json:"status"`
+}
+
+// Store is an in-memory stand-in for a PostgreSQL database. Every query runs through
+// query(), which creates a client span with the database semantic conventions and
+// sleeps for a realistic latency, so the traces look like those of a real driver.
+type Store struct {
+	tracer trace.Tracer
+	logger core.Logger
+
+	mu     sync.Mutex
+	orders map[string]Order
+	stock  map[string]int
+	nextID int
+}
+
+// NewStore seeds a few orders and stock levels
+func NewStore(tracer trace.Tracer, logger core.Logger) *Store {
+	return &Store{
+		tracer: tracer,
+		logger: logger,
+		orders: map[string]Order{
+			"o-1001": {ID: "o-1001", SKU: "sku-keyboard", Quantity: 1, Status: "shipped"},
+			"o-1002": {ID: "o-1002", SKU: "sku-monitor", Quantity: 2, Status: "paid"},
+		},
+		stock:  map[string]int{"sku-keyboard": 12, "sku-monitor": 3, "sku-mouse": 0},
+		nextID: 1003,
+	}
+}
+
+// GetOrder runs SELECT ... FROM orders
+func (s *Store) GetOrder(ctx context.Context, id string) (Order, error) {
+	var order Order
+	err := s.query(ctx, "SELECT", "orders", "SELECT id, sku, quantity, status FROM orders WHERE id = $1", func() error {
+		found, ok := s.orders[id]
+		if !ok {
+			return ErrNotFound
+		}
+		order = found
+		return nil
+	})
+	return order, err
+}
+
+// GetStock runs SELECT ... FROM inventory
+func (s *Store) GetStock(ctx context.Context, sku string) (int, error) {
+	var quantity int
+	err := s.query(ctx, "SELECT", "inventory", "SELECT quantity FROM inventory WHERE sku = $1", func() error {
+		found, ok := s.stock[sku]
+		if !ok {
+			return ErrNotFound
+		}
+		quantity = found
+		return nil
+	})
+	return quantity, err
+}
+
+// CreateOrder reserves stock and inserts the order inside one transaction span
+func (s *Store) CreateOrder(ctx context.Context, sku string, quantity int) (order Order, err error) {
+	ctx, span := s.tracer.Start(ctx, "db.transaction CreateOrder", trace.WithSpanKind(trace.SpanKindInternal))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if err = s.query(ctx, "BEGIN", "", "BEGIN", nil); err != nil {
+		return Order{}, err
+	}
+
+	err = s.query(ctx, "UPDATE", "inventory", "UPDATE inventory SET quantity = quantity - $2 WHERE sku = $1 AND quantity >= $2", func() error {
+		available, ok := s.stock[sku]
+		if !ok {
+			return ErrNotFound
+		}
+		if available < quantity {
+			return ErrInsufficientStock
+		}
+		s.stock[sku] = available - quantity
+		return nil
+	})
+	if err != nil {
+		s.query(ctx, "ROLLBACK", "", "ROLLBACK", nil)
+		return Order{}, err
+	}
+
+	err = s.query(ctx, "INSERT", "orders", "INSERT INTO orders (id, sku, quantity, status) VALUES ($1, $2, $3, 'pending')", func() error {
+		order = Order{ID: fmt.Sprintf("o-%d", s.nextID), SKU: sku, Quantity: quantity, Status: "pending"}
+		s.nextID++
+		s.orders[order.ID] = order
+		return nil
+	})
+	if err != nil {
+		s.query(ctx, "ROLLBACK", "", "ROLLBACK", nil)
+		return Order{}, err
+	}
+
+	err = s.query(ctx, "COMMIT", "", "COMMIT", nil)
+	return order, err
+}
+
+// query wraps one statement in a client span; ErrNotFound is a normal outcome and does not mark the span as failed
+func (s *Store) query(ctx context.Context, operation, table, statement string, run func() error) error {
+	name := operation
+	if table != "" {
+		name = operation + " " + table
+	}
+	ctx, span := s.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBNamespace("shop"),
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(statement),
+	)
+	if table != "" {
+		span.SetAttributes(semconv.DBCollectionName(table))
+	}
+
+	// Simulated network round trip plus the occasional slow query; its "Slow query" warning
+	// below also becomes the span's event
+	latency := time.Duration(2+rng.Intn(8)) * time.Millisecond
+	if rng.Intn(20) == 0 {
+		latency += 150 * time.Millisecond
+	}
+	time.Sleep(latency)
+
+	var err error
+	if run != nil {
+		s.mu.Lock()
+		err = run()
+		s.mu.Unlock()
+	}
+
+	queryLogger := traceLogger(ctx, s.logger)
+	fields := []interface{}{
+		"db.operation", operation,
+		"db.statement", strings.Fields(statement)[0] + " ...",
+		"duration_ms", latency.Milliseconds(),
+	}
+	switch {
+	case err == nil:
+		queryLogger.Debugw("Query executed", fields...)
+	case errors.Is(err, ErrNotFound):
+		span.SetAttributes(attribute.Int("db.response.rows", 0))
+		queryLogger.Debugw("Query returned no rows", fields...)
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		queryLogger.Warnw("Query failed", append(fields, "error", err.Error())...)
+	}
+	if latency > 100*time.Millisecond {
+		queryLogger.Warnw("Slow query", fields...)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/hostmeta"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/spanevents"
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// initTracing installs a global tracer provider exporting to Jaeger over OTLP; Jaeger only
+// accepts spans, so metrics and logs are left out
+func initTracing(ctx context.Context, endpoint string, identity otelresource.Identity, sampling otelsetup.SamplingConfig, logger core.Logger) (func(context.Context) error, error) {
+	sampler, err := sampling.NewSampler()
+	if err != nil {
+		return nil, err
+	}
+	_, shutdown, err := otelsetup.Setup(ctx, identity.Config(otelsetup.Config{
+		Attributes:     map[string]string{"host.name": hostmeta.Get().Hostname},
+		Endpoint:       endpoint,
+		Insecure:       true,
+		TLS:            certwatch.FromEnv("OTLP", logger),
+		ExportInterval: 2 * time.Second,
+		Signals:        otelsetup.Traces,
+		// parent_based in tracing.yaml respects the caller's decision and samples root spans by ratio
+		Sampler:             sampler,
+		SamplingLogInterval: sampling.LogInterval,
+	}), logger)
+	return shutdown, err
+}
+
+// traceLogger adds the IDs of the span in ctx, so every log line can be looked up in Jaeger,
+// and records its Warn and Error entries as events on that span
+func traceLogger(ctx context.Context, logger core.Logger) core.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+	return spanevents.Wrap(ctx, logger.With(
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+		"trace_sampled", spanContext.IsSampled(),
+	))
+}
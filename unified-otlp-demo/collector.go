@@ -0,0 +1,273 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SignalReport summarises what the collector received for one signal
+type SignalReport struct {
+	Items      int      `json:"items"`
+	Identities []string `json:"identities"`
+}
+
+// MockCollector is a minimal OTLP/HTTP receiver for all three signals. It records the
+// resource identity each signal arrived with, so the demo can show they are identical.
+type MockCollector struct {
+	logger  core.Logger
+	addr    string
+	handler http.Handler
+
+	serverMu sync.Mutex
+	server   *http.Server
+	closed   bool
+
+	mu         sync.Mutex
+	items      map[string]int
+	identities map[string]map[string]bool
+	spanTraces map[string]bool
+	logTraces  map[string]bool
+}
+
+// StartMockCollector listens on addr and serves POST /v1/logs, /v1/traces and /v1/metrics
+func StartMockCollector(addr string, logger core.Logger) (*MockCollector, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	c := &MockCollector{
+		logger:     logger,
+		addr:       listener.Addr().String(),
+		items:      map[string]int{},
+		identities: map[string]map[string]bool{},
+		spanTraces: map[string]bool{},
+		logTraces:  map[string]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", c.handleLogs)
+	mux.HandleFunc("/v1/traces", c.handleTraces)
+	mux.HandleFunc("/v1/metrics", c.handleMetrics)
+	c.handler = mux
+	c.serve(listener)
+	return c, nil
+}
+
+func (c *MockCollector) serve(listener net.Listener) {
+	c.serverMu.Lock()
+	defer c.serverMu.Unlock()
+	c.server = &http.Server{Handler: c.handler}
+	go c.server.Serve(listener)
+}
+
+// Addr returns the host:port the collector listens on
+func (c *MockCollector) Addr() string {
+	return c.addr
+}
+
+// Close stops the collector
+func (c *MockCollector) Close() error {
+	c.serverMu.Lock()
+	defer c.serverMu.Unlock()
+	c.closed = true
+	return c.server.Close()
+}
+
+// Outage stops listening for d, so exporters get connection refused, then listens on the
+// same address again
+func (c *MockCollector) Outage(d time.Duration) {
+	c.logger.Warnw("Mock collector going down", "addr", c.addr, "outage", d.String())
+	c.serverMu.Lock()
+	c.server.Close()
+	c.serverMu.Unlock()
+	time.AfterFunc(d, func() {
+		c.serverMu.Lock()
+		closed := c.closed
+		c.serverMu.Unlock()
+		if closed {
+			return
+		}
+		listener, err := net.Listen("tcp", c.addr)
+		if err != nil {
+			c.logger.Errorw("Mock collector failed to come back", "addr", c.addr, "error", err.Error())
+			return
+		}
+		c.serve(listener)
+		c.logger.Infow("Mock collector back up", "addr", c.addr)
+	})
+}
+
+// Report returns per-signal counts and identities, plus how many traced log records
+// point at a trace the collector also received spans for
+func (c *MockCollector) Report() (map[string]SignalReport, int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := make(map[string]SignalReport, len(c.items))
+	for signal, items := range c.items {
+		identities := make([]string, 0, len(c.identities[signal]))
+		for identity := range c.identities[signal] {
+			identities = append(identities, identity)
+		}
+		sort.Strings(identities)
+		report[signal] = SignalReport{Items: items, Identities: identities}
+	}
+
+	matched := 0
+	for traceID := range c.logTraces {
+		if c.spanTraces[traceID] {
+			matched++
+		}
+	}
+	return report, matched, len(c.logTraces)
+}
+
+func (c *MockCollector) handleLogs(w http.ResponseWriter, r *http.Request) {
+	var req collogsv1.ExportLogsServiceRequest
+	if !c.decode(w, r, &req) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceLogs := range req.GetResourceLogs() {
+		c.observe("logs", resourceLogs.GetResource())
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				c.items["logs"]++
+				if len(record.GetTraceId()) > 0 {
+					c.logTraces[hex.EncodeToString(record.GetTraceId())] = true
+				}
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *MockCollector) handleTraces(w http.ResponseWriter, r *http.Request) {
+	var req coltracev1.ExportTraceServiceRequest
+	if !c.decode(w, r, &req) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceSpans := range req.GetResourceSpans() {
+		c.observe("traces", resourceSpans.GetResource())
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				c.items["traces"]++
+				c.spanTraces[hex.EncodeToString(span.GetTraceId())] = true
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *MockCollector) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var req colmetricsv1.ExportMetricsServiceRequest
+	if !c.decode(w, r, &req) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceMetrics := range req.GetResourceMetrics() {
+		c.observe("metrics", resourceMetrics.GetResource())
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			c.items["metrics"] += len(scopeMetrics.GetMetrics())
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// observe records the resource identity of one export; the first time an identity shows up
+// for a signal it is logged
+func (c *MockCollector) observe(signal string, res *resourcev1.Resource) {
+	attrs := attributesToMap(res.GetAttributes())
+	identity := fmt.Sprintf("%s@%s (%s, %s)",
+		attrs["service.name"], attrs["service.version"], attrs["deployment.environment"], attrs["service.instance.id"])
+
+	if c.identities[signal] == nil {
+		c.identities[signal] = map[string]bool{}
+	}
+	if c.identities[signal][identity] {
+		return
+	}
+	c.identities[signal][identity] = true
+	c.logger.Infow("Mock collector received new resource",
+		"signal", signal,
+		"resource", map[string]string{
+			"service.name":           attrs["service.name"],
+			"service.version":        attrs["service.version"],
+			"deployment.environment": attrs["deployment.environment"],
+			"service.instance.id":    attrs["service.instance.id"],
+			"telemetry.sdk":          attrs["telemetry.sdk.name"] + " " + attrs["telemetry.sdk.version"],
+		},
+	)
+}
+
+// decode reads an optionally gzip-compressed protobuf body
+func (c *MockCollector) decode(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err == nil {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		c.logger.Warnw("Mock collector received undecodable payload", "path", r.URL.Path, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func attributesToMap(kvs []*commonv1.KeyValue) map[string]string {
+	result := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		result[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return result
+}
+
+func anyValueString(v *commonv1.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return value.StringValue
+	case *commonv1.AnyValue_IntValue:
+		return fmt.Sprint(value.IntValue)
+	case *commonv1.AnyValue_DoubleValue:
+		return fmt.Sprint(value.DoubleValue)
+	case *commonv1.AnyValue_BoolValue:
+		return fmt.Sprint(value.BoolValue)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
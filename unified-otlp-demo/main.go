@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/certwatch"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/otelresource"
+	"github.com/kart-io/go-example/pkg/otelsetup"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const instrumentationName = "github.com/kart-io/go-example/unified-otlp-demo"
+
+// ErrOutOfStock fails an order during inventory reservation
+var ErrOutOfStock = errors.New("out of stock")
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Unified OTLP Pipeline Demo ===")
+	console.Println("Logs, traces and metrics sharing one resource and one collector endpoint")
+	console.Println()
+
+	// The single identity every signal carries
+	identity := otelresource.Get()
+	initialFields := identity.Fields()
+
+	// Diagnostics about the pipeline itself must not go through the pipeline
+	baseLogger, err := logger.New(&option.LogOption{
+		Engine:        "zap",
+		Level:         "info",
+		Format:        "json",
+		OutputPaths:   []string{"stderr"},
+		InitialFields: initialFields,
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	diagnostics := baseLogger.With("component", "telemetry")
+
+	// Without an external endpoint, run an in-process collector so the demo is self-contained
+	endpoint := os.Getenv("OTLP_ENDPOINT")
+	var collector *MockCollector
+	if endpoint == "" {
+		collector, err = StartMockCollector("127.0.0.1:0", baseLogger.With("component", "mock-collector"))
+		if err != nil {
+			crash.Fatal(diagnostics, "Failed to start mock collector", "error", err.Error())
+		}
+		defer collector.Close()
+		endpoint = collector.Addr()
+	}
+
+	ctx := context.Background()
+	telemetry, shutdownTelemetry, err := otelsetup.Setup(ctx, identity.Config(otelsetup.Config{
+		Endpoint:       endpoint,
+		Protocol:       getEnvOrDefault("OTLP_PROTOCOL", otelsetup.ProtocolHTTP),
+		Insecure:       getEnvOrDefault("OTLP_INSECURE", "true") == "true",
+		TLS:            certwatch.FromEnv("OTLP", diagnostics),
+		Headers:        parseHeaders(os.Getenv("OTLP_HEADERS")),
+		ExportInterval: getDurationEnv("EXPORT_INTERVAL", 2*time.Second),
+		SpoolDir:       dirEnv("OTLP_SPOOL_DIR", "unified-otlp-demo-spool"),
+		SpoolMaxAge:    getDurationEnv("OTLP_SPOOL_MAX_AGE", 0),
+		DeadLetterDir:  dirEnv("OTLP_DEAD_LETTER_DIR", "unified-otlp-demo-dead-letter"),
+	}), diagnostics)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to initialize telemetry", "error", err.Error())
+	}
+	// From here a crash shuts the providers down first, so what is queued or spooled is kept
+	crash.OnExit("otel", func() error {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTelemetry(shutdownCtx)
+	})
+
+	// Log lines go to stdout and, through the sink, into the same OTel pipeline as spans and metrics
+	if err := zap.RegisterSink("otel", func(*url.URL) (zap.Sink, error) {
+		return NewOTelSink(telemetry.LoggerProvider, instrumentationName), nil
+	}); err != nil {
+		crash.Fatal(diagnostics, "Failed to register OTel sink", "error", err.Error())
+	}
+	logOption := &option.LogOption{
+		Engine:        "zap",
+		Level:         "info",
+		Format:        "json",
+		OutputPaths:   []string{"stdout", "otel://logs"},
+		InitialFields: initialFields,
+	}
+	cliflags.Apply(logOption)
+	appLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create application logger", "error", err.Error())
+	}
+	// Hooks run newest first: the logger is flushed before the providers it exports through
+	crash.OnExit("application logger", appLogger.Flush)
+	serviceLogger := appLogger.With("component", "orders")
+	defer crash.Recover(serviceLogger)
+
+	tracer := otel.Tracer(instrumentationName)
+	meter := otel.Meter(instrumentationName)
+	processed, err := meter.Int64Counter("orders.processed",
+		metric.WithUnit("{order}"),
+		metric.WithDescription("Orders processed by outcome"),
+	)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create counter", "error", err.Error())
+	}
+	duration, err := meter.Float64Histogram("order.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time spent processing an order"),
+	)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create histogram", "error", err.Error())
+	}
+
+	sanitize.Startup(diagnostics, logOption, "otlp_endpoint", endpoint, "mock_collector", collector != nil)
+	orders := getIntEnv("ORDERS", 30)
+	outage := getDurationEnv("COLLECTOR_OUTAGE", 0)
+	diagnostics.Infow("Processing orders", "endpoint", endpoint, "mock_collector", collector != nil, "orders", orders)
+	for i := 1; i <= orders; i++ {
+		// A third of the way in the collector drops out; what is exported meanwhile is spooled
+		if collector != nil && outage > 0 && i == orders/3+1 {
+			collector.Outage(outage)
+		}
+		processOrder(ctx, tracer, processed, duration, serviceLogger, fmt.Sprintf("ord-%04d", i))
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Flush the logger first so its records are queued before the providers shut down
+	if err := appLogger.Flush(); err != nil {
+		diagnostics.Warnw("Application logger flush failed", "error", err.Error())
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		diagnostics.Warnw("Telemetry shutdown failed", "error", err.Error())
+	}
+
+	if collector == nil {
+		diagnostics.Infow("Demo finished", "endpoint", endpoint)
+		return
+	}
+	signals, matched, traced := collector.Report()
+	consistent := len(signals) == 3
+	for _, report := range signals {
+		consistent = consistent && len(report.Identities) == 1 && report.Identities[0] == signals["logs"].Identities[0]
+	}
+	diagnostics.Infow("Demo finished",
+		"signals", signals,
+		"identity_consistent", consistent,
+		"traced_log_traces", traced,
+		"traces_with_spans", matched,
+	)
+}
+
+// processOrder runs one order inside a span, recording metrics and logs against the same context
+func processOrder(ctx context.Context, tracer trace.Tracer, processed metric.Int64Counter, duration metric.Float64Histogram, serviceLogger core.Logger, orderID string) {
+	ctx, span := tracer.Start(ctx, "order.process", trace.WithAttributes(attribute.String("order.id", orderID)))
+	defer span.End()
+	log := traceLogger(ctx, serviceLogger)
+
+	start := time.Now()
+	err := reserveInventory(ctx, tracer, log, orderID)
+	if err == nil {
+		err = chargePayment(ctx, tracer)
+	}
+	elapsed := time.Since(start)
+
+	outcome := "completed"
+	if err != nil {
+		outcome = "failed"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Errorw("Order failed", "order_id", orderID, "duration_ms", elapsed.Milliseconds(), "error", err.Error())
+	} else {
+		log.Infow("Order completed", "order_id", orderID, "duration_ms", elapsed.Milliseconds())
+	}
+	processed.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+	duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+func reserveInventory(ctx context.Context, tracer trace.Tracer, log core.Logger, orderID string) error {
+	ctx, span := tracer.Start(ctx, "inventory.reserve")
+	defer span.End()
+
+	time.Sleep(time.Duration(5+rng.Intn(20)) * time.Millisecond)
+	if rng.Intn(100) < 10 {
+		span.SetStatus(codes.Error, ErrOutOfStock.Error())
+		traceLogger(ctx, log).Warnw("Inventory reservation failed", "order_id", orderID, "reason", ErrOutOfStock.Error())
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+func chargePayment(ctx context.Context, tracer trace.Tracer) error {
+	_, span := tracer.Start(ctx, "payment.charge", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	time.Sleep(time.Duration(20+rng.Intn(60)) * time.Millisecond)
+	return nil
+}
+
+// dirEnv reads a directory from key, defaulting to name under the system temp dir; "off"
+// turns the feature off
+func dirEnv(key, name string) string {
+	dir := getEnvOrDefault(key, filepath.Join(os.TempDir(), name))
+	if dir == "off" {
+		return ""
+	}
+	return dir
+}
+
+// parseHeaders reads "key=value,key2=value2", the format of OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if key, val, ok := strings.Cut(strings.TrimSpace(pair), "="); ok && key != "" {
+			headers[key] = val
+		}
+	}
+	return headers
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// consumedKeys become record fields instead of attributes
+var consumedKeys = map[string]bool{
+	"level": true, "timestamp": true, "message": true, "engine": true, "trace_id": true, "span_id": true,
+}
+
+var severities = map[string]otellog.Severity{
+	"debug": otellog.SeverityDebug,
+	"info":  otellog.SeverityInfo,
+	"warn":  otellog.SeverityWarn,
+	"error": otellog.SeverityError,
+	"fatal": otellog.SeverityFatal,
+	"panic": otellog.SeverityFatal,
+}
+
+// OTelSink is a zap sink that re-emits each JSON line through the OTel logs SDK, so log
+// records share the resource and exporter configuration of traces and metrics
+type OTelSink struct {
+	provider *sdklog.LoggerProvider
+	logger   otellog.Logger
+	skip     map[string]bool
+}
+
+// NewOTelSink emits through provider; keys already on the resource are dropped from attributes
+func NewOTelSink(provider *sdklog.LoggerProvider, scope string) *OTelSink {
+	skip := make(map[string]bool, len(consumedKeys)+len(resourceKeys))
+	for key := range consumedKeys {
+		skip[key] = true
+	}
+	for _, key := range resourceKeys {
+		skip[key] = true
+	}
+	return &OTelSink{provider: provider, logger: provider.Logger(scope), skip: skip}
+}
+
+// Write converts one JSON log line into an OTel log record
+func (s *OTelSink) Write(p []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return len(p), nil
+	}
+
+	var record otellog.Record
+	record.SetObservedTimestamp(time.Now())
+	if ts, ok := raw["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			record.SetTimestamp(t)
+		}
+	}
+	level, _ := raw["level"].(string)
+	record.SetSeverity(severities[level])
+	record.SetSeverityText(level)
+	message, _ := raw["message"].(string)
+	record.SetBody(otellog.StringValue(message))
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		if !s.skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		record.AddAttributes(otellog.KeyValue{Key: key, Value: toLogValue(raw[key])})
+	}
+
+	s.logger.Emit(withSpanContext(context.Background(), raw), record)
+	return len(p), nil
+}
+
+// Sync exports buffered records; zap calls it on Flush and before exiting on Fatal
+func (s *OTelSink) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.provider.ForceFlush(ctx)
+}
+
+// Close is a no-op; the otelsetup shutdown function stops the provider
+func (s *OTelSink) Close() error {
+	return nil
+}
+
+// withSpanContext restores the span the log line was written in, which the SDK copies
+// into the record's trace_id and span_id
+func withSpanContext(ctx context.Context, raw map[string]interface{}) context.Context {
+	traceHex, _ := raw["trace_id"].(string)
+	spanHex, _ := raw["span_id"].(string)
+	traceID, err := trace.TraceIDFromHex(traceHex)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(spanHex)
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+func toLogValue(v interface{}) otellog.Value {
+	switch value := v.(type) {
+	case string:
+		return otellog.StringValue(value)
+	case bool:
+		return otellog.BoolValue(value)
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return otellog.Int64Value(n)
+		}
+		f, _ := value.Float64()
+		return otellog.Float64Value(f)
+	case []interface{}:
+		values := make([]otellog.Value, 0, len(value))
+		for _, item := range value {
+			values = append(values, toLogValue(item))
+		}
+		return otellog.SliceValue(values...)
+	case map[string]interface{}:
+		kvs := make([]otellog.KeyValue, 0, len(value))
+		for key, item := range value {
+			kvs = append(kvs, otellog.KeyValue{Key: key, Value: toLogValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	case nil:
+		return otellog.Value{}
+	default:
+		return otellog.StringValue(fmt.Sprint(value))
+	}
+}
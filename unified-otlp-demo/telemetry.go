@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/kart-io/logger/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resourceKeys are the identity attributes shared by all three signals. The logger writes
+// them as InitialFields for the console; the OTLP path carries them on the resource instead.
+var resourceKeys = []string{"service.name", "service.version", "deployment.environment", "service.instance.id"}
+
+// traceLogger adds the IDs of the span in ctx; the OTel sink turns them back into the
+// record's trace context
+func traceLogger(ctx context.Context, logger core.Logger) core.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+	return logger.With("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/rng"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+	"go.uber.org/zap"
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Vector Sink Demo ===")
+	console.Println("Ships logs to Vector's http_server source, falling back to a local file while Vector is down")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	// Diagnostics about the sink itself must not go through the sink
+	baseLogger, err := logger.New(&option.LogOption{
+		Engine:      "zap",
+		Level:       getEnvOrDefault("SINK_LOG_LEVEL", "info"),
+		Format:      "json",
+		OutputPaths: []string{"stderr"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	})
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	crash.OnExit("logger", baseLogger.Flush)
+	defer crash.Recover(baseLogger)
+	defer resusage.FromEnv(baseLogger)()
+	defer pidfile.FromEnv(baseLogger)()
+
+	diagnostics := baseLogger.With("component", "vector-sink")
+
+	ingestURL := os.Getenv("VECTOR_URL")
+	healthURL := getEnvOrDefault("VECTOR_HEALTH_URL", "http://localhost:8686/health")
+	var mock *MockVector
+	if ingestURL == "" {
+		mock, err = StartMockVector("127.0.0.1:0", baseLogger.With("component", "mock-vector"))
+		if err != nil {
+			crash.Fatal(diagnostics, "Failed to start mock Vector", "error", err.Error())
+		}
+		defer mock.Close()
+		ingestURL = mock.IngestURL()
+		healthURL = mock.HealthURL()
+
+		outageAt := getDurationEnv("OUTAGE_AT", 1500*time.Millisecond)
+		outageFor := getDurationEnv("OUTAGE_FOR", 2*time.Second)
+		mock.Outage(outageAt, outageFor)
+		diagnostics.Infow("Started in-process mock Vector",
+			"ingest_url", ingestURL,
+			"outage_at", outageAt.String(),
+			"outage_for", outageFor.String(),
+		)
+	}
+
+	sink, err := NewVectorSink(VectorConfig{
+		IngestURL:      ingestURL,
+		HealthURL:      healthURL,
+		HealthInterval: getDurationEnv("HEALTH_INTERVAL", 500*time.Millisecond),
+		FallbackPath:   getEnvOrDefault("FALLBACK_PATH", "data/vector-fallback.log"),
+		BatchSize:      getIntEnv("BATCH_SIZE", 100),
+		FlushEvery:     250 * time.Millisecond,
+		QueueSize:      getIntEnv("QUEUE_SIZE", 2000),
+		Timeout:        2 * time.Second,
+	}, diagnostics)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create Vector sink", "error", err.Error())
+	}
+
+	// The zap engine opens output paths through zap's sink registry, so a custom scheme plugs straight in
+	if err := zap.RegisterSink("vector", func(*url.URL) (zap.Sink, error) { return sink, nil }); err != nil {
+		crash.Fatal(diagnostics, "Failed to register Vector sink", "error", err.Error())
+	}
+	crash.OnExit("Vector sink", sink.Close)
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"vector://http"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"environment":     getEnvOrDefault("DEPLOY_ENV", "development"),
+		},
+	}
+	cliflags.Apply(logOption)
+	appLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(diagnostics, "Failed to create application logger", "error", err.Error())
+	}
+	crash.OnExit("application logger", appLogger.Flush)
+
+	sanitize.Startup(diagnostics, logOption, "ingest_url", ingestURL, "health_url", healthURL, "mock_vector", mock != nil)
+	rate := getIntEnv("RATE", 300)
+	duration := getDurationEnv("DURATION", 5*time.Second)
+	diagnostics.Infow("Generating traffic", "rate_per_sec", rate, "duration", duration.String())
+
+	total := generateTraffic(appLogger, rate, duration)
+
+	// Give the health checker a chance to see a recovery and replay the file before shutting down
+	time.Sleep(2 * getDurationEnv("HEALTH_INTERVAL", 500*time.Millisecond))
+	appLogger.Flush()
+	sink.Close()
+
+	stats := sink.Stats()
+	fields := []interface{}{"lines_written", total, "stats", stats}
+	if mock != nil {
+		fields = append(fields, "mock_vector_received", mock.Events())
+	}
+	diagnostics.Infow("Vector demo finished", fields...)
+
+	fmt.Fprintf(os.Stderr, "\nwritten=%d delivered=%d fallback=%d replayed=%d dropped=%d\n",
+		total, stats.Delivered, stats.FellBack, stats.Replayed, stats.Dropped)
+}
+
+// generateTraffic writes a steady stream of application events for the given duration.
+// Each message stays under 100/s because the zap production preset samples identical messages above that.
+func generateTraffic(appLogger core.Logger, rate int, duration time.Duration) int {
+	events := []string{
+		"Order created", "Order paid", "Cart updated", "User signed in",
+		"Search executed", "Inventory checked", "Coupon applied", "Profile viewed",
+	}
+
+	perTick := rate / 100
+	if perTick < 1 {
+		perTick = 1
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	written := 0
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		for i := 0; i < perTick; i++ {
+			written++
+			message := events[rng.Intn(len(events))]
+			latency := rng.Intn(300)
+			if written%97 == 0 {
+				appLogger.Errorw(message+" failed", "request_id", written, "status", 500, "latency_ms", latency)
+				continue
+			}
+			appLogger.Infow(message, "request_id", written, "status", 200, "latency_ms", latency)
+		}
+	}
+	return written
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
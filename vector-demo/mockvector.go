@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// MockVector imitates Vector's http_server source plus the /health route of its API,
+// and can be switched off for a while to exercise the file fallback
+type MockVector struct {
+	logger   core.Logger
+	listener net.Listener
+	server   *http.Server
+
+	down    atomic.Bool
+	batches atomic.Uint64
+	events  atomic.Uint64
+}
+
+// StartMockVector listens on addr and serves POST / (source) and GET /health (API)
+func StartMockVector(addr string, logger core.Logger) (*MockVector, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	m := &MockVector{logger: logger, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", m.handleHealth)
+	mux.HandleFunc("/", m.handleIngest)
+	m.server = &http.Server{Handler: mux}
+
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// IngestURL is where the http_server source accepts NDJSON
+func (m *MockVector) IngestURL() string {
+	return "http://" + m.listener.Addr().String() + "/"
+}
+
+// HealthURL is the API health endpoint
+func (m *MockVector) HealthURL() string {
+	return "http://" + m.listener.Addr().String() + "/health"
+}
+
+// Events returns how many events were accepted
+func (m *MockVector) Events() uint64 {
+	return m.events.Load()
+}
+
+// Outage takes the mock down after delay for the given duration
+func (m *MockVector) Outage(delay, duration time.Duration) {
+	time.AfterFunc(delay, func() {
+		m.down.Store(true)
+		m.logger.Warnw("Mock Vector going down", "duration", duration.String())
+		time.AfterFunc(duration, func() {
+			m.down.Store(false)
+			m.logger.Infow("Mock Vector back up")
+		})
+	})
+}
+
+// Close stops the server
+func (m *MockVector) Close() error {
+	return m.server.Close()
+}
+
+func (m *MockVector) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if m.down.Load() {
+		http.Error(w, `{"ok":false}`, http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (m *MockVector) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if m.down.Load() {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	events := 0
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			events++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.batches.Add(1)
+	m.events.Add(uint64(events))
+	m.logger.Debugw("Mock Vector accepted batch", "events", events)
+	w.WriteHeader(http.StatusOK)
+}
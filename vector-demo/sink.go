@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// VectorConfig controls delivery to Vector's http_server source and the file fallback
+type VectorConfig struct {
+	IngestURL      string
+	HealthURL      string
+	HealthInterval time.Duration
+	FallbackPath   string
+	BatchSize      int
+	FlushEvery     time.Duration
+	QueueSize      int
+	Timeout        time.Duration
+}
+
+// VectorStats are cumulative counters
+type VectorStats struct {
+	Delivered   uint64 `json:"delivered"`
+	FellBack    uint64 `json:"written_to_fallback"`
+	Replayed    uint64 `json:"replayed"`
+	Dropped     uint64 `json:"dropped_queue_full"`
+	Transitions uint64 `json:"health_transitions"`
+	Healthy     bool   `json:"healthy"`
+}
+
+// VectorSink is a zap sink that posts NDJSON batches to Vector while it is healthy
+// and appends to a local file while it is not, replaying the file once Vector recovers
+type VectorSink struct {
+	cfg    VectorConfig
+	client *http.Client
+	logger core.Logger
+
+	healthy atomic.Bool
+
+	queue   chan []byte
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	fallback *os.File
+
+	delivered   atomic.Uint64
+	fellBack    atomic.Uint64
+	replayed    atomic.Uint64
+	dropped     atomic.Uint64
+	transitions atomic.Uint64
+}
+
+// NewVectorSink checks health once, then starts the shipper and the health checker
+func NewVectorSink(cfg VectorConfig, diagnostics core.Logger) (*VectorSink, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.FallbackPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fallback directory: %w", err)
+	}
+
+	s := &VectorSink{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		logger:  diagnostics,
+		queue:   make(chan []byte, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	healthy := s.checkHealth() == nil
+	s.healthy.Store(healthy)
+	diagnostics.Infow("Initial Vector health check",
+		"health_url", cfg.HealthURL,
+		"healthy", healthy,
+		"fallback_path", cfg.FallbackPath,
+	)
+
+	go s.run()
+	return s, nil
+}
+
+// Write queues one encoded log line
+func (s *VectorSink) Write(p []byte) (int, error) {
+	line := append([]byte(nil), bytes.TrimRight(p, "\n")...)
+
+	select {
+	case s.queue <- line:
+	default:
+		if dropped := s.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			s.logger.Warnw("Vector queue full, dropping log lines",
+				"queue_size", s.cfg.QueueSize,
+				"dropped_total", dropped,
+			)
+		}
+	}
+	return len(p), nil
+}
+
+// Sync blocks until queued lines are delivered or written to the fallback file
+func (s *VectorSink) Sync() error {
+	ack := make(chan struct{})
+	select {
+	case s.flushCh <- ack:
+		<-ack
+	case <-s.stopped:
+	}
+	return nil
+}
+
+// Close flushes and stops the sink
+func (s *VectorSink) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		<-s.stopped
+	})
+	return nil
+}
+
+// Stats returns a snapshot of the counters
+func (s *VectorSink) Stats() VectorStats {
+	return VectorStats{
+		Delivered:   s.delivered.Load(),
+		FellBack:    s.fellBack.Load(),
+		Replayed:    s.replayed.Load(),
+		Dropped:     s.dropped.Load(),
+		Transitions: s.transitions.Load(),
+		Healthy:     s.healthy.Load(),
+	}
+}
+
+func (s *VectorSink) run() {
+	defer close(s.stopped)
+	defer s.closeFallback()
+
+	flushTicker := time.NewTicker(s.cfg.FlushEvery)
+	defer flushTicker.Stop()
+	healthTicker := time.NewTicker(s.cfg.HealthInterval)
+	defer healthTicker.Stop()
+
+	var batch [][]byte
+	flush := func() {
+		if len(batch) > 0 {
+			s.deliver(batch)
+			batch = nil
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case line := <-s.queue:
+				batch = append(batch, line)
+				if len(batch) >= s.cfg.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case line := <-s.queue:
+			batch = append(batch, line)
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-healthTicker.C:
+			s.probe()
+		case ack := <-s.flushCh:
+			drain()
+			close(ack)
+		case <-s.done:
+			drain()
+			return
+		}
+	}
+}
+
+// probe runs the periodic health check and replays the fallback file after recovery
+func (s *VectorSink) probe() {
+	err := s.checkHealth()
+	switch {
+	case err != nil && s.healthy.Load():
+		s.setHealthy(false, err)
+	case err == nil && !s.healthy.Load():
+		s.setHealthy(true, nil)
+		s.replayFallback()
+	}
+}
+
+func (s *VectorSink) setHealthy(healthy bool, cause error) {
+	s.healthy.Store(healthy)
+	s.transitions.Add(1)
+
+	if healthy {
+		s.logger.Infow("Vector is healthy again, switching back from file fallback", "ingest_url", s.cfg.IngestURL)
+		return
+	}
+	s.logger.Warnw("Vector unhealthy, switching to file fallback",
+		"ingest_url", s.cfg.IngestURL,
+		"fallback_path", s.cfg.FallbackPath,
+		"error", cause.Error(),
+	)
+}
+
+// deliver posts to Vector when healthy; any failure marks it unhealthy and diverts the batch to the file
+func (s *VectorSink) deliver(batch [][]byte) {
+	if s.healthy.Load() {
+		err := s.post(batch)
+		if err == nil {
+			s.delivered.Add(uint64(len(batch)))
+			s.logger.Debugw("Batch delivered to Vector", "lines", len(batch))
+			return
+		}
+		s.setHealthy(false, err)
+	}
+	s.writeFallback(batch)
+}
+
+func (s *VectorSink) post(batch [][]byte) error {
+	body := bytes.Join(batch, []byte("\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.IngestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("vector returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *VectorSink) checkHealth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.HealthURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *VectorSink) writeFallback(batch [][]byte) {
+	if s.fallback == nil {
+		file, err := os.OpenFile(s.cfg.FallbackPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			s.dropped.Add(uint64(len(batch)))
+			s.logger.Errorw("Failed to open fallback file, lines lost", "path", s.cfg.FallbackPath, "error", err.Error())
+			return
+		}
+		s.fallback = file
+	}
+
+	w := bufio.NewWriter(s.fallback)
+	for _, line := range batch {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		s.logger.Errorw("Failed to write fallback file", "path", s.cfg.FallbackPath, "error", err.Error())
+		return
+	}
+	s.fellBack.Add(uint64(len(batch)))
+}
+
+// replayFallback sends lines buffered on disk to Vector and truncates the file on success
+func (s *VectorSink) replayFallback() {
+	s.closeFallback()
+
+	file, err := os.Open(s.cfg.FallbackPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		s.logger.Errorw("Failed to open fallback file for replay", "path", s.cfg.FallbackPath, "error", err.Error())
+		return
+	}
+	defer file.Close()
+
+	start := time.Now()
+	replayed := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var batch [][]byte
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.post(batch); err != nil {
+			return err
+		}
+		replayed += len(batch)
+		batch = nil
+		return nil
+	}
+
+	for scanner.Scan() {
+		batch = append(batch, append([]byte(nil), scanner.Bytes()...))
+		if len(batch) >= s.cfg.BatchSize {
+			if err := send(); err != nil {
+				s.replayFailed(replayed, err)
+				return
+			}
+		}
+	}
+	if err := send(); err != nil {
+		s.replayFailed(replayed, err)
+		return
+	}
+
+	if err := os.Truncate(s.cfg.FallbackPath, 0); err != nil {
+		s.logger.Errorw("Failed to truncate fallback file after replay", "path", s.cfg.FallbackPath, "error", err.Error())
+	}
+	s.replayed.Add(uint64(replayed))
+	s.logger.Infow("Replayed fallback file to Vector",
+		"path", s.cfg.FallbackPath,
+		"lines", replayed,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+// replayFailed keeps the whole file for the next recovery; Vector may see the replayed prefix twice
+func (s *VectorSink) replayFailed(replayed int, err error) {
+	s.logger.Warnw("Fallback replay interrupted, keeping file for next recovery",
+		"path", s.cfg.FallbackPath,
+		"replayed_before_failure", replayed,
+		"error", err.Error(),
+	)
+	s.setHealthy(false, err)
+}
+
+func (s *VectorSink) closeFallback() {
+	if s.fallback != nil {
+		s.fallback.Close()
+		s.fallback = nil
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"github.com/kart-io/go-example/pkg/devconsole"
+	"github.com/kart-io/go-example/pkg/outputlevels"
+	"github.com/kart-io/go-example/pkg/secretref"
+	"github.com/kart-io/go-example/pkg/timefmt"
 	"github.com/kart-io/logger/option"
 )
 
@@ -14,6 +19,41 @@ type Config struct {
 	Server ServerConfig `mapstructure:"server" yaml:"server" json:"server"`
 	Service ServiceConfig `mapstructure:"service" yaml:"service" json:"service"`
 	Logger option.LogOption `mapstructure:"logger" yaml:"logger" json:"logger"`
+	Monitoring MonitoringConfig `mapstructure:"monitoring" yaml:"monitoring" json:"monitoring"`
+	// OutputLevels is logger.output_levels, read apart from Logger, which has no such field
+	OutputLevels []OutputLevel `mapstructure:"-" yaml:"-" json:"output_levels,omitempty"`
+	// Streams is logger.streams, read apart from Logger like OutputLevels
+	Streams StreamsConfig `mapstructure:"-" yaml:"-" json:"streams"`
+	// Timestamp is logger.timestamp: the layout and time zone of the timestamp field
+	Timestamp timefmt.Config `mapstructure:"-" yaml:"-" json:"timestamp"`
+}
+
+// StreamsConfig splits console output by level for platforms that treat stderr as the
+// error stream: with Split, debug and info go to stdout, warn and above to stderr and
+// ErrorLog, if set
+type StreamsConfig struct {
+	Split    bool   `mapstructure:"split" yaml:"split" json:"split"`
+	ErrorLog string `mapstructure:"error_log" yaml:"error_log" json:"error_log,omitempty"`
+}
+
+// OutputLevel sets the minimum level of one logger output; Output is one of
+// logger.output_paths, or "otlp" for the OTLP exporter. A list rather than a map, since
+// viper lowercases map keys and splits them at dots.
+type OutputLevel struct {
+	Output string `mapstructure:"output" yaml:"output" json:"output"`
+	Level  string `mapstructure:"level" yaml:"level" json:"level"`
+}
+
+// LogOutputLevels returns OutputLevels by output, for outputlevels.New
+func (c *Config) LogOutputLevels() map[string]string {
+	if len(c.OutputLevels) == 0 {
+		return nil
+	}
+	levels := make(map[string]string, len(c.OutputLevels))
+	for _, ol := range c.OutputLevels {
+		levels[ol.Output] = ol.Level
+	}
+	return levels
 }
 
 // ServerConfig contains server-specific settings
@@ -30,6 +70,14 @@ type ServiceConfig struct {
 	Description string `mapstructure:"description" yaml:"description" json:"description"`
 }
 
+// MonitoringConfig contains runtime monitoring settings
+type MonitoringConfig struct {
+	// ResourceLogInterval logs goroutines, heap, open FDs and CPU at this interval; 0 disables it
+	ResourceLogInterval time.Duration `mapstructure:"resource_log_interval" yaml:"resource_log_interval" json:"resource_log_interval"`
+	// FDWatchInterval checks open FDs against RLIMIT_NOFILE at this interval; 0 disables it
+	FDWatchInterval time.Duration `mapstructure:"fd_watch_interval" yaml:"fd_watch_interval" json:"fd_watch_interval"`
+}
+
 
 
 // ConfigManager manages configuration loading and conversion
@@ -90,12 +138,31 @@ func (cm *ConfigManager) LoadConfig(configPath string, configName string) (*Conf
 	if err := v.Unmarshal(cm.config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := v.UnmarshalKey("logger.output_levels", &cm.config.OutputLevels); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: logger.output_levels: %w", err)
+	}
+	if err := v.UnmarshalKey("logger.streams", &cm.config.Streams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: logger.streams: %w", err)
+	}
+	if err := v.UnmarshalKey("logger.timestamp", &cm.config.Timestamp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: logger.timestamp: %w", err)
+	}
 	
 	// Validate configuration
 	if err := cm.validateConfig(); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 	
+	// Header values may reference a secret file or variable instead of holding the key;
+	// the logger's exporter is built once, so they are resolved once, here
+	if otlp := cm.config.Logger.OTLP; otlp != nil && secretref.HasRefs(otlp.Headers) {
+		headers, err := secretref.Resolve(otlp.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve OTLP headers: %w", err)
+		}
+		otlp.Headers = headers
+	}
+	
 	return cm.config, nil
 }
 
@@ -143,6 +210,10 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logger.disable_caller", false)
 	v.SetDefault("logger.disable_stacktrace", false)
 	v.SetDefault("logger.output_paths", []string{"stdout"})
+
+	// Monitoring defaults
+	v.SetDefault("monitoring.resource_log_interval", "0s")
+	v.SetDefault("monitoring.fd_watch_interval", "0s")
 }
 
 // validateConfig validates the loaded configuration
@@ -167,11 +238,34 @@ func (cm *ConfigManager) validateConfig() error {
 		return fmt.Errorf("invalid logger level: %s", config.Logger.Level)
 	}
 	
-	validFormats := map[string]bool{"json": true, "console": true}
+	validFormats := map[string]bool{"json": true, "console": true, devconsole.Format: true}
 	if !validFormats[config.Logger.Format] {
-		return fmt.Errorf("invalid logger format: %s (must be 'json' or 'console')", config.Logger.Format)
+		return fmt.Errorf("invalid logger format: %s (must be 'json', 'console' or '%s')", config.Logger.Format, devconsole.Format)
 	}
 	
+	if err := outputlevels.Validate(&config.Logger, config.LogOutputLevels()); err != nil {
+		return fmt.Errorf("invalid logger output levels: %w", err)
+	}
+	if config.Streams.Split && len(config.OutputLevels) > 0 {
+		return fmt.Errorf("invalid logger streams: split cannot be combined with output_levels")
+	}
+	if config.Streams.ErrorLog != "" && !config.Streams.Split {
+		return fmt.Errorf("invalid logger streams: error_log is only written with split")
+	}
+	if err := config.Timestamp.Validate(); err != nil {
+		return fmt.Errorf("invalid logger timestamp: %w", err)
+	}
+	if !config.Timestamp.IsZero() && config.Logger.Engine != "zap" {
+		return fmt.Errorf("invalid logger timestamp: needs the zap engine, not %s", config.Logger.Engine)
+	}
+
+	if config.Monitoring.ResourceLogInterval < 0 {
+		return fmt.Errorf("invalid resource log interval: %s", config.Monitoring.ResourceLogInterval)
+	}
+	if config.Monitoring.FDWatchInterval < 0 {
+		return fmt.Errorf("invalid fd watch interval: %s", config.Monitoring.FDWatchInterval)
+	}
+
 	// OTLP validation is handled by the logger package
 	
 	return nil
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kart-io/go-example/pkg/outputlevels"
+)
+
+// seeds are the shipped configurations plus the ways a hand-edited file usually breaks
+func seeds(f *testing.F) {
+	for _, name := range []string{"app.yaml", "production.yaml", "testing.yaml"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, data := range []string{
+		"",
+		"server: [",
+		"server:\n\tport: 8080",
+		"server:\n  port: http",
+		"server:\n  port: -1",
+		"server:\n  port: 99999999999999999999",
+		"logger:\n  engine: logrus",
+		"logger:\n  level: [info]",
+		"logger:\n  output_paths: stdout",
+		"logger:\n  output_paths: {a: b}",
+		"logger:\n  otlp:\n    timeout: soon",
+		"logger:\n  otlp:\n    headers: [a, b]",
+		"logger:\n  output_levels: {stdout: info}",
+		"logger:\n  output_levels:\n    - {output: logs/other.log, level: debug}",
+		"logger:\n  output_levels:\n    - {output: stdout, level: verbose}",
+		"logger:\n  output_levels:\n    - {output: otlp, level: warn}",
+		"logger:\n  streams: {split: yes, error_log: logs/error.log}",
+		"logger:\n  streams: {error_log: logs/error.log}",
+		"logger:\n  streams: [split]",
+		"logger:\n  engine: zap\n  timestamp: {layout: epoch_millis, timezone: local}",
+		"logger:\n  timestamp: {layout: rfc3339nano}",
+		"logger:\n  engine: zap\n  timestamp: {timezone: Mars/Olympus}",
+		"monitoring:\n  resource_log_interval: -5s",
+		"monitoring:\n  fd_watch_interval: forever",
+		"service: &a [*a]",
+		"? [a, b]\n: c",
+		"--- !!binary\nAAAA",
+		"\x00\xff",
+	} {
+		f.Add([]byte(data))
+	}
+}
+
+// errorPrefixes are how LoadConfig says which step failed; every error names one
+var errorPrefixes = []string{"failed to read config file", "failed to unmarshal config", "config validation failed", "failed to resolve OTLP headers"}
+
+// checkResult fails unless err names the step that failed, or the configuration that
+// loaded passes the same checks validateConfig makes
+func checkResult(t *testing.T, cfg *Config, err error) {
+	t.Helper()
+	if err != nil {
+		for _, prefix := range errorPrefixes {
+			if strings.HasPrefix(err.Error(), prefix+": ") && len(err.Error()) > len(prefix)+2 {
+				return
+			}
+		}
+		t.Fatalf("error does not say what failed: %q", err)
+	}
+	if cfg == nil {
+		t.Fatal("nil configuration without an error")
+	}
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		t.Errorf("accepted port %d", cfg.Server.Port)
+	}
+	if cfg.Logger.Engine != "zap" && cfg.Logger.Engine != "slog" {
+		t.Errorf("accepted engine %q", cfg.Logger.Engine)
+	}
+	if cfg.Monitoring.ResourceLogInterval < 0 || cfg.Monitoring.FDWatchInterval < 0 {
+		t.Errorf("accepted negative interval %+v", cfg.Monitoring)
+	}
+	if err := outputlevels.Validate(&cfg.Logger, cfg.LogOutputLevels()); err != nil {
+		t.Errorf("accepted output levels %+v: %v", cfg.OutputLevels, err)
+	}
+	if err := cfg.Timestamp.Validate(); err != nil {
+		t.Errorf("accepted timestamp %+v: %v", cfg.Timestamp, err)
+	}
+	if cfg.Streams.ErrorLog != "" && !cfg.Streams.Split {
+		t.Errorf("accepted error_log %q without split", cfg.Streams.ErrorLog)
+	}
+}
+
+func writeConfig(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fuzz.yaml")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func FuzzLoadConfigFromFile(f *testing.F) {
+	seeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cfg, logOption, err := LoadConfigFromFile(writeConfig(t, data))
+		checkResult(t, cfg, err)
+		if err == nil && logOption != &cfg.Logger {
+			t.Error("log option is not the loaded logger section")
+		}
+	})
+}
+
+// FuzzLoadConfig feeds APP_* overrides on top of the file, since the environment wins
+// over it and is checked the same way
+func FuzzLoadConfig(f *testing.F) {
+	seeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// One input drives both: the file as is, and its lines as override values
+		lines := strings.Split(string(data), "\n")
+		for i, env := range []string{
+			"APP_SERVER_PORT",
+			"APP_LOGGER_ENGINE",
+			"APP_LOGGER_LEVEL",
+			"APP_LOGGER_FORMAT",
+			"APP_MONITORING_RESOURCE_LOG_INTERVAL",
+		} {
+			if i >= len(lines) || strings.ContainsRune(lines[i], 0) {
+				continue
+			}
+			t.Setenv(env, lines[i])
+		}
+
+		path := writeConfig(t, data)
+		cfg, err := NewConfigManager().LoadConfig(filepath.Dir(path), "fuzz")
+		checkResult(t, cfg, err)
+	})
+}
@@ -1,57 +1,80 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/kart-io/logger"
 	"github.com/kart-io/logger/core"
 	"github.com/kart-io/logger/option"
 	"github.com/kart-io/version"
 
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/outputlevels"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/go-example/pkg/timefmt"
 	"github.com/kart-io/go-example/viper-config-demo/config"
 )
 
 func main() {
-	fmt.Println("=== Viper Configuration Demo ===")
-	fmt.Printf("Starting application with configuration-driven logging\n\n")
-
-	// Load configuration based on environment or command line argument
-	var configFile string
-	if len(os.Args) > 1 {
-		configFile = os.Args[1]
-		fmt.Printf("📁 Loading config from argument: %s\n", configFile)
+	cliflags.Parse()
+	console.Println("=== Viper Configuration Demo ===")
+	console.Printf("Starting application with configuration-driven logging\n\n")
+
+	// Load configuration from --config, a file named on the command line, or APP_ENV
+	configFile := cliflags.ConfigOr(flag.Arg(0))
+	if configFile != "" {
+		console.Printf("📁 Loading config from argument: %s\n", configFile)
 	} else {
 		env := os.Getenv("APP_ENV")
 		if env == "" {
 			env = "app"
 		}
 		configFile = env + ".yaml"
-		fmt.Printf("📁 Loading config from environment (%s): %s\n", env, configFile)
+		console.Printf("📁 Loading config from environment (%s): %s\n", env, configFile)
 	}
 
 	// Load configuration and create logger option
 	appConfig, logOption, err := config.LoadConfigFromFile(configFile)
 	if err != nil {
-		fmt.Printf("❌ Failed to load configuration: %v\n", err)
-		fmt.Println("Available config files:")
-		fmt.Println("  - app.yaml (development)")
-		fmt.Println("  - production.yaml")
-		fmt.Println("  - testing.yaml")
-		fmt.Println("\nUsage: go run main.go [config-file]")
-		fmt.Println("   or: APP_ENV=production go run main.go")
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		console.Println("Available config files:")
+		console.Println("  - app.yaml (development)")
+		console.Println("  - production.yaml")
+		console.Println("  - testing.yaml")
+		console.Println("\nUsage: go run main.go [--config config-file | config-file]")
+		console.Println("   or: APP_ENV=production go run main.go")
 		os.Exit(1)
 	}
+	// Command-line flags override both the file and APP_* variables
+	if port := cliflags.PortOr(""); port != "" {
+		appConfig.Server.Port, _ = strconv.Atoi(port)
+	}
+	cliflags.Apply(logOption)
 
 	// Show loaded configuration
-	fmt.Printf("✅ Configuration loaded successfully\n")
-	fmt.Printf("   Engine: %s\n", logOption.Engine)
-	fmt.Printf("   Level: %s\n", logOption.Level)
-	fmt.Printf("   Format: %s\n", logOption.Format)
-	fmt.Printf("   Output Paths: %v\n", logOption.OutputPaths)
+	console.Printf("✅ Configuration loaded successfully\n")
+	console.Printf("   Engine: %s\n", logOption.Engine)
+	console.Printf("   Level: %s\n", logOption.Level)
+	console.Printf("   Format: %s\n", logOption.Format)
+	console.Printf("   Output Paths: %v\n", logOption.OutputPaths)
 	// Extract service information for display (from config file and version package)
 	serviceName := appConfig.Service.Name
 	serviceVersion := appConfig.Service.Version
@@ -65,13 +88,13 @@ func main() {
 		serviceVersion = versionInfo.GitVersion
 	}
 
-	fmt.Printf("   Service: %s v%s\n", serviceName, serviceVersion)
+	console.Printf("   Service: %s v%s\n", serviceName, serviceVersion)
 	if logOption.IsOTLPEnabled() {
-		fmt.Printf("   OTLP: %s (%s)\n", logOption.OTLPEndpoint, logOption.OTLP.Protocol)
+		console.Printf("   OTLP: %s (%s)\n", logOption.OTLPEndpoint, logOption.OTLP.Protocol)
 	} else {
-		fmt.Printf("   OTLP: disabled\n")
+		console.Printf("   OTLP: disabled\n")
 	}
-	fmt.Println()
+	console.Println()
 
 	// Service info is now handled automatically via version package and -ldflags injection
 	// No need to override OTLP service fields manually
@@ -85,12 +108,42 @@ func main() {
 	}).AddInitialField("commit", getShortCommit(versionInfo.GitCommit)).
 		AddInitialField("build_date", versionInfo.BuildDate)
 
+	checked := []*option.LogOption{logOption}
+	if appConfig.Streams.ErrorLog != "" {
+		checked = append(checked, &option.LogOption{OutputPaths: []string{appConfig.Streams.ErrorLog}})
+	}
+	outputs := outputcheck.Verify(context.Background(), checked...)
+	// logger.timestamp wraps the output paths in a sink that rewrites the timestamp field
+	if err := timefmt.Apply(logOption, appConfig.Timestamp); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to configure log timestamps: %v\n", err)
+		os.Exit(1)
+	}
 	// Create logger with all initial fields
-	serviceLogger, err := logger.New(logOption)
+	// logger.output_levels gives stdout, files and OTLP their own minimum levels;
+	// logger.streams.split sends warnings and errors to stderr instead
+	var serviceLogger core.Logger
+	if appConfig.Streams.Split {
+		serviceLogger, err = outputlevels.Split(logOption, appConfig.Streams.ErrorLog)
+	} else {
+		serviceLogger, err = outputlevels.New(logOption, appConfig.LogOutputLevels())
+	}
 	if err != nil {
-		fmt.Printf("❌ Failed to initialize logger with initial fields: %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ Failed to initialize logger with initial fields: %v\n", err)
 		os.Exit(1)
 	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+
+	// monitoring.resource_log_interval, or APP_MONITORING_RESOURCE_LOG_INTERVAL to override it
+	if interval := appConfig.Monitoring.ResourceLogInterval; interval > 0 {
+		defer resusage.Start(serviceLogger.With("component", "resusage"), interval)()
+	}
+	if interval := appConfig.Monitoring.FDWatchInterval; interval > 0 {
+		defer resusage.StartFDMonitor(serviceLogger.With("component", "resusage"), interval)()
+	}
+	defer pidfile.FromEnv(serviceLogger)()
 
 	// Log startup information
 	serviceLogger.Infow("Application starting",
@@ -107,14 +160,57 @@ func main() {
 		gin.SetMode(gin.TestMode)
 	}
 
+	// gin.Default prints its route table and request lines to stdout; keep them with the banners
+	gin.DefaultWriter = console.Writer()
+	r := newRouter(serviceLogger, appConfig, logOption, configFile, summary.Middleware())
+
+	// Start server
+	port := ":" + strconv.Itoa(appConfig.Server.Port)
+
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption,
+		"port", port,
+		"environment", appConfig.Server.Environment,
+		"config_file", configFile,
+	)
+	serviceLogger.Infow("Starting server",
+		"port", port,
+		"environment", appConfig.Server.Environment,
+		"endpoints", []string{"/", "/health", "/version", "/config", "/logger/test"},
+		"logger_config", fmt.Sprintf("%s/%s/%s", logOption.Engine, logOption.Level, logOption.Format),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Failed to start server", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// newRouter serves the demo API for the loaded configuration behind middleware;
+// /debug/config exists only in the development environment
+func newRouter(serviceLogger core.Logger, appConfig *config.Config, logOption *option.LogOption, configFile string, middleware ...gin.HandlerFunc) *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware...)
 
-	// Add middleware for request logging
-	r.Use(loggingMiddleware(serviceLogger))
+	// Add middleware for request logging; handlers log through the request logger, which
+	// carries request_id, method, route and client_ip
+	r.Use(logcontext.GinRequestLogger(serviceLogger), loggingMiddleware())
 
 	// Routes
 	r.GET("/", func(c *gin.Context) {
-		serviceLogger.Infow("Handling root request", "endpoint", "/", "method", "GET")
+		logcontext.MustFromGin(c).Infow("Handling root request")
 		c.JSON(http.StatusOK, gin.H{
 			"message":     "Viper Configuration Demo API",
 			"service":     appConfig.Service.Name,
@@ -126,27 +222,22 @@ func main() {
 	})
 
 	r.GET("/health", func(c *gin.Context) {
-		serviceLogger.Debugw("Health check requested", "endpoint", "/health")
-		c.JSON(http.StatusOK, gin.H{
-			"status":      "healthy",
-			"service":     appConfig.Service.Name,
-			"version":     appConfig.Service.Version,
-			"environment": appConfig.Server.Environment,
-			"uptime":      "running",
-		})
-	})
+		logcontext.MustFromGin(c).Debugw("Health check requested")
+	}, health.ServiceHandler(appConfig.Service.Name, appConfig.Service.Version, map[string]health.Checker{
+		"config": func(ctx context.Context) health.Check {
+			return health.Healthy(map[string]interface{}{
+				"environment": appConfig.Server.Environment,
+				"config_file": configFile,
+			})
+		},
+	}))
 
-	r.GET("/version", func(c *gin.Context) {
-		serviceLogger.Infow("Version info requested", "endpoint", "/version", "method", "GET")
-		c.JSON(http.StatusOK, gin.H{
-			"build_info":  versionInfo,
-			"config_info": appConfig.Service,
-			"server_info": appConfig.Server,
-		})
-	})
+	// Build information is served like in every other demo; the service and server
+	// sections of the configuration are part of /config
+	r.GET("/version", buildinfo.Handler())
 
 	r.GET("/config", func(c *gin.Context) {
-		serviceLogger.Infow("Configuration info requested", "endpoint", "/config")
+		logcontext.MustFromGin(c).Infow("Configuration info requested")
 
 		// Return sanitized configuration (without sensitive data)
 		sanitizedConfig := sanitizeConfig(appConfig)
@@ -157,16 +248,17 @@ func main() {
 	})
 
 	r.GET("/logger/test", func(c *gin.Context) {
-		serviceLogger.Infow("Logger test endpoint accessed", "endpoint", "/logger/test")
+		log := logcontext.MustFromGin(c)
+		log.Infow("Logger test endpoint accessed")
 
 		// Test all log levels
-		serviceLogger.Debug("This is a debug message")
-		serviceLogger.Info("This is an info message")
-		serviceLogger.Warn("This is a warning message")
-		serviceLogger.Error("This is an error message (simulated)")
+		log.Debug("This is a debug message")
+		log.Info("This is an info message")
+		log.Warn("This is a warning message")
+		log.Error("This is an error message (simulated)")
 
 		// Test structured logging
-		serviceLogger.Infow("Structured logging test",
+		log.Infow("Structured logging test",
 			"user_id", "12345",
 			"action", "test_logging",
 			"timestamp", "2025-09-01T15:00:00Z",
@@ -183,42 +275,28 @@ func main() {
 	// Environment-specific routes
 	if appConfig.Server.Environment == "development" {
 		r.GET("/debug/config", func(c *gin.Context) {
-			serviceLogger.Debugw("Debug config endpoint accessed", "endpoint", "/debug/config")
+			logcontext.MustFromGin(c).Debugw("Debug config endpoint accessed")
 			c.JSON(http.StatusOK, gin.H{
-				"raw_config": appConfig,
+				"raw_config": sanitizeConfig(appConfig),
 				"log_option": sanitizeLogOption(logOption),
 				"env_vars":   getRelevantEnvVars(),
 			})
 		})
 	}
 
-	// Start server
-	port := ":" + strconv.Itoa(appConfig.Server.Port)
-
-	serviceLogger.Infow("Starting server",
-		"port", port,
-		"environment", appConfig.Server.Environment,
-		"endpoints", []string{"/", "/health", "/version", "/config", "/logger/test"},
-		"logger_config", fmt.Sprintf("%s/%s/%s", logOption.Engine, logOption.Level, logOption.Format),
-	)
-
-	if err := r.Run(port); err != nil {
-		serviceLogger.Fatalw("Failed to start server", "error", err.Error(), "port", port)
-	}
+	return r
 }
 
-// loggingMiddleware creates a Gin middleware for request logging
-func loggingMiddleware(logger core.Logger) gin.HandlerFunc {
+// loggingMiddleware creates a Gin middleware for request logging through the request logger
+func loggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Process request
 		c.Next()
 
 		// Log request details
-		logger.Infow("HTTP request processed",
-			"method", c.Request.Method,
+		logcontext.MustFromGin(c).Infow("HTTP request processed",
 			"path", c.Request.URL.Path,
 			"status", c.Writer.Status(),
-			"client_ip", c.ClientIP(),
 			"user_agent", c.Request.UserAgent(),
 		)
 	}
@@ -230,17 +308,9 @@ func sanitizeConfig(cfg *config.Config) *config.Config {
 
 	// Remove sensitive OTLP headers from logger config
 	if sanitized.Logger.OTLP != nil && sanitized.Logger.OTLP.Headers != nil {
-		sanitizedHeaders := make(map[string]string)
-		for k, v := range sanitized.Logger.OTLP.Headers {
-			if k == "x-api-key" || k == "authorization" {
-				sanitizedHeaders[k] = "***REDACTED***"
-			} else {
-				sanitizedHeaders[k] = v
-			}
-		}
 		// Create a copy of the OTLP config to avoid modifying the original
 		otlpCopy := *sanitized.Logger.OTLP
-		otlpCopy.Headers = sanitizedHeaders
+		otlpCopy.Headers = sanitize.Headers(sanitized.Logger.OTLP.Headers)
 		sanitized.Logger.OTLP = &otlpCopy
 	}
 
@@ -249,17 +319,8 @@ func sanitizeConfig(cfg *config.Config) *config.Config {
 
 // sanitizeLogOption removes sensitive information from log option
 func sanitizeLogOption(opt *option.LogOption) map[string]interface{} {
-	result := map[string]interface{}{
-		"engine":             opt.Engine,
-		"level":              opt.Level,
-		"format":             opt.Format,
-		"development":        opt.Development,
-		"disable_caller":     opt.DisableCaller,
-		"disable_stacktrace": opt.DisableStacktrace,
-		"output_paths":       opt.OutputPaths,
-		"otlp_enabled":       opt.IsOTLPEnabled(),
-		"otlp_endpoint":      opt.OTLPEndpoint,
-	}
+	result := sanitize.LogOption(opt)
+	result["otlp"] = sanitize.OTLP(opt)
 
 	// Service info is handled via version package and -ldflags injection
 	// Add version info for API response
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/logger/option"
+
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/testlog"
+	"github.com/kart-io/go-example/viper-config-demo/config"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// testConfig is app.yaml's shape with an OTLP header that must never be served
+func testConfig(environment string) (*config.Config, *option.LogOption) {
+	logOption := &option.LogOption{
+		Engine: "slog",
+		Level:  "debug",
+		Format: "json",
+		OTLP: &option.OTLPOption{
+			Endpoint: "localhost:4317",
+			Headers:  map[string]string{"Authorization": "Bearer otlp-secret-token"},
+		},
+	}
+	appConfig := &config.Config{
+		Server:  config.ServerConfig{Port: 8084, Name: "viper-config-demo", Environment: environment},
+		Service: config.ServiceConfig{Name: "viper-config-api", Version: "v1.0.0", Description: "Viper configuration demo"},
+		Logger:  *logOption,
+	}
+	return appConfig, logOption
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		target      string
+		status      int
+		body        string
+		// absent must not appear in the body
+		absent  string
+		level   string
+		message string
+		fields  []interface{}
+	}{
+		{name: "root", environment: "development", target: "/", status: http.StatusOK, body: `"config_file":"app.yaml"`,
+			level: "info", message: "Handling root request", fields: []interface{}{"route", "/"}},
+		{name: "health", environment: "development", target: "/health", status: http.StatusOK, body: `"service":"viper-config-api"`,
+			level: "debug", message: "Health check requested", fields: []interface{}{"route", "/health"}},
+		{name: "version", environment: "development", target: "/version", status: http.StatusOK, body: `"go_version"`},
+		{name: "config is sanitized", environment: "development", target: "/config", status: http.StatusOK, body: `"loaded_from":"app.yaml"`,
+			absent: "otlp-secret-token", level: "info", message: "Configuration info requested"},
+		{name: "logger test", environment: "development", target: "/logger/test", status: http.StatusOK, body: `"levels_tested"`,
+			level: "info", message: "Structured logging test", fields: []interface{}{"user_id", "12345", "success", true}},
+		{name: "debug config in development", environment: "development", target: "/debug/config", status: http.StatusOK, body: `"log_option"`,
+			absent: "otlp-secret-token", level: "debug", message: "Debug config endpoint accessed"},
+		{name: "no debug config in production", environment: "production", target: "/debug/config", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := testlog.New(t)
+			appConfig, logOption := testConfig(tt.environment)
+			w := httptest.NewRecorder()
+			newRouter(rec.Logger, appConfig, logOption, "app.yaml").ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", w.Body, tt.body)
+			}
+			if tt.absent != "" && strings.Contains(w.Body.String(), tt.absent) {
+				t.Errorf("body leaks %q: %s", tt.absent, w.Body)
+			}
+			if tt.message != "" {
+				rec.AssertLogged(tt.level, tt.message, tt.fields...)
+			}
+			rec.AssertLogged("info", "HTTP request processed", "method", "GET", "path", tt.target, "status", tt.status)
+		})
+	}
+}
+
+func TestLoggerTestCoversLevels(t *testing.T) {
+	rec := testlog.New(t)
+	appConfig, logOption := testConfig("development")
+	newRouter(rec.Logger, appConfig, logOption, "app.yaml").ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/logger/test", nil))
+
+	for level, message := range map[string]string{
+		"debug": "This is a debug message",
+		"info":  "This is an info message",
+		"warn":  "This is a warning message",
+		"error": "This is an error message",
+	} {
+		rec.AssertLogged(level, message)
+	}
+}
+
+func TestHealthFollowsContract(t *testing.T) {
+	appConfig, logOption := testConfig("testing")
+	w := httptest.NewRecorder()
+	newRouter(testlog.New(t).Logger, appConfig, logOption, "testing.yaml").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if problems := health.Validate(w.Body.Bytes()); problems != nil {
+		t.Errorf("/health breaks the contract: %v\n%s", problems, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), `"environment":"testing"`) {
+		t.Errorf("config check without the environment: %s", w.Body)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/audit"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/logcontext"
+	"github.com/kart-io/go-example/pkg/metrics"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/core"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+const (
+	signatureHeader  = "X-Signature-256"
+	deliveryHeader   = "X-Delivery-ID"
+	eventTypeHeader  = "X-Event-Type"
+	adminTokenHeader = "X-Admin-Token"
+
+	// maxPayloadBytes limits the size of accepted webhook bodies
+	maxPayloadBytes = 1 << 20
+)
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Webhook Receiver Demo ===")
+	console.Println("HMAC signature verification, payload persistence and admin replay")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "slog",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	secret := getEnvOrDefault("WEBHOOK_SECRET", "demo-secret")
+	// The admin routes list and replay stored payloads; ADMIN_TOKEN has no default and
+	// without it they are not served at all
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	storePath := filepath.Join("data", "webhook-events.jsonl")
+
+	store, err := NewEventStore(storePath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to open event store", "path", storePath, "error", err.Error())
+	}
+	serviceLogger.Infow("Event store loaded", "path", storePath, "stored_events", store.Count())
+
+	// Signature checks and admin requests go to the audit trail in AUDIT_LOG_FILE
+	auditPath := getEnvOrDefault("AUDIT_LOG_FILE", "logs/audit.log")
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		crash.Fatal(serviceLogger, "Failed to open audit log", "path", auditPath, "error", err.Error())
+	}
+	defer auditLog.Close()
+
+	dispatcher := &Dispatcher{logger: serviceLogger.With("component", "dispatcher")}
+
+	gin.SetMode(gin.ReleaseMode)
+	reg := metrics.New("webhook-demo")
+	reg.GaugeFunc("webhook_stored_events", "Events in the replay store.", func() float64 { return float64(store.Count()) })
+
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery(), reg.RED(), logcontext.GinRequestLogger(serviceLogger))
+
+	r.POST("/webhooks/:source", func(c *gin.Context) {
+		source := c.Param("source")
+		deliveryID := c.GetHeader(deliveryHeader)
+		eventType := c.GetHeader(eventTypeHeader)
+		requestLogger := logcontext.MustFromGin(c).With(webhookFields(c)...)
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPayloadBytes+1))
+		if err != nil {
+			requestLogger.Errorw("Failed to read webhook body", "error", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable body"})
+			return
+		}
+		if len(body) > maxPayloadBytes {
+			requestLogger.Warnw("Webhook payload too large", "max_bytes", maxPayloadBytes)
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "payload too large"})
+			return
+		}
+
+		// The sender is the actor: it is who the secret authenticates
+		reason := verifySignature(secret, c.GetHeader(signatureHeader), body)
+		check := audit.GinEvent(c, audit.ActionWebhookSignature, audit.OutcomeSuccess, reason)
+		check.Actor = source
+		if reason != "" {
+			check.Outcome = audit.OutcomeDenied
+		}
+		auditLog.RecordOrLog(check, requestLogger)
+		if reason != "" {
+			requestLogger.Warnw("Webhook signature verification failed",
+				"reason", reason,
+				"payload_bytes", len(body),
+			)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		if deliveryID == "" || eventType == "" {
+			requestLogger.Warnw("Webhook missing required headers",
+				"required_headers", []string{deliveryHeader, eventTypeHeader},
+			)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing delivery or event type header"})
+			return
+		}
+
+		if !json.Valid(body) {
+			requestLogger.Warnw("Webhook payload is not valid JSON", "payload_bytes", len(body))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "payload must be JSON"})
+			return
+		}
+
+		event := StoredEvent{
+			ID:         deliveryID,
+			Source:     source,
+			EventType:  eventType,
+			ReceivedAt: time.Now().UTC(),
+			Payload:    json.RawMessage(body),
+		}
+
+		stored, err := store.Append(event)
+		if err != nil {
+			requestLogger.Errorw("Failed to persist webhook event", "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to persist event"})
+			return
+		}
+		if !stored {
+			requestLogger.Infow("Duplicate webhook delivery ignored")
+			c.JSON(http.StatusOK, gin.H{"status": "duplicate", "id": deliveryID})
+			return
+		}
+
+		requestLogger.Infow("Webhook accepted",
+			"payload_bytes", len(body),
+			"stored_events", store.Count(),
+		)
+
+		dispatcher.Dispatch(event, false)
+
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "id": deliveryID})
+	})
+
+	endpoints := []string{"/webhooks/:source", "/health", "/version", "/metrics"}
+	if adminToken != "" {
+		admin := r.Group("/admin", auditLog.GinMiddleware(audit.ActionAdminRequest, serviceLogger), adminAuth(adminToken))
+
+		admin.GET("/events", func(c *gin.Context) {
+			since, err := parseSince(c.Query("since"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			events := store.List(since)
+			logcontext.MustFromGin(c).Infow("Stored events listed", "since", since, "count", len(events))
+			c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+		})
+
+		admin.POST("/replay/:id", func(c *gin.Context) {
+			log := logcontext.MustFromGin(c)
+			id := c.Param("id")
+			event, ok := store.Get(id)
+			if !ok {
+				log.Warnw("Replay requested for unknown event", "delivery_id", id)
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found", "id": id})
+				return
+			}
+
+			replays := store.MarkReplayed(id)
+			log.Infow("Replaying stored event",
+				"delivery_id", id,
+				"webhook_source", event.Source,
+				"event_type", event.EventType,
+				"replay_count", replays,
+			)
+			dispatcher.Dispatch(event, true)
+
+			c.JSON(http.StatusOK, gin.H{"status": "replayed", "id": id, "replays": replays})
+		})
+
+		admin.POST("/replay", func(c *gin.Context) {
+			since, err := parseSince(c.Query("since"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			log := logcontext.MustFromGin(c)
+			events := store.List(since)
+			log.Infow("Bulk replay started", "since", since, "events", len(events))
+
+			start := time.Now()
+			for i, event := range events {
+				store.MarkReplayed(event.ID)
+				dispatcher.Dispatch(event, true)
+				log.Debugw("Bulk replay progress", "replayed", i+1, "total", len(events))
+			}
+
+			log.Infow("Bulk replay completed",
+				"events", len(events),
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			c.JSON(http.StatusOK, gin.H{"status": "replayed", "count": len(events)})
+		})
+		endpoints = append(endpoints, "/admin/events", "/admin/replay", "/admin/replay/:id")
+	} else {
+		serviceLogger.Warnw("Admin routes disabled", "reason", "ADMIN_TOKEN not set")
+	}
+
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/metrics", reg.GinHandler())
+	r.GET("/health", health.Handler(map[string]health.Checker{
+		"event_store": func(ctx context.Context) health.Check {
+			return health.Healthy(map[string]interface{}{"stored_events": store.Count()})
+		},
+	}))
+
+	port := getEnvOrDefault("PORT", "8090")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port, "store_path", storePath, "webhook_secret", secret, "admin_token", adminToken, "audit_log", auditPath)
+	serviceLogger.Infow("Starting webhook receiver",
+		"port", port,
+		"store_path", storePath,
+		"endpoints", endpoints,
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Send a signed webhook:")
+	console.Printf("  BODY='{\"order_id\":\"o-1001\",\"status\":\"paid\"}'\n")
+	console.Printf("  SIG=$(printf '%%s' \"$BODY\" | openssl dgst -sha256 -hmac %s | sed 's/^.* //')\n", secret)
+	console.Printf("  curl -X POST http://localhost:%s/webhooks/billing -H \"%s: sha256=$SIG\" -H \"%s: evt-1\" -H \"%s: order.paid\" -d \"$BODY\"\n",
+		port, signatureHeader, deliveryHeader, eventTypeHeader)
+	if adminToken != "" {
+		// The token itself stays out of the output
+		console.Println("Replay stored events:")
+		console.Printf("  curl -X POST http://localhost:%s/admin/replay/evt-1 -H \"%s: $ADMIN_TOKEN\"\n", port, adminTokenHeader)
+		console.Printf("  curl -X POST http://localhost:%s/admin/replay -H \"%s: $ADMIN_TOKEN\"\n", port, adminTokenHeader)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// Dispatcher hands accepted events to the (simulated) business handlers
+type Dispatcher struct {
+	logger core.Logger
+}
+
+// Dispatch processes an event; replay marks re-dispatches from the admin API
+func (d *Dispatcher) Dispatch(event StoredEvent, replay bool) {
+	start := time.Now()
+
+	// Simulate downstream processing
+	time.Sleep(5 * time.Millisecond)
+
+	d.logger.Infow("Event dispatched",
+		"delivery_id", event.ID,
+		"webhook_source", event.Source,
+		"event_type", event.EventType,
+		"replay", replay,
+		"age_ms", time.Since(event.ReceivedAt).Milliseconds(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+// webhookFields identifies a delivery in every log line written while handling it
+func webhookFields(c *gin.Context) []interface{} {
+	return []interface{}{
+		"webhook_source", c.Param("source"),
+		"delivery_id", c.GetHeader(deliveryHeader),
+		"event_type", c.GetHeader(eventTypeHeader),
+	}
+}
+
+// verifySignature checks a "sha256=<hex>" HMAC header and returns a failure reason, or "" if valid
+func verifySignature(secret, header string, body []byte) string {
+	if header == "" {
+		return "missing_signature"
+	}
+	if !strings.HasPrefix(header, "sha256=") {
+		return "unsupported_algorithm"
+	}
+
+	provided, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return "malformed_signature"
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(provided, mac.Sum(nil)) {
+		return "signature_mismatch"
+	}
+	return ""
+}
+
+// adminAuth protects the admin endpoints with a static token
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hmac.Equal([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) {
+			logcontext.MustFromGin(c).Warnw("Unauthorized admin request", "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// parseSince parses an optional RFC3339 "since" query parameter
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since parameter (expected RFC3339): %w", err)
+	}
+	return since, nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
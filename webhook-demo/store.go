@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StoredEvent is a webhook delivery that passed signature verification
+type StoredEvent struct {
+	ID         string          `json:"id"`
+	Source     string          `json:"source"`
+	EventType  string          `json:"event_type"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Payload    json.RawMessage `json:"payload"`
+	Replays    int             `json:"replays"`
+}
+
+// EventStore persists accepted webhook payloads as JSON lines. Events are only reachable
+// through the store's lock: Append keeps its own copy and Get and List return copies, since
+// MarkReplayed updates Replays while handlers encode what they got.
+type EventStore struct {
+	mu     sync.RWMutex
+	path   string
+	events []*StoredEvent
+	index  map[string]*StoredEvent
+}
+
+// NewEventStore opens (or creates) the store file and loads existing events
+func NewEventStore(path string) (*EventStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	store := &EventStore{
+		path:  path,
+		index: make(map[string]*StoredEvent),
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event StoredEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("corrupt store entry: %w", err)
+		}
+		store.events = append(store.events, &event)
+		store.index[event.ID] = &event
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	return store, nil
+}
+
+// Append persists a new event, rejecting duplicate delivery IDs
+func (s *EventStore) Append(event StoredEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.index[event.ID]; exists {
+		return false, nil
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open store: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return false, fmt.Errorf("failed to write event: %w", err)
+	}
+
+	s.events = append(s.events, &event)
+	s.index[event.ID] = &event
+	return true, nil
+}
+
+// Get returns a copy of a stored event by delivery ID
+func (s *EventStore) Get(id string) (StoredEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.index[id]
+	if !ok {
+		return StoredEvent{}, false
+	}
+	return *event, true
+}
+
+// List returns copies of the stored events received at or after since
+func (s *EventStore) List(since time.Time) []StoredEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]StoredEvent, 0, len(s.events))
+	for _, event := range s.events {
+		if !event.ReceivedAt.Before(since) {
+			result = append(result, *event)
+		}
+	}
+	return result
+}
+
+// MarkReplayed increments the in-memory replay counter for an event
+func (s *EventStore) MarkReplayed(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.index[id]
+	if !ok {
+		return 0
+	}
+	event.Replays++
+	return event.Replays
+}
+
+// Count returns the number of stored events
+func (s *EventStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.events)
+}
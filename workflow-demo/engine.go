@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kart-io/logger/core"
+)
+
+// Workflow statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// RetryPolicy controls how failed activity attempts are retried
+type RetryPolicy struct {
+	MaxAttempts        int
+	InitialInterval    time.Duration
+	BackoffCoefficient float64
+	MaxInterval        time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.InitialInterval)
+	for i := 1; i < attempt; i++ {
+		d *= p.BackoffCoefficient
+	}
+	if p.MaxInterval > 0 && time.Duration(d) > p.MaxInterval {
+		return p.MaxInterval
+	}
+	return time.Duration(d)
+}
+
+// Activity is a single unit of work inside a workflow
+type Activity struct {
+	Name    string
+	Fn      func(ctx context.Context, ac *ActivityContext) error
+	Timeout time.Duration
+	Retry   RetryPolicy
+}
+
+// WorkflowDefinition is an ordered list of activities
+type WorkflowDefinition struct {
+	Type       string
+	Activities []Activity
+}
+
+// ActivityContext is passed to activities so they can read input and report progress
+type ActivityContext struct {
+	WorkflowID string
+	Attempt    int
+	Input      map[string]interface{}
+	logger     core.Logger
+	started    time.Time
+}
+
+// Heartbeat reports progress of a long-running activity
+func (ac *ActivityContext) Heartbeat(progress string) {
+	ac.logger.Infow("Activity heartbeat",
+		"progress", progress,
+		"elapsed_ms", time.Since(ac.started).Milliseconds(),
+	)
+}
+
+// NonRetryableError stops retries regardless of the retry policy
+type NonRetryableError struct {
+	Reason string
+}
+
+func (e *NonRetryableError) Error() string {
+	return e.Reason
+}
+
+// HistoryEvent is one entry in a workflow's execution history
+type HistoryEvent struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Activity string    `json:"activity,omitempty"`
+	Attempt  int       `json:"attempt,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Execution is the observable state of a running or finished workflow
+type Execution struct {
+	WorkflowID      string                 `json:"workflow_id"`
+	Type            string                 `json:"type"`
+	Status          string                 `json:"status"`
+	CurrentActivity string                 `json:"current_activity,omitempty"`
+	Input           map[string]interface{} `json:"input"`
+	History         []HistoryEvent         `json:"history"`
+	StartedAt       time.Time              `json:"started_at"`
+	ClosedAt        *time.Time             `json:"closed_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// Engine runs workflows in-process and keeps their execution history
+type Engine struct {
+	logger core.Logger
+
+	mu         sync.RWMutex
+	executions map[string]*Execution
+}
+
+// NewEngine creates a workflow engine
+func NewEngine(logger core.Logger) *Engine {
+	return &Engine{
+		logger:     logger,
+		executions: make(map[string]*Execution),
+	}
+}
+
+// Start launches a workflow asynchronously and returns its execution snapshot
+func (e *Engine) Start(def WorkflowDefinition, workflowID string, input map[string]interface{}) (Execution, error) {
+	e.mu.Lock()
+	if _, exists := e.executions[workflowID]; exists {
+		e.mu.Unlock()
+		return Execution{}, fmt.Errorf("workflow %s already exists", workflowID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exec := &Execution{
+		WorkflowID: workflowID,
+		Type:       def.Type,
+		Status:     StatusRunning,
+		Input:      input,
+		StartedAt:  time.Now().UTC(),
+		cancel:     cancel,
+	}
+	e.executions[workflowID] = exec
+	snapshot := exec.snapshot()
+	e.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		e.run(ctx, def, exec)
+	}()
+
+	return snapshot, nil
+}
+
+// Cancel requests cancellation of a running workflow
+func (e *Engine) Cancel(workflowID string) error {
+	e.mu.RLock()
+	exec, ok := e.executions[workflowID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("workflow %s not found", workflowID)
+	}
+	exec.cancel()
+	return nil
+}
+
+// Get returns a snapshot of a workflow execution
+func (e *Engine) Get(workflowID string) (Execution, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	exec, ok := e.executions[workflowID]
+	if !ok {
+		return Execution{}, false
+	}
+	return exec.snapshot(), true
+}
+
+// ReportRunning logs every running workflow with its age and current activity
+func (e *Engine) ReportRunning(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		e.mu.RLock()
+		running := make([]Execution, 0)
+		for _, exec := range e.executions {
+			if exec.Status == StatusRunning {
+				running = append(running, exec.snapshot())
+			}
+		}
+		e.mu.RUnlock()
+
+		sort.Slice(running, func(i, j int) bool { return running[i].StartedAt.Before(running[j].StartedAt) })
+		for _, exec := range running {
+			e.logger.Infow("Workflow still running",
+				"workflow_id", exec.WorkflowID,
+				"workflow_type", exec.Type,
+				"current_activity", exec.CurrentActivity,
+				"age_ms", time.Since(exec.StartedAt).Milliseconds(),
+			)
+		}
+	}
+}
+
+func (e *Engine) run(ctx context.Context, def WorkflowDefinition, exec *Execution) {
+	wfLogger := e.logger.With("workflow_id", exec.WorkflowID, "workflow_type", def.Type)
+	wfLogger.Infow("Workflow started", "activities", len(def.Activities))
+	e.record(exec, HistoryEvent{Type: "workflow_started"})
+
+	for _, activity := range def.Activities {
+		e.setCurrent(exec, activity.Name)
+
+		if err := e.runActivity(ctx, wfLogger, exec, activity); err != nil {
+			status := StatusFailed
+			if errors.Is(err, context.Canceled) {
+				status = StatusCancelled
+			}
+			e.close(exec, status, HistoryEvent{Type: "workflow_" + status, Activity: activity.Name, Error: err.Error()})
+
+			logFn := wfLogger.Errorw
+			if status == StatusCancelled {
+				logFn = wfLogger.Warnw
+			}
+			logFn("Workflow finished",
+				"status", status,
+				"failed_activity", activity.Name,
+				"error", err.Error(),
+				"duration_ms", time.Since(exec.StartedAt).Milliseconds(),
+			)
+			return
+		}
+	}
+
+	e.close(exec, StatusCompleted, HistoryEvent{Type: "workflow_completed"})
+	wfLogger.Infow("Workflow finished",
+		"status", StatusCompleted,
+		"duration_ms", time.Since(exec.StartedAt).Milliseconds(),
+	)
+}
+
+// runActivity executes one activity with timeout and retries
+func (e *Engine) runActivity(ctx context.Context, wfLogger core.Logger, exec *Execution, activity Activity) error {
+	var lastErr error
+	for attempt := 1; attempt <= activity.Retry.MaxAttempts; attempt++ {
+		actLogger := wfLogger.With("activity", activity.Name, "attempt", attempt)
+		ac := &ActivityContext{
+			WorkflowID: exec.WorkflowID,
+			Attempt:    attempt,
+			Input:      exec.Input,
+			logger:     actLogger,
+			started:    time.Now(),
+		}
+
+		actLogger.Infow("Activity started",
+			"max_attempts", activity.Retry.MaxAttempts,
+			"timeout", activity.Timeout.String(),
+		)
+		e.record(exec, HistoryEvent{Type: "activity_started", Activity: activity.Name, Attempt: attempt})
+
+		actCtx, cancel := context.WithTimeout(ctx, activity.Timeout)
+		err := activity.Fn(actCtx, ac)
+		if err == nil && actCtx.Err() == context.DeadlineExceeded {
+			err = actCtx.Err()
+		}
+		cancel()
+
+		duration := time.Since(ac.started).Milliseconds()
+		if err == nil {
+			actLogger.Infow("Activity finished", "outcome", "completed", "duration_ms", duration)
+			e.record(exec, HistoryEvent{Type: "activity_completed", Activity: activity.Name, Attempt: attempt})
+			return nil
+		}
+
+		lastErr = err
+		e.record(exec, HistoryEvent{Type: "activity_failed", Activity: activity.Name, Attempt: attempt, Error: err.Error()})
+
+		var nonRetryable *NonRetryableError
+		switch {
+		case ctx.Err() != nil:
+			actLogger.Warnw("Activity finished", "outcome", "cancelled", "duration_ms", duration)
+			return ctx.Err()
+		case errors.As(err, &nonRetryable):
+			actLogger.Errorw("Activity finished",
+				"outcome", "failed",
+				"retryable", false,
+				"error", err.Error(),
+				"duration_ms", duration,
+			)
+			return err
+		case attempt == activity.Retry.MaxAttempts:
+			actLogger.Errorw("Activity finished",
+				"outcome", "failed",
+				"retryable", true,
+				"error", err.Error(),
+				"duration_ms", duration,
+			)
+		default:
+			delay := activity.Retry.delay(attempt)
+			actLogger.Warnw("Activity finished",
+				"outcome", "retrying",
+				"error", err.Error(),
+				"next_attempt_in", delay.String(),
+				"duration_ms", duration,
+			)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	return fmt.Errorf("activity %s failed after %d attempts: %w", activity.Name, activity.Retry.MaxAttempts, lastErr)
+}
+
+func (e *Engine) record(exec *Execution, event HistoryEvent) {
+	event.Time = time.Now().UTC()
+	e.mu.Lock()
+	exec.History = append(exec.History, event)
+	e.mu.Unlock()
+}
+
+func (e *Engine) setCurrent(exec *Execution, activity string) {
+	e.mu.Lock()
+	exec.CurrentActivity = activity
+	e.mu.Unlock()
+}
+
+func (e *Engine) close(exec *Execution, status string, event HistoryEvent) {
+	now := time.Now().UTC()
+	event.Time = now
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exec.Status = status
+	exec.History = append(exec.History, event)
+	exec.ClosedAt = &now
+	if status == StatusCompleted {
+		exec.CurrentActivity = ""
+	}
+}
+
+// snapshot copies the execution; callers must hold the engine lock
+func (exec *Execution) snapshot() Execution {
+	copied := *exec
+	copied.History = append([]HistoryEvent(nil), exec.History...)
+	copied.cancel = nil
+	return copied
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kart-io/go-example/pkg/buildinfo"
+	"github.com/kart-io/go-example/pkg/cliflags"
+	"github.com/kart-io/go-example/pkg/console"
+	"github.com/kart-io/go-example/pkg/crash"
+	"github.com/kart-io/go-example/pkg/health"
+	"github.com/kart-io/go-example/pkg/leakwatch"
+	"github.com/kart-io/go-example/pkg/outputcheck"
+	"github.com/kart-io/go-example/pkg/pidfile"
+	"github.com/kart-io/go-example/pkg/requestid"
+	"github.com/kart-io/go-example/pkg/resusage"
+	"github.com/kart-io/go-example/pkg/runsummary"
+	"github.com/kart-io/go-example/pkg/sanitize"
+	"github.com/kart-io/logger"
+	"github.com/kart-io/logger/option"
+	"github.com/kart-io/version"
+)
+
+// startRequest is the body accepted by POST /workflows
+type startRequest struct {
+	WorkflowID string `json:"workflow_id"`
+	Customer   string `json:"customer" binding:"required"`
+	Email      string `json:"email"`
+	Records    int    `json:"records"`
+	// FlakyProvision fails the first provisioning attempt to demonstrate retries
+	FlakyProvision bool `json:"flaky_provision"`
+}
+
+func main() {
+	cliflags.Parse()
+	console.Println("=== Workflow Engine Demo ===")
+	console.Println("In-process workflow engine with activity start/finish, retry and heartbeat logging")
+	console.Println()
+
+	versionInfo := version.Get()
+
+	logOption := &option.LogOption{
+		Engine:      "zap",
+		Level:       "info",
+		Format:      "json",
+		OutputPaths: []string{"stdout"},
+		InitialFields: map[string]interface{}{
+			"service.name":    versionInfo.ServiceName,
+			"service.version": versionInfo.GitVersion,
+			"component":       "workflow-engine",
+		},
+	}
+
+	cliflags.Apply(logOption)
+	outputs := outputcheck.Verify(context.Background(), logOption)
+	serviceLogger, err := logger.New(logOption)
+	if err != nil {
+		crash.Fatal(nil, "Failed to create logger", "error", err.Error())
+	}
+	summary := runsummary.New()
+	serviceLogger = summary.Wrap(serviceLogger, logOption)
+	crash.OnExit("logger", serviceLogger.Flush)
+	defer crash.Recover(serviceLogger)
+	defer resusage.FromEnv(serviceLogger)()
+	defer leakwatch.FromEnv(serviceLogger)()
+	defer pidfile.FromEnv(serviceLogger)()
+
+	engine := NewEngine(serviceLogger)
+	onboarding := onboardingWorkflow()
+
+	// Background work and the server both stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go engine.ReportRunning(ctx, 5*time.Second)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(summary.Middleware())
+	r.Use(gin.Recovery())
+	r.GET("/version", buildinfo.Handler())
+	r.GET("/health", health.Handler(nil))
+
+	r.POST("/workflows", func(c *gin.Context) {
+		var req startRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.WorkflowID == "" {
+			req.WorkflowID = requestid.New(requestid.Workflow)
+		}
+		if req.Records == 0 {
+			req.Records = 5
+		}
+
+		exec, err := engine.Start(onboarding, req.WorkflowID, map[string]interface{}{
+			"customer":        req.Customer,
+			"email":           req.Email,
+			"records":         req.Records,
+			"flaky_provision": req.FlakyProvision,
+		})
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, exec)
+	})
+
+	r.GET("/workflows/:id", func(c *gin.Context) {
+		exec, ok := engine.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "workflow not found"})
+			return
+		}
+		c.JSON(http.StatusOK, exec)
+	})
+
+	r.POST("/workflows/:id/cancel", func(c *gin.Context) {
+		if err := engine.Cancel(c.Param("id")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "cancel requested"})
+	})
+
+	port := getEnvOrDefault("PORT", "8094")
+	outputs.Log(serviceLogger)
+	sanitize.Startup(serviceLogger, logOption, "port", port)
+	serviceLogger.Infow("Starting workflow engine",
+		"port", port,
+		"workflow_type", onboarding.Type,
+		"endpoints", []string{"/workflows", "/workflows/:id", "/workflows/:id/cancel", "/version"},
+	)
+
+	console.Printf("Starting server on port %s\n", port)
+	console.Println("Try these scenarios:")
+	console.Printf("  curl -X POST http://localhost:%s/workflows -d '{\"workflow_id\":\"wf-1\",\"customer\":\"acme\",\"email\":\"ops@acme.example\"}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/workflows -d '{\"workflow_id\":\"wf-2\",\"customer\":\"globex\",\"email\":\"it@globex.example\",\"flaky_provision\":true}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/workflows -d '{\"workflow_id\":\"wf-3\",\"customer\":\"initech\"}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/workflows -d '{\"workflow_id\":\"wf-4\",\"customer\":\"umbrella\",\"email\":\"a@u.example\",\"records\":40}'\n", port)
+	console.Printf("  curl -X POST http://localhost:%s/workflows/wf-4/cancel\n", port)
+	console.Printf("  curl http://localhost:%s/workflows/wf-2\n", port)
+
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			crash.Fatal(serviceLogger, "Server failed to start", "error", err.Error(), "port", port)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		serviceLogger.Errorw("Server shutdown failed", "error", err.Error())
+	}
+	summary.Log(serviceLogger)
+}
+
+// onboardingWorkflow provisions a new customer account
+func onboardingWorkflow() WorkflowDefinition {
+	defaultRetry := RetryPolicy{
+		MaxAttempts:        3,
+		InitialInterval:    200 * time.Millisecond,
+		BackoffCoefficient: 2,
+		MaxInterval:        2 * time.Second,
+	}
+
+	return WorkflowDefinition{
+		Type: "customer_onboarding",
+		Activities: []Activity{
+			{Name: "validate_customer", Fn: validateCustomer, Timeout: time.Second, Retry: defaultRetry},
+			{Name: "provision_account", Fn: provisionAccount, Timeout: 2 * time.Second, Retry: defaultRetry},
+			{Name: "import_records", Fn: importRecords, Timeout: time.Minute, Retry: defaultRetry},
+			{Name: "send_welcome_email", Fn: sendWelcomeEmail, Timeout: time.Second, Retry: defaultRetry},
+		},
+	}
+}
+
+func validateCustomer(ctx context.Context, ac *ActivityContext) error {
+	if email, _ := ac.Input["email"].(string); email == "" {
+		return &NonRetryableError{Reason: "customer email is required"}
+	}
+	return sleepCtx(ctx, 30*time.Millisecond)
+}
+
+func provisionAccount(ctx context.Context, ac *ActivityContext) error {
+	if flaky, _ := ac.Input["flaky_provision"].(bool); flaky && ac.Attempt == 1 {
+		return fmt.Errorf("provisioning API returned 503")
+	}
+	return sleepCtx(ctx, 150*time.Millisecond)
+}
+
+// importRecords is long-running and reports progress through heartbeats
+func importRecords(ctx context.Context, ac *ActivityContext) error {
+	total, _ := ac.Input["records"].(int)
+	for i := 1; i <= total; i++ {
+		if err := sleepCtx(ctx, 400*time.Millisecond); err != nil {
+			return err
+		}
+		if i%5 == 0 || i == total {
+			ac.Heartbeat(fmt.Sprintf("%d/%d records", i, total))
+		}
+	}
+	return nil
+}
+
+func sendWelcomeEmail(ctx context.Context, ac *ActivityContext) error {
+	return sleepCtx(ctx, 50*time.Millisecond)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}